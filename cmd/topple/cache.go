@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+// compilerVersion identifies the running compiler, so the build cache is
+// discarded when it changes. Development builds all report the same Version
+// and are told apart by the hash of their executable.
+func compilerVersion() string {
+	if Version != "dev" {
+		return Version
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return Version
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return Version
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return Version
+	}
+	return Version + "-" + hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// saveBuildCache writes the build cache after a compilation. Failing to save
+// only costs a slower next build, so it is logged rather than returned.
+func saveBuildCache(cache *compiler.BuildCache, log *slog.Logger, ctx context.Context) {
+	if err := cache.Save(); err != nil {
+		log.WarnContext(ctx, "Could not save build cache", slog.String("error", err.Error()))
+	}
+}
@@ -2,18 +2,28 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/assets"
 	"github.com/fjvillamarin/topple/compiler/codegen"
 	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/observe"
 	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
 	"github.com/fjvillamarin/topple/compiler/transformers"
+	"github.com/fjvillamarin/topple/internal/buildstatus"
 	"github.com/fjvillamarin/topple/internal/filesystem"
+	"github.com/fjvillamarin/topple/internal/project"
 )
 
 // emitSet tracks which intermediate artifacts to emit during compilation.
@@ -65,17 +75,48 @@ func parseEmit(raw string) (emitSet, error) {
 // and various customization flags.
 type CompileCmd struct {
 	// Positional arguments
-	Input  string `arg:"" required:"" help:"Path to a PSX file or directory"`
+	Input  string `arg:"" optional:"" help:"Path to a PSX file or directory (default: the root of the project manifest)"`
 	Output string `arg:"" optional:"" help:"Output directory for compiled Python files (default: same as input)"`
 
 	// Flags
-	Emit       string `help:"Emit intermediate artifacts (comma-separated: tokens,ast,resolution,transformed-ast,all)" short:"e" default:""`
-	SourceRoot string `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	Emit     string `help:"Emit intermediate artifacts (comma-separated: tokens,ast,resolution,transformed-ast,all)" short:"e" default:""`
+	Lockfile string `help:"Vendored package lockfile mode (auto, frozen, update)" enum:"auto,frozen,update" default:"auto"`
+	CompileFlags
+	SourceMap   bool     `help:"Write a source map next to each generated file, for trace-map" name:"source-map"`
+	Stubs       bool     `help:"Write a .pyi stub next to each generated file, declaring its views, functions and classes for IDEs and type checkers" name:"stubs"`
+	NoCache     bool     `help:"Compile every file instead of reusing unchanged outputs from the build cache" name:"no-cache"`
+	FailOnSlow  int      `help:"Fail when compiling a file takes longer than this many milliseconds, listing the time each stage took on the slow files" name:"fail-on-slow" placeholder:"MS"`
+	CheckAssets bool     `help:"Fail when a relative asset reference such as src=\"./logo.png\" points to a missing file" name:"check-assets"`
+	AssetDir    string   `help:"Copy referenced assets here under content-hashed names, rewrite the references and write a manifest (implies --check-assets)" name:"asset-dir" default:""`
+	AssetURL    string   `help:"URL prefix the asset directory is served under" name:"asset-url" default:"/static"`
+	Release     bool     `help:"Production build: only generate views and helpers reachable from the --entry points" name:"release"`
+	Entry       []string `help:"Entry point for --release: a .psx file, or file.psx:Name for one view, function or class" name:"entry"`
+	Events      string   `help:"Write each step of the build (discovered files, resolved imports, cache hits, diagnostics and outputs) to this file as NDJSON" name:"events" placeholder:"FILE" default:""`
+	Build       []string `help:"Build target of the project manifest to compile, from its [build.<name>] tables; repeatable (default: every build target when no input is given)" name:"build" placeholder:"NAME"`
+
+	// The build cache and event log the build targets of one invocation
+	// share; nil when compiling a single build
+	cache  *compiler.BuildCache `kong:"-"`
+	events observe.EventSink    `kong:"-"`
 }
 
-func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
-	// Typically you'd load your configuration file using CLI.Config here,
-	// merge it with flag values, and create a final config struct.
+func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) (err error) {
+	// Without an input, the build targets of the manifest are compiled
+	builds, err := c.builds(globals)
+	if err != nil {
+		return err
+	}
+	if len(builds) > 0 {
+		return c.runBuilds(globals, builds, ctx, log)
+	}
+
+	// Arguments and flags left out come from the project manifest
+	if c.Input, err = projectInput(globals, c.Input); err != nil {
+		return err
+	}
+	if c.Output == "" {
+		c.Output = globals.Project.OutputDir()
+	}
 	log.InfoContext(*ctx, "Running compile command with options")
 	log.InfoContext(*ctx, "Debug mode", slog.Bool("enabled", globals.Debug))
 	log.InfoContext(*ctx, "Recursive mode", slog.Bool("enabled", globals.Recursive))
@@ -88,6 +129,59 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 		return err
 	}
 
+	lockMode, err := module.ParseLockMode(c.Lockfile)
+	if err != nil {
+		return err
+	}
+
+	options, err := c.options()
+	if err != nil {
+		return err
+	}
+	options.OutputDir = c.Output
+	options.SourceMaps = c.SourceMap
+	options.Stubs = c.Stubs
+	metrics := observe.NewCounters()
+	options.Metrics = metrics
+	timings := observe.NewTimings()
+	options.Timings = timings
+	if c.events != nil {
+		options.Events = c.events
+	} else {
+		eventLog, closeEvents, err := openEventLog(c.Events)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := closeEvents(); err == nil {
+				err = closeErr
+			}
+		}()
+		options.Events = eventLog
+	}
+	if c.CheckAssets || c.AssetDir != "" {
+		options.Assets = assets.NewResolver(assets.Config{
+			RootDir:    c.projectRoot(),
+			OutputDir:  c.AssetDir,
+			PublicPath: c.AssetURL,
+		})
+	}
+	multiOpts := compiler.MultiFileOptions{LockMode: lockMode, Options: options, SearchPaths: searchPaths(c.SearchPath)}
+	if c.Release {
+		if len(c.Entry) == 0 {
+			return fmt.Errorf("--release needs at least one --entry point")
+		}
+		for _, entry := range c.Entry {
+			entryPoint, err := compiler.ParseEntryPoint(entry)
+			if err != nil {
+				return err
+			}
+			multiOpts.EntryPoints = append(multiOpts.EntryPoints, entryPoint)
+		}
+	} else if len(c.Entry) > 0 {
+		return fmt.Errorf("--entry is only used by --release builds")
+	}
+
 	// Default behavior: if no output directory is provided, we'll output .py files in the same directory as the input files
 	if c.Output == "" {
 		log.DebugContext(*ctx, "No output path specified, will create .py files in the same directory as input files")
@@ -99,7 +193,7 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 	fs := filesystem.NewFileSystem(log)
 
 	// Initialize the compiler service
-	cmp := compiler.NewCompiler(log)
+	cmp := compiler.NewCompilerWithOptions(log, options)
 
 	// Check if input exists
 	exists, err := fs.Exists(c.Input)
@@ -124,7 +218,7 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 		log.DebugContext(*ctx, "Input is a directory", slog.String("path", c.Input))
 
 		// List all PSX files
-		files, err := fs.ListPSXFiles(c.Input, globals.Recursive)
+		files, err := listSources(*ctx, fs, c.Input, globals.Recursive, log)
 		if err != nil {
 			return fmt.Errorf("error listing PSX files: %w", err)
 		}
@@ -134,13 +228,14 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 		if emit.any() {
 			// Emit path: compile files individually to write intermediate artifacts
 			for _, file := range files {
-				if err := compileFile(fs, cmp, file, c.Output, emit, log, *ctx); err != nil {
+				if err := compileFile(fs, cmp, file, c.Output, emit, options, log, *ctx); err != nil {
 					return err
 				}
 			}
 		} else {
 			// Fast path: use multi-file compiler for proper dependency resolution
-			if err := compileMultiFile(files, c.Input, c.Output, c.SourceRoot, log, *ctx); err != nil {
+			multiOpts.Cache = c.buildCache(c.Input)
+			if _, err := compileMultiFile(files, c.Input, c.Output, c.SourceRoot, multiOpts, log, *ctx); err != nil {
 				return err
 			}
 		}
@@ -155,7 +250,7 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 
 		if emit.any() {
 			// Emit path: compile single file with intermediate artifacts
-			if err := compileFile(fs, cmp, c.Input, c.Output, emit, log, *ctx); err != nil {
+			if err := compileFile(fs, cmp, c.Input, c.Output, emit, options, log, *ctx); err != nil {
 				return err
 			}
 		} else {
@@ -166,81 +261,396 @@ func (c *CompileCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logge
 			siblingFiles, err := fs.ListPSXFiles(inputDir, false)
 			if err != nil || len(siblingFiles) <= 1 {
 				// No sibling files or error - fall back to single-file compilation
-				if err := compileFile(fs, cmp, c.Input, c.Output, emit, log, *ctx); err != nil {
+				if err := compileFile(fs, cmp, c.Input, c.Output, emit, options, log, *ctx); err != nil {
 					return err
 				}
 			} else {
 				// Multiple PSX files in directory - use multi-file compiler
-				if err := compileSingleWithContext(c.Input, siblingFiles, inputDir, c.Output, c.SourceRoot, log, *ctx); err != nil {
+				multiOpts.Cache = c.buildCache(inputDir)
+				if err := compileSingleWithContext(c.Input, siblingFiles, inputDir, c.Output, c.SourceRoot, multiOpts, log, *ctx); err != nil {
 					return err
 				}
 			}
 		}
 	}
 
+	if options.Assets != nil {
+		if err := options.Assets.WriteManifest(); err != nil {
+			return err
+		}
+		log.InfoContext(*ctx, "Resolved assets", slog.Int("copied", len(options.Assets.Manifest())))
+	}
+
 	elapsed := time.Since(startTime)
 	log.InfoContext(*ctx, "Compilation completed", slog.Duration("elapsed", elapsed))
+	for _, name := range metrics.Names() {
+		log.DebugContext(*ctx, "Compilation metric", slog.String("name", name), slog.Int64("value", metrics.Get(name)))
+	}
 
+	if c.FailOnSlow > 0 {
+		return checkTimeBudget(os.Stderr, timings, time.Duration(c.FailOnSlow)*time.Millisecond)
+	}
 	return nil
 }
 
-// compileMultiFile compiles multiple PSX files with import resolution
-func compileMultiFile(files []string, rootDir, outputDir, sourceRoot string, log *slog.Logger, ctx context.Context) error {
-	log.DebugContext(ctx, "Using multi-file compilation", slog.Int("fileCount", len(files)))
+// builds returns the build targets of the manifest to compile: those named
+// by --build, or all of them when no input is given
+func (c *CompileCmd) builds(globals *Globals) ([]*project.Build, error) {
+	if len(c.Build) == 0 {
+		if c.Input != "" {
+			return nil, nil
+		}
+		return globals.Project.Builds(), nil
+	}
+	if c.Input != "" {
+		return nil, fmt.Errorf("--build compiles the inputs of the manifest's build targets and takes no input path")
+	}
+	if globals.Project == nil {
+		return nil, fmt.Errorf("--build needs a %s declaring build targets", project.FileName)
+	}
+	var builds []*project.Build
+	for _, name := range c.Build {
+		build := globals.Project.Build(name)
+		if build == nil {
+			names := make([]string, len(globals.Project.Builds()))
+			for i, build := range globals.Project.Builds() {
+				names[i] = build.Name
+			}
+			return nil, fmt.Errorf("unknown build target %q (valid: %s)", name, strings.Join(names, ", "))
+		}
+		builds = append(builds, build)
+	}
+	return builds, nil
+}
 
-	// Create multi-file compiler
-	multiCompiler := compiler.NewMultiFileCompiler(log)
+// runBuilds compiles build targets in turn, sharing the build cache of the
+// manifest's directory and the event log
+func (c *CompileCmd) runBuilds(globals *Globals, builds []*project.Build, ctx *context.Context, log *slog.Logger) (err error) {
+	cache := c.buildCache(filepath.Dir(globals.Project.Path))
+	eventLog, closeEvents, err := openEventLog(c.Events)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeEvents(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, build := range builds {
+		log.InfoContext(*ctx, "Compiling build target", slog.String("name", build.Name))
+		cmd := c.forBuild(globals, build)
+		cmd.cache = cache
+		cmd.events = eventLog
+		if err := cmd.Run(globals, ctx, log); err != nil {
+			return fmt.Errorf("build %s: %w", build.Name, err)
+		}
+	}
+	return nil
+}
 
-	// Use --source-root if provided, otherwise fall back to rootDir
-	resolveRoot := rootDir
-	if sourceRoot != "" {
-		resolveRoot = sourceRoot
+// forBuild returns the command compiling a build target: its root to its
+// output directory, with the settings it declares unless the command line
+// gives them
+func (c *CompileCmd) forBuild(globals *Globals, build *project.Build) *CompileCmd {
+	cmd := *c
+	cmd.Build = nil
+	cmd.Input = build.Root
+	cmd.Output = build.Out
+	override := func(name string) (any, bool) {
+		if globals.flagGiven(name) {
+			return nil, false
+		}
+		return build.Flag(name)
 	}
 
-	// Prepare options
-	opts := compiler.MultiFileOptions{
-		RootDir: resolveRoot,
-		Files:   files,
+	if value, ok := override("source-root"); ok {
+		cmd.SourceRoot = value.(string)
+	}
+	if value, ok := override("search-path"); ok {
+		cmd.SearchPath = manifestStrings(value)
+	}
+	if value, ok := override("target"); ok {
+		cmd.Target = value.(string)
+	}
+	if value, ok := override("strict-props"); ok {
+		cmd.StrictProps = value.(bool)
 	}
+	if value, ok := override("strict-imports"); ok {
+		cmd.StrictImports = value.(bool)
+	}
+	// A build with entry points is a release build of what they reach
+	if value, ok := override("entry"); ok {
+		cmd.Entry = manifestStrings(value)
+		cmd.Release = true
+	}
+	return &cmd
+}
 
-	// Compile all files
-	output, err := multiCompiler.CompileProject(ctx, opts)
+// manifestStrings converts an array of the manifest to strings
+func manifestStrings(value any) []string {
+	items := value.([]any)
+	strs := make([]string, len(items))
+	for i, item := range items {
+		strs[i] = item.(string)
+	}
+	return strs
+}
+
+// openEventLog creates the event log of --events; close flushes it and
+// reports a failed write. Without a path events are discarded.
+func openEventLog(path string) (events observe.EventSink, close func() error, err error) {
+	if path == "" {
+		return nil, func() error { return nil }, nil
+	}
+	file, err := os.Create(path)
 	if err != nil {
-		// Log all compilation errors
-		if output != nil && len(output.Errors) > 0 {
-			for _, compErr := range output.Errors {
-				detailsMsg := ""
-				if compErr.Details != nil {
-					detailsMsg = compErr.Details.Error()
-				}
-				log.ErrorContext(ctx, "Compilation error",
-					slog.String("file", compErr.File),
-					slog.String("stage", compErr.Stage),
-					slog.String("message", compErr.Message),
-					slog.String("details", detailsMsg))
+		return nil, nil, fmt.Errorf("error creating event log: %w", err)
+	}
+	log := observe.NewEventLog(file)
+	return log, func() error {
+		err := log.Err()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("error writing event log %s: %w", path, err)
+		}
+		return nil
+	}, nil
+}
+
+// checkTimeBudget fails when a file took longer than budget to compile,
+// listing the slow files with the time each stage took
+func checkTimeBudget(w io.Writer, timings *observe.Timings, budget time.Duration) error {
+	var slow []string
+	for _, file := range timings.Files() {
+		if timings.Total(file) > budget {
+			slow = append(slow, file)
+		}
+	}
+	if len(slow) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(w, "Files over the %s budget:\n", budget)
+	for _, file := range slow {
+		var stages []string
+		for _, stage := range observe.Stages {
+			if elapsed := timings.Stage(file, stage); elapsed > 0 {
+				stages = append(stages, fmt.Sprintf("%s %s", stage, elapsed.Round(time.Microsecond)))
 			}
 		}
-		return fmt.Errorf("multi-file compilation failed: %w", err)
+		fmt.Fprintf(w, "  %s: %s (%s)\n", file, timings.Total(file).Round(time.Microsecond), strings.Join(stages, ", "))
+	}
+	return fmt.Errorf("%s took longer than %s to compile", plural(len(slow), "file"), budget)
+}
+
+// projectRoot returns the directory imports and asset manifest entries are
+// relative to: the source root, or else the input directory
+func (c *CompileCmd) projectRoot() string {
+	if c.SourceRoot != "" {
+		return c.SourceRoot
+	}
+	if info, err := os.Stat(c.Input); err == nil && info.IsDir() {
+		return c.Input
+	}
+	return filepath.Dir(c.Input)
+}
+
+// buildCache opens the build cache of the project rooted at rootDir, or
+// returns nil when caching is disabled. The cache lives in the source root
+// when one is given, whatever the output directory is.
+func (c *CompileCmd) buildCache(rootDir string) *compiler.BuildCache {
+	if c.NoCache {
+		return nil
+	}
+	if c.cache != nil {
+		return c.cache
+	}
+	if c.SourceRoot != "" {
+		rootDir = c.SourceRoot
+	}
+	return compiler.OpenBuildCache(filepath.Join(rootDir, compiler.BuildCacheDirName), compilerVersion())
+}
+
+// listSources lists the PSX files to compile in a directory. In recursive
+// mode the module resolver also checks that each can be imported by name;
+// those that cannot, such as 404-page.psx or files hidden by a module of the
+// same name, are still compiled, with a warning.
+func listSources(ctx context.Context, fs filesystem.FileSystem, dir string, recursive bool, log *slog.Logger) ([]string, error) {
+	files, err := fs.ListPSXFiles(dir, recursive)
+	if err != nil || !recursive {
+		return files, err
+	}
+
+	modules, err := module.NewResolver(module.Config{RootDir: dir, FileSystem: fs, Logger: log}).DiscoverModules(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	discovered := make(map[string]bool, len(modules))
+	for _, path := range modules {
+		discovered[path] = true
+	}
+	rootInit := filepath.Join(dir, "__init__.psx")
+	if absInit, err := fs.AbsolutePath(rootInit); err == nil {
+		rootInit = absInit
+	}
+	for _, path := range files {
+		absPath, err := fs.AbsolutePath(path)
+		if err == nil && !discovered[absPath] && absPath != rootInit && !module.IsVendoredPath(absPath) {
+			log.WarnContext(ctx, "PSX file cannot be imported by module name", slog.String("path", path))
+		}
+	}
+	return files, nil
+}
+
+// searchPaths returns the search paths for absolute imports: the given
+// flags, then those of $TOPPLEPATH
+func searchPaths(flags []string) []string {
+	return append(append([]string{}, flags...), module.SearchPathsFromEnv()...)
+}
+
+// projectInput returns the path a command works on: the argument, or the
+// source root of the project manifest when it is left out
+func projectInput(globals *Globals, input string) (string, error) {
+	if input != "" {
+		return input, nil
+	}
+	if globals.Project == nil {
+		return "", fmt.Errorf("no input path given and no %s found in the working directory or its parents", project.FileName)
+	}
+	return globals.Project.Root, nil
+}
+
+// importPolicy builds the policy deciding which absolute imports are
+// Python modules from the import flags
+func importPolicy(external, internal []string, strict bool) (module.ImportPolicy, error) {
+	policy := module.ImportPolicy{External: external, Internal: internal, Strict: strict}
+	return policy, policy.Validate()
+}
+
+// CompileFlags are the flags of the commands that compile views: compile,
+// verify and watch.
+type CompileFlags struct {
+	SourceRoot     string   `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	SearchPath     []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+	HTMLComments   string   `help:"What to do with <!-- --> comments in views (strip, render, python)" name:"html-comments" enum:"strip,render,python" default:"strip"`
+	Intrinsic      []string `help:"Extra tag names to compile as built-in elements, such as design-system primitives" name:"intrinsic-element" sep:","`
+	Deny           []string `help:"Tag names that may not be used in views" name:"deny-element" sep:","`
+	Defaults       string   `help:"JSON file of attributes every element of a tag or composition of a view gets unless it sets them, such as {\"button\": {\"type\": \"button\"}}" name:"attribute-defaults" placeholder:"FILE" default:""`
+	EarlyReturns   string   `help:"Whether return may end view rendering early (allow, forbid)" name:"early-returns" enum:"allow,forbid" default:"allow"`
+	Markdown       bool     `help:"Render <Markdown> blocks to static HTML at compile time" name:"markdown"`
+	Whitespace     bool     `help:"Keep whitespace-only text between tags and interpolations, such as the space in <b>a</b> <i>b</i> (always kept inside <pre> and <textarea>)" name:"preserve-whitespace"`
+	SourceMarkers  bool     `help:"Surround each generated view class with comments naming the PSX lines it came from" name:"source-markers"`
+	LineDirectives bool     `help:"Write a '# line: file.psx:N' comment before the generated code of each source line, for debuggers and stack-trace rewriters" name:"line-directives"`
+	StrictProps    bool     `help:"Fail when a literal attribute passed to a view does not match the parameter's annotation, such as count=\"5\" for count: int" name:"strict-props"`
+	HTMLCheck      bool     `help:"Check markup after parsing: void elements such as <br> with content fail, and nesting browsers would restructure, such as <div> inside <p>, is a warning" name:"html-check" default:"true" negatable:""`
+	Naming         string   `help:"Naming conventions to enforce, such as views=pascal,modules=snake,slots=kebab (cases: pascal, camel, snake, kebab)" name:"naming" default:""`
+	External       []string `help:"Module prefixes to emit as Python imports without looking for a PSX module, such as datetime or fastapi" name:"external-module" sep:","`
+	Internal       []string `help:"Module prefixes that must resolve to a PSX module" name:"internal-module" sep:","`
+	StrictImports  bool     `help:"Fail on absolute imports that resolve to no PSX module unless declared with --external-module" name:"strict-imports"`
+	RuntimeAPI     int      `help:"Runtime API version of the topple package to generate code for, so apps pinned to an older release keep working (default: the latest)" name:"runtime-api"`
+	RuntimeModule  string   `help:"Module generated code imports the runtime from, such as a vendored copy at myapp._vendor.psx (default: topple.psx)" name:"runtime-module" default:""`
+	Header         string   `help:"Comment written at the top of every generated file, such as a license or generation notice; {source} is replaced with the source file and {hash} with a hash of its content. Files without it at output paths are not overwritten" name:"header" default:"${default_header}"`
+	Target         string   `help:"Python version generated code must run on (py38, py310, py312); newer constructs such as type statements and PEP 695 generics are lowered to older equivalents" name:"target" enum:"py38,py310,py312" default:"py312"`
+}
+
+// options builds the compiler options the flags set
+func (f *CompileFlags) options() (compiler.Options, error) {
+	commentMode, err := transformers.ParseHTMLCommentMode(f.HTMLComments)
+	if err != nil {
+		return compiler.Options{}, err
+	}
+	returnMode, err := transformers.ParseEarlyReturnMode(f.EarlyReturns)
+	if err != nil {
+		return compiler.Options{}, err
+	}
+	var attributeDefaults transformers.AttributeDefaults
+	if f.Defaults != "" {
+		data, err := os.ReadFile(f.Defaults)
+		if err != nil {
+			return compiler.Options{}, fmt.Errorf("error reading attribute defaults: %w", err)
+		}
+		if attributeDefaults, err = transformers.ParseAttributeDefaults(f.Defaults, data); err != nil {
+			return compiler.Options{}, err
+		}
+	}
+	options := compiler.Options{
+		HTMLComments:       commentMode,
+		Elements:           transformers.ElementPolicy{Intrinsic: f.Intrinsic, Denied: f.Deny},
+		Defaults:           attributeDefaults,
+		EarlyReturns:       returnMode,
+		Markdown:           f.Markdown,
+		PreserveWhitespace: f.Whitespace,
+		Markers:            f.SourceMarkers,
+		LineDirectives:     f.LineDirectives,
+		StrictProps:        f.StrictProps,
+		SkipHTMLCheck:      !f.HTMLCheck,
+	}
+	if options.Naming, err = resolver.ParseNamingConventions(f.Naming); err != nil {
+		return compiler.Options{}, err
+	}
+	if options.Imports, err = importPolicy(f.External, f.Internal, f.StrictImports); err != nil {
+		return compiler.Options{}, err
+	}
+	if options.RuntimeAPI, err = transformers.ParseRuntimeAPI(f.RuntimeAPI); err != nil {
+		return compiler.Options{}, err
+	}
+	if options.RuntimeModule, err = transformers.ParseRuntimeModule(f.RuntimeModule); err != nil {
+		return compiler.Options{}, err
+	}
+	if options.Header, err = compiler.ParseHeader(f.Header); err != nil {
+		return compiler.Options{}, err
+	}
+	if options.Target, err = codegen.ParseTarget(f.Target); err != nil {
+		return compiler.Options{}, err
+	}
+	return options, nil
+}
+
+// compileMultiFile compiles multiple PSX files with import resolution.
+// RootDir and Files of opts are filled in from the arguments.
+func compileMultiFile(files []string, rootDir, outputDir, sourceRoot string, opts compiler.MultiFileOptions, log *slog.Logger, ctx context.Context) (*compiler.MultiFileOutput, error) {
+	output, err := compileProject(files, rootDir, sourceRoot, opts, log, ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Write all output files
 	fs := filesystem.NewFileSystem(log)
-	for inputPath, code := range output.CompiledFiles {
-		// Determine output path
+	if err := writeLockfile(fs, output, log, ctx); err != nil {
+		return nil, err
+	}
+	outputPaths := make(map[string]string, len(output.CompiledFiles))
+	for inputPath := range output.CompiledFiles {
 		outputPath, err := fs.GetOutputPath(inputPath, outputDir)
 		if err != nil {
-			return fmt.Errorf("error determining output path for %s: %w", inputPath, err)
+			return nil, fmt.Errorf("error determining output path for %s: %w", inputPath, err)
 		}
+		outputPaths[inputPath] = outputPath
+	}
+	if err := checkOutputs(outputPaths, opts.Options); err != nil {
+		return nil, err
+	}
+	for inputPath, code := range output.CompiledFiles {
+		outputPath := outputPaths[inputPath]
 
 		// Ensure output directory exists
 		outputDirPath := filepath.Dir(outputPath)
 		if err := fs.MkdirAll(outputDirPath, 0755); err != nil {
-			return fmt.Errorf("error creating output directory %s: %w", outputDirPath, err)
+			return nil, fmt.Errorf("error creating output directory %s: %w", outputDirPath, err)
 		}
 
 		// Write file
 		if err := fs.WriteFile(outputPath, code, 0644); err != nil {
-			return fmt.Errorf("error writing output file %s: %w", outputPath, err)
+			return nil, fmt.Errorf("error writing output file %s: %w", outputPath, err)
+		}
+		if err := writeSourceMap(fs, output.SourceMaps[inputPath], inputPath, outputPath); err != nil {
+			return nil, err
+		}
+		if err := writeStub(fs, output.Stubs[inputPath], outputPath); err != nil {
+			return nil, err
 		}
 
 		log.InfoContext(ctx, "Compiled file",
@@ -250,13 +660,70 @@ func compileMultiFile(files []string, rootDir, outputDir, sourceRoot string, log
 	}
 
 	log.InfoContext(ctx, "Multi-file compilation successful", slog.Int("filesCompiled", len(output.CompiledFiles)))
-	return nil
+	return output, nil
+}
+
+// compileProject compiles PSX files with import resolution without writing
+// anything, logging each compilation error of a failed build
+func compileProject(files []string, rootDir, sourceRoot string, opts compiler.MultiFileOptions, log *slog.Logger, ctx context.Context) (*compiler.MultiFileOutput, error) {
+	log.DebugContext(ctx, "Using multi-file compilation", slog.Int("fileCount", len(files)))
+
+	// Create multi-file compiler
+	multiCompiler := compiler.NewMultiFileCompiler(log)
+
+	// Use --source-root if provided, otherwise fall back to rootDir
+	resolveRoot := rootDir
+	if sourceRoot != "" {
+		resolveRoot = sourceRoot
+	}
+
+	// Prepare options
+	opts.RootDir = resolveRoot
+	opts.Files = files
+
+	// Compile all files
+	output, err := multiCompiler.CompileProject(ctx, opts)
+	saveBuildCache(opts.Cache, log, ctx)
+	if err != nil {
+		// Report all compilation errors
+		if output != nil && len(output.Errors) > 0 {
+			printCompilationErrors(os.Stderr, output.Errors)
+			for _, compErr := range output.Errors {
+				reportCrash(compErr.Details, log)
+			}
+		}
+		projErr := &buildstatus.ProjectError{Err: err}
+		if output != nil {
+			projErr.Errors = output.Errors
+		}
+		return nil, projErr
+	}
+	logEliminated(output, log, ctx)
+	return output, nil
+}
+
+// logEliminated reports what dead-code elimination left out of a release build
+func logEliminated(output *compiler.MultiFileOutput, log *slog.Logger, ctx context.Context) {
+	if output.Eliminated == nil {
+		return
+	}
+	files, definitions := 0, 0
+	for filePath, names := range output.Eliminated {
+		if names == nil {
+			files++
+			log.DebugContext(ctx, "Dropped unreachable file", slog.String("file", filePath))
+			continue
+		}
+		definitions += len(names)
+		log.DebugContext(ctx, "Dropped unreachable definitions", slog.String("file", filePath), slog.Any("names", names))
+	}
+	log.InfoContext(ctx, "Eliminated dead code", slog.Int("files", files), slog.Int("definitions", definitions))
 }
 
 // compileSingleWithContext compiles a single PSX file using multi-file compilation
 // to resolve cross-file view imports. It compiles all sibling files for context
 // but only writes the output for the target file.
-func compileSingleWithContext(targetFile string, allFiles []string, rootDir, outputDir, sourceRoot string, log *slog.Logger, ctx context.Context) error {
+func compileSingleWithContext(targetFile string, allFiles []string, rootDir, outputDir, sourceRoot string, opts compiler.MultiFileOptions, log *slog.Logger, ctx context.Context) error {
 	log.DebugContext(ctx, "Using multi-file compilation for single file",
 		slog.String("target", targetFile),
 		slog.Int("contextFiles", len(allFiles)))
@@ -269,28 +736,21 @@ func compileSingleWithContext(targetFile string, allFiles []string, rootDir, out
 		resolveRoot = sourceRoot
 	}
 
-	opts := compiler.MultiFileOptions{
-		RootDir: resolveRoot,
-		Files:   allFiles,
-	}
+	opts.RootDir = resolveRoot
+	opts.Files = allFiles
 
 	output, err := multiCompiler.CompileProject(ctx, opts)
+	saveBuildCache(opts.Cache, log, ctx)
 	if err != nil {
 		if output != nil && len(output.Errors) > 0 {
+			printCompilationErrors(os.Stderr, output.Errors)
 			for _, compErr := range output.Errors {
-				detailsMsg := ""
-				if compErr.Details != nil {
-					detailsMsg = compErr.Details.Error()
-				}
-				log.ErrorContext(ctx, "Compilation error",
-					slog.String("file", compErr.File),
-					slog.String("stage", compErr.Stage),
-					slog.String("message", compErr.Message),
-					slog.String("details", detailsMsg))
+				reportCrash(compErr.Details, log)
 			}
 		}
 		return fmt.Errorf("multi-file compilation failed: %w", err)
 	}
+	logEliminated(output, log, ctx)
 
 	// Resolve target file to absolute path for lookup
 	absTarget, err := filepath.Abs(targetFile)
@@ -300,6 +760,9 @@ func compileSingleWithContext(targetFile string, allFiles []string, rootDir, out
 
 	// Write output only for the target file
 	fs := filesystem.NewFileSystem(log)
+	if err := writeLockfile(fs, output, log, ctx); err != nil {
+		return err
+	}
 	for inputPath, code := range output.CompiledFiles {
 		absInput, _ := filepath.Abs(inputPath)
 		if absInput != absTarget {
@@ -310,6 +773,9 @@ func compileSingleWithContext(targetFile string, allFiles []string, rootDir, out
 		if err != nil {
 			return fmt.Errorf("error determining output path for %s: %w", inputPath, err)
 		}
+		if err := checkOutputs(map[string]string{inputPath: outputPath}, opts.Options); err != nil {
+			return err
+		}
 
 		outputDirPath := filepath.Dir(outputPath)
 		if err := fs.MkdirAll(outputDirPath, 0755); err != nil {
@@ -319,6 +785,12 @@ func compileSingleWithContext(targetFile string, allFiles []string, rootDir, out
 		if err := fs.WriteFile(outputPath, code, 0644); err != nil {
 			return fmt.Errorf("error writing output file %s: %w", outputPath, err)
 		}
+		if err := writeSourceMap(fs, output.SourceMaps[inputPath], inputPath, outputPath); err != nil {
+			return err
+		}
+		if err := writeStub(fs, output.Stubs[inputPath], outputPath); err != nil {
+			return err
+		}
 
 		log.InfoContext(ctx, "Compiled file",
 			slog.String("input", inputPath),
@@ -329,9 +801,91 @@ func compileSingleWithContext(targetFile string, allFiles []string, rootDir, out
 	return nil
 }
 
+// checkOutputs refuses to overwrite Python files topple did not generate,
+// checking every output path, by input path, before anything is written
+func checkOutputs(outputPaths map[string]string, options compiler.Options) error {
+	inputs := make([]string, 0, len(outputPaths))
+	for inputPath := range outputPaths {
+		inputs = append(inputs, inputPath)
+	}
+	sort.Strings(inputs)
+
+	collisions := 0
+	for _, inputPath := range inputs {
+		err := options.CheckOutput(inputPath, displayPath(outputPaths[inputPath]))
+		var collision *compiler.OutputCollision
+		if errors.As(err, &collision) {
+			printDiagnostics(os.Stderr, inputPath, nil, err)
+			collisions++
+		} else if err != nil {
+			return err
+		}
+	}
+	if collisions > 0 {
+		return fmt.Errorf("refusing to overwrite %s topple did not generate", plural(collisions, "Python file"))
+	}
+	return nil
+}
+
+// writeSourceMap writes the source map of a generated file next to it; nil
+// maps, when source maps are off, are skipped
+func writeSourceMap(fs filesystem.FileSystem, sourceMap *sourcemap.Map, inputPath, outputPath string) error {
+	if sourceMap == nil {
+		return nil
+	}
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return fmt.Errorf("error resolving input path: %w", err)
+	}
+	absOutput, err := filepath.Abs(outputPath)
+	if err != nil {
+		return fmt.Errorf("error resolving output path: %w", err)
+	}
+	sourceMap.Locate(absOutput, absInput)
+
+	data, err := sourceMap.Marshal()
+	if err != nil {
+		return fmt.Errorf("error encoding source map for %s: %w", outputPath, err)
+	}
+	mapPath := sourcemap.PathFor(outputPath)
+	if err := fs.WriteFile(mapPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing source map %s: %w", mapPath, err)
+	}
+	return nil
+}
+
+// writeStub writes the .pyi stub of a generated file next to it; nil stubs,
+// when stubs are off, are skipped
+func writeStub(fs filesystem.FileSystem, stub []byte, outputPath string) error {
+	if stub == nil {
+		return nil
+	}
+	stubPath := strings.TrimSuffix(outputPath, ".py") + ".pyi"
+	if err := fs.WriteFile(stubPath, stub, 0644); err != nil {
+		return fmt.Errorf("error writing stub %s: %w", stubPath, err)
+	}
+	return nil
+}
+
+// writeLockfile persists the vendored package lockfile when compilation produced a new one
+func writeLockfile(fs filesystem.FileSystem, output *compiler.MultiFileOutput, log *slog.Logger, ctx context.Context) error {
+	if !output.LockfileChanged || output.Lockfile == nil {
+		return nil
+	}
+
+	if err := module.WriteLockfile(fs, output.LockfilePath, output.Lockfile); err != nil {
+		return fmt.Errorf("error writing lockfile %s: %w", output.LockfilePath, err)
+	}
+
+	log.InfoContext(ctx, "Wrote lockfile",
+		slog.String("path", output.LockfilePath),
+		slog.Int("packages", len(output.Lockfile.Packages)))
+	return nil
+}
+
 // compileFile compiles a single PSX file to a Python file.
 // When emit flags are set, it runs the pipeline step-by-step and writes intermediate artifacts.
-func compileFile(fs filesystem.FileSystem, cmp compiler.Compiler, inputPath, outputDir string, emit emitSet, log *slog.Logger, ctx context.Context) error {
+func compileFile(fs filesystem.FileSystem, cmp *compiler.StandardCompiler, inputPath, outputDir string, emit emitSet, options compiler.Options, log *slog.Logger, ctx context.Context) error {
 	log.DebugContext(ctx, "Compiling file", slog.String("input", inputPath))
 
 	// Read the input file
@@ -345,6 +899,9 @@ func compileFile(fs filesystem.FileSystem, cmp compiler.Compiler, inputPath, out
 	if err != nil {
 		return fmt.Errorf("error determining output path: %w", err)
 	}
+	if err := checkOutputs(map[string]string{inputPath: outputPath}, options); err != nil {
+		return err
+	}
 
 	// Ensure the output directory exists
 	outputDirPath := filepath.Dir(outputPath)
@@ -358,10 +915,11 @@ func compileFile(fs filesystem.FileSystem, cmp compiler.Compiler, inputPath, out
 			Name:    filepath.Base(inputPath),
 			Content: content,
 		}
-		pythonCode, errors := cmp.Compile(ctx, file)
+		pythonCode, sourceMap, errors := cmp.CompileWithSourceMap(ctx, file)
 		if len(errors) > 0 {
+			printDiagnostics(os.Stderr, inputPath, content, errors...)
 			for _, err := range errors {
-				log.ErrorContext(ctx, "Error compiling file", slog.String("error", err.Error()))
+				reportCrash(err, log)
 			}
 			return fmt.Errorf("error compiling file: %d errors", len(errors))
 		}
@@ -369,6 +927,21 @@ func compileFile(fs filesystem.FileSystem, cmp compiler.Compiler, inputPath, out
 		if err := fs.WriteFile(outputPath, pythonCode, 0644); err != nil {
 			return fmt.Errorf("error writing output file: %w", err)
 		}
+		if options.SourceMaps {
+			if err := writeSourceMap(fs, sourceMap, inputPath, outputPath); err != nil {
+				return err
+			}
+		}
+		if options.Stubs {
+			stub, errors := cmp.CompileStub(ctx, file)
+			if len(errors) > 0 {
+				printDiagnostics(os.Stderr, inputPath, content, errors...)
+				return fmt.Errorf("error generating stub: %d errors", len(errors))
+			}
+			if err := writeStub(fs, stub, outputPath); err != nil {
+				return err
+			}
+		}
 
 		log.InfoContext(ctx, "Compiled file",
 			slog.String("input", inputPath),
@@ -380,20 +953,18 @@ func compileFile(fs filesystem.FileSystem, cmp compiler.Compiler, inputPath, out
 	}
 
 	// Emit path: run pipeline step-by-step
-	return compileFileWithEmit(fs, content, inputPath, outputDir, outputPath, emit, log, ctx)
+	return compileFileWithEmit(fs, content, inputPath, outputDir, outputPath, emit, options, log, ctx)
 }
 
 // compileFileWithEmit runs the compilation pipeline step-by-step,
 // writing intermediate artifacts at each stage based on emit flags.
-func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, outputDir, outputPath string, emit emitSet, log *slog.Logger, ctx context.Context) error {
+func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, outputDir, outputPath string, emit emitSet, options compiler.Options, log *slog.Logger, ctx context.Context) error {
 	filename := filepath.Base(inputPath)
 
 	// Step 1: Scan
-	tokens, errors := compiler.Scan(content)
+	tokens, errors := compiler.ScanWithConfig(content, options.ScannerConfig())
 	if len(errors) > 0 {
-		for _, err := range errors {
-			log.ErrorContext(ctx, "Scan error", slog.String("error", err.Error()))
-		}
+		printDiagnostics(os.Stderr, inputPath, content, errors...)
 		return fmt.Errorf("error scanning file: %d errors", len(errors))
 	}
 
@@ -407,11 +978,9 @@ func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, ou
 	}
 
 	// Step 2: Parse
-	module, errors := compiler.ParseTokens(tokens)
+	module, errors := compiler.ParseTokensWithSyntax(tokens, lexer.SyntaxOf(content))
 	if len(errors) > 0 {
-		for _, err := range errors {
-			log.ErrorContext(ctx, "Parse error", slog.String("error", err.Error()))
-		}
+		printDiagnostics(os.Stderr, inputPath, content, errors...)
 		return fmt.Errorf("error parsing file: %d errors", len(errors))
 	}
 
@@ -432,9 +1001,7 @@ func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, ou
 		return fmt.Errorf("error resolving file: %w", err)
 	}
 	if len(resolutionTable.Errors) > 0 {
-		for _, err := range resolutionTable.Errors {
-			log.ErrorContext(ctx, "Resolution error", slog.String("error", err.Error()))
-		}
+		printDiagnostics(os.Stderr, inputPath, content, resolutionTable.Errors...)
 		return fmt.Errorf("error resolving file: %d errors", len(resolutionTable.Errors))
 	}
 
@@ -453,7 +1020,9 @@ func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, ou
 	}
 
 	// Step 4: Transform
-	transformerVisitor := transformers.NewTransformerVisitor()
+	transformerOptions := options.TransformerOptions()
+	transformerOptions.SourceFile = inputPath
+	transformerVisitor := transformers.NewTransformerVisitorWithOptions(transformerOptions)
 	module, err = transformerVisitor.TransformModule(module, resolutionTable)
 	if err != nil {
 		return fmt.Errorf("error transforming file: %w", err)
@@ -469,13 +1038,39 @@ func compileFileWithEmit(fs filesystem.FileSystem, content []byte, inputPath, ou
 		log.InfoContext(ctx, "Wrote transformed AST file", slog.String("output", tastPath))
 	}
 
-	// Step 5: Codegen (always)
+	// Step 5: Codegen (always), for the target Python version
+	stubbed := module
+	module, err = codegen.Lower(module, options.Target)
+	if err != nil {
+		return fmt.Errorf("error lowering file for %s: %w", options.Target, err)
+	}
 	generator := codegen.NewCodeGenerator()
-	result := generator.Generate(module)
+	generator.Target = options.Target
+	result, err := generator.GenerateContext(ctx, module)
+	if err != nil {
+		return fmt.Errorf("error generating code: %w", err)
+	}
 
-	if err := fs.WriteFile(outputPath, []byte(result), 0644); err != nil {
+	sourceMap := generator.SourceMap()
+	code := options.WithHeader([]byte(result), sourceMap, filepath.Base(inputPath), content)
+	if err := fs.WriteFile(outputPath, code, 0644); err != nil {
 		return fmt.Errorf("error writing output file: %w", err)
 	}
+	if options.SourceMaps {
+		if err := writeSourceMap(fs, sourceMap, inputPath, outputPath); err != nil {
+			return err
+		}
+	}
+	if options.Stubs {
+		stub, err := compiler.GenerateStub(stubbed, options.Target)
+		if err != nil {
+			return fmt.Errorf("error generating stub: %w", err)
+		}
+		stub = options.WithHeader(stub, nil, filepath.Base(inputPath), content)
+		if err := writeStub(fs, stub, outputPath); err != nil {
+			return err
+		}
+	}
 
 	log.InfoContext(ctx, "Compiled file",
 		slog.String("input", inputPath),
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileRecursiveKeepsUnimportableFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/__init__.psx": `view Root():
+    <p>root</p>
+`,
+		"src/404-page.psx": `view NotFound():
+    <p>Not found</p>
+`,
+		"src/home.psx": `view Home():
+    <p>Home</p>
+`,
+	})
+
+	logs, err := runTopple(t, dir, "compile", "-r", "--no-cache", "src", "out")
+	if err != nil {
+		t.Fatalf("compile failed: %v\n%s", err, logs)
+	}
+	for _, name := range []string{"__init__.py", "404-page.py", "home.py"} {
+		if _, err := os.Stat(filepath.Join(dir, "out", name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+	// Only the file that cannot be imported by name is warned about
+	if got := strings.Count(logs, "cannot be imported"); got != 1 || !strings.Contains(logs, "404-page.psx") {
+		t.Errorf("expected one warning naming 404-page.psx, got:\n%s", logs)
+	}
+}
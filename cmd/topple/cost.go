@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// CostCmd defines the "cost" command, which estimates the render cost of
+// each view of a project and reports those exceeding the thresholds, so
+// heavy views can be split before they slow down rendering
+type CostCmd struct {
+	// Positional argument
+	Input string `arg:"" optional:"" help:"Project directory whose views are estimated (default: the root of the project manifest)"`
+
+	// Flags
+	SourceRoot      string   `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	SearchPath      []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+	MaxElements     int      `help:"Elements and compositions a view may write (0 for no limit)" default:"200"`
+	MaxLoopDepth    int      `help:"Nesting of loops around markup a view may have (0 for no limit)" default:"2"`
+	MaxDynamicRatio float64  `help:"Share of nodes computed at render time a view may have, from 0 to 1 (0 for no limit)" default:"0.8"`
+	MaxEstimate     int      `help:"Loop-weighted node count a view may reach, each loop assumed to run 10 times (0 for no limit)" default:"2000"`
+	All             bool     `help:"Report every view, not only those exceeding a threshold"`
+	Check           bool     `help:"Fail if a view exceeds a threshold"`
+	Format          string   `help:"Output format: text, json" default:"text" enum:"text,json"`
+}
+
+// costJSON is the JSON form of a view's cost; paths are relative to the
+// input directory
+type costJSON struct {
+	Name         string   `json:"name"`
+	File         string   `json:"file"`
+	Line         int      `json:"line"`
+	Elements     int      `json:"elements"`
+	Compositions int      `json:"compositions"`
+	LoopDepth    int      `json:"loop_depth"`
+	DynamicNodes int      `json:"dynamic_nodes"`
+	StaticNodes  int      `json:"static_nodes"`
+	DynamicRatio float64  `json:"dynamic_ratio"`
+	Estimate     int      `json:"estimate"`
+	Exceeded     []string `json:"exceeded"`
+}
+
+func (c *CostCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var err error
+	if c.Input, err = projectInput(globals, c.Input); err != nil {
+		return err
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(c.Input)
+	if err != nil {
+		return fmt.Errorf("error checking input path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a directory: the estimate covers a whole project", c.Input)
+	}
+	if c.MaxDynamicRatio < 0 || c.MaxDynamicRatio > 1 {
+		return fmt.Errorf("--max-dynamic-ratio must be between 0 and 1, got %v", c.MaxDynamicRatio)
+	}
+	rootDir, err := fs.AbsolutePath(c.Input)
+	if err != nil {
+		return fmt.Errorf("error resolving input path: %w", err)
+	}
+	files, err := fs.ListPSXFiles(rootDir, globals.Recursive)
+	if err != nil {
+		return fmt.Errorf("error listing PSX files: %w", err)
+	}
+
+	resolveRoot := rootDir
+	if c.SourceRoot != "" {
+		resolveRoot = c.SourceRoot
+	}
+	report, err := compiler.NewMultiFileCompiler(log).Cost(*ctx, compiler.MultiFileOptions{
+		RootDir:     resolveRoot,
+		Files:       files,
+		SearchPaths: searchPaths(c.SearchPath),
+	}, compiler.CostThresholds{
+		MaxElements:     c.MaxElements,
+		MaxLoopDepth:    c.MaxLoopDepth,
+		MaxDynamicRatio: c.MaxDynamicRatio,
+		MaxEstimate:     c.MaxEstimate,
+	})
+	if err != nil {
+		return err
+	}
+
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(rootDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	}
+
+	var views []*compiler.ViewCost
+	exceeding := 0
+	for _, view := range report.Views {
+		if len(view.Exceeded) > 0 {
+			exceeding++
+		}
+		if c.All || len(view.Exceeded) > 0 {
+			views = append(views, view)
+		}
+	}
+
+	switch c.Format {
+	case "json":
+		out := []costJSON{}
+		for _, view := range views {
+			out = append(out, costJSON{
+				Name:         view.Name,
+				File:         relative(view.File),
+				Line:         view.Line,
+				Elements:     view.Elements,
+				Compositions: view.Compositions,
+				LoopDepth:    view.LoopDepth,
+				DynamicNodes: view.DynamicNodes,
+				StaticNodes:  view.StaticNodes,
+				DynamicRatio: view.DynamicRatio(),
+				Estimate:     view.Estimate,
+				Exceeded:     append([]string{}, view.Exceeded...),
+			})
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding estimates: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		for _, view := range views {
+			fmt.Printf("%s (%s:%d): %s, %s, loop depth %d, %.0f%% dynamic, estimate %d\n",
+				view.Name, relative(view.File), view.Line,
+				plural(view.Elements, "element"), plural(view.Compositions, "composition"),
+				view.LoopDepth, view.DynamicRatio()*100, view.Estimate)
+			for _, reason := range view.Exceeded {
+				fmt.Printf("  exceeds: %s\n", reason)
+			}
+		}
+	}
+
+	printCompilationErrors(os.Stderr, report.Errors)
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("estimate is incomplete: %s could not be analyzed", plural(len(report.Errors), "file"))
+	}
+	if c.Check && exceeding > 0 {
+		return fmt.Errorf("%d of %s exceed the render cost thresholds", exceeding, plural(len(report.Views), "view"))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+// crashBundle is the bug report written when the compiler panics. It is
+// redacted: paths are reduced to their base names and the source is only
+// identified by its hash, with the text of string and markup tokens removed.
+type crashBundle struct {
+	Version    string   `json:"version"`
+	GoVersion  string   `json:"go_version"`
+	Platform   string   `json:"platform"`
+	Time       string   `json:"time"`
+	Args       []string `json:"args"`
+	File       string   `json:"file,omitempty"`
+	SourceHash string   `json:"source_sha256,omitempty"`
+	Stage      string   `json:"stage"`
+	Panic      string   `json:"panic"`
+	Location   string   `json:"location,omitempty"`
+	Tokens     []string `json:"tokens,omitempty"`
+	Stack      string   `json:"stack"`
+}
+
+// reportCrash writes a crash bundle for an internal compiler error and tells
+// the user where to find it. Other errors are ignored.
+func reportCrash(err error, log *slog.Logger) {
+	var ierr *compiler.InternalError
+	if !errors.As(err, &ierr) {
+		return
+	}
+
+	path, writeErr := writeCrashBundle(ierr)
+	if writeErr != nil {
+		log.Error("Could not write crash report", slog.String("error", writeErr.Error()))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%v\nA crash report was written to %s\nPlease attach it when reporting this bug.\n", ierr, path)
+}
+
+// writeCrashBundle writes the redacted report of ierr to the temp directory
+// and returns its path
+func writeCrashBundle(ierr *compiler.InternalError) (string, error) {
+	bundle := crashBundle{
+		Version:    Version,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Time:       time.Now().UTC().Format(time.RFC3339),
+		Args:       redactArgs(os.Args[1:]),
+		SourceHash: ierr.SourceHash,
+		Stage:      ierr.Stage,
+		Panic:      fmt.Sprint(ierr.Value),
+		Stack:      string(ierr.Stack),
+	}
+	if ierr.File != "" {
+		bundle.File = filepath.Base(ierr.File)
+	}
+	if ierr.Span.Start.Line > 0 {
+		bundle.Location = ierr.Span.String()
+	}
+	for _, tok := range ierr.Tokens {
+		bundle.Tokens = append(bundle.Tokens, fmt.Sprintf("%s %s %q", tok.Span, tok.Type, tok.Lexeme))
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding crash report: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "topple-crash-*.json")
+	if err != nil {
+		return "", fmt.Errorf("error creating crash report: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return "", fmt.Errorf("error writing crash report: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// redactArgs reduces paths in command-line arguments to their base names
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		name, value, isFlag := strings.Cut(arg, "=")
+		if !isFlag {
+			name, value = "", arg
+		}
+		if strings.ContainsRune(value, filepath.Separator) {
+			value = filepath.Base(value)
+		}
+		if isFlag {
+			redacted[i] = name + "=" + value
+		} else {
+			redacted[i] = value
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+// diagnosticRenderer formats the errors of the compiler as code frames,
+// colored when stderr is a terminal; set by main from --color
+var diagnosticRenderer = diagnostics.Renderer{}
+
+// colorEnabled decides whether output to f is colored: always, never, or
+// auto, which colors terminals unless NO_COLOR is set
+func colorEnabled(mode string, f *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printDiagnostics renders the errors of a file with frames pulled from its
+// source, read from disk when src is nil. Internal compiler errors are left
+// to reportCrash.
+func printDiagnostics(w io.Writer, path string, src []byte, errs ...error) {
+	for _, err := range errs {
+		var ierr *compiler.InternalError
+		if errors.As(err, &ierr) {
+			continue
+		}
+		for _, d := range diagnostics.Collect(err) {
+			printDiagnostic(w, path, src, d)
+		}
+	}
+}
+
+// printCompilationErrors renders the errors of a project build, prefixing
+// errors without a location with the stage that failed
+func printCompilationErrors(w io.Writer, errs []*compiler.CompilationError) {
+	sources := make(map[string][]byte)
+	for _, compErr := range errs {
+		if compErr.Details == nil {
+			printDiagnostic(w, compErr.File, nil, &diagnostics.Diagnostic{Severity: diagnostics.Error, Message: compErr.Message})
+			continue
+		}
+		var ierr *compiler.InternalError
+		if errors.As(compErr.Details, &ierr) {
+			continue
+		}
+		for _, d := range diagnostics.Collect(compErr.Details) {
+			if d.Code == "" && d.Span.IsZero() {
+				d.Message = compErr.Message + ": " + d.Message
+			}
+			file := d.File
+			if file == "" {
+				file = compErr.File
+			}
+			src, ok := sources[file]
+			if !ok {
+				src, _ = os.ReadFile(file)
+				sources[file] = src
+			}
+			printDiagnostic(w, file, src, d)
+		}
+	}
+}
+
+// printDiagnostic renders d, located in path unless it names its own file
+func printDiagnostic(w io.Writer, path string, src []byte, d *diagnostics.Diagnostic) {
+	if d.File == "" {
+		d.File = path
+	}
+	if src == nil && !d.Span.IsZero() {
+		src, _ = os.ReadFile(d.File)
+	}
+	d.File = displayPath(d.File)
+	fmt.Fprintln(w, diagnosticRenderer.Render(d, src))
+}
+
+// displayPath shortens path to be relative to the working directory when it
+// is inside it
+func displayPath(path string) string {
+	if path == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// ExposeCmd defines the "expose" command, which generates the __init__.py of
+// a package re-exporting the views of its modules
+type ExposeCmd struct {
+	// Positional argument
+	Package string `arg:"" required:"" help:"Package directory whose modules' views are re-exported"`
+
+	// Flags
+	Output     string   `help:"Directory to write __init__.py to, usually where the package is compiled (default: the package directory), or - for stdout" short:"o" default:""`
+	Check      bool     `help:"Fail if __init__.py is missing or out of date, without writing"`
+	SourceRoot string   `help:"Project root for resolving absolute imports (default: parent of the package directory)" short:"s" default:""`
+	SearchPath []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+}
+
+func (e *ExposeCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(e.Package)
+	if err != nil {
+		return fmt.Errorf("error checking package path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a package directory", e.Package)
+	}
+	pkgDir, err := fs.AbsolutePath(e.Package)
+	if err != nil {
+		return fmt.Errorf("error resolving package path: %w", err)
+	}
+
+	resolveRoot := filepath.Dir(pkgDir)
+	if e.SourceRoot != "" {
+		resolveRoot = e.SourceRoot
+	}
+	exports, err := compiler.NewMultiFileCompiler(log).Expose(*ctx, pkgDir, compiler.MultiFileOptions{
+		RootDir:     resolveRoot,
+		SearchPaths: searchPaths(e.SearchPath),
+	})
+	if err != nil {
+		if exports != nil {
+			printCompilationErrors(os.Stderr, exports.Errors)
+		}
+		return err
+	}
+
+	if e.Output == "-" {
+		_, err = os.Stdout.Write(exports.Code)
+		return err
+	}
+	outputDir := pkgDir
+	if e.Output != "" {
+		outputDir = e.Output
+	}
+	target := filepath.Join(outputDir, "__init__.py")
+
+	if e.Check {
+		current, err := os.ReadFile(target)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error reading %s: %w", target, err)
+		}
+		if !bytes.Equal(current, exports.Code) {
+			return fmt.Errorf("%s is out of date: run topple expose %s", target, e.Package)
+		}
+		return nil
+	}
+
+	if err := fs.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory %s: %w", outputDir, err)
+	}
+	if err := fs.WriteFile(target, exports.Code, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", target, err)
+	}
+	log.InfoContext(*ctx, "Exposed package views", slog.String("output", target), slog.Int("modules", len(exports.Modules)))
+	return nil
+}
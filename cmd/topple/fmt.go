@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/fjvillamarin/topple/compiler/format"
+	"github.com/fjvillamarin/topple/internal/diff"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// FmtCmd defines the "fmt" command, which rewrites PSX files in the
+// canonical layout. With --check or --diff it only reports what would
+// change; --check also fails when anything would, so it can gate CI.
+type FmtCmd struct {
+	// Positional arguments
+	Paths []string `arg:"" optional:"" help:"PSX files or directories to format (default: the root of the project manifest)"`
+
+	// Flags
+	Check bool `help:"List the files that are not formatted and fail if there are any, without writing"`
+	Diff  bool `help:"Print the changes formatting would make as a unified diff, without writing"`
+	Width int  `help:"Line width to wrap long lines at" default:"88"`
+}
+
+func (f *FmtCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	if len(f.Paths) == 0 {
+		root, err := projectInput(globals, "")
+		if err != nil {
+			return err
+		}
+		f.Paths = []string{root}
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	var files []string
+	for _, path := range f.Paths {
+		isDir, err := fs.IsDir(path)
+		if err != nil {
+			return fmt.Errorf("error checking input path: %w", err)
+		}
+		if !isDir {
+			files = append(files, path)
+			continue
+		}
+		sources, err := fs.ListPSXFiles(path, globals.Recursive)
+		if err != nil {
+			return fmt.Errorf("error listing PSX files: %w", err)
+		}
+		files = append(files, sources...)
+	}
+
+	unformatted, failed := 0, 0
+	for _, path := range files {
+		src, err := fs.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading file %s: %w", path, err)
+		}
+
+		formatted, errs := format.Source(src, format.Options{Width: f.Width})
+		if len(errs) > 0 {
+			failed++
+			printDiagnostics(os.Stderr, path, src, errs...)
+			continue
+		}
+		if bytes.Equal(formatted, src) {
+			continue
+		}
+		unformatted++
+
+		switch {
+		case f.Diff:
+			fmt.Print(diff.Unified(path, path, src, formatted))
+		case f.Check:
+			fmt.Println(path)
+		default:
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("error reading file %s: %w", path, err)
+			}
+			if err := fs.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("error writing file %s: %w", path, err)
+			}
+			log.DebugContext(*ctx, "Formatted file", slog.String("file", path))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be parsed", failed, len(files))
+	}
+	if unformatted > 0 && f.Check {
+		return fmt.Errorf("%d of %d files are not formatted", unformatted, len(files))
+	}
+	return nil
+}
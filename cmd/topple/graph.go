@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/depgraph"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// GraphCmd defines the "graph" command, which exports the dependency graph
+// of a project for architecture reviews and CI checks
+type GraphCmd struct {
+	// Positional argument
+	Input string `arg:"" optional:"" help:"Project directory to graph (default: the root of the project manifest)"`
+
+	// Flags
+	Output           string   `help:"File to write, or - for stdout" short:"o" default:"-"`
+	Format           string   `help:"Output format: dot, json, mermaid" default:"dot" enum:"dot,json,mermaid"`
+	CollapsePackages bool     `help:"Show one node per package directory instead of one per file" name:"collapse-packages"`
+	HighlightCycles  bool     `help:"Mark the files and imports that form import cycles" name:"highlight-cycles"`
+	Entry            string   `help:"Only show this file and the files it imports, directly or transitively"`
+	SourceRoot       string   `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	SearchPath       []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+}
+
+func (g *GraphCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var err error
+	if g.Input, err = projectInput(globals, g.Input); err != nil {
+		return err
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(g.Input)
+	if err != nil {
+		return fmt.Errorf("error checking input path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a directory: the graph covers a whole project", g.Input)
+	}
+	rootDir, err := fs.AbsolutePath(g.Input)
+	if err != nil {
+		return fmt.Errorf("error resolving input path: %w", err)
+	}
+	files, err := fs.ListPSXFiles(rootDir, globals.Recursive)
+	if err != nil {
+		return fmt.Errorf("error listing PSX files: %w", err)
+	}
+
+	resolveRoot := rootDir
+	if g.SourceRoot != "" {
+		resolveRoot = g.SourceRoot
+	}
+	project, err := compiler.NewMultiFileCompiler(log).Graph(*ctx, compiler.MultiFileOptions{
+		RootDir:     resolveRoot,
+		Files:       files,
+		SearchPaths: searchPaths(g.SearchPath),
+	})
+	if err != nil {
+		return err
+	}
+
+	opts := depgraph.ExportOptions{
+		Root:             rootDir,
+		CollapsePackages: g.CollapsePackages,
+		HighlightCycles:  g.HighlightCycles,
+	}
+	if g.Entry != "" {
+		if opts.Entry, err = fs.AbsolutePath(g.Entry); err != nil {
+			return fmt.Errorf("error resolving entry path: %w", err)
+		}
+		if !project.Graph.HasFile(opts.Entry) {
+			return fmt.Errorf("entry %s is not a PSX file of the project", g.Entry)
+		}
+	}
+	export, err := project.Graph.Export(opts)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	switch g.Format {
+	case "json":
+		err = export.WriteJSON(&buf)
+	case "mermaid":
+		err = export.WriteMermaid(&buf)
+	default:
+		err = export.WriteDOT(&buf)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing graph: %w", err)
+	}
+	if g.Output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(g.Output, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing graph: %w", err)
+	}
+
+	printCompilationErrors(os.Stderr, project.Errors)
+	if len(project.Errors) > 0 {
+		return fmt.Errorf("graph is incomplete: %s could not be analyzed", plural(len(project.Errors), "file"))
+	}
+	return nil
+}
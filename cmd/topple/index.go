@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// IndexCmd defines the "index" command, which exports the definitions of a
+// project and the references to them for editors and code hosts that do not
+// speak the language server protocol
+type IndexCmd struct {
+	// Positional argument
+	Input string `arg:"" optional:"" help:"Project directory to index (default: the root of the project manifest)"`
+
+	// Flags
+	Output     string   `help:"File to write, or - for stdout (default: tags for ctags, dump.lsif for lsif, in the project directory)" short:"o" default:""`
+	Format     string   `help:"Output format: ctags, lsif" default:"ctags" enum:"ctags,lsif"`
+	SourceRoot string   `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	SearchPath []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+}
+
+func (x *IndexCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var err error
+	if x.Input, err = projectInput(globals, x.Input); err != nil {
+		return err
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(x.Input)
+	if err != nil {
+		return fmt.Errorf("error checking input path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a directory: the index covers a whole project", x.Input)
+	}
+	rootDir, err := fs.AbsolutePath(x.Input)
+	if err != nil {
+		return fmt.Errorf("error resolving input path: %w", err)
+	}
+	files, err := fs.ListPSXFiles(rootDir, globals.Recursive)
+	if err != nil {
+		return fmt.Errorf("error listing PSX files: %w", err)
+	}
+
+	resolveRoot := rootDir
+	if x.SourceRoot != "" {
+		resolveRoot = x.SourceRoot
+	}
+	index, err := compiler.NewMultiFileCompiler(log).Index(*ctx, compiler.MultiFileOptions{
+		RootDir:     resolveRoot,
+		Files:       files,
+		SearchPaths: searchPaths(x.SearchPath),
+	})
+	if err != nil {
+		return err
+	}
+
+	output := x.Output
+	if output == "" {
+		output = filepath.Join(rootDir, map[string]string{"ctags": "tags", "lsif": "dump.lsif"}[x.Format])
+	}
+	baseDir := rootDir
+	if output != "-" {
+		if baseDir, err = filepath.Abs(filepath.Dir(output)); err != nil {
+			return fmt.Errorf("error resolving output path: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch x.Format {
+	case "lsif":
+		err = index.WriteLSIF(&buf, rootDir, compilerVersion())
+	default:
+		err = index.WriteCtags(&buf, baseDir)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing index: %w", err)
+	}
+	if output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(output, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing index: %w", err)
+	}
+	if output != "-" {
+		log.InfoContext(*ctx, "Wrote index", slog.String("path", output), slog.Int("definitions", len(index.Definitions)), slog.Int("references", len(index.References)))
+	}
+
+	printCompilationErrors(os.Stderr, index.Errors)
+	if len(index.Errors) > 0 {
+		return fmt.Errorf("index is incomplete: %s could not be analyzed", plural(len(index.Errors), "file"))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+	"github.com/fjvillamarin/topple/internal/project"
+)
+
+// InitCmd defines the "init" command, which scaffolds the project manifest
+type InitCmd struct {
+	// Positional argument
+	Dir string `arg:"" optional:"" help:"Project directory to create topple.toml in (default: the working directory)" default:"."`
+
+	// Flags
+	Root  string `help:"Source root to declare, relative to the project directory (default: src when it exists)" default:""`
+	Out   string `help:"Output directory to declare, relative to the project directory (default: none, outputs are written next to the sources)" default:""`
+	Force bool   `help:"Overwrite an existing topple.toml"`
+}
+
+func (i *InitCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	fs := filesystem.NewFileSystem(log)
+
+	path := filepath.Join(i.Dir, project.FileName)
+	exists, err := fs.Exists(path)
+	if err != nil {
+		return err
+	}
+	if exists && !i.Force {
+		return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+	}
+
+	root := i.Root
+	if root == "" {
+		if isDir, err := fs.IsDir(filepath.Join(i.Dir, "src")); err == nil && isDir {
+			root = "src"
+		}
+	}
+	data := project.Scaffold(root, i.Out)
+	// The scaffold must load, or every command run in the project would fail
+	if _, err := project.Parse(path, data); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := fs.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Created %s\n", path)
+	return nil
+}
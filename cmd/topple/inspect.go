@@ -77,7 +77,7 @@ func (c *InspectCmd) inspectSummary(content []byte, filename string) error {
 	var module *ast.Module
 	var parseErrs []error
 	if len(scanErrs) == 0 {
-		module, parseErrs = compiler.ParseTokens(tokens)
+		module, parseErrs = compiler.ParseTokensWithSyntax(tokens, scanner.Syntax())
 	}
 
 	// Count AST nodes
@@ -90,7 +90,7 @@ func (c *InspectCmd) inspectSummary(content []byte, filename string) error {
 
 	// Resolve
 	var table *resolver.ResolutionTable
-	if module != nil {
+	if module != nil && len(parseErrs) == 0 {
 		res := resolver.NewResolver()
 		table, _ = res.Resolve(module)
 	}
@@ -289,7 +289,7 @@ func (c *InspectCmd) inspectAST(content []byte, filename string) error {
 // since the partial data is useful for debugging scope/binding issues.
 func (c *InspectCmd) inspectResolution(content []byte, filename string) error {
 	module, errors := compiler.Parse(content)
-	if module == nil {
+	if len(errors) > 0 {
 		return formatParseErrors(errors)
 	}
 
@@ -320,7 +320,7 @@ func (c *InspectCmd) inspectResolution(content []byte, filename string) error {
 // since the partial data is useful for debugging scope/binding issues.
 func (c *InspectCmd) inspectAnnotated(content []byte, filename string) error {
 	module, errors := compiler.Parse(content)
-	if module == nil {
+	if len(errors) > 0 {
 		return formatParseErrors(errors)
 	}
 
@@ -349,7 +349,7 @@ func (c *InspectCmd) inspectAnnotated(content []byte, filename string) error {
 // inspectTransform shows the AST after view transformation.
 func (c *InspectCmd) inspectTransform(content []byte, filename string) error {
 	module, errors := compiler.Parse(content)
-	if module == nil {
+	if len(errors) > 0 {
 		return formatParseErrors(errors)
 	}
 
@@ -387,7 +387,7 @@ func (c *InspectCmd) inspectTransform(content []byte, filename string) error {
 // inspectCodegen shows the generated Python code.
 func (c *InspectCmd) inspectCodegen(content []byte, filename string) error {
 	module, errors := compiler.Parse(content)
-	if module == nil {
+	if len(errors) > 0 {
 		return formatParseErrors(errors)
 	}
 
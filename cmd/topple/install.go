@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// InstallCmd defines the "install" command, which unpacks archives made by
+// "topple pack" into the topple_modules directory of a project
+type InstallCmd struct {
+	// Positional argument
+	Archives []string `arg:"" required:"" help:"Package archives to install: .tgz files or http(s) URLs"`
+
+	// Flags
+	Root string `help:"Project root whose topple_modules the packages are installed into (default: the source root of the project manifest, or the working directory)" default:""`
+}
+
+func (i *InstallCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	fs := filesystem.NewFileSystem(log)
+
+	root := i.Root
+	if root == "" {
+		root = "."
+		if globals.Project != nil {
+			root = globals.Project.Root
+		}
+	}
+	vendorDir := filepath.Join(root, module.VendorDirName)
+
+	for _, source := range i.Archives {
+		archive, err := readArchive(*ctx, source)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", source, err)
+		}
+		pkgDir, err := archive.Install(fs, vendorDir)
+		if err != nil {
+			return fmt.Errorf("error installing %s: %w", source, err)
+		}
+		log.InfoContext(*ctx, "Installed package",
+			slog.String("package", archive.Manifest.Name),
+			slog.String("version", archive.Manifest.Version),
+			slog.String("path", pkgDir))
+	}
+
+	// The lockfile records what is installed now, so that builds verify it
+	index, err := module.LoadVendorIndex(fs, vendorDir)
+	if err != nil {
+		return fmt.Errorf("error loading vendored packages: %w", err)
+	}
+	for _, err := range index.Errors {
+		log.WarnContext(*ctx, "Vendored package problem", slog.String("error", err.Error()))
+	}
+	lock, err := module.GenerateLockfile(fs, index)
+	if err != nil {
+		return fmt.Errorf("error generating lockfile: %w", err)
+	}
+	lockPath := filepath.Join(root, module.LockfileName)
+	if err := module.WriteLockfile(fs, lockPath, lock); err != nil {
+		return fmt.Errorf("error writing lockfile %s: %w", lockPath, err)
+	}
+	log.InfoContext(*ctx, "Wrote lockfile", slog.String("path", lockPath), slog.Int("packages", len(lock.Packages)))
+	return nil
+}
+
+// readArchive reads a package archive from a file or an http(s) URL
+func readArchive(ctx context.Context, source string) (*module.Archive, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return module.ReadArchive(f)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", resp.Status)
+	}
+	return module.ReadArchive(resp.Body)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/transformers"
+	"github.com/fjvillamarin/topple/internal/lsp"
+)
+
+// LspCmd defines the "lsp" command. It runs a language server over stdin and
+// stdout; logs go to stderr. Only options that change how sources are
+// scanned apply, since the server does not generate code.
+type LspCmd struct {
+	HTMLComments string `help:"What to do with <!-- --> comments in views (strip, render, python)" name:"html-comments" enum:"strip,render,python" default:"strip"`
+	Markdown     bool   `help:"Render <Markdown> blocks to static HTML at compile time" name:"markdown"`
+}
+
+func (l *LspCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	commentMode, err := transformers.ParseHTMLCommentMode(l.HTMLComments)
+	if err != nil {
+		return err
+	}
+	options := compiler.Options{HTMLComments: commentMode, Markdown: l.Markdown}
+	return lsp.NewServer(log, options, Version).Run(*ctx, os.Stdin, os.Stdout)
+}
@@ -3,12 +3,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"runtime"
+	"runtime/debug"
+	"strings"
+	"syscall"
 
 	"github.com/alecthomas/kong"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/project"
 )
 
 var Version = "dev" // This will be set by the build system
@@ -27,6 +35,20 @@ type Globals struct {
 	Version   VersionFlag `name:"version" help:"Print version information and quit"`
 	Recursive bool        `help:"Process directories recursively" short:"r"`
 	TSLib     string      `help:"Path to the Tree-sitter library binary" short:"t" default:"./tree-sitter-topple/topple.dylib"`
+	Color     string      `help:"Color diagnostics: auto (when stderr is a terminal), always, never" enum:"auto,always,never" default:"auto"`
+
+	// Project is the manifest found from the working directory, nil when
+	// there is none
+	Project *project.Manifest `kong:"-"`
+
+	// omitted holds the flags the command line leaves out, which the
+	// manifest and its builds may set
+	omitted map[string]bool `kong:"-"`
+}
+
+// flagGiven reports whether a flag was given on the command line
+func (g *Globals) flagGiven(name string) bool {
+	return !g.omitted[name]
 }
 
 // CLI holds the root command structure including global flags
@@ -34,11 +56,23 @@ type CLI struct {
 	Globals
 
 	// Commands
-	Compile CompileCmd `cmd:"" help:"Compile PSX files to Python"`
-	Watch   WatchCmd   `cmd:"" help:"Watch for changes and compile on the fly"`
-	Scan    ScanCmd    `cmd:"" help:"Run the scanner and show/output tokens"`
-	Parse   ParseCmd   `cmd:"" help:"Parse source files and show/output AST"`
-	Inspect InspectCmd `cmd:"" help:"Inspect compilation stages for a PSX file"`
+	Compile  CompileCmd  `cmd:"" help:"Compile PSX files to Python"`
+	Watch    WatchCmd    `cmd:"" help:"Watch for changes and compile on the fly"`
+	Scan     ScanCmd     `cmd:"" help:"Run the scanner and show/output tokens"`
+	Parse    ParseCmd    `cmd:"" help:"Parse source files and show/output AST"`
+	Inspect  InspectCmd  `cmd:"" help:"Inspect compilation stages for a PSX file"`
+	Verify   VerifyCmd   `cmd:"" help:"Check that recompiling reproduces an existing build byte for byte"`
+	Lsp      LspCmd      `cmd:"" help:"Run the language server over stdio for editor integration"`
+	TraceMap TraceMapCmd `cmd:"" name:"trace-map" help:"Rewrite a Python traceback to point at .psx sources"`
+	Fmt      FmtCmd      `cmd:"" help:"Reformat PSX files in the canonical layout"`
+	Usage    UsageCmd    `cmd:"" help:"Report where each view is instantiated and with which attributes"`
+	Index    IndexCmd    `cmd:"" help:"Export definitions and references as ctags or LSIF for editor navigation"`
+	Graph    GraphCmd    `cmd:"" help:"Export the import graph of a project as DOT, JSON or Mermaid"`
+	Expose   ExposeCmd   `cmd:"" help:"Generate a package __init__.py re-exporting the views of its modules"`
+	Cost     CostCmd     `cmd:"" help:"Estimate the render cost of each view and report those exceeding thresholds"`
+	Pack     PackCmd     `cmd:"" help:"Compile a component library into an archive for other projects"`
+	Install  InstallCmd  `cmd:"" help:"Unpack component library archives into topple_modules"`
+	Init     InitCmd     `cmd:"" help:"Create a topple.toml project manifest"`
 }
 
 func main() {
@@ -51,16 +85,29 @@ func main() {
 		os.Args = append(os.Args, "--help")
 	}
 
+	// The project manifest supplies the flags the command line leaves out
+	manifest, err := findManifest()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "topple: error: %v\n", err)
+		os.Exit(1)
+	}
+	cli.Globals.Project = manifest
+	cli.Globals.omitted = make(map[string]bool)
+
 	// Parse the command line arguments
 	kCtx := kong.Parse(&cli,
 		kong.Name("topple"),
 		kong.Description("Topple Compiler CLI - compile PSX (Python Syntax eXtension) views into Python code"),
 		kong.UsageOnError(),
 		kong.Vars{
-			"version": "v0.1.0",
+			"version":        "v0.1.0",
+			"default_header": compiler.DefaultHeader,
 		},
+		kong.Resolvers(manifestResolver(manifest, cli.Globals.omitted)),
 	)
 
+	diagnosticRenderer.Color = colorEnabled(cli.Globals.Color, os.Stderr)
+
 	// -------------------------------------------------------------------------
 	// Logger
 	level := slog.LevelInfo
@@ -69,8 +116,25 @@ func main() {
 		level = slog.LevelDebug
 	}
 
+	// The language server speaks its protocol on stdout, and trace-map,
+	// fmt and usage print their results there, as do scan and parse when
+	// their output is for other tools
+	logOutput := os.Stdout
+	switch strings.Fields(kCtx.Command())[0] {
+	case "lsp", "trace-map", "fmt", "usage":
+		logOutput = os.Stderr
+	case "scan":
+		if cli.Scan.Format != "text" {
+			logOutput = os.Stderr
+		}
+	case "parse":
+		if cli.Parse.ASTFormat == "json" {
+			logOutput = os.Stderr
+		}
+	}
+
 	log := slog.New(
-		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		slog.NewTextHandler(logOutput, &slog.HandlerOptions{
 			Level: level,
 		}),
 	)
@@ -78,17 +142,63 @@ func main() {
 	// -------------------------------------------------------------------------
 	// Context
 
-	ctx := context.Background()
+	// SIGINT and SIGTERM cancel the context: commands stop, letting work in
+	// flight finish or abort and flushing what they write. A second signal
+	// kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
 	// -------------------------------------------------------------------------
 	// GOMAXPROCS
 
 	log.DebugContext(ctx, "startup", slog.Int("GOMAXPROCS", runtime.GOMAXPROCS(0)))
+	if manifest != nil {
+		log.DebugContext(ctx, "project manifest", slog.String("path", manifest.Path))
+	}
 
 	// -------------------------------------------------------------------------
 	// Run
 
+	// Panics outside the compiler pipeline still leave a crash report behind
+	defer func() {
+		if r := recover(); r != nil {
+			reportCrash(&compiler.InternalError{Stage: kCtx.Command(), Value: r, Stack: debug.Stack()}, log)
+			panic(r)
+		}
+	}()
+
 	if err := kCtx.Run(&cli.Globals, &ctx, log); err != nil {
+		if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "topple: interrupted")
+			stop()
+			os.Exit(130)
+		}
 		kCtx.FatalIfErrorf(err)
 	}
 }
+
+// findManifest looks for the project manifest from the working directory
+func findManifest() (*project.Manifest, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return project.Find(cwd)
+}
+
+// manifestResolver fills in the flags the command line leaves out from the
+// project manifest, if any, recording them in omitted
+func manifestResolver(manifest *project.Manifest, omitted map[string]bool) kong.Resolver {
+	return kong.ResolverFunc(func(_ *kong.Context, _ *kong.Path, flag *kong.Flag) (any, error) {
+		// Kong only resolves the flags the command line leaves out
+		omitted[flag.Name] = true
+		if value, ok := manifest.Flag(flag.Name); ok {
+			return value, nil
+		}
+		return nil, nil
+	})
+}
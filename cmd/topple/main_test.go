@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+// writeFiles writes files relative to dir, creating their directories
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runTopple parses args as the command line and runs the command from dir,
+// without a project manifest. It returns the log and the command's error.
+func runTopple(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cli := CLI{}
+	cli.Globals.omitted = make(map[string]bool)
+	parser, err := kong.New(&cli,
+		kong.Name("topple"),
+		kong.Vars{
+			"version":        "v0.1.0",
+			"default_header": compiler.DefaultHeader,
+		},
+		kong.Resolvers(manifestResolver(nil, cli.Globals.omitted)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kCtx, err := parser.Parse(args)
+	if err != nil {
+		t.Fatalf("parsing %v: %v", args, err)
+	}
+
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	ctx := context.Background()
+	err = kCtx.Run(&cli.Globals, &ctx, log)
+	return logs.String(), err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// PackCmd defines the "pack" command, which compiles a component library
+// into an archive that other projects install with "topple install"
+type PackCmd struct {
+	// Positional argument
+	Package string `arg:"" required:"" help:"Package directory holding a topple-package.json"`
+
+	// Flags
+	Output     string   `help:"Directory to write the <name>-<version>.tgz archive to (default: the working directory), or - for stdout" short:"o" default:""`
+	SourceRoot string   `help:"Project root for resolving absolute imports (default: parent of the package directory)" short:"s" default:""`
+	SearchPath []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+}
+
+func (p *PackCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(p.Package)
+	if err != nil {
+		return fmt.Errorf("error checking package path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a package directory", p.Package)
+	}
+
+	packed, err := compiler.NewMultiFileCompiler(log).Pack(*ctx, p.Package, compiler.MultiFileOptions{
+		RootDir:     p.SourceRoot,
+		SearchPaths: searchPaths(p.SearchPath),
+	})
+	if err != nil {
+		if packed != nil {
+			printCompilationErrors(os.Stderr, packed.Errors)
+		}
+		return err
+	}
+
+	var archive bytes.Buffer
+	if err := module.WriteArchive(&archive, packed.Manifest.Name, packed.Files); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if p.Output == "-" {
+		_, err = os.Stdout.Write(archive.Bytes())
+		return err
+	}
+
+	outputDir := p.Output
+	if outputDir == "" {
+		outputDir = "."
+	}
+	if err := fs.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory %s: %w", outputDir, err)
+	}
+	target := filepath.Join(outputDir, module.ArchiveName(packed.Manifest))
+	if err := fs.WriteFile(target, archive.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", target, err)
+	}
+	log.InfoContext(*ctx, "Packed package",
+		slog.String("package", packed.Manifest.Name),
+		slog.String("version", packed.Manifest.Version),
+		slog.String("output", target),
+		slog.Int("modules", len(packed.Symbols.Modules)))
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"path/filepath"
@@ -9,6 +10,10 @@ import (
 	"time"
 
 	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/astjson"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
 	"github.com/fjvillamarin/topple/compiler/resolver"
 	"github.com/fjvillamarin/topple/internal/filesystem"
 )
@@ -21,8 +26,9 @@ type ParseCmd struct {
 	Output string `arg:"" optional:"" help:"Output directory for output files (default: same as input)"`
 
 	// Whether to write output files
-	WriteAST bool   `help:"Write AST to .ast files" short:"w" default:"false"`
-	Format   string `help:"Resolution output format: text, json, all, annotated, none" default:"none" enum:"text,json,all,annotated,none"`
+	WriteAST  bool   `help:"Write AST to .ast files" short:"w" default:"false"`
+	Format    string `help:"Resolution output format: text, json, all, annotated, none" default:"none" enum:"text,json,all,annotated,none"`
+	ASTFormat string `help:"AST output format: text, json (a versioned encoding for other tools)" default:"text" enum:"text,json"`
 }
 
 // Run executes the parse command.
@@ -57,13 +63,13 @@ func (p *ParseCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 
 		log.InfoContext(*ctx, "Parsing files in directory", slog.Int("fileCount", len(sources)))
 		for _, file := range sources {
-			if err := parseFile(fs, file, p.Output, p.WriteAST, p.Format, log, *ctx); err != nil {
+			if err := parseFile(fs, file, p.Output, p.WriteAST, p.Format, p.ASTFormat, log, *ctx); err != nil {
 				return err
 			}
 		}
 	} else {
 		// Single file
-		if err := parseFile(fs, p.Input, p.Output, p.WriteAST, p.Format, log, *ctx); err != nil {
+		if err := parseFile(fs, p.Input, p.Output, p.WriteAST, p.Format, p.ASTFormat, log, *ctx); err != nil {
 			return err
 		}
 	}
@@ -74,7 +80,7 @@ func (p *ParseCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 
 // parseFile runs the parser on a single file, prints AST to console,
 // and optionally writes AST to a .ast file and resolution outputs
-func parseFile(fs filesystem.FileSystem, path, outputDir string, writeAST bool, format string, log *slog.Logger, ctx context.Context) error {
+func parseFile(fs filesystem.FileSystem, path, outputDir string, writeAST bool, format, astFormat string, log *slog.Logger, ctx context.Context) error {
 	log.DebugContext(ctx, "Parsing file", slog.String("file", path))
 
 	content, err := fs.ReadFile(path)
@@ -97,33 +103,31 @@ func parseFile(fs filesystem.FileSystem, path, outputDir string, writeAST bool,
 
 	// Format AST into a string
 	filename := filepath.Base(path)
-	var output strings.Builder
-
-	output.WriteString(fmt.Sprintf("=== %s ===\n\n", filename))
-
-	if program != nil {
-		printer := compiler.NewASTPrinter("  ")
-		output.WriteString(printer.Print(program))
-		output.WriteString("\n")
-	}
-
-	if len(errors) > 0 {
-		output.WriteString(fmt.Sprintf("\n-- Errors (%d) --\n", len(errors)))
-		for i, e := range errors {
-			output.WriteString(fmt.Sprintf("%d: %v\n", i+1, e))
+	var output string
+	if astFormat == "json" {
+		output, err = formatASTJSON(path, program, errors)
+		if err != nil {
+			return fmt.Errorf("error encoding the AST of %s: %w", path, err)
 		}
+	} else {
+		output = formatASTText(filename, content, program, errors)
 	}
 
 	if !writeAST {
 		// Print to console if not writing to file
-		fmt.Println()
-		fmt.Print(output.String())
+		if astFormat == "text" {
+			fmt.Println()
+		}
+		fmt.Print(output)
 	}
 
 	// Write to file if requested
 	if writeAST {
 		outputPath := getASTOutputPath(fs, path, outputDir)
-		if err := fs.WriteFile(outputPath, []byte(output.String()), 0644); err != nil {
+		if astFormat == "json" {
+			outputPath += ".json"
+		}
+		if err := fs.WriteFile(outputPath, []byte(output), 0644); err != nil {
 			return fmt.Errorf("error writing AST file: %w", err)
 		}
 		log.InfoContext(ctx, "Wrote AST file",
@@ -141,6 +145,74 @@ func parseFile(fs filesystem.FileSystem, path, outputDir string, writeAST bool,
 	return nil
 }
 
+// formatASTText prints the AST, then the errors with code frames
+func formatASTText(filename string, content []byte, program *ast.Module, errors []error) string {
+	var output strings.Builder
+
+	output.WriteString(fmt.Sprintf("=== %s ===\n\n", filename))
+
+	if program != nil {
+		printer := compiler.NewASTPrinter("  ")
+		output.WriteString(printer.Print(program))
+		output.WriteString("\n")
+	}
+
+	if len(errors) > 0 {
+		output.WriteString(fmt.Sprintf("\n-- Errors (%d) --\n", len(errors)))
+		for i, e := range errors {
+			switch err := e.(type) {
+			case *parser.ParseError:
+				output.WriteString(fmt.Sprintf("%d: %s\n", i+1, err.CodeFrame(content, filename)))
+			case *lexer.ScannerError:
+				output.WriteString(fmt.Sprintf("%d: %s\n", i+1, err.CodeFrame(content, filename)))
+			default:
+				output.WriteString(fmt.Sprintf("%d: %v\n", i+1, e))
+			}
+		}
+	}
+	return output.String()
+}
+
+// jsonParseResult is the output of parse --ast-format json
+type jsonParseResult struct {
+	File   string           `json:"file"`
+	AST    json.RawMessage  `json:"ast"`
+	Errors []jsonDiagnostic `json:"errors"`
+}
+
+// formatASTJSON encodes the AST with astjson, alongside the errors
+func formatASTJSON(path string, program *ast.Module, errors []error) (string, error) {
+	result := jsonParseResult{File: path, AST: json.RawMessage("null"), Errors: []jsonDiagnostic{}}
+	if program != nil {
+		data, err := astjson.Marshal(program)
+		if err != nil {
+			return "", err
+		}
+		result.AST = data
+	}
+	for _, e := range errors {
+		diag := jsonDiagnostic{File: path, Error: e.Error()}
+		switch err := e.(type) {
+		case *parser.ParseError:
+			diag.Error = err.Message
+			diag.Code = string(err.Code)
+			diag.Hint = err.Hint
+			diag.Span = toJSONSpan(err.Token.Span)
+		case *lexer.ScannerError:
+			diag.Error = err.Message
+			diag.Code = string(err.Code)
+			diag.Hint = err.Hint
+			diag.Span = toJSONSpan(err.Range)
+		}
+		result.Errors = append(result.Errors, diag)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
 // getASTOutputPath determines the output path for an AST file
 func getASTOutputPath(fs filesystem.FileSystem, inputPath, outputDir string) string {
 	var outputPath string
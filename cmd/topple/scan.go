@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"path/filepath"
@@ -21,6 +22,9 @@ type ScanCmd struct {
 
 	// Whether to write output files
 	WriteTokens bool `help:"Write tokens to .tok files" short:"w" default:"false"`
+
+	Format         string `help:"Token output format: text, json (one token per line), py-tokenize (like python -m tokenize)" default:"text" enum:"text,json,py-tokenize"`
+	CheckRoundtrip bool   `help:"Fail unless the token lexemes, with the whitespace between them, reproduce the source" default:"false"`
 }
 
 // Run executes the scan command.
@@ -86,13 +90,13 @@ func (s *ScanCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 
 		log.InfoContext(*ctx, "Scanning files in directory", slog.Int("fileCount", len(sources)))
 		for _, file := range sources {
-			if err := scanFile(fs, file, s.Output, s.WriteTokens, log, *ctx); err != nil {
+			if err := scanFile(fs, file, s, log, *ctx); err != nil {
 				return err
 			}
 		}
 	} else {
 		// Single file
-		if err := scanFile(fs, s.Input, s.Output, s.WriteTokens, log, *ctx); err != nil {
+		if err := scanFile(fs, s.Input, s, log, *ctx); err != nil {
 			return err
 		}
 	}
@@ -103,7 +107,7 @@ func (s *ScanCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 
 // scanFile runs the scanner on a single file, prints tokens to console,
 // and optionally writes tokens to a .tok file
-func scanFile(fs filesystem.FileSystem, path, outputDir string, writeTokens bool, log *slog.Logger, ctx context.Context) error {
+func scanFile(fs filesystem.FileSystem, path string, cmd *ScanCmd, log *slog.Logger, ctx context.Context) error {
 	log.DebugContext(ctx, "Scanning file", slog.String("file", path))
 
 	content, err := fs.ReadFile(path)
@@ -111,11 +115,61 @@ func scanFile(fs filesystem.FileSystem, path, outputDir string, writeTokens bool
 		return fmt.Errorf("error reading file %s: %w", path, err)
 	}
 
-	scanner := lexer.NewScanner(content)
+	// Markup comments are dropped unless preserved, and the roundtrip
+	// needs their text
+	cfg := lexer.DefaultScannerConfig()
+	cfg.PreserveHTMLComments = cmd.CheckRoundtrip
+	scanner := lexer.NewScannerWithConfig(content, cfg)
 	tokens := scanner.ScanTokens()
 
-	// Format tokens into a string
 	filename := filepath.Base(path)
+	var output string
+	switch cmd.Format {
+	case "json":
+		output, err = formatTokensJSON(path, tokens, scanner.Errors)
+		if err != nil {
+			return fmt.Errorf("error encoding tokens of %s: %w", path, err)
+		}
+	case "py-tokenize":
+		output = formatPyTokens(tokens, scanner.Comments())
+	default:
+		output = formatTokensText(filename, content, tokens, scanner.Errors)
+	}
+
+	if !cmd.WriteTokens {
+		// Print to console if not writing to file
+		if cmd.Format == "text" {
+			fmt.Println()
+		}
+		fmt.Print(output)
+	}
+
+	// Write to file if requested
+	if cmd.WriteTokens {
+		outputPath := getTokenOutputPath(fs, path, cmd.Output)
+		if cmd.Format == "json" {
+			outputPath += ".jsonl"
+		}
+		if err := fs.WriteFile(outputPath, []byte(output), 0644); err != nil {
+			return fmt.Errorf("error writing token file: %w", err)
+		}
+		log.InfoContext(ctx, "Wrote token file",
+			slog.String("input", path),
+			slog.String("output", outputPath))
+	}
+
+	if cmd.CheckRoundtrip {
+		if err := lexer.CheckRoundtrip(content, tokens, scanner.Comments()); err != nil {
+			return fmt.Errorf("%s: tokens do not reproduce the source: %w", path, err)
+		}
+		log.InfoContext(ctx, "Tokens reproduce the source", slog.String("file", path))
+	}
+
+	return nil
+}
+
+// formatTokensText lists the tokens, then the scan errors with code frames
+func formatTokensText(filename string, content []byte, tokens []lexer.Token, errs []error) string {
 	var output strings.Builder
 
 	output.WriteString(fmt.Sprintf("=== %s ===\n\n", filename))
@@ -125,31 +179,117 @@ func scanFile(fs filesystem.FileSystem, path, outputDir string, writeTokens bool
 			i, tok.Type, int(tok.Type), tok.Lexeme, tok.Literal, tok.Span.String()))
 	}
 
-	if len(scanner.Errors) > 0 {
-		output.WriteString(fmt.Sprintf("\n-- Errors (%d) --\n", len(scanner.Errors)))
-		for i, e := range scanner.Errors {
+	if len(errs) > 0 {
+		output.WriteString(fmt.Sprintf("\n-- Errors (%d) --\n", len(errs)))
+		for i, e := range errs {
+			if scanErr, ok := e.(*lexer.ScannerError); ok {
+				output.WriteString(fmt.Sprintf("%d: %s\n", i+1, scanErr.CodeFrame(content, filename)))
+				continue
+			}
 			output.WriteString(fmt.Sprintf("%d: %v\n", i+1, e))
 		}
 	}
+	return output.String()
+}
 
-	if !writeTokens {
-		// Print to console if not writing to file
-		fmt.Println()
-		fmt.Print(output.String())
+// formatPyTokens lists the tokens the way "python -m tokenize" does
+func formatPyTokens(tokens, comments []lexer.Token) string {
+	var output strings.Builder
+	for _, tok := range lexer.PyTokens(tokens, comments) {
+		output.WriteString(tok.String())
+		output.WriteString("\n")
 	}
+	return output.String()
+}
 
-	// Write to file if requested
-	if writeTokens {
-		outputPath := getTokenOutputPath(fs, path, outputDir)
-		if err := fs.WriteFile(outputPath, []byte(output.String()), 0644); err != nil {
-			return fmt.Errorf("error writing token file: %w", err)
+// jsonPosition is a 1-based source position in the JSON token stream
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// jsonSpan is a source range in the JSON token stream
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+// jsonToken is a line of the JSON token stream
+type jsonToken struct {
+	File    string   `json:"file"`
+	Type    string   `json:"type"`
+	PyType  string   `json:"py_type"`
+	Lexeme  string   `json:"lexeme"`
+	Literal any      `json:"literal,omitempty"`
+	Span    jsonSpan `json:"span"`
+}
+
+// jsonDiagnostic is an error in JSON output: a line of the JSON token
+// stream, or an entry of the errors of parse --ast-format json
+type jsonDiagnostic struct {
+	File  string   `json:"file"`
+	Error string   `json:"error"`
+	Code  string   `json:"code,omitempty"`
+	Hint  string   `json:"hint,omitempty"`
+	Span  jsonSpan `json:"span"`
+}
+
+func toJSONSpan(span lexer.Span) jsonSpan {
+	return jsonSpan{
+		Start: jsonPosition{Line: span.Start.Line, Column: span.Start.Column},
+		End:   jsonPosition{Line: span.End.Line, Column: span.End.Column},
+	}
+}
+
+// jsonLiteral converts a token's literal value to one JSON can hold: bytes
+// become a string of the characters U+0000 to U+00FF, as Python's latin-1
+// codec decodes them, and complex numbers an object of their parts
+func jsonLiteral(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		runes := make([]rune, len(v))
+		for i, b := range v {
+			runes[i] = rune(b)
 		}
-		log.InfoContext(ctx, "Wrote token file",
-			slog.String("input", path),
-			slog.String("output", outputPath))
+		return string(runes)
+	case complex128:
+		return map[string]float64{"real": real(v), "imag": imag(v)}
 	}
+	return v
+}
 
-	return nil
+// formatTokensJSON writes a JSON object per line for each token, then one
+// for each scan error
+func formatTokensJSON(path string, tokens []lexer.Token, errs []error) (string, error) {
+	var output strings.Builder
+	enc := json.NewEncoder(&output)
+	enc.SetEscapeHTML(false)
+	for _, tok := range tokens {
+		record := jsonToken{
+			File:    path,
+			Type:    tok.Type.String(),
+			PyType:  tok.Type.PyTokenName(),
+			Lexeme:  tok.Lexeme,
+			Literal: jsonLiteral(tok.Literal),
+			Span:    toJSONSpan(tok.Span),
+		}
+		if err := enc.Encode(record); err != nil {
+			return "", err
+		}
+	}
+	for _, e := range errs {
+		record := jsonDiagnostic{File: path, Error: e.Error()}
+		if scanErr, ok := e.(*lexer.ScannerError); ok {
+			record.Error = scanErr.Message
+			record.Code = string(scanErr.Code)
+			record.Hint = scanErr.Hint
+			record.Span = toJSONSpan(scanErr.Range)
+		}
+		if err := enc.Encode(record); err != nil {
+			return "", err
+		}
+	}
+	return output.String(), nil
 }
 
 // getTokenOutputPath determines the output path for a token file
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
+)
+
+// TraceMapCmd defines the "trace-map" command. It reads a Python traceback
+// and prints it with the frames of generated files pointing at the .psx
+// lines they were compiled from, using the maps written by --source-map.
+type TraceMapCmd struct {
+	Input string `arg:"" optional:"" help:"File holding the traceback (default: standard input)" type:"path"`
+}
+
+func (t *TraceMapCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var in io.Reader = os.Stdin
+	if t.Input != "" {
+		file, err := os.Open(t.Input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	// Most frames are in files without a map, which is not worth logging
+	return sourcemap.NewRewriter(os.ReadFile).Rewrite(in, os.Stdout)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// UsageCmd defines the "usage" command, a census of where each view of a
+// project is instantiated and which attributes its uses pass. Views and
+// parameters with no uses are listed too, so they can be removed safely.
+type UsageCmd struct {
+	// Positional argument
+	Input string `arg:"" optional:"" help:"Project directory to take the census of (default: the root of the project manifest)"`
+
+	// Flags
+	SourceRoot string   `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	SearchPath []string `help:"Extra directory to search for absolute imports after the source root, such as a shared component library; repeatable, searched in order before $TOPPLEPATH" name:"search-path"`
+	View       string   `help:"Only report the views with this name"`
+	Format     string   `help:"Output format: text, json" default:"text" enum:"text,json"`
+}
+
+// usageJSON is the JSON form of a view's census; paths are relative to the
+// input directory
+type usageJSON struct {
+	Name       string         `json:"name"`
+	File       string         `json:"file"`
+	Line       int            `json:"line"`
+	Params     []string       `json:"params"`
+	Count      int            `json:"count"`
+	Attributes map[string]int `json:"attributes"`
+	Uses       []useJSON      `json:"uses"`
+}
+
+type useJSON struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	Attributes []string `json:"attributes"`
+}
+
+func (u *UsageCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var err error
+	if u.Input, err = projectInput(globals, u.Input); err != nil {
+		return err
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	isDir, err := fs.IsDir(u.Input)
+	if err != nil {
+		return fmt.Errorf("error checking input path: %w", err)
+	}
+	if !isDir {
+		return fmt.Errorf("%s is not a directory: the census covers a whole project", u.Input)
+	}
+	rootDir, err := fs.AbsolutePath(u.Input)
+	if err != nil {
+		return fmt.Errorf("error resolving input path: %w", err)
+	}
+	files, err := fs.ListPSXFiles(rootDir, globals.Recursive)
+	if err != nil {
+		return fmt.Errorf("error listing PSX files: %w", err)
+	}
+
+	resolveRoot := rootDir
+	if u.SourceRoot != "" {
+		resolveRoot = u.SourceRoot
+	}
+	report, err := compiler.NewMultiFileCompiler(log).Usage(*ctx, compiler.MultiFileOptions{
+		RootDir:     resolveRoot,
+		Files:       files,
+		SearchPaths: searchPaths(u.SearchPath),
+	})
+	if err != nil {
+		return err
+	}
+
+	relative := func(path string) string {
+		if rel, err := filepath.Rel(rootDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+		return path
+	}
+
+	views := report.Views
+	if u.View != "" {
+		views = nil
+		for _, view := range report.Views {
+			if view.Name == u.View {
+				views = append(views, view)
+			}
+		}
+	}
+
+	switch u.Format {
+	case "json":
+		out := []usageJSON{}
+		for _, view := range views {
+			entry := usageJSON{
+				Name:       view.Name,
+				File:       relative(view.File),
+				Line:       view.Line,
+				Params:     append([]string{}, view.Params...),
+				Count:      len(view.Uses),
+				Attributes: view.Attributes,
+				Uses:       []useJSON{},
+			}
+			for _, use := range view.Uses {
+				entry.Uses = append(entry.Uses, useJSON{
+					File:       relative(use.File),
+					Line:       use.Line,
+					Column:     use.Column,
+					Attributes: append([]string{}, use.Attributes...),
+				})
+			}
+			out = append(out, entry)
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding census: %w", err)
+		}
+		fmt.Println(string(data))
+	default:
+		for i, view := range views {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s (%s:%d): %s\n", view.Name, relative(view.File), view.Line, plural(len(view.Uses), "use"))
+			if len(view.Attributes) > 0 {
+				fmt.Printf("  attributes: %s\n", attributeCounts(view))
+			}
+			for _, use := range view.Uses {
+				fmt.Printf("  %s:%d:%d\n", relative(use.File), use.Line, use.Column)
+			}
+		}
+	}
+
+	printCompilationErrors(os.Stderr, report.Errors)
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("census is incomplete: %s could not be analyzed", plural(len(report.Errors), "file"))
+	}
+	return nil
+}
+
+// attributeCounts lists the attributes of a view's uses, declared
+// parameters first in declaration order, then the others by name
+func attributeCounts(view *compiler.ViewUsage) string {
+	declared := make(map[string]bool, len(view.Params))
+	var parts, extra []string
+	for _, name := range view.Params {
+		declared[name] = true
+		parts = append(parts, fmt.Sprintf("%s %d", name, view.Attributes[name]))
+	}
+	for name := range view.Attributes {
+		if !declared[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		parts = append(parts, fmt.Sprintf("%s %d", name, view.Attributes[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plural formats a count with its noun
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
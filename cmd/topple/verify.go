@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// VerifyCmd defines the "verify" command. It recompiles sources without
+// writing anything and byte-compares the result with an existing build, so
+// nondeterministic output (ordering, generated names, timestamps) is caught.
+type VerifyCmd struct {
+	// Positional argument
+	Input string `arg:"" optional:"" help:"Path to a PSX file or directory (default: the root of the project manifest)"`
+
+	// Flags
+	Against string `help:"Build directory to compare with (default: the out directory of the project manifest, or the .py files next to the sources)" default:""`
+	CompileFlags
+}
+
+// verifyMismatch is one output that could not be reproduced
+type verifyMismatch struct {
+	Input  string
+	Output string
+	Reason string
+}
+
+func (v *VerifyCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
+	var err error
+	if v.Input, err = projectInput(globals, v.Input); err != nil {
+		return err
+	}
+	if v.Against == "" {
+		v.Against = globals.Project.OutputDir()
+	}
+	fs := filesystem.NewFileSystem(log)
+
+	options, err := v.options()
+	if err != nil {
+		return err
+	}
+	options.OutputDir = v.Against
+	// The lockfile is part of the build being verified: it must match and is never rewritten
+	opts := compiler.MultiFileOptions{LockMode: module.LockFrozen, Options: options, SearchPaths: searchPaths(v.SearchPath)}
+
+	isDir, err := fs.IsDir(v.Input)
+	if err != nil {
+		return fmt.Errorf("error checking input path: %w", err)
+	}
+
+	// A single file is compiled with its siblings so its imports resolve
+	rootDir := v.Input
+	if !isDir {
+		rootDir = filepath.Dir(v.Input)
+	}
+	files, err := listSources(*ctx, fs, rootDir, isDir && globals.Recursive, log)
+	if err != nil {
+		return fmt.Errorf("error listing PSX files: %w", err)
+	}
+	targets := files
+	if !isDir {
+		targets = []string{v.Input}
+	}
+	// findCompiled compares absolute paths
+	for i, target := range targets {
+		if targets[i], err = fs.AbsolutePath(target); err != nil {
+			return fmt.Errorf("error resolving input path: %w", err)
+		}
+	}
+
+	// Compile twice: outputs that differ between runs can never be reproduced
+	first, err := compileProject(files, rootDir, v.SourceRoot, opts, log, *ctx)
+	if err != nil {
+		return err
+	}
+	second, err := compileProject(files, rootDir, v.SourceRoot, opts, log, *ctx)
+	if err != nil {
+		return err
+	}
+
+	var mismatches []verifyMismatch
+	for _, target := range targets {
+		inputPath, code, ok := findCompiled(fs, first.CompiledFiles, target)
+		if !ok {
+			return fmt.Errorf("no output was compiled for %s", target)
+		}
+
+		outputPath, err := fs.GetOutputPath(inputPath, v.Against)
+		if err != nil {
+			return fmt.Errorf("error determining output path for %s: %w", inputPath, err)
+		}
+
+		if again := second.CompiledFiles[inputPath]; !bytes.Equal(code, again) {
+			mismatches = append(mismatches, verifyMismatch{
+				Input:  inputPath,
+				Output: outputPath,
+				Reason: "nondeterministic: two compilations differ " + firstDifference(code, again, "first", "second"),
+			})
+			continue
+		}
+
+		existing, err := os.ReadFile(outputPath)
+		if os.IsNotExist(err) {
+			mismatches = append(mismatches, verifyMismatch{Input: inputPath, Output: outputPath, Reason: "missing from the build"})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", outputPath, err)
+		}
+		// A stale header is told apart from stale code: it usually means the
+		// template changed and the build was not regenerated
+		if lines := len(options.Header.Lines); lines > 0 && !bytes.Equal(leadingLines(code, lines), leadingLines(existing, lines)) {
+			mismatches = append(mismatches, verifyMismatch{
+				Input:  inputPath,
+				Output: outputPath,
+				Reason: "does not carry the current header " + firstDifference(leadingLines(code, lines), leadingLines(existing, lines), "compiled", "existing"),
+			})
+			continue
+		}
+		if !bytes.Equal(code, existing) {
+			mismatches = append(mismatches, verifyMismatch{
+				Input:  inputPath,
+				Output: outputPath,
+				Reason: "differs from the build " + firstDifference(code, existing, "compiled", "existing"),
+			})
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Output < mismatches[j].Output })
+	for _, m := range mismatches {
+		fmt.Printf("%s: %s\n", m.Output, m.Reason)
+		log.DebugContext(*ctx, "Output not reproduced", slog.String("input", m.Input), slog.String("output", m.Output))
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("verification failed: %d of %d outputs were not reproduced", len(mismatches), len(targets))
+	}
+	fmt.Printf("Verified %d outputs: all reproduced byte for byte\n", len(targets))
+	return nil
+}
+
+// findCompiled looks up the output of target, whose path may be spelled
+// differently from the compiler's keys
+func findCompiled(fs filesystem.FileSystem, compiled map[string][]byte, target string) (string, []byte, bool) {
+	if code, ok := compiled[target]; ok {
+		return target, code, true
+	}
+	for inputPath, code := range compiled {
+		if abs, err := fs.AbsolutePath(inputPath); err == nil && abs == target {
+			return inputPath, code, true
+		}
+	}
+	return "", nil, false
+}
+
+// leadingLines returns the first n lines of code, with their newlines
+func leadingLines(code []byte, n int) []byte {
+	end := 0
+	for ; n > 0 && end < len(code); n-- {
+		next := bytes.IndexByte(code[end:], '\n')
+		if next < 0 {
+			return code
+		}
+		end += next + 1
+	}
+	return code[:end]
+}
+
+// firstDifference describes where two outputs first differ, by line
+func firstDifference(got, want []byte, gotLabel, wantLabel string) string {
+	gotLines := bytes.Split(got, []byte("\n"))
+	wantLines := bytes.Split(want, []byte("\n"))
+
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w []byte
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if !bytes.Equal(g, w) {
+			return fmt.Sprintf("at line %d:\n  %s: %q\n  %s: %q", i+1, gotLabel, g, wantLabel, w)
+		}
+	}
+	return "in line endings"
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestVerifyRelativeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/page.psx": `from card import Card
+
+view Page():
+    <Card title="Home" />
+`,
+		"src/card.psx": `view Card(title: str):
+    <h1>{title}</h1>
+`,
+	})
+
+	if logs, err := runTopple(t, dir, "compile", "--no-cache", "src", "out"); err != nil {
+		t.Fatalf("compile failed: %v\n%s", err, logs)
+	}
+	// The input is relative to the working directory
+	if logs, err := runTopple(t, dir, "verify", "src", "--against", "out"); err != nil {
+		t.Fatalf("verify failed: %v\n%s", err, logs)
+	}
+	if logs, err := runTopple(t, dir, "-r", "verify", "src", "--against", "out"); err != nil {
+		t.Fatalf("recursive verify failed: %v\n%s", err, logs)
+	}
+}
@@ -2,33 +2,59 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
+	"golang.org/x/sync/errgroup"
+
 	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/depgraph"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/internal/buildstatus"
 	"github.com/fjvillamarin/topple/internal/filesystem"
+	"github.com/fjvillamarin/topple/internal/livereload"
 )
 
 // WatchCmd defines the "watch" command.
 type WatchCmd struct {
 	// Positional argument
-	Directory string `arg:"" required:"" help:"Directory to watch for changes"`
+	Directory string `arg:"" optional:"" help:"Directory to watch for changes (default: the root of the project manifest)"`
 
 	// Options (shared with compile)
 	Delay int  `help:"Debounce delay in milliseconds" default:"300"`
 	Clear bool `help:"Clear terminal on each compilation" default:"false"`
 
 	// Options for output
-	Output     string `help:"Output directory for compiled Python files (default: same as input)" default:""`
-	SourceRoot string `help:"Project root for resolving absolute imports (default: input directory)" short:"s" default:""`
+	Output string `help:"Output directory for compiled Python files (default: same as input)" default:""`
+	CompileFlags
+	SourceMap bool `help:"Write a source map next to each generated file, for trace-map" name:"source-map"`
+	Stubs     bool `help:"Write a .pyi stub next to each generated file, declaring its views, functions and classes for IDEs and type checkers" name:"stubs"`
+
+	// Machine-readable build state for editors and task runners
+	StatusFile string `help:"Write the state, timestamps and diagnostics of the latest build to this JSON file" name:"status-file" default:""`
+	Events     string `help:"Write each step of every build (discovered files, resolved imports, cache hits, diagnostics and outputs) to this file as NDJSON" name:"events" placeholder:"FILE" default:""`
+
+	// Live reload for a dev server
+	Serve     bool   `help:"Run a live-reload server notifying browsers, over a WebSocket, of the modules each build rewrites" name:"serve"`
+	ServeAddr string `help:"Address of the live-reload server" name:"serve-addr" default:"127.0.0.1:35729"`
 }
 
-func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) error {
-	// We'll only use the Output directory if explicitly set
+func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger) (err error) {
+	if w.Directory, err = projectInput(globals, w.Directory); err != nil {
+		return err
+	}
+	// We'll only use the Output directory if explicitly set or declared by
+	// the project manifest
 	// Otherwise files will be created in the same directory as source files
+	if w.Output == "" {
+		w.Output = globals.Project.OutputDir()
+	}
 
 	// Configure logging
 	log.InfoContext(*ctx, "Watching directory",
@@ -40,8 +66,24 @@ func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 	// Initialize filesystem service
 	fs := filesystem.NewFileSystem(log)
 
-	// Initialize the compiler service
-	cmp := compiler.NewCompiler(log)
+	options, err := w.options()
+	if err != nil {
+		return err
+	}
+	options.OutputDir = w.Output
+	options.SourceMaps = w.SourceMap
+	options.Stubs = w.Stubs
+	eventLog, closeEvents, err := openEventLog(w.Events)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeEvents(); err == nil {
+			err = closeErr
+		}
+	}()
+	options.Events = eventLog
+	opts := compiler.MultiFileOptions{LockMode: module.LockAuto, Options: options, SearchPaths: searchPaths(w.SearchPath)}
 
 	// Check if directory exists
 	exists, err := fs.Exists(w.Directory)
@@ -61,19 +103,50 @@ func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 		return fmt.Errorf("path is not a directory: %s", w.Directory)
 	}
 
+	status := buildstatus.New(w.StatusFile, w.Directory)
+	reportStatus := func(err error) {
+		if err != nil {
+			log.WarnContext(*ctx, "Could not update status file", slog.String("error", err.Error()))
+		}
+	}
+
 	// Initial compilation
 	log.InfoContext(*ctx, "Performing initial compilation")
-	if err := compileDirectory(fs, cmp, w.Directory, w.Output, w.SourceRoot, globals.Recursive, log, *ctx); err != nil {
+	reportStatus(status.Started())
+	output, err := compileDirectory(fs, w.Directory, w.Output, w.SourceRoot, globals.Recursive, opts, log, *ctx)
+	reportStatus(status.Finished(err, false))
+	if err != nil {
 		return fmt.Errorf("initial compilation failed: %w", err)
 	}
 
+	// Dependencies of the last successful build decide what a change affects;
+	// every rebuild patches this graph in place rather than building its own
+	graph := output.Graph
+
 	// Start watching
 	log.InfoContext(*ctx, "Starting file watcher")
 
-	// Create a cancellable context for the watcher
-	watchCtx, cancel := context.WithCancel(context.Background())
+	// Builds run in the group, in the background; when the watch stops, on
+	// SIGINT or SIGTERM, a build in flight is cancelled and waited for, so
+	// no output is left half-written and its outcome reaches the status file
+	// before the event log is closed
+	group, groupCtx := errgroup.WithContext(*ctx)
+	watchCtx, cancel := context.WithCancel(groupCtx)
 	defer cancel()
 
+	// The live-reload server runs in the group too: failing to serve, such
+	// as when the address is taken, stops the watch with its error
+	var reload *livereload.Server
+	if w.Serve {
+		reload = livereload.NewServer(log)
+		group.Go(func() error {
+			if err := reload.ListenAndServe(watchCtx, w.ServeAddr); err != nil {
+				return fmt.Errorf("live-reload server: %w", err)
+			}
+			return nil
+		})
+	}
+
 	// Start watching the directory
 	events, err := fs.WatchFiles(watchCtx, []string{w.Directory}, globals.Recursive)
 	if err != nil {
@@ -84,18 +157,45 @@ func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 	timer := time.NewTimer(time.Duration(w.Delay) * time.Millisecond)
 	timer.Stop()
 
-	// Track when we need to recompile
+	// Track when we need to recompile, and which files changed since the
+	// last successful build
 	needsRecompile := false
+	changed := make(map[string]bool)
+
+	// A build runs in the background so new changes can abort it
+	var cancelBuild context.CancelFunc
+	var rebuilding map[string]string // Files of the running build and why
+	var building []string            // Changes the running build compiles
+	buildResults := make(chan watchBuild, 1)
+	defer func() {
+		// Stop the watcher and the live-reload server, and cancel a build in
+		// flight, before waiting for them
+		cancel()
+		if cancelBuild != nil {
+			cancelBuild()
+		}
+		if waitErr := group.Wait(); err == nil {
+			err = waitErr
+		}
+		if cancelBuild != nil {
+			result := <-buildResults
+			reportStatus(status.Finished(result.err, errors.Is(result.err, context.Canceled)))
+		}
+	}()
 
 	// Print watching message
 	fmt.Printf("Watching '%s' for changes...\n", w.Directory)
+	if reload != nil {
+		fmt.Printf("Live reload: add <script src=\"http://%s/livereload.js\"></script> to your pages\n", w.ServeAddr)
+	}
 
 	// Watch loop
 
 	for {
 		select {
-		case <-(*ctx).Done():
-			// Context was cancelled (Ctrl+C or similar)
+		case <-groupCtx.Done():
+			// Context was cancelled (Ctrl+C or similar), or the live-reload
+			// server failed, whose error the group returns
 			log.InfoContext(*ctx, "Stopping watch due to context cancellation")
 			return nil
 
@@ -117,46 +217,192 @@ func (w *WatchCmd) Run(globals *Globals, ctx *context.Context, log *slog.Logger)
 				continue
 			}
 
+			if path, err := filepath.Abs(event.Path); err == nil {
+				changed[path] = true
+			}
+
+			// Abort an in-flight build; it would compile stale sources
+			if cancelBuild != nil {
+				log.DebugContext(*ctx, "Cancelling in-flight compilation")
+				cancelBuild()
+			}
+
 			// Reset debounce timer
 			timer.Reset(time.Duration(w.Delay) * time.Millisecond)
 			needsRecompile = true
 
+		case result := <-buildResults:
+			cancelBuild = nil
+			err := result.err
+			cancelled := errors.Is(err, context.Canceled)
+			reportStatus(status.Finished(err, cancelled))
+			if err != nil {
+				// The changes still have to be compiled by the next build
+				for _, path := range building {
+					changed[path] = true
+				}
+			} else {
+				graph = result.output.Graph
+				printRebuilt(w.Directory, result.output.Graph, rebuilding)
+			}
+			if reload != nil && !cancelled {
+				reload.Notify(reloadMessage(w.Directory, w.SourceRoot, rebuilding, err))
+			}
+			switch {
+			case cancelled:
+				log.InfoContext(*ctx, "Compilation cancelled by newer changes")
+			case err != nil:
+				log.ErrorContext(*ctx, "Compilation failed", slog.String("error", err.Error()))
+				fmt.Printf("Compilation error: %v\n", err)
+			default:
+				log.InfoContext(*ctx, "Compilation successful")
+				fmt.Println("Compilation successful")
+			}
+
 		case <-timer.C:
-			if needsRecompile {
+			// Wait for a cancelled build to report back before starting the next one
+			if needsRecompile && cancelBuild == nil {
 				// Clear terminal if requested
 				if w.Clear {
 					clearTerminal()
 				}
 
-				// Recompile
-				log.InfoContext(*ctx, "Recompiling after file changes")
-				if err := compileDirectory(fs, cmp, w.Directory, w.Output, w.SourceRoot, globals.Recursive, log, *ctx); err != nil {
-					log.ErrorContext(*ctx, "Compilation failed", slog.String("error", err.Error()))
-					fmt.Printf("Compilation error: %v\n", err)
-				} else {
-					log.InfoContext(*ctx, "Compilation successful")
-					fmt.Println("Compilation successful")
+				// Recompile the changed files and everything depending on them
+				building = building[:0]
+				for path := range changed {
+					building = append(building, path)
+				}
+				changed = make(map[string]bool)
+				rebuilding = affectedFiles(graph, building)
+				buildOpts := opts
+				buildOpts.Graph = graph
+				buildOpts.Rebuild = make([]string, 0, len(rebuilding))
+				for path := range rebuilding {
+					buildOpts.Rebuild = append(buildOpts.Rebuild, path)
 				}
 
+				log.InfoContext(*ctx, "Recompiling after file changes",
+					slog.Int("changed", len(building)),
+					slog.Int("affected", len(rebuilding)))
+				reportStatus(status.Started())
+				buildCtx, cancel := context.WithCancel(groupCtx)
+				cancelBuild = cancel
+				group.Go(func() error {
+					defer cancel()
+					// A failed build is reported and the watch goes on
+					output, err := compileDirectory(fs, w.Directory, w.Output, w.SourceRoot, globals.Recursive, buildOpts, log, buildCtx)
+					buildResults <- watchBuild{output: output, err: err}
+					return nil
+				})
+
 				needsRecompile = false
+			} else if needsRecompile {
+				timer.Reset(time.Duration(w.Delay) * time.Millisecond)
+			}
+		}
+	}
+}
+
+// watchBuild is the outcome of a background build
+type watchBuild struct {
+	output *compiler.MultiFileOutput
+	err    error
+}
+
+// affectedFiles returns the files a rebuild after the given changes must
+// compile, each mapped to the dependency that made it stale ("" for changed
+// files): the reverse-dependency closure of the changes in the graph of the
+// last successful build, plus new files, which that graph does not know
+func affectedFiles(graph *depgraph.DependencyGraph, changed []string) map[string]string {
+	affected := graph.GetAffected(changed)
+	for _, path := range changed {
+		if _, known := affected[path]; known {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			affected[path] = ""
+		}
+	}
+	return affected
+}
+
+// printRebuilt lists the files a rebuild compiled, in compilation order, with
+// the reason each dependent was recompiled
+func printRebuilt(directory string, graph *depgraph.DependencyGraph, rebuilt map[string]string) {
+	layers, err := graph.GetCompilationLayers()
+	if err != nil {
+		return
+	}
+	root, err := filepath.Abs(directory)
+	if err != nil {
+		root = directory
+	}
+	display := func(path string) string {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			return rel
+		}
+		return path
+	}
+
+	for _, layer := range layers {
+		sort.Strings(layer)
+		for _, path := range layer {
+			via, ok := rebuilt[path]
+			switch {
+			case !ok:
+				continue
+			case via == "":
+				fmt.Printf("Rebuilt %s (changed)\n", display(path))
+			default:
+				fmt.Printf("Rebuilt %s (imports %s)\n", display(path), display(via))
 			}
 		}
 	}
 }
 
+// reloadMessage tells browsers what a finished build did: the modules and
+// files it rewrote, or why it failed. Modules are named relative to the
+// source root, the watched directory by default.
+func reloadMessage(directory, sourceRoot string, rebuilt map[string]string, err error) livereload.Message {
+	if err != nil {
+		return livereload.Message{Type: livereload.Error, Error: err.Error()}
+	}
+	if sourceRoot == "" {
+		sourceRoot = directory
+	}
+	dir, _ := filepath.Abs(directory)
+	root, _ := filepath.Abs(sourceRoot)
+
+	msg := livereload.Message{Type: livereload.Reload}
+	for path := range rebuilt {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			msg.Files = append(msg.Files, filepath.ToSlash(rel))
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		name := strings.ReplaceAll(filepath.ToSlash(strings.TrimSuffix(rel, ".psx")), "/", ".")
+		msg.Modules = append(msg.Modules, strings.TrimSuffix(name, ".__init__"))
+	}
+	sort.Strings(msg.Files)
+	sort.Strings(msg.Modules)
+	return msg
+}
+
 // compileDirectory compiles all PSX files in a directory using multi-file
 // compilation for proper cross-file view import resolution.
-func compileDirectory(fs filesystem.FileSystem, _ compiler.Compiler, inputDir, outputDir, sourceRoot string, recursive bool, log *slog.Logger, ctx context.Context) error {
+func compileDirectory(fs filesystem.FileSystem, inputDir, outputDir, sourceRoot string, recursive bool, opts compiler.MultiFileOptions, log *slog.Logger, ctx context.Context) (*compiler.MultiFileOutput, error) {
 	// List all PSX files
-	files, err := fs.ListPSXFiles(inputDir, recursive)
+	files, err := listSources(ctx, fs, inputDir, recursive, log)
 	if err != nil {
-		return fmt.Errorf("error listing PSX files: %w", err)
+		return nil, fmt.Errorf("error listing PSX files: %w", err)
 	}
 
 	log.InfoContext(ctx, "Found PSX files to compile", slog.Int("count", len(files)))
 
 	// Use multi-file compilation for proper dependency resolution
-	return compileMultiFile(files, inputDir, outputDir, sourceRoot, log, ctx)
+	return compileMultiFile(files, inputDir, outputDir, sourceRoot, opts, log, ctx)
 }
 
 // clearTerminal clears the terminal screen
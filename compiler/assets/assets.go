@@ -0,0 +1,178 @@
+// Package assets resolves relative asset references in view attributes,
+// such as <img src="./logo.png">, at compile time.
+//
+// References are resolved against the directory of the .psx file that
+// contains them, and a reference to a missing file is a compile error. With
+// an output directory configured, each referenced file is also copied there
+// under a content-hashed name, the reference is rewritten to the hashed URL,
+// and a manifest maps the original files to their URLs for the web server:
+//
+//	resolver := assets.NewResolver(assets.Config{
+//		RootDir:    "src",
+//		OutputDir:  "dist/static",
+//		PublicPath: "/static",
+//	})
+//	url, err := resolver.Resolve("/proj/src/pages/home.psx", "./logo.png")
+//	// url == "/static/logo.3f2a9c1e.png", dist/static/logo.3f2a9c1e.png written
+//	err = resolver.WriteManifest()
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ManifestName is the manifest written to the asset output directory
+const ManifestName = "manifest.json"
+
+// hashLength is how many hex digits of the content hash go into file names
+const hashLength = 8
+
+// Config configures a Resolver
+type Config struct {
+	RootDir    string // Project root; manifest keys are relative to it
+	OutputDir  string // Where hashed copies are written; empty only verifies references
+	PublicPath string // URL prefix of OutputDir, e.g. "/static"
+}
+
+// Resolver checks and rewrites asset references. It is safe for concurrent
+// use.
+type Resolver struct {
+	config Config
+
+	mu       sync.Mutex
+	manifest map[string]string // Asset path relative to RootDir -> URL
+}
+
+// NewResolver creates a resolver with the given configuration
+func NewResolver(config Config) *Resolver {
+	return &Resolver{config: config, manifest: make(map[string]string)}
+}
+
+// IsReference reports whether an attribute value is a relative asset
+// reference ("./" or "../")
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, "./") || strings.HasPrefix(value, "../")
+}
+
+// Resolve checks that the asset ref, used in sourceFile, exists and returns
+// the value the attribute should have. Without an output directory the
+// reference is returned unchanged.
+func (r *Resolver) Resolve(sourceFile, ref string) (string, error) {
+	// Query strings and fragments (icon.svg#play) are kept as written
+	file, suffix := ref, ""
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		file, suffix = ref[:i], ref[i:]
+	}
+
+	assetPath := filepath.Join(filepath.Dir(sourceFile), filepath.FromSlash(file))
+	info, err := os.Stat(assetPath)
+	if err != nil {
+		return "", fmt.Errorf("asset %q not found: %s does not exist", ref, assetPath)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("asset %q is a directory: %s", ref, assetPath)
+	}
+
+	if r.config.OutputDir == "" {
+		return ref, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.manifestKey(assetPath)
+	if url, ok := r.manifest[key]; ok {
+		return url + suffix, nil
+	}
+
+	name, err := r.copyHashed(assetPath)
+	if err != nil {
+		return "", err
+	}
+	url := path.Join("/", r.config.PublicPath, name)
+	if r.config.PublicPath == "" {
+		url = name
+	}
+	r.manifest[key] = url
+	return url + suffix, nil
+}
+
+// Manifest returns the assets copied so far, keyed by their path relative
+// to the project root
+func (r *Resolver) Manifest() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	manifest := make(map[string]string, len(r.manifest))
+	for key, url := range r.manifest {
+		manifest[key] = url
+	}
+	return manifest
+}
+
+// WriteManifest writes the manifest to the output directory. It does nothing
+// when no output directory is configured.
+func (r *Resolver) WriteManifest() error {
+	if r.config.OutputDir == "" {
+		return nil
+	}
+
+	// Maps are encoded with sorted keys, so the manifest is stable between builds
+	data, err := json.MarshalIndent(r.Manifest(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding asset manifest: %w", err)
+	}
+	if err := os.MkdirAll(r.config.OutputDir, 0o755); err != nil {
+		return fmt.Errorf("error creating asset directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.config.OutputDir, ManifestName), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing asset manifest: %w", err)
+	}
+	return nil
+}
+
+// copyHashed copies assetPath to the output directory under a name that
+// includes its content hash, and returns that name
+func (r *Resolver) copyHashed(assetPath string) (string, error) {
+	content, err := os.ReadFile(assetPath)
+	if err != nil {
+		return "", fmt.Errorf("error reading asset %s: %w", assetPath, err)
+	}
+	sum := sha256.Sum256(content)
+
+	ext := filepath.Ext(assetPath)
+	base := strings.TrimSuffix(filepath.Base(assetPath), ext)
+	name := base + "." + hex.EncodeToString(sum[:])[:hashLength] + ext
+
+	if err := os.MkdirAll(r.config.OutputDir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating asset directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(r.config.OutputDir, name), content, 0o644); err != nil {
+		return "", fmt.Errorf("error copying asset %s: %w", assetPath, err)
+	}
+	return name, nil
+}
+
+// manifestKey returns the slash-separated path of assetPath relative to the
+// project root, or its absolute path when it lies outside the root
+func (r *Resolver) manifestKey(assetPath string) string {
+	abs, err := filepath.Abs(assetPath)
+	if err != nil {
+		abs = assetPath
+	}
+	if r.config.RootDir != "" {
+		if root, err := filepath.Abs(r.config.RootDir); err == nil {
+			if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(abs)
+}
@@ -0,0 +1,78 @@
+package assets
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsReference(t *testing.T) {
+	for value, expected := range map[string]bool{
+		"./logo.png":            true,
+		"../shared/icons.svg":   true,
+		"/static/logo.png":      false,
+		"https://cdn.test/x":    false,
+		"logo.png":              false,
+		"data:image/png;base64": false,
+	} {
+		if got := IsReference(value); got != expected {
+			t.Errorf("IsReference(%q) = %v, want %v", value, got, expected)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	source := filepath.Join(root, "pages", "home.psx")
+	if err := os.MkdirAll(filepath.Join(root, "pages", "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pages", "img", "logo.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Verification only leaves references unchanged
+	verifier := NewResolver(Config{RootDir: root})
+	if got, err := verifier.Resolve(source, "./img/logo.png"); err != nil || got != "./img/logo.png" {
+		t.Errorf("expected the reference unchanged, got %q, %v", got, err)
+	}
+	_, err := verifier.Resolve(source, "./img/missing.png")
+	if err == nil || !strings.Contains(err.Error(), `asset "./img/missing.png" not found`) {
+		t.Errorf("expected a missing asset error, got %v", err)
+	}
+	if _, err := verifier.Resolve(source, "./img"); err == nil {
+		t.Errorf("expected a directory to be rejected")
+	}
+
+	// With an output directory assets are copied under hashed names
+	outDir := filepath.Join(root, "dist")
+	resolver := NewResolver(Config{RootDir: root, OutputDir: outDir, PublicPath: "/static"})
+	url, err := resolver.Resolve(source, "./img/logo.png#frame")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !strings.HasPrefix(url, "/static/logo.") || !strings.HasSuffix(url, ".png#frame") {
+		t.Errorf("unexpected URL %q", url)
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(url, "/static/"), "#frame")
+	if content, err := os.ReadFile(filepath.Join(outDir, name)); err != nil || string(content) != "png" {
+		t.Errorf("expected the asset to be copied to %s: %v", name, err)
+	}
+
+	if err := resolver.WriteManifest(); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outDir, ManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest["pages/img/logo.png"] != "/static/"+name {
+		t.Errorf("unexpected manifest %v", manifest)
+	}
+}
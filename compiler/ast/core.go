@@ -63,6 +63,7 @@ type ExprVisitor interface {
 	VisitArgument(a *Argument) Visitor
 	VisitLambda(l *Lambda) Visitor
 	VisitFString(f *FString) Visitor
+	VisitConcatenatedString(c *ConcatenatedString) Visitor
 	VisitFStringMiddle(f *FStringMiddle) Visitor
 	VisitFStringReplacementField(f *FStringReplacementField) Visitor
 	VisitFStringConversion(f *FStringConversion) Visitor
@@ -121,4 +122,5 @@ type StmtVisitor interface {
 	VisitHTMLContent(h *HTMLContent) Visitor
 	VisitHTMLText(h *HTMLText) Visitor
 	VisitHTMLInterpolation(h *HTMLInterpolation) Visitor
+	VisitHTMLComment(h *HTMLComment) Visitor
 }
@@ -25,3 +25,118 @@ func (d *Decorator) Accept(visitor Visitor) {
 func (d *Decorator) String() string {
 	return fmt.Sprintf("Decorator(%s)", d.Expr)
 }
+
+// DeprecatedMarker is the name of the @deprecated("use X instead") marker.
+// It is read by the compiler and never reaches the generated code, unless
+// the module binds the name itself: see Module.BindsDeprecated.
+const DeprecatedMarker = "deprecated"
+
+// BindsDeprecated reports whether the module binds the name of the
+// @deprecated marker anywhere, with an import, an assignment or a
+// definition, such as PEP 702's "from typing_extensions import deprecated".
+// Its @deprecated decorators are then calls of that function, which must
+// run, and not the marker.
+func (m *Module) BindsDeprecated() bool {
+	bound := false
+	binds := func(target Expr) {
+		Inspect(target, func(node Node) bool {
+			switch n := node.(type) {
+			case *Name:
+				bound = bound || n.Token.Lexeme == DeprecatedMarker
+			case *Attribute, *Subscript, *Call:
+				return false // Not a binding of a name
+			}
+			return true
+		})
+	}
+	Inspect(m, func(node Node) bool {
+		switch n := node.(type) {
+		case *ImportFromStmt:
+			for _, name := range n.Names {
+				if name.AsName != nil {
+					binds(name.AsName)
+				} else if name.DottedName != nil && len(name.DottedName.Names) > 0 {
+					binds(name.DottedName.Names[len(name.DottedName.Names)-1])
+				}
+			}
+		case *ImportStmt:
+			for _, name := range n.Names {
+				if name.AsName != nil {
+					binds(name.AsName)
+				} else if name.DottedName != nil && len(name.DottedName.Names) > 0 {
+					binds(name.DottedName.Names[0])
+				}
+			}
+		case *AssignStmt:
+			for _, target := range n.Targets {
+				binds(target)
+			}
+		case *AnnotationStmt:
+			binds(n.Target)
+		case *AssignExpr:
+			binds(n.Left)
+		case *For:
+			binds(n.Target)
+		case *With:
+			for _, item := range n.Items {
+				binds(item.As)
+			}
+		case *Function:
+			binds(n.Name)
+		case *Class:
+			binds(n.Name)
+		case *ViewStmt:
+			binds(n.Name)
+		}
+		return !bound
+	})
+	return bound
+}
+
+// Deprecation reports whether the decorator is the @deprecated marker, with
+// or without a message, and returns the message
+func (d *Decorator) Deprecation() (message string, ok bool) {
+	switch expr := d.Expr.(type) {
+	case *Name:
+		return "", expr.Token.Lexeme == DeprecatedMarker
+	case *Call:
+		callee, isName := expr.Callee.(*Name)
+		if !isName || callee.Token.Lexeme != DeprecatedMarker {
+			return "", false
+		}
+		if len(expr.Arguments) > 0 {
+			if literal, isLiteral := expr.Arguments[0].Value.(*Literal); isLiteral && literal.Type == LiteralTypeString {
+				message, _ = literal.Value.(string)
+			}
+		}
+		return message, true
+	}
+	return "", false
+}
+
+// Definition returns the statement under a stack of decorators
+func (d *Decorator) Definition() Stmt {
+	stmt := d.Stmt
+	for {
+		inner, ok := stmt.(*Decorator)
+		if !ok {
+			return stmt
+		}
+		stmt = inner.Stmt
+	}
+}
+
+// Deprecated reports whether a stack of decorators holds the @deprecated
+// marker, and returns its message
+func (d *Decorator) Deprecated() (message string, ok bool) {
+	for stmt := Stmt(d); ; {
+		decorator, isDecorator := stmt.(*Decorator)
+		if !isDecorator {
+			return "", false
+		}
+		if message, ok := decorator.Deprecation(); ok {
+			return message, true
+		}
+		stmt = decorator.Stmt
+	}
+}
@@ -0,0 +1,26 @@
+package ast
+
+import "testing"
+
+func TestBindsDeprecated(t *testing.T) {
+	marker := HExprStmt(HCall(N("deprecated"), S("use g")))
+	tests := []struct {
+		name   string
+		module *Module
+		binds  bool
+	}{
+		{"marker only", HModule(marker), false},
+		{"from-import", HModule(HImportFrom("typing_extensions", []*ImportName{HImportN("deprecated")}), marker), true},
+		{"aliased from-import", HModule(HImportFrom("warnings", []*ImportName{HImportN("deprecated", "old")})), false},
+		{"alias to deprecated", HModule(HImportFrom("warnings", []*ImportName{HImportN("warn", "deprecated")})), true},
+		{"import", HModule(HImport(HImportN("deprecated"))), true},
+		{"assignment", HModule(HAssign([]Expr{HTuple(N("a"), N("deprecated"))}, N("pair"))), true},
+		{"attribute assignment", HModule(HAssign([]Expr{HAttributeAccess(N("deprecated"), "x")}, I(1))), false},
+		{"nested definition", HModule(HClass("C", nil, []Stmt{HFunction("deprecated", nil, []Stmt{HPass()}, nil)})), true},
+	}
+	for _, tt := range tests {
+		if got := tt.module.BindsDeprecated(); got != tt.binds {
+			t.Errorf("%s: BindsDeprecated() = %v, want %v", tt.name, got, tt.binds)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 // FString represents an f-string literal: f"text {expr} more text"
 type FString struct {
 	Parts []FStringPart // The parts of the f-string (middle text and replacement fields)
+	Quote string        // The opening prefix and quote as written, such as f" or rf'''
 
 	Span lexer.Span
 }
@@ -31,6 +32,33 @@ func (f *FString) Accept(visitor Visitor) {
 	visitor.VisitFString(f)
 }
 
+// ConcatenatedString is adjacent string literals of which at least one is
+// an f-string, such as "a" f"{b}", which Python joins into one string.
+// Adjacent literals without an f-string are folded into a single Literal.
+type ConcatenatedString struct {
+	Parts []Expr // The *Literal and *FString parts in source order
+
+	Span lexer.Span
+}
+
+func (c *ConcatenatedString) isExpr() {}
+
+func (c *ConcatenatedString) GetSpan() lexer.Span {
+	return c.Span
+}
+
+func (c *ConcatenatedString) String() string {
+	parts := make([]string, len(c.Parts))
+	for i, part := range c.Parts {
+		parts[i] = part.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *ConcatenatedString) Accept(visitor Visitor) {
+	visitor.VisitConcatenatedString(c)
+}
+
 // FStringPart is the interface for parts of an f-string
 type FStringPart interface {
 	Node
@@ -39,7 +67,8 @@ type FStringPart interface {
 
 // FStringMiddle represents literal text in an f-string
 type FStringMiddle struct {
-	Value string // The literal text
+	Value  string // The literal text, with escapes decoded and doubled braces single
+	Lexeme string // The text as written; empty for text that is not Python source, such as markup
 
 	Span lexer.Span
 }
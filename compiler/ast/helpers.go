@@ -96,6 +96,15 @@ func HAttr(name string, value Expr) HTMLAttribute {
 	}
 }
 
+// HSpread creates a {...mapping} attribute spread
+func HSpread(value Expr) HTMLAttribute {
+	return HTMLAttribute{
+		Name:   lexer.Token{Lexeme: "...", Type: lexer.Ellipsis},
+		Value:  value,
+		Spread: true,
+	}
+}
+
 // ExprStmt wraps an expression as a statement
 func HExprStmt(expr Expr) *ExprStmt {
 	return &ExprStmt{Expr: expr}
@@ -0,0 +1,27 @@
+package ast
+
+import (
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// HTMLComment represents a preserved HTML comment: <!-- text -->
+// Comments are only parsed into the AST when comment preservation is enabled.
+type HTMLComment struct {
+	Text string // The comment body, without the <!-- and --> delimiters
+
+	Span lexer.Span
+}
+
+func (h *HTMLComment) isStmt() {}
+
+func (h *HTMLComment) GetSpan() lexer.Span {
+	return h.Span
+}
+
+func (h *HTMLComment) String() string {
+	return "<!--" + h.Text + "-->"
+}
+
+func (h *HTMLComment) Accept(visitor Visitor) {
+	visitor.VisitHTMLComment(h)
+}
@@ -36,9 +36,11 @@ type HTMLContentPart interface {
 	isHTMLContentPart()
 }
 
-// HTMLText represents literal text in HTML content
+// HTMLText represents literal text in HTML content, or a {~} marker
+// trimming the whitespace of the text around it
 type HTMLText struct {
 	Value string // The literal text
+	Trim  bool   // Whether this is a {~} marker, with no text
 
 	Span lexer.Span
 }
@@ -50,6 +52,9 @@ func (h *HTMLText) GetSpan() lexer.Span {
 }
 
 func (h *HTMLText) String() string {
+	if h.Trim {
+		return "{~}"
+	}
 	return h.Value
 }
 
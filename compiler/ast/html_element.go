@@ -16,17 +16,20 @@ const (
 	HTMLSingleLineElement                        // <span>content</span> (single line)
 )
 
-// HTMLAttribute represents an HTML attribute
+// HTMLAttribute represents an HTML attribute, or a {...mapping} spreading
+// several attributes at once
 type HTMLAttribute struct {
-	Name  lexer.Token // Attribute name
-	Value Expr        // Attribute value (can be string literal or expression)
-	Span  lexer.Span
+	Name   lexer.Token // Attribute name; the '...' token of a spread
+	Value  Expr        // Attribute value (can be string literal or expression); the mapping of a spread
+	Spread bool        // Whether this is a {...mapping} spread
+	Span   lexer.Span
 }
 
 // HTMLElement represents an HTML element statement
 type HTMLElement struct {
 	Type       HTMLElementType // Type of HTML element
-	TagName    lexer.Token     // Tag name (e.g., "div", "span")
+	TagName    lexer.Token     // Tag name (e.g., "div", "span"); "{expr}" as written for a computed tag name; "" for a <>...</> fragment
+	TagExpr    Expr            // Expression computing the tag name (e.g., <{tag}>), or nil
 	Attributes []HTMLAttribute // HTML attributes
 	Content    []Stmt          // Content inside the element (for container elements)
 	IsClosing  bool            // Whether this is a closing tag
@@ -60,3 +63,50 @@ func (h *HTMLElement) String() string {
 		return fmt.Sprintf("HTMLElement(%s)", h.TagName.Lexeme)
 	}
 }
+
+// IsFragment reports whether the element is a <>...</> fragment, which
+// groups its content without a wrapper element
+func (h *HTMLElement) IsFragment() bool {
+	return h.TagExpr == nil && h.TagName.Lexeme == ""
+}
+
+// SlotParams returns the names the content of a scoped slot binds with its let
+// attribute, let={item} or let={(item, index)}, to the values the slot passes.
+// ok is false when the element has no let attribute; names is nil when the
+// attribute does not name values.
+func (h *HTMLElement) SlotParams() (names []*Name, ok bool) {
+	for _, attr := range h.Attributes {
+		if !attr.Spread && attr.Name.Lexeme == "let" {
+			return LetNames(attr.Value), true
+		}
+	}
+	return nil, false
+}
+
+// LetNames returns the names a let attribute value binds: a name, or a
+// parenthesized tuple of distinct names. It returns nil for other values.
+func LetNames(value Expr) []*Name {
+	if group, ok := value.(*GroupExpr); ok {
+		value = group.Expression
+	}
+	switch v := value.(type) {
+	case *Name:
+		return []*Name{v}
+	case *TupleExpr:
+		names := make([]*Name, 0, len(v.Elements))
+		seen := make(map[string]bool, len(v.Elements))
+		for _, element := range v.Elements {
+			name, ok := element.(*Name)
+			if !ok || seen[name.Token.Lexeme] {
+				return nil
+			}
+			seen[name.Token.Lexeme] = true
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		return names
+	}
+	return nil
+}
@@ -12,9 +12,13 @@ const (
 	LiteralTypeNumber
 	LiteralTypeBool
 	LiteralTypeNone
+	LiteralTypeBytes
 )
 
-// Literal represents a literal value (number, string, etc.).
+// Literal represents a literal value (number, string, etc.). The value of a
+// string is its text with escapes decoded, and that of bytes a []byte.
+// Adjacent string literals, as in "a" "b", are folded into one Literal whose
+// token keeps their lexemes.
 type Literal struct {
 	Token lexer.Token
 	Value any
@@ -64,6 +68,8 @@ func (l *Literal) String() string {
 		}
 	case LiteralTypeNone:
 		return "None"
+	case LiteralTypeBytes:
+		return fmt.Sprintf("b%q", l.Value)
 	default:
 		return fmt.Sprintf("%v", l.Value)
 	}
@@ -0,0 +1,109 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Transformer rewrites the nodes of a tree. Transform is called on the nodes
+// held by fields of a node interface type, such as Expr, Stmt or Pattern,
+// and returns the node to put in their place, which must implement the
+// same interface: the rewrite panics otherwise. Returning
+// RewriteChildren(node, t) is the default: a copy of node whose children
+// are transformed in turn.
+type Transformer interface {
+	Transform(node Node) Node
+}
+
+// TransformerFunc adapts a function to the Transformer interface
+type TransformerFunc func(node Node) Node
+
+func (f TransformerFunc) Transform(node Node) Node {
+	return f(node)
+}
+
+// Rewrite returns a copy of the tree rooted at node, rewritten bottom-up:
+// the children of each node are rewritten first, then f is called on the
+// copy and returns the node to use instead, or the copy itself to keep it.
+// The tree rooted at node is left unchanged.
+func Rewrite(node Node, f func(Node) Node) Node {
+	var t TransformerFunc
+	t = func(n Node) Node {
+		return f(RewriteChildren(n, t))
+	}
+	return t(node)
+}
+
+// RewriteChildren returns a copy of node whose children are t's
+// transformations of node's children. The traversal needs no code per node
+// type: it copies every field, so new node types are handled as they are
+// added. Nodes in fields of a concrete type, such as a function's *Name,
+// cannot be replaced by another type of node, so they are copied with
+// their own children transformed rather than passed to t. Tokens, spans
+// and literal values are shared with node.
+func RewriteChildren(node Node, t Transformer) Node {
+	if node == nil {
+		return nil
+	}
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return node
+	}
+	return rewriteValue(v, t).Interface().(Node)
+}
+
+var astPackage = reflect.TypeFor[Name]().PkgPath()
+
+// rewriteValue returns a copy of v with the nodes it holds in node
+// interfaces transformed by t
+func rewriteValue(v reflect.Value, t Transformer) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() || v.NumMethod() == 0 || v.Type().PkgPath() != astPackage {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		node, ok := v.Interface().(Node)
+		if !ok {
+			// Not a node itself, such as a dict's key-value pair: copy it
+			out.Set(rewriteValue(v.Elem(), t))
+			return out
+		}
+		replacement := t.Transform(node)
+		if replacement == nil {
+			return out
+		}
+		r := reflect.ValueOf(replacement)
+		if !r.Type().AssignableTo(v.Type()) {
+			panic(fmt.Sprintf("ast: cannot replace %T with %T, which does not implement %s", v.Interface(), replacement, v.Type()))
+		}
+		out.Set(r)
+		return out
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct || v.Elem().Type().PkgPath() != astPackage {
+			return v
+		}
+		out := reflect.New(v.Elem().Type())
+		out.Elem().Set(rewriteValue(v.Elem(), t))
+		return out
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPackage {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		for i := range v.NumField() {
+			out.Field(i).Set(rewriteValue(v.Field(i), t))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			out.Index(i).Set(rewriteValue(v.Index(i), t))
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package ast
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// rename returns a rewrite function renaming the names called from to to
+func rename(from, to string) func(Node) Node {
+	return func(node Node) Node {
+		if name, ok := node.(*Name); ok && name.Token.Lexeme == from {
+			return N(to)
+		}
+		return node
+	}
+}
+
+// program builds f(*x); y = {"a": x, **x}; def g(p=x): return x
+func program(x string) *Module {
+	return HModule(
+		HExprStmt(HCall(N("f"), HStar(N(x)))),
+		HAssign([]Expr{N("y")}, HDict(HKeyValue(S("a"), N(x)), HDictUnpack(N(x)))),
+		HFunction("g", []*Parameter{HParamWithDefault("p", "int", N(x))}, []Stmt{HReturn(N(x))}, nil),
+	)
+}
+
+func TestRewrite(t *testing.T) {
+	original := program("x")
+	rewritten := Rewrite(original, rename("x", "z"))
+
+	if !reflect.DeepEqual(rewritten, program("z")) {
+		t.Errorf("expected every x replaced, in interface fields, slices and dict pairs")
+	}
+	if !reflect.DeepEqual(original, program("x")) {
+		t.Errorf("expected the original tree to be left unchanged")
+	}
+	if rewritten.(*Module).Body[2].(*Function).Name == original.Body[2].(*Function).Name {
+		t.Errorf("expected nodes in fields of a concrete type to be copied, not shared")
+	}
+}
+
+// Nodes in fields of a concrete type are copied rather than replaced
+func TestRewriteConcreteFields(t *testing.T) {
+	function := HFunction("x", []*Parameter{HParam("x")}, []Stmt{HReturn(N("x"))}, nil)
+	rewritten := Rewrite(function, rename("x", "z")).(*Function)
+
+	if rewritten.Name.Token.Lexeme != "x" || rewritten.Parameters.Parameters[0].Name.Token.Lexeme != "x" {
+		t.Errorf("expected the names of the function and its parameter to be kept")
+	}
+	if value := rewritten.Body[0].(*ReturnStmt).Value.(*Name); value.Token.Lexeme != "z" {
+		t.Errorf("expected the returned name to be replaced, got %s", value.Token.Lexeme)
+	}
+}
+
+// Children are rewritten before their parent, which sees them rewritten
+func TestRewriteIsBottomUp(t *testing.T) {
+	var visited []string
+	Rewrite(HBinary(N("a"), lexer.Plus, "+", HCall(N("f"), N("b"))), func(node Node) Node {
+		switch n := node.(type) {
+		case *Name:
+			visited = append(visited, n.Token.Lexeme)
+		case *Call:
+			visited = append(visited, "call "+n.Callee.(*Name).Token.Lexeme)
+		case *Binary:
+			visited = append(visited, "binary")
+		}
+		return rename("f", "g")(node)
+	})
+
+	expected := []string{"a", "f", "b", "call g", "binary"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("expected the visit order %v, got %v", expected, visited)
+	}
+}
+
+func TestRewriteToNil(t *testing.T) {
+	rewritten := Rewrite(HReturn(N("x")), func(node Node) Node {
+		if _, ok := node.(*Name); ok {
+			return nil
+		}
+		return node
+	})
+	if value := rewritten.(*ReturnStmt).Value; value != nil {
+		t.Errorf("expected the returned value to be removed, got %#v", value)
+	}
+	if RewriteChildren(nil, TransformerFunc(func(node Node) Node { return node })) != nil {
+		t.Errorf("expected nil to be rewritten to nil")
+	}
+}
+
+func TestRewriteWrongType(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "cannot replace *ast.Name with *ast.PassStmt, which does not implement ast.Expr") {
+			t.Errorf("expected a panic for a statement in place of an expression, got %v", r)
+		}
+	}()
+	Rewrite(HExprStmt(N("x")), func(node Node) Node {
+		if _, ok := node.(*Name); ok {
+			return HPass()
+		}
+		return node
+	})
+}
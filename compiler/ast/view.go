@@ -20,6 +20,8 @@ type ViewStmt struct {
 	ReturnType Expr           // Return type annotation (optional)
 	Body       []Stmt         // View body containing Python statements and HTML elements
 	IsAsync    bool           // Whether it's an async view
+	IsLayout   bool           // Whether it's declared with 'layout', whose default slot receives the content of the views using it
+	Layout     Expr           // Layout of 'use layout', a name or a call (optional)
 	Kind       ViewKind       // View kind
 
 	Span lexer.Span
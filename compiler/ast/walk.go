@@ -0,0 +1,92 @@
+package ast
+
+import "reflect"
+
+// Walker walks the nodes of a tree. Walk is called on each node and returns
+// the walker for its children, or nil to skip them; after the children, it
+// is called with nil.
+type Walker interface {
+	Walk(node Node) Walker
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Walk(node Node) Walker {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Walk traverses the tree rooted at node depth-first: it calls w.Walk(node),
+// and unless that returns nil, walks each child of node with the returned
+// walker, then calls its Walk(nil). Like Rewrite, it needs no code per node
+// type; unlike Rewrite, it also visits the nodes held by fields of a concrete
+// type, such as a function's *Name, and those of attributes and other
+// values that are not nodes themselves.
+func Walk(w Walker, node Node) {
+	if node == nil {
+		return
+	}
+	v := reflect.ValueOf(node)
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return
+	}
+	if w = w.Walk(node); w == nil {
+		return
+	}
+	if v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Struct {
+		walkFields(v.Elem(), w)
+	}
+	w.Walk(nil)
+}
+
+// Inspect traverses the tree rooted at node depth-first, calling f on each
+// node and walking its children when f returns true. Each call that returns
+// true is followed by a call f(nil) once the children are walked.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// walkValue walks the nodes v holds
+func walkValue(v reflect.Value, w Walker) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			walkValue(v.Elem(), w)
+		}
+	case reflect.Pointer:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct || v.Elem().Type().PkgPath() != astPackage {
+			return
+		}
+		if node, ok := v.Interface().(Node); ok {
+			Walk(w, node)
+			return
+		}
+		walkValue(v.Elem(), w)
+	case reflect.Struct:
+		if v.Type().PkgPath() != astPackage {
+			return
+		}
+		if v.CanAddr() {
+			if node, ok := v.Addr().Interface().(Node); ok {
+				Walk(w, node)
+				return
+			}
+		}
+		walkFields(v, w)
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			walkValue(v.Index(i), w)
+		}
+	}
+}
+
+// walkFields walks the nodes held by the exported fields of a struct
+func walkFields(v reflect.Value, w Walker) {
+	for i := range v.NumField() {
+		if v.Type().Field(i).IsExported() {
+			walkValue(v.Field(i), w)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	var names []string
+	Inspect(program("x"), func(node Node) bool {
+		if name, ok := node.(*Name); ok {
+			names = append(names, name.Token.Lexeme)
+		}
+		return true
+	})
+
+	// Names in interface fields, dict pairs and fields of a concrete type,
+	// such as the function's and its parameter's, in source order
+	want := []string{"f", "x", "y", "x", "x", "g", "p", "int", "x", "x"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got names %v, want %v", names, want)
+	}
+}
+
+// Returning false skips the children of a node, but not its siblings
+func TestInspectSkipsChildren(t *testing.T) {
+	var visited []string
+	Inspect(program("x"), func(node Node) bool {
+		switch node.(type) {
+		case nil:
+		case *Function:
+			visited = append(visited, "Function")
+			return false
+		case *Name:
+			visited = append(visited, "Name")
+		}
+		return true
+	})
+
+	if want := []string{"Name", "Name", "Name", "Name", "Name", "Function"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("got %v, want %v", visited, want)
+	}
+}
+
+// Each node whose children are walked is followed by a nil, so walkers can
+// track their depth
+func TestWalkDepth(t *testing.T) {
+	depth, maxDepth := 0, 0
+	Inspect(HModule(HExprStmt(HList(HList(N("x"))))), func(node Node) bool {
+		if node == nil {
+			depth--
+			return true
+		}
+		depth++
+		maxDepth = max(maxDepth, depth)
+		return true
+	})
+
+	// Module > ExprStmt > ListExpr > ListExpr > Name
+	if depth != 0 || maxDepth != 5 {
+		t.Errorf("expected to end at depth 0 after reaching 5, got %d and %d", depth, maxDepth)
+	}
+}
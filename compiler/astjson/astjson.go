@@ -0,0 +1,539 @@
+// Package astjson encodes ASTs as JSON and decodes them back, so tools
+// written in other languages can read and produce them.
+//
+// A document is {"version": 1, "root": node}. A node is an object whose
+// "node" member names its Go type in package ast, such as "Binary" or
+// "HTMLElement", followed by its fields in declaration order, named in
+// snake_case: Span becomes "span" and TagName "tag_name". Absent optional
+// nodes and nil lists are null.
+//
+// Spans are {"start": {"line": 1, "column": 1}, "end": {...}}, with the
+// scanner's 1-based lines and columns. Tokens are objects with the token
+// "type" by name, such as "Identifier", its "lexeme", "span", and
+// "literal" when it has one. Enumerations such as the literal type are
+// their integer values.
+//
+// Literal values keep their Go type: strings, booleans and null are JSON
+// values, and numbers and bytes are tagged, as {"int": 1}, {"float": 1.5},
+// {"complex": [0, 2]} and {"bytes": "..."}, the bytes being the characters
+// U+0000 to U+00FF.
+//
+// Version changes whenever the encoding of an existing node changes, and
+// Unmarshal rejects documents of other versions.
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Version is the version of the encoding
+const Version = 1
+
+// Marshal encodes the tree rooted at node as a versioned document
+func Marshal(node ast.Node) ([]byte, error) {
+	var e encoder
+	e.buf.WriteString(`{"version":`)
+	fmt.Fprint(&e.buf, Version)
+	e.buf.WriteString(`,"root":`)
+	if err := e.value(reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+	e.buf.WriteString("}")
+	return e.buf.Bytes(), nil
+}
+
+// MarshalIndent is like Marshal but indents the document
+func MarshalIndent(node ast.Node, indent string) ([]byte, error) {
+	data, err := Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Unmarshal decodes a document written by Marshal
+func Unmarshal(data []byte) (ast.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	version, err := intValue(doc["version"])
+	if err != nil {
+		return nil, fmt.Errorf("version: %w", err)
+	}
+	if version != Version {
+		return nil, fmt.Errorf("unsupported AST JSON version %d, expected %d", version, Version)
+	}
+
+	var root ast.Node
+	if err := decode(reflect.ValueOf(&root).Elem(), doc["root"], "root"); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+var (
+	spanType   = reflect.TypeFor[lexer.Span]()
+	tokenType  = reflect.TypeFor[lexer.Token]()
+	astPackage = reflect.TypeFor[ast.Name]().PkgPath()
+)
+
+// nodeTypes maps the names of the struct types of package ast to their
+// types: those of the nodes visitors visit, the dictionary entries, and
+// those the fields of either refer to
+var nodeTypes = func() map[string]reflect.Type {
+	types := map[string]reflect.Type{}
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || t.PkgPath() != astPackage || types[t.Name()] != nil {
+			return
+		}
+		types[t.Name()] = t
+		for i := range t.NumField() {
+			add(t.Field(i).Type)
+		}
+	}
+
+	visitor := reflect.TypeFor[ast.Visitor]()
+	for i := range visitor.NumMethod() {
+		if m := visitor.Method(i).Type; m.NumIn() == 1 {
+			add(m.In(0))
+		}
+	}
+	add(reflect.TypeFor[ast.KeyValuePair]())
+	add(reflect.TypeFor[ast.DoubleStarredPair]())
+	return types
+}()
+
+// fieldName returns the JSON name of a struct field, its name in snake
+// case, as "tag_name" for TagName and "html_type" for HTMLType
+func fieldName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// ── encoding ─────────────────────────────────────────────────────────
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) json(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.buf.Write(data)
+	return nil
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	switch v.Type() {
+	case spanType:
+		e.span(v.Interface().(lexer.Span))
+		return nil
+	case tokenType:
+		return e.token(v.Interface().(lexer.Token))
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.NumMethod() == 0 {
+			return e.literal(v.Elem().Interface())
+		}
+		return e.value(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		return e.value(v.Elem())
+	case reflect.Struct:
+		return e.node(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		e.buf.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+		return nil
+	case reflect.Bool:
+		return e.json(v.Bool())
+	case reflect.String:
+		return e.json(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.json(v.Int())
+	}
+	return fmt.Errorf("cannot encode a value of type %s", v.Type())
+}
+
+func (e *encoder) node(v reflect.Value) error {
+	t := v.Type()
+	if nodeTypes[t.Name()] != t {
+		return fmt.Errorf("cannot encode unknown node type %s", t)
+	}
+	e.buf.WriteString(`{"node":`)
+	if err := e.json(t.Name()); err != nil {
+		return err
+	}
+	for i := range t.NumField() {
+		e.buf.WriteByte(',')
+		if err := e.json(fieldName(t.Field(i).Name)); err != nil {
+			return err
+		}
+		e.buf.WriteByte(':')
+		if err := e.value(v.Field(i)); err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), t.Field(i).Name, err)
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+func (e *encoder) span(s lexer.Span) {
+	fmt.Fprintf(&e.buf, `{"start":{"line":%d,"column":%d},"end":{"line":%d,"column":%d}}`,
+		s.Start.Line, s.Start.Column, s.End.Line, s.End.Column)
+}
+
+func (e *encoder) token(tok lexer.Token) error {
+	e.buf.WriteString(`{"type":`)
+	if err := e.json(tok.Type.String()); err != nil {
+		return err
+	}
+	e.buf.WriteString(`,"lexeme":`)
+	if err := e.json(tok.Lexeme); err != nil {
+		return err
+	}
+	if tok.Literal != nil {
+		e.buf.WriteString(`,"literal":`)
+		if err := e.literal(tok.Literal); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteString(`,"span":`)
+	e.span(tok.Span)
+	e.buf.WriteByte('}')
+	return nil
+}
+
+func (e *encoder) literal(v any) error {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return e.json(v)
+	case int:
+		return e.json(map[string]int{"int": v})
+	case int64:
+		return e.json(map[string]int64{"int": v})
+	case float64:
+		return e.json(map[string]float64{"float": v})
+	case complex128:
+		return e.json(map[string][2]float64{"complex": {real(v), imag(v)}})
+	case []byte:
+		runes := make([]rune, len(v))
+		for i, b := range v {
+			runes[i] = rune(b)
+		}
+		return e.json(map[string]string{"bytes": string(runes)})
+	}
+	return fmt.Errorf("cannot encode a literal value of type %T", v)
+}
+
+// ── decoding ─────────────────────────────────────────────────────────
+
+var tokenTypes = func() map[string]lexer.TokenType {
+	types := map[string]lexer.TokenType{}
+	for tt := lexer.TokenType(0); tt <= lexer.Illegal; tt++ {
+		types[tt.String()] = tt
+	}
+	return types
+}()
+
+// decode sets dst from the decoded JSON value src; path locates src in
+// the document, for errors
+func decode(dst reflect.Value, src any, path string) error {
+	switch dst.Type() {
+	case spanType:
+		span, err := decodeSpan(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		dst.Set(reflect.ValueOf(span))
+		return nil
+	case tokenType:
+		tok, err := decodeToken(src, path)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(tok))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if src == nil {
+			return nil
+		}
+		if dst.NumMethod() == 0 {
+			v, err := decodeLiteral(src)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			dst.Set(reflect.ValueOf(v))
+			return nil
+		}
+		obj, ok := src.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a node, got %T", path, src)
+		}
+		name, _ := obj["node"].(string)
+		t, ok := nodeTypes[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown node type %q", path, name)
+		}
+		node := reflect.New(t)
+		if !node.Type().Implements(dst.Type()) {
+			return fmt.Errorf("%s: %s does not implement %s", path, name, dst.Type())
+		}
+		if err := decodeNode(node.Elem(), obj, path); err != nil {
+			return err
+		}
+		dst.Set(node)
+		return nil
+	case reflect.Pointer:
+		if src == nil {
+			return nil
+		}
+		ptr := reflect.New(dst.Type().Elem())
+		if err := decode(ptr.Elem(), src, path); err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	case reflect.Struct:
+		obj, ok := src.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a %s, got %T", path, dst.Type().Name(), src)
+		}
+		return decodeNode(dst, obj, path)
+	case reflect.Slice:
+		if src == nil {
+			return nil
+		}
+		items, ok := src.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", path, src)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(slice.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", path, src)
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", path, src)
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := intValue(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		dst.SetInt(n)
+		return nil
+	}
+	return fmt.Errorf("%s: cannot decode a value of type %s", path, dst.Type())
+}
+
+// decodeNode sets the fields of the struct dst from obj. Absent fields
+// stay zero; unknown ones are an error.
+func decodeNode(dst reflect.Value, obj map[string]any, path string) error {
+	t := dst.Type()
+	if name, ok := obj["node"]; ok && name != t.Name() {
+		return fmt.Errorf("%s: expected a %s node, got %v", path, t.Name(), name)
+	}
+	known := map[string]bool{"node": true}
+	for i := range t.NumField() {
+		name := fieldName(t.Field(i).Name)
+		known[name] = true
+		if err := decode(dst.Field(i), obj[name], path+"."+name); err != nil {
+			return err
+		}
+	}
+	for name := range obj {
+		if !known[name] {
+			return fmt.Errorf("%s: unknown field %q of %s", path, name, t.Name())
+		}
+	}
+	return nil
+}
+
+func decodeSpan(src any) (lexer.Span, error) {
+	obj, ok := src.(map[string]any)
+	if !ok {
+		return lexer.Span{}, fmt.Errorf("expected a span, got %T", src)
+	}
+	start, err := decodePosition(obj["start"])
+	if err != nil {
+		return lexer.Span{}, fmt.Errorf("start: %w", err)
+	}
+	end, err := decodePosition(obj["end"])
+	if err != nil {
+		return lexer.Span{}, fmt.Errorf("end: %w", err)
+	}
+	return lexer.Span{Start: start, End: end}, nil
+}
+
+func decodePosition(src any) (lexer.Position, error) {
+	obj, ok := src.(map[string]any)
+	if !ok {
+		return lexer.Position{}, fmt.Errorf("expected a position, got %T", src)
+	}
+	line, err := intValue(obj["line"])
+	if err != nil {
+		return lexer.Position{}, fmt.Errorf("line: %w", err)
+	}
+	column, err := intValue(obj["column"])
+	if err != nil {
+		return lexer.Position{}, fmt.Errorf("column: %w", err)
+	}
+	return lexer.Position{Line: int(line), Column: int(column)}, nil
+}
+
+func decodeToken(src any, path string) (lexer.Token, error) {
+	obj, ok := src.(map[string]any)
+	if !ok {
+		return lexer.Token{}, fmt.Errorf("%s: expected a token, got %T", path, src)
+	}
+	name, _ := obj["type"].(string)
+	tt, ok := tokenTypes[name]
+	if !ok {
+		return lexer.Token{}, fmt.Errorf("%s: unknown token type %q", path, name)
+	}
+	lexeme, ok := obj["lexeme"].(string)
+	if !ok {
+		return lexer.Token{}, fmt.Errorf("%s: expected a lexeme, got %T", path, obj["lexeme"])
+	}
+	literal, err := decodeLiteral(obj["literal"])
+	if err != nil {
+		return lexer.Token{}, fmt.Errorf("%s.literal: %w", path, err)
+	}
+	span, err := decodeSpan(obj["span"])
+	if err != nil {
+		return lexer.Token{}, fmt.Errorf("%s.span: %w", path, err)
+	}
+	return lexer.Token{Type: tt, Lexeme: lexeme, Literal: literal, Span: span}, nil
+}
+
+func decodeLiteral(src any) (any, error) {
+	switch v := src.(type) {
+	case nil, string, bool:
+		return v, nil
+	case map[string]any:
+		if len(v) != 1 {
+			break
+		}
+		switch {
+		case v["int"] != nil:
+			return intValue(v["int"])
+		case v["float"] != nil:
+			return floatValue(v["float"])
+		case v["complex"] != nil:
+			parts, ok := v["complex"].([]any)
+			if !ok || len(parts) != 2 {
+				return nil, fmt.Errorf("expected the two parts of a complex number")
+			}
+			re, err := floatValue(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			im, err := floatValue(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			return complex(re, im), nil
+		case v["bytes"] != nil:
+			s, ok := v["bytes"].(string)
+			if !ok {
+				return nil, fmt.Errorf("expected bytes as a string")
+			}
+			b := make([]byte, 0, len(s))
+			for _, r := range s {
+				if r > 0xff {
+					return nil, fmt.Errorf("bytes hold the character %q, above U+00FF", r)
+				}
+				b = append(b, byte(r))
+			}
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a literal value, got %v", src)
+}
+
+func intValue(src any) (int64, error) {
+	n, ok := src.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected an integer, got %v", src)
+	}
+	return n.Int64()
+}
+
+func floatValue(src any) (float64, error) {
+	n, ok := src.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %v", src)
+	}
+	return n.Float64()
+}
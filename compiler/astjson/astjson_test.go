@@ -0,0 +1,153 @@
+package astjson
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Span":      "span",
+		"TagName":   "tag_name",
+		"IsAsync":   "is_async",
+		"HTMLType":  "html_type",
+		"ReturnsID": "returns_id",
+		"X2Y":       "x2_y",
+	}
+	for in, want := range tests {
+		if got := fieldName(in); got != want {
+			t.Errorf("fieldName(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+// Every struct type of package ast can be encoded and decoded
+func TestEveryNodeTypeIsKnown(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), filepath.Join("..", "ast"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || !ts.Name.IsExported() {
+						continue
+					}
+					if _, ok := ts.Type.(*ast.StructType); ok && nodeTypes[ts.Name.Name] == nil {
+						t.Errorf("node type %s is not known to astjson", ts.Name.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Decoding a document and encoding it again gives the same document, and
+// a tree that prints as the parsed one does
+func TestRoundtripTestdata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "input", "*", "*.psx"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no test inputs found: %v", err)
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		module, errs := compiler.Parse(src)
+		if len(errs) > 0 || module == nil {
+			continue
+		}
+		data, err := Marshal(module)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		decoded, err := Unmarshal(data)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		again, err := Marshal(decoded)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if !bytes.Equal(data, again) {
+			t.Errorf("%s: the decoded tree encodes differently", file)
+		}
+		if compiler.NewASTPrinter("  ").Print(module) != compiler.NewASTPrinter("  ").Print(decoded) {
+			t.Errorf("%s: the decoded tree prints differently", file)
+		}
+	}
+}
+
+// The encoding is pinned by a golden document; a change to it needs a new
+// Version
+func TestGoldenDocument(t *testing.T) {
+	src := "view Card(title: str, n: int = 2):\n    x = b\"\\xff\" if n > 1.5 else 2j\n    label = f\"{n!r:>3}\"\n    <div class=\"card\">\n        <h1>{title}</h1>\n        for i in range(n):\n            <p>{label} {i}</p>\n    </div>\n"
+	module, errs := compiler.Parse([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	data, err := MarshalIndent(module, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	golden := filepath.Join("testdata", "card.json")
+	if os.Getenv("UPDATE_GOLDEN") == "1" {
+		if err := os.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("the encoding differs from %s; if the change is intended, bump Version and regenerate it with UPDATE_GOLDEN=1", golden)
+	}
+	if _, err := Unmarshal(want); err != nil {
+		t.Errorf("cannot decode %s: %v", golden, err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	span := `{"start":{"line":1,"column":1},"end":{"line":1,"column":2}}`
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"other version", `{"version":99,"root":null}`, "unsupported AST JSON version 99"},
+		{"unknown node", `{"version":1,"root":{"node":"Nope"}}`, `unknown node type "Nope"`},
+		{"statement for an expression", `{"version":1,"root":{"node":"ExprStmt","expr":{"node":"PassStmt","span":` + span + `},"span":` + span + `}}`, "PassStmt does not implement ast.Expr"},
+		{"unknown field", `{"version":1,"root":{"node":"PassStmt","span":` + span + `,"extra":1}}`, `unknown field "extra"`},
+		{"unknown token type", `{"version":1,"root":{"node":"Name","token":{"type":"Nope","lexeme":"x","span":` + span + `},"span":` + span + `}}`, `unknown token type "Nope"`},
+		{"bad literal", `{"version":1,"root":{"node":"Literal","token":{"type":"Number","lexeme":"1","span":` + span + `},"value":[1],"type":1,"span":` + span + `}}`, "expected a literal value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
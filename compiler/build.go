@@ -0,0 +1,199 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/depgraph"
+	"github.com/fjvillamarin/topple/compiler/observe"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+// Build is a compilation of a project kept in memory between builds, for
+// long-lived processes such as watch mode or an editor integration; it is
+// the in-memory analogue of BuildCache. After files change on disk,
+// Invalidate them and Recompile: unchanged files keep their ASTs and symbols,
+// and files none of whose dependencies changed keep their resolved and
+// generated output, so only the changed files and their dependents are
+// compiled again.
+//
+// Files that failed to compile are compiled again by every Recompile until
+// they succeed. A Build is safe for concurrent use; builds are serialized.
+type Build struct {
+	mu       sync.Mutex
+	compiler *MultiFileCompiler
+	opts     MultiFileOptions
+	files    map[string]*buildFile     // Absolute file path -> what is kept of it
+	graph    *depgraph.DependencyGraph // Dependency graph, patched by every build
+	affected map[string]bool           // Files the running build compiles again
+	reuse    bool                      // Whether outputs of unaffected files are reused
+}
+
+// buildFile is what a Build keeps of a file between builds
+type buildFile struct {
+	module *ast.Module  // Parsed source
+	deps   []string     // Files it imported when it was compiled, sorted
+	result *layerResult // Output of its last compilation; nil until it succeeds
+}
+
+// NewBuild prepares an in-memory build of the project described by opts;
+// nothing is compiled until Recompile. opts.Cache and opts.Rebuild are
+// ignored, as the Build tracks changes itself. A nil logger discards all
+// output.
+func NewBuild(logger observe.Logger, opts MultiFileOptions) *Build {
+	opts.Cache = nil
+	opts.Rebuild = nil
+	b := &Build{
+		compiler: NewMultiFileCompiler(logger),
+		opts:     opts,
+		files:    make(map[string]*buildFile),
+		// Assets are checked and copied while files are transformed, and
+		// dead-code elimination depends on every file: both need every file
+		// compiled again
+		reuse: opts.Options.Assets == nil && len(opts.EntryPoints) == 0,
+	}
+	b.compiler.build = b
+	return b
+}
+
+// Invalidate marks files as changed on disk, so the next Recompile parses
+// them again and compiles them and every file depending on them. Files added
+// to or deleted from the project are detected without being invalidated.
+func (b *Build) Invalidate(paths []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		delete(b.files, absPath)
+	}
+}
+
+// Recompile compiles the project, reusing what is unchanged since the
+// previous build. The output covers every file, reused or not, like the
+// output of CompileProject.
+func (b *Build) Recompile(ctx context.Context) (*MultiFileOutput, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The graph of the previous build is patched with the files that
+	// changed, so its compilation order is only computed again when
+	// dependencies changed
+	if b.graph == nil {
+		b.graph = depgraph.NewGraph()
+	}
+	b.compiler.depGraph = b.graph
+	b.affected = nil
+	return b.compiler.CompileProject(ctx, b.opts)
+}
+
+// module returns the AST kept for filePath
+func (b *Build) module(filePath string) (*ast.Module, bool) {
+	if b == nil {
+		return nil, false
+	}
+	file, ok := b.files[filePath]
+	if !ok {
+		return nil, false
+	}
+	return file.module, true
+}
+
+// parsed keeps the AST of a file parsed by the running build
+func (b *Build) parsed(filePath string, module *ast.Module) {
+	if b == nil {
+		return
+	}
+	b.files[filePath] = &buildFile{module: module}
+}
+
+// plan decides which files of graph the running build compiles again: files
+// without an output, files whose imports resolve to other files than when
+// they were compiled, and every file depending on them
+func (b *Build) plan(graph *depgraph.DependencyGraph) {
+	if b == nil {
+		return
+	}
+	var changed []string
+	for _, filePath := range graph.GetAllFiles() {
+		file := b.files[filePath]
+		if file == nil || file.result == nil || !slices.Equal(file.deps, sortedDependencies(graph, filePath)) {
+			changed = append(changed, filePath)
+		}
+	}
+
+	b.affected = make(map[string]bool)
+	for filePath := range graph.GetAffected(changed) {
+		b.affected[filePath] = true
+	}
+}
+
+// unchanged reports whether filePath keeps its symbols and output from the
+// previous build
+func (b *Build) unchanged(filePath string) bool {
+	if b == nil || b.affected == nil || b.affected[filePath] {
+		return false
+	}
+	file, ok := b.files[filePath]
+	return ok && file.result != nil
+}
+
+// lookup returns the output of filePath from the previous build if it is
+// still up to date
+func (b *Build) lookup(filePath string) (layerResult, bool) {
+	if b == nil || !b.reuse || !b.unchanged(filePath) {
+		return layerResult{}, false
+	}
+	return *b.files[filePath].result, true
+}
+
+// store keeps the output of a file compiled by the running build, which
+// imports deps
+func (b *Build) store(filePath string, deps []string, result layerResult) {
+	if b == nil {
+		return
+	}
+	file, ok := b.files[filePath]
+	if !ok {
+		return
+	}
+	file.deps = deps
+	file.result = &result
+}
+
+// forget drops the output of a file that failed to compile
+func (b *Build) forget(filePath string) {
+	if b == nil {
+		return
+	}
+	if file, ok := b.files[filePath]; ok {
+		file.result = nil
+	}
+}
+
+// finish drops the files that are no longer part of the project, with
+// their symbols
+func (b *Build) finish(graph *depgraph.DependencyGraph, registry *symbol.Registry) {
+	if b == nil {
+		return
+	}
+	for filePath := range b.files {
+		if !graph.HasFile(filePath) {
+			delete(b.files, filePath)
+			registry.RemoveModule(filePath)
+		}
+	}
+}
+
+// sortedDependencies returns the files filePath imports, sorted
+func sortedDependencies(graph *depgraph.DependencyGraph, filePath string) []string {
+	deps := slices.Clone(graph.GetDependencies(filePath))
+	slices.Sort(deps)
+	return deps
+}
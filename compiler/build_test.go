@@ -0,0 +1,184 @@
+package compiler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+func TestBuild_Recompile(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": `view Button(label: str):
+    <button>{label}</button>
+`,
+		"pages/home.psx": `from components.button import Button
+
+view Home():
+    <Button label="Go" />
+`,
+		"pages/about.psx": `view About():
+    <p>About</p>
+`,
+	})
+	button := filepath.Join(tmpDir, "components/button.psx")
+	home := filepath.Join(tmpDir, "pages/home.psx")
+	about := filepath.Join(tmpDir, "pages/about.psx")
+
+	metrics := observe.NewCounters()
+	opts := MultiFileOptions{RootDir: tmpDir, Files: []string{tmpDir}, Options: Options{Metrics: metrics}}
+	build := NewBuild(nil, opts)
+
+	// recompile runs a build and returns how many files were parsed,
+	// compiled and reused by it
+	var parsed, compiled, reused int64
+	recompile := func() (*MultiFileOutput, error) {
+		t.Helper()
+		nodes, files, cached := metrics.Get(observe.Nodes), metrics.Get(observe.Files), metrics.Get(observe.Cached)
+		output, err := build.Recompile(context.Background())
+		parsed = metrics.Get(observe.Nodes) - nodes
+		compiled = metrics.Get(observe.Files) - files
+		reused = metrics.Get(observe.Cached) - cached
+		return output, err
+	}
+	expect := func(wantCompiled, wantReused int64) {
+		t.Helper()
+		if compiled != wantCompiled {
+			t.Errorf("expected %d files compiled, got %d", wantCompiled, compiled)
+		}
+		if reused != wantReused {
+			t.Errorf("expected %d outputs reused, got %d", wantReused, reused)
+		}
+	}
+	// fresh compiles the project from scratch, for comparison
+	fresh := func() *MultiFileOutput {
+		t.Helper()
+		opts := opts
+		opts.Options.Metrics = nil
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v", err)
+		}
+		return output
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := recompile()
+	if err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+	expect(3, 0)
+
+	second, err := recompile()
+	if err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+	expect(0, 3)
+	if parsed != 0 {
+		t.Errorf("expected nothing to be parsed again, got %d nodes", parsed)
+	}
+	for path, code := range first.CompiledFiles {
+		if string(second.CompiledFiles[path]) != string(code) {
+			t.Errorf("reused output of %s differs from the compiled one", path)
+		}
+	}
+
+	// A changed dependency recompiles its importers from their kept ASTs
+	write(button, "view Button(label: str):\n    <button class=\"btn\">{label}</button>\n")
+	build.Invalidate([]string{button})
+	third, err := recompile()
+	if err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+	expect(2, 1)
+	want := fresh()
+	for _, path := range []string{button, home, about} {
+		if string(third.CompiledFiles[path]) != string(want.CompiledFiles[path]) {
+			t.Errorf("output of %s differs from a fresh compilation:\n%s\nwant:\n%s", path, third.CompiledFiles[path], want.CompiledFiles[path])
+		}
+	}
+
+	// New files are picked up and deleted ones dropped without invalidation
+	contact := filepath.Join(tmpDir, "pages/contact.psx")
+	write(contact, "view Contact():\n    <p>Contact</p>\n")
+	if err := os.Remove(about); err != nil {
+		t.Fatal(err)
+	}
+	fourth, err := recompile()
+	if err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+	expect(1, 2)
+	if _, ok := fourth.CompiledFiles[about]; ok {
+		t.Error("expected the deleted file to be dropped")
+	}
+	if fourth.Registry.HasModule(about) {
+		t.Error("expected the symbols of the deleted file to be dropped")
+	}
+	if _, ok := fourth.CompiledFiles[contact]; !ok {
+		t.Error("expected the new file to be compiled")
+	}
+
+	// Files that fail are compiled again until they succeed
+	write(button, "view Button(label: str):\n    <button>{label}</span>\n")
+	build.Invalidate([]string{button})
+	if _, err := recompile(); err == nil {
+		t.Fatal("expected the broken file to fail")
+	}
+	if _, err := recompile(); err == nil {
+		t.Fatal("expected the broken file to fail again")
+	}
+	write(button, "view Button(label: str):\n    <button>{label}</button>\n")
+	build.Invalidate([]string{button})
+	if _, err := recompile(); err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+	expect(2, 1)
+}
+
+func TestBuild_ResolutionFailureIsRetried(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/card.psx": `view Card():
+    <div>card</div>
+`,
+		"pages/home.psx": `from components.card import Card
+
+view Home():
+    <Card />
+`,
+	})
+	card := filepath.Join(tmpDir, "components/card.psx")
+	build := NewBuild(nil, MultiFileOptions{RootDir: tmpDir, Files: []string{tmpDir}})
+
+	if _, err := build.Recompile(context.Background()); err != nil {
+		t.Fatalf("Recompile failed: %v", err)
+	}
+
+	// Renaming the view breaks the importer, which is not invalidated itself
+	if err := os.WriteFile(card, []byte("view Panel():\n    <div>card</div>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build.Invalidate([]string{card})
+	output, err := build.Recompile(context.Background())
+	if err == nil {
+		t.Fatal("expected the importer to fail resolution")
+	}
+	if len(output.Errors) != 1 || output.Errors[0].File != filepath.Join(tmpDir, "pages/home.psx") {
+		t.Errorf("expected one error in the importer, got %v", output.Errors)
+	}
+
+	if err := os.WriteFile(card, []byte("view Card():\n    <div>card</div>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	build.Invalidate([]string{card})
+	if _, err := build.Recompile(context.Background()); err != nil {
+		t.Fatalf("Recompile failed after the fix: %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/codegen"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/transformers"
+)
+
+const (
+	// BuildCacheDirName is the build cache directory created in the project root
+	BuildCacheDirName = ".topple-cache"
+
+	// buildCacheFormat is bumped when the cache layout changes
+	buildCacheFormat = 2
+
+	buildCacheIndex   = "index.json"
+	buildCacheObjects = "objects"
+)
+
+// BuildCache remembers the generated code of each file of a project so
+// unchanged files are not compiled again. An output is reused when its key
+// matches: the key covers the compiler version and options, the file's
+// content hash and the keys of the files it imports, so a change anywhere in
+// a file's dependencies invalidates it and everything downstream.
+//
+// A nil *BuildCache is valid and caches nothing.
+type BuildCache struct {
+	dir         string
+	version     string
+	fingerprint string
+	entries     map[string]*buildCacheEntry
+
+	hashes map[string]string // File path -> content hash, "" if unreadable
+	keys   map[string]string // File path -> current key, "" if not computable
+}
+
+// buildCacheIndexFile is the on-disk index of a BuildCache
+type buildCacheIndexFile struct {
+	Format   int                         `json:"format"`
+	Compiler string                      `json:"compiler"`
+	Entries  map[string]*buildCacheEntry `json:"entries"`
+}
+
+// buildCacheEntry records the last successful compilation of a file
+type buildCacheEntry struct {
+	Hash     string          `json:"hash"`               // Content hash of the file
+	Key      string          `json:"key"`                // Key the output is stored under
+	Deps     []string        `json:"deps,omitempty"`     // Files the file imported
+	Warnings []cachedWarning `json:"warnings,omitempty"` // Warnings the compilation reported
+}
+
+// cachedWarning is a warning of a cached compilation, reported again when
+// its output is reused
+type cachedWarning struct {
+	Stage   string                  `json:"stage"`
+	Message string                  `json:"message"`
+	Details *diagnostics.Diagnostic `json:"details"`
+}
+
+func (w *cachedWarning) Error() string {
+	return w.Message
+}
+
+func (w *cachedWarning) Diagnostic() *diagnostics.Diagnostic {
+	return w.Details
+}
+
+// OpenBuildCache loads the build cache in dir for the given compiler
+// version. A missing or unreadable cache, or one written by another compiler
+// version, starts out empty.
+func OpenBuildCache(dir, version string) *BuildCache {
+	cache := &BuildCache{
+		dir:     dir,
+		version: version,
+		entries: make(map[string]*buildCacheEntry),
+		hashes:  make(map[string]string),
+		keys:    make(map[string]string),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, buildCacheIndex))
+	if err != nil {
+		return cache
+	}
+	var index buildCacheIndexFile
+	if json.Unmarshal(data, &index) != nil || index.Format != buildCacheFormat || index.Compiler != version {
+		return cache
+	}
+	for path, entry := range index.Entries {
+		if entry != nil {
+			cache.entries[path] = entry
+		}
+	}
+	return cache
+}
+
+// buildCacheOptions are the fields of Options that change the generated
+// code. Their JSON encoding is part of every cache key, so it must not depend
+// on pointers or anything else that differs between runs.
+type buildCacheOptions struct {
+	HTMLComments       transformers.HTMLCommentMode
+	Elements           transformers.ElementPolicy
+	Defaults           transformers.AttributeDefaults
+	EarlyReturns       transformers.EarlyReturnMode
+	Markdown           bool
+	PreserveWhitespace bool
+	Markers            bool
+	LineDirectives     bool
+	StrictProps        bool
+	SkipHTMLCheck      bool
+	Naming             resolver.NamingConventions
+	Imports            module.ImportPolicy
+	RuntimeAPI         transformers.RuntimeAPI
+	RuntimeModule      string
+	Target             codegen.Target
+	Header             Header
+	OutputDir          string
+}
+
+// buildCacheIgnoredOptions are the fields of Options left out of the cache
+// key: sinks that do not affect the output, and options that make
+// compilations bypass the cache. A new field of Options must be added to
+// buildCacheOptions or here.
+var buildCacheIgnoredOptions = []string{"Assets", "SourceMaps", "Stubs", "Metrics", "Timings", "Events"}
+
+// buildCacheFingerprint encodes the options of opts that change the
+// generated code
+func buildCacheFingerprint(opts Options) string {
+	data, err := json.Marshal(buildCacheOptions{
+		HTMLComments:       opts.HTMLComments,
+		Elements:           opts.Elements,
+		Defaults:           opts.Defaults,
+		EarlyReturns:       opts.EarlyReturns,
+		Markdown:           opts.Markdown,
+		PreserveWhitespace: opts.PreserveWhitespace,
+		Markers:            opts.Markers,
+		LineDirectives:     opts.LineDirectives,
+		StrictProps:        opts.StrictProps,
+		SkipHTMLCheck:      opts.SkipHTMLCheck,
+		Naming:             opts.Naming,
+		Imports:            opts.Imports,
+		RuntimeAPI:         opts.RuntimeAPI,
+		RuntimeModule:      opts.RuntimeModule,
+		Target:             opts.Target,
+		Header:             opts.Header,
+		OutputDir:          opts.OutputDir,
+	})
+	if err != nil {
+		// The options are plain values; this cannot happen
+		panic(fmt.Sprintf("cannot encode build cache options: %v", err))
+	}
+	return string(data)
+}
+
+// begin prepares the cache for a compilation with the given options
+func (b *BuildCache) begin(opts Options) {
+	if b == nil {
+		return
+	}
+	b.fingerprint = buildCacheFingerprint(opts)
+
+	// Files may have changed since a previous compilation
+	b.hashes = make(map[string]string)
+	b.keys = make(map[string]string)
+}
+
+// lookup returns the cached output of filePath, with the warnings its
+// compilation reported, if it is still up to date
+func (b *BuildCache) lookup(filePath string) (layerResult, bool) {
+	if b == nil {
+		return layerResult{}, false
+	}
+	entry, ok := b.entries[filePath]
+	if !ok {
+		return layerResult{}, false
+	}
+	key := b.key(filePath, make(map[string]bool))
+	if key == "" || key != entry.Key {
+		return layerResult{}, false
+	}
+	code, err := os.ReadFile(b.objectPath(key))
+	if err != nil {
+		return layerResult{}, false
+	}
+	result := layerResult{code: code}
+	for i := range entry.Warnings {
+		warning := entry.Warnings[i]
+		result.warnings = append(result.warnings, &warning)
+	}
+	return result, true
+}
+
+// store records the output and warnings of a successful compilation of
+// filePath, which imports deps. Dependencies must be stored before the files
+// importing them; when one is not, the file is still recorded without
+// output, so that files importing each other through deferred imports can
+// be stored in turn.
+func (b *BuildCache) store(filePath string, deps []string, result layerResult) error {
+	if b == nil {
+		return nil
+	}
+	hash := b.hash(filePath)
+	if hash == "" {
+		return fmt.Errorf("cannot hash %s", filePath)
+	}
+
+	deps = append([]string(nil), deps...)
+	sort.Strings(deps)
+	var warnings []cachedWarning
+	for _, warning := range result.warnings {
+		warnings = append(warnings, cachedWarning{Stage: warningStage(warning), Message: warning.Error(), Details: diagnostics.From(warning)})
+	}
+	b.entries[filePath] = &buildCacheEntry{Hash: hash, Deps: deps, Warnings: warnings}
+	delete(b.keys, filePath)
+
+	key := b.key(filePath, make(map[string]bool))
+	if key == "" {
+		return fmt.Errorf("a dependency of %s is not cached", filePath)
+	}
+	b.entries[filePath].Key = key
+
+	if err := os.MkdirAll(filepath.Join(b.dir, buildCacheObjects), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(b.objectPath(key), result.code, 0o644)
+}
+
+// track records the content and dependencies of filePath, a shared library
+// module compiled elsewhere, so the files importing it can be stored and are
+// invalidated when it changes. Dependencies must be tracked or stored first.
+func (b *BuildCache) track(filePath string, deps []string) {
+	if b == nil {
+		return
+	}
+	deps = append([]string(nil), deps...)
+	sort.Strings(deps)
+	b.entries[filePath] = &buildCacheEntry{Hash: b.hash(filePath), Deps: deps}
+	delete(b.keys, filePath)
+	b.entries[filePath].Key = b.key(filePath, make(map[string]bool))
+}
+
+// deps returns the recorded dependencies of filePath
+func (b *BuildCache) deps(filePath string) []string {
+	if b == nil || b.entries[filePath] == nil {
+		return nil
+	}
+	return b.entries[filePath].Deps
+}
+
+// forget drops the entry of a file that failed to compile
+func (b *BuildCache) forget(filePath string) {
+	if b == nil {
+		return
+	}
+	delete(b.entries, filePath)
+	delete(b.keys, filePath)
+}
+
+// Save writes the index and removes outputs no entry refers to anymore.
+// Entries of files that no longer exist are dropped.
+func (b *BuildCache) Save() error {
+	if b == nil {
+		return nil
+	}
+
+	live := make(map[string]bool)
+	for path, entry := range b.entries {
+		if _, err := os.Stat(path); err != nil || entry.Key == "" {
+			delete(b.entries, path)
+			continue
+		}
+		live[entry.Key+".py"] = true
+	}
+
+	data, err := json.MarshalIndent(buildCacheIndexFile{
+		Format:   buildCacheFormat,
+		Compiler: b.version,
+		Entries:  b.entries,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding build cache index: %w", err)
+	}
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return fmt.Errorf("error creating build cache: %w", err)
+	}
+
+	// Write the index atomically so an interrupted save leaves the old one
+	tmp, err := os.CreateTemp(b.dir, buildCacheIndex+".*")
+	if err != nil {
+		return fmt.Errorf("error writing build cache index: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing build cache index: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), filepath.Join(b.dir, buildCacheIndex)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing build cache index: %w", err)
+	}
+
+	objects, _ := os.ReadDir(filepath.Join(b.dir, buildCacheObjects))
+	for _, object := range objects {
+		if !live[object.Name()] {
+			os.Remove(filepath.Join(b.dir, buildCacheObjects, object.Name()))
+		}
+	}
+	return nil
+}
+
+// key computes the current key of filePath from its content and the keys of
+// its recorded dependencies. It returns "" when the file or one of its
+// dependencies cannot be read or has no entry.
+func (b *BuildCache) key(filePath string, visiting map[string]bool) string {
+	key, _ := b.keyOf(filePath, visiting)
+	return key
+}
+
+// keyOf computes the key of filePath. A dependency that imports filePath
+// back, which deferred imports allow, contributes its content hash instead
+// of its key; keys computed inside such a cycle depend on where it was
+// entered, so they are reported as cyclic and not memoized.
+func (b *BuildCache) keyOf(filePath string, visiting map[string]bool) (string, bool) {
+	if key, ok := b.keys[filePath]; ok {
+		return key, false
+	}
+	entry, ok := b.entries[filePath]
+	if !ok {
+		return "", false
+	}
+	hash := b.hash(filePath)
+	if hash == "" {
+		return "", false
+	}
+	if visiting[filePath] {
+		return hash, true
+	}
+
+	visiting[filePath] = true
+	defer delete(visiting, filePath)
+
+	cyclic := false
+	var material strings.Builder
+	fmt.Fprintf(&material, "%s\n%s\n%s\n%s\n", b.version, b.fingerprint, filePath, hash)
+	for _, dep := range entry.Deps {
+		depKey, depCyclic := b.keyOf(dep, visiting)
+		if depKey == "" {
+			// Not memoized: the dependency may be stored later in the build
+			return "", false
+		}
+		cyclic = cyclic || depCyclic
+		fmt.Fprintf(&material, "%s %s\n", dep, depKey)
+	}
+
+	sum := sha256.Sum256([]byte(material.String()))
+	key := hex.EncodeToString(sum[:])
+	if !cyclic {
+		b.keys[filePath] = key
+	}
+	return key, cyclic
+}
+
+// hash returns the content hash of filePath, or "" if it cannot be read
+func (b *BuildCache) hash(filePath string) string {
+	if hash, ok := b.hashes[filePath]; ok {
+		return hash
+	}
+	hash := ""
+	if content, err := os.ReadFile(filePath); err == nil {
+		sum := sha256.Sum256(content)
+		hash = hex.EncodeToString(sum[:])
+	}
+	b.hashes[filePath] = hash
+	return hash
+}
+
+func (b *BuildCache) objectPath(key string) string {
+	return filepath.Join(b.dir, buildCacheObjects, key+".py")
+}
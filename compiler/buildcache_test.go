@@ -0,0 +1,288 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/assets"
+	"github.com/fjvillamarin/topple/compiler/codegen"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+func TestBuildCache(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": `view Button(label: str):
+    <button>{label}</button>
+`,
+		"pages/home.psx": `from components.button import Button
+
+view Home():
+    <Button label="Go" />
+`,
+		"pages/about.psx": `view About():
+    <p>About</p>
+`,
+	})
+	cacheDir := filepath.Join(tmpDir, BuildCacheDirName)
+	button := filepath.Join(tmpDir, "components/button.psx")
+	home := filepath.Join(tmpDir, "pages/home.psx")
+	about := filepath.Join(tmpDir, "pages/about.psx")
+
+	// compile runs a build with a freshly opened cache and returns its counters
+	compile := func(version string, options Options) (*MultiFileOutput, *observe.Counters) {
+		t.Helper()
+		metrics := observe.NewCounters()
+		options.Metrics = metrics
+		cache := OpenBuildCache(cacheDir, version)
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: options,
+			Cache:   cache,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if len(output.CompiledFiles) != 3 {
+			t.Fatalf("expected 3 outputs, got %d", len(output.CompiledFiles))
+		}
+		return output, metrics
+	}
+	expect := func(metrics *observe.Counters, compiled, cached int64) {
+		t.Helper()
+		if got := metrics.Get(observe.Files); got != compiled {
+			t.Errorf("expected %d files compiled, got %d", compiled, got)
+		}
+		if got := metrics.Get(observe.Cached); got != cached {
+			t.Errorf("expected %d outputs from the cache, got %d", cached, got)
+		}
+	}
+
+	first, metrics := compile("1.0", Options{})
+	expect(metrics, 3, 0)
+
+	second, metrics := compile("1.0", Options{})
+	expect(metrics, 0, 3)
+	for path, code := range first.CompiledFiles {
+		if string(second.CompiledFiles[path]) != string(code) {
+			t.Errorf("cached output of %s differs from the compiled one", path)
+		}
+	}
+
+	// A changed dependency invalidates its importers, not unrelated files
+	if err := os.WriteFile(button, []byte("view Button(label: str):\n    <button class=\"btn\">{label}</button>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third, metrics := compile("1.0", Options{})
+	expect(metrics, 2, 1)
+	if string(third.CompiledFiles[about]) != string(first.CompiledFiles[about]) {
+		t.Errorf("expected the unrelated file to come from the cache")
+	}
+	if string(third.CompiledFiles[home]) != string(first.CompiledFiles[home]) {
+		t.Errorf("expected the importer's output to be unchanged")
+	}
+
+	// New compiler versions and different options start over
+	_, metrics = compile("1.1", Options{})
+	expect(metrics, 3, 0)
+	_, metrics = compile("1.1", Options{Markdown: true})
+	expect(metrics, 3, 0)
+}
+
+func TestBuildCacheSearchPathLibrary(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"app/home.psx": `from ui.badge import Badge
+
+view Home():
+    <Badge text="hi" />
+`,
+		"shared/ui/badge.psx": `view Badge(text: str):
+    <span>{text}</span>
+`,
+	})
+	appDir := filepath.Join(tmpDir, "app")
+	cacheDir := filepath.Join(appDir, BuildCacheDirName)
+
+	compile := func() *observe.Counters {
+		t.Helper()
+		metrics := observe.NewCounters()
+		cache := OpenBuildCache(cacheDir, "1.0")
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir:     appDir,
+			Files:       []string{appDir},
+			SearchPaths: []string{filepath.Join(tmpDir, "shared")},
+			Options:     Options{Metrics: metrics},
+			Cache:       cache,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return metrics
+	}
+
+	compile()
+	if metrics := compile(); metrics.Get(observe.Cached) != 1 {
+		t.Errorf("expected the importer of a library to be cached, got %d", metrics.Get(observe.Cached))
+	}
+
+	// A changed library module invalidates its importers
+	badge := filepath.Join(tmpDir, "shared/ui/badge.psx")
+	if err := os.WriteFile(badge, []byte("view Badge(text: str):\n    <b>{text}</b>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if metrics := compile(); metrics.Get(observe.Files) != 1 || metrics.Get(observe.Cached) != 0 {
+		t.Errorf("expected the importer to be recompiled, got %d compiled and %d cached", metrics.Get(observe.Files), metrics.Get(observe.Cached))
+	}
+}
+
+func TestBuildCacheDeferredCycle(t *testing.T) {
+	// b.psx imports a.psx back inside a function
+	tmpDir := setupTestFiles(t, map[string]string{
+		"a.psx": `import b
+
+def func_a():
+    return b.func_b()
+`,
+		"b.psx": `def func_b():
+    import a
+    return a.func_a
+`,
+	})
+	cacheDir := filepath.Join(tmpDir, BuildCacheDirName)
+
+	compile := func() *observe.Counters {
+		t.Helper()
+		metrics := observe.NewCounters()
+		cache := OpenBuildCache(cacheDir, "1.0")
+		_, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{Metrics: metrics},
+			Cache:   cache,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return metrics
+	}
+
+	compile()
+	if metrics := compile(); metrics.Get(observe.Cached) != 2 {
+		t.Errorf("expected both files of the cycle to be cached, got %d", metrics.Get(observe.Cached))
+	}
+
+	// A change to either file invalidates both
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.psx"), []byte("import b\n\ndef func_a():\n    return b.func_b() + 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if metrics := compile(); metrics.Get(observe.Files) != 2 {
+		t.Errorf("expected both files to be recompiled, got %d", metrics.Get(observe.Files))
+	}
+	if metrics := compile(); metrics.Get(observe.Cached) != 2 {
+		t.Errorf("expected both files to be cached again, got %d", metrics.Get(observe.Cached))
+	}
+}
+
+// Every option either changes the cache key or is deliberately left out
+func TestBuildCacheReplaysWarnings(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components.psx": `@deprecated("use Card")
+view Panel(title: str):
+    <p><div>{title}</div></p>
+`,
+		"page.psx": `from components import Panel
+
+view Page():
+    <Panel title="Home" />
+`,
+	})
+	cacheDir := filepath.Join(tmpDir, BuildCacheDirName)
+
+	// compile runs a build with a freshly opened cache and describes its
+	// warnings by stage, code and message
+	compile := func() ([]string, int64) {
+		t.Helper()
+		metrics := observe.NewCounters()
+		cache := OpenBuildCache(cacheDir, "v1")
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{Metrics: metrics},
+			Cache:   cache,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v", err)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		var warnings []string
+		for _, warning := range output.Warnings {
+			warnings = append(warnings, fmt.Sprintf("%s %s %s", warning.Stage, diagnostics.From(warning).Code, warning.Error()))
+		}
+		sort.Strings(warnings)
+		return warnings, metrics.Get(observe.Cached)
+	}
+
+	first, cached := compile()
+	if len(first) != 3 || cached != 0 {
+		t.Fatalf("expected three warnings from a fresh build, got %d cached files and:\n%s", cached, strings.Join(first, "\n"))
+	}
+	second, cached := compile()
+	if cached != 2 {
+		t.Fatalf("expected both files from the cache, got %d", cached)
+	}
+	if !slices.Equal(first, second) {
+		t.Errorf("expected cached files to report the same warnings:\n%s\ngot:\n%s", strings.Join(first, "\n"), strings.Join(second, "\n"))
+	}
+}
+
+func TestBuildCacheOptionsCoverOptions(t *testing.T) {
+	keyed := reflect.TypeFor[buildCacheOptions]()
+	options := reflect.TypeFor[Options]()
+	for i := range options.NumField() {
+		field := options.Field(i)
+		if _, ok := keyed.FieldByName(field.Name); !ok && !slices.Contains(buildCacheIgnoredOptions, field.Name) {
+			t.Errorf("Options.%s is neither part of the build cache key nor in buildCacheIgnoredOptions", field.Name)
+		}
+	}
+	for _, name := range buildCacheIgnoredOptions {
+		if _, ok := options.FieldByName(name); !ok {
+			t.Errorf("buildCacheIgnoredOptions names %s, which is not a field of Options", name)
+		}
+	}
+}
+
+// The cache key does not depend on pointers that differ between runs
+func TestBuildCacheFingerprintIsStable(t *testing.T) {
+	opts := Options{Markdown: true, Target: codegen.Py310}
+	fingerprint := buildCacheFingerprint(opts)
+
+	opts.Assets = assets.NewResolver(assets.Config{})
+	opts.Metrics = observe.NewCounters()
+	if got := buildCacheFingerprint(opts); got != fingerprint {
+		t.Errorf("expected the fingerprint to ignore sinks and assets, got %s and %s", fingerprint, got)
+	}
+
+	opts.Markdown = false
+	if buildCacheFingerprint(opts) == fingerprint {
+		t.Errorf("expected a changed option to change the fingerprint")
+	}
+}
@@ -1,12 +1,24 @@
 package codegen
 
 import (
+	"context"
 	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
 	"strings"
+	"unicode/utf8"
 )
 
 type CodeGenerator struct {
+	// LineDirectives names the source file in '# line: file:N' comments
+	// written before statements that start a new source line; when empty,
+	// none are written
+	LineDirectives string
+
+	// Target is the Python version the code is written for; see Lower for
+	// the constructs a module must not hold for it
+	Target Target
+
 	builder strings.Builder
 	indent  int
 
@@ -14,6 +26,22 @@ type CodeGenerator struct {
 	needsNewline bool
 	atLineStart  bool
 
+	// Output position, for the source map: the current line and the
+	// characters written on it, and where the last character written is
+	line, column int
+	last         sourcemap.Position
+	mappings     []sourcemap.Mapping
+
+	// Source line named by the last line directive
+	directiveLine int
+
+	// Number of f-string replacement fields the output is inside
+	fields int
+
+	// Cancellation of GenerateContext, checked before each statement
+	ctx context.Context
+	err error
+
 	ast.Visitor
 }
 
@@ -30,23 +58,122 @@ func (cg *CodeGenerator) Generate(node ast.Node) string {
 	cg.indent = 0
 	cg.needsNewline = false
 	cg.atLineStart = true
+	cg.line, cg.column = 1, 0
+	cg.last = sourcemap.Position{}
+	cg.mappings = nil
+	cg.directiveLine = 0
+	cg.fields = 0
 
 	node.Accept(cg)
 	return cg.builder.String()
 }
 
+// GenerateContext generates Python code like Generate but stops before the
+// next statement once ctx is cancelled, returning ctx's error. It also
+// returns an error when the module holds code the Target cannot express.
+func (cg *CodeGenerator) GenerateContext(ctx context.Context, node ast.Node) (string, error) {
+	cg.ctx, cg.err = ctx, nil
+	defer func() { cg.ctx = nil }()
+
+	code := cg.Generate(node)
+	if cg.err != nil {
+		return "", cg.err
+	}
+	return code, nil
+}
+
+// SourceMap returns the mappings of the statements written by the last
+// Generate that carry a source span, ordered by generated start
+func (cg *CodeGenerator) SourceMap() *sourcemap.Map {
+	return sourcemap.New(cg.mappings)
+}
+
 // Helper methods for formatting
 func (cg *CodeGenerator) write(s string) {
 	if cg.atLineStart && cg.indent > 0 && s != "\n" {
 		cg.builder.WriteString(strings.Repeat("    ", cg.indent))
+		cg.column += 4 * cg.indent
 		cg.atLineStart = false
 	}
 	cg.builder.WriteString(s)
+	cg.advance(s)
 	if s == "\n" {
 		cg.atLineStart = true
 	}
 }
 
+// advance moves the output position past s
+func (cg *CodeGenerator) advance(s string) {
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			cg.advanceLine(s)
+			return
+		}
+		cg.advanceLine(s[:i])
+		cg.line++
+		cg.column = 0
+		s = s[i+1:]
+	}
+}
+
+func (cg *CodeGenerator) advanceLine(s string) {
+	if s != "" {
+		cg.column += utf8.RuneCountInString(s)
+		cg.last = sourcemap.Position{Line: cg.line, Column: cg.column}
+	}
+}
+
+// position returns where the next statement starts, after any pending indentation
+func (cg *CodeGenerator) position() sourcemap.Position {
+	if cg.atLineStart {
+		return sourcemap.Position{Line: cg.line, Column: 4*cg.indent + 1}
+	}
+	return sourcemap.Position{Line: cg.line, Column: cg.column + 1}
+}
+
+// writeMapped writes a statement, mapping its output to its source span.
+// Statements synthesized without a span are covered by their parent.
+func (cg *CodeGenerator) writeMapped(stmt ast.Stmt) {
+	span := stmt.GetSpan()
+	if span.Start.Line == 0 {
+		stmt.Accept(cg)
+		return
+	}
+
+	cg.writeLineDirective(span.Start.Line)
+
+	index := len(cg.mappings)
+	start := cg.position()
+	cg.mappings = append(cg.mappings, sourcemap.Mapping{
+		Generated: sourcemap.Range{Start: start},
+		Original: sourcemap.Range{
+			Start: sourcemap.Position{Line: span.Start.Line, Column: span.Start.Column},
+			End:   sourcemap.Position{Line: span.End.Line, Column: span.End.Column},
+		},
+	})
+	stmt.Accept(cg)
+
+	end := cg.last
+	if end.Line < start.Line || end.Line == start.Line && end.Column < start.Column {
+		// Nothing was written
+		cg.mappings = append(cg.mappings[:index], cg.mappings[index+1:]...)
+		return
+	}
+	cg.mappings[index].Generated.End = end
+}
+
+// writeLineDirective names the source line of a statement about to be
+// written on its own line, unless the last directive already named it
+func (cg *CodeGenerator) writeLineDirective(line int) {
+	if cg.LineDirectives == "" || !cg.atLineStart || line == cg.directiveLine {
+		return
+	}
+	cg.writef("# line: %s:%d", cg.LineDirectives, line)
+	cg.newline()
+	cg.directiveLine = line
+}
+
 func (cg *CodeGenerator) writef(format string, args ...interface{}) {
 	cg.write(fmt.Sprintf(format, args...))
 }
@@ -63,9 +190,31 @@ func (cg *CodeGenerator) decreaseIndent() {
 	cg.indent--
 }
 
+// fail records an error of the generation; the first one is returned
+func (cg *CodeGenerator) fail(err error) {
+	if cg.err == nil {
+		cg.err = err
+	}
+}
+
 func (cg *CodeGenerator) writeStmts(stmts []ast.Stmt) {
+	onlyComments := len(stmts) > 0
 	for _, stmt := range stmts {
-		stmt.Accept(cg)
+		if cg.ctx != nil && cg.err == nil {
+			cg.err = cg.ctx.Err()
+		}
+		if cg.err != nil {
+			return
+		}
+		cg.writeMapped(stmt)
+		if _, ok := stmt.(*ast.HTMLComment); !ok {
+			onlyComments = false
+		}
+	}
+	// A block holding nothing but comments is not valid Python
+	if onlyComments {
+		cg.write("pass")
+		cg.newline()
 	}
 }
 
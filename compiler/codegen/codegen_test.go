@@ -3,6 +3,8 @@ package codegen
 import (
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
 	"os"
 	"path/filepath"
 	"strings"
@@ -34,6 +36,13 @@ func TestCodeGeneration(t *testing.T) {
 				return &ast.Literal{Value: float64(3.14), Type: ast.LiteralTypeNumber}
 			},
 		},
+		{
+			category: "literals",
+			name:     "float_whole",
+			buildAST: func() ast.Node {
+				return &ast.Literal{Value: float64(2), Type: ast.LiteralTypeNumber}
+			},
+		},
 		{
 			category: "literals",
 			name:     "string",
@@ -233,6 +242,22 @@ func TestCodeGeneration(t *testing.T) {
 				}
 			},
 		},
+		{
+			category: "expressions",
+			name:     "dict_unpack_ternary",
+			buildAST: func() ast.Node {
+				return &ast.DictExpr{
+					Pairs: []ast.DictPair{
+						&ast.DoubleStarredPair{Expr: &ast.Name{Token: lexer.Token{Lexeme: "base"}}},
+						&ast.DoubleStarredPair{Expr: &ast.TernaryExpr{
+							Condition: &ast.Name{Token: lexer.Token{Lexeme: "condition"}},
+							TrueExpr:  &ast.Name{Token: lexer.Token{Lexeme: "extra"}},
+							FalseExpr: &ast.DictExpr{},
+						}},
+					},
+				}
+			},
+		},
 
 		// Statements
 		{
@@ -575,6 +600,96 @@ func TestCodeGeneration(t *testing.T) {
 }
 
 // normalizeOutput normalizes the output by trimming trailing whitespace
+func TestSourceMap(t *testing.T) {
+	src := "import os\n\ndef f(items):\n    for item in items:\n        print(item)\n    return len(items)\n"
+	module, errs := parser.NewParser(lexer.NewScanner([]byte(src)).ScanTokens()).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	// A statement synthesized without a span is covered by its parent
+	function := module.Body[1].(*ast.Function)
+	function.Body = append(function.Body, &ast.PassStmt{})
+
+	generator := NewCodeGenerator()
+	code := generator.Generate(module)
+	expectedCode := "import os\ndef f(items):\n    for item in items:\n        print(item)\n    return len(items)\n    pass\n\n"
+	if code != expectedCode {
+		t.Fatalf("unexpected code:\n%s", code)
+	}
+
+	pos := func(line, column int) sourcemap.Position { return sourcemap.Position{Line: line, Column: column} }
+	expected := []sourcemap.Range{
+		{Start: pos(1, 1), End: pos(1, 9)},  // import os
+		{Start: pos(2, 1), End: pos(6, 8)},  // def f, through the synthesized pass
+		{Start: pos(3, 5), End: pos(4, 19)}, // for
+		{Start: pos(4, 9), End: pos(4, 19)}, // print(item)
+		{Start: pos(5, 5), End: pos(5, 21)}, // return
+	}
+	mappings := generator.SourceMap().Mappings
+	if len(mappings) != len(expected) {
+		t.Fatalf("expected %d mappings, got %+v", len(expected), mappings)
+	}
+	for i, mapping := range mappings {
+		if mapping.Generated != expected[i] {
+			t.Errorf("mapping %d: generated %+v, want %+v", i, mapping.Generated, expected[i])
+		}
+	}
+	if original := mappings[4].Original.Start; original != pos(6, 5) {
+		t.Errorf("expected the return to map to 6:5, got %+v", original)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	src := "import os; import sys\n\ndef f(items):\n    for item in items:\n        print(item)\n    return len(items)\n"
+	module, errs := parser.NewParser(lexer.NewScanner([]byte(src)).ScanTokens()).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	generator := NewCodeGenerator()
+	generator.LineDirectives = "app.psx"
+	code := generator.Generate(module)
+	expectedCode := `# line: app.psx:1
+import os
+import sys
+# line: app.psx:3
+def f(items):
+    # line: app.psx:4
+    for item in items:
+        # line: app.psx:5
+        print(item)
+    # line: app.psx:6
+    return len(items)
+
+`
+	if code != expectedCode {
+		t.Fatalf("unexpected code:\n%s", code)
+	}
+
+	// Mappings point past the directives
+	mappings := generator.SourceMap().Mappings
+	if start := mappings[len(mappings)-1].Generated.Start; start != (sourcemap.Position{Line: 11, Column: 5}) {
+		t.Errorf("expected the return mapped at 11:5, got %+v", start)
+	}
+
+	// Without a file name, no directives are written
+	if code := NewCodeGenerator().Generate(module); strings.Contains(code, "# line:") {
+		t.Errorf("expected no line directives, got:\n%s", code)
+	}
+}
+
+func TestParameterMarkers(t *testing.T) {
+	src := "def f(a, /, b, *, c=..., **d):\n    return a[..., 0]\n"
+	module, errs := parser.NewParser(lexer.NewScanner([]byte(src)).ScanTokens()).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if code := NewCodeGenerator().Generate(module); code != src+"\n" {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+}
+
 func normalizeOutput(s string) string {
 	lines := strings.Split(s, "\n")
 	for i := range lines {
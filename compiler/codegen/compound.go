@@ -214,11 +214,23 @@ func (cg *CodeGenerator) VisitFunction(f *ast.Function) ast.Visitor {
 }
 
 func (cg *CodeGenerator) VisitParameterList(p *ast.ParameterList) ast.Visitor {
+	starred := false // Whether a * or *args was written
 	for i, param := range p.Parameters {
 		if i > 0 {
 			cg.write(", ")
 		}
+		if param.IsKeywordOnly && !starred {
+			// The bare * the parser left out of the parameters
+			cg.write("*, ")
+			starred = true
+		}
 		param.Accept(cg)
+		if param.IsStar {
+			starred = true
+		}
+		if p.HasSlash && i == p.SlashIndex {
+			cg.write(", /")
+		}
 	}
 	return cg
 }
@@ -3,7 +3,9 @@ package codegen
 import (
 	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
 	"strconv"
+	"strings"
 )
 
 // Expression visitors
@@ -17,12 +19,11 @@ func (cg *CodeGenerator) VisitLiteral(l *ast.Literal) ast.Visitor {
 	// Handle the case where literal type might be wrong - check actual value type
 	switch v := l.Value.(type) {
 	case string:
-		if l.Type == ast.LiteralTypeString {
-			// Check if this is a raw string by looking at the lexeme
-			lexeme := l.Token.Lexeme
-			if len(lexeme) > 0 && (lexeme[0] == 'r' || lexeme[0] == 'R') {
-				// Raw string - output the lexeme directly (includes 'r' prefix)
-				cg.write(lexeme)
+		if cg.nestedQuotesLimited() {
+			cg.writeFieldString(v, l.Span)
+		} else if l.Type == ast.LiteralTypeString {
+			if keepsLexeme(l.Token.Lexeme) {
+				cg.write(l.Token.Lexeme)
 			} else {
 				// Normal string - use strconv.Quote to properly escape all special characters
 				cg.write(strconv.Quote(v))
@@ -31,12 +32,23 @@ func (cg *CodeGenerator) VisitLiteral(l *ast.Literal) ast.Visitor {
 			// String value but wrong type - treat as string anyway
 			cg.write(strconv.Quote(v))
 		}
+	case []byte:
+		if keepsLexeme(l.Token.Lexeme) {
+			cg.write(l.Token.Lexeme)
+		} else {
+			cg.write(quoteBytes(v))
+		}
 	case int:
 		cg.write(fmt.Sprintf("%d", v))
 	case int64:
 		cg.write(fmt.Sprintf("%d", v))
 	case float64:
-		cg.write(fmt.Sprintf("%g", v))
+		// Shortest exact form, kept recognizable as a float: 2.0, not 2
+		text := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eIN") {
+			text += ".0"
+		}
+		cg.write(text)
 	case bool:
 		if v {
 			cg.write("True")
@@ -45,7 +57,10 @@ func (cg *CodeGenerator) VisitLiteral(l *ast.Literal) ast.Visitor {
 		}
 	default:
 		// Fallback for other types
-		if l.Type == ast.LiteralTypeNone {
+		if l.Token.Type == lexer.Ellipsis {
+			// The parser reads ... as a literal without a value
+			cg.write("...")
+		} else if l.Type == ast.LiteralTypeNone {
 			cg.write("None")
 		} else {
 			cg.write(fmt.Sprintf("%v", v))
@@ -54,6 +69,42 @@ func (cg *CodeGenerator) VisitLiteral(l *ast.Literal) ast.Visitor {
 	return cg
 }
 
+// keepsLexeme reports whether a string literal is written as in the source
+// rather than from its value: raw strings, which may not be quotable the
+// way they are written, and \N{...} escapes, which are not decoded
+func keepsLexeme(lexeme string) bool {
+	quote := strings.IndexAny(lexeme, "\"'")
+	if quote < 0 {
+		return false
+	}
+	return strings.ContainsAny(lexeme[:quote], "rR") || strings.Contains(lexeme, `\N{`)
+}
+
+// quoteBytes returns a bytes literal with the value b
+func quoteBytes(b []byte) string {
+	var sb strings.Builder
+	sb.WriteString(`b"`)
+	for _, c := range b {
+		switch {
+		case c == '\\' || c == '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c >= ' ' && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
 func (cg *CodeGenerator) VisitAttribute(a *ast.Attribute) ast.Visitor {
 	a.Object.Accept(cg)
 	cg.write(".")
@@ -184,13 +235,39 @@ func (cg *CodeGenerator) VisitDictExpr(d *ast.DictExpr) ast.Visitor {
 			p.Value.Accept(cg)
 		case *ast.DoubleStarredPair:
 			cg.write("**")
-			p.Expr.Accept(cg)
+			if bindsLooserThanBitwiseOr(p.Expr) {
+				cg.write("(")
+				p.Expr.Accept(cg)
+				cg.write(")")
+			} else {
+				p.Expr.Accept(cg)
+			}
 		}
 	}
 	cg.write("}")
 	return cg
 }
 
+// bindsLooserThanBitwiseOr reports whether expr needs parentheses after '**'
+// in a dictionary display, which only takes a bitwise or expression. Parsed
+// code keeps its own parentheses; transformers may build such pairs, for
+// example from a conditional {...mapping} attribute spread.
+func bindsLooserThanBitwiseOr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.TernaryExpr, *ast.Lambda, *ast.AssignExpr:
+		return true
+	case *ast.Unary:
+		return e.Operator.Type == lexer.Not
+	case *ast.Binary:
+		switch e.Operator.Type {
+		case lexer.Or, lexer.And, lexer.EqualEqual, lexer.BangEqual, lexer.Less, lexer.LessEqual,
+			lexer.Greater, lexer.GreaterEqual, lexer.In, lexer.NotIn, lexer.Is, lexer.IsNot:
+			return true
+		}
+	}
+	return false
+}
+
 func (cg *CodeGenerator) VisitListComp(lc *ast.ListComp) ast.Visitor {
 	cg.write("[")
 	lc.Element.Accept(cg)
@@ -1,22 +1,49 @@
 package codegen
 
 import (
+	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"strconv"
 	"strings"
 )
 
 // F-string visitors
 
 func (cg *CodeGenerator) VisitFString(f *ast.FString) ast.Visitor {
-	cg.write("f\"")
+	quote := "\""
+	if cg.nestedQuotesLimited() {
+		// Before 3.12 a replacement field cannot reuse the quote of its
+		// f-string, and an f-string nested twice has no quote left
+		if cg.fields > 1 {
+			cg.fail(fmt.Errorf("f-string nested twice at %s cannot be written for %s: it needs Python 3.12 or later", f.Span, cg.Target))
+		}
+		quote = "'"
+	}
+	cg.write("f" + quote)
 	for _, part := range f.Parts {
 		part.Accept(cg)
 	}
-	cg.write("\"")
+	cg.write(quote)
+	return cg
+}
+
+// VisitConcatenatedString writes the parts side by side, for Python to join
+func (cg *CodeGenerator) VisitConcatenatedString(c *ast.ConcatenatedString) ast.Visitor {
+	for i, part := range c.Parts {
+		if i > 0 {
+			cg.write(" ")
+		}
+		part.Accept(cg)
+	}
 	return cg
 }
 
 func (cg *CodeGenerator) VisitFStringMiddle(f *ast.FStringMiddle) ast.Visitor {
+	if cg.nestedQuotesLimited() {
+		cg.writeFieldText(f.Value, f.Span)
+		return cg
+	}
 	// Escape special characters for f-string content
 	value := f.Value
 	// Escape backslashes first
@@ -36,7 +63,9 @@ func (cg *CodeGenerator) VisitFStringMiddle(f *ast.FStringMiddle) ast.Visitor {
 
 func (cg *CodeGenerator) VisitFStringReplacementField(f *ast.FStringReplacementField) ast.Visitor {
 	cg.write("{")
+	cg.fields++
 	f.Expression.Accept(cg)
+	cg.fields--
 	if f.Conversion != nil {
 		f.Conversion.Accept(cg)
 	}
@@ -79,7 +108,45 @@ func (cg *CodeGenerator) VisitFStringFormatMiddle(f *ast.FStringFormatMiddle) as
 
 func (cg *CodeGenerator) VisitFStringFormatReplacementField(f *ast.FStringFormatReplacementField) ast.Visitor {
 	cg.write("{")
+	cg.fields++
 	f.Expression.Accept(cg)
+	cg.fields--
 	cg.write("}")
 	return cg
 }
+
+// nestedQuotesLimited reports whether the output is inside an f-string
+// replacement field, where before Python 3.12 strings can neither reuse the
+// f-string's quote nor hold a backslash
+func (cg *CodeGenerator) nestedQuotesLimited() bool {
+	return cg.fields > 0 && !cg.Target.supports(12)
+}
+
+// writeFieldString writes a string literal inside a replacement field for a
+// target before 3.12, in single quotes
+func (cg *CodeGenerator) writeFieldString(value string, span lexer.Span) {
+	if cg.fields > 1 {
+		// The enclosing f-strings took both quotes
+		cg.fail(fmt.Errorf("string %s nested in two f-string replacement fields at %s cannot be written for %s: it needs Python 3.12 or later", strconv.Quote(value), span, cg.Target))
+	}
+	cg.checkFieldText(value, span)
+	cg.write("'" + value + "'")
+}
+
+// writeFieldText writes the text of an f-string nested in a replacement
+// field for a target before 3.12
+func (cg *CodeGenerator) writeFieldText(value string, span lexer.Span) {
+	cg.checkFieldText(value, span)
+	value = strings.ReplaceAll(value, "{", "{{")
+	value = strings.ReplaceAll(value, "}", "}}")
+	cg.write(value)
+}
+
+// checkFieldText fails on text inside a replacement field that could only be
+// written with quotes or escapes, which need Python 3.12 there
+func (cg *CodeGenerator) checkFieldText(value string, span lexer.Span) {
+	quoted := strconv.Quote(value)
+	if strings.ContainsAny(value, "'\"") || strings.Contains(quoted[1:len(quoted)-1], "\\") {
+		cg.fail(fmt.Errorf("string %s in an f-string replacement field at %s cannot be written for %s: quotes and escapes there need Python 3.12 or later", quoted, span, cg.Target))
+	}
+}
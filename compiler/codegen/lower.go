@@ -0,0 +1,585 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Lower returns module rewritten for target, leaving module unchanged.
+// Constructs newer than the target are replaced by their older equivalents:
+//
+//	type Alias = int          ->  Alias: TypeAlias = int (Alias = int before 3.10)
+//	def first[T](x: list[T])  ->  T = TypeVar("T"); def first(x: list[T])
+//	class Box[T: Base]:       ->  T = TypeVar("T", bound=Base); class Box(Generic[T]):
+//
+// with the typing names they use imported. A type parameter becomes one
+// TypeVar declared at module level, before the top-level statement using it
+// first; parameters of the same name declared differently get a numbered
+// name, such as T_1. Before 3.10, annotations are left unevaluated with
+// from __future__ import annotations, and types in positions that are
+// evaluated (alias values, base classes, TypeVar bounds and cast arguments)
+// are written with typing names: list[int] | None becomes
+// Optional[List[int]]. Constructs without an equivalent, such as match
+// statements before 3.10 or except* before 3.11, are errors.
+func Lower(module *ast.Module, target Target) (*ast.Module, error) {
+	if target.supports(12) {
+		return module, nil
+	}
+	l := &lowering{
+		target:       target,
+		typing:       make(map[string]bool),
+		declarations: make(map[string]string),
+		declared:     make(map[string]bool),
+	}
+	body := make([]ast.Stmt, 0, len(module.Body))
+	for _, stmt := range module.Body {
+		s, err := l.stmt(stmt)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, l.hoisted...)
+		body = append(body, s)
+		l.hoisted = nil
+	}
+	if !target.supports(10) {
+		body = ast.Rewrite(&ast.Module{Body: body}, l.evaluatedTypes).(*ast.Module).Body
+	}
+	if len(l.typing) > 0 {
+		body = insertImport(body, l.typingImport())
+	}
+	if !target.supports(10) && !hasFutureAnnotations(body) {
+		body = insertFuture(body, "annotations")
+	}
+	lowered := *module
+	lowered.Body = body
+	return &lowered, nil
+}
+
+// lowering rewrites the statements of a module for a target
+type lowering struct {
+	target Target
+	typing map[string]bool // Names the lowered module imports from typing
+
+	// TypeVar declarations of the type parameters lowered so far, by the
+	// parameter's name and declaration, and the names they are declared as
+	declarations map[string]string
+	declared     map[string]bool
+	// Declarations for the top-level statement being lowered
+	hoisted []ast.Stmt
+}
+
+// stmts lowers a block, copying it
+func (l *lowering) stmts(stmts []ast.Stmt) ([]ast.Stmt, error) {
+	if stmts == nil {
+		return nil, nil
+	}
+	lowered := make([]ast.Stmt, 0, len(stmts))
+	for _, stmt := range stmts {
+		s, err := l.stmt(stmt)
+		if err != nil {
+			return nil, err
+		}
+		lowered = append(lowered, s)
+	}
+	return lowered, nil
+}
+
+// stmt lowers a statement. The TypeVar declarations it needs are added to
+// the hoisted ones.
+func (l *lowering) stmt(stmt ast.Stmt) (lowered ast.Stmt, err error) {
+	switch s := stmt.(type) {
+	case *ast.TypeAlias:
+		var params []ast.TypeParam
+		for _, param := range s.Params {
+			if p, ok := param.(*ast.TypeParam); ok {
+				params = append(params, *p)
+			}
+		}
+		renames, err := l.typeVars(params)
+		if err != nil {
+			return nil, err
+		}
+		target := l.name(s.Name.Lexeme)
+		value := renames.Transform(s.Value).(ast.Expr)
+		if !l.target.supports(10) {
+			// typing.TypeAlias is new in 3.10; a plain assignment is an alias
+			return &ast.AssignStmt{Targets: []ast.Expr{target}, Value: l.typeExpr(value), Span: s.Span}, nil
+		}
+		l.typing["TypeAlias"] = true
+		return &ast.AnnotationStmt{Target: target, Type: l.name("TypeAlias"), Value: value, HasValue: true, Span: s.Span}, nil
+
+	case *ast.Function:
+		params := make([]ast.TypeParam, len(s.TypeParameters))
+		for i, param := range s.TypeParameters {
+			params[i] = *param
+		}
+		renames, err := l.typeVars(params)
+		if err != nil {
+			return nil, err
+		}
+		function := *s
+		function.TypeParameters = nil
+		function = *renames.Transform(&function).(*ast.Function)
+		if function.Body, err = l.stmts(function.Body); err != nil {
+			return nil, err
+		}
+		return &function, nil
+
+	case *ast.Class:
+		renames, err := l.typeVars(s.TypeParams)
+		if err != nil {
+			return nil, err
+		}
+		class := *s
+		class.TypeParams = nil
+		if len(s.TypeParams) > 0 {
+			class.Args = l.genericBase(s.Args, s.TypeParams)
+		}
+		class = *renames.Transform(&class).(*ast.Class)
+		if !l.target.supports(10) {
+			for i, arg := range class.Args {
+				if arg.Name == nil && !arg.IsDoubleStar {
+					base := *arg
+					base.Value = l.typeExpr(arg.Value)
+					class.Args[i] = &base
+				}
+			}
+		}
+		if class.Body, err = l.stmts(class.Body); err != nil {
+			return nil, err
+		}
+		return &class, nil
+
+	case *ast.Decorator:
+		decorator := *s
+		if decorator.Stmt, err = l.stmt(s.Stmt); err != nil {
+			return nil, err
+		}
+		return &decorator, nil
+
+	case *ast.If:
+		lowered := *s
+		if lowered.Body, err = l.stmts(s.Body); err != nil {
+			return nil, err
+		}
+		if lowered.Else, err = l.stmts(s.Else); err != nil {
+			return nil, err
+		}
+		return &lowered, nil
+
+	case *ast.For:
+		lowered := *s
+		if lowered.Body, err = l.stmts(s.Body); err != nil {
+			return nil, err
+		}
+		if lowered.Else, err = l.stmts(s.Else); err != nil {
+			return nil, err
+		}
+		return &lowered, nil
+
+	case *ast.While:
+		lowered := *s
+		if lowered.Body, err = l.stmts(s.Body); err != nil {
+			return nil, err
+		}
+		if lowered.Else, err = l.stmts(s.Else); err != nil {
+			return nil, err
+		}
+		return &lowered, nil
+
+	case *ast.With:
+		lowered := *s
+		if lowered.Body, err = l.stmts(s.Body); err != nil {
+			return nil, err
+		}
+		return &lowered, nil
+
+	case *ast.Try:
+		lowered := *s
+		if lowered.Body, err = l.stmts(s.Body); err != nil {
+			return nil, err
+		}
+		lowered.Excepts = make([]ast.Except, len(s.Excepts))
+		for i, except := range s.Excepts {
+			if except.IsStar && !l.target.supports(11) {
+				return nil, l.unsupported("except*", except.Span, 11)
+			}
+			lowered.Excepts[i] = except
+			if lowered.Excepts[i].Body, err = l.stmts(except.Body); err != nil {
+				return nil, err
+			}
+		}
+		if lowered.Else, err = l.stmts(s.Else); err != nil {
+			return nil, err
+		}
+		if lowered.Finally, err = l.stmts(s.Finally); err != nil {
+			return nil, err
+		}
+		return &lowered, nil
+
+	case *ast.MatchStmt:
+		if !l.target.supports(10) {
+			return nil, l.unsupported("match statement", s.Span, 10)
+		}
+		lowered := *s
+		lowered.Cases = make([]ast.CaseBlock, len(s.Cases))
+		for i, c := range s.Cases {
+			lowered.Cases[i] = c
+			if lowered.Cases[i].Body, err = l.stmts(c.Body); err != nil {
+				return nil, err
+			}
+		}
+		return &lowered, nil
+	}
+	return stmt, nil
+}
+
+// typeVars hoists the declarations of type parameters:
+//
+//	T          ->  T = TypeVar("T")
+//	T: Base    ->  T = TypeVar("T", bound=Base)
+//	T: (A, B)  ->  T = TypeVar("T", A, B)
+//	**P        ->  P = ParamSpec("P")
+//
+// A parameter declared like an earlier one of its name shares its
+// declaration; the parameters declared as another name are returned.
+func (l *lowering) typeVars(params []ast.TypeParam) (renamer, error) {
+	renames := renamer{}
+	for _, param := range params {
+		name := param.Name.Lexeme
+		switch {
+		case param.Default != nil:
+			return nil, fmt.Errorf("default of type parameter %s at %s cannot be lowered for %s: type parameter defaults need Python 3.13", name, param.Span, l.target)
+		case param.IsStar:
+			return nil, l.unsupported(fmt.Sprintf("TypeVarTuple parameter *%s", name), param.Span, 11)
+		case param.IsDoubleStar && !l.target.supports(10):
+			return nil, l.unsupported(fmt.Sprintf("ParamSpec parameter **%s", name), param.Span, 10)
+		}
+
+		factory := "TypeVar"
+		if param.IsDoubleStar {
+			factory = "ParamSpec"
+		}
+		var args []*ast.Argument
+		if constraints, ok := param.Bound.(*ast.TupleExpr); ok {
+			for _, constraint := range constraints.Elements {
+				args = append(args, &ast.Argument{Value: l.typeExpr(constraint)})
+			}
+		} else if param.Bound != nil {
+			args = append(args, &ast.Argument{Name: l.name("bound"), Value: l.typeExpr(param.Bound)})
+		}
+
+		key := name + " = " + NewCodeGenerator().Generate(&ast.Call{Callee: l.name(factory), Arguments: args})
+		declared, ok := l.declarations[key]
+		if !ok {
+			declared = name
+			for n := 1; l.declared[declared]; n++ {
+				declared = fmt.Sprintf("%s_%d", name, n)
+			}
+			l.declarations[key] = declared
+			l.declared[declared] = true
+			l.typing[factory] = true
+			args = append([]*ast.Argument{{Value: &ast.Literal{Value: declared, Type: ast.LiteralTypeString}}}, args...)
+			l.hoisted = append(l.hoisted, &ast.AssignStmt{
+				Targets: []ast.Expr{l.name(declared)},
+				Value:   &ast.Call{Callee: l.name(factory), Arguments: args},
+				Span:    param.Span,
+			})
+		}
+		if declared != name {
+			renames[name] = declared
+		}
+	}
+	return renames, nil
+}
+
+// renamer renames the type parameters of a lowered generic that are
+// declared under another name where they are used, up to the generics
+// inside it declaring parameters of the same name
+type renamer map[string]string
+
+func (r renamer) Transform(node ast.Node) ast.Node {
+	if len(r) == 0 {
+		return node
+	}
+	switch n := node.(type) {
+	case *ast.Name:
+		if declared, ok := r[n.Token.Lexeme]; ok {
+			renamed := *n
+			renamed.Token.Lexeme = declared
+			return &renamed
+		}
+		return n
+	case *ast.Function:
+		names := make([]string, len(n.TypeParameters))
+		for i, param := range n.TypeParameters {
+			names[i] = param.Name.Lexeme
+		}
+		return ast.RewriteChildren(n, r.without(names))
+	case *ast.Class:
+		names := make([]string, len(n.TypeParams))
+		for i, param := range n.TypeParams {
+			names[i] = param.Name.Lexeme
+		}
+		return ast.RewriteChildren(n, r.without(names))
+	}
+	return ast.RewriteChildren(node, r)
+}
+
+// without returns the renames of the parameters other than names
+func (r renamer) without(names []string) renamer {
+	inner := r
+	for _, name := range names {
+		if _, ok := inner[name]; ok {
+			if len(inner) == len(r) {
+				inner = make(renamer, len(r))
+				for k, v := range r {
+					inner[k] = v
+				}
+			}
+			delete(inner, name)
+		}
+	}
+	return inner
+}
+
+// evaluatedTypes rewrites the types of cast calls and TypeAlias
+// annotations, which are evaluated when the code runs, see typeExpr
+func (l *lowering) evaluatedTypes(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.Call:
+		if len(n.Arguments) > 0 && isTypingName(n.Callee, "cast") && n.Arguments[0].Name == nil {
+			arg := *n.Arguments[0]
+			arg.Value = l.typeExpr(arg.Value)
+			n.Arguments[0] = &arg
+		}
+	case *ast.AnnotationStmt:
+		if n.Value != nil && isTypingName(n.Type, "TypeAlias") {
+			n.Value = l.typeExpr(n.Value)
+		}
+	}
+	return node
+}
+
+// builtinGenerics are the typing names of the builtin types PEP 585 made
+// generic in 3.9
+var builtinGenerics = map[string]string{
+	"list":      "List",
+	"dict":      "Dict",
+	"set":       "Set",
+	"frozenset": "FrozenSet",
+	"tuple":     "Tuple",
+	"type":      "Type",
+}
+
+// typeExpr returns a type evaluated at run time written for targets before
+// 3.10: builtins subscripted as generics (PEP 585) use their typing names
+// and unions written with | (PEP 604) use Union, or Optional for a union
+// with None.
+func (l *lowering) typeExpr(expr ast.Expr) ast.Expr {
+	if l.target.supports(10) {
+		return expr
+	}
+	switch e := expr.(type) {
+	case *ast.Binary:
+		if e.Operator.Type != lexer.Pipe {
+			return expr
+		}
+		var members []ast.Expr
+		optional := false
+		for _, member := range unionMembers(e) {
+			if literal, ok := member.(*ast.Literal); ok && literal.Type == ast.LiteralTypeNone {
+				optional = true
+				continue
+			}
+			members = append(members, l.typeExpr(member))
+		}
+		if optional && len(members) == 1 {
+			l.typing["Optional"] = true
+			return &ast.Subscript{Object: l.name("Optional"), Indices: members, Span: e.Span}
+		}
+		if optional {
+			members = append(members, &ast.Literal{Type: ast.LiteralTypeNone})
+		}
+		l.typing["Union"] = true
+		return &ast.Subscript{Object: l.name("Union"), Indices: members, Span: e.Span}
+	case *ast.Subscript:
+		subscript := *e
+		if name, ok := e.Object.(*ast.Name); ok && builtinGenerics[name.Token.Lexeme] != "" {
+			generic := builtinGenerics[name.Token.Lexeme]
+			l.typing[generic] = true
+			subscript.Object = l.name(generic)
+		}
+		subscript.Indices = make([]ast.Expr, len(e.Indices))
+		for i, index := range e.Indices {
+			subscript.Indices[i] = l.typeExpr(index)
+		}
+		return &subscript
+	case *ast.ListExpr:
+		list := *e
+		list.Elements = make([]ast.Expr, len(e.Elements))
+		for i, element := range e.Elements {
+			list.Elements[i] = l.typeExpr(element)
+		}
+		return &list
+	case *ast.TupleExpr:
+		tuple := *e
+		tuple.Elements = make([]ast.Expr, len(e.Elements))
+		for i, element := range e.Elements {
+			tuple.Elements[i] = l.typeExpr(element)
+		}
+		return &tuple
+	case *ast.GroupExpr:
+		return l.typeExpr(e.Expression)
+	}
+	return expr
+}
+
+// unionMembers returns the members of a union written with |, in order
+func unionMembers(expr ast.Expr) []ast.Expr {
+	if group, ok := expr.(*ast.GroupExpr); ok {
+		expr = group.Expression
+	}
+	if binary, ok := expr.(*ast.Binary); ok && binary.Operator.Type == lexer.Pipe {
+		return append(unionMembers(binary.Left), unionMembers(binary.Right)...)
+	}
+	return []ast.Expr{expr}
+}
+
+// isTypingName reports whether expr names a typing member, as name or
+// typing.name
+func isTypingName(expr ast.Expr, name string) bool {
+	switch e := expr.(type) {
+	case *ast.Name:
+		return e.Token.Lexeme == name
+	case *ast.Attribute:
+		object, ok := e.Object.(*ast.Name)
+		return ok && object.Token.Lexeme == "typing" && e.Name.Lexeme == name
+	}
+	return false
+}
+
+// genericBase returns the bases of a generic class with Generic[...] of its
+// type parameters added after the positional ones
+func (l *lowering) genericBase(args []*ast.Argument, params []ast.TypeParam) []*ast.Argument {
+	l.typing["Generic"] = true
+	indices := make([]ast.Expr, len(params))
+	for i, param := range params {
+		indices[i] = l.name(param.Name.Lexeme)
+	}
+	generic := &ast.Argument{Value: &ast.Subscript{Object: l.name("Generic"), Indices: indices}}
+
+	at := 0
+	for at < len(args) && args[at].Name == nil && !args[at].IsDoubleStar {
+		at++
+	}
+	bases := make([]*ast.Argument, 0, len(args)+1)
+	bases = append(bases, args[:at]...)
+	bases = append(bases, generic)
+	return append(bases, args[at:]...)
+}
+
+// typingImport returns the import of the typing names the lowered module uses
+func (l *lowering) typingImport() ast.Stmt {
+	names := make([]string, 0, len(l.typing))
+	for name := range l.typing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	imported := make([]*ast.ImportName, len(names))
+	for i, name := range names {
+		imported[i] = &ast.ImportName{DottedName: &ast.DottedName{Names: []*ast.Name{l.name(name)}}}
+	}
+	return &ast.ImportFromStmt{
+		DottedName: &ast.DottedName{Names: []*ast.Name{l.name("typing")}},
+		Names:      imported,
+	}
+}
+
+func (l *lowering) name(name string) *ast.Name {
+	return &ast.Name{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}}
+}
+
+// unsupported returns the error for a construct Python 3.minor introduced
+func (l *lowering) unsupported(construct string, span lexer.Span, minor int) error {
+	return fmt.Errorf("%s at %s cannot be lowered for %s: it needs Python 3.%d or later", construct, span, l.target, minor)
+}
+
+// insertImport adds an import to a module body, after its docstring and
+// __future__ imports, which must come first
+func insertImport(body []ast.Stmt, stmt ast.Stmt) []ast.Stmt {
+	at := 0
+	if len(body) > 0 && isDocstring(body[0]) {
+		at = 1
+	}
+	for at < len(body) {
+		from, ok := body[at].(*ast.ImportFromStmt)
+		if !ok || dottedName(from.DottedName) != "__future__" {
+			break
+		}
+		at++
+	}
+	inserted := make([]ast.Stmt, 0, len(body)+1)
+	inserted = append(inserted, body[:at]...)
+	inserted = append(inserted, stmt)
+	return append(inserted, body[at:]...)
+}
+
+// hasFutureAnnotations reports whether a module body imports annotations
+// from __future__
+func hasFutureAnnotations(body []ast.Stmt) bool {
+	for _, stmt := range body {
+		if from, ok := stmt.(*ast.ImportFromStmt); ok && dottedName(from.DottedName) == "__future__" {
+			for _, name := range from.Names {
+				if dottedName(name.DottedName) == "annotations" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// insertFuture adds a __future__ import to a module body, after its
+// docstring
+func insertFuture(body []ast.Stmt, feature string) []ast.Stmt {
+	future := &ast.ImportFromStmt{
+		DottedName: &ast.DottedName{Names: []*ast.Name{{Token: lexer.Token{Lexeme: "__future__", Type: lexer.Identifier}}}},
+		Names:      []*ast.ImportName{{DottedName: &ast.DottedName{Names: []*ast.Name{{Token: lexer.Token{Lexeme: feature, Type: lexer.Identifier}}}}}},
+	}
+	at := 0
+	if len(body) > 0 && isDocstring(body[0]) {
+		at = 1
+	}
+	inserted := make([]ast.Stmt, 0, len(body)+1)
+	inserted = append(inserted, body[:at]...)
+	inserted = append(inserted, future)
+	return append(inserted, body[at:]...)
+}
+
+// isDocstring reports whether a statement is a string on its own
+func isDocstring(stmt ast.Stmt) bool {
+	if expr, ok := stmt.(*ast.ExprStmt); ok {
+		literal, ok := expr.Expr.(*ast.Literal)
+		return ok && literal.Type == ast.LiteralTypeString
+	}
+	return false
+}
+
+// dottedName returns a dotted module path as written, such as os.path
+func dottedName(name *ast.DottedName) string {
+	if name == nil {
+		return ""
+	}
+	path := ""
+	for i, part := range name.Names {
+		if i > 0 {
+			path += "."
+		}
+		path += part.Token.Lexeme
+	}
+	return path
+}
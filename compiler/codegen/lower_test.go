@@ -0,0 +1,222 @@
+package codegen
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func parseModule(t *testing.T, src string) *ast.Module {
+	t.Helper()
+	module, errs := parser.NewParser(lexer.NewScanner([]byte(src)).ScanTokens()).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return module
+}
+
+func TestParseTarget(t *testing.T) {
+	for _, name := range []string{"py38", "py310", "py312"} {
+		target, err := ParseTarget(name)
+		if err != nil || target.String() != name {
+			t.Errorf("%s: got %v, %v", name, target, err)
+		}
+	}
+	if target, err := ParseTarget(""); err != nil || target != Py312 {
+		t.Errorf("expected py312 by default, got %v, %v", target, err)
+	}
+	if _, err := ParseTarget("py27"); err == nil || !strings.Contains(err.Error(), "py38, py310, py312") {
+		t.Errorf("expected an error listing the targets, got %v", err)
+	}
+	if Target(0).String() != "py312" {
+		t.Errorf("expected the zero target to be py312, got %s", Target(0))
+	}
+}
+
+func TestLower(t *testing.T) {
+	src := `"""Docs."""
+from __future__ import annotations
+type Pair[T] = tuple[T, T]
+def first[T: (int, str)](items: list[T]) -> T:
+    return items[0]
+@dataclass
+class Box[T: Base, **P](Base, metaclass=Meta):
+    def get[U](self, u: U) -> T:
+        return self.value
+`
+	tests := []struct {
+		target   Target
+		expected string
+	}{
+		{Py310, `"Docs."
+from __future__ import annotations
+from typing import Generic, ParamSpec, TypeAlias, TypeVar
+T = TypeVar("T")
+Pair: TypeAlias = tuple[T, T]
+T_1 = TypeVar("T_1", int, str)
+def first(items: list[T_1]) -> T_1:
+    return items[0]
+
+T_2 = TypeVar("T_2", bound=Base)
+P = ParamSpec("P")
+U = TypeVar("U")
+@dataclass
+class Box(Base, Generic[T_2, P], metaclass=Meta):
+    def get(self, u: U) -> T_2:
+        return self.value
+
+`},
+		{Py312, `"Docs."
+from __future__ import annotations
+type Pair[T] = tuple[T, T]
+def first[T: (int, str)](items: list[T]) -> T:
+    return items[0]
+
+@dataclass
+class Box[T: Base, **P](Base, metaclass=Meta):
+    def get[U](self, u: U) -> T:
+        return self.value
+
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.target.String(), func(t *testing.T) {
+			module := parseModule(t, src)
+			lowered, err := Lower(module, test.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if code := NewCodeGenerator().Generate(lowered); code != test.expected {
+				t.Errorf("unexpected code:\n%s", code)
+			}
+			// The module lowered is left as written
+			if _, ok := module.Body[2].(*ast.TypeAlias); !ok {
+				t.Errorf("expected the type alias left in the module, got %T", module.Body[2])
+			}
+		})
+	}
+
+	// Before 3.10 an alias is a plain assignment, and annotations are not
+	// evaluated
+	lowered, err := Lower(parseModule(t, "type Num = int\n"), Py38)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := NewCodeGenerator().Generate(lowered); code != "from __future__ import annotations\nNum = int\n" {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+}
+
+func TestLowerEvaluatedTypes(t *testing.T) {
+	src := `"""Docs."""
+from typing import cast
+type Row = dict[str, list[int] | None]
+def first[T](items: list[T]) -> T | None:
+    return cast(T | None, items[0])
+def last[T](items: list[T]) -> T:
+    return cast(tuple[int, ...], items)[-1]
+class Rows(list[Row], Generic[T]):
+    def get[T: str | bytes](self, key: T) -> T:
+        return key
+`
+	// Annotations are left as written; the types evaluated at run time use
+	// typing names, and each TypeVar is declared once at module level
+	expected := `"Docs."
+from __future__ import annotations
+from typing import Dict, List, Optional, Tuple, TypeVar, Union
+from typing import cast
+Row = Dict[str, Optional[List[int]]]
+T = TypeVar("T")
+def first(items: list[T]) -> T | None:
+    return cast(Optional[T], items[0])
+
+def last(items: list[T]) -> T:
+    return cast(Tuple[int, ...], items)[-1]
+
+T_1 = TypeVar("T_1", bound=Union[str, bytes])
+class Rows(List[Row], Generic[T]):
+    def get(self, key: T_1) -> T_1:
+        return key
+
+`
+	module := parseModule(t, src)
+	lowered, err := Lower(module, Py38)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := NewCodeGenerator().Generate(lowered); code != expected {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+	if code := NewCodeGenerator().Generate(module); !strings.Contains(code, "cast(T | None, items[0])") {
+		t.Errorf("expected the module lowered to be left as written, got:\n%s", code)
+	}
+
+	// A module importing annotations from __future__ imports them once
+	lowered, err = Lower(parseModule(t, "from __future__ import annotations\nx = cast(int | None, y)\n"), Py38)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := NewCodeGenerator().Generate(lowered); code != "from __future__ import annotations\nfrom typing import Optional\nx = cast(Optional[int], y)\n" {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+
+	// 3.10 evaluates them as written
+	lowered, err = Lower(parseModule(t, src), Py310)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := NewCodeGenerator().Generate(lowered); !strings.Contains(code, "Row: TypeAlias = dict[str, list[int] | None]") || strings.Contains(code, "__future__") {
+		t.Errorf("unexpected code:\n%s", code)
+	}
+}
+
+func TestLowerUnsupported(t *testing.T) {
+	tests := []struct {
+		src    string
+		target Target
+		error  string
+	}{
+		{"match x:\n    case 1:\n        pass\n", Py38, "match statement at L1:1"},
+		{"try:\n    pass\nexcept* ValueError:\n    pass\n", Py310, "except* at L3:1"},
+		{"def f[**P](x): pass\n", Py38, "ParamSpec parameter **P"},
+		{"def f[*Ts](x): pass\n", Py310, "TypeVarTuple parameter *Ts"},
+		{"if x:\n    def f[T = int](x): pass\n", Py310, "default of type parameter T"},
+	}
+	for _, test := range tests {
+		_, err := Lower(parseModule(t, test.src), test.target)
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Errorf("%q for %s: expected an error about %q, got %v", test.src, test.target, test.error, err)
+		}
+	}
+
+	// Newer targets keep them
+	if _, err := Lower(parseModule(t, "match x:\n    case 1:\n        pass\n"), Py310); err != nil {
+		t.Errorf("expected match to be kept for py310, got %v", err)
+	}
+}
+
+func TestFStringQuotes(t *testing.T) {
+	module := parseModule(t, "x = f\"{label} {'card'} {f'{n}px'}\"\n")
+
+	if code := NewCodeGenerator().Generate(module); code != "x = f\"{label} {\"card\"} {f\"{n}px\"}\"\n" {
+		t.Errorf("unexpected py312 code:\n%s", code)
+	}
+
+	// Before 3.12 a replacement field cannot reuse the f-string's quote
+	generator := NewCodeGenerator()
+	generator.Target = Py310
+	code, err := generator.GenerateContext(context.Background(), module)
+	if err != nil || code != "x = f\"{label} {'card'} {f'{n}px'}\"\n" {
+		t.Errorf("unexpected py310 code, error %v:\n%s", err, code)
+	}
+
+	// nor hold escapes
+	escaped := parseModule(t, "x = f\"{'a\\n'.join(items)}\"\n")
+	if _, err := generator.GenerateContext(context.Background(), escaped); err == nil || !strings.Contains(err.Error(), "need Python 3.12") {
+		t.Errorf("expected an error about the escape, got %v", err)
+	}
+}
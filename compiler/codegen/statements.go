@@ -62,6 +62,22 @@ func (cg *CodeGenerator) VisitPassStmt(p *ast.PassStmt) ast.Visitor {
 	return cg
 }
 
+// VisitHTMLComment emits a preserved HTML comment as Python line comments.
+// The transformer only leaves comments in the tree when they should appear
+// in the generated code.
+func (cg *CodeGenerator) VisitHTMLComment(h *ast.HTMLComment) ast.Visitor {
+	for _, line := range strings.Split(strings.TrimSpace(h.Text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			cg.write("#")
+		} else {
+			cg.write("# " + line)
+		}
+		cg.newline()
+	}
+	return cg
+}
+
 func (cg *CodeGenerator) VisitBreakStmt(b *ast.BreakStmt) ast.Visitor {
 	cg.write("break")
 	cg.newline()
@@ -0,0 +1,57 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Target is the Python version generated code must run on. Lower rewrites a
+// module's constructs that are newer than the target into older equivalents,
+// and the code generator writes the rest in a form the target accepts. The
+// zero value targets Python 3.12.
+type Target int
+
+const (
+	Py38  Target = 8  // Python 3.8 and later
+	Py310 Target = 10 // Python 3.10 and later
+	Py312 Target = 12 // Python 3.12 and later
+)
+
+// Targets lists the supported targets, oldest first
+var Targets = []Target{Py38, Py310, Py312}
+
+// ParseTarget parses a target such as py38; "" selects Py312
+func ParseTarget(s string) (Target, error) {
+	if s == "" {
+		return Py312, nil
+	}
+	for _, target := range Targets {
+		if s == target.String() {
+			return target, nil
+		}
+	}
+	names := make([]string, len(Targets))
+	for i, target := range Targets {
+		names[i] = target.String()
+	}
+	return Py312, fmt.Errorf("unknown target %q (valid: %s)", s, strings.Join(names, ", "))
+}
+
+// String returns the target's name, such as py38
+func (t Target) String() string {
+	return fmt.Sprintf("py3%d", t.minor())
+}
+
+// minor returns the targeted minor version of Python 3
+func (t Target) minor() int {
+	if t == 0 {
+		return int(Py312)
+	}
+	return int(t)
+}
+
+// supports reports whether code for the target may use what Python 3.minor
+// introduced
+func (t Target) supports(minor int) bool {
+	return t.minor() >= minor
+}
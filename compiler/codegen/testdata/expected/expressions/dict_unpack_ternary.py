@@ -0,0 +1 @@
+{**base, **(extra if condition else {})}
\ No newline at end of file
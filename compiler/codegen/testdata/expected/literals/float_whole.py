@@ -0,0 +1 @@
+2.0
\ No newline at end of file
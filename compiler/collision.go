@@ -0,0 +1,72 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/transformers"
+)
+
+// OutputCollision is the error of an output path holding a Python file the
+// compiler did not generate, such as a hand-written module next to a .psx
+// file of the same name, which compiling would overwrite
+type OutputCollision struct {
+	Source string // The .psx file
+	Output string // The Python file at its output path
+}
+
+func (e *OutputCollision) Error() string {
+	return fmt.Sprintf("%s: compiling would overwrite %s, which topple did not generate", e.Source, e.Output)
+}
+
+// Diagnostic reports the collision at the source file
+func (e *OutputCollision) Diagnostic() *diagnostics.Diagnostic {
+	return &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     diagnostics.CodeOutputCollision,
+		Message:  fmt.Sprintf("compiling would overwrite %s, which topple did not generate", e.Output),
+		File:     e.Source,
+		Notes:    []string{"generated files start with the header or the runtime import, and this one does not"},
+		Hints: []string{
+			"rename the .psx file or the Python file, or pass another <output> directory to topple compile",
+			"if the file is an output of an older header, delete it",
+		},
+	}
+}
+
+// CheckOutput returns an *OutputCollision when path, the output of source,
+// holds a file the compiler did not generate. Generated files are told apart
+// by the header, the default header of an earlier build, or for views
+// generated without one by the runtime import they start with. The check
+// holds without a header too: only files recognized as generated are
+// overwritten.
+func (o Options) CheckOutput(source, path string) error {
+	existing, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if o.Header.Marks(existing) || defaultHeader.Marks(existing) || o.importsRuntime(existing) {
+		return nil
+	}
+	return &OutputCollision{Source: source, Output: path}
+}
+
+// defaultHeader is the parsed DefaultHeader, which marks the outputs of
+// builds that used it whatever the current header is
+var defaultHeader, _ = ParseHeader(DefaultHeader)
+
+// importsRuntime reports whether code starts with the runtime import of
+// generated views
+func (o Options) importsRuntime(code []byte) bool {
+	runtime := o.RuntimeModule
+	if runtime == "" {
+		runtime = transformers.DefaultRuntimeModule
+	}
+	return strings.HasPrefix(string(code), "from "+runtime+" import ")
+}
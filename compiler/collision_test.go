@@ -0,0 +1,82 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+func TestCheckOutput(t *testing.T) {
+	dir := t.TempDir()
+	header, err := ParseHeader(DefaultHeader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	options := Options{Header: header}
+
+	files := map[string]string{
+		"generated.py":   "# Generated by topple from pages/generated.psx; do not edit.\nx = 1\n",
+		"view.py":        "from topple.psx import BaseView, el\nclass View(BaseView):\n    pass\n",
+		"handwritten.py": "print('mine')\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Generated files and missing ones may be written
+	for _, name := range []string{"generated.py", "view.py", "missing.py"} {
+		if err := options.CheckOutput("a.psx", filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// Hand-written ones are reported at the source
+	err = options.CheckOutput("handwritten.psx", filepath.Join(dir, "handwritten.py"))
+	var collision *OutputCollision
+	if !errors.As(err, &collision) || collision.Output != filepath.Join(dir, "handwritten.py") {
+		t.Fatalf("expected an output collision, got %v", err)
+	}
+	if d := diagnostics.From(err); d == nil || d.Code != diagnostics.CodeOutputCollision || d.File != "handwritten.psx" {
+		t.Errorf("expected an E0500 diagnostic at handwritten.psx, got %+v", d)
+	}
+
+	// Views importing another runtime module are not recognized
+	options.RuntimeModule = "app._vendor.psx"
+	if err := options.CheckOutput("view.psx", filepath.Join(dir, "view.py")); !errors.As(err, &collision) {
+		t.Errorf("expected an output collision, got %v", err)
+	}
+
+}
+
+func TestCheckOutputWithoutHeader(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"generated.py":   "# Generated by topple from pages/generated.psx; do not edit.\nx = 1\n",
+		"view.py":        "from topple.psx import BaseView, el\nclass View(BaseView):\n    pass\n",
+		"handwritten.py": "print('mine')\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// --header "" still overwrites views and outputs of the default header
+	options := Options{}
+	for _, name := range []string{"generated.py", "view.py", "missing.py"} {
+		if err := options.CheckOutput("a.psx", filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// but not hand-written files
+	var collision *OutputCollision
+	if err := options.CheckOutput("handwritten.psx", filepath.Join(dir, "handwritten.py")); !errors.As(err, &collision) {
+		t.Errorf("expected an output collision without a header, got %v", err)
+	}
+}
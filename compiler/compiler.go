@@ -2,13 +2,19 @@ package compiler
 
 import (
 	"context"
-	"log/slog"
+	"path/filepath"
 
+	"github.com/fjvillamarin/topple/compiler/assets"
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/codegen"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/htmlcheck"
 	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/observe"
 	"github.com/fjvillamarin/topple/compiler/parser"
 	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
 	"github.com/fjvillamarin/topple/compiler/transformers"
 )
 
@@ -26,53 +32,288 @@ type Compiler interface {
 
 // StandardCompiler is the standard implementation of the Compiler interface
 type StandardCompiler struct {
-	logger *slog.Logger
+	logger  observe.Logger
+	options Options
 }
 
-// NewCompiler creates a new StandardCompiler with default options
-func NewCompiler(logger *slog.Logger) *StandardCompiler {
-	if logger == nil {
-		logger = slog.Default()
-	}
+// Options configures compilation
+type Options struct {
+	HTMLComments       transformers.HTMLCommentMode   // How <!-- ... --> comments are emitted
+	Elements           transformers.ElementPolicy     // Project-specific intrinsic and denied elements
+	Defaults           transformers.AttributeDefaults // Attributes elements and compositions get unless they set them, such as type="button" on every <button>
+	EarlyReturns       transformers.EarlyReturnMode   // Whether return may end view rendering early
+	Markdown           bool                           // Render <Markdown> blocks to static HTML at compile time
+	PreserveWhitespace bool                           // Keep whitespace-only text between inline content, as in <b>a</b> <i>b</i>; always kept inside <pre> and <textarea>
+	Assets             *assets.Resolver               // Checks and rewrites relative asset references; nil leaves them as written
+	Markers            bool                           // Comment each view class with the PSX lines it was compiled from
+	LineDirectives     bool                           // Write a '# line: file.psx:N' comment before statements from each new source line
+	StrictProps        bool                           // Fail when a literal attribute value does not match the annotated type of a view parameter
+	SkipHTMLCheck      bool                           // Skip checking markup for void elements with content and nesting browsers would restructure
+	Naming             resolver.NamingConventions     // Fail on view, module and slot names that break these conventions
+	Imports            module.ImportPolicy            // Absolute imports emitted as Python imports without a PSX lookup, and those that must be PSX modules
+	RuntimeAPI         transformers.RuntimeAPI        // Runtime API version to target; 0 targets the current one
+	RuntimeModule      string                         // Module generated code imports the runtime from, such as a vendored copy; "" imports topple.psx
+	Target             codegen.Target                 // Python version generated code must run on; constructs newer than it are lowered
+	Header             Header                         // Comment written at the top of every generated file, such as a license
+	SourceMaps         bool                           // CompileProject returns a source map of every generated file
+	Stubs              bool                           // CompileProject returns a .pyi stub of every generated file, declaring its views, functions and classes
+	OutputDir          string                         // Where CompileProject's outputs are written; imports between them are rewritten to match
+	Metrics            observe.MetricsSink            // Receives pipeline counters; nil discards them
+	Timings            observe.TimingSink             // Receives how long each stage took on each file; nil discards them
+	Events             observe.EventSink              // Receives each step of a build, such as cache hits, diagnostics and outputs; nil discards them
+}
 
+// NewCompiler creates a new StandardCompiler with default options.
+// A nil logger discards all output.
+func NewCompiler(logger observe.Logger) *StandardCompiler {
+	return NewCompilerWithOptions(logger, Options{})
+}
+
+// NewCompilerWithOptions creates a new StandardCompiler with the given options
+func NewCompilerWithOptions(logger observe.Logger, options Options) *StandardCompiler {
 	return &StandardCompiler{
-		logger: logger,
+		logger:  observe.LoggerOrNop(logger),
+		options: options,
 	}
 }
 
 // Compile takes a Biscuit source code and compiles it to Python code
 func (c *StandardCompiler) Compile(ctx context.Context, file File) ([]byte, []error) {
-	ast, errors := Parse(file.Content)
+	code, _, errs := c.CompileWithSourceMap(ctx, file)
+	return code, errs
+}
+
+// CompileWithSourceMap compiles like Compile and also returns the source map
+// of the generated code. Its file names are left for the caller to set with
+// Locate, since they depend on where the output is written.
+func (c *StandardCompiler) CompileWithSourceMap(ctx context.Context, file File) (code []byte, sourceMap *sourcemap.Map, errs []error) {
+	site := &crashSite{file: file.Name, content: file.Content, stage: "scan"}
+	c.options.event(observe.Event{Kind: observe.EventDiscover, File: file.Name})
+	defer func() {
+		for _, err := range errs {
+			c.options.diagnosticEvents(file.Name, site.stage, err, diagnostics.Error)
+		}
+		if code != nil {
+			c.options.event(observe.Event{Kind: observe.EventEmit, File: file.Name, Bytes: len(code)})
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			code, sourceMap, errs = nil, nil, []error{site.internalError(r)}
+		}
+	}()
+
+	clock := startClock(c.options.timings(), file.Name, observe.StageScan)
+	defer clock.stop()
+
+	module, errs := c.transform(ctx, file, site, clock)
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+
+	site.stage = "codegen"
+	clock.next(observe.StageCodegen)
+	module, err := codegen.Lower(module, c.options.Target)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	generator := codegen.NewCodeGenerator()
+	generator.LineDirectives = c.options.lineDirectives(file.Name)
+	generator.Target = c.options.Target
+	result, err := generator.GenerateContext(ctx, module)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	c.options.metrics().Count(observe.Files, 1)
+
+	sourceMap = generator.SourceMap()
+	return c.options.WithHeader([]byte(result), sourceMap, filepath.Base(file.Name), file.Content), sourceMap, nil
+}
+
+// CompileStub compiles file like Compile but returns the .pyi stub of the
+// generated module, which declares its views, functions and classes
+func (c *StandardCompiler) CompileStub(ctx context.Context, file File) (stub []byte, errs []error) {
+	site := &crashSite{file: file.Name, content: file.Content, stage: "scan"}
+	defer func() {
+		if r := recover(); r != nil {
+			stub, errs = nil, []error{site.internalError(r)}
+		}
+	}()
+
+	clock := startClock(c.options.timings(), file.Name, observe.StageScan)
+	defer clock.stop()
+
+	module, errs := c.transform(ctx, file, site, clock)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	site.stage = "codegen"
+	clock.next(observe.StageCodegen)
+	stub, err := GenerateStub(module, c.options.Target)
+	if err != nil {
+		return nil, []error{err}
+	}
+	return c.options.WithHeader(stub, nil, filepath.Base(file.Name), file.Content), nil
+}
+
+// CompileAST compiles like Compile but stops before code generation and
+// returns the transformed Python AST, so embedders can run their own passes
+// over it and emit it with a codegen.CodeGenerator or a printer of their own.
+// The module holds only Python nodes: views are already classes. It is not
+// yet lowered for Options.Target; codegen.Lower does that.
+func (c *StandardCompiler) CompileAST(ctx context.Context, file File) (module *ast.Module, errs []error) {
+	site := &crashSite{file: file.Name, content: file.Content, stage: "scan"}
+	defer func() {
+		if r := recover(); r != nil {
+			module, errs = nil, []error{site.internalError(r)}
+		}
+	}()
+
+	clock := startClock(c.options.timings(), file.Name, observe.StageScan)
+	defer clock.stop()
+
+	module, errs = c.transform(ctx, file, site, clock)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	c.options.metrics().Count(observe.Files, 1)
+	return module, nil
+}
+
+// transform runs the pipeline up to code generation: it scans, parses and
+// resolves file and transforms it to a Python module. site follows the
+// current stage for crash reports, and clock times them.
+func (c *StandardCompiler) transform(ctx context.Context, file File, site *crashSite, clock *stageClock) (*ast.Module, []error) {
+	metrics := c.options.metrics()
+	c.logger.Debug("Compiling file", "file", file.Name)
+
+	scanner := lexer.NewScannerWithConfig(file.Content, c.options.ScannerConfig())
+	site.where = func() lexer.Span {
+		pos := scanner.Position()
+		return lexer.Span{Start: pos, End: pos}
+	}
+	tokens, err := scanner.ScanTokensContext(ctx)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if len(scanner.Errors) > 0 {
+		return nil, scanner.Errors
+	}
+	metrics.Count(observe.Tokens, int64(len(tokens)))
+
+	site.stage, site.tokens = "parse", tokens
+	clock.next(observe.StageParse)
+	p := parser.NewParser(tokens)
+	p.Syntax = scanner.Syntax()
+	site.where = func() lexer.Span { return tokenSpan(p.Tokens, p.Current) }
+	module, errors := p.ParseContext(ctx)
 	if len(errors) > 0 {
 		return nil, errors
 	}
+	metrics.Count(observe.Nodes, countNodes(module))
+	logInputStats(c.logger, file.Name, tokens, module)
+	site.where = nil
+
+	if !c.options.SkipHTMLCheck {
+		errs, warnings := htmlcheck.Check(module)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		for _, warning := range warnings {
+			c.logger.Warn("Compilation warning", "file", file.Name, "warning", warning)
+			c.options.diagnosticEvents(file.Name, "parse", warning, diagnostics.Warning)
+		}
+	}
 
 	// Variable resolution phase
+	site.stage = "resolve"
+	clock.next(observe.StageResolve)
 	r := resolver.NewResolver()
-	resolutionTable, err := r.Resolve(ast)
+	r.StrictProps = c.options.StrictProps
+	r.Naming = c.options.Naming
+	r.SourceFilePath = file.Name
+	resolutionTable, err := r.ResolveContext(ctx, module)
+	if resolutionTable != nil && len(resolutionTable.Errors) > 0 {
+		return nil, resolutionTable.Errors
+	}
 	if err != nil {
 		return nil, []error{err}
 	}
-	if len(resolutionTable.Errors) > 0 {
-		return nil, resolutionTable.Errors
+	for _, warning := range resolutionTable.Warnings {
+		c.logger.Warn("Compilation warning", "file", file.Name, "warning", warning)
+		c.options.diagnosticEvents(file.Name, "resolve", warning, diagnostics.Warning)
 	}
 
 	// Transformation phase with resolution information
-	transformerVisitor := transformers.NewTransformerVisitor()
-	ast, err = transformerVisitor.TransformModule(ast, resolutionTable)
+	site.stage = "transform"
+	clock.next(observe.StageTransform)
+	transformerOptions := c.options.TransformerOptions()
+	transformerOptions.SourceFile = file.Name
+	transformerVisitor := transformers.NewTransformerVisitorWithOptions(transformerOptions)
+	module, err = transformerVisitor.TransformModuleContext(ctx, module, resolutionTable)
 	if err != nil {
 		return nil, []error{err}
 	}
+	return module, nil
+}
 
-	generator := codegen.NewCodeGenerator()
-	result := generator.Generate(ast)
+// ScannerConfig returns the scanner configuration for these options.
+func (o Options) ScannerConfig() lexer.ScannerConfig {
+	cfg := lexer.DefaultScannerConfig()
+	cfg.PreserveHTMLComments = o.HTMLComments.Preserved()
+	cfg.PreserveWhitespace = o.PreserveWhitespace
+	if o.Markdown {
+		cfg.RawTextElements = append(cfg.RawTextElements, transformers.MarkdownTag)
+	}
+	return cfg
+}
 
-	return []byte(result), nil
+// timings returns the configured timing sink, or one that discards timings
+func (o Options) timings() observe.TimingSink {
+	return observe.TimingsOrNop(o.Timings)
+}
+
+// metrics returns the configured metrics sink, or one that discards counters
+func (o Options) metrics() observe.MetricsSink {
+	return observe.MetricsOrNop(o.Metrics)
+}
+
+// lineDirectives returns the file name line directives of a generated file
+// name, or "" when they are off. Like view markers, they use the base name so
+// builds stay reproducible across checkouts.
+func (o Options) lineDirectives(file string) string {
+	if !o.LineDirectives || file == "" {
+		return ""
+	}
+	return filepath.Base(file)
+}
+
+// TransformerOptions returns the transformer options for these options.
+func (o Options) TransformerOptions() transformers.Options {
+	opts := transformers.Options{
+		HTMLComments:  o.HTMLComments,
+		Elements:      o.Elements,
+		Defaults:      o.Defaults,
+		EarlyReturns:  o.EarlyReturns,
+		Markdown:      o.Markdown,
+		Markers:       o.Markers,
+		RuntimeAPI:    o.RuntimeAPI,
+		RuntimeModule: o.RuntimeModule,
+	}
+	if o.Assets != nil {
+		opts.Assets = o.Assets
+	}
+	return opts
 }
 
 // Scan tokenizes source code and returns the tokens.
 func Scan(src []byte) ([]lexer.Token, []error) {
-	scanner := lexer.NewScanner(src)
+	return ScanWithConfig(src, lexer.DefaultScannerConfig())
+}
+
+// ScanWithConfig tokenizes source code with the given scanner configuration.
+func ScanWithConfig(src []byte, cfg lexer.ScannerConfig) ([]lexer.Token, []error) {
+	scanner := lexer.NewScannerWithConfig(src, cfg)
 	tokens := scanner.ScanTokens()
 	if len(scanner.Errors) > 0 {
 		return nil, scanner.Errors
@@ -80,22 +321,27 @@ func Scan(src []byte) ([]lexer.Token, []error) {
 	return tokens, nil
 }
 
-// ParseTokens parses a token stream into an AST.
+// ParseTokens parses a token stream into an AST with the current syntax.
+// With syntax errors the module holds the statements that parsed.
 func ParseTokens(tokens []lexer.Token) (*ast.Module, []error) {
+	return ParseTokensWithSyntax(tokens, lexer.CurrentSyntax)
+}
+
+// ParseTokensWithSyntax parses the token stream of a file selecting syntax
+// with its pragma, reporting constructs of later versions
+func ParseTokensWithSyntax(tokens []lexer.Token, syntax lexer.SyntaxVersion) (*ast.Module, []error) {
 	p := parser.NewParser(tokens)
-	program, errors := p.Parse()
-	if len(errors) > 0 {
-		return nil, errors
-	}
-	return program, nil
+	p.Syntax = syntax
+	return p.Parse()
 }
 
 // Parse scans a source file and returns a parsed AST.
-// It returns both the AST and a slice of any errors encountered during scanning and parsing.
+// It returns both the AST and a slice of any errors encountered during scanning and parsing;
+// the AST is nil when scanning failed and partial when parsing did.
 func Parse(src []byte) (*ast.Module, []error) {
 	tokens, errors := Scan(src)
 	if len(errors) > 0 {
 		return nil, errors
 	}
-	return ParseTokens(tokens)
+	return ParseTokensWithSyntax(tokens, lexer.SyntaxOf(src))
 }
@@ -0,0 +1,979 @@
+package consteval
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// unary applies a unary operator
+func unary(e *ast.Unary, right Value) (Value, error) {
+	switch e.Operator.Type {
+	case lexer.Not:
+		return !truthy(right), nil
+	case lexer.Minus:
+		if n, ok := integer(right); ok {
+			if n == math.MinInt64 {
+				return nil, errorf(e, "OverflowError: integer does not fit in 64 bits")
+			}
+			return -n, nil
+		}
+		if f, ok := right.(float64); ok {
+			return -f, nil
+		}
+	case lexer.Plus:
+		if n, ok := integer(right); ok {
+			return n, nil
+		}
+		if f, ok := right.(float64); ok {
+			return f, nil
+		}
+	case lexer.Tilde:
+		if n, ok := integer(right); ok {
+			return ^n, nil
+		}
+	}
+	return nil, errorf(e, "TypeError: bad operand type for unary %s: '%s'", e.Operator.Lexeme, typeName(right))
+}
+
+// binary applies a binary operator other than and and or
+func binary(e *ast.Binary, left, right Value) (Value, error) {
+	op := e.Operator.Type
+	switch op {
+	case lexer.EqualEqual:
+		return equal(left, right), nil
+	case lexer.BangEqual:
+		return !equal(left, right), nil
+	case lexer.Is, lexer.IsNot:
+		same := identical(left, right)
+		return same == (op == lexer.Is), nil
+	case lexer.In, lexer.NotIn:
+		found, err := contains(e, right, left)
+		if err != nil {
+			return nil, err
+		}
+		return found == (op == lexer.In), nil
+	case lexer.Less, lexer.LessEqual, lexer.Greater, lexer.GreaterEqual:
+		c, ok := compare(left, right)
+		if !ok {
+			return nil, errorf(e, "TypeError: '%s' not supported between instances of '%s' and '%s'", e.Operator.Lexeme, typeName(left), typeName(right))
+		}
+		switch op {
+		case lexer.Less:
+			return c < 0, nil
+		case lexer.LessEqual:
+			return c <= 0, nil
+		case lexer.Greater:
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	}
+
+	if x, ok := integer(left); ok {
+		if y, ok := integer(right); ok {
+			return intBinary(e, x, y)
+		}
+	}
+	if x, ok := number(left); ok {
+		if y, ok := number(right); ok {
+			return floatBinary(e, x, y)
+		}
+	}
+
+	switch op {
+	case lexer.Plus:
+		switch l := left.(type) {
+		case string:
+			if r, ok := right.(string); ok {
+				if len(l)+len(r) > maxBytes {
+					return nil, tooLarge(e, l)
+				}
+				return l + r, nil
+			}
+		case *List:
+			if r, ok := right.(*List); ok {
+				if len(l.Items)+len(r.Items) > maxItems {
+					return nil, tooLarge(e, l)
+				}
+				return &List{Items: append(append([]Value{}, l.Items...), r.Items...)}, nil
+			}
+		case Tuple:
+			if r, ok := right.(Tuple); ok {
+				if len(l)+len(r) > maxItems {
+					return nil, tooLarge(e, l)
+				}
+				return append(append(Tuple{}, l...), r...), nil
+			}
+		}
+	case lexer.Star:
+		if _, ok := integer(left); ok {
+			left, right = right, left
+		}
+		if n, ok := integer(right); ok {
+			return repeat(e, left, n)
+		}
+	}
+	return nil, errorf(e, "TypeError: unsupported operand types for %s: '%s' and '%s'", e.Operator.Lexeme, typeName(left), typeName(right))
+}
+
+// intBinary applies an arithmetic operator to two ints
+func intBinary(e *ast.Binary, x, y int64) (Value, error) {
+	overflow := func() (Value, error) {
+		return nil, errorf(e, "OverflowError: result of %s does not fit in 64 bits", e.Operator.Lexeme)
+	}
+	switch e.Operator.Type {
+	case lexer.Plus:
+		r := x + y
+		if (x > 0 && y > 0 && r < 0) || (x < 0 && y < 0 && r >= 0) {
+			return overflow()
+		}
+		return r, nil
+	case lexer.Minus:
+		r := x - y
+		if (x >= 0 && y < 0 && r < 0) || (x < 0 && y > 0 && r >= 0) {
+			return overflow()
+		}
+		return r, nil
+	case lexer.Star:
+		r, ok := multiply(x, y)
+		if !ok {
+			return overflow()
+		}
+		return r, nil
+	case lexer.Slash:
+		if y == 0 {
+			return nil, errorf(e, "ZeroDivisionError: division by zero")
+		}
+		return float64(x) / float64(y), nil
+	case lexer.SlashSlash, lexer.Percent:
+		if y == 0 {
+			return nil, errorf(e, "ZeroDivisionError: integer division or modulo by zero")
+		}
+		q, r := x/y, x%y
+		if r != 0 && (r < 0) != (y < 0) {
+			// Python rounds the quotient down and gives the remainder the divisor's sign
+			q, r = q-1, r+y
+		}
+		if e.Operator.Type == lexer.Percent {
+			return r, nil
+		}
+		return q, nil
+	case lexer.StarStar:
+		if y < 0 {
+			return math.Pow(float64(x), float64(y)), nil
+		}
+		// Exponentiation by squaring
+		result, base := int64(1), x
+		for exp := y; exp > 0; exp >>= 1 {
+			var ok bool
+			if exp&1 == 1 {
+				if result, ok = multiply(result, base); !ok {
+					return overflow()
+				}
+			}
+			if exp > 1 {
+				if base, ok = multiply(base, base); !ok {
+					return overflow()
+				}
+			}
+		}
+		return result, nil
+	case lexer.Ampersand:
+		return x & y, nil
+	case lexer.Pipe:
+		return x | y, nil
+	case lexer.Caret:
+		return x ^ y, nil
+	case lexer.LessLess:
+		if y < 0 {
+			return nil, errorf(e, "ValueError: negative shift count")
+		}
+		if y >= 63 || x<<y>>y != x {
+			return overflow()
+		}
+		return x << y, nil
+	case lexer.GreaterGreater:
+		if y < 0 {
+			return nil, errorf(e, "ValueError: negative shift count")
+		}
+		if y >= 63 {
+			y = 63
+		}
+		return x >> y, nil
+	}
+	return nil, errorf(e, "TypeError: unsupported operand types for %s: 'int' and 'int'", e.Operator.Lexeme)
+}
+
+// multiply multiplies two ints, reporting false when the product overflows
+func multiply(x, y int64) (int64, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	r := x * y
+	if r/y != x || (x == -1 && y == math.MinInt64) || (y == -1 && x == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
+}
+
+// floatBinary applies an arithmetic operator to numbers, one of them a float
+func floatBinary(e *ast.Binary, x, y float64) (Value, error) {
+	switch e.Operator.Type {
+	case lexer.Plus:
+		return x + y, nil
+	case lexer.Minus:
+		return x - y, nil
+	case lexer.Star:
+		return x * y, nil
+	case lexer.Slash:
+		if y == 0 {
+			return nil, errorf(e, "ZeroDivisionError: float division by zero")
+		}
+		return x / y, nil
+	case lexer.SlashSlash, lexer.Percent:
+		if y == 0 {
+			return nil, errorf(e, "ZeroDivisionError: float modulo")
+		}
+		q := math.Floor(x / y)
+		if e.Operator.Type == lexer.SlashSlash {
+			return q, nil
+		}
+		return x - q*y, nil
+	case lexer.StarStar:
+		return math.Pow(x, y), nil
+	}
+	return nil, errorf(e, "TypeError: unsupported operand types for %s: 'float' and 'float'", e.Operator.Lexeme)
+}
+
+// repeat evaluates sequence * n
+func repeat(e *ast.Binary, sequence Value, n int64) (Value, error) {
+	if n < 0 {
+		n = 0
+	}
+	length, limit := 0, int64(maxItems)
+	switch s := sequence.(type) {
+	case string:
+		length, limit = len(s), maxBytes
+	case *List:
+		length = len(s.Items)
+	case Tuple:
+		length = len(s)
+	default:
+		return nil, errorf(e, "TypeError: can't multiply sequence by non-int of type '%s'", typeName(sequence))
+	}
+	if length > 0 && n > limit/int64(length) {
+		return nil, tooLarge(e, sequence)
+	}
+	switch s := sequence.(type) {
+	case string:
+		return strings.Repeat(s, int(n)), nil
+	case *List:
+		items := []Value{}
+		for i := int64(0); i < n; i++ {
+			items = append(items, s.Items...)
+		}
+		return &List{Items: items}, nil
+	}
+	items := Tuple{}
+	for i := int64(0); i < n; i++ {
+		items = append(items, sequence.(Tuple)...)
+	}
+	return items, nil
+}
+
+// tooLarge reports that a string or sequence like v would grow past what
+// is built at compile time: maxBytes for strings, maxItems for sequences
+func tooLarge(node interface{ GetSpan() lexer.Span }, v Value) *Error {
+	if _, ok := v.(string); ok {
+		return errorf(node, "string of more than %d bytes built at compile time", maxBytes)
+	}
+	return errorf(node, "sequence of more than %d items built at compile time", maxItems)
+}
+
+// identical approximates Python's is: singletons and numbers compare by
+// value, containers by identity
+func identical(a, b Value) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		x, ok := b.(bool)
+		return ok && a == x
+	case *List:
+		x, ok := b.(*List)
+		return ok && a == x
+	case *Dict:
+		x, ok := b.(*Dict)
+		return ok && a == x
+	}
+	return typeName(a) == typeName(b) && equal(a, b)
+}
+
+// contains evaluates item in container
+func contains(e *ast.Binary, container, item Value) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, errorf(e, "TypeError: 'in <string>' requires string as left operand, not %s", typeName(item))
+		}
+		return strings.Contains(c, s), nil
+	case *Dict:
+		return c.lookup(item) >= 0, nil
+	case *List, Tuple:
+		items, _ := iterate(container, e)
+		for _, candidate := range items {
+			if equal(candidate, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, errorf(e, "TypeError: argument of type '%s' is not iterable", typeName(container))
+}
+
+// compare orders two values, reporting false when Python cannot
+func compare(a, b Value) (int, bool) {
+	if x, ok := integer(a); ok {
+		if y, ok := integer(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+	switch a := a.(type) {
+	case string:
+		if s, ok := b.(string); ok {
+			return strings.Compare(a, s), true
+		}
+	case *List:
+		if l, ok := b.(*List); ok {
+			return compareItems(a.Items, l.Items)
+		}
+	case Tuple:
+		if t, ok := b.(Tuple); ok {
+			return compareItems(a, t)
+		}
+	}
+	return 0, false
+}
+
+func compareItems(a, b []Value) (int, bool) {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if equal(a[i], b[i]) {
+			continue
+		}
+		return compare(a[i], b[i])
+	}
+	switch {
+	case len(a) < len(b):
+		return -1, true
+	case len(a) > len(b):
+		return 1, true
+	}
+	return 0, true
+}
+
+// subscript evaluates object[index]
+func subscript(e ast.Node, object, index Value) (Value, error) {
+	switch o := object.(type) {
+	case *Dict:
+		if !hashable(index) {
+			return nil, errorf(e, "TypeError: unhashable type: '%s'", typeName(index))
+		}
+		if i := o.lookup(index); i >= 0 {
+			return o.Values[i], nil
+		}
+		return nil, errorf(e, "KeyError: %s", repr(index))
+	case string:
+		runes := []rune(o)
+		i, err := position(index, len(runes), e)
+		if err != nil {
+			return nil, err
+		}
+		return string(runes[i]), nil
+	case *List:
+		i, err := position(index, len(o.Items), e)
+		if err != nil {
+			return nil, err
+		}
+		return o.Items[i], nil
+	case Tuple:
+		i, err := position(index, len(o), e)
+		if err != nil {
+			return nil, err
+		}
+		return o[i], nil
+	}
+	return nil, errorf(e, "TypeError: '%s' object is not subscriptable", typeName(object))
+}
+
+// position resolves a sequence index, counting negative ones from the end
+func position(index Value, length int, e ast.Node) (int, error) {
+	n, ok := integer(index)
+	if !ok {
+		return 0, errorf(e, "TypeError: indices must be integers, not %s", typeName(index))
+	}
+	if n < 0 {
+		n += int64(length)
+	}
+	if n < 0 || n >= int64(length) {
+		return 0, errorf(e, "IndexError: index out of range")
+	}
+	return int(n), nil
+}
+
+// iterate returns the items a for loop over a value visits
+func iterate(v Value, e ast.Node) ([]Value, error) {
+	switch v := v.(type) {
+	case string:
+		var items []Value
+		for _, r := range v {
+			items = append(items, string(r))
+		}
+		return items, nil
+	case *List:
+		return append([]Value{}, v.Items...), nil
+	case Tuple:
+		return v, nil
+	case *Dict:
+		return append([]Value{}, v.Keys...), nil
+	}
+	return nil, errorf(e, "TypeError: '%s' object is not iterable", typeName(v))
+}
+
+// builtin is a pure Python builtin function
+type builtin func(call *ast.Call, args []Value, keywords []Keyword) (Value, error)
+
+var builtins = map[string]builtin{
+	"abs":       builtinAbs,
+	"bool":      builtinBool,
+	"enumerate": builtinEnumerate,
+	"float":     builtinFloat,
+	"int":       builtinInt,
+	"len":       builtinLen,
+	"list":      builtinList,
+	"max":       builtinMinMax(1),
+	"min":       builtinMinMax(-1),
+	"range":     builtinRange,
+	"repr":      builtinRepr,
+	"sorted":    builtinSorted,
+	"str":       builtinStr,
+	"tuple":     builtinTuple,
+	"zip":       builtinZip,
+}
+
+// arity checks the number of positional arguments and that no keywords are
+// given, unless allowed
+func arity(call *ast.Call, name string, args []Value, keywords []Keyword, min, max int, allowed ...string) error {
+	if len(args) < min || len(args) > max {
+		return errorf(call, "TypeError: %s() takes %d to %d arguments (%d given)", name, min, max, len(args))
+	}
+	for _, keyword := range keywords {
+		ok := false
+		for _, name := range allowed {
+			ok = ok || keyword.Name == name
+		}
+		if !ok {
+			return errorf(call, "TypeError: %s() got an unexpected keyword argument '%s'", name, keyword.Name)
+		}
+	}
+	return nil
+}
+
+func builtinAbs(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "abs", args, keywords, 1, 1); err != nil {
+		return nil, err
+	}
+	if n, ok := integer(args[0]); ok {
+		if n == math.MinInt64 {
+			return nil, errorf(call, "OverflowError: integer does not fit in 64 bits")
+		}
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	}
+	if f, ok := args[0].(float64); ok {
+		return math.Abs(f), nil
+	}
+	return nil, errorf(call, "TypeError: bad operand type for abs(): '%s'", typeName(args[0]))
+}
+
+func builtinBool(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "bool", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	return len(args) == 1 && truthy(args[0]), nil
+}
+
+func builtinEnumerate(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "enumerate", args, keywords, 1, 2, "start"); err != nil {
+		return nil, err
+	}
+	start := int64(0)
+	if len(args) == 2 {
+		keywords = append(keywords, Keyword{Name: "start", Value: args[1]})
+	}
+	for _, keyword := range keywords {
+		n, ok := integer(keyword.Value)
+		if !ok {
+			return nil, errorf(call, "TypeError: enumerate() start must be an integer")
+		}
+		start = n
+	}
+	items, err := iterate(args[0], call)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]Value, len(items))
+	for i, item := range items {
+		pairs[i] = Tuple{start + int64(i), item}
+	}
+	return &List{Items: pairs}, nil
+}
+
+func builtinFloat(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "float", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return 0.0, nil
+	}
+	if f, ok := number(args[0]); ok {
+		return f, nil
+	}
+	if s, ok := args[0].(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err == nil {
+			return f, nil
+		}
+	}
+	return nil, errorf(call, "ValueError: could not convert %s to float", repr(args[0]))
+}
+
+func builtinInt(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "int", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return int64(0), nil
+	}
+	if n, ok := integer(args[0]); ok {
+		return n, nil
+	}
+	switch v := args[0].(type) {
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) || math.Abs(v) >= math.MaxInt64 {
+			return nil, errorf(call, "OverflowError: cannot convert %s to int", repr(v))
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(v), "_", ""), 10, 64)
+		if err == nil {
+			return n, nil
+		}
+	}
+	return nil, errorf(call, "ValueError: invalid literal for int(): %s", repr(args[0]))
+}
+
+func builtinLen(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "len", args, keywords, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return int64(len([]rune(v))), nil
+	case *List:
+		return int64(len(v.Items)), nil
+	case Tuple:
+		return int64(len(v)), nil
+	case *Dict:
+		return int64(len(v.Keys)), nil
+	}
+	return nil, errorf(call, "TypeError: object of type '%s' has no len()", typeName(args[0]))
+}
+
+func builtinList(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "list", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return &List{Items: []Value{}}, nil
+	}
+	items, err := iterate(args[0], call)
+	if err != nil {
+		return nil, err
+	}
+	return &List{Items: append([]Value{}, items...)}, nil
+}
+
+func builtinTuple(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "tuple", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return Tuple{}, nil
+	}
+	items, err := iterate(args[0], call)
+	if err != nil {
+		return nil, err
+	}
+	return append(Tuple{}, items...), nil
+}
+
+// builtinMinMax returns min (sign -1) or max (sign 1)
+func builtinMinMax(sign int) builtin {
+	name := "max"
+	if sign < 0 {
+		name = "min"
+	}
+	return func(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+		if err := arity(call, name, args, keywords, 1, math.MaxInt32); err != nil {
+			return nil, err
+		}
+		items := args
+		if len(args) == 1 {
+			var err error
+			if items, err = iterate(args[0], call); err != nil {
+				return nil, err
+			}
+		}
+		if len(items) == 0 {
+			return nil, errorf(call, "ValueError: %s() arg is an empty sequence", name)
+		}
+		best := items[0]
+		for _, item := range items[1:] {
+			c, ok := compare(item, best)
+			if !ok {
+				return nil, errorf(call, "TypeError: cannot compare '%s' and '%s'", typeName(item), typeName(best))
+			}
+			if c*sign > 0 {
+				best = item
+			}
+		}
+		return best, nil
+	}
+}
+
+func builtinRange(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "range", args, keywords, 1, 3); err != nil {
+		return nil, err
+	}
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := integer(arg)
+		if !ok {
+			return nil, errorf(call, "TypeError: '%s' object cannot be interpreted as an integer", typeName(arg))
+		}
+		bounds[i] = n
+	}
+	start, stop, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, stop = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return nil, errorf(call, "ValueError: range() arg 3 must not be zero")
+	}
+	items := []Value{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		if len(items) >= maxItems {
+			return nil, errorf(call, "range of more than %d items built at compile time", maxItems)
+		}
+		items = append(items, i)
+	}
+	return &List{Items: items}, nil
+}
+
+func builtinRepr(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "repr", args, keywords, 1, 1); err != nil {
+		return nil, err
+	}
+	return repr(args[0]), nil
+}
+
+func builtinSorted(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "sorted", args, keywords, 1, 1, "reverse"); err != nil {
+		return nil, err
+	}
+	items, err := iterate(args[0], call)
+	if err != nil {
+		return nil, err
+	}
+	items = append([]Value{}, items...)
+	reverse := false
+	for _, keyword := range keywords {
+		reverse = truthy(keyword.Value)
+	}
+
+	var failed error
+	sort.SliceStable(items, func(i, j int) bool {
+		c, ok := compare(items[i], items[j])
+		if !ok && failed == nil {
+			failed = errorf(call, "TypeError: cannot compare '%s' and '%s'", typeName(items[i]), typeName(items[j]))
+		}
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	if failed != nil {
+		return nil, failed
+	}
+	return &List{Items: items}, nil
+}
+
+func builtinStr(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "str", args, keywords, 0, 1); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return str(args[0]), nil
+}
+
+func builtinZip(call *ast.Call, args []Value, keywords []Keyword) (Value, error) {
+	if err := arity(call, "zip", args, keywords, 0, math.MaxInt32); err != nil {
+		return nil, err
+	}
+	var columns [][]Value
+	shortest := -1
+	for _, arg := range args {
+		items, err := iterate(arg, call)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, items)
+		if shortest < 0 || len(items) < shortest {
+			shortest = len(items)
+		}
+	}
+	rows := []Value{}
+	for i := 0; i < shortest; i++ {
+		row := make(Tuple, len(columns))
+		for j, column := range columns {
+			row[j] = column[i]
+		}
+		rows = append(rows, row)
+	}
+	return &List{Items: rows}, nil
+}
+
+// callMethod calls a method of a string, list or dict
+func callMethod(call *ast.Call, object Value, name string, args []Value, keywords []Keyword) (Value, error) {
+	unknown := func() (Value, error) {
+		return nil, errorf(call, "'%s' method %s() is not supported at compile time", typeName(object), name)
+	}
+	strArg := func(i int) (string, error) {
+		s, ok := args[i].(string)
+		if !ok {
+			return "", errorf(call, "TypeError: %s() argument must be str, not %s", name, typeName(args[i]))
+		}
+		return s, nil
+	}
+
+	switch o := object.(type) {
+	case string:
+		switch name {
+		case "upper", "lower", "title", "capitalize":
+			if err := arity(call, name, args, keywords, 0, 0); err != nil {
+				return nil, err
+			}
+			switch name {
+			case "upper":
+				return strings.ToUpper(o), nil
+			case "lower":
+				return strings.ToLower(o), nil
+			case "title":
+				return titleCase(o), nil
+			}
+			if o == "" {
+				return o, nil
+			}
+			runes := []rune(strings.ToLower(o))
+			runes[0] = unicode.ToUpper(runes[0])
+			return string(runes), nil
+
+		case "strip", "lstrip", "rstrip":
+			if err := arity(call, name, args, keywords, 0, 1); err != nil {
+				return nil, err
+			}
+			cutset := " \t\n\r\v\f"
+			if len(args) == 1 && args[0] != nil {
+				var err error
+				if cutset, err = strArg(0); err != nil {
+					return nil, err
+				}
+			}
+			switch name {
+			case "lstrip":
+				return strings.TrimLeft(o, cutset), nil
+			case "rstrip":
+				return strings.TrimRight(o, cutset), nil
+			}
+			return strings.Trim(o, cutset), nil
+
+		case "replace":
+			if err := arity(call, name, args, keywords, 2, 2); err != nil {
+				return nil, err
+			}
+			old, err := strArg(0)
+			if err != nil {
+				return nil, err
+			}
+			replacement, err := strArg(1)
+			if err != nil {
+				return nil, err
+			}
+			if size := int64(len(o)) + int64(strings.Count(o, old))*int64(len(replacement)-len(old)); size > maxBytes {
+				return nil, tooLarge(call, o)
+			}
+			return strings.ReplaceAll(o, old, replacement), nil
+
+		case "startswith", "endswith":
+			if err := arity(call, name, args, keywords, 1, 1); err != nil {
+				return nil, err
+			}
+			affix, err := strArg(0)
+			if err != nil {
+				return nil, err
+			}
+			if name == "startswith" {
+				return strings.HasPrefix(o, affix), nil
+			}
+			return strings.HasSuffix(o, affix), nil
+
+		case "split":
+			if err := arity(call, name, args, keywords, 0, 1, "sep"); err != nil {
+				return nil, err
+			}
+			for _, keyword := range keywords {
+				args = append(args, keyword.Value)
+			}
+			var parts []string
+			if len(args) == 0 || args[0] == nil {
+				parts = strings.Fields(o)
+			} else {
+				sep, err := strArg(0)
+				if err != nil {
+					return nil, err
+				}
+				if sep == "" {
+					return nil, errorf(call, "ValueError: empty separator")
+				}
+				parts = strings.Split(o, sep)
+			}
+			items := make([]Value, len(parts))
+			for i, part := range parts {
+				items[i] = part
+			}
+			return &List{Items: items}, nil
+
+		case "join":
+			if err := arity(call, name, args, keywords, 1, 1); err != nil {
+				return nil, err
+			}
+			items, err := iterate(args[0], call)
+			if err != nil {
+				return nil, err
+			}
+			parts := make([]string, len(items))
+			size := 0
+			for i, item := range items {
+				s, ok := item.(string)
+				if !ok {
+					return nil, errorf(call, "TypeError: sequence item %d: expected str instance, %s found", i, typeName(item))
+				}
+				parts[i] = s
+				if size += len(s) + len(o); size > maxBytes+len(o) {
+					return nil, tooLarge(call, o)
+				}
+			}
+			return strings.Join(parts, o), nil
+		}
+
+	case *List:
+		if name == "append" {
+			if err := arity(call, name, args, keywords, 1, 1); err != nil {
+				return nil, err
+			}
+			o.Items = append(o.Items, args[0])
+			return nil, nil
+		}
+
+	case *Dict:
+		switch name {
+		case "get":
+			if err := arity(call, name, args, keywords, 1, 2); err != nil {
+				return nil, err
+			}
+			if !hashable(args[0]) {
+				return nil, errorf(call, "TypeError: unhashable type: '%s'", typeName(args[0]))
+			}
+			if i := o.lookup(args[0]); i >= 0 {
+				return o.Values[i], nil
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return nil, nil
+		case "keys", "values", "items":
+			if err := arity(call, name, args, keywords, 0, 0); err != nil {
+				return nil, err
+			}
+			items := make([]Value, len(o.Keys))
+			for i, key := range o.Keys {
+				switch name {
+				case "keys":
+					items[i] = key
+				case "values":
+					items[i] = o.Values[i]
+				default:
+					items[i] = Tuple{key, o.Values[i]}
+				}
+			}
+			return &List{Items: items}, nil
+		}
+	}
+	return unknown()
+}
+
+// titleCase capitalizes the first letter of every word, as str.title does
+func titleCase(s string) string {
+	runes := []rune(s)
+	previousLetter := false
+	for i, r := range runes {
+		if previousLetter {
+			runes[i] = unicode.ToLower(r)
+		} else {
+			runes[i] = unicode.ToUpper(r)
+		}
+		previousLetter = unicode.IsLetter(r)
+	}
+	return string(runes)
+}
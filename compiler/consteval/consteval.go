@@ -0,0 +1,806 @@
+// Package consteval evaluates calls of pure helper functions at compile time.
+//
+// A module-level function decorated with @compile_time promises to compute
+// its result from its arguments alone. Where a view calls it with constant
+// arguments, such as icon_path("check"), the call is run by a small
+// interpreter over the function's AST and the result is inlined as a
+// literal. Calls with other arguments are left to run at runtime, so the
+// function is still generated, without the decorator.
+//
+// The interpreter covers the side-effect-free core of Python: assignments,
+// if, for and while with break and continue, return, and expressions over
+// None, bools, ints, floats, strings, lists, tuples and dicts, including
+// f-strings without format specs, calls of other @compile_time functions,
+// the builtins abs, bool, enumerate, float, int, len, list, max, min,
+// range, repr, sorted, str, tuple and zip, and the common string, list and
+// dict methods. Anything else is reported as an error rather than guessed.
+package consteval
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// DecoratorName marks functions whose calls may be evaluated at compile time
+const DecoratorName = "compile_time"
+
+const (
+	maxSteps = 1_000_000 // Statements and loop iterations one evaluation may run
+	maxDepth = 64        // Nested calls of compile-time functions
+	maxItems = 100_000   // Items of a sequence built at compile time
+	maxBytes = 1 << 20   // Bytes of a string built at compile time
+)
+
+// Error is a failure to evaluate a call, located in the code that caused it
+type Error struct {
+	Message string
+	Span    lexer.Span
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at %s", e.Message, e.Span)
+}
+
+func errorf(node interface{ GetSpan() lexer.Span }, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Span: node.GetSpan()}
+}
+
+// Keyword is a keyword argument of a call
+type Keyword struct {
+	Name  string
+	Value Value
+}
+
+// Interpreter evaluates the compile-time functions of one module
+type Interpreter struct {
+	functions map[string]*ast.Function
+	steps     int
+	depth     int
+}
+
+// New collects the @compile_time functions defined at the top level of module
+func New(module *ast.Module) (*Interpreter, error) {
+	in := &Interpreter{functions: make(map[string]*ast.Function)}
+	for _, stmt := range module.Body {
+		decorator, ok := stmt.(*ast.Decorator)
+		if !ok || !hasCompileTime(decorator) {
+			continue
+		}
+		fn, ok := decorator.Stmt.(*ast.Function)
+		if !ok || !IsDecorator(decorator) {
+			return nil, errorf(decorator, "@%s must be the only decorator of a function", DecoratorName)
+		}
+		if fn.IsAsync {
+			return nil, errorf(fn, "@%s function %s cannot be async", DecoratorName, fn.Name.Token.Lexeme)
+		}
+		in.functions[fn.Name.Token.Lexeme] = fn
+	}
+	return in, nil
+}
+
+// IsDecorator reports whether a decorator is @compile_time
+func IsDecorator(decorator *ast.Decorator) bool {
+	name, ok := decorator.Expr.(*ast.Name)
+	return ok && name.Token.Lexeme == DecoratorName
+}
+
+// hasCompileTime reports whether @compile_time is among a statement's
+// stacked decorators
+func hasCompileTime(decorator *ast.Decorator) bool {
+	for {
+		if IsDecorator(decorator) {
+			return true
+		}
+		next, ok := decorator.Stmt.(*ast.Decorator)
+		if !ok {
+			return false
+		}
+		decorator = next
+	}
+}
+
+// Has reports whether name is a compile-time function of the module
+func (in *Interpreter) Has(name string) bool {
+	if in == nil {
+		return false
+	}
+	_, ok := in.functions[name]
+	return ok
+}
+
+// IsConstant reports whether an expression is made of literals only, so it
+// can be evaluated without any variables
+func IsConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		_, complex := e.Value.(complex128)
+		return !complex
+	case *ast.GroupExpr:
+		return IsConstant(e.Expression)
+	case *ast.Unary:
+		return IsConstant(e.Right)
+	case *ast.Binary:
+		return IsConstant(e.Left) && IsConstant(e.Right)
+	case *ast.ListExpr:
+		return allConstant(e.Elements)
+	case *ast.TupleExpr:
+		return allConstant(e.Elements)
+	case *ast.DictExpr:
+		for _, pair := range e.Pairs {
+			kv, ok := pair.(*ast.KeyValuePair)
+			if !ok || !IsConstant(kv.Key) || !IsConstant(kv.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func allConstant(exprs []ast.Expr) bool {
+	for _, expr := range exprs {
+		if !IsConstant(expr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Constant evaluates an expression IsConstant accepts
+func (in *Interpreter) Constant(expr ast.Expr) (Value, error) {
+	in.steps = 0
+	return in.eval(expr, map[string]Value{})
+}
+
+// Call evaluates a call of the compile-time function name
+func (in *Interpreter) Call(name string, args []Value, keywords []Keyword, span lexer.Span) (Value, error) {
+	fn, ok := in.functions[name]
+	if !ok {
+		return nil, &Error{Message: fmt.Sprintf("%s is not a @%s function", name, DecoratorName), Span: span}
+	}
+	in.steps, in.depth = 0, 0
+	return in.call(fn, args, keywords, span)
+}
+
+// call runs a compile-time function with bound arguments
+func (in *Interpreter) call(fn *ast.Function, args []Value, keywords []Keyword, span lexer.Span) (Value, error) {
+	name := fn.Name.Token.Lexeme
+	if in.depth >= maxDepth {
+		return nil, &Error{Message: fmt.Sprintf("calls of %s nest more than %d deep", name, maxDepth), Span: span}
+	}
+	in.depth++
+	defer func() { in.depth-- }()
+
+	vars, err := in.bind(fn, args, keywords, span)
+	if err != nil {
+		return nil, err
+	}
+	flow, result, err := in.execBlock(fn.Body, vars)
+	if err != nil {
+		return nil, err
+	}
+	if flow == flowReturn {
+		return result, nil
+	}
+	return nil, nil
+}
+
+// bind assigns the arguments of a call to the function's parameters
+func (in *Interpreter) bind(fn *ast.Function, args []Value, keywords []Keyword, span lexer.Span) (map[string]Value, error) {
+	name := fn.Name.Token.Lexeme
+	vars := make(map[string]Value)
+	var params []*ast.Parameter
+	if fn.Parameters != nil {
+		params = fn.Parameters.Parameters
+	}
+
+	positional := 0
+	for _, param := range params {
+		if param.IsStar || param.IsDoubleStar {
+			return nil, errorf(param, "@%s functions cannot take *args or **kwargs", DecoratorName)
+		}
+		if !param.IsKeywordOnly {
+			positional++
+		}
+	}
+	if len(args) > positional {
+		return nil, &Error{Message: fmt.Sprintf("%s() takes %d positional arguments but %d were given", name, positional, len(args)), Span: span}
+	}
+
+	i := 0
+	for _, param := range params {
+		if !param.IsKeywordOnly && i < len(args) {
+			vars[param.Name.Token.Lexeme] = args[i]
+			i++
+		}
+	}
+	for _, keyword := range keywords {
+		found := false
+		for _, param := range params {
+			if param.Name.Token.Lexeme == keyword.Name && !param.IsSlash {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &Error{Message: fmt.Sprintf("%s() got an unexpected keyword argument '%s'", name, keyword.Name), Span: span}
+		}
+		if _, bound := vars[keyword.Name]; bound {
+			return nil, &Error{Message: fmt.Sprintf("%s() got multiple values for argument '%s'", name, keyword.Name), Span: span}
+		}
+		vars[keyword.Name] = keyword.Value
+	}
+	for _, param := range params {
+		paramName := param.Name.Token.Lexeme
+		if _, bound := vars[paramName]; bound {
+			continue
+		}
+		if param.Default == nil {
+			return nil, &Error{Message: fmt.Sprintf("%s() missing required argument '%s'", name, paramName), Span: span}
+		}
+		value, err := in.eval(param.Default, map[string]Value{})
+		if err != nil {
+			return nil, err
+		}
+		vars[paramName] = value
+	}
+	return vars, nil
+}
+
+// flow is how a statement passes control on
+type flow int
+
+const (
+	flowNext flow = iota
+	flowReturn
+	flowBreak
+	flowContinue
+)
+
+// execBlock runs statements until one transfers control
+func (in *Interpreter) execBlock(stmts []ast.Stmt, vars map[string]Value) (flow, Value, error) {
+	for _, stmt := range stmts {
+		flow, result, err := in.exec(stmt, vars)
+		if err != nil || flow != flowNext {
+			return flow, result, err
+		}
+	}
+	return flowNext, nil, nil
+}
+
+// step counts work done, stopping evaluations that do not end
+func (in *Interpreter) step(node ast.Node) error {
+	in.steps++
+	if in.steps > maxSteps {
+		return errorf(node, "evaluation did not finish within %d steps", maxSteps)
+	}
+	return nil
+}
+
+func (in *Interpreter) exec(stmt ast.Stmt, vars map[string]Value) (flow, Value, error) {
+	if err := in.step(stmt); err != nil {
+		return flowNext, nil, err
+	}
+
+	switch s := stmt.(type) {
+	case *ast.ExprStmt:
+		_, err := in.eval(s.Expr, vars)
+		return flowNext, nil, err
+
+	case *ast.AssignStmt:
+		value, err := in.eval(s.Value, vars)
+		if err != nil {
+			return flowNext, nil, err
+		}
+		if len(s.Targets) > 1 {
+			return flowNext, nil, in.unpack(s.Targets, value, vars, s)
+		}
+		return flowNext, nil, in.assign(s.Targets[0], value, vars)
+
+	case *ast.AnnotationStmt:
+		if !s.HasValue {
+			return flowNext, nil, nil
+		}
+		value, err := in.eval(s.Value, vars)
+		if err != nil {
+			return flowNext, nil, err
+		}
+		return flowNext, nil, in.assign(s.Target, value, vars)
+
+	case *ast.MultiStmt:
+		return in.execBlock(s.Stmts, vars)
+
+	case *ast.If:
+		condition, err := in.eval(s.Condition, vars)
+		if err != nil {
+			return flowNext, nil, err
+		}
+		if truthy(condition) {
+			return in.execBlock(s.Body, vars)
+		}
+		return in.execBlock(s.Else, vars)
+
+	case *ast.For:
+		iterable, err := in.eval(s.Iterable, vars)
+		if err != nil {
+			return flowNext, nil, err
+		}
+		items, err := iterate(iterable, s.Iterable)
+		if err != nil {
+			return flowNext, nil, err
+		}
+		for _, item := range items {
+			if err := in.step(s); err != nil {
+				return flowNext, nil, err
+			}
+			if err := in.assign(s.Target, item, vars); err != nil {
+				return flowNext, nil, err
+			}
+			flow, result, err := in.execBlock(s.Body, vars)
+			if err != nil || flow == flowReturn {
+				return flow, result, err
+			}
+			if flow == flowBreak {
+				return flowNext, nil, nil
+			}
+		}
+		return in.execBlock(s.Else, vars)
+
+	case *ast.While:
+		for {
+			if err := in.step(s); err != nil {
+				return flowNext, nil, err
+			}
+			condition, err := in.eval(s.Test, vars)
+			if err != nil {
+				return flowNext, nil, err
+			}
+			if !truthy(condition) {
+				return in.execBlock(s.Else, vars)
+			}
+			flow, result, err := in.execBlock(s.Body, vars)
+			if err != nil || flow == flowReturn {
+				return flow, result, err
+			}
+			if flow == flowBreak {
+				return flowNext, nil, nil
+			}
+		}
+
+	case *ast.ReturnStmt:
+		if s.Value == nil {
+			return flowReturn, nil, nil
+		}
+		value, err := in.eval(s.Value, vars)
+		return flowReturn, value, err
+
+	case *ast.BreakStmt:
+		return flowBreak, nil, nil
+
+	case *ast.ContinueStmt:
+		return flowContinue, nil, nil
+
+	case *ast.PassStmt:
+		return flowNext, nil, nil
+
+	case *ast.RaiseStmt:
+		return flowNext, nil, in.raise(s, vars)
+	}
+	return flowNext, nil, errorf(stmt, "%T is not supported in @%s functions", stmt, DecoratorName)
+}
+
+// raise reports a raised exception as an error, with its message when it
+// can be evaluated: raise ValueError("unknown icon") fails with
+// "ValueError: unknown icon"
+func (in *Interpreter) raise(s *ast.RaiseStmt, vars map[string]Value) error {
+	if !s.HasException {
+		return errorf(s, "exception re-raised")
+	}
+	call, ok := s.Exception.(*ast.Call)
+	if !ok {
+		return errorf(s, "%s raised", s.Exception)
+	}
+	name, ok := call.Callee.(*ast.Name)
+	if !ok {
+		return errorf(s, "%s raised", call)
+	}
+	if len(call.Arguments) == 0 {
+		return errorf(s, "%s raised", name.Token.Lexeme)
+	}
+	message, err := in.eval(call.Arguments[0].Value, vars)
+	if err != nil {
+		return err
+	}
+	return errorf(s, "%s: %s", name.Token.Lexeme, str(message))
+}
+
+// assign binds a value to an assignment target
+func (in *Interpreter) assign(target ast.Expr, value Value, vars map[string]Value) error {
+	switch t := target.(type) {
+	case *ast.Name:
+		vars[t.Token.Lexeme] = value
+		return nil
+	case *ast.GroupExpr:
+		return in.assign(t.Expression, value, vars)
+	case *ast.TupleExpr:
+		return in.unpack(t.Elements, value, vars, t)
+	case *ast.ListExpr:
+		return in.unpack(t.Elements, value, vars, t)
+	case *ast.Subscript:
+		object, err := in.eval(t.Object, vars)
+		if err != nil {
+			return err
+		}
+		index, err := in.index(t.Indices, vars)
+		if err != nil {
+			return err
+		}
+		switch o := object.(type) {
+		case *Dict:
+			if !hashable(index) {
+				return errorf(t, "TypeError: unhashable type: '%s'", typeName(index))
+			}
+			o.set(index, value)
+			return nil
+		case *List:
+			i, err := position(index, len(o.Items), t)
+			if err != nil {
+				return err
+			}
+			o.Items[i] = value
+			return nil
+		}
+		return errorf(t, "TypeError: '%s' object does not support item assignment", typeName(object))
+	}
+	return errorf(target, "cannot assign to %T at compile time", target)
+}
+
+// unpack assigns the items of a value to several targets
+func (in *Interpreter) unpack(targets []ast.Expr, value Value, vars map[string]Value, node ast.Node) error {
+	items, err := iterate(value, node)
+	if err != nil {
+		return err
+	}
+	if len(items) != len(targets) {
+		return errorf(node, "ValueError: expected %d values to unpack, got %d", len(targets), len(items))
+	}
+	for i, target := range targets {
+		if err := in.assign(target, items[i], vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *Interpreter) eval(expr ast.Expr, vars map[string]Value) (Value, error) {
+	switch e := expr.(type) {
+	case *ast.Literal:
+		switch v := e.Value.(type) {
+		case nil, bool, int64, float64, string:
+			return v, nil
+		case int:
+			return int64(v), nil
+		}
+		return nil, errorf(e, "literal %s is not supported at compile time", e)
+
+	case *ast.Name:
+		name := e.Token.Lexeme
+		if value, ok := vars[name]; ok {
+			return value, nil
+		}
+		return nil, errorf(e, "NameError: name '%s' is not defined at compile time", name)
+
+	case *ast.GroupExpr:
+		return in.eval(e.Expression, vars)
+
+	case *ast.Unary:
+		right, err := in.eval(e.Right, vars)
+		if err != nil {
+			return nil, err
+		}
+		return unary(e, right)
+
+	case *ast.Binary:
+		left, err := in.eval(e.Left, vars)
+		if err != nil {
+			return nil, err
+		}
+		// and and or return an operand, evaluating the right one only if needed
+		switch e.Operator.Type {
+		case lexer.And:
+			if !truthy(left) {
+				return left, nil
+			}
+			return in.eval(e.Right, vars)
+		case lexer.Or:
+			if truthy(left) {
+				return left, nil
+			}
+			return in.eval(e.Right, vars)
+		}
+		right, err := in.eval(e.Right, vars)
+		if err != nil {
+			return nil, err
+		}
+		return binary(e, left, right)
+
+	case *ast.TernaryExpr:
+		condition, err := in.eval(e.Condition, vars)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(condition) {
+			return in.eval(e.TrueExpr, vars)
+		}
+		return in.eval(e.FalseExpr, vars)
+
+	case *ast.ListExpr:
+		items, err := in.evalAll(e.Elements, vars)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Items: items}, nil
+
+	case *ast.TupleExpr:
+		items, err := in.evalAll(e.Elements, vars)
+		if err != nil {
+			return nil, err
+		}
+		return Tuple(items), nil
+
+	case *ast.DictExpr:
+		dict := &Dict{}
+		for _, pair := range e.Pairs {
+			kv, ok := pair.(*ast.KeyValuePair)
+			if !ok {
+				return nil, errorf(pair, "dict unpacking is not supported at compile time")
+			}
+			key, err := in.eval(kv.Key, vars)
+			if err != nil {
+				return nil, err
+			}
+			if !hashable(key) {
+				return nil, errorf(kv, "TypeError: unhashable type: '%s'", typeName(key))
+			}
+			value, err := in.eval(kv.Value, vars)
+			if err != nil {
+				return nil, err
+			}
+			dict.set(key, value)
+		}
+		return dict, nil
+
+	case *ast.Subscript:
+		object, err := in.eval(e.Object, vars)
+		if err != nil {
+			return nil, err
+		}
+		if len(e.Indices) == 1 {
+			if slice, ok := e.Indices[0].(*ast.Slice); ok {
+				return in.slice(object, slice, vars)
+			}
+		}
+		index, err := in.index(e.Indices, vars)
+		if err != nil {
+			return nil, err
+		}
+		return subscript(e, object, index)
+
+	case *ast.FString:
+		return in.fstring(e, vars)
+
+	case *ast.ConcatenatedString:
+		var out []byte
+		for _, part := range e.Parts {
+			value, err := in.eval(part, vars)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, str(value)...)
+		}
+		return string(out), nil
+
+	case *ast.Call:
+		return in.evalCall(e, vars)
+	}
+	return nil, errorf(expr, "%T is not supported in @%s functions", expr, DecoratorName)
+}
+
+func (in *Interpreter) evalAll(exprs []ast.Expr, vars map[string]Value) ([]Value, error) {
+	values := make([]Value, len(exprs))
+	for i, expr := range exprs {
+		value, err := in.eval(expr, vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// index evaluates the indices of a subscript; several make a tuple
+func (in *Interpreter) index(indices []ast.Expr, vars map[string]Value) (Value, error) {
+	values, err := in.evalAll(indices, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return Tuple(values), nil
+}
+
+// slice evaluates object[start:end:step]
+func (in *Interpreter) slice(object Value, slice *ast.Slice, vars map[string]Value) (Value, error) {
+	bound := func(expr ast.Expr) (*int64, error) {
+		if expr == nil {
+			return nil, nil
+		}
+		value, err := in.eval(expr, vars)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		n, ok := integer(value)
+		if !ok {
+			return nil, errorf(expr, "TypeError: slice indices must be integers or None, not %s", typeName(value))
+		}
+		return &n, nil
+	}
+	start, err := bound(slice.StartIndex)
+	if err != nil {
+		return nil, err
+	}
+	end, err := bound(slice.EndIndex)
+	if err != nil {
+		return nil, err
+	}
+	step, err := bound(slice.Step)
+	if err != nil {
+		return nil, err
+	}
+	if step != nil && *step == 0 {
+		return nil, errorf(slice, "ValueError: slice step cannot be zero")
+	}
+
+	pick := func(length int) []int {
+		return sliceIndices(length, start, end, step)
+	}
+	switch o := object.(type) {
+	case string:
+		runes := []rune(o)
+		var out []rune
+		for _, i := range pick(len(runes)) {
+			out = append(out, runes[i])
+		}
+		return string(out), nil
+	case *List:
+		items := []Value{}
+		for _, i := range pick(len(o.Items)) {
+			items = append(items, o.Items[i])
+		}
+		return &List{Items: items}, nil
+	case Tuple:
+		items := Tuple{}
+		for _, i := range pick(len(o)) {
+			items = append(items, o[i])
+		}
+		return items, nil
+	}
+	return nil, errorf(slice, "TypeError: '%s' object is not subscriptable", typeName(object))
+}
+
+// sliceIndices returns the positions a slice selects, as Python computes them
+func sliceIndices(length int, start, end, step *int64) []int {
+	s := int64(1)
+	if step != nil {
+		s = *step
+	}
+	n := int64(length)
+	clamp := func(bound *int64, def, low, high int64) int64 {
+		if bound == nil {
+			return def
+		}
+		i := *bound
+		if i < 0 {
+			i += n
+		}
+		if i < low {
+			return low
+		}
+		if i > high {
+			return high
+		}
+		return i
+	}
+
+	var indices []int
+	if s > 0 {
+		from, to := clamp(start, 0, 0, n), clamp(end, n, 0, n)
+		for i := from; i < to; i += s {
+			indices = append(indices, int(i))
+		}
+	} else {
+		from, to := clamp(start, n-1, -1, n-1), clamp(end, -1, -1, n-1)
+		if end != nil && *end < -n {
+			to = -1
+		}
+		for i := from; i > to; i += s {
+			indices = append(indices, int(i))
+		}
+	}
+	return indices
+}
+
+// fstring evaluates an f-string
+func (in *Interpreter) fstring(e *ast.FString, vars map[string]Value) (Value, error) {
+	var out []byte
+	for _, part := range e.Parts {
+		switch p := part.(type) {
+		case *ast.FStringMiddle:
+			out = append(out, p.Value...)
+		case *ast.FStringReplacementField:
+			if p.Equal || p.FormatSpec != nil {
+				return nil, errorf(p, "f-string format specs are not supported at compile time")
+			}
+			value, err := in.eval(p.Expression, vars)
+			if err != nil {
+				return nil, err
+			}
+			if p.Conversion != nil && p.Conversion.Type != "s" {
+				out = append(out, repr(value)...)
+			} else {
+				out = append(out, str(value)...)
+			}
+		default:
+			return nil, errorf(part, "%T is not supported in f-strings at compile time", part)
+		}
+		if len(out) > maxBytes {
+			return nil, tooLarge(e, "")
+		}
+	}
+	return string(out), nil
+}
+
+// evalCall evaluates a call of a compile-time function, a builtin or a method
+func (in *Interpreter) evalCall(e *ast.Call, vars map[string]Value) (Value, error) {
+	var args []Value
+	var keywords []Keyword
+	for _, arg := range e.Arguments {
+		if arg.IsStar || arg.IsDoubleStar {
+			return nil, errorf(arg, "argument unpacking is not supported at compile time")
+		}
+		value, err := in.eval(arg.Value, vars)
+		if err != nil {
+			return nil, err
+		}
+		if arg.Name != nil {
+			keywords = append(keywords, Keyword{Name: arg.Name.Token.Lexeme, Value: value})
+		} else {
+			args = append(args, value)
+		}
+	}
+
+	switch callee := e.Callee.(type) {
+	case *ast.Name:
+		name := callee.Token.Lexeme
+		if _, shadowed := vars[name]; !shadowed {
+			if fn, ok := in.functions[name]; ok {
+				return in.call(fn, args, keywords, e.Span)
+			}
+			if builtin, ok := builtins[name]; ok {
+				return builtin(e, args, keywords)
+			}
+		}
+		return nil, errorf(callee, "%s cannot be called at compile time: only @%s functions and pure builtins can", name, DecoratorName)
+
+	case *ast.Attribute:
+		object, err := in.eval(callee.Object, vars)
+		if err != nil {
+			return nil, err
+		}
+		return callMethod(e, object, callee.Name.Lexeme, args, keywords)
+	}
+	return nil, errorf(e.Callee, "%s cannot be called at compile time", e.Callee)
+}
@@ -0,0 +1,230 @@
+package consteval
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func parseModule(t *testing.T, source string) *ast.Module {
+	t.Helper()
+
+	scanner := lexer.NewScanner([]byte(source))
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("Scanner errors: %v", scanner.Errors)
+	}
+	module, errors := parser.NewParser(tokens).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Parser errors: %v", errors)
+	}
+	return module
+}
+
+// evaluate returns repr(body) where body is the result of a compile-time
+// function without parameters
+func evaluate(t *testing.T, body string) (string, error) {
+	t.Helper()
+
+	source := "@compile_time\ndef f():\n"
+	for _, line := range strings.Split(body, "\n") {
+		source += "    " + line + "\n"
+	}
+	in, err := New(parseModule(t, source))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	value, err := in.Call("f", nil, nil, lexer.Span{})
+	if err != nil {
+		return "", err
+	}
+	return repr(value), nil
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"arithmetic", "return 1 + 2 * 3 - 4", "3"},
+		{"true division", "return 7 / 2", "3.5"},
+		{"whole float", "return 4 / 2", "2.0"},
+		{"floor division", "return -7 // 2", "-4"},
+		{"modulo sign", "return -7 % 3", "2"},
+		{"power", "return 2 ** 10", "1024"},
+		{"negative power", "return 2 ** -1", "0.5"},
+		{"string concat", `return "a" + "b" * 3`, "'abbb'"},
+		{"comparison chain", "return 1 < 2 < 3", "True"},
+		{"and or", `return 0 or "x" and "y"`, "'y'"},
+		{"membership", `return "b" in ["a", "b"] and "z" not in {"a": 1}`, "True"},
+		{"ternary", `return "yes" if [] else "no"`, "'no'"},
+		{"fstring", "x = 5\nreturn f\"x={x} r={'q'!r}\"", `"x=5 r='q'"`},
+		{"dict subscript", `d = {"a": 1}` + "\nd[\"b\"] = 2\nreturn d", "{'a': 1, 'b': 2}"},
+		{"list slice", "return [1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"string reverse", `return "abc"[::-1]`, "'cba'"},
+		{"negative index", `return "abc"[-1]`, "'c'"},
+		{"for else", "total = 0\nfor i in range(5):\n    if i == 3:\n        continue\n    total += i\nelse:\n    total += 100\nreturn total", "107"},
+		{"while break", "n = 0\nwhile True:\n    n += 1\n    if n > 4:\n        break\nreturn n", "5"},
+		{"unpacking", "a, b = 1, 2\nreturn (b, a)", "(2, 1)"},
+		{"enumerate zip", `return [list(enumerate("ab")), zip([1, 2], "xy")]`, "[[(0, 'a'), (1, 'b')], [(1, 'x'), (2, 'y')]]"},
+		{"builtins", `return (len("héllo"), str(1.5), int("42"), min(3, 1, 2), max([1, 5]), abs(-2), sorted([3, 1], reverse=True))`, "(5, '1.5', 42, 1, 5, 2, [3, 1])"},
+		{"string methods", `return "  Hello World ".strip().lower().replace("world", "there").split(" ")`, "['hello', 'there']"},
+		{"join title", `return "-".join(["a", "b"]).title()`, "'A-B'"},
+		{"dict methods", `d = {"a": 1}` + "\nreturn (d.get(\"a\"), d.get(\"b\", 0), d.items())", "(1, 0, [('a', 1)])"},
+		{"no return", "pass", "None"},
+		{"large float", "return 1e16 * 10", "1e+17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluate(t, tt.body)
+			if err != nil {
+				t.Fatalf("evaluation failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"key error", `return {"a": 1}["b"]`, "KeyError: 'b'"},
+		{"index error", "return [1][3]", "IndexError"},
+		{"zero division", "return 1 // 0", "ZeroDivisionError"},
+		{"raise", `raise ValueError("bad icon")`, "ValueError: bad icon"},
+		{"undefined name", "return settings", "NameError: name 'settings' is not defined"},
+		{"impure call", `return open("x")`, "open cannot be called at compile time"},
+		{"overflow", "return 2 ** 64", "OverflowError"},
+		{"endless loop", "while True:\n    pass", "did not finish"},
+		{"unsupported statement", "import os", "not supported"},
+		{"format spec", `return f"{1:.2f}"`, "format specs are not supported"},
+		{"doubled string", "s = \"ab\"\nfor i in range(26):\n    s = s + s\nreturn s", "string of more than 1048576 bytes"},
+		{"doubled list", "items = [1]\nfor i in range(26):\n    items = items + items\nreturn items", "sequence of more than 100000 items"},
+		{"doubled tuple", "items = (1,)\nfor i in range(26):\n    items = items + items\nreturn items", "sequence of more than 100000 items"},
+		{"repeated string", `return "ab" * 1000000`, "string of more than 1048576 bytes"},
+		{"doubled f-string", "s = \"ab\"\nfor i in range(26):\n    s = f\"{s}{s}\"\nreturn s", "string of more than 1048576 bytes"},
+		{"replace", "s = \"ab\" * 1000\nreturn s.replace(\"\", s)", "string of more than 1048576 bytes"},
+		{"join", "s = \"ab\" * 1000\nreturn s.join([s] * 1000)", "string of more than 1048576 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evaluate(t, tt.body)
+			if err == nil {
+				t.Fatalf("expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	module := parseModule(t, `@compile_time
+def icon_path(name, size=16, *, ext="svg"):
+    return f"/icons/{size}/{name}.{ext}"
+
+@compile_time
+def icon(name):
+    return icon_path(name, size=32)
+
+def runtime_only():
+    return 1
+`)
+	in, err := New(module)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if !in.Has("icon_path") || in.Has("runtime_only") {
+		t.Fatalf("expected only decorated functions, got %v", in.functions)
+	}
+
+	tests := []struct {
+		name     string
+		function string
+		args     []Value
+		keywords []Keyword
+		want     Value
+	}{
+		{"defaults", "icon_path", []Value{"check"}, nil, "/icons/16/check.svg"},
+		{"keywords", "icon_path", []Value{"x"}, []Keyword{{Name: "ext", Value: "png"}, {Name: "size", Value: int64(8)}}, "/icons/8/x.png"},
+		{"nested call", "icon", []Value{"check"}, nil, "/icons/32/check.svg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := in.Call(tt.function, tt.args, tt.keywords, lexer.Span{})
+			if err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := in.Call("icon_path", []Value{"a", int64(1), "png"}, nil, lexer.Span{}); err == nil {
+		t.Error("expected keyword-only parameter to reject a positional argument")
+	}
+	if _, err := in.Call("icon_path", nil, nil, lexer.Span{}); err == nil || !strings.Contains(err.Error(), "missing required argument 'name'") {
+		t.Errorf("expected a missing argument error, got %v", err)
+	}
+}
+
+func TestNewRejectsStackedDecorators(t *testing.T) {
+	module := parseModule(t, "@cache\n@compile_time\ndef f():\n    return 1\n")
+	if _, err := New(module); err == nil || !strings.Contains(err.Error(), "only decorator") {
+		t.Errorf("expected stacked decorators to be rejected, got %v", err)
+	}
+}
+
+func TestToExpr(t *testing.T) {
+	value := &List{Items: []Value{int64(-1), 2.5, "a", nil, true, Tuple{int64(1)}, &Dict{Keys: []Value{"k"}, Values: []Value{int64(2)}}}}
+	expr, err := ToExpr(value, lexer.Span{})
+	if err != nil {
+		t.Fatalf("ToExpr failed: %v", err)
+	}
+	list, ok := expr.(*ast.ListExpr)
+	if !ok || len(list.Elements) != 7 {
+		t.Fatalf("expected a list of 7 elements, got %#v", expr)
+	}
+	if _, ok := list.Elements[0].(*ast.GroupExpr); !ok {
+		t.Errorf("expected a negative number to be parenthesized, got %#v", list.Elements[0])
+	}
+	if _, ok := list.Elements[6].(*ast.DictExpr); !ok {
+		t.Errorf("expected a dict expression, got %#v", list.Elements[6])
+	}
+
+	inf, _ := evaluate(t, "return 1e308 * 10")
+	if inf != "inf" {
+		t.Fatalf("expected inf, got %s", inf)
+	}
+	if _, err := ToExpr(math.Inf(1), lexer.Span{}); err == nil {
+		t.Error("expected inf not to be inlined")
+	}
+}
+
+func TestIsConstant(t *testing.T) {
+	module := parseModule(t, `x = [1, -2, "a" + "b", {"k": (None, True)}]
+y = [1, name]
+z = f(1)
+`)
+	want := []bool{true, false, false}
+	for i, stmt := range module.Body {
+		value := stmt.(*ast.AssignStmt).Value
+		if got := IsConstant(value); got != want[i] {
+			t.Errorf("IsConstant(%s) = %v, want %v", value, got, want[i])
+		}
+	}
+}
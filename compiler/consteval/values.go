@@ -0,0 +1,360 @@
+package consteval
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Value is a Python value during compile-time evaluation: nil for None, or
+// a bool, int64, float64, string, *List, Tuple or *Dict
+type Value any
+
+// List is a mutable Python list
+type List struct {
+	Items []Value
+}
+
+// Tuple is an immutable Python tuple
+type Tuple []Value
+
+// Dict is a Python dict, which keeps its insertion order
+type Dict struct {
+	Keys   []Value
+	Values []Value
+}
+
+// lookup returns the position of key, or -1
+func (d *Dict) lookup(key Value) int {
+	for i, k := range d.Keys {
+		if equal(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// set adds or replaces the value of key
+func (d *Dict) set(key, value Value) {
+	if i := d.lookup(key); i >= 0 {
+		d.Values[i] = value
+		return
+	}
+	d.Keys = append(d.Keys, key)
+	d.Values = append(d.Values, value)
+}
+
+// typeName returns the Python name of a value's type, for error messages
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "str"
+	case *List:
+		return "list"
+	case Tuple:
+		return "tuple"
+	case *Dict:
+		return "dict"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// truthy reports whether Python considers a value true
+func truthy(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case *List:
+		return len(v.Items) > 0
+	case Tuple:
+		return len(v) > 0
+	case *Dict:
+		return len(v.Keys) > 0
+	}
+	return true
+}
+
+// hashable reports whether a value may be a dict key
+func hashable(v Value) bool {
+	switch v := v.(type) {
+	case *List, *Dict:
+		return false
+	case Tuple:
+		for _, item := range v {
+			if !hashable(item) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// number returns a numeric value as a float, treating bools as 0 and 1
+func number(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// integer returns an integral value, treating bools as 0 and 1
+func integer(v Value) (int64, bool) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// equal compares values the way Python's == does
+func equal(a, b Value) bool {
+	if x, ok := integer(a); ok {
+		if y, ok := integer(b); ok {
+			return x == y
+		}
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+		return false
+	}
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		s, ok := b.(string)
+		return ok && a == s
+	case *List:
+		l, ok := b.(*List)
+		return ok && equalItems(a.Items, l.Items)
+	case Tuple:
+		t, ok := b.(Tuple)
+		return ok && equalItems(a, t)
+	case *Dict:
+		d, ok := b.(*Dict)
+		if !ok || len(a.Keys) != len(d.Keys) {
+			return false
+		}
+		for i, key := range a.Keys {
+			j := d.lookup(key)
+			if j < 0 || !equal(a.Values[i], d.Values[j]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func equalItems(a, b []Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// str converts a value to text the way Python's str() does
+func str(v Value) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return repr(v)
+}
+
+// repr converts a value to text the way Python's repr() does
+func repr(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return quote(v)
+	case *List:
+		return "[" + reprItems(v.Items) + "]"
+	case Tuple:
+		if len(v) == 1 {
+			return "(" + repr(v[0]) + ",)"
+		}
+		return "(" + reprItems(v) + ")"
+	case *Dict:
+		pairs := make([]string, len(v.Keys))
+		for i, key := range v.Keys {
+			pairs[i] = repr(key) + ": " + repr(v.Values[i])
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+func reprItems(items []Value) string {
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = repr(item)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// quote quotes a string the way Python's repr() does, preferring single
+// quotes
+func quote(s string) string {
+	q := byte('\'')
+	if strings.Contains(s, "'") && !strings.Contains(s, `"`) {
+		q = '"'
+	}
+	var b strings.Builder
+	b.WriteByte(q)
+	for _, r := range s {
+		switch {
+		case r == rune(q) || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(q)
+	return b.String()
+}
+
+// formatFloat formats a float the way Python's repr() does: the shortest
+// text that reads back as the same value, always recognizable as a float
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+
+	// The exponent of the shortest form decides the notation, as in Python
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	_, exponent, _ := strings.Cut(s, "e")
+	exp, _ := strconv.Atoi(exponent)
+	if exp < -4 || exp >= 16 {
+		return s
+	}
+	s = strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// ToExpr converts a value to the expression that creates it, spanning the
+// code it replaces
+func ToExpr(v Value, span lexer.Span) (ast.Expr, error) {
+	switch v := v.(type) {
+	case nil:
+		return &ast.Literal{Type: ast.LiteralTypeNone, Span: span}, nil
+	case bool:
+		return &ast.Literal{Type: ast.LiteralTypeBool, Value: v, Span: span}, nil
+	case int64, float64:
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			return nil, fmt.Errorf("cannot inline %s", repr(v))
+		}
+		literal := &ast.Literal{Type: ast.LiteralTypeNumber, Value: v, Span: span}
+		if n, _ := number(v); n < 0 || math.Signbit(n) {
+			// The literal replaces a call, which binds tighter than a sign
+			return &ast.GroupExpr{Expression: literal, Span: span}, nil
+		}
+		return literal, nil
+	case string:
+		return &ast.Literal{Type: ast.LiteralTypeString, Value: v, Span: span}, nil
+	case *List:
+		elements, err := toExprs(v.Items, span)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ListExpr{Elements: elements, Span: span}, nil
+	case Tuple:
+		elements, err := toExprs(v, span)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TupleExpr{Elements: elements, Span: span}, nil
+	case *Dict:
+		pairs := make([]ast.DictPair, len(v.Keys))
+		for i, key := range v.Keys {
+			k, err := ToExpr(key, span)
+			if err != nil {
+				return nil, err
+			}
+			value, err := ToExpr(v.Values[i], span)
+			if err != nil {
+				return nil, err
+			}
+			pairs[i] = &ast.KeyValuePair{Key: k, Value: value, Span: span}
+		}
+		return &ast.DictExpr{Pairs: pairs, Span: span}, nil
+	}
+	return nil, fmt.Errorf("cannot inline a value of type %s", typeName(v))
+}
+
+func toExprs(items []Value, span lexer.Span) ([]ast.Expr, error) {
+	exprs := make([]ast.Expr, len(items))
+	for i, item := range items {
+		expr, err := ToExpr(item, span)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = expr
+	}
+	return exprs, nil
+}
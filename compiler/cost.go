@@ -0,0 +1,247 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// loopIterations is how many times a loop is assumed to run when weighing
+// the markup inside it, since the real count is only known at render time
+const loopIterations = 10
+
+// minRatioNodes is the number of nodes below which a view's dynamic node
+// ratio is not checked: a short view such as <p>{text}</p> is all dynamic
+// and still cheap
+const minRatioNodes = 20
+
+// CostReport is the estimated render cost of the views of a project
+type CostReport struct {
+	Views  []*ViewCost         // Sorted by file, then line
+	Errors []*CompilationError // Files that could not be parsed or resolved; their compositions may count as elements
+}
+
+// ViewCost is the estimated render cost of one view, from the markup of its
+// body alone: a composed view counts as one node, whatever it renders
+type ViewCost struct {
+	Name         string
+	File         string
+	Line         int
+	Elements     int      // HTML elements the body writes
+	Compositions int      // Elements instantiating a view
+	LoopDepth    int      // Deepest nesting of for and while loops around markup
+	DynamicNodes int      // Interpolations, expression attributes, spreads and computed tags
+	StaticNodes  int      // Literal text and attributes, and props of compositions left to literal defaults
+	Estimate     int      // Nodes weighted by the loops around them, each assumed to run loopIterations times
+	Exceeded     []string // Thresholds the view exceeds, described
+}
+
+// DynamicRatio returns the share of the view's nodes computed at render time
+func (v *ViewCost) DynamicRatio() float64 {
+	total := v.DynamicNodes + v.StaticNodes
+	if total == 0 {
+		return 0
+	}
+	return float64(v.DynamicNodes) / float64(total)
+}
+
+// CostThresholds bounds the render cost of a view; a zero field is no bound
+type CostThresholds struct {
+	MaxElements     int     // Elements and compositions
+	MaxLoopDepth    int     // Nesting of loops around markup
+	MaxDynamicRatio float64 // Share of dynamic nodes, checked from minRatioNodes nodes up
+	MaxEstimate     int     // Loop-weighted node count
+}
+
+// check records in v.Exceeded the thresholds v exceeds
+func (t CostThresholds) check(v *ViewCost) {
+	if elements := v.Elements + v.Compositions; t.MaxElements > 0 && elements > t.MaxElements {
+		v.Exceeded = append(v.Exceeded, fmt.Sprintf("%d elements, more than %d", elements, t.MaxElements))
+	}
+	if t.MaxLoopDepth > 0 && v.LoopDepth > t.MaxLoopDepth {
+		v.Exceeded = append(v.Exceeded, fmt.Sprintf("loops nested %d deep, more than %d", v.LoopDepth, t.MaxLoopDepth))
+	}
+	if t.MaxDynamicRatio > 0 && v.DynamicNodes+v.StaticNodes >= minRatioNodes && v.DynamicRatio() > t.MaxDynamicRatio {
+		v.Exceeded = append(v.Exceeded, fmt.Sprintf("%.0f%% dynamic nodes, more than %.0f%%", v.DynamicRatio()*100, t.MaxDynamicRatio*100))
+	}
+	if t.MaxEstimate > 0 && v.Estimate > t.MaxEstimate {
+		v.Exceeded = append(v.Exceeded, fmt.Sprintf("estimated cost %d, more than %d", v.Estimate, t.MaxEstimate))
+	}
+}
+
+// Cost resolves every file of the project and estimates the render cost of
+// each view it defines, recording the thresholds each exceeds. Compositions
+// are told from HTML elements the way the compiler binds them. Files with
+// errors are reported in the Errors of the result, which is still returned.
+func (c *MultiFileCompiler) Cost(ctx context.Context, opts MultiFileOptions, thresholds CostThresholds) (*CostReport, error) {
+	p, err := c.resolveProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &CostReport{Errors: p.errors}
+
+	for _, filePath := range p.order {
+		table, ok := p.tables[filePath]
+		if !ok || !p.files[filePath] {
+			continue
+		}
+		for _, view := range table.Views {
+			cost := &ViewCost{
+				Name: view.Name.Token.Lexeme,
+				File: filePath,
+				Line: view.Span.Start.Line,
+			}
+			m := costMeter{cost: cost, compositions: table.ViewElements}
+			m.body(view.Body, 0)
+			thresholds.check(cost)
+			report.Views = append(report.Views, cost)
+		}
+	}
+
+	sort.Slice(report.Views, func(i, j int) bool {
+		a, b := report.Views[i], report.Views[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].File < report.Errors[j].File })
+	return report, nil
+}
+
+// costMeter walks the body of a view, counting its markup into cost
+type costMeter struct {
+	cost         *ViewCost
+	compositions map[*ast.HTMLElement]*ast.ViewStmt
+}
+
+// node counts one rendered node at a loop depth
+func (m *costMeter) node(depth int, dynamic bool) {
+	if dynamic {
+		m.cost.DynamicNodes++
+	} else {
+		m.cost.StaticNodes++
+	}
+	weight := 1
+	for range depth {
+		weight *= loopIterations
+	}
+	m.cost.Estimate += weight
+	m.cost.LoopDepth = max(m.cost.LoopDepth, depth)
+}
+
+// body counts the markup of statements nested in depth loops. Nested
+// functions and classes are left out: they render only when called.
+func (m *costMeter) body(stmts []ast.Stmt, depth int) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.HTMLElement:
+			m.element(s, depth)
+		case *ast.HTMLContent:
+			for _, part := range s.Parts {
+				_, interpolated := part.(*ast.HTMLInterpolation)
+				m.node(depth, interpolated)
+			}
+		case *ast.ExprStmt:
+			// A bare expression in markup renders its value
+			_, literal := s.Expr.(*ast.Literal)
+			m.node(depth, !literal)
+		case *ast.For:
+			m.body(s.Body, depth+1)
+			m.body(s.Else, depth)
+		case *ast.While:
+			m.body(s.Body, depth+1)
+			m.body(s.Else, depth)
+		case *ast.If:
+			m.body(s.Body, depth)
+			m.body(s.Else, depth)
+		case *ast.With:
+			m.body(s.Body, depth)
+		case *ast.Try:
+			m.body(s.Body, depth)
+			for _, except := range s.Excepts {
+				m.body(except.Body, depth)
+			}
+			m.body(s.Else, depth)
+			m.body(s.Finally, depth)
+		case *ast.MatchStmt:
+			for _, c := range s.Cases {
+				m.body(c.Body, depth)
+			}
+		case *ast.MultiStmt:
+			m.body(s.Stmts, depth)
+		}
+	}
+}
+
+// element counts an element, its attributes and its content. The props a
+// composition leaves to literal defaults count as static attributes.
+func (m *costMeter) element(h *ast.HTMLElement, depth int) {
+	view, composition := m.compositions[h]
+	if composition {
+		m.cost.Compositions++
+	} else {
+		m.cost.Elements++
+	}
+	m.node(depth, h.TagExpr != nil)
+	for _, attr := range h.Attributes {
+		_, literal := attr.Value.(*ast.Literal)
+		m.node(depth, attr.Spread || (attr.Value != nil && !literal))
+	}
+	if composition {
+		for range omittedConstants(view, h.Attributes) {
+			m.node(depth, false)
+		}
+	}
+	m.body(h.Content, depth)
+}
+
+// constantProps returns the parameters of a view whose value is known at
+// compile time where it is composed with attributes: literal attribute
+// values, boolean attributes and the literal defaults of the parameters the
+// site leaves out. A spread may set any parameter, so none are known at a
+// site with one.
+func constantProps(view *ast.ViewStmt, attributes []ast.HTMLAttribute) map[string]*ast.Literal {
+	props := make(map[string]*ast.Literal)
+	if view.Params == nil {
+		return props
+	}
+	passed := make(map[string]ast.HTMLAttribute, len(attributes))
+	for _, attr := range attributes {
+		if attr.Spread {
+			return props
+		}
+		passed[attr.Name.Lexeme] = attr
+	}
+
+	for _, param := range view.Params.Parameters {
+		if param == nil || param.Name == nil || param.IsStar || param.IsDoubleStar {
+			continue
+		}
+		name := param.Name.Token.Lexeme
+		if attr, ok := passed[name]; ok {
+			if attr.Value == nil {
+				props[name] = &ast.Literal{Type: ast.LiteralTypeBool, Value: true, Span: attr.Span}
+			} else if literal, ok := attr.Value.(*ast.Literal); ok {
+				props[name] = literal
+			}
+			continue
+		}
+		if literal, ok := param.Default.(*ast.Literal); ok {
+			props[name] = literal
+		}
+	}
+	return props
+}
+
+// omittedConstants returns how many constant props of a composition its
+// attributes leave out
+func omittedConstants(view *ast.ViewStmt, attributes []ast.HTMLAttribute) int {
+	constants := constantProps(view, attributes)
+	for _, attr := range attributes {
+		delete(constants, attr.Name.Lexeme)
+	}
+	return len(constants)
+}
@@ -0,0 +1,147 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+func TestCost(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"ui.psx": `view Button(label: str):
+    <button class="btn">{label}</button>
+`,
+		"page.psx": `from ui import Button
+
+view Table(rows):
+    <table>
+        for row in rows:
+            <tr>
+                for cell in row:
+                    <td>{cell}</td>
+            </tr>
+    </table>
+    <Button label="More" />
+`,
+	})
+	ui := filepath.Join(tmpDir, "ui.psx")
+	page := filepath.Join(tmpDir, "page.psx")
+
+	report, err := NewMultiFileCompiler(nil).Cost(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	}, CostThresholds{MaxElements: 3, MaxLoopDepth: 1, MaxEstimate: 100})
+	if err != nil {
+		t.Fatalf("Cost failed: %v", err)
+	}
+	if len(report.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", report.Errors)
+	}
+
+	expected := []*ViewCost{
+		{
+			Name:         "Table",
+			File:         page,
+			Line:         3,
+			Elements:     3,
+			Compositions: 1,
+			LoopDepth:    2,
+			DynamicNodes: 1,
+			StaticNodes:  5,
+			// <table>, <Button> and its label; <tr>; <td> and {cell}
+			Estimate: 3 + 10 + 2*100,
+			Exceeded: []string{
+				"4 elements, more than 3",
+				"loops nested 2 deep, more than 1",
+				"estimated cost 213, more than 100",
+			},
+		},
+		{
+			Name:         "Button",
+			File:         ui,
+			Line:         1,
+			Elements:     1,
+			DynamicNodes: 1,
+			StaticNodes:  2,
+			Estimate:     3,
+		},
+	}
+	if !reflect.DeepEqual(report.Views, expected) {
+		for _, view := range report.Views {
+			t.Logf("%+v", *view)
+		}
+		t.Errorf("unexpected costs")
+	}
+	if ratio := expected[0].DynamicRatio(); ratio != 1.0/6 {
+		t.Errorf("expected a dynamic ratio of 1/6, got %v", ratio)
+	}
+}
+
+func TestConstantProps(t *testing.T) {
+	view := ast.HView("Button", []*ast.Parameter{
+		ast.HParam("label"),
+		ast.HParamWithDefault("variant", "", ast.S("primary")),
+		ast.HParamWithDefault("size", "", ast.S("md")),
+		ast.HParamWithDefault("items", "", ast.HList()),
+		ast.HParamWithDefault("disabled", "", ast.B(false)),
+	})
+
+	props := constantProps(view, []ast.HTMLAttribute{
+		ast.HAttr("label", ast.N("text")),
+		ast.HAttr("size", ast.S("lg")),
+		ast.HAttr("disabled", nil),
+	})
+	want := map[string]any{
+		"variant":  "primary", // omitted, literal default
+		"size":     "lg",      // literal at the composition site
+		"disabled": true,      // boolean attribute
+	}
+	if len(props) != len(want) {
+		t.Fatalf("expected %d constant props, got %v", len(want), props)
+	}
+	for name, value := range want {
+		if literal, ok := props[name]; !ok || literal.Value != value {
+			t.Errorf("expected %s = %v, got %v", name, value, props[name])
+		}
+	}
+
+	// A spread may set any prop
+	spread := ast.HTMLAttribute{Spread: true, Value: ast.N("extra")}
+	if props := constantProps(view, []ast.HTMLAttribute{ast.HAttr("size", ast.S("lg")), spread}); len(props) != 0 {
+		t.Errorf("expected no constant props with a spread, got %v", props)
+	}
+}
+
+func TestCostConstantProps(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"page.psx": `view Button(label: str, variant: str = "primary", size: str = "md", icons: list = []):
+    <button class={variant}>{label}</button>
+
+view Page(title, extra):
+    <Button label={title} />
+    <Button label={title} {...extra} />
+`,
+	})
+
+	report, err := NewMultiFileCompiler(nil).Cost(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	}, CostThresholds{})
+	if err != nil || len(report.Errors) > 0 {
+		t.Fatalf("Cost failed: %v %v", err, report.Errors)
+	}
+	var page *ViewCost
+	for _, view := range report.Views {
+		if view.Name == "Page" {
+			page = view
+		}
+	}
+	// Both tags; variant and size left to their defaults on the first site,
+	// which a spread may set on the second
+	if page == nil || page.StaticNodes != 4 || page.DynamicNodes != 3 {
+		t.Errorf("expected 4 static and 3 dynamic nodes, got %+v", page)
+	}
+}
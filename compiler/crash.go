@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime/debug"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// InternalError reports a panic inside the compiler. It carries what a bug
+// report needs without the user's source: a hash of the file, the location
+// the compiler was at and the tokens around it with their text redacted.
+type InternalError struct {
+	File       string        // File being compiled
+	Stage      string        // Pipeline stage: "scan", "parse", "resolve", "transform" or "codegen"
+	Value      any           // Value passed to panic
+	Stack      []byte        // Stack trace of the panicking goroutine
+	SourceHash string        // SHA-256 of the file content, empty if unavailable
+	Span       lexer.Span    // Best known source location, zero if unknown
+	Tokens     []lexer.Token // Redacted tokens around Span
+}
+
+func (e *InternalError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("internal compiler error in %s: %v", e.Stage, e.Value)
+	}
+	return fmt.Sprintf("internal compiler error in %s stage of %s: %v", e.Stage, e.File, e.Value)
+}
+
+// crashSite tracks where a compilation is, so a panic can be turned into an
+// InternalError pointing at the failure
+type crashSite struct {
+	file    string
+	content []byte
+	stage   string
+	tokens  []lexer.Token
+	where   func() lexer.Span // Current source location of the running stage
+}
+
+// snippetLines is how many lines around the failure the token snippet covers
+const snippetLines = 2
+
+// internalError builds the error for a recovered panic value. It must be
+// called from the deferred function that recovered, so the stack still shows
+// the panicking frames.
+func (s *crashSite) internalError(value any) *InternalError {
+	ierr := &InternalError{
+		File:  s.file,
+		Stage: s.stage,
+		Value: value,
+		Stack: debug.Stack(),
+	}
+
+	content := s.content
+	if content == nil {
+		content, _ = os.ReadFile(s.file)
+	}
+	if content != nil {
+		sum := sha256.Sum256(content)
+		ierr.SourceHash = hex.EncodeToString(sum[:])
+	}
+
+	if s.where != nil {
+		ierr.Span = s.where()
+	}
+	if ierr.Span.Start.Line > 0 {
+		for _, tok := range s.tokens {
+			if tok.Span.Start.Line >= ierr.Span.Start.Line-snippetLines && tok.Span.Start.Line <= ierr.Span.End.Line+snippetLines {
+				ierr.Tokens = append(ierr.Tokens, redactToken(tok))
+			}
+		}
+	}
+	return ierr
+}
+
+// redactToken drops the text of tokens that carry user content, keeping
+// their kind and position
+func redactToken(tok lexer.Token) lexer.Token {
+	switch tok.Type {
+	case lexer.String, lexer.FStringMiddle, lexer.HTMLTextInline, lexer.HTMLComment, lexer.Illegal:
+		tok.Lexeme = fmt.Sprintf("<redacted %d bytes>", len(tok.Lexeme))
+	}
+	tok.Literal = nil
+	return tok
+}
+
+// tokenSpan returns the span of the token a parser is at
+func tokenSpan(tokens []lexer.Token, current int) lexer.Span {
+	if len(tokens) == 0 {
+		return lexer.Span{}
+	}
+	return tokens[min(current, len(tokens)-1)].Span
+}
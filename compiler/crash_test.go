@@ -0,0 +1,57 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// panickingMetrics panics when the named counter is recorded
+type panickingMetrics struct{ name string }
+
+func (m panickingMetrics) Count(name string, delta int64) {
+	if name == m.name {
+		panic("boom")
+	}
+}
+
+func TestCompileRecoversPanics(t *testing.T) {
+	src := []byte("view Greeting(name: str):\n    <p title=\"secret\">Hello {name}</p>\n")
+	cmp := NewCompilerWithOptions(nil, Options{Metrics: panickingMetrics{name: "nodes"}})
+
+	code, errs := cmp.Compile(context.Background(), File{Name: "greeting.psx", Content: src})
+	if code != nil {
+		t.Errorf("expected no output, got %q", code)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+
+	var ierr *InternalError
+	if !errors.As(errs[0], &ierr) {
+		t.Fatalf("expected an InternalError, got %T: %v", errs[0], errs[0])
+	}
+	if ierr.Stage != "parse" || ierr.File != "greeting.psx" || ierr.Value != "boom" {
+		t.Errorf("unexpected error details: %v", ierr)
+	}
+	if len(ierr.SourceHash) != 64 {
+		t.Errorf("expected a SHA-256 source hash, got %q", ierr.SourceHash)
+	}
+	if !strings.Contains(string(ierr.Stack), "panickingMetrics") {
+		t.Errorf("expected the stack to show the panicking frame:\n%s", ierr.Stack)
+	}
+	if len(ierr.Tokens) == 0 {
+		t.Fatal("expected tokens around the failure")
+	}
+	for _, tok := range ierr.Tokens {
+		if strings.Contains(tok.Lexeme, "secret") || strings.Contains(tok.Lexeme, "Hello") {
+			t.Errorf("token text was not redacted: %s %q", tok.Type, tok.Lexeme)
+		}
+		if tok.Type == lexer.Identifier && tok.Lexeme == "" {
+			t.Errorf("identifier lost its name")
+		}
+	}
+}
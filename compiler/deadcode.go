@@ -0,0 +1,412 @@
+package compiler
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// EntryPoint is a root of dead-code elimination: a whole file, or a single
+// view, function or class in it
+type EntryPoint struct {
+	File string // Path of the .psx file
+	Name string // Top-level definition to keep; empty keeps everything in File
+}
+
+// ParseEntryPoint parses "path/to/file.psx" or "path/to/file.psx:Name"
+func ParseEntryPoint(s string) (EntryPoint, error) {
+	file, name := s, ""
+	if i := strings.LastIndex(s, ":"); i >= 0 && isIdentifier(s[i+1:]) {
+		file, name = s[:i], s[i+1:]
+	}
+	if !strings.HasSuffix(file, ".psx") {
+		return EntryPoint{}, fmt.Errorf("invalid entry point %q: expected a .psx file, optionally followed by :Name", s)
+	}
+	return EntryPoint{File: file, Name: name}, nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && (i == 0 || !('0' <= r && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// binding is a top-level name of a file
+type binding struct {
+	file string
+	name string
+}
+
+// importTarget is what a "from x import name" binding refers to
+type importTarget struct {
+	file string // Resolved project file
+	name string // Name imported from it
+}
+
+// fileScope lists how the top-level statements of a file use and define names
+type fileScope struct {
+	defs    map[string]ast.Stmt            // Views, functions and classes that may be dropped
+	imports map[string]importTarget        // Names bound by "from x import" of project files
+	always  []ast.Stmt                     // Statements that run whenever the file is loaded
+	modules []string                       // Project files imported whole ("import x", wildcards)
+	listed  []string                       // Names listed in __all__, which "from x import *" imports
+	from    map[*ast.ImportFromStmt]string // Resolved file of each project "from" import
+
+	bindsDeprecated bool // Whether @deprecated decorators are a function of the file rather than the marker
+}
+
+// deadCode computes which top-level definitions are reachable from the
+// entry points. Reachability is conservative: any use of a name counts, even
+// one shadowed by a local variable, and statements with side effects
+// (decorated definitions, assignments, calls) are always kept.
+type deadCode struct {
+	scopes  map[string]*fileScope
+	loaded  map[string]bool
+	reached map[binding]bool
+}
+
+// eliminateDeadCode drops the definitions of astMap unreachable from the
+// entry points, and the files that are never loaded. It returns the pruned
+// modules and, per file, the names of the dropped definitions; a dropped
+// file maps to nil.
+func (c *MultiFileCompiler) eliminateDeadCode(astMap map[string]*ast.Module, entries []EntryPoint) (map[string]*ast.Module, map[string][]string, error) {
+	dc := &deadCode{
+		scopes:  make(map[string]*fileScope),
+		loaded:  make(map[string]bool),
+		reached: make(map[binding]bool),
+	}
+	for filePath, module := range astMap {
+		dc.scopes[filePath] = c.scopeOf(filePath, module)
+	}
+
+	for _, entry := range entries {
+		filePath, err := filepath.Abs(entry.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid entry point %s: %w", entry.File, err)
+		}
+		scope, ok := dc.scopes[filePath]
+		if !ok {
+			return nil, nil, fmt.Errorf("entry point %s is not part of the project", entry.File)
+		}
+		if entry.Name == "" {
+			dc.useAll(filePath)
+			continue
+		}
+		if _, defined := scope.defs[entry.Name]; !defined {
+			if _, imported := scope.imports[entry.Name]; !imported {
+				return nil, nil, fmt.Errorf("entry point %s:%s: %s does not define %s", entry.File, entry.Name, entry.File, entry.Name)
+			}
+		}
+		dc.load(filePath)
+		dc.use(filePath, entry.Name)
+	}
+
+	pruned := make(map[string]*ast.Module, len(astMap))
+	eliminated := make(map[string][]string)
+	for filePath, module := range astMap {
+		if !dc.loaded[filePath] {
+			eliminated[filePath] = nil
+			continue
+		}
+		body, dropped := dc.prune(filePath, module.Body)
+		pruned[filePath] = &ast.Module{Body: body, Span: module.Span}
+		if len(dropped) > 0 {
+			eliminated[filePath] = dropped
+		}
+	}
+	return pruned, eliminated, nil
+}
+
+// scopeOf sorts the top-level statements of a file
+func (c *MultiFileCompiler) scopeOf(filePath string, module *ast.Module) *fileScope {
+	scope := &fileScope{
+		defs:    make(map[string]ast.Stmt),
+		imports: make(map[string]importTarget),
+		from:    make(map[*ast.ImportFromStmt]string),
+
+		bindsDeprecated: module.BindsDeprecated(),
+	}
+
+	// Resolved targets of the imports, from the dependency graph
+	node, _ := c.depGraph.GetFileNode(filePath)
+	resolved := make(map[ast.Node][]string)
+	if node != nil {
+		for _, imp := range node.Imports {
+			resolved[imp.Statement] = append(resolved[imp.Statement], imp.ModulePath)
+		}
+	}
+
+	for _, stmt := range module.Body {
+		switch s := stmt.(type) {
+		case *ast.ViewStmt:
+			scope.defs[s.Name.Token.Lexeme] = s
+		case *ast.Function:
+			scope.defs[s.Name.Token.Lexeme] = s
+		case *ast.Class:
+			scope.defs[s.Name.Token.Lexeme] = s
+		case *ast.Decorator:
+			// Decorators run when the definition is evaluated (route
+			// registration), so decorated definitions are kept like any
+			// other statement with side effects, unless the only decorator
+			// is the compile-time @deprecated marker
+			if !scope.bindsDeprecated && onlyDeprecation(s) {
+				scope.defs[definitionName(s.Definition())] = s
+			} else {
+				scope.always = append(scope.always, stmt)
+			}
+		case *ast.ImportStmt:
+			scope.modules = append(scope.modules, resolved[s]...)
+			scope.always = append(scope.always, s)
+		case *ast.ImportFromStmt:
+			targets := resolved[s]
+			if len(targets) == 0 {
+				// Not a project module: kept as written
+				scope.always = append(scope.always, s)
+				continue
+			}
+			scope.from[s] = targets[0]
+			if s.IsWildcard {
+				scope.modules = append(scope.modules, targets[0])
+				scope.always = append(scope.always, s)
+				continue
+			}
+			for _, name := range s.Names {
+				imported := name.DottedName.Names[0].Token.Lexeme
+				local := imported
+				if name.AsName != nil {
+					local = name.AsName.Token.Lexeme
+				}
+				scope.imports[local] = importTarget{file: targets[0], name: imported}
+			}
+		default:
+			scope.always = append(scope.always, stmt)
+			scope.listed = append(scope.listed, allEntries(stmt)...)
+		}
+	}
+	return scope
+}
+
+// load marks a file as imported at runtime, which runs its statements
+func (dc *deadCode) load(filePath string) {
+	scope, ok := dc.scopes[filePath]
+	if !ok || dc.loaded[filePath] {
+		return
+	}
+	dc.loaded[filePath] = true
+
+	for _, module := range scope.modules {
+		dc.useAll(module)
+	}
+	for _, stmt := range scope.always {
+		for _, name := range referencedNames(stmt) {
+			dc.use(filePath, name)
+		}
+	}
+	for _, name := range scope.listed {
+		dc.use(filePath, name)
+	}
+}
+
+// useAll marks every top-level name of a file as used
+func (dc *deadCode) useAll(filePath string) {
+	scope, ok := dc.scopes[filePath]
+	if !ok {
+		return
+	}
+	dc.load(filePath)
+	for name := range scope.defs {
+		dc.use(filePath, name)
+	}
+	for name := range scope.imports {
+		dc.use(filePath, name)
+	}
+}
+
+// use marks a top-level name of a file as used, and what it refers to
+func (dc *deadCode) use(filePath, name string) {
+	b := binding{file: filePath, name: name}
+	if dc.reached[b] {
+		return
+	}
+	dc.reached[b] = true
+
+	scope := dc.scopes[filePath]
+	if def, ok := scope.defs[name]; ok {
+		for _, ref := range referencedNames(def) {
+			dc.use(filePath, ref)
+		}
+	}
+	if target, ok := scope.imports[name]; ok {
+		dc.load(target.file)
+		targetScope, ok := dc.scopes[target.file]
+		if !ok {
+			return
+		}
+		_, isDef := targetScope.defs[target.name]
+		_, isImport := targetScope.imports[target.name]
+		if isDef || isImport {
+			dc.use(target.file, target.name)
+			return
+		}
+		// Not a definition: a submodule or a name bound by another
+		// statement. Keep the module and, for a package, its siblings.
+		dc.useAll(target.file)
+		if filepath.Base(target.file) == "__init__.psx" {
+			dir := filepath.Dir(target.file)
+			for other := range dc.scopes {
+				if filepath.Dir(other) == dir {
+					dc.useAll(other)
+				}
+			}
+		}
+	}
+}
+
+// prune drops the unreachable definitions of a loaded file and the imports
+// of names that are no longer used, returning the kept statements and the
+// names of the dropped definitions
+func (dc *deadCode) prune(filePath string, body []ast.Stmt) ([]ast.Stmt, []string) {
+	scope := dc.scopes[filePath]
+	var kept []ast.Stmt
+	var dropped []string
+
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.ViewStmt, *ast.Function, *ast.Class:
+			name := definitionName(s)
+			if !dc.reached[binding{file: filePath, name: name}] {
+				dropped = append(dropped, name)
+				continue
+			}
+		case *ast.Decorator:
+			if name := definitionName(s.Definition()); !scope.bindsDeprecated && onlyDeprecation(s) && !dc.reached[binding{file: filePath, name: name}] {
+				dropped = append(dropped, name)
+				continue
+			}
+		case *ast.ImportFromStmt:
+			if _, project := scope.from[s]; project && !s.IsWildcard {
+				var names []*ast.ImportName
+				for _, name := range s.Names {
+					local := name.DottedName.Names[0].Token.Lexeme
+					if name.AsName != nil {
+						local = name.AsName.Token.Lexeme
+					}
+					if dc.reached[binding{file: filePath, name: local}] {
+						names = append(names, name)
+					}
+				}
+				if len(names) == 0 {
+					continue
+				}
+				if len(names) < len(s.Names) {
+					trimmed := *s
+					trimmed.Names = names
+					stmt = &trimmed
+				}
+			}
+		}
+		kept = append(kept, stmt)
+	}
+
+	sort.Strings(dropped)
+	return kept, dropped
+}
+
+func definitionName(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.ViewStmt:
+		return s.Name.Token.Lexeme
+	case *ast.Function:
+		return s.Name.Token.Lexeme
+	case *ast.Class:
+		return s.Name.Token.Lexeme
+	}
+	return ""
+}
+
+// onlyDeprecation reports whether every decorator of a stack is the
+// @deprecated marker, which leaves nothing to run at import time
+func onlyDeprecation(d *ast.Decorator) bool {
+	for stmt := ast.Stmt(d); ; {
+		decorator, ok := stmt.(*ast.Decorator)
+		if !ok {
+			return definitionName(stmt) != ""
+		}
+		if _, marker := decorator.Deprecation(); !marker {
+			return false
+		}
+		stmt = decorator.Stmt
+	}
+}
+
+// allEntries returns the strings a statement puts in __all__: those of an
+// assignment to it, or of a call of one of its methods such as
+// __all__.append("Card"). Every string of a computed value counts too.
+func allEntries(stmt ast.Stmt) []string {
+	var value ast.Node
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if len(s.Targets) != 1 || !isAllName(s.Targets[0]) {
+			return nil
+		}
+		value = s.Value
+	case *ast.ExprStmt:
+		call, ok := s.Expr.(*ast.Call)
+		if !ok {
+			return nil
+		}
+		if method, ok := call.Callee.(*ast.Attribute); !ok || !isAllName(method.Object) {
+			return nil
+		}
+		value = call
+	default:
+		return nil
+	}
+
+	var names []string
+	ast.Inspect(value, func(n ast.Node) bool {
+		if literal, ok := n.(*ast.Literal); ok {
+			if name, ok := literal.Value.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// isAllName reports whether expr is the name __all__
+func isAllName(expr ast.Expr) bool {
+	name, ok := expr.(*ast.Name)
+	return ok && name.Token.Lexeme == "__all__"
+}
+
+// referencedNames returns the identifiers and component tags used in node
+func referencedNames(node ast.Node) []string {
+	seen := make(map[string]bool)
+	var names []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		var name string
+		switch n := n.(type) {
+		case *ast.Name:
+			name = n.Token.Lexeme
+		case *ast.HTMLElement:
+			name = n.TagName.Lexeme
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+		return true
+	})
+	return names
+}
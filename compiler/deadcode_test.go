@@ -0,0 +1,168 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntryPoint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected EntryPoint
+		wantErr  bool
+	}{
+		{input: "pages/home.psx", expected: EntryPoint{File: "pages/home.psx"}},
+		{input: "pages/home.psx:Home", expected: EntryPoint{File: "pages/home.psx", Name: "Home"}},
+		{input: `C:\app\home.psx`, expected: EntryPoint{File: `C:\app\home.psx`}},
+		{input: "pages/home.py", wantErr: true},
+		{input: "pages/home.psx:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEntryPoint(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEntryPoint(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.expected {
+			t.Errorf("ParseEntryPoint(%q) = %+v, want %+v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDeadCodeElimination(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/ui.psx": `def css(*names):
+    return " ".join(names)
+
+def unused_helper():
+    return 1
+
+view Button(label: str):
+    <button class={css("btn")}>{label}</button>
+
+view Badge(text: str):
+    <span>{text}</span>
+
+@deprecated("use Badge")
+view Unused():
+    <p>never rendered</p>
+`,
+		"components/legacy.psx": `view Old():
+    <marquee>old</marquee>
+`,
+		"pages/home.psx": `from components.ui import Button, Badge
+
+view Home():
+    <Button label="Go" />
+
+view Draft():
+    <Badge text="draft" />
+`,
+	})
+	home := filepath.Join(tmpDir, "pages/home.psx")
+	ui := filepath.Join(tmpDir, "components/ui.psx")
+	legacy := filepath.Join(tmpDir, "components/legacy.psx")
+
+	compile := func(entries ...EntryPoint) *MultiFileOutput {
+		t.Helper()
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir:     tmpDir,
+			Files:       []string{tmpDir},
+			EntryPoints: entries,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+		}
+		return output
+	}
+
+	output := compile(EntryPoint{File: home, Name: "Home"})
+	expected := map[string][]string{
+		home:   {"Draft"},
+		ui:     {"Badge", "Unused", "unused_helper"},
+		legacy: nil,
+	}
+	if !reflect.DeepEqual(output.Eliminated, expected) {
+		t.Errorf("Eliminated = %v, want %v", output.Eliminated, expected)
+	}
+	if _, ok := output.CompiledFiles[legacy]; ok {
+		t.Errorf("expected the unreachable file not to be output")
+	}
+
+	homeCode := string(output.CompiledFiles[home])
+	if !strings.Contains(homeCode, "from components.ui import Button\n") {
+		t.Errorf("expected the import of the dropped view to be trimmed, got:\n%s", homeCode)
+	}
+	uiCode := string(output.CompiledFiles[ui])
+	for _, kept := range []string{"def css(", "class Button("} {
+		if !strings.Contains(uiCode, kept) {
+			t.Errorf("expected %q to be kept, got:\n%s", kept, uiCode)
+		}
+	}
+
+	// A whole-file entry keeps everything it defines
+	output = compile(EntryPoint{File: home})
+	if names := output.Eliminated[ui]; !reflect.DeepEqual(names, []string{"Unused", "unused_helper"}) {
+		t.Errorf("expected Badge to be kept for Draft, dropped %v", names)
+	}
+
+	_, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir:     tmpDir,
+		Files:       []string{tmpDir},
+		EntryPoints: []EntryPoint{{File: home, Name: "Missing"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "does not define Missing") {
+		t.Errorf("expected an unknown entry point name to be reported, got %v", err)
+	}
+}
+
+func TestDeadCodeEliminationKeepsAll(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/ui.psx": `__all__ = ["Button", "Badge"]
+__all__.append("Icon")
+
+view Button(label: str):
+    <button>{label}</button>
+
+view Badge(text: str):
+    <span>{text}</span>
+
+view Icon(name: str):
+    <i class={name}></i>
+
+view Internal():
+    <p>internal</p>
+`,
+		"pages/home.psx": `from components.ui import Button
+
+view Home():
+    <Button label="Go" />
+`,
+	})
+	home := filepath.Join(tmpDir, "pages/home.psx")
+	ui := filepath.Join(tmpDir, "components/ui.psx")
+
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir:     tmpDir,
+		Files:       []string{tmpDir},
+		EntryPoints: []EntryPoint{{File: home, Name: "Home"}},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	// Python code importing * from the module imports every name of __all__
+	if names := output.Eliminated[ui]; !reflect.DeepEqual(names, []string{"Internal"}) {
+		t.Errorf("expected only Internal to be dropped, dropped %v", names)
+	}
+	uiCode := string(output.CompiledFiles[ui])
+	for _, kept := range []string{"class Button(", "class Badge(", "class Icon("} {
+		if !strings.Contains(uiCode, kept) {
+			t.Errorf("expected %q to be kept, got:\n%s", kept, uiCode)
+		}
+	}
+}
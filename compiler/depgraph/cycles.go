@@ -1,8 +1,11 @@
 package depgraph
 
+import "sort"
+
 // DetectCycles finds all cycles in the dependency graph using depth-first search.
 // Returns a list of cycles, where each cycle is a path of files forming a loop.
-// Returns nil if no cycles are found.
+// Returns nil if no cycles are found. Deferred dependencies are not followed:
+// a cycle they close resolves when the function importing is called.
 func (g *DependencyGraph) DetectCycles() ([][]string, error) {
 	visited := make(map[string]bool)
 	recStack := make(map[string]bool)
@@ -17,7 +20,9 @@ func (g *DependencyGraph) DetectCycles() ([][]string, error) {
 	}
 
 	if len(cycles) > 0 {
-		return cycles, NewCycleError(cycles)
+		err := NewCycleError(cycles)
+		err.Breaks = g.SuggestCycleBreaks()
+		return cycles, err
 	}
 	return nil, nil
 }
@@ -65,3 +70,150 @@ func extractCycle(path []string, cycleTo string) []string {
 	// If not found in path (shouldn't happen), return the full path
 	return append(path, cycleTo)
 }
+
+// CycleBreak is an import that, together with the other breaks suggested
+// alongside it, closes every cycle
+type CycleBreak struct {
+	From string // File holding the import
+	To   string // File it imports
+}
+
+// SuggestCycleBreaks returns a small set of imports whose removal breaks
+// every cycle, sorted, or nil when there is no cycle. Only imports run when
+// a module is imported are considered: moving one of them into the
+// functions that use it, or out of its file, breaks the cycles through it.
+//
+// Finding the smallest such set, a minimum feedback arc set, is NP-hard.
+// The Eades-Lin-Smyth heuristic orders the files so that few imports point
+// backwards, and the backward imports that no longer close a cycle once
+// the others are removed are dropped, so every suggested break is needed.
+func (g *DependencyGraph) SuggestCycleBreaks() []CycleBreak {
+	order := g.feedbackOrder()
+	position := make(map[string]int, len(order))
+	for i, file := range order {
+		position[file] = i
+	}
+
+	// Keep the forward imports, which cannot form a cycle, and collect the
+	// backward ones
+	kept := make(map[string][]string)
+	var candidates []CycleBreak
+	for _, from := range order {
+		for _, to := range g.edges[from] {
+			if position[to] > position[from] {
+				kept[from] = append(kept[from], to)
+			} else {
+				candidates = append(candidates, CycleBreak{From: from, To: to})
+			}
+		}
+	}
+	sortBreaks(candidates)
+
+	// Restore the backward imports that close no cycle with what is kept
+	var breaks []CycleBreak
+	for _, candidate := range candidates {
+		if candidate.From != candidate.To && !reaches(kept, candidate.To, candidate.From) {
+			kept[candidate.From] = append(kept[candidate.From], candidate.To)
+			continue
+		}
+		breaks = append(breaks, candidate)
+	}
+	return breaks
+}
+
+// feedbackOrder orders the files with the Eades-Lin-Smyth heuristic: sinks
+// go last and sources first as they appear, and otherwise the file whose
+// imports outnumber its importers the most goes first. Ties are broken by
+// path, so the order is deterministic.
+func (g *DependencyGraph) feedbackOrder() []string {
+	remaining := make(map[string]bool, len(g.nodes))
+	for file := range g.nodes {
+		remaining[file] = true
+	}
+	importers := make(map[string][]string)
+	for from, deps := range g.edges {
+		for _, to := range deps {
+			importers[to] = append(importers[to], from)
+		}
+	}
+	degree := func(files []string, self string) int {
+		n := 0
+		for _, file := range files {
+			if remaining[file] && file != self {
+				n++
+			}
+		}
+		return n
+	}
+
+	var head, tail []string
+	for len(remaining) > 0 {
+		files := make([]string, 0, len(remaining))
+		for file := range remaining {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+
+		var sinks, sources []string
+		for _, file := range files {
+			if degree(g.edges[file], file) == 0 {
+				sinks = append(sinks, file)
+			} else if degree(importers[file], file) == 0 {
+				sources = append(sources, file)
+			}
+		}
+		switch {
+		case len(sinks) > 0:
+			for _, file := range sinks {
+				delete(remaining, file)
+			}
+			tail = append(sinks, tail...)
+		case len(sources) > 0:
+			for _, file := range sources {
+				delete(remaining, file)
+			}
+			head = append(head, sources...)
+		default:
+			best, bestDelta := "", 0
+			for _, file := range files {
+				delta := degree(g.edges[file], file) - degree(importers[file], file)
+				if best == "" || delta > bestDelta {
+					best, bestDelta = file, delta
+				}
+			}
+			delete(remaining, best)
+			head = append(head, best)
+		}
+	}
+	return append(head, tail...)
+}
+
+// reaches reports whether to can be reached from from along edges
+func reaches(edges map[string][]string, from, to string) bool {
+	seen := map[string]bool{from: true}
+	stack := []string{from}
+	for len(stack) > 0 {
+		file := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if file == to {
+			return true
+		}
+		for _, dep := range edges[file] {
+			if !seen[dep] {
+				seen[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	return false
+}
+
+// sortBreaks sorts breaks by importing file, then imported file
+func sortBreaks(breaks []CycleBreak) {
+	sort.Slice(breaks, func(i, j int) bool {
+		if breaks[i].From != breaks[j].From {
+			return breaks[i].From < breaks[j].From
+		}
+		return breaks[i].To < breaks[j].To
+	})
+}
@@ -34,6 +34,13 @@ Create a new graph and add files:
 		// Compile file...
 	}
 
+Files that do not depend on each other can be compiled concurrently.
+GetCompilationLayers groups the files into layers: every file depends only
+on files of earlier layers, and each layer is sorted for determinism:
+
+	layers, err := graph.GetCompilationLayers()
+	// layers == [[/project/a.psx] [/project/b.psx] [/project/c.psx]]
+
 # Extract Imports from AST
 
 The package provides import extraction from parsed AST:
@@ -46,12 +53,54 @@ The package provides import extraction from parsed AST:
 
 	// Build graph from imports
 	for _, imp := range imports {
-		graph.AddDependency("/project/main.psx", imp.ModulePath)
+		if imp.Deferred {
+			graph.AddDeferredDependency("/project/main.psx", imp.ModulePath)
+		} else {
+			graph.AddDependency("/project/main.psx", imp.ModulePath)
+		}
 	}
 
+Imports inside function and view bodies are marked Deferred: Python only
+runs them when the function is called, after both modules have loaded, so
+they may import a module that imports the file back. Deferred dependencies
+do not order compilation and are ignored by cycle detection, but they count
+for GetDependencies, GetDependents and GetAffected. Imports under a
+top-level if, try or with still run on import and are hard dependencies.
+
+# Incremental Updates
+
+Long-lived processes such as watch mode patch the graph instead of building
+it again. With a resolver set, UpdateFile adds a file or replaces its AST and
+dependencies with those of its current imports, and RemoveFile drops a file
+and the dependencies on it:
+
+	graph.SetResolver(resolver)
+	graph.UpdateFile("/project/b.psx", editedModuleB)
+	graph.RemoveFile("/project/c.psx")
+
+The compilation order and layers are kept between calls and only computed
+again once files are added or removed or hard dependencies change, so
+editing a file without touching its top-level imports costs no sort. Imports
+of files not in the graph stay in the importing file's Imports and become
+dependencies when those files are added.
+
+# Export
+
+Export turns the graph into labelled nodes and edges for display, optionally
+collapsed to one node per package directory, restricted to what an entry file
+depends on, and with import cycles marked. The result is written as Graphviz
+DOT, JSON or a Mermaid flowchart:
+
+	export, err := graph.Export(depgraph.ExportOptions{
+		Root:             "/project",
+		CollapsePackages: true,
+		HighlightCycles:  true,
+	})
+	err = export.WriteDOT(os.Stdout)
+
 # Algorithms
 
-The package uses two well-known graph algorithms:
+The package uses four well-known graph algorithms:
 
 Topological Sort (Kahn's Algorithm):
   - Determines valid compilation order for files
@@ -63,6 +112,15 @@ Cycle Detection (Depth-First Search):
   - Returns the actual paths forming cycles
   - Useful for detailed error reporting
 
+Strongly Connected Components (Tarjan's Algorithm):
+  - Marks every node and edge of a cycle when exporting
+  - Time complexity: O(V + E)
+
+Feedback Arc Set (Eades-Lin-Smyth Heuristic):
+  - Suggests imports whose removal breaks every cycle (SuggestCycleBreaks)
+  - Not always the smallest set, but every suggested import is needed
+  - Time complexity: O(V² + V·E)
+
 # Error Handling
 
 Circular dependencies are reported with detailed paths:
@@ -80,8 +138,14 @@ Circular dependencies are reported with detailed paths:
 		//     /project/b.psx
 		//      ↓ imports
 		//     /project/a.psx
+		//
+		// Suggestions:
+		//   consider moving the import of /project/a.psx out of module level in /project/b.psx, into the functions that use it
 	}
 
+The suggestions come from cycleErr.Breaks, computed by SuggestCycleBreaks,
+which tools can also call directly on a graph.
+
 # Integration
 
 This package is designed to integrate with:
@@ -7,7 +7,8 @@ import (
 
 // CycleError represents a circular dependency error
 type CycleError struct {
-	Cycles [][]string // List of cycles, each cycle is a path of files
+	Cycles [][]string   // List of cycles, each cycle is a path of files
+	Breaks []CycleBreak // Imports whose removal breaks every cycle, see SuggestCycleBreaks
 }
 
 // Error returns a formatted error message showing all detected cycles
@@ -26,9 +27,26 @@ func (e *CycleError) Error() string {
 		sb.WriteString("\n")
 	}
 
+	if len(e.Breaks) > 0 {
+		sb.WriteString("Suggestions:\n")
+		for _, hint := range e.Hints() {
+			sb.WriteString(fmt.Sprintf("  %s\n", hint))
+		}
+	}
+
 	return sb.String()
 }
 
+// Hints returns one suggestion per import to break, such as "consider
+// moving the import of b.psx out of module level in a.psx"
+func (e *CycleError) Hints() []string {
+	hints := make([]string, 0, len(e.Breaks))
+	for _, b := range e.Breaks {
+		hints = append(hints, fmt.Sprintf("consider moving the import of %s out of module level in %s, into the functions that use it", b.To, b.From))
+	}
+	return hints
+}
+
 // NewCycleError creates a new cycle error
 func NewCycleError(cycles [][]string) *CycleError {
 	return &CycleError{Cycles: cycles}
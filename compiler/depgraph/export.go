@@ -0,0 +1,309 @@
+package depgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ExportOptions selects what Export shows of the graph
+type ExportOptions struct {
+	Root             string // Files are labelled relative to it; files outside it are external
+	CollapsePackages bool   // One node per directory instead of one per file
+	HighlightCycles  bool   // Mark the nodes and edges of import cycles
+	Entry            string // Only show this file and the files it depends on, directly or transitively; "" shows all
+}
+
+// Export is the graph as shown to people: labelled nodes, for files or
+// packages, and the imports between them
+type Export struct {
+	Nodes []*ExportNode // Sorted by ID
+	Edges []*ExportEdge // Sorted by From, then To
+}
+
+// ExportNode is a file, or a package when packages are collapsed
+type ExportNode struct {
+	ID       string   `json:"id"`                 // Path relative to the root, slash-separated; packages end in '/'
+	Files    []string `json:"files"`              // Files the node stands for, sorted
+	External bool     `json:"external,omitempty"` // Outside the root, such as a shared library
+	Cycle    bool     `json:"cycle,omitempty"`    // Part of an import cycle (with HighlightCycles)
+}
+
+// ExportEdge is one node importing another
+type ExportEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Deferred bool   `json:"deferred,omitempty"` // Only imported inside function and view bodies
+	Cycle    bool   `json:"cycle,omitempty"`    // Part of an import cycle (with HighlightCycles)
+}
+
+// Export builds the exported form of the graph. Deferred imports are kept
+// as deferred edges but never form cycles. With CollapsePackages, imports
+// between files of the same package are dropped and cycles are those
+// between packages.
+func (g *DependencyGraph) Export(opts ExportOptions) (*Export, error) {
+	files := g.GetAllFiles()
+	if opts.Entry != "" {
+		if !g.HasFile(opts.Entry) {
+			return nil, fmt.Errorf("entry file not in graph: %s", opts.Entry)
+		}
+		files = g.closure(opts.Entry)
+	}
+	sort.Strings(files)
+	included := make(map[string]bool, len(files))
+	for _, file := range files {
+		included[file] = true
+	}
+
+	export := &Export{}
+	nodes := make(map[string]*ExportNode)
+	nodeOf := make(map[string]string, len(files))
+	for _, file := range files {
+		id, external := exportID(file, opts)
+		node, ok := nodes[id]
+		if !ok {
+			node = &ExportNode{ID: id, External: external}
+			nodes[id] = node
+			export.Nodes = append(export.Nodes, node)
+		}
+		node.Files = append(node.Files, file)
+		nodeOf[file] = id
+	}
+
+	edges := make(map[[2]string]*ExportEdge)
+	for _, file := range files {
+		for _, dep := range g.GetDependencies(file) {
+			if !included[dep] || nodeOf[file] == nodeOf[dep] && opts.CollapsePackages {
+				continue
+			}
+			deferred := !slices.Contains(g.edges[file], dep)
+			key := [2]string{nodeOf[file], nodeOf[dep]}
+			if edge, ok := edges[key]; ok {
+				edge.Deferred = edge.Deferred && deferred
+				continue
+			}
+			edge := &ExportEdge{From: key[0], To: key[1], Deferred: deferred}
+			edges[key] = edge
+			export.Edges = append(export.Edges, edge)
+		}
+	}
+
+	sort.Slice(export.Nodes, func(i, j int) bool { return export.Nodes[i].ID < export.Nodes[j].ID })
+	sort.Slice(export.Edges, func(i, j int) bool {
+		a, b := export.Edges[i], export.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	if opts.HighlightCycles {
+		export.markCycles(nodes)
+	}
+	return export, nil
+}
+
+// closure returns file and the files it depends on, directly or
+// transitively, deferred dependencies included
+func (g *DependencyGraph) closure(file string) []string {
+	seen := map[string]bool{file: true}
+	queue := []string{file}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.GetDependencies(current) {
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	files := make([]string, 0, len(seen))
+	for f := range seen {
+		files = append(files, f)
+	}
+	return files
+}
+
+// exportID returns the ID of the node showing file, and whether the file is
+// outside the root
+func exportID(file string, opts ExportOptions) (string, bool) {
+	label, external := file, opts.Root != ""
+	if rel, err := filepath.Rel(opts.Root, file); external && err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		label, external = rel, false
+	}
+	if dir := filepath.Dir(label); opts.CollapsePackages && dir != "." {
+		return filepath.ToSlash(dir) + "/", external
+	}
+	return filepath.ToSlash(label), external
+}
+
+// markCycles marks the nodes and edges of import cycles: the edges between
+// nodes of the same strongly connected component, following only imports
+// that run when a module is imported
+func (e *Export) markCycles(nodes map[string]*ExportNode) {
+	successors := make(map[string][]string)
+	for _, edge := range e.Edges {
+		if !edge.Deferred {
+			successors[edge.From] = append(successors[edge.From], edge.To)
+		}
+	}
+
+	// Tarjan's algorithm
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	component := make(map[string]int)
+	var stack []string
+	next, components := 0, 0
+	var connect func(id string)
+	connect = func(id string) {
+		index[id], lowlink[id] = next, next
+		next++
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, succ := range successors[id] {
+			if _, visited := index[succ]; !visited {
+				connect(succ)
+				lowlink[id] = min(lowlink[id], lowlink[succ])
+			} else if onStack[succ] {
+				lowlink[id] = min(lowlink[id], index[succ])
+			}
+		}
+		if lowlink[id] == index[id] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component[top] = components
+				if top == id {
+					break
+				}
+			}
+			components++
+		}
+	}
+	for _, node := range e.Nodes {
+		if _, visited := index[node.ID]; !visited {
+			connect(node.ID)
+		}
+	}
+
+	for _, edge := range e.Edges {
+		if !edge.Deferred && component[edge.From] == component[edge.To] {
+			edge.Cycle = true
+			nodes[edge.From].Cycle = true
+			nodes[edge.To].Cycle = true
+		}
+	}
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Deferred imports
+// and external nodes are dashed, and cycles are red.
+func (e *Export) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph topple {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range e.Nodes {
+		var attrs []string
+		if node.External {
+			attrs = append(attrs, "style=dashed")
+		}
+		if node.Cycle {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s%s;\n", dotQuote(node.ID), dotAttributes(attrs))
+	}
+	for _, edge := range e.Edges {
+		var attrs []string
+		if edge.Deferred {
+			attrs = append(attrs, "style=dashed")
+		}
+		if edge.Cycle {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", dotQuote(edge.From), dotQuote(edge.To), dotAttributes(attrs))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotAttributes formats a DOT attribute list, or "" when there is none
+func dotAttributes(attrs []string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(attrs, ", ") + "]"
+}
+
+// dotQuote quotes a DOT ID, escaping quotes and backslashes
+func dotQuote(id string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"`
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Deferred imports are
+// dotted, external nodes dashed, and cycles are red.
+func (e *Export) WriteMermaid(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(e.Nodes))
+	var external, cycle []string
+	for i, node := range e.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[node.ID], strings.ReplaceAll(node.ID, `"`, "#quot;"))
+		if node.External {
+			external = append(external, ids[node.ID])
+		}
+		if node.Cycle {
+			cycle = append(cycle, ids[node.ID])
+		}
+	}
+	var cycleEdges []string
+	for i, edge := range e.Edges {
+		arrow := "-->"
+		if edge.Deferred {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+		if edge.Cycle {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+	if len(external) > 0 {
+		b.WriteString("  classDef external stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&b, "  class %s external\n", strings.Join(external, ","))
+	}
+	if len(cycle) > 0 {
+		b.WriteString("  classDef cycle stroke:red\n")
+		fmt.Fprintf(&b, "  class %s cycle\n", strings.Join(cycle, ","))
+		fmt.Fprintf(&b, "  linkStyle %s stroke:red\n", strings.Join(cycleEdges, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the graph as a JSON object with "nodes" and "edges"
+func (e *Export) WriteJSON(w io.Writer) error {
+	out := struct {
+		Nodes []*ExportNode `json:"nodes"`
+		Edges []*ExportEdge `json:"edges"`
+	}{Nodes: e.Nodes, Edges: e.Edges}
+	if out.Nodes == nil {
+		out.Nodes = []*ExportNode{}
+	}
+	if out.Edges == nil {
+		out.Edges = []*ExportEdge{}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,172 @@
+package depgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportGraph builds a/x -> b/y -> a/z (deferred), b/y -> c.psx -> b/w -> b/y,
+// and /lib/ui.psx imported by c.psx
+func exportGraph(t *testing.T) *DependencyGraph {
+	t.Helper()
+	graph := NewGraph()
+	for _, file := range []string{"/p/a/x.psx", "/p/a/z.psx", "/p/b/y.psx", "/p/b/w.psx", "/p/c.psx", "/lib/ui.psx"} {
+		graph.AddFile(file, createEmptyModule())
+	}
+	graph.AddDependency("/p/a/x.psx", "/p/b/y.psx")
+	graph.AddDeferredDependency("/p/b/y.psx", "/p/a/z.psx")
+	graph.AddDependency("/p/b/y.psx", "/p/c.psx")
+	graph.AddDependency("/p/c.psx", "/p/b/w.psx")
+	graph.AddDependency("/p/b/w.psx", "/p/b/y.psx")
+	graph.AddDependency("/p/c.psx", "/lib/ui.psx")
+	return graph
+}
+
+func edgeList(export *Export) []string {
+	var edges []string
+	for _, edge := range export.Edges {
+		desc := edge.From + " -> " + edge.To
+		if edge.Deferred {
+			desc += " deferred"
+		}
+		if edge.Cycle {
+			desc += " cycle"
+		}
+		edges = append(edges, desc)
+	}
+	return edges
+}
+
+func TestExport(t *testing.T) {
+	graph := exportGraph(t)
+
+	export, err := graph.Export(ExportOptions{Root: "/p", HighlightCycles: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	expected := []string{
+		"a/x.psx -> b/y.psx",
+		"b/w.psx -> b/y.psx cycle",
+		"b/y.psx -> a/z.psx deferred",
+		"b/y.psx -> c.psx cycle",
+		"c.psx -> /lib/ui.psx",
+		"c.psx -> b/w.psx cycle",
+	}
+	if got := edgeList(export); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected edges %v, got %v", expected, got)
+	}
+	var cycle, external []string
+	for _, node := range export.Nodes {
+		if node.Cycle {
+			cycle = append(cycle, node.ID)
+		}
+		if node.External {
+			external = append(external, node.ID)
+		}
+	}
+	if want := []string{"b/w.psx", "b/y.psx", "c.psx"}; !reflect.DeepEqual(cycle, want) {
+		t.Errorf("expected cycle nodes %v, got %v", want, cycle)
+	}
+	if want := []string{"/lib/ui.psx"}; !reflect.DeepEqual(external, want) {
+		t.Errorf("expected external nodes %v, got %v", want, external)
+	}
+}
+
+func TestExport_CollapsePackages(t *testing.T) {
+	graph := exportGraph(t)
+
+	export, err := graph.Export(ExportOptions{Root: "/p", CollapsePackages: true, HighlightCycles: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	// a -> b is only a cycle through the deferred b/y -> a/z
+	expected := []string{
+		"a/ -> b/",
+		"b/ -> a/ deferred",
+		"b/ -> c.psx cycle",
+		"c.psx -> /lib/",
+		"c.psx -> b/ cycle",
+	}
+	if got := edgeList(export); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected edges %v, got %v", expected, got)
+	}
+	if files := export.Nodes[1].Files; export.Nodes[1].ID != "a/" || len(files) != 2 {
+		t.Errorf("expected package a/ to hold 2 files, got %s %v", export.Nodes[1].ID, files)
+	}
+}
+
+func TestExport_Entry(t *testing.T) {
+	graph := exportGraph(t)
+
+	export, err := graph.Export(ExportOptions{Root: "/p", Entry: "/p/c.psx"})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	var ids []string
+	for _, node := range export.Nodes {
+		ids = append(ids, node.ID)
+	}
+	// c.psx reaches a/z.psx through the deferred import of b/y.psx, but not a/x.psx
+	if want := []string{"/lib/ui.psx", "a/z.psx", "b/w.psx", "b/y.psx", "c.psx"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected nodes %v, got %v", want, ids)
+	}
+	for _, edge := range export.Edges {
+		if edge.Cycle {
+			t.Errorf("expected no cycles without HighlightCycles, got %s -> %s", edge.From, edge.To)
+		}
+	}
+
+	if _, err := graph.Export(ExportOptions{Entry: "/p/missing.psx"}); err == nil {
+		t.Errorf("expected an error for an entry outside the graph")
+	}
+}
+
+func TestExport_Formats(t *testing.T) {
+	graph := NewGraph()
+	graph.AddFile("/p/a.psx", createEmptyModule())
+	graph.AddFile("/p/b.psx", createEmptyModule())
+	graph.AddDependency("/p/a.psx", "/p/b.psx")
+	graph.AddDependency("/p/b.psx", "/p/a.psx")
+	export, err := graph.Export(ExportOptions{Root: "/p", HighlightCycles: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var dot bytes.Buffer
+	if err := export.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`digraph topple {`, `"a.psx" [color=red];`, `"a.psx" -> "b.psx" [color=red];`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("expected DOT output to contain %q, got:\n%s", want, dot.String())
+		}
+	}
+
+	var mermaid bytes.Buffer
+	if err := export.WriteMermaid(&mermaid); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"flowchart LR\n", `n0["a.psx"]`, "n0 --> n1", "class n0,n1 cycle", "linkStyle 0,1 stroke:red"} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("expected Mermaid output to contain %q, got:\n%s", want, mermaid.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := export.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Nodes []ExportNode `json:"nodes"`
+		Edges []ExportEdge `json:"edges"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded.Nodes) != 2 || len(decoded.Edges) != 2 || !decoded.Edges[0].Cycle {
+		t.Errorf("unexpected JSON graph: %s", out.String())
+	}
+}
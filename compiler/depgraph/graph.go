@@ -2,13 +2,25 @@ package depgraph
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/module"
 )
 
 // DependencyGraph represents dependencies between files
 type DependencyGraph struct {
-	nodes map[string]*FileNode // File path -> node
-	edges map[string][]string  // File path -> dependencies
+	nodes    map[string]*FileNode // File path -> node
+	edges    map[string][]string  // File path -> dependencies
+	deferred map[string][]string  // File path -> dependencies only imported inside functions and views
+
+	resolver module.Resolver // Resolves the imports of files given to UpdateFile
+
+	// Compilation order and layers, kept until the files or hard
+	// dependencies change; nil when not computed
+	order  []string
+	layers [][]string
 }
 
 // FileNode represents a single file in the graph
@@ -24,6 +36,7 @@ type Import struct {
 	ModulePath string   // Resolved file path
 	Names      []string // Imported names (empty for "import x")
 	IsWildcard bool     // True for "from x import *"
+	Deferred   bool     // Inside a function or view body, so not run when the module is imported
 	Location   Location // Source location
 }
 
@@ -36,8 +49,9 @@ type Location struct {
 // NewGraph creates a new dependency graph
 func NewGraph() *DependencyGraph {
 	return &DependencyGraph{
-		nodes: make(map[string]*FileNode),
-		edges: make(map[string][]string),
+		nodes:    make(map[string]*FileNode),
+		edges:    make(map[string][]string),
+		deferred: make(map[string][]string),
 	}
 }
 
@@ -53,6 +67,21 @@ func (g *DependencyGraph) AddFile(filePath string, module *ast.Module) error {
 		Imports:  []*Import{},
 	}
 	g.edges[filePath] = []string{}
+	g.invalidateOrder()
+
+	// Files whose imports were recorded before this one was added now depend on it
+	for from, node := range g.nodes {
+		for _, imp := range node.Imports {
+			if imp.ModulePath != filePath {
+				continue
+			}
+			if imp.Deferred {
+				g.AddDeferredDependency(from, filePath)
+			} else {
+				g.AddDependency(from, filePath)
+			}
+		}
+	}
 
 	return nil
 }
@@ -74,10 +103,34 @@ func (g *DependencyGraph) AddDependency(from, to string) error {
 	}
 
 	g.edges[from] = append(g.edges[from], to)
+	g.invalidateOrder()
+	return nil
+}
+
+// AddDeferredDependency adds a dependency edge for an import that only runs
+// inside a function or view body. Deferred edges make a file depend on
+// another for recompilation, but they do not order compilation and may
+// close a cycle.
+func (g *DependencyGraph) AddDeferredDependency(from, to string) error {
+	if _, exists := g.nodes[from]; !exists {
+		return fmt.Errorf("source file not in graph: %s", from)
+	}
+	if _, exists := g.nodes[to]; !exists {
+		return fmt.Errorf("target file not in graph: %s", to)
+	}
+
+	for _, dep := range g.deferred[from] {
+		if dep == to {
+			return nil
+		}
+	}
+
+	g.deferred[from] = append(g.deferred[from], to)
 	return nil
 }
 
-// GetDependencies returns files that the given file depends on
+// GetDependencies returns files that the given file depends on, deferred
+// dependencies last
 func (g *DependencyGraph) GetDependencies(filePath string) []string {
 	deps, exists := g.edges[filePath]
 	if !exists {
@@ -86,23 +139,71 @@ func (g *DependencyGraph) GetDependencies(filePath string) []string {
 	// Return a copy to prevent external modification
 	result := make([]string, len(deps))
 	copy(result, deps)
+	for _, dep := range g.deferred[filePath] {
+		if !slices.Contains(result, dep) {
+			result = append(result, dep)
+		}
+	}
+	return result
+}
+
+// GetDeferredDependencies returns files that the given file only imports
+// inside function and view bodies
+func (g *DependencyGraph) GetDeferredDependencies(filePath string) []string {
+	var result []string
+	for _, dep := range g.deferred[filePath] {
+		if !slices.Contains(g.edges[filePath], dep) {
+			result = append(result, dep)
+		}
+	}
 	return result
 }
 
-// GetDependents returns files that depend on the given file
+// GetDependents returns files that depend on the given file, deferred
+// dependencies included
 func (g *DependencyGraph) GetDependents(filePath string) []string {
 	var dependents []string
-	for file, deps := range g.edges {
-		for _, dep := range deps {
-			if dep == filePath {
-				dependents = append(dependents, file)
-				break
-			}
+	for file := range g.nodes {
+		if slices.Contains(g.edges[file], filePath) || slices.Contains(g.deferred[file], filePath) {
+			dependents = append(dependents, file)
 		}
 	}
 	return dependents
 }
 
+// GetAffected returns the files that must be recompiled when the given files
+// change: the changed files in the graph and every file depending on them,
+// directly or not. Each maps to the dependency it was reached through, or to
+// "" when it changed itself.
+func (g *DependencyGraph) GetAffected(changed []string) map[string]string {
+	affected := make(map[string]string)
+	var queue []string
+	for _, filePath := range changed {
+		if _, exists := g.nodes[filePath]; !exists {
+			continue
+		}
+		if _, seen := affected[filePath]; !seen {
+			affected[filePath] = ""
+			queue = append(queue, filePath)
+		}
+	}
+
+	for len(queue) > 0 {
+		filePath := queue[0]
+		queue = queue[1:]
+		dependents := g.GetDependents(filePath)
+		sort.Strings(dependents)
+		for _, dependent := range dependents {
+			if _, seen := affected[dependent]; seen {
+				continue
+			}
+			affected[dependent] = filePath
+			queue = append(queue, dependent)
+		}
+	}
+	return affected
+}
+
 // HasFile checks if a file is in the graph
 func (g *DependencyGraph) HasFile(filePath string) bool {
 	_, exists := g.nodes[filePath]
@@ -118,6 +219,8 @@ func (g *DependencyGraph) FileCount() int {
 func (g *DependencyGraph) Clear() {
 	g.nodes = make(map[string]*FileNode)
 	g.edges = make(map[string][]string)
+	g.deferred = make(map[string][]string)
+	g.invalidateOrder()
 }
 
 // GetFileNode returns the FileNode for a given path
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -69,10 +70,31 @@ func (m *mockFileSystem) ListPSXFiles(dir string, recursive bool) ([]string, err
 	return nil, nil
 }
 
+func (m *mockFileSystem) ReadDir(dir string) ([]string, error) {
+	prefix := dir + string(filepath.Separator)
+	seen := make(map[string]bool)
+	var names []string
+	for p := range m.files {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name := strings.SplitN(p[len(prefix):], string(filepath.Separator), 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
 func (m *mockFileSystem) MkdirAll(path string, perm os.FileMode) error {
 	return nil
 }
 
+func (m *mockFileSystem) RemoveAll(path string) error {
+	return nil
+}
+
 func (m *mockFileSystem) ResolvePath(path string) (string, error) {
 	return filepath.Abs(path)
 }
@@ -248,6 +270,40 @@ func TestGetDependents(t *testing.T) {
 	}
 }
 
+func TestGetAffected(t *testing.T) {
+	graph := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		graph.AddFile("/project/"+name+".psx", createEmptyModule())
+	}
+
+	// d -> b -> a, c -> a, e is independent
+	graph.AddDependency("/project/b.psx", "/project/a.psx")
+	graph.AddDependency("/project/c.psx", "/project/a.psx")
+	graph.AddDependency("/project/d.psx", "/project/b.psx")
+
+	affected := graph.GetAffected([]string{"/project/a.psx", "/project/missing.psx"})
+	expected := map[string]string{
+		"/project/a.psx": "",
+		"/project/b.psx": "/project/a.psx",
+		"/project/c.psx": "/project/a.psx",
+		"/project/d.psx": "/project/b.psx",
+	}
+	if len(affected) != len(expected) {
+		t.Fatalf("expected %d affected files, got %v", len(expected), affected)
+	}
+	for file, via := range expected {
+		if got, ok := affected[file]; !ok || got != via {
+			t.Errorf("affected[%s] = %q (present: %v), want %q", file, got, ok, via)
+		}
+	}
+
+	// A changed dependent is reported as changed, not as reached
+	affected = graph.GetAffected([]string{"/project/b.psx", "/project/a.psx"})
+	if affected["/project/b.psx"] != "" {
+		t.Errorf("changed file b.psx should map to \"\", got %q", affected["/project/b.psx"])
+	}
+}
+
 func TestClear(t *testing.T) {
 	graph := NewGraph()
 	moduleA := createEmptyModule()
@@ -564,6 +620,297 @@ func TestGetCompilationOrder_WithCycle(t *testing.T) {
 	}
 }
 
+func TestGetCompilationLayers(t *testing.T) {
+	graph := NewGraph()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		graph.AddFile("/project/"+name+".psx", createEmptyModule())
+	}
+
+	// Diamond a <- b, c <- d, plus the independent e
+	graph.AddDependency("/project/b.psx", "/project/a.psx")
+	graph.AddDependency("/project/c.psx", "/project/a.psx")
+	graph.AddDependency("/project/d.psx", "/project/b.psx")
+	graph.AddDependency("/project/d.psx", "/project/c.psx")
+
+	layers, err := graph.GetCompilationLayers()
+	if err != nil {
+		t.Fatalf("GetCompilationLayers() error = %v", err)
+	}
+
+	expected := [][]string{
+		{"/project/a.psx", "/project/e.psx"},
+		{"/project/b.psx", "/project/c.psx"},
+		{"/project/d.psx"},
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("expected %v, got %v", expected, layers)
+	}
+}
+
+func TestGetCompilationLayers_WithCycle(t *testing.T) {
+	graph := NewGraph()
+	graph.AddFile("/project/a.psx", createEmptyModule())
+	graph.AddFile("/project/b.psx", createEmptyModule())
+	graph.AddDependency("/project/a.psx", "/project/b.psx")
+	graph.AddDependency("/project/b.psx", "/project/a.psx")
+
+	_, err := graph.GetCompilationLayers()
+	if _, ok := err.(*CycleError); !ok {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+}
+
+func TestSuggestCycleBreaks(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][2]string
+		want  []CycleBreak
+	}{
+		{
+			name:  "no cycle",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}},
+		},
+		{
+			name:  "one cycle",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}},
+			want:  []CycleBreak{{From: "c", To: "a"}},
+		},
+		{
+			name:  "cycles sharing an import",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}, {"c", "b"}},
+			want:  []CycleBreak{{From: "b", To: "c"}},
+		},
+		{
+			name:  "separate cycles",
+			edges: [][2]string{{"a", "b"}, {"b", "a"}, {"c", "d"}, {"d", "c"}, {"b", "c"}},
+			want:  []CycleBreak{{From: "a", To: "b"}, {From: "d", To: "c"}},
+		},
+		{
+			name:  "self import",
+			edges: [][2]string{{"a", "a"}, {"a", "b"}},
+			want:  []CycleBreak{{From: "a", To: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := NewGraph()
+			for _, name := range []string{"a", "b", "c", "d"} {
+				graph.AddFile(name, createEmptyModule())
+			}
+			for _, edge := range tt.edges {
+				graph.AddDependency(edge[0], edge[1])
+			}
+
+			breaks := graph.SuggestCycleBreaks()
+			if !reflect.DeepEqual(breaks, tt.want) {
+				t.Fatalf("expected breaks %v, got %v", tt.want, breaks)
+			}
+
+			// Removing the breaks leaves no cycle
+			pruned := NewGraph()
+			for _, name := range []string{"a", "b", "c", "d"} {
+				pruned.AddFile(name, createEmptyModule())
+			}
+			for _, edge := range tt.edges {
+				if !slices.Contains(breaks, CycleBreak{From: edge[0], To: edge[1]}) {
+					pruned.AddDependency(edge[0], edge[1])
+				}
+			}
+			if cycles, _ := pruned.DetectCycles(); len(cycles) > 0 {
+				t.Errorf("cycles left after removing the breaks: %v", cycles)
+			}
+		})
+	}
+}
+
+func TestCycleErrorSuggestions(t *testing.T) {
+	graph := NewGraph()
+	graph.AddFile("/project/a.psx", createEmptyModule())
+	graph.AddFile("/project/b.psx", createEmptyModule())
+	graph.AddDependency("/project/a.psx", "/project/b.psx")
+	graph.AddDependency("/project/b.psx", "/project/a.psx")
+
+	_, err := graph.GetCompilationLayers()
+	cycleErr, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+	want := []CycleBreak{{From: "/project/b.psx", To: "/project/a.psx"}}
+	if !reflect.DeepEqual(cycleErr.Breaks, want) {
+		t.Errorf("expected breaks %v, got %v", want, cycleErr.Breaks)
+	}
+	if !strings.Contains(err.Error(), "consider moving the import of /project/a.psx out of module level in /project/b.psx") {
+		t.Errorf("error should suggest the break:\n%s", err.Error())
+	}
+}
+
+func TestDeferredDependency(t *testing.T) {
+	graph := NewGraph()
+	graph.AddFile("/project/a.psx", createEmptyModule())
+	graph.AddFile("/project/b.psx", createEmptyModule())
+
+	// a imports b at the top level, b imports a inside a function
+	graph.AddDependency("/project/a.psx", "/project/b.psx")
+	if err := graph.AddDeferredDependency("/project/b.psx", "/project/a.psx"); err != nil {
+		t.Fatalf("AddDeferredDependency() error = %v", err)
+	}
+
+	if cycles, _ := graph.DetectCycles(); cycles != nil {
+		t.Errorf("deferred import should not close a cycle, got %v", cycles)
+	}
+	order, err := graph.GetCompilationOrder()
+	if err != nil {
+		t.Fatalf("GetCompilationOrder() error = %v", err)
+	}
+	if expected := []string{"/project/b.psx", "/project/a.psx"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected %v, got %v", expected, order)
+	}
+
+	if deps := graph.GetDependencies("/project/b.psx"); !reflect.DeepEqual(deps, []string{"/project/a.psx"}) {
+		t.Errorf("GetDependencies() should include deferred dependencies, got %v", deps)
+	}
+	if deps := graph.GetDeferredDependencies("/project/b.psx"); !reflect.DeepEqual(deps, []string{"/project/a.psx"}) {
+		t.Errorf("GetDeferredDependencies() = %v", deps)
+	}
+	if dependents := graph.GetDependents("/project/a.psx"); !reflect.DeepEqual(dependents, []string{"/project/b.psx"}) {
+		t.Errorf("GetDependents() should include deferred dependents, got %v", dependents)
+	}
+
+	// A deferred import already imported at the top level stays a hard edge
+	graph.AddDeferredDependency("/project/a.psx", "/project/b.psx")
+	if deps := graph.GetDeferredDependencies("/project/a.psx"); len(deps) != 0 {
+		t.Errorf("expected no deferred-only dependencies, got %v", deps)
+	}
+	if deps := graph.GetDependencies("/project/a.psx"); len(deps) != 1 {
+		t.Errorf("expected 1 dependency, got %v", deps)
+	}
+}
+
+// === Incremental Update Tests ===
+
+// importsModule returns a module importing mods at the top level, and
+// deferred inside a function
+func importsModule(mods []string, deferred ...string) *ast.Module {
+	name := func(lexeme string) *ast.Name { return &ast.Name{Token: lexer.Token{Lexeme: lexeme}} }
+	importOf := func(mod string) *ast.ImportStmt {
+		return &ast.ImportStmt{Names: []*ast.ImportName{{DottedName: &ast.DottedName{Names: []*ast.Name{name(mod)}}}}}
+	}
+	module := createEmptyModule()
+	for _, mod := range mods {
+		module.Body = append(module.Body, importOf(mod))
+	}
+	if len(deferred) > 0 {
+		fn := &ast.Function{Name: name("load")}
+		for _, mod := range deferred {
+			fn.Body = append(fn.Body, importOf(mod))
+		}
+		module.Body = append(module.Body, fn)
+	}
+	return module
+}
+
+func TestUpdateFile(t *testing.T) {
+	graph := NewGraph()
+	if err := graph.UpdateFile("/project/a.psx", createEmptyModule()); err == nil {
+		t.Error("expected an error without a resolver")
+	}
+	graph.SetResolver(newMockResolver(map[string]string{
+		"a": "/project/a.psx",
+		"b": "/project/b.psx",
+		"c": "/project/c.psx",
+	}))
+
+	// c is imported before it is added: the dependency appears with it
+	for path, module := range map[string]*ast.Module{
+		"/project/a.psx": createEmptyModule(),
+		"/project/b.psx": importsModule([]string{"a", "c"}),
+	} {
+		if err := graph.UpdateFile(path, module); err != nil {
+			t.Fatalf("UpdateFile(%s) error = %v", path, err)
+		}
+	}
+	if deps := graph.GetDependencies("/project/b.psx"); !reflect.DeepEqual(deps, []string{"/project/a.psx"}) {
+		t.Errorf("expected b to depend on a only, got %v", deps)
+	}
+	if err := graph.UpdateFile("/project/c.psx", createEmptyModule()); err != nil {
+		t.Fatalf("UpdateFile(c) error = %v", err)
+	}
+	if deps := graph.GetDependencies("/project/b.psx"); !reflect.DeepEqual(deps, []string{"/project/a.psx", "/project/c.psx"}) {
+		t.Errorf("expected b to depend on a and c, got %v", deps)
+	}
+
+	order, err := graph.GetCompilationOrder()
+	if err != nil {
+		t.Fatalf("GetCompilationOrder() error = %v", err)
+	}
+	if order[len(order)-1] != "/project/b.psx" {
+		t.Errorf("expected b last, got %v", order)
+	}
+
+	// Editing a file without changing its hard imports keeps the order
+	module := importsModule([]string{"c", "a"}, "c")
+	if err := graph.UpdateFile("/project/b.psx", module); err != nil {
+		t.Fatalf("UpdateFile(b) error = %v", err)
+	}
+	if graph.order == nil || graph.layers != nil {
+		t.Error("expected the computed order to be kept")
+	}
+	if node, _ := graph.GetFileNode("/project/b.psx"); node.AST != module || len(node.Imports) != 3 {
+		t.Errorf("expected the node to hold the new AST and its 3 imports, got %+v", node)
+	}
+
+	// Changing them computes it again
+	if err := graph.UpdateFile("/project/a.psx", importsModule([]string{"b"})); err != nil {
+		t.Fatalf("UpdateFile(a) error = %v", err)
+	}
+	if graph.order != nil {
+		t.Error("expected the computed order to be dropped")
+	}
+	if _, err := graph.GetCompilationOrder(); err == nil {
+		t.Error("expected a cycle between a and b")
+	}
+}
+
+func TestRemoveFile(t *testing.T) {
+	graph := NewGraph()
+	graph.SetResolver(newMockResolver(map[string]string{
+		"a": "/project/a.psx",
+		"b": "/project/b.psx",
+	}))
+	graph.UpdateFile("/project/a.psx", createEmptyModule())
+	graph.UpdateFile("/project/b.psx", importsModule([]string{"a"}, "a"))
+	if _, err := graph.GetCompilationLayers(); err != nil {
+		t.Fatalf("GetCompilationLayers() error = %v", err)
+	}
+
+	graph.RemoveFile("/project/a.psx")
+	if graph.HasFile("/project/a.psx") || graph.FileCount() != 1 {
+		t.Errorf("expected only b to remain, got %v", graph.GetAllFiles())
+	}
+	if deps := graph.GetDependencies("/project/b.psx"); len(deps) != 0 {
+		t.Errorf("expected no dependencies left, got %v", deps)
+	}
+	layers, err := graph.GetCompilationLayers()
+	if err != nil || !reflect.DeepEqual(layers, [][]string{{"/project/b.psx"}}) {
+		t.Errorf("expected the layers to be computed again, got %v, %v", layers, err)
+	}
+
+	// Adding the file back restores the dependencies on it
+	graph.AddFile("/project/a.psx", createEmptyModule())
+	if deps := graph.GetDependencies("/project/b.psx"); !reflect.DeepEqual(deps, []string{"/project/a.psx"}) {
+		t.Errorf("expected b to depend on a again, got %v", deps)
+	}
+	if deps := graph.GetDeferredDependencies("/project/b.psx"); len(deps) != 0 {
+		t.Errorf("expected the deferred import to stay behind the hard one, got %v", deps)
+	}
+
+	graph.RemoveFile("/project/missing.psx")
+	if graph.FileCount() != 2 {
+		t.Errorf("expected removing a missing file to do nothing, got %d files", graph.FileCount())
+	}
+}
+
 // === Import Extraction Tests ===
 
 func TestExtractImports_NoImports(t *testing.T) {
@@ -580,6 +927,49 @@ func TestExtractImports_NoImports(t *testing.T) {
 	}
 }
 
+func TestExtractImports_Deferred(t *testing.T) {
+	name := func(lexeme string) *ast.Name { return &ast.Name{Token: lexer.Token{Lexeme: lexeme}} }
+	importOf := func(mod string) *ast.ImportStmt {
+		return &ast.ImportStmt{Names: []*ast.ImportName{{DottedName: &ast.DottedName{Names: []*ast.Name{name(mod)}}}}}
+	}
+	module := &ast.Module{Body: []ast.Stmt{
+		importOf("top"),
+		&ast.If{Condition: name("DEBUG"), Body: []ast.Stmt{importOf("conditional")}},
+		&ast.Function{Name: name("load"), Body: []ast.Stmt{importOf("in_function")}},
+		&ast.ViewStmt{Name: name("Page"), Body: []ast.Stmt{importOf("in_view")}},
+		&ast.Class{Name: name("Widget"), Body: []ast.Stmt{
+			&ast.Function{Name: name("render"), Body: []ast.Stmt{importOf("in_method")}},
+		}},
+	}}
+	resolver := newMockResolver(map[string]string{
+		"top":         "/project/top.psx",
+		"conditional": "/project/conditional.psx",
+		"in_function": "/project/in_function.psx",
+		"in_view":     "/project/in_view.psx",
+		"in_method":   "/project/in_method.psx",
+	})
+
+	imports, err := ExtractImports(module, "/project/main.psx", resolver)
+	if err != nil {
+		t.Fatalf("ExtractImports() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, imp := range imports {
+		got[filepath.Base(imp.ModulePath)] = imp.Deferred
+	}
+	expected := map[string]bool{
+		"top.psx":         false,
+		"conditional.psx": false,
+		"in_function.psx": true,
+		"in_view.psx":     true,
+		"in_method.psx":   true,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
 func TestConvertDottedNameToPath(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -10,6 +10,11 @@ import (
 // ExtractImports extracts all imports from a module AST.
 // It resolves import paths using the provided module resolver.
 // Unresolved imports are skipped (they will be reported as errors during resolution phase).
+//
+// Imports inside function and view bodies are marked Deferred: Python runs
+// them when the function is called, not when the module is imported, so
+// they may close an import cycle. Imports in the other compound statements
+// of the module, such as if, try and class bodies, run on import.
 func ExtractImports(
 	astModule *ast.Module,
 	sourceFile string,
@@ -21,10 +26,7 @@ func ExtractImports(
 		imports:    []*Import{},
 	}
 
-	// Visit all top-level statements
-	for _, stmt := range astModule.Body {
-		extractor.visitStatement(stmt)
-	}
+	extractor.visitStatements(astModule.Body)
 
 	return extractor.imports, nil
 }
@@ -34,18 +36,66 @@ type importExtractor struct {
 	sourceFile string
 	resolver   module.Resolver
 	imports    []*Import
+	deferred   bool // Inside a function or view body
+}
+
+// visitStatements extracts the imports of a block
+func (e *importExtractor) visitStatements(stmts []ast.Stmt) {
+	for _, stmt := range stmts {
+		e.visitStatement(stmt)
+	}
 }
 
-// visitStatement checks if a statement is an import and extracts it
+// visitStatement extracts the imports of a statement and the blocks it holds
 func (e *importExtractor) visitStatement(stmt ast.Stmt) {
 	switch s := stmt.(type) {
 	case *ast.ImportStmt:
 		e.handleImportStmt(s)
 	case *ast.ImportFromStmt:
 		e.handleImportFromStmt(s)
+	case *ast.Function:
+		e.visitDeferred(s.Body)
+	case *ast.ViewStmt:
+		e.visitDeferred(s.Body)
+	case *ast.Decorator:
+		e.visitStatement(s.Stmt)
+	case *ast.Class:
+		e.visitStatements(s.Body)
+	case *ast.If:
+		e.visitStatements(s.Body)
+		e.visitStatements(s.Else)
+	case *ast.For:
+		e.visitStatements(s.Body)
+		e.visitStatements(s.Else)
+	case *ast.While:
+		e.visitStatements(s.Body)
+		e.visitStatements(s.Else)
+	case *ast.With:
+		e.visitStatements(s.Body)
+	case *ast.Try:
+		e.visitStatements(s.Body)
+		for _, except := range s.Excepts {
+			e.visitStatements(except.Body)
+		}
+		e.visitStatements(s.Else)
+		e.visitStatements(s.Finally)
+	case *ast.MatchStmt:
+		for _, c := range s.Cases {
+			e.visitStatements(c.Body)
+		}
+	case *ast.HTMLElement:
+		e.visitStatements(s.Content)
 	}
 }
 
+// visitDeferred extracts the imports of a function or view body
+func (e *importExtractor) visitDeferred(body []ast.Stmt) {
+	outer := e.deferred
+	e.deferred = true
+	e.visitStatements(body)
+	e.deferred = outer
+}
+
 // handleImportStmt processes "import x" and "import x as y" statements
 func (e *importExtractor) handleImportStmt(stmt *ast.ImportStmt) {
 	for _, name := range stmt.Names {
@@ -61,6 +111,7 @@ func (e *importExtractor) handleImportStmt(stmt *ast.ImportStmt) {
 			ModulePath: filePath,
 			Names:      []string{}, // import x doesn't import specific names
 			IsWildcard: false,
+			Deferred:   e.deferred,
 			Location:   extractLocation(stmt),
 		})
 	}
@@ -89,6 +140,11 @@ func (e *importExtractor) handleImportFromStmt(stmt *ast.ImportFromStmt) {
 		filePath, err = e.resolver.ResolveAbsolute(context.Background(), modulePath)
 	}
 
+	if module.IsNamespacePackage(err) && !stmt.IsWildcard {
+		// A namespace package has no file: the names it imports are its modules
+		e.handleNamespaceImport(stmt)
+		return
+	}
 	if err != nil {
 		// Skip unresolved imports - they will be caught during resolution
 		return
@@ -107,10 +163,42 @@ func (e *importExtractor) handleImportFromStmt(stmt *ast.ImportFromStmt) {
 		ModulePath: filePath,
 		Names:      names,
 		IsWildcard: stmt.IsWildcard,
+		Deferred:   e.deferred,
 		Location:   extractLocation(stmt),
 	})
 }
 
+// handleNamespaceImport records the modules "from ns import a, b" imports
+// from a namespace package, each a dependency of its own
+func (e *importExtractor) handleNamespaceImport(stmt *ast.ImportFromStmt) {
+	for _, name := range stmt.Names {
+		modulePath := name.DottedName.Names[0].Token.Lexeme
+		if stmt.DottedName != nil {
+			modulePath = convertDottedNameToPath(stmt.DottedName) + "." + modulePath
+		}
+
+		var filePath string
+		var err error
+		if stmt.DotCount > 0 {
+			filePath, err = e.resolver.ResolveRelative(context.Background(), stmt.DotCount, modulePath, e.sourceFile)
+		} else {
+			filePath, err = e.resolver.ResolveAbsolute(context.Background(), modulePath)
+		}
+		if err != nil {
+			continue
+		}
+
+		e.imports = append(e.imports, &Import{
+			Statement:  stmt,
+			ModulePath: filePath,
+			Names:      []string{},
+			IsWildcard: false,
+			Deferred:   e.deferred,
+			Location:   extractLocation(stmt),
+		})
+	}
+}
+
 // convertDottedNameToPath converts a dotted name AST node to a module path string
 func convertDottedNameToPath(dottedName *ast.DottedName) string {
 	if dottedName == nil {
@@ -0,0 +1,100 @@
+package depgraph
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/module"
+)
+
+// SetResolver sets the module resolver UpdateFile resolves imports with
+func (g *DependencyGraph) SetResolver(resolver module.Resolver) {
+	g.resolver = resolver
+}
+
+// UpdateFile adds a file to the graph or replaces its AST, and replaces its
+// dependencies with the imports of module, resolved with the graph's
+// resolver. The rest of the graph is left in place, and the compilation
+// order is only computed again when the file is new or its hard
+// dependencies changed.
+//
+// Imports of files not in the graph are kept in the file's Imports and
+// become dependencies when those files are added.
+func (g *DependencyGraph) UpdateFile(filePath string, module *ast.Module) error {
+	if g.resolver == nil {
+		return fmt.Errorf("no module resolver to extract the imports of %s", filePath)
+	}
+	imports, err := ExtractImports(module, filePath, g.resolver)
+	if err != nil {
+		return err
+	}
+
+	node, exists := g.nodes[filePath]
+	if !exists {
+		if err := g.AddFile(filePath, module); err != nil {
+			return err
+		}
+		node = g.nodes[filePath]
+	}
+	node.AST = module
+	node.Imports = imports
+
+	var edges, deferred []string
+	for _, imp := range imports {
+		if _, exists := g.nodes[imp.ModulePath]; !exists {
+			continue
+		}
+		if imp.Deferred {
+			if !slices.Contains(deferred, imp.ModulePath) {
+				deferred = append(deferred, imp.ModulePath)
+			}
+		} else if !slices.Contains(edges, imp.ModulePath) {
+			edges = append(edges, imp.ModulePath)
+		}
+	}
+	if !sameFiles(g.edges[filePath], edges) {
+		g.invalidateOrder()
+	}
+	g.edges[filePath] = append([]string{}, edges...)
+	g.deferred[filePath] = deferred
+	return nil
+}
+
+// RemoveFile removes a file and the dependencies on it from the graph. Files
+// that imported it keep the import in their Imports, so the dependency comes
+// back if the file is added again.
+func (g *DependencyGraph) RemoveFile(filePath string) {
+	if _, exists := g.nodes[filePath]; !exists {
+		return
+	}
+	delete(g.nodes, filePath)
+	delete(g.edges, filePath)
+	delete(g.deferred, filePath)
+	for from, deps := range g.edges {
+		g.edges[from] = slices.DeleteFunc(deps, func(dep string) bool { return dep == filePath })
+	}
+	for from, deps := range g.deferred {
+		g.deferred[from] = slices.DeleteFunc(deps, func(dep string) bool { return dep == filePath })
+	}
+	g.invalidateOrder()
+}
+
+// invalidateOrder drops the computed compilation order and layers
+func (g *DependencyGraph) invalidateOrder() {
+	g.order = nil
+	g.layers = nil
+}
+
+// sameFiles reports whether a and b hold the same files, in any order
+func sameFiles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, file := range a {
+		if !slices.Contains(b, file) {
+			return false
+		}
+	}
+	return true
+}
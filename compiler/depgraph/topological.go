@@ -1,5 +1,10 @@
 package depgraph
 
+import (
+	"slices"
+	"sort"
+)
+
 // GetCompilationOrder returns files in topological order using Kahn's algorithm.
 // Returns an error if circular dependencies are detected.
 //
@@ -12,7 +17,13 @@ package depgraph
 //     - Decrement its in-degree
 //     - If in-degree becomes 0, add to queue
 //  4. If result length < total files, circular dependency detected
+//
+// The order is kept until files or hard dependencies change.
 func (g *DependencyGraph) GetCompilationOrder() ([]string, error) {
+	if g.order != nil {
+		return slices.Clone(g.order), nil
+	}
+
 	// 1. Calculate in-degrees (number of dependencies for each file)
 	// in-degree[file] = number of files that file depends on
 	inDegree := make(map[string]int)
@@ -62,9 +73,78 @@ func (g *DependencyGraph) GetCompilationOrder() ([]string, error) {
 	// 4. Check for cycles
 	if len(result) != len(g.nodes) {
 		// Cycle detected - use cycle detection to get details
-		cycles, _ := g.DetectCycles()
-		return nil, NewCycleError(cycles)
+		_, err := g.DetectCycles()
+		return nil, err
 	}
 
+	g.order = slices.Clone(result)
 	return result, nil
 }
+
+// GetCompilationLayers groups files into layers: every file depends only on
+// files in earlier layers, so the files of one layer can be compiled
+// concurrently once the previous layers are done. Files within a layer are
+// sorted. Returns an error if circular dependencies are detected. Like the
+// compilation order, the layers are kept until files or hard dependencies
+// change.
+func (g *DependencyGraph) GetCompilationLayers() ([][]string, error) {
+	if g.layers != nil {
+		return cloneLayers(g.layers), nil
+	}
+
+	// remaining[file] = number of dependencies not yet placed in a layer
+	remaining := make(map[string]int, len(g.nodes))
+	dependents := make(map[string][]string)
+	for file := range g.nodes {
+		remaining[file] = len(g.edges[file])
+		for _, dep := range g.edges[file] {
+			dependents[dep] = append(dependents[dep], file)
+		}
+	}
+
+	var current []string
+	for file, count := range remaining {
+		if count == 0 {
+			current = append(current, file)
+		}
+	}
+
+	var layers [][]string
+	placed := 0
+	for len(current) > 0 {
+		sort.Strings(current)
+		layers = append(layers, current)
+		placed += len(current)
+
+		var next []string
+		for _, file := range current {
+			for _, dependent := range dependents[file] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+
+	if placed != len(g.nodes) {
+		_, err := g.DetectCycles()
+		return nil, err
+	}
+
+	g.layers = cloneLayers(layers)
+	if g.layers == nil {
+		g.layers = [][]string{}
+	}
+	return layers, nil
+}
+
+// cloneLayers copies layers, so callers cannot modify the kept ones
+func cloneLayers(layers [][]string) [][]string {
+	var clone [][]string
+	for _, layer := range layers {
+		clone = append(clone, slices.Clone(layer))
+	}
+	return clone
+}
@@ -0,0 +1,64 @@
+package diagnostics
+
+// Code identifies a kind of diagnostic, so it can be looked up in the
+// documentation and matched by tools. Errors are numbered by stage: E01xx
+// for the scanner, E02xx for the parser, E03xx for name resolution and E04xx
+// for module resolution, E05xx for writing outputs; warnings use W.
+type Code string
+
+// Scanner errors
+const (
+	CodeInvalidCharacter   Code = "E0100" // Character that starts no token, or an invalid pragma
+	CodeUnterminatedString Code = "E0101" // String or f-string not closed before the end of the line or file
+	CodeInvalidEscape      Code = "E0102" // Malformed escape sequence in a string
+	CodeInvalidNumber      Code = "E0103" // Malformed numeric literal
+	CodeIndentation        Code = "E0104" // Indentation that matches no enclosing block
+	CodeMalformedMarkup    Code = "E0105" // Invalid character, attribute value or comment in markup
+)
+
+// Parser errors
+const (
+	CodeSyntax             Code = "E0200" // Token the grammar does not allow here
+	CodeUnclosedElement    Code = "E0201" // Element whose closing tag is missing
+	CodeMismatchedTag      Code = "E0202" // Closing tag that names another element or is misplaced
+	CodeStrayClosingTag    Code = "E0203" // Closing tag without an opening tag
+	CodeInvalidDecoration  Code = "E0204" // Decorator on a statement that cannot be decorated
+	CodeDuplicateAttribute Code = "E0205" // Attribute given twice on the same element
+	CodeSlotParams         Code = "E0206" // let attribute that does not name the values of a scoped slot
+	CodeVoidContent        Code = "E0207" // Void element such as <br> or <img> given content
+	CodeSyntaxVersion      Code = "E0208" // Construct newer than the syntax version the file selects with its pragma
+	CodeInvalidNesting     Code = "W0200" // Element nested where browsers would move or close it, such as <div> inside <p>
+)
+
+// Name resolution errors and warnings
+const (
+	CodeScopeDeclaration    Code = "E0300" // Misplaced global or nonlocal declaration
+	CodeAssignTarget        Code = "E0301" // Expression that cannot be assigned to
+	CodePropType            Code = "E0302" // Literal attribute value that does not match the annotated type of a view parameter
+	CodeDuplicateSlotTarget Code = "E0303" // Slot of a view composition filled by both an attribute and child content
+	CodeAsyncContext        Code = "E0304" // await, async for, async with or an async view outside an async function or view
+	CodeAttributeSpread     Code = "E0305" // Attribute spread that is not a mapping, or passes a key the element cannot take
+	CodeNamingConvention    Code = "E0306" // View, module or slot name that breaks the project's naming conventions
+	CodeLayout              Code = "E0307" // Layout without a default slot, or 'use layout' naming a view that is not a layout
+	CodeViewArgument        Code = "E0308" // Attribute that is not a parameter of the composed view, or a required parameter left out
+	CodeScopedSlot          Code = "E0309" // let naming a value the slot it fills does not pass
+	CodeDeprecated          Code = "W0300" // Use of a view, function or class marked @deprecated
+	CodeSlotContent         Code = "W0301" // Slot filled by an expression that cannot render as content, such as a number
+)
+
+// Module resolution errors
+const (
+	CodeModuleNotFound        Code = "E0400" // Import of a module that exists on no search path
+	CodeInvalidRelativeImport Code = "E0401" // Relative import that cannot be resolved from its file
+	CodeImportAboveRoot       Code = "E0402" // Relative import climbing above the project root
+	CodeInvalidImportPath     Code = "E0403" // Malformed import path
+	CodeImportCase            Code = "E0404" // Import whose casing does not match the file or directory it names
+	CodeInvalidManifest       Code = "E0410" // Vendored package manifest that cannot be read
+	CodeMissingDependency     Code = "E0411" // Vendored package requiring a package that is not vendored
+	CodeVersionConflict       Code = "E0412" // Vendored packages requiring different versions of a package
+)
+
+// Output errors
+const (
+	CodeOutputCollision Code = "E0500" // Output path holding a Python file the compiler did not generate
+)
@@ -0,0 +1,142 @@
+// Package diagnostics defines the structured errors and warnings reported by
+// the stages of the compiler, and renders them as code frames.
+//
+// Every stage keeps its own error types (lexer.ScannerError,
+// parser.ParseError, resolver.Error, module.ResolutionError, ...); each of
+// them implements Diagnoser, so callers holding a plain error can recover the
+// severity, error code, source span and fix hints:
+//
+//	for _, d := range diagnostics.Collect(err) {
+//		d.File = path
+//		fmt.Fprint(os.Stderr, diagnostics.Renderer{Color: true}.Render(d, src))
+//	}
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity ranks diagnostics
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Position is a 1-based line and column, columns counted in characters
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Span is a range of source text; the zero Span means no location
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// IsZero reports whether the span has no location
+func (s Span) IsZero() bool {
+	return s.Start.Line == 0
+}
+
+// Label is a secondary location that explains a diagnostic, such as the
+// opening tag of an element whose closing tag is wrong
+type Label struct {
+	Span    Span
+	Message string
+}
+
+// Diagnostic is an error or warning with its location in the source
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Message  string
+	File     string   // Source file, when known
+	Span     Span     // Offending source text; zero when the diagnostic has no location
+	Labels   []Label  // Related locations, each rendered with its own code frame
+	Notes    []string // Context without a location, such as the paths searched for a module
+	Hints    []string // Suggested fixes
+
+	Expected    []string     // Tokens that would have been accepted at Span, for syntax errors
+	Suggestions []Suggestion // Fixes a tool can apply, such as replacing '=' with '=='
+}
+
+// Suggestion is a fix that replaces the source text at Span with Replacement
+type Suggestion struct {
+	Span        Span
+	Replacement string
+	Message     string // Shown to the user, e.g. "did you mean '=='?"
+}
+
+// Header returns the first line of the rendered diagnostic, e.g.
+// "error[E0201]: missing closing tag </p>"
+func (d *Diagnostic) Header() string {
+	if d.Code == "" {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s[%s]: %s", d.Severity, d.Code, d.Message)
+}
+
+// Error returns the diagnostic on one line, prefixed with its location
+func (d *Diagnostic) Error() string {
+	var location []string
+	if d.File != "" {
+		location = append(location, d.File)
+	}
+	if !d.Span.IsZero() {
+		location = append(location, fmt.Sprint(d.Span.Start.Line), fmt.Sprint(d.Span.Start.Column))
+	}
+	if len(location) == 0 {
+		return d.Header()
+	}
+	return strings.Join(location, ":") + ": " + d.Header()
+}
+
+// Diagnostic returns d, so a Diagnostic can be reported as an error
+func (d *Diagnostic) Diagnostic() *Diagnostic {
+	return d
+}
+
+// Diagnoser is implemented by the errors of each compiler stage
+type Diagnoser interface {
+	Diagnostic() *Diagnostic
+}
+
+// From returns the diagnostic of the first error in err's chain that has
+// one. Other errors become an error diagnostic without code or location.
+func From(err error) *Diagnostic {
+	var diagnoser Diagnoser
+	if errors.As(err, &diagnoser) {
+		return diagnoser.Diagnostic()
+	}
+	return &Diagnostic{Severity: Error, Message: err.Error()}
+}
+
+// Collect returns the diagnostics of err, one per error joined in it, such
+// as the errors of a file that failed resolution
+func Collect(err error) []*Diagnostic {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if _, ok := e.(Diagnoser); ok {
+			break
+		}
+		if joined, ok := e.(interface{ Unwrap() []error }); ok {
+			var all []*Diagnostic
+			for _, inner := range joined.Unwrap() {
+				all = append(all, Collect(inner)...)
+			}
+			return all
+		}
+	}
+	return []*Diagnostic{From(err)}
+}
@@ -0,0 +1,92 @@
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// stageError stands in for the error type of a compiler stage
+type stageError struct {
+	code Code
+	line int
+}
+
+func (e *stageError) Error() string { return fmt.Sprintf("stage error %s", e.code) }
+
+func (e *stageError) Diagnostic() *Diagnostic {
+	return &Diagnostic{
+		Severity: Error,
+		Code:     e.code,
+		Message:  "stage error",
+		Span:     Span{Start: Position{Line: e.line, Column: 1}, End: Position{Line: e.line, Column: 2}},
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Diagnostic
+		want string
+	}{
+		{
+			name: "file and span",
+			d: Diagnostic{Code: CodeSyntax, Message: "expected ':'", File: "app.psx",
+				Span: Span{Start: Position{Line: 3, Column: 7}}},
+			want: "app.psx:3:7: error[E0200]: expected ':'",
+		},
+		{
+			name: "warning without code",
+			d:    Diagnostic{Severity: Warning, Message: "unused import", File: "app.psx"},
+			want: "app.psx: warning: unused import",
+		},
+		{
+			name: "no location",
+			d:    Diagnostic{Code: CodeModuleNotFound, Message: "module not found"},
+			want: "error[E0400]: module not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	wrapped := fmt.Errorf("compiling app.psx: %w", &stageError{code: CodeUnclosedElement, line: 2})
+	if d := From(wrapped); d.Code != CodeUnclosedElement || d.Span.Start.Line != 2 {
+		t.Errorf("From(wrapped) = %+v, want the diagnostic of the wrapped error", d)
+	}
+
+	d := From(errors.New("disk full"))
+	if d.Severity != Error || d.Code != "" || d.Message != "disk full" || !d.Span.IsZero() {
+		t.Errorf("From(plain) = %+v, want an error without code or location", d)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	joined := fmt.Errorf("resolution failed: %w", errors.Join(
+		&stageError{code: CodeScopeDeclaration, line: 1},
+		errors.New("plain"),
+		errors.Join(&stageError{code: CodeAssignTarget, line: 5}),
+	))
+
+	got := Collect(joined)
+	want := []Code{CodeScopeDeclaration, "", CodeAssignTarget}
+	if len(got) != len(want) {
+		t.Fatalf("Collect() returned %d diagnostics, want %d", len(got), len(want))
+	}
+	for i, d := range got {
+		if d.Code != want[i] {
+			t.Errorf("diagnostic %d has code %q, want %q", i, d.Code, want[i])
+		}
+	}
+
+	if got := Collect(errors.New("single")); len(got) != 1 || got[0].Message != "single" {
+		t.Errorf("Collect(single) = %v, want one diagnostic", got)
+	}
+}
@@ -0,0 +1,142 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ANSI escape sequences of the colored output
+const (
+	reset  = "\033[0m"
+	bold   = "\033[1m"
+	red    = "\033[1;31m"
+	yellow = "\033[1;33m"
+	blue   = "\033[1;34m"
+	cyan   = "\033[1;36m"
+)
+
+// Renderer formats diagnostics as code frames: the offending source line
+// with a caret underline, then the related locations, notes, hints and
+// suggestions, e.g.
+//
+//	error[E0201]: missing closing tag </span>
+//	 --> app.psx:2:22
+//	  |
+//	2 |     <div><span>text</div>
+//	  |                      ^^^
+//	note: <span> opened here
+//	 --> app.psx:2:11
+//	  |
+//	2 |     <div><span>text</div>
+//	  |           ^^^^
+//	  = hint: add </span> before </div>
+type Renderer struct {
+	Color bool // Highlight with ANSI colors, for terminals
+}
+
+// Render formats d with frames pulled from src, the contents of d.File
+func (r Renderer) Render(d *Diagnostic, src []byte) string {
+	accent := red
+	if d.Severity == Warning {
+		accent = yellow
+	}
+
+	var sb strings.Builder
+	if d.Code == "" {
+		sb.WriteString(r.paint(accent, d.Severity.String()))
+	} else {
+		sb.WriteString(r.paint(accent, fmt.Sprintf("%s[%s]", d.Severity, d.Code)))
+	}
+	sb.WriteString(r.paint(bold, ": "+d.Message) + "\n")
+
+	lines := strings.Split(string(src), "\n")
+	if d.Span.IsZero() {
+		if d.File != "" {
+			sb.WriteString(fmt.Sprintf(" %s %s\n", r.paint(blue, "-->"), d.File))
+		}
+	} else {
+		r.frame(&sb, lines, d.File, d.Span, accent)
+	}
+	for _, label := range d.Labels {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", r.paint(cyan, "note"), label.Message))
+		r.frame(&sb, lines, d.File, label.Span, blue)
+	}
+	for _, note := range d.Notes {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", r.paint(cyan, "note"), note))
+	}
+	for _, hint := range d.Hints {
+		sb.WriteString(fmt.Sprintf("  %s %s: %s\n", r.paint(blue, "="), r.paint(bold, "hint"), hint))
+	}
+	for _, suggestion := range d.Suggestions {
+		sb.WriteString(fmt.Sprintf("  %s %s: %s\n", r.paint(blue, "="), r.paint(bold, "help"), suggestion.Message))
+	}
+	return sb.String()
+}
+
+// CodeFrame formats the source line of span with a caret underline, without
+// a header
+func (r Renderer) CodeFrame(src []byte, filename string, span Span) string {
+	var sb strings.Builder
+	r.frame(&sb, strings.Split(string(src), "\n"), filename, span, red)
+	return sb.String()
+}
+
+// frame writes the location of span and, when src has the line, the line
+// with carets under the span. A span covering several lines is underlined to
+// the end of its first line.
+func (r Renderer) frame(sb *strings.Builder, lines []string, filename string, span Span, accent string) {
+	line := span.Start.Line
+	gutter := len(fmt.Sprint(line))
+	pad := strings.Repeat(" ", gutter)
+
+	if filename != "" {
+		sb.WriteString(fmt.Sprintf("%s%s %s:%d:%d\n", pad, r.paint(blue, "-->"), filename, line, span.Start.Column))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s%s %d:%d\n", pad, r.paint(blue, "-->"), line, span.Start.Column))
+	}
+	if line < 1 || line > len(lines) {
+		return
+	}
+
+	text := strings.TrimRight(lines[line-1], "\r")
+	runes := []rune(text)
+	bar := r.paint(blue, "|")
+	sb.WriteString(fmt.Sprintf("%s %s\n", pad, bar))
+	sb.WriteString(fmt.Sprintf("%s %s %s\n", r.paint(blue, fmt.Sprint(line)), bar, text))
+
+	startCol := span.Start.Column
+	if startCol < 1 {
+		startCol = 1
+	}
+	width := 1
+	if span.End.Line == line && span.End.Column > startCol {
+		width = span.End.Column - startCol
+	} else if span.End.Line > line {
+		width = len(runes) - startCol + 1
+	}
+	if maxWidth := len(runes) - startCol + 1; width > maxWidth && maxWidth > 0 {
+		width = maxWidth
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	// Preserve tabs so the caret lines up with the source line
+	var indent strings.Builder
+	for i := 0; i < startCol-1 && i < len(runes); i++ {
+		if runes[i] == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%s %s %s%s\n", pad, bar, indent.String(), r.paint(accent, strings.Repeat("^", width))))
+}
+
+// paint wraps text in an ANSI style when colors are enabled
+func (r Renderer) paint(style, text string) string {
+	if !r.Color {
+		return text
+	}
+	return style + text + reset
+}
@@ -0,0 +1,135 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	src := []byte("view App():\n    <div><span>text</div>\n")
+	d := &Diagnostic{
+		Code:    CodeUnclosedElement,
+		Message: "missing closing tag </span>",
+		File:    "app.psx",
+		Span:    Span{Start: Position{Line: 2, Column: 22}, End: Position{Line: 2, Column: 25}},
+		Labels: []Label{{
+			Span:    Span{Start: Position{Line: 2, Column: 11}, End: Position{Line: 2, Column: 15}},
+			Message: "<span> opened here",
+		}},
+		Notes: []string{"elements must be closed in the order they were opened"},
+		Hints: []string{"add </span> before </div>"},
+	}
+
+	want := strings.Join([]string{
+		"error[E0201]: missing closing tag </span>",
+		" --> app.psx:2:22",
+		"  |",
+		"2 |     <div><span>text</div>",
+		"  |                      ^^^",
+		"note: <span> opened here",
+		" --> app.psx:2:11",
+		"  |",
+		"2 |     <div><span>text</div>",
+		"  |           ^^^^",
+		"note: elements must be closed in the order they were opened",
+		"  = hint: add </span> before </div>",
+		"",
+	}, "\n")
+
+	if got := (Renderer{}).Render(d, src); got != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderWithoutSpan(t *testing.T) {
+	d := &Diagnostic{Code: CodeModuleNotFound, Message: "module 'widgets' not found", File: "app.psx",
+		Notes: []string{"searched /project/widgets"}}
+
+	want := "error[E0400]: module 'widgets' not found\n --> app.psx\nnote: searched /project/widgets\n"
+	if got := (Renderer{}).Render(d, nil); got != want {
+		t.Errorf("Render() =\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	src := []byte("x = 1\n")
+	span := Span{Start: Position{Line: 1, Column: 1}, End: Position{Line: 1, Column: 2}}
+
+	got := Renderer{Color: true}.Render(&Diagnostic{Severity: Warning, Code: CodeDeprecated, Message: "deprecated", Span: span}, src)
+	if !strings.HasPrefix(got, yellow+"warning[W0300]"+reset) {
+		t.Errorf("warning header not colored yellow: %q", got)
+	}
+	if !strings.Contains(got, yellow+"^"+reset) {
+		t.Errorf("warning underline not colored yellow: %q", got)
+	}
+
+	plain := Renderer{}.Render(&Diagnostic{Message: "failed", Span: span}, src)
+	if strings.Contains(plain, "\033[") {
+		t.Errorf("uncolored output contains escape sequences: %q", plain)
+	}
+}
+
+func TestCodeFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		span Span
+		want string
+	}{
+		{
+			name: "tab indentation",
+			src:  "\tx = $",
+			span: Span{Start: Position{Line: 1, Column: 6}, End: Position{Line: 1, Column: 7}},
+			want: " --> app.psx:1:6\n  |\n1 | \tx = $\n  | \t    ^\n",
+		},
+		{
+			name: "multi-line span",
+			src:  "f(a,\n  b)",
+			span: Span{Start: Position{Line: 1, Column: 2}, End: Position{Line: 2, Column: 4}},
+			want: " --> app.psx:1:2\n  |\n1 | f(a,\n  |  ^^^\n",
+		},
+		{
+			name: "line past the end",
+			src:  "x",
+			span: Span{Start: Position{Line: 4, Column: 1}},
+			want: " --> app.psx:4:1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Renderer{}).CodeFrame([]byte(tt.src), "app.psx", tt.span); got != tt.want {
+				t.Errorf("CodeFrame() =\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSuggestions(t *testing.T) {
+	src := []byte("if x = 1:\n    pass\n")
+	d := &Diagnostic{
+		Code:     CodeSyntax,
+		Message:  "cannot assign with '=' in an if condition",
+		File:     "app.psx",
+		Span:     Span{Start: Position{Line: 1, Column: 6}, End: Position{Line: 1, Column: 7}},
+		Expected: []string{"':'"},
+		Suggestions: []Suggestion{{
+			Span:        Span{Start: Position{Line: 1, Column: 6}, End: Position{Line: 1, Column: 7}},
+			Replacement: "==",
+			Message:     "did you mean '=='?",
+		}},
+	}
+
+	want := strings.Join([]string{
+		"error[E0200]: cannot assign with '=' in an if condition",
+		" --> app.psx:1:6",
+		"  |",
+		"1 | if x = 1:",
+		"  |      ^",
+		"  = help: did you mean '=='?",
+		"",
+	}, "\n")
+	if got := (Renderer{}).Render(d, src); got != want {
+		t.Errorf("Render() =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,13 +1,20 @@
 package compiler
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/assets"
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/codegen"
+	"github.com/fjvillamarin/topple/compiler/transformers"
 )
 
 func TestE2E(t *testing.T) {
@@ -135,3 +142,595 @@ func compileFile(inputPath string) string {
 
 	return string(result)
 }
+
+func TestHTMLCommentModes(t *testing.T) {
+	src := []byte(`view Page():
+    <!--[if IE]><p>Old browser</p><![endif]-->
+    <div>
+        <!-- marker: header -->
+        <p>a <!-- inline --> b</p>
+    </div>
+`)
+
+	tests := []struct {
+		mode    transformers.HTMLCommentMode
+		want    []string
+		notWant []string
+	}{
+		{
+			mode:    transformers.HTMLCommentsStrip,
+			want:    []string{`return el("div", el("p", "a b"))`},
+			notWant: []string{"<!--", "# marker"},
+		},
+		{
+			mode: transformers.HTMLCommentsRender,
+			want: []string{
+				`raw("<!--[if IE]><p>Old browser</p><![endif]-->")`,
+				`raw("<!-- marker: header -->")`,
+				`el("p", ["a ", raw("<!-- inline -->"), " b"])`,
+			},
+			notWant: []string{"# marker"},
+		},
+		{
+			mode: transformers.HTMLCommentsPython,
+			want: []string{
+				"# [if IE]><p>Old browser</p><![endif]",
+				"# marker: header",
+				"# inline",
+			},
+			notWant: []string{"<!--"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.mode), func(t *testing.T) {
+			cmp := NewCompilerWithOptions(slog.Default(), Options{HTMLComments: tt.mode})
+			out, errs := cmp.Compile(context.Background(), File{Name: "page.psx", Content: src})
+			if len(errs) > 0 {
+				t.Fatalf("compile errors: %v", errs)
+			}
+
+			code := string(out)
+			for _, want := range tt.want {
+				if !strings.Contains(code, want) {
+					t.Errorf("output missing %q:\n%s", want, code)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(code, notWant) {
+					t.Errorf("output unexpectedly contains %q:\n%s", notWant, code)
+				}
+			}
+		})
+	}
+}
+
+func TestElementPolicy(t *testing.T) {
+	src := []byte(`view Page():
+    <Icon name="close"/>
+    <div><ui-card>text</ui-card></div>
+`)
+
+	tests := []struct {
+		name     string
+		elements transformers.ElementPolicy
+		want     string
+		errMsg   string
+	}{
+		{
+			name:   "undeclared component",
+			errMsg: "undefined view component 'Icon'",
+		},
+		{
+			name:     "intrinsic component",
+			elements: transformers.ElementPolicy{Intrinsic: []string{"Icon"}},
+			want:     `el("Icon", "", {"name": "close"})`,
+		},
+		{
+			name:     "denied element",
+			elements: transformers.ElementPolicy{Intrinsic: []string{"Icon"}, Denied: []string{"ui-card"}},
+			errMsg:   "element 'ui-card'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp := NewCompilerWithOptions(nil, Options{Elements: tt.elements})
+			out, errs := cmp.Compile(context.Background(), File{Name: "page.psx", Content: src})
+
+			if tt.errMsg != "" {
+				if len(errs) == 0 || !strings.Contains(errs[0].Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, errs)
+				}
+				return
+			}
+			if len(errs) > 0 {
+				t.Fatalf("compile errors: %v", errs)
+			}
+			if !strings.Contains(string(out), tt.want) {
+				t.Errorf("output missing %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestAttributeDefaults(t *testing.T) {
+	defaults, err := transformers.ParseAttributeDefaults("tokens.json", []byte(`{
+		"button": {"type": "button"},
+		"input": {"required": true},
+		"Card": {"tone": "muted"}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseAttributeDefaults failed: %v", err)
+	}
+	src := []byte(`view Card(title, tone="plain"):
+    <div class={tone}>{title}</div>
+
+view Page(props):
+    <button>Go</button>
+    <button type="submit">Send</button>
+    <button {...props}>Spread</button>
+    <input />
+    <Card title="x" />
+    <Card title="y" tone="loud" />
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{Defaults: defaults})
+	out, errs := cmp.Compile(context.Background(), File{Name: "page.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	for _, want := range []string{
+		`el("button", "Go", {"type": "button"})`,
+		`el("button", "Send", {"type": "submit"})`,
+		`el("button", "Spread", {"type": "button", **self.props})`,
+		`el("input", "", {"required": True})`,
+		`Card(tone="muted", title="x")`,
+		`Card(title="y", tone="loud")`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// A default naming no parameter of its view is reported with its origin
+	defaults.Tags["Card"] = []transformers.DefaultAttribute{{Name: "color", Value: "red"}}
+	cmp = NewCompilerWithOptions(nil, Options{Defaults: defaults})
+	if _, errs := cmp.Compile(context.Background(), File{Name: "page.psx", Content: src}); len(errs) == 0 ||
+		!strings.Contains(errs[0].Error(), "default attribute 'color' declared for Card in tokens.json is not a parameter of Card") {
+		t.Errorf("expected an unknown default to be reported, got %v", errs)
+	}
+
+	for _, invalid := range []string{`[]`, `{"button": {"type": 1}}`, `{"a b": {}}`} {
+		if _, err := transformers.ParseAttributeDefaults("tokens.json", []byte(invalid)); err == nil || !strings.HasPrefix(err.Error(), "tokens.json: ") {
+			t.Errorf("expected %s to be rejected naming the file, got %v", invalid, err)
+		}
+	}
+}
+
+func TestEarlyReturnModes(t *testing.T) {
+	src := []byte(`view Items(items: list):
+    if not items:
+        <p>Empty</p>
+        return
+    <ul>
+        for item in items:
+            <li>{item}</li>
+    </ul>
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{EarlyReturns: transformers.EarlyReturnsAllow})
+	if _, errs := cmp.Compile(context.Background(), File{Name: "items.psx", Content: src}); len(errs) > 0 {
+		t.Fatalf("compile errors with early returns allowed: %v", errs)
+	}
+
+	cmp = NewCompilerWithOptions(nil, Options{EarlyReturns: transformers.EarlyReturnsForbid})
+	_, errs := cmp.Compile(context.Background(), File{Name: "items.psx", Content: src})
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "'return' at L4:9") {
+		t.Errorf("expected the return to be rejected, got %v", errs)
+	}
+}
+
+func TestMarkdownBlocks(t *testing.T) {
+	src := []byte(`view Docs():
+    <article>
+        <Markdown>
+            # Install
+
+            Run ` + "`pip install topple`" + ` and see {the docs}.
+        </Markdown>
+    </article>
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{Markdown: true})
+	code, errs := cmp.Compile(context.Background(), File{Name: "docs.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	out := string(code)
+	if !strings.Contains(out, `_markdown_1 = raw("<h1>Install</h1>\n<p>Run <code>pip install topple</code> and see {the docs}.</p>\n")`) {
+		t.Errorf("expected the rendered block to be hoisted, got:\n%s", out)
+	}
+	if !strings.Contains(out, "_markdown_1]") && !strings.Contains(out, "_markdown_1)") {
+		t.Errorf("expected the view to reference the hoisted block, got:\n%s", out)
+	}
+
+	// Without the option, Markdown is an ordinary (undefined) component
+	cmp = NewCompilerWithOptions(nil, Options{})
+	if _, errs := cmp.Compile(context.Background(), File{Name: "docs.psx", Content: src}); len(errs) == 0 {
+		t.Errorf("expected <Markdown> to be rejected when the option is off")
+	}
+}
+
+func TestPreserveWhitespace(t *testing.T) {
+	src := []byte(`view Name(first: str, last: str):
+    <p><b>{first}</b> <i>{last}</i></p>
+    <p>{first} {last}</p>
+    <pre><b>{first}</b> <i>{last}</i></pre>
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{PreserveWhitespace: true})
+	code, errs := cmp.Compile(context.Background(), File{Name: "name.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	out := string(code)
+	if strings.Count(out, `.append(" ")`) != 2 {
+		t.Errorf("expected the spaces between inline elements to be kept, got:\n%s", out)
+	}
+	if !strings.Contains(out, `f"{escape(self.first)} {escape(self.last)}"`) {
+		t.Errorf("expected the space between interpolations to be kept, got:\n%s", out)
+	}
+
+	// Without the option, only <pre> keeps it
+	code, errs = NewCompilerWithOptions(nil, Options{}).Compile(context.Background(), File{Name: "name.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	if out := string(code); strings.Count(out, `.append(" ")`) != 1 || !strings.Contains(out, `f"{escape(self.first)}{escape(self.last)}"`) {
+		t.Errorf("expected whitespace to be kept only inside <pre>, got:\n%s", out)
+	}
+}
+
+func TestAssetReferences(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "logo.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "header.psx")
+	src := []byte(`view Header():
+    <link rel="stylesheet" href="./missing.css" />
+    <a href="./about">About</a>
+    <img src="./logo.png" />
+`)
+
+	resolver := assets.NewResolver(assets.Config{RootDir: dir, OutputDir: filepath.Join(dir, "static"), PublicPath: "/static"})
+	cmp := NewCompilerWithOptions(nil, Options{Assets: resolver})
+	_, errs := cmp.Compile(context.Background(), File{Name: source, Content: src})
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), `href attribute at L2:28-L2:48: asset "./missing.css" not found`) {
+		t.Fatalf("expected the missing stylesheet to be reported, got %v", errs)
+	}
+
+	src = bytes.Replace(src, []byte("./missing.css"), []byte("./logo.png"), 1)
+	code, errs := cmp.Compile(context.Background(), File{Name: source, Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	url := resolver.Manifest()["logo.png"]
+	if !strings.HasPrefix(url, "/static/logo.") {
+		t.Fatalf("expected logo.png in the manifest, got %v", resolver.Manifest())
+	}
+	if strings.Count(string(code), url) != 2 {
+		t.Errorf("expected both references rewritten to %s, got:\n%s", url, code)
+	}
+	if !strings.Contains(string(code), `"./about"`) {
+		t.Errorf("expected page links to be left alone, got:\n%s", code)
+	}
+}
+
+func TestSourceMarkers(t *testing.T) {
+	src := []byte(`import os
+
+view Home(title: str):
+    <div>
+        <h1>{title}</h1>
+    </div>
+
+def helper():
+    return 1
+
+view Footer():
+    <p>Bye</p>
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{Markers: true})
+	code, errs := cmp.Compile(context.Background(), File{Name: filepath.Join("src", "pages", "home.psx"), Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	out := string(code)
+	for _, marker := range []string{
+		"# topple:begin view Home home.psx:3-6\nclass Home(BaseView):",
+		"# topple:end view Home\ndef helper():",
+		"# topple:begin view Footer home.psx:11-12\nclass Footer(BaseView):",
+		"# topple:end view Footer\n",
+	} {
+		if !strings.Contains(out, marker) {
+			t.Errorf("expected %q in:\n%s", marker, out)
+		}
+	}
+
+	// Markers are off by default
+	code, _ = NewCompilerWithOptions(nil, Options{}).Compile(context.Background(), File{Name: "home.psx", Content: src})
+	if strings.Contains(string(code), "topple:") {
+		t.Errorf("expected no markers by default, got:\n%s", code)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	src := []byte(`import os
+
+view Home(title: str):
+    x = 1; y = 2
+    <h1>{title}</h1>
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{LineDirectives: true})
+	code, errs := cmp.Compile(context.Background(), File{Name: filepath.Join("src", "pages", "home.psx"), Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	out := string(code)
+	for _, directive := range []string{
+		"# line: home.psx:1\nimport os\n",
+		"# line: home.psx:3\nclass Home(BaseView):",
+		"        # line: home.psx:4\n        x = 1\n        y = 2\n        # line: home.psx:5\n",
+	} {
+		if !strings.Contains(out, directive) {
+			t.Errorf("expected %q in:\n%s", directive, out)
+		}
+	}
+
+	// Directives are off by default
+	code, _ = NewCompilerWithOptions(nil, Options{}).Compile(context.Background(), File{Name: "home.psx", Content: src})
+	if strings.Contains(string(code), "# line:") {
+		t.Errorf("expected no line directives by default, got:\n%s", code)
+	}
+}
+
+func TestSourceMarkersWithLineDirectives(t *testing.T) {
+	src := []byte(`import os
+
+view Card(title: str):
+    <div>{title}</div>
+
+def helper():
+    return 1
+`)
+
+	cmp := NewCompilerWithOptions(nil, Options{Markers: true, LineDirectives: true})
+	code, errs := cmp.Compile(context.Background(), File{Name: "a.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	// The end marker names no source line, so no directive comes before it
+	expected := `from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+# line: a.psx:1
+import os
+# line: a.psx:3
+# topple:begin view Card a.psx:3-4
+class Card(BaseView):
+    def __init__(self, title: str) -> None:
+        super().__init__()
+        self.title = title
+
+    def _render(self) -> Element:
+        # line: a.psx:4
+        return el("div", escape(self.title))
+
+# topple:end view Card
+# line: a.psx:6
+def helper():
+    # line: a.psx:7
+    return 1
+
+`
+	if string(code) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, code)
+	}
+}
+
+func TestCompileAST(t *testing.T) {
+	src := []byte("view Home(title: str):\n    <h1>{title}</h1>\n")
+	file := File{Name: "home.psx", Content: src}
+	cmp := NewCompiler(nil)
+
+	module, errs := cmp.CompileAST(context.Background(), file)
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	var class *ast.Class
+	for _, stmt := range module.Body {
+		if _, ok := stmt.(*ast.ViewStmt); ok {
+			t.Fatalf("expected views to be transformed, got %T", stmt)
+		}
+		if c, ok := stmt.(*ast.Class); ok {
+			class = c
+		}
+	}
+	if class == nil || class.Name.Token.Lexeme != "Home" {
+		t.Fatalf("expected a Home class in the module, got %v", module.Body)
+	}
+
+	// Emitting the module yields what Compile writes
+	code, _ := cmp.Compile(context.Background(), file)
+	if generated := codegen.NewCodeGenerator().Generate(module); generated != string(code) {
+		t.Errorf("expected the emitted AST to match Compile:\n%s\ngot:\n%s", code, generated)
+	}
+
+	if _, errs := cmp.CompileAST(context.Background(), File{Name: "bad.psx", Content: []byte("view (\n")}); len(errs) == 0 {
+		t.Error("expected errors for invalid source")
+	}
+}
+
+func TestRuntimeAPITargets(t *testing.T) {
+	src := []byte("view Hello():\n    <p>Hello</p>\n")
+
+	tests := []struct {
+		name     string
+		api      transformers.RuntimeAPI
+		expected string
+	}{
+		{"current by default", 0, "from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api\nrequire_api(3)\nclass Hello"},
+		{"attributes", transformers.RuntimeAPIAttributes, "raw, require_api\nrequire_api(3)\n"},
+		{"versioned", transformers.RuntimeAPIVersioned, "raw, require_api\nrequire_api(2)\n"},
+		{"legacy", transformers.RuntimeAPILegacy, "from topple.psx import BaseView, Element, el, escape, fragment, raw\nclass Hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, errs := NewCompilerWithOptions(nil, Options{RuntimeAPI: tt.api}).Compile(context.Background(), File{Name: "hello.psx", Content: src})
+			if len(errs) > 0 {
+				t.Fatalf("compile errors: %v", errs)
+			}
+			if !strings.Contains(string(code), tt.expected) {
+				t.Errorf("expected %q in:\n%s", tt.expected, code)
+			}
+		})
+	}
+
+	// class and style are serialized by helpers older runtimes do not have
+	classes := []byte("view Tag(kind):\n    <p class={kind}>Tag</p>\n")
+	code, _ := NewCompilerWithOptions(nil, Options{}).Compile(context.Background(), File{Name: "tag.psx", Content: classes})
+	if expected := `{"class": class_names(self.kind)}`; !strings.Contains(string(code), expected) {
+		t.Errorf("expected %q in:\n%s", expected, code)
+	}
+	code, _ = NewCompilerWithOptions(nil, Options{RuntimeAPI: transformers.RuntimeAPIVersioned}).Compile(context.Background(), File{Name: "tag.psx", Content: classes})
+	if expected := `{"class": escape(self.kind)}`; !strings.Contains(string(code), expected) || strings.Contains(string(code), "class_names") {
+		t.Errorf("expected %q and no class_names in:\n%s", expected, code)
+	}
+
+	// Modules without views need no runtime and carry no check
+	code, _ = NewCompilerWithOptions(nil, Options{}).Compile(context.Background(), File{Name: "util.psx", Content: []byte("x = 1\n")})
+	if strings.Contains(string(code), "require_api") {
+		t.Errorf("expected no runtime check without views, got:\n%s", code)
+	}
+
+	// A vendored runtime is imported from where it lives
+	code, _ = NewCompilerWithOptions(nil, Options{RuntimeModule: "app._vendor.psx"}).Compile(context.Background(), File{Name: "hello.psx", Content: src})
+	if expected := "from app._vendor.psx import BaseView, Element"; !strings.Contains(string(code), expected) {
+		t.Errorf("expected %q in:\n%s", expected, code)
+	}
+	for _, path := range []string{"app..psx", "app.1psx", "app-ui.psx"} {
+		if _, err := transformers.ParseRuntimeModule(path); err == nil {
+			t.Errorf("expected runtime module %q to be rejected", path)
+		}
+	}
+	if path, err := transformers.ParseRuntimeModule(""); err != nil || path != transformers.DefaultRuntimeModule {
+		t.Errorf("expected \"\" to select %s, got %q (%v)", transformers.DefaultRuntimeModule, path, err)
+	}
+
+	for _, version := range []int{-1, 4} {
+		if _, err := transformers.ParseRuntimeAPI(version); err == nil {
+			t.Errorf("expected runtime API %d to be rejected", version)
+		}
+	}
+	if api, err := transformers.ParseRuntimeAPI(0); err != nil || api != transformers.CurrentRuntimeAPI {
+		t.Errorf("expected 0 to select the current API, got %d (%v)", api, err)
+	}
+
+	// The runtime package must provide the version the compiler targets
+	runtime, err := os.ReadFile(filepath.Join("..", "topple", "psx.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if declared := fmt.Sprintf("\nAPI_VERSION = %d\n", transformers.CurrentRuntimeAPI); !strings.Contains(string(runtime), declared) {
+		t.Errorf("expected topple/psx.py to declare %q", strings.TrimSpace(declared))
+	}
+}
+
+func TestPythonTargets(t *testing.T) {
+	src := []byte("type Items = list[str]\n\nview Card[T](item: T, label: str):\n    <div class={f\"{label} {'card'}\"}>{item}</div>\n")
+
+	code, errs := NewCompilerWithOptions(nil, Options{Target: codegen.Py310}).Compile(context.Background(), File{Name: "card.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	for _, expected := range []string{
+		"from typing import Generic, TypeAlias, TypeVar\n",
+		"Items: TypeAlias = list[str]\n",
+		"T = TypeVar(\"T\")\nclass Card(BaseView, Generic[T]):\n",
+		"f\"{self.label} {'card'}\"",
+	} {
+		if !strings.Contains(string(code), expected) {
+			t.Errorf("expected %q in:\n%s", expected, code)
+		}
+	}
+
+	// Multi-file builds lower the same way
+	dir := t.TempDir()
+	input := filepath.Join(dir, "card.psx")
+	if err := os.WriteFile(input, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: dir,
+		Files:   []string{input},
+		Options: Options{Target: codegen.Py38},
+	})
+	if err != nil || len(output.Errors) > 0 {
+		t.Fatalf("compile errors: %v %v", err, output.Errors)
+	}
+	if code := string(output.CompiledFiles[input]); !strings.HasPrefix(code, "from __future__ import annotations\n") || !strings.Contains(code, "Items = List[str]\n") || strings.Contains(code, "type ") {
+		t.Errorf("expected the alias lowered for py38, got:\n%s", code)
+	}
+}
+
+func TestPython38Output(t *testing.T) {
+	python, err := exec.LookPath("python3.8")
+	if err == nil {
+		err = exec.Command(python, "-c", "pass").Run()
+	}
+	if err != nil {
+		t.Skip("python3.8 is not installed")
+	}
+
+	src := []byte(`from typing import cast
+
+type Row = dict[str, list[int] | None]
+
+def first[T](items: list[T]) -> T | None:
+    return cast(T | None, items[0]) if items else None
+
+class Rows(dict[str, Row]):
+    def get_row[K: str | bytes](self, key: K) -> Row | None:
+        return self.get(key)
+
+view Card[T](item: T, rows: Rows | None = None, tags: list[str] = []):
+    <div class={" ".join(tags)}>{item} {first(tags)}</div>
+`)
+	code, errs := NewCompilerWithOptions(nil, Options{Target: codegen.Py38}).Compile(context.Background(), File{Name: "card.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "card.py"), code, 0644); err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The module imports and renders on Python 3.8
+	cmd := exec.Command(python, "-c", "import card; print(card.Card(1, tags=['a', 'b']).render())")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PYTHONPATH="+dir+string(os.PathListSeparator)+root)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("python3.8 failed: %v\n%s\ngenerated:\n%s", err, out, code)
+	}
+	if rendered := strings.TrimSpace(string(out)); rendered != `<div class="a b">1a</div>` {
+		t.Errorf("unexpected render %q", rendered)
+	}
+}
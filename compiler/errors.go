@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
@@ -64,3 +66,19 @@ func (e *ResolverError) Span() string {
 func NewResolverError(message string, line int, column int) *ResolverError {
 	return &ResolverError{Message: message, Line: line, Column: column}
 }
+
+// errorList holds the errors of a file that failed a stage. Its message
+// joins theirs on one line, and each stays reachable through errors.As.
+type errorList []error
+
+func (l errorList) Error() string {
+	messages := make([]string, len(l))
+	for i, err := range l {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (l errorList) Unwrap() []error {
+	return l
+}
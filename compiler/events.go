@@ -0,0 +1,54 @@
+package compiler
+
+import (
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+// events returns the configured event sink, or one that discards events
+func (o Options) events() observe.EventSink {
+	return observe.EventsOrNop(o.Events)
+}
+
+// event stamps e with the current time and sends it to the event sink
+func (o Options) event(e observe.Event) {
+	e.Time = time.Now()
+	o.events().Event(e)
+}
+
+// diagnosticEvents sends one diagnostic event per error joined in err,
+// which a stage of the pipeline reported on file
+func (o Options) diagnosticEvents(file, stage string, err error, severity diagnostics.Severity) {
+	for _, d := range diagnostics.Collect(err) {
+		o.event(observe.Event{
+			Kind:     observe.EventDiagnostic,
+			File:     file,
+			Stage:    stage,
+			Severity: severity.String(),
+			Code:     string(d.Code),
+			Message:  d.Message,
+			Line:     d.Span.Start.Line,
+			Column:   d.Span.Start.Column,
+		})
+	}
+}
+
+// compilationEvents sends the diagnostic events of the errors of a project
+// build
+func (o Options) compilationEvents(errs []*CompilationError, severity diagnostics.Severity) {
+	for _, e := range errs {
+		if e.Details == nil {
+			o.event(observe.Event{
+				Kind:     observe.EventDiagnostic,
+				File:     e.File,
+				Stage:    e.Stage,
+				Severity: severity.String(),
+				Message:  e.Message,
+			})
+			continue
+		}
+		o.diagnosticEvents(e.File, e.Stage, e.Details, severity)
+	}
+}
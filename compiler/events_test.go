@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+func TestBuildEvents(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"button.psx": `view Button(label: str):
+    <button>{label}</button>
+`,
+		"home.psx": `from button import Button
+
+view Home():
+    <Button label="Go" />
+`,
+	})
+	button := filepath.Join(tmpDir, "button.psx")
+	home := filepath.Join(tmpDir, "home.psx")
+	cacheDir := filepath.Join(tmpDir, BuildCacheDirName)
+
+	// build compiles the project with a freshly opened cache and returns the
+	// events it wrote
+	build := func() []observe.Event {
+		t.Helper()
+		var buf bytes.Buffer
+		log := observe.NewEventLog(&buf)
+		cache := OpenBuildCache(cacheDir, "1.0")
+		NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{Events: log},
+			Cache:   cache,
+		})
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if err := log.Err(); err != nil {
+			t.Fatalf("event log failed: %v", err)
+		}
+
+		var events []observe.Event
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var e observe.Event
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("invalid event line %q: %v", line, err)
+			}
+			if e.Time.IsZero() {
+				t.Errorf("event without a time: %s", line)
+			}
+			events = append(events, e)
+		}
+		if len(events) < 2 || events[0].Kind != observe.EventBuildStart || events[len(events)-1].Kind != observe.EventBuildEnd {
+			t.Fatalf("expected events between build_start and build_end, got %+v", events)
+		}
+		return events
+	}
+	// files returns the sorted files of the events of a kind
+	files := func(events []observe.Event, kind string) []string {
+		var files []string
+		for _, e := range events {
+			if e.Kind == kind {
+				files = append(files, e.File)
+			}
+		}
+		slices.Sort(files)
+		return files
+	}
+	expect := func(events []observe.Event, kind string, want ...string) {
+		t.Helper()
+		if got := files(events, kind); !slices.Equal(got, want) {
+			t.Errorf("%s events: expected %v, got %v", kind, want, got)
+		}
+	}
+
+	first := build()
+	expect(first, observe.EventDiscover, button, home)
+	expect(first, observe.EventCacheMiss, button, home)
+	expect(first, observe.EventCacheHit)
+	expect(first, observe.EventResolve, home)
+	expect(first, observe.EventEmit, button, home)
+	for _, e := range first {
+		if e.Kind == observe.EventResolve && (e.Import != button || e.Line != 1) {
+			t.Errorf("expected home.psx to resolve button.psx on line 1, got %+v", e)
+		}
+		if e.Kind == observe.EventEmit && (e.Cached || e.Bytes == 0) {
+			t.Errorf("expected a compiled output, got %+v", e)
+		}
+	}
+	if end := first[len(first)-1]; end.Files != 2 || end.Errors != 0 || end.Message != "" {
+		t.Errorf("unexpected build_end: %+v", end)
+	}
+
+	second := build()
+	expect(second, observe.EventCacheHit, button, home)
+	expect(second, observe.EventCacheMiss)
+	for _, e := range second {
+		if e.Kind == observe.EventEmit && !e.Cached {
+			t.Errorf("expected a cached output, got %+v", e)
+		}
+	}
+
+	// A broken file is reported as a diagnostic of a failed build
+	if err := os.WriteFile(home, []byte("view Home(:\n    <p>x</p>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third := build()
+	expect(third, observe.EventCacheMiss, home)
+	expect(third, observe.EventDiagnostic, home)
+	for _, e := range third {
+		if e.Kind == observe.EventDiagnostic && (e.Stage != "parse" || e.Severity != "error" || e.Code == "" || e.Line != 1) {
+			t.Errorf("unexpected diagnostic: %+v", e)
+		}
+	}
+	if end := third[len(third)-1]; end.Errors != 1 || end.Message == "" {
+		t.Errorf("expected a failed build_end, got %+v", end)
+	}
+}
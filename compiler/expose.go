@@ -0,0 +1,145 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+// PackageExports is a generated __init__.py re-exporting the views of the
+// modules of a package, so that "from package import *" works against the
+// compiled code
+type PackageExports struct {
+	Code    []byte              // Contents of __init__.py
+	Modules map[string][]string // Module name -> views re-exported from it, sorted
+	Errors  []*CompilationError // Modules that could not be parsed or exported
+}
+
+// Expose generates the __init__.py of the package in pkgDir. It re-exports
+// the views each module of the package defines and would bind in a wildcard
+// import: the views listed by the module's __all__, or its public views.
+// Private modules, whose names start with an underscore, are left out, and
+// a package with its own __init__.psx declares its exports there instead.
+func (c *MultiFileCompiler) Expose(ctx context.Context, pkgDir string, opts MultiFileOptions) (*PackageExports, error) {
+	pkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package directory %s: %w", pkgDir, err)
+	}
+	files, err := filepath.Glob(filepath.Join(pkgDir, "*.psx"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s has no PSX modules to expose", pkgDir)
+	}
+	for _, filePath := range files {
+		if filepath.Base(filePath) == "__init__.psx" {
+			return nil, fmt.Errorf("%s has an __init__.psx: declare the package's exports there instead", pkgDir)
+		}
+	}
+
+	opts.Files = files
+	p, err := c.resolveProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	exports := &PackageExports{Modules: make(map[string][]string), Errors: p.errors}
+
+	definedBy := make(map[string]string) // View name -> module that exports it
+	for _, filePath := range files {
+		name := strings.TrimSuffix(filepath.Base(filePath), ".psx")
+		mod, ok := p.modules[filePath]
+		if !ok || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if !isIdentifier(name) {
+			exports.Errors = append(exports.Errors, &CompilationError{
+				File:    filePath,
+				Stage:   "expose",
+				Message: fmt.Sprintf("module name '%s' cannot be imported from Python", name),
+			})
+			continue
+		}
+		moduleSymbols, err := c.symbolRegistry.GetModuleSymbols(filePath)
+		if err != nil {
+			continue
+		}
+
+		defined := definedViews(mod)
+		var views []string
+		for _, sym := range moduleSymbols.GetWildcardSymbols() {
+			// Views the module imports are exported by the module defining them
+			if sym.Type != symbol.SymbolView || !defined[sym.Node] {
+				continue
+			}
+			if other, exists := definedBy[sym.Name]; exists {
+				exports.Errors = append(exports.Errors, &CompilationError{
+					File:    filePath,
+					Stage:   "expose",
+					Message: fmt.Sprintf("view %s is also exported by %s.psx", sym.Name, other),
+				})
+				continue
+			}
+			definedBy[sym.Name] = name
+			views = append(views, sym.Name)
+		}
+		if len(views) > 0 {
+			sort.Strings(views)
+			exports.Modules[name] = views
+		}
+	}
+	if len(exports.Errors) > 0 {
+		return exports, fmt.Errorf("exposing %s failed with %d errors", pkgDir, len(exports.Errors))
+	}
+
+	exports.Code = exports.generate()
+	return exports, nil
+}
+
+// generate writes the __init__.py of the exports, modules and names sorted
+// so the file only changes when the exports do
+func (e *PackageExports) generate() []byte {
+	modules := make([]string, 0, len(e.Modules))
+	var all []string
+	for name, views := range e.Modules {
+		modules = append(modules, name)
+		all = append(all, views...)
+	}
+	sort.Strings(modules)
+	sort.Strings(all)
+
+	var sb strings.Builder
+	sb.WriteString("# Generated by topple expose; do not edit.\n")
+	for _, name := range modules {
+		fmt.Fprintf(&sb, "from .%s import %s\n", name, strings.Join(e.Modules[name], ", "))
+	}
+	sb.WriteString("\n__all__ = [")
+	if len(all) > 0 {
+		sb.WriteString("\n")
+		for _, view := range all {
+			fmt.Fprintf(&sb, "    %q,\n", view)
+		}
+	}
+	sb.WriteString("]\n")
+	return []byte(sb.String())
+}
+
+// definedViews returns the views a module defines at its top level, as
+// opposed to those it imports
+func definedViews(mod *ast.Module) map[ast.Node]bool {
+	views := make(map[ast.Node]bool)
+	for _, stmt := range mod.Body {
+		if decorator, ok := stmt.(*ast.Decorator); ok {
+			stmt = decorator.Definition()
+		}
+		if view, ok := stmt.(*ast.ViewStmt); ok {
+			views[view] = true
+		}
+	}
+	return views
+}
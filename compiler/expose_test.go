@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpose(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": `
+view Button(label: str):
+    <button>{label}</button>
+
+view _Icon():
+    <i />
+`,
+		"components/card.psx": `
+from .button import *
+
+__all__ = ["Card", "CardHeader"]
+
+view Card():
+    <div><Button label="More" /></div>
+
+view CardHeader():
+    <h2>Title</h2>
+
+view Unlisted():
+    <p />
+
+def helper():
+    pass
+`,
+		"components/_internal.psx": `
+view Secret():
+    <p />
+`,
+		"duplicates/a.psx": `
+view Button():
+    <button />
+`,
+		"duplicates/b.psx": `
+view Button():
+    <a />
+`,
+		"package/__init__.psx": `
+view Index():
+    <p />
+`,
+	})
+	expose := func(pkg string) (*PackageExports, error) {
+		return NewMultiFileCompiler(nil).Expose(context.Background(), filepath.Join(tmpDir, pkg), MultiFileOptions{RootDir: tmpDir})
+	}
+
+	exports, err := expose("components")
+	if err != nil {
+		t.Fatalf("Expose failed: %v", err)
+	}
+	want := `# Generated by topple expose; do not edit.
+from .button import Button
+from .card import Card, CardHeader
+
+__all__ = [
+    "Button",
+    "Card",
+    "CardHeader",
+]
+`
+	if string(exports.Code) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, exports.Code)
+	}
+
+	exports, err = expose("duplicates")
+	if err == nil || len(exports.Errors) != 1 || !strings.Contains(exports.Errors[0].Message, "view Button is also exported by a.psx") {
+		t.Errorf("expected a duplicate view error, got %v", err)
+	}
+
+	if _, err := expose("package"); err == nil || !strings.Contains(err.Error(), "has an __init__.psx") {
+		t.Errorf("expected an error for a package with an __init__.psx, got %v", err)
+	}
+}
@@ -0,0 +1,588 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// Line wrapping
+
+// wrap returns the lines of prefix, expr and suffix: a single line when it
+// fits, otherwise the outermost brackets of expr split with one item per
+// line. Only calls, collection displays and parenthesized tuples are split.
+func (f *Formatter) wrap(prefix string, expr ast.Expr, suffix string) []string {
+	text := prefix + f.render(expr) + suffix
+	if f.fits(text) {
+		return []string{text}
+	}
+
+	switch e := expr.(type) {
+	case *ast.Call:
+		if len(e.Arguments) == 0 || isBareGenerator(e) {
+			break
+		}
+		args := make([]string, len(e.Arguments))
+		for i, arg := range e.Arguments {
+			args[i] = f.render(arg)
+		}
+		return f.split(prefix+f.render(e.Callee)+"(", args, ")"+suffix)
+	case *ast.ListExpr:
+		if len(e.Elements) > 0 {
+			return f.split(prefix+"[", f.items(e.Elements), "]"+suffix)
+		}
+	case *ast.SetExpr:
+		if len(e.Elements) > 0 {
+			return f.split(prefix+"{", f.items(e.Elements), "}"+suffix)
+		}
+	case *ast.DictExpr:
+		if len(e.Pairs) > 0 {
+			pairs := make([]string, len(e.Pairs))
+			for i, pair := range e.Pairs {
+				pairs[i] = f.pair(pair)
+			}
+			return f.split(prefix+"{", pairs, "}"+suffix)
+		}
+	case *ast.TupleExpr:
+		if isParenthesized(e) && len(e.Elements) > 0 {
+			return f.split(prefix+"(", f.items(e.Elements), ")"+suffix)
+		}
+	}
+	return []string{text}
+}
+
+// wrapItems returns the lines of header, then items inside open and close,
+// then suffix: a single line when it fits, otherwise one item per line
+func (f *Formatter) wrapItems(header, open string, items []string, close, suffix string) []string {
+	text := header + open + strings.Join(items, ", ") + close + suffix
+	if f.fits(text) || len(items) == 0 {
+		return []string{text}
+	}
+	return f.split(header+open, items, close+suffix)
+}
+
+// split returns first, then each item indented on its own line followed by a
+// comma, then last
+func (f *Formatter) split(first string, items []string, last string) []string {
+	lines := make([]string, 0, len(items)+2)
+	lines = append(lines, first)
+	for _, item := range items {
+		lines = append(lines, "    "+item+",")
+	}
+	return append(lines, last)
+}
+
+// items returns the text of each expression
+func (f *Formatter) items(exprs []ast.Expr) []string {
+	items := make([]string, len(exprs))
+	for i, expr := range exprs {
+		items[i] = f.render(expr)
+	}
+	return items
+}
+
+// list returns comma-separated expressions
+func (f *Formatter) list(exprs []ast.Expr) string {
+	return strings.Join(f.items(exprs), ", ")
+}
+
+// isParenthesized reports whether a tuple was written in parentheses. The
+// span of a bare tuple starts at its first element.
+func isParenthesized(t *ast.TupleExpr) bool {
+	return len(t.Elements) == 0 || t.Span.Start != t.Elements[0].GetSpan().Start
+}
+
+// isBareGenerator reports whether a call's only argument is a generator
+// expression written without parentheses of its own, as in any(x for x in y)
+func isBareGenerator(c *ast.Call) bool {
+	if len(c.Arguments) != 1 {
+		return false
+	}
+	gen, ok := c.Arguments[0].Value.(*ast.GenExpr)
+	return ok && !isParenthesizedGenerator(gen)
+}
+
+// isParenthesizedGenerator reports whether a generator expression has its
+// own parentheses. The span of a bare one starts at its element.
+func isParenthesizedGenerator(g *ast.GenExpr) bool {
+	return g.Span.Start != g.Element.GetSpan().Start
+}
+
+// Expressions
+
+func (f *Formatter) VisitName(n *ast.Name) ast.Visitor {
+	f.write(n.Token.Lexeme)
+	return f
+}
+
+func (f *Formatter) VisitLiteral(l *ast.Literal) ast.Visitor {
+	// Numbers, strings and ellipses keep their source text
+	if l.Token.Lexeme != "" {
+		f.write(l.Token.Lexeme)
+		return f
+	}
+	switch {
+	case l.Type == ast.LiteralTypeBool && l.Value == true:
+		f.write("True")
+	case l.Type == ast.LiteralTypeBool:
+		f.write("False")
+	default:
+		f.write("None")
+	}
+	return f
+}
+
+func (f *Formatter) VisitAttribute(a *ast.Attribute) ast.Visitor {
+	a.Object.Accept(f)
+	f.write("." + a.Name.Lexeme)
+	return f
+}
+
+func (f *Formatter) VisitCall(c *ast.Call) ast.Visitor {
+	c.Callee.Accept(f)
+	f.write("(")
+	for i, arg := range c.Arguments {
+		if i > 0 {
+			f.write(", ")
+		}
+		arg.Accept(f)
+	}
+	f.write(")")
+	return f
+}
+
+func (f *Formatter) VisitSubscript(s *ast.Subscript) ast.Visitor {
+	s.Object.Accept(f)
+	f.write("[" + f.list(s.Indices) + "]")
+	return f
+}
+
+func (f *Formatter) VisitBinary(b *ast.Binary) ast.Visitor {
+	b.Left.Accept(f)
+	f.write(" " + b.Operator.Lexeme + " ")
+	b.Right.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitUnary(u *ast.Unary) ast.Visitor {
+	f.write(u.Operator.Lexeme)
+	if u.Operator.Lexeme == "not" {
+		f.write(" ")
+	}
+	u.Right.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitAssignExpr(a *ast.AssignExpr) ast.Visitor {
+	a.Left.Accept(f)
+	f.write(" := ")
+	a.Right.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitStarExpr(s *ast.StarExpr) ast.Visitor {
+	f.write("*")
+	s.Expr.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitTernaryExpr(t *ast.TernaryExpr) ast.Visitor {
+	t.TrueExpr.Accept(f)
+	f.write(" if ")
+	t.Condition.Accept(f)
+	f.write(" else ")
+	t.FalseExpr.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitListExpr(l *ast.ListExpr) ast.Visitor {
+	f.write("[" + f.list(l.Elements) + "]")
+	return f
+}
+
+func (f *Formatter) VisitTupleExpr(t *ast.TupleExpr) ast.Visitor {
+	text := f.list(t.Elements)
+	if len(t.Elements) == 1 {
+		text += ","
+	}
+	if isParenthesized(t) {
+		text = "(" + text + ")"
+	}
+	f.write(text)
+	return f
+}
+
+func (f *Formatter) VisitSetExpr(s *ast.SetExpr) ast.Visitor {
+	f.write("{" + f.list(s.Elements) + "}")
+	return f
+}
+
+func (f *Formatter) VisitDictExpr(d *ast.DictExpr) ast.Visitor {
+	pairs := make([]string, len(d.Pairs))
+	for i, pair := range d.Pairs {
+		pairs[i] = f.pair(pair)
+	}
+	f.write("{" + strings.Join(pairs, ", ") + "}")
+	return f
+}
+
+// pair returns the text of a dict entry
+func (f *Formatter) pair(pair ast.DictPair) string {
+	switch p := pair.(type) {
+	case *ast.KeyValuePair:
+		return f.render(p.Key) + ": " + f.render(p.Value)
+	case *ast.DoubleStarredPair:
+		return "**" + f.render(p.Expr)
+	}
+	panic(fmt.Sprintf("format: unexpected dict entry %T", pair))
+}
+
+func (f *Formatter) VisitListComp(lc *ast.ListComp) ast.Visitor {
+	f.write("[" + f.render(lc.Element) + f.clauses(lc.Clauses) + "]")
+	return f
+}
+
+func (f *Formatter) VisitSetComp(sc *ast.SetComp) ast.Visitor {
+	f.write("{" + f.render(sc.Element) + f.clauses(sc.Clauses) + "}")
+	return f
+}
+
+func (f *Formatter) VisitDictComp(dc *ast.DictComp) ast.Visitor {
+	f.write("{" + f.render(dc.Key) + ": " + f.render(dc.Value) + f.clauses(dc.Clauses) + "}")
+	return f
+}
+
+func (f *Formatter) VisitGenExpr(ge *ast.GenExpr) ast.Visitor {
+	text := f.render(ge.Element) + f.clauses(ge.Clauses)
+	if isParenthesizedGenerator(ge) {
+		text = "(" + text + ")"
+	}
+	f.write(text)
+	return f
+}
+
+// clauses returns the for and if clauses of a comprehension, each preceded
+// by a space
+func (f *Formatter) clauses(clauses []ast.ForIfClause) string {
+	var b strings.Builder
+	for _, clause := range clauses {
+		b.WriteString(" ")
+		if clause.IsAsync {
+			b.WriteString("async ")
+		}
+		b.WriteString("for " + f.render(clause.Target) + " in " + f.render(clause.Iter))
+		for _, cond := range clause.Ifs {
+			b.WriteString(" if " + f.render(cond))
+		}
+	}
+	return b.String()
+}
+
+func (f *Formatter) VisitYieldExpr(y *ast.YieldExpr) ast.Visitor {
+	f.write("yield")
+	if y.IsFrom {
+		f.write(" from")
+	}
+	if y.Value != nil {
+		f.write(" ")
+		y.Value.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitGroupExpr(g *ast.GroupExpr) ast.Visitor {
+	f.write("(")
+	g.Expression.Accept(f)
+	f.write(")")
+	return f
+}
+
+func (f *Formatter) VisitSlice(s *ast.Slice) ast.Visitor {
+	if s.StartIndex != nil {
+		s.StartIndex.Accept(f)
+	}
+	f.write(":")
+	if s.EndIndex != nil {
+		s.EndIndex.Accept(f)
+	}
+	if s.Step != nil {
+		f.write(":")
+		s.Step.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitAwaitExpr(a *ast.AwaitExpr) ast.Visitor {
+	f.write("await ")
+	a.Expr.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitArgument(a *ast.Argument) ast.Visitor {
+	if a.Name != nil {
+		f.write(a.Name.Token.Lexeme + "=")
+	}
+	if a.IsStar {
+		f.write("*")
+	} else if a.IsDoubleStar {
+		f.write("**")
+	}
+	a.Value.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitLambda(l *ast.Lambda) ast.Visitor {
+	f.write("lambda")
+	if params := f.parameters(l.Parameters); len(params) > 0 {
+		f.write(" " + strings.Join(params, ", "))
+	}
+	f.write(": ")
+	l.Body.Accept(f)
+	return f
+}
+
+// Parameters
+
+// parameters returns the text of each parameter, with the / and * markers
+// the parameter list implies
+func (f *Formatter) parameters(p *ast.ParameterList) []string {
+	if p == nil {
+		return nil
+	}
+	var items []string
+	star := false
+	for i, param := range p.Parameters {
+		if param.IsStar {
+			star = true
+		}
+		if param.IsKeywordOnly && !star {
+			// A bare * is not kept as a parameter
+			items = append(items, "*")
+			star = true
+		}
+		items = append(items, f.render(param))
+		if p.HasSlash && i == p.SlashIndex {
+			items = append(items, "/")
+		}
+	}
+	return items
+}
+
+func (f *Formatter) VisitParameterList(p *ast.ParameterList) ast.Visitor {
+	f.write(strings.Join(f.parameters(p), ", "))
+	return f
+}
+
+func (f *Formatter) VisitParameter(p *ast.Parameter) ast.Visitor {
+	if p.IsStar {
+		f.write("*")
+	} else if p.IsDoubleStar {
+		f.write("**")
+	}
+	if p.Name != nil {
+		f.write(p.Name.Token.Lexeme)
+	}
+	if p.Annotation != nil {
+		f.write(": ")
+		p.Annotation.Accept(f)
+	}
+	if p.Default != nil {
+		if p.Annotation != nil {
+			f.write(" = ")
+		} else {
+			f.write("=")
+		}
+		p.Default.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitTypeParamExpr(t *ast.TypeParam) ast.Visitor {
+	if t.IsStar {
+		f.write("*")
+	} else if t.IsDoubleStar {
+		f.write("**")
+	}
+	f.write(t.Name.Lexeme)
+	if t.Bound != nil {
+		f.write(": ")
+		t.Bound.Accept(f)
+	}
+	if t.Default != nil {
+		f.write(" = ")
+		t.Default.Accept(f)
+	}
+	return f
+}
+
+// F-strings
+
+func (f *Formatter) VisitFString(fs *ast.FString) ast.Visitor {
+	f.write(fs.Quote)
+	for _, part := range fs.Parts {
+		part.Accept(f)
+	}
+	// The closing quote is the opening one without its prefix
+	f.write(strings.TrimLeft(fs.Quote, "fFrRbBuUtT"))
+	return f
+}
+
+func (f *Formatter) VisitConcatenatedString(c *ast.ConcatenatedString) ast.Visitor {
+	for i, part := range c.Parts {
+		if i > 0 {
+			f.write(" ")
+		}
+		part.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitFStringMiddle(m *ast.FStringMiddle) ast.Visitor {
+	if m.Lexeme != "" {
+		f.write(m.Lexeme)
+		return f
+	}
+	f.write(m.Value)
+	return f
+}
+
+func (f *Formatter) VisitFStringReplacementField(r *ast.FStringReplacementField) ast.Visitor {
+	f.writeField(r.Expression, r.Equal, r.Conversion, r.FormatSpec)
+	return f
+}
+
+func (f *Formatter) VisitFStringConversion(c *ast.FStringConversion) ast.Visitor {
+	f.write("!" + c.Type)
+	return f
+}
+
+func (f *Formatter) VisitFStringFormatSpec(s *ast.FStringFormatSpec) ast.Visitor {
+	f.write(":")
+	for _, part := range s.Spec {
+		part.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitFStringFormatMiddle(m *ast.FStringFormatMiddle) ast.Visitor {
+	f.write(m.Value)
+	return f
+}
+
+func (f *Formatter) VisitFStringFormatReplacementField(r *ast.FStringFormatReplacementField) ast.Visitor {
+	f.writeField(r.Expression, r.Equal, r.Conversion, r.FormatSpec)
+	return f
+}
+
+// writeField writes an f-string replacement field
+func (f *Formatter) writeField(expr ast.Expr, equal bool, conversion *ast.FStringConversion, spec *ast.FStringFormatSpec) {
+	text := f.render(expr)
+	if strings.HasPrefix(text, "{") {
+		// {{ would be an escaped brace
+		text = " " + text
+	}
+	f.write("{" + text)
+	if equal {
+		f.write("=")
+	}
+	if conversion != nil {
+		conversion.Accept(f)
+	}
+	if spec != nil {
+		spec.Accept(f)
+	}
+	f.write("}")
+}
+
+// Patterns
+
+func (f *Formatter) VisitLiteralPattern(lp *ast.LiteralPattern) ast.Visitor {
+	lp.Value.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitCapturePattern(cp *ast.CapturePattern) ast.Visitor {
+	f.write(cp.Name.Token.Lexeme)
+	return f
+}
+
+func (f *Formatter) VisitWildcardPattern(wp *ast.WildcardPattern) ast.Visitor {
+	f.write("_")
+	return f
+}
+
+func (f *Formatter) VisitValuePattern(vp *ast.ValuePattern) ast.Visitor {
+	vp.Value.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitGroupPattern(gp *ast.GroupPattern) ast.Visitor {
+	f.write("(" + f.render(gp.Pattern) + ")")
+	return f
+}
+
+func (f *Formatter) VisitSequencePattern(sp *ast.SequencePattern) ast.Visitor {
+	text := f.patterns(sp.Patterns)
+	if sp.IsTuple {
+		if len(sp.Patterns) == 1 {
+			text += ","
+		}
+		f.write("(" + text + ")")
+		return f
+	}
+	f.write("[" + text + "]")
+	return f
+}
+
+func (f *Formatter) VisitStarPattern(sp *ast.StarPattern) ast.Visitor {
+	f.write("*" + f.render(sp.Pattern))
+	return f
+}
+
+func (f *Formatter) VisitMappingPattern(mp *ast.MappingPattern) ast.Visitor {
+	var items []string
+	for _, pair := range mp.Pairs {
+		items = append(items, f.render(pair.Key)+": "+f.render(pair.Pattern))
+	}
+	if mp.HasRest {
+		items = append(items, "**"+f.render(mp.DoubleStar))
+	}
+	f.write("{" + strings.Join(items, ", ") + "}")
+	return f
+}
+
+func (f *Formatter) VisitClassPattern(cp *ast.ClassPattern) ast.Visitor {
+	var items []string
+	for _, pattern := range cp.Patterns {
+		items = append(items, f.render(pattern))
+	}
+	for _, kwd := range cp.KwdPatterns {
+		items = append(items, kwd.Name.Token.Lexeme+"="+f.render(kwd.Pattern))
+	}
+	f.write(f.render(cp.Class) + "(" + strings.Join(items, ", ") + ")")
+	return f
+}
+
+func (f *Formatter) VisitAsPattern(ap *ast.AsPattern) ast.Visitor {
+	f.write(f.render(ap.Pattern) + " as " + ap.Target.Token.Lexeme)
+	return f
+}
+
+func (f *Formatter) VisitOrPattern(op *ast.OrPattern) ast.Visitor {
+	parts := make([]string, len(op.Patterns))
+	for i, pattern := range op.Patterns {
+		parts[i] = f.render(pattern)
+	}
+	f.write(strings.Join(parts, " | "))
+	return f
+}
+
+// patterns returns comma-separated patterns
+func (f *Formatter) patterns(patterns []ast.Pattern) string {
+	parts := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		parts[i] = f.render(pattern)
+	}
+	return strings.Join(parts, ", ")
+}
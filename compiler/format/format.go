@@ -0,0 +1,371 @@
+// Package format reprints PSX sources in a canonical layout: four-space
+// indentation, one statement per line, normalized spacing inside
+// expressions, HTML tags and attributes, and at most one blank line between
+// statements (two around top-level definitions). Lines longer than the
+// configured width are wrapped at their outermost brackets or, for HTML
+// tags, one attribute per line.
+//
+// The formatter works from the parsed AST, so it never changes what a file
+// means: string and number literals, HTML text and f-string text are written
+// exactly as they appear in the source. # comments, which the parser does not
+// keep, are put back next to the statements they were written with.
+package format
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+// DefaultWidth is the line width used when Options.Width is zero
+const DefaultWidth = 88
+
+// markdownTag is the element whose content is raw Markdown, kept verbatim
+const markdownTag = "Markdown"
+
+// Options configures the formatter
+type Options struct {
+	Width int // Lines longer than this are wrapped where possible
+}
+
+// Source formats a PSX file. When the source does not scan or parse, it is
+// returned unchanged together with the errors.
+func Source(src []byte, opts Options) ([]byte, []error) {
+	cfg := lexer.DefaultScannerConfig()
+	cfg.PreserveHTMLComments = true
+	cfg.RawTextElements = []string{markdownTag}
+	cfg.PreserveWhitespace = true
+
+	scanner := lexer.NewScannerWithConfig(src, cfg)
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		return src, scanner.Errors
+	}
+	p := parser.NewParser(tokens)
+	p.Syntax = scanner.Syntax()
+	module, errors := p.Parse()
+	if len(errors) > 0 {
+		return src, errors
+	}
+
+	f := NewFormatter(opts)
+	f.setComments(src, scanner.Comments())
+	f.setKeywords(tokens)
+	return []byte(f.Format(module)), nil
+}
+
+// lastKind is what the formatter wrote last in the current block, which
+// decides the blank lines before the next statement
+type lastKind int
+
+const (
+	lastNone       lastKind = iota // Nothing yet: the block just started
+	lastComment                    // A comment or decorator: what follows is attached to it
+	lastStatement                  // A statement
+	lastDefinition                 // A def, class or view
+)
+
+// comment is a # comment from the source
+type comment struct {
+	line, column int
+	text         string
+	ownLine      bool // Nothing but whitespace precedes it on its line
+}
+
+// Formatter reprints an AST as PSX source
+type Formatter struct {
+	builder strings.Builder
+	indent  int
+	width   int
+
+	// Pending comments, in source order; next is the first not yet written
+	comments []comment
+	next     int
+
+	// Source lines of the else and finally keywords, which the AST does not
+	// keep, in order
+	keywords map[lexer.TokenType][]int
+
+	// Layout state: the last source line written, what was written last,
+	// how deep in blocks the formatter is, and the first source line after
+	// the statement being written, which bounds the comments its blocks take
+	lastLine int
+	last     lastKind
+	depth    int
+	limit    int
+
+	views       int // How many views enclose the block being written
+	inlineDepth int // Above zero while rendering single-line element content
+}
+
+// NewFormatter creates a formatter
+func NewFormatter(opts Options) *Formatter {
+	width := opts.Width
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	return &Formatter{width: width}
+}
+
+// setComments records the comments to put back into the output
+func (f *Formatter) setComments(src []byte, tokens []lexer.Token) {
+	lines := strings.Split(string(src), "\n")
+	f.comments = f.comments[:0]
+	for _, tok := range tokens {
+		c := comment{line: tok.Span.Start.Line, column: tok.Span.Start.Column, text: tok.Lexeme}
+		if c.line >= 1 && c.line <= len(lines) {
+			c.ownLine = strings.HasPrefix(strings.TrimSpace(lines[c.line-1]), c.text)
+		}
+		f.comments = append(f.comments, c)
+	}
+	f.next = 0
+}
+
+// setKeywords records where the else and finally keywords are
+func (f *Formatter) setKeywords(tokens []lexer.Token) {
+	f.keywords = map[lexer.TokenType][]int{}
+	for _, tok := range tokens {
+		if tok.Type == lexer.Else || tok.Type == lexer.Finally {
+			f.keywords[tok.Type] = append(f.keywords[tok.Type], tok.Span.Start.Line)
+		}
+	}
+}
+
+// clauseLine returns the line of the else or finally keyword introducing a
+// block that starts on line body: the last such keyword at or before it
+func (f *Formatter) clauseLine(keyword lexer.TokenType, body int) int {
+	line := body - 1
+	for _, l := range f.keywords[keyword] {
+		if l > body {
+			break
+		}
+		line = l
+	}
+	return line
+}
+
+// Format returns the source of a module
+func (f *Formatter) Format(module *ast.Module) string {
+	f.builder.Reset()
+	f.indent, f.depth = 0, 0
+	f.lastLine, f.last = 0, lastNone
+	f.limit = math.MaxInt
+
+	f.writeBlock(module.Body, math.MaxInt)
+	f.writeComments(math.MaxInt, 0)
+	return f.builder.String()
+}
+
+// render returns the text of a node written on a single line
+func (f *Formatter) render(node ast.Node) string {
+	sub := &Formatter{width: f.width}
+	node.Accept(sub)
+	return sub.builder.String()
+}
+
+func (f *Formatter) write(s string) {
+	f.builder.WriteString(s)
+}
+
+// writeLine writes one indented line. Newlines inside s belong to
+// multi-line literals and are written as they are.
+func (f *Formatter) writeLine(s string) {
+	if s != "" {
+		f.builder.WriteString(strings.Repeat("    ", f.indent))
+		f.builder.WriteString(s)
+	}
+	f.builder.WriteString("\n")
+}
+
+// fits reports whether text fits on a line at the current indentation.
+// Lines after the first, which only multi-line literals produce, are
+// written as they are.
+func (f *Formatter) fits(text string) bool {
+	for i, line := range strings.Split(text, "\n") {
+		n := utf8.RuneCountInString(line)
+		if i == 0 {
+			n += 4 * f.indent
+		}
+		if n > f.width {
+			return false
+		}
+	}
+	return true
+}
+
+// blankLines writes the blank lines before an item starting on source line
+// line: those of the source, at most one inside blocks and two at the top
+// level, with top-level definitions always set apart by two
+func (f *Formatter) blankLines(line int, definition bool) {
+	maxBlank := 1
+	if f.depth == 0 {
+		maxBlank = 2
+	}
+	n := line - f.lastLine - 1
+	if n > maxBlank {
+		n = maxBlank
+	}
+	if n < 0 {
+		n = 0
+	}
+	switch {
+	case f.last == lastNone:
+		n = 0
+	case f.last == lastDefinition, definition && f.last == lastStatement:
+		n = maxBlank
+	}
+	for range n {
+		f.builder.WriteString("\n")
+	}
+}
+
+// writeComments writes the pending comments that start before source line
+// limit and at or right of column, each on its own line
+func (f *Formatter) writeComments(limit, column int) {
+	f.writeLeading(limit, column, false)
+}
+
+// writeLeading writes comments like writeComments. With definition set,
+// they lead a definition and are set apart like it.
+func (f *Formatter) writeLeading(limit, column int, definition bool) {
+	for f.next < len(f.comments) {
+		c := f.comments[f.next]
+		if c.line >= limit || c.column < column {
+			return
+		}
+		f.blankLines(c.line, definition)
+		definition = false
+		f.writeLine(c.text)
+		f.next++
+		f.lastLine = max(f.lastLine, c.line)
+		f.last = lastComment
+	}
+}
+
+// writeLogical writes the lines of a statement or header spanning source
+// lines first to last. Comments written inside that range move above it,
+// except the last one when it follows code, which stays at the end of the
+// line.
+func (f *Formatter) writeLogical(lines []string, first, last int, definition bool) {
+	var trailing string
+	end := f.next
+	for end < len(f.comments) && f.comments[end].line <= last {
+		end++
+	}
+	if end > f.next && !f.comments[end-1].ownLine && f.comments[end-1].line >= first {
+		trailing = f.comments[end-1].text
+		end--
+	}
+
+	f.writeLeading(first, 0, definition)
+	f.blankLines(first, definition)
+	for ; f.next < end; f.next++ {
+		f.writeLine(f.comments[f.next].text)
+	}
+	if trailing != "" {
+		f.next++
+		lines[len(lines)-1] += "  " + trailing
+	}
+	for _, line := range lines {
+		f.writeLine(line)
+	}
+	f.lastLine = max(f.lastLine, last)
+	f.last = lastStatement
+	if definition {
+		f.last = lastDefinition
+	}
+}
+
+// writeBlock writes the statements of a block. Comments after its last
+// statement and before source line limit that are indented like the block
+// belong to it.
+func (f *Formatter) writeBlock(stmts []ast.Stmt, limit int) {
+	f.writeLedBlock(nil, stmts, limit)
+}
+
+// writeLedBlock writes a block like writeBlock, with lead, when not nil,
+// writing a line that is not a statement of the block at its head
+func (f *Formatter) writeLedBlock(lead func(), stmts []ast.Stmt, limit int) {
+	outerLimit, outerLast := f.limit, f.last
+	f.last = lastNone
+	if lead != nil {
+		f.limit = limit
+		if len(stmts) > 0 {
+			f.limit = lineOf(stmts[0])
+		}
+		lead()
+	}
+
+	for i := 0; i < len(stmts); {
+		// A chained assignment spans several statements, and so does view
+		// text with several words
+		chain, value, size := chainedAssignment(stmts[i:])
+		words := 0
+		if f.views > 0 {
+			words = f.sameLineText(stmts[i:])
+		}
+		n := max(1, size, words)
+
+		f.limit = limit
+		if i+n < len(stmts) {
+			f.limit = lineOf(stmts[i+n])
+		}
+		switch {
+		case size > 1:
+			f.writeChain(chain, value, stmts[i:i+n])
+		case words > 1:
+			f.writeText(stmts[i : i+n])
+		default:
+			stmts[i].Accept(f)
+		}
+		i += n
+	}
+
+	if len(stmts) > 0 {
+		f.writeComments(limit, stmts[0].GetSpan().Start.Column)
+	}
+	f.limit, f.last = outerLimit, outerLast
+}
+
+// writeBody writes an indented block
+func (f *Formatter) writeBody(stmts []ast.Stmt, limit int) {
+	f.indent++
+	f.depth++
+	f.writeBlock(stmts, limit)
+	f.depth--
+	f.indent--
+}
+
+// lineOf returns the first source line of a node
+func lineOf(node ast.Node) int {
+	return node.GetSpan().Start.Line
+}
+
+// endLine returns the last source line of a node
+func endLine(node ast.Node) int {
+	return node.GetSpan().End.Line
+}
+
+// Visit implements ast.Visitor
+func (f *Formatter) Visit(node ast.Node) ast.Visitor {
+	node.Accept(f)
+	return f
+}
+
+// VisitModule writes the statements of a module
+func (f *Formatter) VisitModule(m *ast.Module) ast.Visitor {
+	f.writeBlock(m.Body, math.MaxInt)
+	return f
+}
+
+// VisitMultiStmt writes each statement of a statement list on its own line
+func (f *Formatter) VisitMultiStmt(m *ast.MultiStmt) ast.Visitor {
+	for _, stmt := range m.Stmts {
+		stmt.Accept(f)
+	}
+	return f
+}
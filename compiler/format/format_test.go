@@ -0,0 +1,116 @@
+package format
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+// TestFormatGolden formats each testdata/input file and compares the result
+// with testdata/expected
+func TestFormatGolden(t *testing.T) {
+	updateGolden := os.Getenv("UPDATE_GOLDEN") == "1"
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "input", "*.psx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range inputs {
+		name := filepath.Base(input)
+		t.Run(strings.TrimSuffix(name, ".psx"), func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, errs := Source(src, Options{})
+			if len(errs) > 0 {
+				t.Fatalf("format failed: %v", errs)
+			}
+
+			expectedPath := filepath.Join("testdata", "expected", name)
+			if updateGolden {
+				if err := os.MkdirAll(filepath.Dir(expectedPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(expectedPath, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(expectedPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with UPDATE_GOLDEN=1): %v", err)
+			}
+			if string(got) != string(expected) {
+				t.Errorf("output differs from %s\ngot:\n%s\nexpected:\n%s", expectedPath, got, expected)
+			}
+		})
+	}
+}
+
+// TestFormatPreservesMeaning formats every compiler test input and checks
+// that formatting twice changes nothing and that the formatted file
+// compiles to the same Python as the original
+func TestFormatPreservesMeaning(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("..", "testdata", "input", "*", "*.psx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := filepath.Glob(filepath.Join("testdata", "input", "*.psx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs = append(inputs, golden...)
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, errs := Source(src, Options{})
+			if len(errs) > 0 {
+				t.Skipf("does not parse: %v", errs[0])
+			}
+
+			again, errs := Source(formatted, Options{})
+			if len(errs) > 0 {
+				t.Fatalf("formatted source does not parse: %v\n%s", errs, formatted)
+			}
+			if string(again) != string(formatted) {
+				t.Errorf("formatting is not idempotent\nfirst:\n%s\nsecond:\n%s", formatted, again)
+			}
+
+			want, wantErrs := compile(input, src)
+			got, gotErrs := compile(input, formatted)
+			if wantErrs > 0 || gotErrs > 0 {
+				// Error positions move with the code
+				if gotErrs != wantErrs {
+					t.Errorf("formatted source reports %d errors, want %d\n%s", gotErrs, wantErrs, got)
+				}
+				return
+			}
+			if got != want {
+				t.Errorf("formatted source compiles differently\nformatted:\n%s\ngot:\n%s\nwant:\n%s", formatted, got, want)
+			}
+		})
+	}
+}
+
+// compile returns the Python compiled from src, or its errors and how many
+// there are
+func compile(name string, src []byte) (string, int) {
+	code, errs := compiler.NewCompiler(nil).Compile(context.Background(), compiler.File{Name: name, Content: src})
+	if len(errs) > 0 {
+		var b strings.Builder
+		for _, err := range errs {
+			b.WriteString(err.Error() + "\n")
+		}
+		return b.String(), len(errs)
+	}
+	return string(code), 0
+}
@@ -0,0 +1,225 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+func (f *Formatter) VisitViewStmt(v *ast.ViewStmt) ast.Visitor {
+	limit := f.limit
+	header := "view " + v.Name.Token.Lexeme
+	if v.IsLayout {
+		header = "layout " + v.Name.Token.Lexeme
+	}
+	if v.IsAsync {
+		header = "async " + header
+	}
+	f.writeHeader(f.signature(header, v.TypeParams, v.Params, v.ReturnType), lineOf(v), f.signatureEnd(v.Name, v.Params, v.ReturnType), true)
+	f.views++
+	if v.Layout != nil {
+		// 'use layout' heads the body, before its first statement
+		f.indent++
+		f.depth++
+		f.writeLedBlock(func() {
+			f.writeLogical(f.wrap("use layout ", v.Layout, ""), lineOf(v.Layout), endLine(v.Layout), false)
+		}, v.Body, limit)
+		f.depth--
+		f.indent--
+	} else {
+		f.writeBody(v.Body, limit)
+	}
+	f.views--
+	return f
+}
+
+func (f *Formatter) VisitHTMLElement(h *ast.HTMLElement) ast.Visitor {
+	if f.inline() {
+		// Inside single-line content
+		f.write(f.element(h)[0])
+		return f
+	}
+
+	last := lineOf(h)
+	for _, attr := range h.Attributes {
+		last = max(last, attr.Span.End.Line)
+	}
+	if h.Type != ast.HTMLMultilineElement {
+		f.writeLogical(f.element(h), lineOf(h), max(last, endLine(h)), false)
+		return f
+	}
+
+	limit := f.limit
+	f.writeLogical(f.openTag(h, ">"), lineOf(h), last, false)
+	f.writeBody(h.Content, endLine(h))
+	f.writeComments(endLine(h), 0)
+	f.writeLine("</" + h.TagName.Lexeme + ">")
+	f.lastLine = max(f.lastLine, endLine(h))
+	f.last = lastStatement
+	f.limit = limit
+	return f
+}
+
+// element returns the lines of a self-closing or single-line element
+func (f *Formatter) element(h *ast.HTMLElement) []string {
+	if h.Type == ast.HTMLSelfClosingTag {
+		return f.openTag(h, " />")
+	}
+	var content strings.Builder
+	for _, stmt := range h.Content {
+		content.WriteString(f.renderInline(stmt))
+	}
+	lines := f.openTag(h, ">")
+	lines[len(lines)-1] += content.String() + "</" + h.TagName.Lexeme + ">"
+	return lines
+}
+
+// openTag returns the lines of an opening tag ending in end: one line when
+// it fits, otherwise one attribute per line with end on a line of its own
+func (f *Formatter) openTag(h *ast.HTMLElement, end string) []string {
+	attrs := make([]string, len(h.Attributes))
+	for i, attr := range h.Attributes {
+		attrs[i] = f.attribute(attr)
+	}
+	text := "<" + h.TagName.Lexeme
+	if len(attrs) > 0 {
+		text += " " + strings.Join(attrs, " ")
+	}
+	if f.inline() || f.fits(text+end) || len(attrs) < 2 {
+		return []string{text + end}
+	}
+
+	lines := []string{"<" + h.TagName.Lexeme}
+	for _, attr := range attrs {
+		lines = append(lines, "    "+attr)
+	}
+	return append(lines, strings.TrimPrefix(end, " "))
+}
+
+// attribute returns the text of an attribute
+func (f *Formatter) attribute(attr ast.HTMLAttribute) string {
+	if attr.Spread {
+		return "{..." + f.spread(attr.Value) + "}"
+	}
+	name := attr.Name.Lexeme
+	if attr.Value == nil {
+		return name
+	}
+	switch value := attr.Value.(type) {
+	case *ast.Literal:
+		// Quoted strings, numbers and booleans written without braces
+		if value.Token.Type == lexer.String {
+			if markupString(value) {
+				return name + "=" + value.Token.Lexeme
+			}
+		} else if value.Token.Lexeme != "" && value.Type != ast.LiteralTypeString {
+			return name + "=" + value.Token.Lexeme
+		}
+	case *ast.FString:
+		// Quoted text with {expression} parts, as opposed to a Python
+		// f-string in braces
+		if !strings.ContainsAny(value.Quote[:1], "fFrRbBuUtT") {
+			return name + "=" + f.render(value)
+		}
+	}
+	return name + "={" + f.interpolated(attr.Value) + "}"
+}
+
+// markupString reports whether a string literal reads the same as a quoted
+// attribute value, which keeps backslashes as written: its value is the
+// text between its quotes, unlike that of "a\n" or "a" "b"
+func markupString(l *ast.Literal) bool {
+	lexeme := l.Token.Lexeme
+	value, ok := l.Value.(string)
+	if !ok || len(lexeme) < 2 || (lexeme[0] != '"' && lexeme[0] != '\'') || lexeme[len(lexeme)-1] != lexeme[0] {
+		return false
+	}
+	return value == lexeme[1:len(lexeme)-1]
+}
+
+// spread returns the text of the mapping of a {...mapping} spread, keeping
+// the "mapping if condition" shorthand, which the parser gives an empty
+// dictionary without width as its else branch
+func (f *Formatter) spread(value ast.Expr) string {
+	if ternary, ok := value.(*ast.TernaryExpr); ok {
+		if dict, ok := ternary.FalseExpr.(*ast.DictExpr); ok && len(dict.Pairs) == 0 && dict.Span.Start == dict.Span.End {
+			return f.render(ternary.TrueExpr) + " if " + f.render(ternary.Condition)
+		}
+	}
+	return f.render(value)
+}
+
+// interpolated returns the text of an expression inside braces
+func (f *Formatter) interpolated(expr ast.Expr) string {
+	text := f.render(expr)
+	if strings.HasPrefix(text, "{") {
+		return " " + text + " "
+	}
+	return text
+}
+
+// inline reports whether the formatter renders single-line element content
+func (f *Formatter) inline() bool {
+	return f.inlineDepth > 0
+}
+
+// renderInline returns the text of a part of single-line element content
+func (f *Formatter) renderInline(node ast.Node) string {
+	sub := &Formatter{width: f.width, inlineDepth: 1}
+	node.Accept(sub)
+	return sub.builder.String()
+}
+
+func (f *Formatter) VisitHTMLContent(h *ast.HTMLContent) ast.Visitor {
+	for _, part := range h.Parts {
+		part.Accept(f)
+	}
+	return f
+}
+
+func (f *Formatter) VisitHTMLText(h *ast.HTMLText) ast.Visitor {
+	f.write(h.String())
+	return f
+}
+
+func (f *Formatter) VisitHTMLInterpolation(h *ast.HTMLInterpolation) ast.Visitor {
+	f.write("{" + f.interpolated(h.Expression) + "}")
+	return f
+}
+
+func (f *Formatter) VisitHTMLComment(h *ast.HTMLComment) ast.Visitor {
+	if f.inline() {
+		f.write(h.String())
+		return f
+	}
+	f.writeLogical([]string{h.String()}, lineOf(h), endLine(h), false)
+	return f
+}
+
+// sameLineText returns how many expression statements at the start of stmts
+// share a source line. View text such as "Hello there" parses as one
+// expression statement per word, which stay together on one line.
+func (f *Formatter) sameLineText(stmts []ast.Stmt) int {
+	n := 0
+	for i, stmt := range stmts {
+		e, ok := stmt.(*ast.ExprStmt)
+		if !ok || e.Span.Start != e.Expr.GetSpan().Start {
+			break
+		}
+		if i > 0 && lineOf(stmt) != endLine(stmts[i-1]) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// writeText writes expression statements that shared a source line
+func (f *Formatter) writeText(stmts []ast.Stmt) {
+	words := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		words[i] = f.render(stmt.(*ast.ExprStmt).Expr)
+	}
+	f.writeLogical([]string{strings.Join(words, " ")}, lineOf(stmts[0]), endLine(stmts[len(stmts)-1]), false)
+}
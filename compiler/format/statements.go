@@ -0,0 +1,492 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Simple statements
+
+func (f *Formatter) VisitExprStmt(e *ast.ExprStmt) ast.Visitor {
+	if e.Span.Start != e.Expr.GetSpan().Start {
+		// The parser has no del statement yet: it keeps the targets of
+		// "del a, b" as an expression statement that starts at "del"
+		f.writeStmt(e, f.wrap("del ", e.Expr, ""))
+		return f
+	}
+	f.writeStmt(e, f.wrap("", e.Expr, ""))
+	return f
+}
+
+func (f *Formatter) VisitAssignStmt(a *ast.AssignStmt) ast.Visitor {
+	if binary, ok := a.Value.(*ast.Binary); ok && len(a.Targets) == 1 && binary.Left == a.Targets[0] {
+		// Augmented assignments are parsed as target = target op value,
+		// sharing the target node
+		prefix := f.render(a.Targets[0]) + " " + binary.Operator.Lexeme + "= "
+		f.writeStmt(a, f.wrap(prefix, binary.Right, ""))
+		return f
+	}
+	f.writeStmt(a, f.wrap(f.targets(a.Targets)+" = ", a.Value, ""))
+	return f
+}
+
+// targets returns the comma-separated targets of an assignment
+func (f *Formatter) targets(targets []ast.Expr) string {
+	parts := make([]string, len(targets))
+	for i, target := range targets {
+		parts[i] = f.render(target)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// chainedAssignment recognizes the statements the parser makes of a chained
+// assignment: a = b = value becomes one assignment per target list sharing
+// the value, or, when the value is not a simple expression, an assignment
+// of the value to a temporary followed by assignments of the temporary in
+// reverse order. It returns the target lists in source order, the value,
+// and how many statements the chain spans, 0 when stmts does not start
+// with one.
+func chainedAssignment(stmts []ast.Stmt) ([][]ast.Expr, ast.Expr, int) {
+	first, ok := stmts[0].(*ast.AssignStmt)
+	if !ok {
+		return nil, nil, 0
+	}
+	sharing := func(value ast.Expr) int {
+		n := 0
+		for _, stmt := range stmts[1:] {
+			assign, ok := stmt.(*ast.AssignStmt)
+			if !ok || assign.Value != value {
+				break
+			}
+			n++
+		}
+		return n
+	}
+
+	if temp, ok := first.Targets[0].(*ast.Name); ok && len(first.Targets) == 1 && temp.Token.Span == first.Value.GetSpan() {
+		if n := sharing(temp); n > 0 {
+			chain := make([][]ast.Expr, n)
+			for i := range n {
+				chain[n-1-i] = stmts[1+i].(*ast.AssignStmt).Targets
+			}
+			return chain, first.Value, n + 1
+		}
+	}
+
+	n := sharing(first.Value)
+	if n == 0 {
+		return nil, nil, 0
+	}
+	chain := make([][]ast.Expr, n+1)
+	for i := range chain {
+		chain[i] = stmts[i].(*ast.AssignStmt).Targets
+	}
+	return chain, first.Value, n + 1
+}
+
+// writeChain writes a chained assignment made of stmts
+func (f *Formatter) writeChain(chain [][]ast.Expr, value ast.Expr, stmts []ast.Stmt) {
+	var prefix strings.Builder
+	for _, targets := range chain {
+		prefix.WriteString(f.targets(targets) + " = ")
+	}
+	last := 0
+	for _, stmt := range stmts {
+		last = max(last, endLine(stmt))
+	}
+	f.writeLogical(f.wrap(prefix.String(), value, ""), lineOf(stmts[0]), last, false)
+}
+
+func (f *Formatter) VisitAnnotationStmt(a *ast.AnnotationStmt) ast.Visitor {
+	prefix := f.render(a.Target) + ": "
+	if !a.HasValue {
+		f.writeStmt(a, f.wrap(prefix, a.Type, ""))
+		return f
+	}
+	f.writeStmt(a, f.wrap(prefix+f.render(a.Type)+" = ", a.Value, ""))
+	return f
+}
+
+func (f *Formatter) VisitReturnStmt(r *ast.ReturnStmt) ast.Visitor {
+	if r.Value == nil {
+		f.writeStmt(r, []string{"return"})
+		return f
+	}
+	f.writeStmt(r, f.wrap("return ", r.Value, ""))
+	return f
+}
+
+func (f *Formatter) VisitRaiseStmt(r *ast.RaiseStmt) ast.Visitor {
+	if !r.HasException {
+		f.writeStmt(r, []string{"raise"})
+		return f
+	}
+	if r.HasFrom {
+		f.writeStmt(r, f.wrap("raise "+f.render(r.Exception)+" from ", r.FromExpr, ""))
+		return f
+	}
+	f.writeStmt(r, f.wrap("raise ", r.Exception, ""))
+	return f
+}
+
+func (f *Formatter) VisitPassStmt(p *ast.PassStmt) ast.Visitor {
+	f.writeStmt(p, []string{"pass"})
+	return f
+}
+
+func (f *Formatter) VisitBreakStmt(b *ast.BreakStmt) ast.Visitor {
+	f.writeStmt(b, []string{"break"})
+	return f
+}
+
+func (f *Formatter) VisitContinueStmt(c *ast.ContinueStmt) ast.Visitor {
+	f.writeStmt(c, []string{"continue"})
+	return f
+}
+
+func (f *Formatter) VisitYieldStmt(y *ast.YieldStmt) ast.Visitor {
+	f.writeStmt(y, []string{f.render(y.Value)})
+	return f
+}
+
+func (f *Formatter) VisitAssertStmt(a *ast.AssertStmt) ast.Visitor {
+	if a.Message == nil {
+		f.writeStmt(a, f.wrap("assert ", a.Test, ""))
+		return f
+	}
+	f.writeStmt(a, f.wrap("assert "+f.render(a.Test)+", ", a.Message, ""))
+	return f
+}
+
+func (f *Formatter) VisitGlobalStmt(g *ast.GlobalStmt) ast.Visitor {
+	f.writeStmt(g, []string{"global " + f.names(g.Names)})
+	return f
+}
+
+func (f *Formatter) VisitNonlocalStmt(n *ast.NonlocalStmt) ast.Visitor {
+	f.writeStmt(n, []string{"nonlocal " + f.names(n.Names)})
+	return f
+}
+
+// names returns comma-separated names
+func (f *Formatter) names(names []*ast.Name) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name.Token.Lexeme
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *Formatter) VisitImportStmt(i *ast.ImportStmt) ast.Visitor {
+	names := make([]string, len(i.Names))
+	for j, name := range i.Names {
+		names[j] = importName(name)
+	}
+	f.writeStmt(i, []string{"import " + strings.Join(names, ", ")})
+	return f
+}
+
+func (f *Formatter) VisitImportFromStmt(i *ast.ImportFromStmt) ast.Visitor {
+	module := strings.Repeat(".", i.DotCount)
+	if i.DottedName != nil {
+		module += dottedName(i.DottedName)
+	}
+	if i.IsWildcard {
+		f.writeStmt(i, []string{"from " + module + " import *"})
+		return f
+	}
+	names := make([]string, len(i.Names))
+	for j, name := range i.Names {
+		names[j] = importName(name)
+	}
+	text := "from " + module + " import " + strings.Join(names, ", ")
+	if f.fits(text) {
+		f.writeStmt(i, []string{text})
+		return f
+	}
+	f.writeStmt(i, f.split("from "+module+" import (", names, ")"))
+	return f
+}
+
+func importName(name *ast.ImportName) string {
+	if name.AsName != nil {
+		return dottedName(name.DottedName) + " as " + name.AsName.Token.Lexeme
+	}
+	return dottedName(name.DottedName)
+}
+
+func dottedName(name *ast.DottedName) string {
+	parts := make([]string, len(name.Names))
+	for i, part := range name.Names {
+		parts[i] = part.Token.Lexeme
+	}
+	return strings.Join(parts, ".")
+}
+
+func (f *Formatter) VisitTypeAlias(t *ast.TypeAlias) ast.Visitor {
+	prefix := "type " + t.Name.Lexeme
+	if len(t.Params) > 0 {
+		prefix += "[" + f.list(t.Params) + "]"
+	}
+	f.writeStmt(t, f.wrap(prefix+" = ", t.Value, ""))
+	return f
+}
+
+// writeStmt writes a simple statement
+func (f *Formatter) writeStmt(stmt ast.Stmt, lines []string) {
+	f.writeLogical(lines, lineOf(stmt), endLine(stmt), false)
+}
+
+// Compound statements
+
+// writeHeader writes the line introducing a block, which spans source lines
+// first to last
+func (f *Formatter) writeHeader(lines []string, first, last int, definition bool) {
+	lines[len(lines)-1] += ":"
+	f.writeLogical(lines, first, last, definition)
+}
+
+// writeClause writes a header continuing a compound statement, like
+// "else:", which follows the previous block without blank lines
+func (f *Formatter) writeClause(lines []string, first, last int) {
+	f.last = lastNone
+	f.writeHeader(lines, first, last, false)
+}
+
+// clauseStart returns the line of the keyword introducing the else or
+// finally block stmts, or limit when there is no such block
+func (f *Formatter) clauseStart(keyword lexer.TokenType, stmts []ast.Stmt, limit int) int {
+	if len(stmts) == 0 {
+		return limit
+	}
+	return f.clauseLine(keyword, lineOf(stmts[0]))
+}
+
+// writeElse writes the else or finally block stmts introduced on source
+// line line
+func (f *Formatter) writeElse(keyword string, line int, stmts []ast.Stmt, limit int) {
+	if len(stmts) == 0 {
+		return
+	}
+	f.writeClause([]string{keyword}, line, line)
+	f.writeBody(stmts, limit)
+}
+
+func (f *Formatter) VisitIf(i *ast.If) ast.Visitor {
+	f.writeIf(i, "if ")
+	return f
+}
+
+// writeIf writes an if statement or, with keyword "elif ", an elif branch
+func (f *Formatter) writeIf(i *ast.If, keyword string) {
+	limit := f.limit
+	lines := f.wrap(keyword, i.Condition, "")
+	if keyword == "elif " {
+		f.writeClause(lines, lineOf(i), endLine(i.Condition))
+	} else {
+		f.writeHeader(lines, lineOf(i), endLine(i.Condition), false)
+	}
+
+	// An elif is parsed as an if alone in the else block, starting in the
+	// column of the if it continues
+	if len(i.Else) == 1 {
+		if elif, ok := i.Else[0].(*ast.If); ok && elif.Span.Start.Column == i.Span.Start.Column && lineOf(elif) > lineOf(i) {
+			f.writeBody(i.Body, lineOf(elif))
+			f.limit = limit
+			f.writeIf(elif, "elif ")
+			return
+		}
+	}
+	line := f.clauseStart(lexer.Else, i.Else, limit)
+	f.writeBody(i.Body, line)
+	f.writeElse("else", line, i.Else, limit)
+}
+
+func (f *Formatter) VisitWhile(w *ast.While) ast.Visitor {
+	limit := f.limit
+	f.writeHeader(f.wrap("while ", w.Test, ""), lineOf(w), endLine(w.Test), false)
+	line := f.clauseStart(lexer.Else, w.Else, limit)
+	f.writeBody(w.Body, line)
+	f.writeElse("else", line, w.Else, limit)
+	return f
+}
+
+func (f *Formatter) VisitFor(fr *ast.For) ast.Visitor {
+	limit := f.limit
+	prefix := "for "
+	if fr.IsAsync {
+		prefix = "async for "
+	}
+	prefix += f.render(fr.Target) + " in "
+	f.writeHeader(f.wrap(prefix, fr.Iterable, ""), lineOf(fr), endLine(fr.Iterable), false)
+	line := f.clauseStart(lexer.Else, fr.Else, limit)
+	f.writeBody(fr.Body, line)
+	f.writeElse("else", line, fr.Else, limit)
+	return f
+}
+
+func (f *Formatter) VisitWith(w *ast.With) ast.Visitor {
+	limit := f.limit
+	items := make([]string, len(w.Items))
+	last := lineOf(w)
+	for i, item := range w.Items {
+		items[i] = f.render(item.Expr)
+		last = max(last, endLine(item.Expr))
+		if item.As != nil {
+			items[i] += " as " + f.render(item.As)
+			last = max(last, endLine(item.As))
+		}
+	}
+	keyword := "with "
+	if w.IsAsync {
+		keyword = "async with "
+	}
+	f.writeHeader([]string{keyword + strings.Join(items, ", ")}, lineOf(w), last, false)
+	f.writeBody(w.Body, limit)
+	return f
+}
+
+func (f *Formatter) VisitTry(t *ast.Try) ast.Visitor {
+	limit := f.limit
+	finally := f.clauseStart(lexer.Finally, t.Finally, limit)
+	els := f.clauseStart(lexer.Else, t.Else, finally)
+	// Each block ends where the next clause starts
+	next := func(index int) int {
+		if index < len(t.Excepts) {
+			return t.Excepts[index].Span.Start.Line
+		}
+		return els
+	}
+
+	f.writeHeader([]string{"try"}, lineOf(t), lineOf(t), false)
+	f.writeBody(t.Body, next(0))
+	for i, handler := range t.Excepts {
+		keyword := "except"
+		if handler.IsStar {
+			keyword = "except*"
+		}
+		line := handler.Span.Start.Line
+		last := line
+		if handler.Type != nil {
+			keyword += " " + f.render(handler.Type)
+			last = endLine(handler.Type)
+			if handler.Name != nil {
+				keyword += " as " + handler.Name.Token.Lexeme
+			}
+		}
+		f.writeClause([]string{keyword}, line, last)
+		f.writeBody(handler.Body, next(i+1))
+	}
+	f.writeElse("else", els, t.Else, finally)
+	f.writeElse("finally", finally, t.Finally, limit)
+	return f
+}
+
+func (f *Formatter) VisitDecorator(d *ast.Decorator) ast.Visitor {
+	f.writeLogical(f.wrap("@", d.Expr, ""), lineOf(d), endLine(d.Expr), true)
+	// The decorated statement follows directly
+	f.last = lastComment
+	d.Stmt.Accept(f)
+	return f
+}
+
+func (f *Formatter) VisitClass(c *ast.Class) ast.Visitor {
+	limit := f.limit
+	header := "class " + c.Name.Token.Lexeme
+	if len(c.TypeParams) > 0 {
+		params := make([]string, len(c.TypeParams))
+		for i := range c.TypeParams {
+			params[i] = f.render(&c.TypeParams[i])
+		}
+		header += "[" + strings.Join(params, ", ") + "]"
+	}
+	last := endLine(c.Name)
+	lines := []string{header}
+	if len(c.Args) > 0 {
+		args := make([]string, len(c.Args))
+		for i, arg := range c.Args {
+			args[i] = f.render(arg)
+			last = max(last, endLine(arg))
+		}
+		lines = f.wrapItems(header, "(", args, ")", "")
+	}
+	f.writeHeader(lines, lineOf(c), last, true)
+	f.writeBody(c.Body, limit)
+	return f
+}
+
+func (f *Formatter) VisitFunction(fn *ast.Function) ast.Visitor {
+	limit := f.limit
+	header := "def " + fn.Name.Token.Lexeme
+	if fn.IsAsync {
+		header = "async " + header
+	}
+	f.writeHeader(f.signature(header, fn.TypeParameters, fn.Parameters, fn.ReturnType), lineOf(fn), f.signatureEnd(fn.Name, fn.Parameters, fn.ReturnType), true)
+	f.writeBody(fn.Body, limit)
+	return f
+}
+
+// signature returns the lines of a def or view header without its colon,
+// wrapping the parameters one per line when it does not fit
+func (f *Formatter) signature(header string, typeParams []*ast.TypeParam, params *ast.ParameterList, returnType ast.Expr) []string {
+	if len(typeParams) > 0 {
+		parts := make([]string, len(typeParams))
+		for i, param := range typeParams {
+			parts[i] = f.render(param)
+		}
+		header += "[" + strings.Join(parts, ", ") + "]"
+	}
+	suffix := ""
+	if returnType != nil {
+		suffix = " -> " + f.render(returnType)
+	}
+	return f.wrapItems(header, "(", f.parameters(params), ")", suffix)
+}
+
+// signatureEnd returns the last source line of a def or view header
+func (f *Formatter) signatureEnd(name *ast.Name, params *ast.ParameterList, returnType ast.Expr) int {
+	last := endLine(name)
+	if params != nil && len(params.Parameters) > 0 {
+		last = max(last, endLine(params))
+	}
+	if returnType != nil {
+		last = max(last, endLine(returnType))
+	}
+	return last
+}
+
+func (f *Formatter) VisitMatch(m *ast.MatchStmt) ast.Visitor {
+	limit := f.limit
+	f.writeHeader(f.wrap("match ", m.Subject, ""), lineOf(m), endLine(m.Subject), false)
+
+	f.indent++
+	f.depth++
+	outerLast := f.last
+	f.last = lastNone
+	for i, c := range m.Cases {
+		patterns := make([]string, len(c.Patterns))
+		last := c.Span.Start.Line
+		for j, pattern := range c.Patterns {
+			patterns[j] = f.render(pattern)
+			last = max(last, endLine(pattern))
+		}
+		header := "case " + strings.Join(patterns, ", ")
+		if c.Guard != nil {
+			header += " if " + f.render(c.Guard)
+			last = max(last, endLine(c.Guard))
+		}
+		caseLimit := limit
+		if i+1 < len(m.Cases) {
+			caseLimit = m.Cases[i+1].Span.Start.Line
+		}
+		f.limit = caseLimit
+		f.writeHeader([]string{header}, c.Span.Start.Line, last, false)
+		f.writeBody(c.Body, caseLimit)
+	}
+	f.last = outerLast
+	f.depth--
+	f.indent--
+	return f
+}
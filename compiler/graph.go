@@ -0,0 +1,28 @@
+package compiler
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler/depgraph"
+)
+
+// ProjectGraph is the dependency graph of a project, for architecture
+// reviews and checks
+type ProjectGraph struct {
+	Graph  *depgraph.DependencyGraph // Project files, and the vendored and library files they import
+	Errors []*CompilationError       // Files that could not be parsed or whose imports did not resolve; their imports may be missing
+}
+
+// Graph parses every file of the project, and the files they import, and
+// returns their dependency graph. Unlike compilation, import cycles are not
+// an error: they are part of what the graph shows. Files with errors are
+// reported in the Errors of the result, which is still returned.
+func (c *MultiFileCompiler) Graph(ctx context.Context, opts MultiFileOptions) (*ProjectGraph, error) {
+	p, err := c.parseProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(p.errors, func(i, j int) bool { return p.errors[i].File < p.errors[j].File })
+	return &ProjectGraph{Graph: c.depGraph, Errors: p.errors}, nil
+}
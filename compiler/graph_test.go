@@ -0,0 +1,48 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"a.psx": `import b
+`,
+		"b.psx": `import a
+`,
+		"c.psx": `def load():
+    import a
+`,
+		"broken.psx": `view Broken(:
+`,
+	})
+	file := func(name string) string { return filepath.Join(tmpDir, name) }
+
+	project, err := NewMultiFileCompiler(nil).Graph(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if err != nil {
+		t.Fatalf("Graph failed: %v", err)
+	}
+
+	// The cycle between a and b is part of the graph, not an error
+	files := project.Graph.GetAllFiles()
+	sort.Strings(files)
+	if want := []string{file("a.psx"), file("b.psx"), file("c.psx")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("expected files %v, got %v", want, files)
+	}
+	if deps := project.Graph.GetDependencies(file("a.psx")); !reflect.DeepEqual(deps, []string{file("b.psx")}) {
+		t.Errorf("expected a.psx to import b.psx, got %v", deps)
+	}
+	if deps := project.Graph.GetDeferredDependencies(file("c.psx")); !reflect.DeepEqual(deps, []string{file("a.psx")}) {
+		t.Errorf("expected c.psx to import a.psx deferred, got %v", deps)
+	}
+	if len(project.Errors) != 1 || project.Errors[0].File != file("broken.psx") {
+		t.Errorf("expected broken.psx to be reported, got %v", project.Errors)
+	}
+}
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
+)
+
+// DefaultHeader is the header template of the CLI: it marks outputs as
+// generated, so compiling again may overwrite them
+const DefaultHeader = "Generated by topple from {source}; do not edit."
+
+// Header is a comment written at the top of every generated file, such as a
+// license or a generation notice, from a template whose variables are
+// replaced per file:
+//
+//	{source}  the .psx file, relative to the project root (its name when compiled alone)
+//	{hash}    the first 16 hex digits of the SHA-256 of the .psx file
+//
+// The header holds no date, so recompiling an unchanged source reproduces it
+// and verify can check that outputs carry the current one. Lines of the
+// template that are not comments are commented out; {{ and }} write braces.
+type Header struct {
+	Lines []string // Template lines, commented out; nil for no header
+}
+
+// ParseHeader parses a header template, rejecting unknown variables
+func ParseHeader(template string) (Header, error) {
+	template = strings.TrimRight(template, "\n")
+	if template == "" {
+		return Header{}, nil
+	}
+	var header Header
+	for _, line := range strings.Split(template, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if _, err := expandHeader(line, "", ""); err != nil {
+			return Header{}, err
+		}
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case line == "":
+			line = "#"
+		default:
+			line = "# " + line
+		}
+		header.Lines = append(header.Lines, line)
+	}
+	return header, nil
+}
+
+// Render returns the header of the file compiled from source, "" when there
+// is no header. source is the file's path relative to the project root.
+func (h Header) Render(source string, content []byte) string {
+	if len(h.Lines) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:16]
+	var b strings.Builder
+	for _, line := range h.Lines {
+		expanded, _ := expandHeader(line, filepath.ToSlash(source), hash)
+		b.WriteString(expanded)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Marks reports whether code begins with the header, rendered for any
+// source and content
+func (h Header) Marks(code []byte) bool {
+	if len(h.Lines) == 0 {
+		return false
+	}
+	lines := strings.SplitN(string(code), "\n", len(h.Lines)+1)
+	if len(lines) <= len(h.Lines) {
+		return false
+	}
+	for i, line := range h.Lines {
+		// The variables are expanded to markers, then to patterns
+		expanded, _ := expandHeader(line, "\x00source\x00", "\x00hash\x00")
+		pattern := strings.NewReplacer("\x00source\x00", ".*", "\x00hash\x00", "[0-9a-f]{16}").Replace(regexp.QuoteMeta(expanded))
+		if !regexp.MustCompile("^" + pattern + "$").MatchString(strings.TrimRight(lines[i], "\r")) {
+			return false
+		}
+	}
+	return true
+}
+
+// expandHeader replaces the variables of a template line
+func expandHeader(line, source, hash string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "{{"), strings.HasPrefix(line[i:], "}}"):
+			b.WriteByte(line[i])
+			i++
+		case line[i] == '{':
+			end := strings.IndexByte(line[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed { in header template: %s", line)
+			}
+			switch name := line[i+1 : i+end]; name {
+			case "source":
+				b.WriteString(source)
+			case "hash":
+				b.WriteString(hash)
+			default:
+				return "", fmt.Errorf("unknown header variable {%s} (valid: {source}, {hash}; write {{ for a brace)", name)
+			}
+			i += end
+		case line[i] == '}':
+			return "", fmt.Errorf("unmatched } in header template (write }} for a brace): %s", line)
+		default:
+			b.WriteByte(line[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// WithHeader prepends the header of the file compiled from source to its
+// code, moving the source map's generated lines below it
+func (o Options) WithHeader(code []byte, sourceMap *sourcemap.Map, source string, content []byte) []byte {
+	header := o.Header.Render(source, content)
+	if header == "" {
+		return code
+	}
+	sourceMap.Shift(len(o.Header.Lines))
+	return append([]byte(header), code...)
+}
@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	header, err := ParseHeader("SPDX-License-Identifier: MIT\n\n# Generated from {source} ({hash}) by topple; do not edit.\n{{literal}}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := []byte("view Hello():\n    <p>Hello</p>\n")
+	sum := sha256.Sum256(content)
+	expected := "# SPDX-License-Identifier: MIT\n" +
+		"#\n" +
+		"# Generated from pages/hello.psx (" + hex.EncodeToString(sum[:8]) + ") by topple; do not edit.\n" +
+		"# {literal}\n"
+	if got := header.Render(filepath.Join("pages", "hello.psx"), content); got != expected {
+		t.Errorf("expected header:\n%s\ngot:\n%s", expected, got)
+	}
+	// The hash follows the source, not the time of the build
+	if header.Render("a.psx", content) != header.Render("a.psx", content) || header.Render("a.psx", content) == header.Render("a.psx", []byte("x = 1\n")) {
+		t.Error("expected the header to change with the source content only")
+	}
+
+	for _, template := range []string{"built {date}", "unclosed {source", "stray }"} {
+		if _, err := ParseHeader(template); err == nil {
+			t.Errorf("expected %q to be rejected", template)
+		}
+	}
+	if header, err := ParseHeader("\n"); err != nil || header.Render("a.psx", content) != "" {
+		t.Errorf("expected an empty template to write no header, got %q (%v)", header.Render("a.psx", content), err)
+	}
+
+	// Files carrying the header are recognized whatever their source
+	if code := header.Render("other.psx", []byte("x = 1\n")) + "x = 1\n"; !header.Marks([]byte(code)) {
+		t.Errorf("expected the header to mark:\n%s", code)
+	}
+	for _, code := range []string{"x = 1\n", "# SPDX-License-Identifier: MIT\n#\n", "# SPDX-License-Identifier: GPL\n#\n# Generated from a.psx (0123456789abcdef) by topple; do not edit.\n# {literal}\n"} {
+		if header.Marks([]byte(code)) {
+			t.Errorf("expected the header not to mark:\n%s", code)
+		}
+	}
+}
+
+func TestCompileWithHeader(t *testing.T) {
+	header, err := ParseHeader("Generated from {source}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte("view Hello():\n    <p>Hello</p>\n")
+
+	// A single file is named by its base name
+	plain, plainMap, errs := NewCompiler(nil).CompileWithSourceMap(context.Background(), File{Name: "views/hello.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	code, sourceMap, errs := NewCompilerWithOptions(nil, Options{Header: header}).CompileWithSourceMap(context.Background(), File{Name: "views/hello.psx", Content: src})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	if expected := "# Generated from hello.psx\n" + string(plain); string(code) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, code)
+	}
+	if sourceMap.Mappings[0].Generated.Start.Line != plainMap.Mappings[0].Generated.Start.Line+1 {
+		t.Errorf("expected the source map to move below the header, got %+v", sourceMap.Mappings[0])
+	}
+
+	// A project names files relative to its root
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pages"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "pages", "hello.psx")
+	if err := os.WriteFile(file, src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: dir,
+		Files:   []string{file},
+		Options: Options{Header: header},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(output.CompiledFiles[file]); !strings.HasPrefix(got, "# Generated from pages/hello.psx\nfrom topple.psx import") {
+		t.Errorf("expected the header first, got:\n%s", got)
+	}
+}
@@ -0,0 +1,79 @@
+package htmlcheck
+
+// voidElements cannot have content or a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// closesParagraph lists the elements whose start tag closes an open <p>
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"details": true, "dialog": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "main": true,
+	"menu": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "table": true, "ul": true,
+}
+
+// noNesting lists, for an element, the elements it may not appear inside
+// at any depth
+var noNesting = map[string][]string{
+	"a":      {"a", "button"},
+	"button": {"button", "a"},
+	"form":   {"form"},
+	"label":  {"label"},
+}
+
+// requiredParents lists, for an element, the elements that may hold it
+// directly
+var requiredParents = map[string][]string{
+	"li":         {"ul", "ol", "menu"},
+	"dt":         {"dl", "div"},
+	"dd":         {"dl", "div"},
+	"tr":         {"table", "thead", "tbody", "tfoot"},
+	"td":         {"tr"},
+	"th":         {"tr"},
+	"thead":      {"table"},
+	"tbody":      {"table"},
+	"tfoot":      {"table"},
+	"caption":    {"table"},
+	"colgroup":   {"table"},
+	"col":        {"colgroup", "table"},
+	"option":     {"select", "datalist", "optgroup"},
+	"optgroup":   {"select"},
+	"figcaption": {"figure"},
+	"legend":     {"fieldset"},
+	"summary":    {"details"},
+}
+
+// htmlElements lists the elements of the HTML standard; other tags are
+// compositions or custom elements
+var htmlElements = map[string]bool{
+	"a": true, "abbr": true, "address": true, "area": true, "article": true,
+	"aside": true, "audio": true, "b": true, "base": true, "bdi": true,
+	"bdo": true, "blockquote": true, "body": true, "br": true, "button": true,
+	"canvas": true, "caption": true, "cite": true, "code": true, "col": true,
+	"colgroup": true, "data": true, "datalist": true, "dd": true, "del": true,
+	"details": true, "dfn": true, "dialog": true, "div": true, "dl": true,
+	"dt": true, "em": true, "embed": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"head": true, "header": true, "hgroup": true, "hr": true, "html": true,
+	"i": true, "iframe": true, "img": true, "input": true, "ins": true,
+	"kbd": true, "label": true, "legend": true, "li": true, "link": true,
+	"main": true, "map": true, "mark": true, "menu": true, "meta": true,
+	"meter": true, "nav": true, "noscript": true, "object": true, "ol": true,
+	"optgroup": true, "option": true, "output": true, "p": true,
+	"picture": true, "pre": true, "progress": true, "q": true, "rp": true,
+	"rt": true, "ruby": true, "s": true, "samp": true, "script": true,
+	"search": true, "section": true, "select": true, "slot": true,
+	"small": true, "source": true, "span": true, "strong": true,
+	"style": true, "sub": true, "summary": true, "sup": true, "table": true,
+	"tbody": true, "td": true, "template": true, "textarea": true,
+	"tfoot": true, "th": true, "thead": true, "time": true, "title": true,
+	"tr": true, "track": true, "u": true, "ul": true, "var": true,
+	"video": true, "wbr": true,
+}
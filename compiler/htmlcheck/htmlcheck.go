@@ -0,0 +1,191 @@
+// Package htmlcheck checks the markup of views for HTML that browsers would
+// not render as written.
+//
+// It runs on the module the parser returns, before name resolution. Void
+// elements such as <br> and <img> given content are errors, since they
+// cannot hold any. Elements nested where the HTML parser of a browser would
+// move or close them, such as a <div> inside a <p>, an <a> inside another
+// <a> or an <li> outside a list, are warnings: the page still renders, but
+// not with the structure the view describes. Mismatched closing tags and
+// duplicate attributes are already reported by the parser.
+//
+// Only built-in HTML elements are checked. Compositions of views, custom
+// elements and computed tags render content the checker cannot see, so
+// elements inside them start afresh. Fragments and <slot> are transparent,
+// since their content renders where they are.
+package htmlcheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Problem is markup that does not render as written
+type Problem struct {
+	Severity diagnostics.Severity
+	Code     diagnostics.Code
+	Message  string
+	Span     lexer.Span
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%s (position %s)", p.Message, p.Span)
+}
+
+// Diagnostic returns the structured form of the problem
+func (p *Problem) Diagnostic() *diagnostics.Diagnostic {
+	return &diagnostics.Diagnostic{
+		Severity: p.Severity,
+		Code:     p.Code,
+		Message:  p.Message,
+		Span:     lexer.DiagnosticSpan(p.Span),
+	}
+}
+
+// Check checks the markup of every view of module, returning the errors
+// and the warnings it finds in source order
+func Check(module *ast.Module) (errs, warnings []error) {
+	c := &checker{}
+	c.stmts(module.Body, nil)
+	return c.errs, c.warnings
+}
+
+type checker struct {
+	errs     []error
+	warnings []error
+}
+
+// stmts checks statements whose enclosing HTML elements are open, the
+// innermost last
+func (c *checker) stmts(stmts []ast.Stmt, open []string) {
+	for _, stmt := range stmts {
+		c.stmt(stmt, open)
+	}
+}
+
+func (c *checker) stmt(stmt ast.Stmt, open []string) {
+	switch s := stmt.(type) {
+	case *ast.HTMLElement:
+		c.element(s, open)
+	case *ast.ViewStmt:
+		c.stmts(s.Body, nil)
+	case *ast.Function:
+		c.stmts(s.Body, nil)
+	case *ast.Class:
+		c.stmts(s.Body, nil)
+	case *ast.Decorator:
+		c.stmt(s.Stmt, open)
+	case *ast.MultiStmt:
+		c.stmts(s.Stmts, open)
+	case *ast.If:
+		c.stmts(s.Body, open)
+		c.stmts(s.Else, open)
+	case *ast.For:
+		c.stmts(s.Body, open)
+		c.stmts(s.Else, open)
+	case *ast.While:
+		c.stmts(s.Body, open)
+		c.stmts(s.Else, open)
+	case *ast.With:
+		c.stmts(s.Body, open)
+	case *ast.Try:
+		c.stmts(s.Body, open)
+		for _, except := range s.Excepts {
+			c.stmts(except.Body, open)
+		}
+		c.stmts(s.Else, open)
+		c.stmts(s.Finally, open)
+	case *ast.MatchStmt:
+		for _, block := range s.Cases {
+			c.stmts(block.Body, open)
+		}
+	}
+}
+
+// element checks an element against the elements enclosing it, then its
+// content
+func (c *checker) element(el *ast.HTMLElement, open []string) {
+	name := strings.ToLower(el.TagName.Lexeme)
+	if el.IsFragment() || name == "slot" {
+		c.stmts(el.Content, open)
+		return
+	}
+	if el.TagExpr != nil || !htmlElements[name] {
+		// What a composition or custom element renders around its content
+		// is unknown
+		c.stmts(el.Content, nil)
+		return
+	}
+
+	if voidElements[name] && len(el.Content) > 0 {
+		c.errs = append(c.errs, &Problem{
+			Severity: diagnostics.Error,
+			Code:     diagnostics.CodeVoidContent,
+			Message:  fmt.Sprintf("<%s> is a void element and cannot have content", name),
+			Span:     el.Span,
+		})
+	}
+	if message := nesting(name, open); message != "" {
+		c.warnings = append(c.warnings, &Problem{
+			Severity: diagnostics.Warning,
+			Code:     diagnostics.CodeInvalidNesting,
+			Message:  message,
+			Span:     el.TagName.Span,
+		})
+	}
+
+	if name == "template" {
+		// Template content is parsed as a document of its own
+		c.stmts(el.Content, nil)
+		return
+	}
+	c.stmts(el.Content, append(open[:len(open):len(open)], name))
+}
+
+// nesting describes how a browser would restructure an element opened
+// inside the open elements, or returns "" when it renders as written
+func nesting(name string, open []string) string {
+	if len(open) == 0 {
+		// At the top of a view, the parent is wherever the view renders
+		return ""
+	}
+	parent := open[len(open)-1]
+
+	if parents, ok := requiredParents[name]; ok && !contains(parents, parent) {
+		return fmt.Sprintf("<%s> should be inside %s, not <%s>", name, tagList(parents), parent)
+	}
+	if closesParagraph[name] && contains(open, "p") {
+		return fmt.Sprintf("<%s> inside <p>: browsers close the <p> before it", name)
+	}
+	for _, outer := range noNesting[name] {
+		if contains(open, outer) {
+			return fmt.Sprintf("<%s> inside <%s>: browsers do not nest them", name, outer)
+		}
+	}
+	return ""
+}
+
+// tagList formats tag names as "<a>, <b> or <c>"
+func tagList(names []string) string {
+	tags := make([]string, len(names))
+	for i, name := range names {
+		tags[i] = "<" + name + ">"
+	}
+	if len(tags) == 1 {
+		return tags[0]
+	}
+	return strings.Join(tags[:len(tags)-1], ", ") + " or " + tags[len(tags)-1]
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
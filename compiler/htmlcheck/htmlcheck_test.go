@@ -0,0 +1,237 @@
+package htmlcheck
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func parseModule(t *testing.T, source string) *ast.Module {
+	t.Helper()
+
+	scanner := lexer.NewScanner([]byte(source))
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("Scanner errors: %v", scanner.Errors)
+	}
+	module, errors := parser.NewParser(tokens).Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Parser errors: %v", errors)
+	}
+	return module
+}
+
+// messages returns the messages of problems
+func messages(t *testing.T, problems []error, code diagnostics.Code) []string {
+	t.Helper()
+
+	var msgs []string
+	for _, err := range problems {
+		p, ok := err.(*Problem)
+		if !ok {
+			t.Fatalf("Expected a *Problem, got %T", err)
+		}
+		if p.Code != code {
+			t.Errorf("Expected code %s, got %s for %q", code, p.Code, p.Message)
+		}
+		msgs = append(msgs, p.Message)
+	}
+	return msgs
+}
+
+func TestVoidElementContent(t *testing.T) {
+	module := parseModule(t, `view Page():
+    <br />
+    <img src="a.png" />
+    <p>
+        <br>oops</br>
+    </p>
+    if True:
+        <input>{value}</input>
+`)
+	errs, warnings := Check(module)
+	if len(warnings) > 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+	want := []string{
+		"<br> is a void element and cannot have content",
+		"<input> is a void element and cannot have content",
+	}
+	if got := messages(t, errs, diagnostics.CodeVoidContent); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected errors %q, got %q", want, got)
+	}
+
+	line := errs[0].(*Problem).Span.Start.Line
+	if line != 5 {
+		t.Errorf("Expected the first error on line 5, got %d", line)
+	}
+}
+
+func TestNesting(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "valid markup",
+			source: `view Page(items: list):
+    <p>Some <b>bold</b> and <a href="/">linked</a> text</p>
+    <ul>
+        for item in items:
+            <li>{item}</li>
+    </ul>
+    <table>
+        <thead><tr><th>Name</th></tr></thead>
+        <tr><td>x</td></tr>
+    </table>
+    <dl>
+        <div><dt>Term</dt><dd>Definition</dd></div>
+    </dl>
+    <select><option>a</option></select>
+`,
+		},
+		{
+			name: "block in paragraph",
+			source: `view Page():
+    <p>
+        <span>
+            <div>block</div>
+        </span>
+        <p>nested</p>
+    </p>
+`,
+			want: []string{
+				"<div> inside <p>: browsers close the <p> before it",
+				"<p> inside <p>: browsers close the <p> before it",
+			},
+		},
+		{
+			name: "interactive in interactive",
+			source: `view Page():
+    <a href="/">
+        <span><a href="/other">inner</a></span>
+        <button>x</button>
+    </a>
+    <form><form></form></form>
+`,
+			want: []string{
+				"<a> inside <a>: browsers do not nest them",
+				"<button> inside <a>: browsers do not nest them",
+				"<form> inside <form>: browsers do not nest them",
+			},
+		},
+		{
+			name: "wrong parent",
+			source: `view Page():
+    <div>
+        <li>stray</li>
+    </div>
+    <table>
+        <td>cell</td>
+    </table>
+    <ul>
+        <div><li>wrapped</li></div>
+    </ul>
+`,
+			want: []string{
+				"<li> should be inside <ul>, <ol> or <menu>, not <div>",
+				"<td> should be inside <tr>, not <table>",
+				"<li> should be inside <ul>, <ol> or <menu>, not <div>",
+			},
+		},
+		{
+			name: "top of a view",
+			source: `view Item(name: str):
+    <li>{name}</li>
+    <td>{name}</td>
+`,
+		},
+		{
+			name: "compositions and templates start afresh",
+			source: `view Card():
+    <div><slot /></div>
+
+view Page():
+    <p>
+        <Card><div>content</div></Card>
+    </p>
+    <ul>
+        <template><li>x</li><div>y</div></template>
+        <my-item><li>z</li></my-item>
+    </ul>
+`,
+		},
+		{
+			name: "slots are transparent",
+			source: `view List():
+    <ul>
+        <slot>
+            <li>empty</li>
+        </slot>
+    </ul>
+    <p>
+        <slot name="extra"><div>block</div></slot>
+    </p>
+`,
+			want: []string{"<div> inside <p>: browsers close the <p> before it"},
+		},
+		{
+			name: "fragments are transparent",
+			source: `view Page():
+    <ul>
+        <><li>a</li><li>b</li></>
+    </ul>
+    <p>
+        <><div>block</div></>
+    </p>
+    <>
+        <li>top</li>
+    </>
+`,
+			want: []string{"<div> inside <p>: browsers close the <p> before it"},
+		},
+		{
+			name: "control flow",
+			source: `view Page(ok: bool):
+    <p>
+        if ok:
+            <div>yes</div>
+        else:
+            <span>no</span>
+    </p>
+`,
+			want: []string{"<div> inside <p>: browsers close the <p> before it"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, warnings := Check(parseModule(t, tt.source))
+			if len(errs) > 0 {
+				t.Errorf("Expected no errors, got %v", errs)
+			}
+			if got := messages(t, warnings, diagnostics.CodeInvalidNesting); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected warnings %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProblemDiagnostic(t *testing.T) {
+	errs, _ := Check(parseModule(t, "view Page():\n    <hr>x</hr>\n"))
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %v", errs)
+	}
+	d := diagnostics.Collect(errs[0])
+	if len(d) != 1 || d[0].Code != diagnostics.CodeVoidContent || d[0].Severity != diagnostics.Error {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	if d[0].Span.Start.Line != 2 || d[0].Span.Start.Column != 5 {
+		t.Errorf("Expected the diagnostic at 2:5, got %d:%d", d[0].Span.Start.Line, d[0].Span.Start.Column)
+	}
+}
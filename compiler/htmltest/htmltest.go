@@ -0,0 +1,230 @@
+// Package htmltest renders PSX views with the Python runtime so components
+// can be tested end to end from Go: a view is compiled with its project,
+// executed by a Python interpreter in a subprocess, and the HTML it renders
+// is compared with a snapshot.
+//
+//	func TestCard(t *testing.T) {
+//		html := htmltest.Render(t, htmltest.View{
+//			File:  "components/card.psx",
+//			Name:  "Card",
+//			Props: map[string]any{"title": "Hello"},
+//		})
+//		htmltest.MatchSnapshot(t, "card", html, htmltest.Normalization{Whitespace: true})
+//	}
+//
+// The topple runtime package must be importable by the interpreter, either
+// installed or found through Runtime.Path.
+package htmltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// PythonEnv names the environment variable that overrides the default
+// interpreter
+const PythonEnv = "TOPPLE_PYTHON"
+
+// DefaultTimeout bounds a render when Runtime.Timeout is zero
+const DefaultTimeout = 30 * time.Second
+
+// renderScript imports the compiled module of a view, instantiates the view
+// with the props as keyword arguments and writes its HTML to stdout. Views
+// are rendered through render_async when the runtime has it, so async views
+// render too.
+const renderScript = `import asyncio, importlib, json, sys
+request = json.load(sys.stdin)
+sys.path.insert(0, request["dir"])
+view = getattr(importlib.import_module(request["module"]), request["view"])(**request["props"])
+if hasattr(view, "render_async"):
+    sys.stdout.write(asyncio.run(view.render_async()))
+else:
+    sys.stdout.write(view.render())
+`
+
+// Runtime locates the Python interpreter that renders views
+type Runtime struct {
+	Python  string        // Interpreter; defaults to $TOPPLE_PYTHON, then python3
+	Path    []string      // Directories prepended to PYTHONPATH, such as the one containing the topple package
+	Timeout time.Duration // Limit of a render; DefaultTimeout when zero
+}
+
+// View is a view to render
+type View struct {
+	File    string           // PSX file defining the view
+	Root    string           // Project root for imports; defaults to the directory of File
+	Name    string           // Name of the view
+	Props   map[string]any   // Arguments of the view, passed by keyword after a JSON round trip
+	Options compiler.Options // Compiler options
+}
+
+// interpreter returns the Python executable of the runtime
+func (r Runtime) interpreter() string {
+	if r.Python != "" {
+		return r.Python
+	}
+	if python := os.Getenv(PythonEnv); python != "" {
+		return python
+	}
+	return "python3"
+}
+
+// Available reports whether the interpreter of the runtime can be found
+func (r Runtime) Available() bool {
+	_, err := exec.LookPath(r.interpreter())
+	return err == nil
+}
+
+// Render compiles the project of v and returns the HTML v renders
+func (r Runtime) Render(ctx context.Context, v View) (string, error) {
+	file, err := filepath.Abs(v.File)
+	if err != nil {
+		return "", fmt.Errorf("invalid view file %s: %w", v.File, err)
+	}
+	root := v.Root
+	if root == "" {
+		root = filepath.Dir(file)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		return "", fmt.Errorf("invalid project root %s: %w", v.Root, err)
+	}
+
+	outDir, err := os.MkdirTemp("", "htmltest-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(outDir)
+
+	module, err := compileProject(ctx, root, file, outDir, v.Options)
+	if err != nil {
+		return "", err
+	}
+
+	props := v.Props
+	if props == nil {
+		props = map[string]any{}
+	}
+	request, err := json.Marshal(map[string]any{
+		"dir":    outDir,
+		"module": module,
+		"view":   v.Name,
+		"props":  props,
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot encode the props of %s: %w", v.Name, err)
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.interpreter(), "-c", renderScript)
+	cmd.Dir = outDir
+	cmd.Env = r.env()
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("rendering %s timed out after %s", v.Name, timeout)
+		}
+		return "", fmt.Errorf("rendering %s failed: %w\n%s", v.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// env returns the environment of the interpreter, with Path prepended to
+// PYTHONPATH
+func (r Runtime) env() []string {
+	env := os.Environ()
+	if len(r.Path) == 0 {
+		return env
+	}
+	paths := make([]string, 0, len(r.Path)+1)
+	for _, path := range r.Path {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		paths = append(paths, path)
+	}
+	if existing := os.Getenv("PYTHONPATH"); existing != "" {
+		paths = append(paths, existing)
+	}
+	return append(env, "PYTHONPATH="+strings.Join(paths, string(os.PathListSeparator)))
+}
+
+// compileProject compiles the project in root to outDir and returns the
+// Python module of file
+func compileProject(ctx context.Context, root, file, outDir string, options compiler.Options) (string, error) {
+	options.OutputDir = outDir
+	output, err := compiler.NewMultiFileCompiler(nil).CompileProject(ctx, compiler.MultiFileOptions{
+		RootDir: root,
+		Files:   []string{root},
+		Options: options,
+	})
+	if err != nil {
+		if output == nil || len(output.Errors) == 0 {
+			return "", fmt.Errorf("compiling %s failed: %w", root, err)
+		}
+		compileErrs := make([]error, len(output.Errors))
+		for i, compileErr := range output.Errors {
+			compileErrs[i] = compileErr
+		}
+		return "", fmt.Errorf("compiling %s failed: %w", root, errors.Join(compileErrs...))
+	}
+	if _, ok := output.CompiledFiles[file]; !ok {
+		return "", fmt.Errorf("%s is not part of the project in %s", file, root)
+	}
+
+	fs := filesystem.NewFileSystem(nil)
+	module := ""
+	for inputPath, code := range output.CompiledFiles {
+		outputPath, err := fs.GetOutputPath(inputPath, outDir)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(outputPath, code, 0o644); err != nil {
+			return "", err
+		}
+		if inputPath == file {
+			module = strings.TrimSuffix(filepath.Base(outputPath), ".py")
+		}
+	}
+	return module, nil
+}
+
+// Render renders v with the default runtime for a test. The test is skipped
+// when there is no Python interpreter, and fails when v cannot be rendered.
+func Render(t testing.TB, v View) string {
+	t.Helper()
+	return RenderWith(t, Runtime{}, v)
+}
+
+// RenderWith renders v with r for a test, like Render
+func RenderWith(t testing.TB, r Runtime, v View) string {
+	t.Helper()
+	if !r.Available() {
+		t.Skipf("Python interpreter %q not found", r.interpreter())
+	}
+	html, err := r.Render(context.Background(), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return html
+}
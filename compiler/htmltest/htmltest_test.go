@@ -0,0 +1,121 @@
+package htmltest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runtime renders with the topple package of this repository
+var runtime = Runtime{Path: []string{"../.."}}
+
+func TestRender(t *testing.T) {
+	html := RenderWith(t, runtime, View{
+		File:  "testdata/project/card.psx",
+		Name:  "Card",
+		Props: map[string]any{"title": "Specials", "tags": []string{"new", "hot"}},
+	})
+
+	for _, want := range []string{
+		"<h2>Specials</h2>",
+		`<span class="badge badge-info">new</span>`,
+		`<span class="badge badge-info">hot</span>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in rendered HTML:\n%s", want, html)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if !runtime.Available() {
+		t.Skip("Python interpreter not found")
+	}
+
+	tests := []struct {
+		name string
+		view View
+		want string
+	}{
+		{
+			name: "unknown view",
+			view: View{File: "testdata/project/card.psx", Name: "Missing"},
+			want: "rendering Missing failed",
+		},
+		{
+			name: "missing props",
+			view: View{File: "testdata/project/card.psx", Name: "Card"},
+			want: "missing 1 required positional argument: 'title'",
+		},
+		{
+			name: "file outside the project",
+			view: View{File: "testdata/project/card.psx", Root: "testdata/project/components", Name: "Card"},
+			want: "is not part of the project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := runtime.Render(context.Background(), tt.view)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRenderCompileError(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "broken.psx")
+	if err := os.WriteFile(file, []byte("view Broken():\n    <div>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runtime.Render(context.Background(), View{File: file, Name: "Broken"})
+	if err == nil || !strings.Contains(err.Error(), "compiling") {
+		t.Errorf("expected a compilation error, got %v", err)
+	}
+}
+
+func TestRenderAsyncView(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "feed.psx")
+	source := `async def load(n):
+    return n * 2
+
+async view Item(n: int):
+    value = await load(n)
+    <li>{value}</li>
+
+async view Feed(count: int):
+    <ul>
+        for n in range(count):
+            <Item n={n} />
+    </ul>
+`
+	if err := os.WriteFile(file, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	html := RenderWith(t, runtime, View{File: file, Name: "Feed", Props: map[string]any{"count": 2}})
+	if want := "<ul><li>0</li><li>2</li></ul>"; html != want {
+		t.Errorf("expected %q, got %q", want, html)
+	}
+}
+
+func TestRuntimeInterpreter(t *testing.T) {
+	t.Setenv(PythonEnv, "")
+	if got := (Runtime{}).interpreter(); got != "python3" {
+		t.Errorf("expected python3 by default, got %q", got)
+	}
+
+	t.Setenv(PythonEnv, "/opt/python/bin/python")
+	if got := (Runtime{}).interpreter(); got != "/opt/python/bin/python" {
+		t.Errorf("expected the interpreter of %s, got %q", PythonEnv, got)
+	}
+	if got := (Runtime{Python: "pypy3"}).interpreter(); got != "pypy3" {
+		t.Errorf("expected the configured interpreter, got %q", got)
+	}
+}
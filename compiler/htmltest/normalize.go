@@ -0,0 +1,228 @@
+package htmltest
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Normalization selects the differences between two renders that snapshots
+// ignore. The zero Normalization compares HTML byte for byte.
+type Normalization struct {
+	// Whitespace collapses runs of whitespace in text to one space and drops
+	// whitespace-only text between tags
+	Whitespace bool
+
+	// SortAttributes orders the attributes of each tag by name
+	SortAttributes bool
+
+	// Indent puts each tag and text run on its own line, indented by
+	// nesting depth, so snapshot diffs point at the element that changed
+	Indent bool
+}
+
+// voidElements have no closing tag and no content
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text that is not markup, or whose whitespace matters
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "pre": true}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	attribute  = regexp.MustCompile(`([^\s=/>"']+)(\s*=\s*("[^"]*"|'[^']*'|[^\s>]+))?`)
+)
+
+// htmlToken is a tag, comment or text run of an HTML document
+type htmlToken struct {
+	text string
+	tag  string // Lowercase name of a tag; "" for text and comments
+	kind int
+}
+
+const (
+	textToken = iota
+	rawToken  // Contents of a raw text element, kept as is
+	openToken
+	closeToken
+	selfClosingToken
+	commentToken
+)
+
+// Normalize rewrites html as selected by n
+func Normalize(html string, n Normalization) string {
+	if !n.Whitespace && !n.SortAttributes && !n.Indent {
+		return html
+	}
+
+	var sb strings.Builder
+	depth := 0
+	for _, tok := range tokenize(html) {
+		if tok.kind == rawToken {
+			sb.WriteString(tok.text)
+			continue
+		}
+
+		text := tok.text
+		switch tok.kind {
+		case textToken:
+			if n.Whitespace || n.Indent {
+				text = whitespace.ReplaceAllString(text, " ")
+				if n.Indent || strings.TrimSpace(text) == "" {
+					text = strings.TrimSpace(text)
+				}
+			}
+		case openToken, selfClosingToken:
+			if n.SortAttributes {
+				text = sortAttributes(tok)
+			}
+		case closeToken:
+			if depth > 0 {
+				depth--
+			}
+		}
+
+		if text != "" {
+			if n.Indent {
+				if sb.Len() > 0 {
+					sb.WriteString("\n")
+				}
+				sb.WriteString(strings.Repeat("  ", depth))
+			}
+			sb.WriteString(text)
+		}
+		if tok.kind == openToken && !voidElements[tok.tag] {
+			depth++
+		}
+	}
+	if n.Indent && sb.Len() > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// tokenize splits html into tags, comments and text runs
+func tokenize(html string) []htmlToken {
+	var tokens []htmlToken
+	for len(html) > 0 {
+		start := strings.IndexByte(html, '<')
+		if start != 0 {
+			if start < 0 {
+				start = len(html)
+			}
+			tokens = append(tokens, htmlToken{text: html[:start], kind: textToken})
+			html = html[start:]
+			continue
+		}
+
+		if strings.HasPrefix(html, "<!--") {
+			end := strings.Index(html, "-->")
+			if end < 0 {
+				end = len(html)
+			} else {
+				end += len("-->")
+			}
+			tokens = append(tokens, htmlToken{text: html[:end], kind: commentToken})
+			html = html[end:]
+			continue
+		}
+
+		end := tagEnd(html)
+		if end < 0 {
+			tokens = append(tokens, htmlToken{text: html, kind: textToken})
+			break
+		}
+		tag := tagToken(html[:end])
+		tokens = append(tokens, tag)
+		html = html[end:]
+
+		if tag.kind == openToken && rawTextElements[tag.tag] {
+			end := strings.Index(strings.ToLower(html), "</"+tag.tag)
+			if end < 0 {
+				end = len(html)
+			}
+			if end > 0 {
+				tokens = append(tokens, htmlToken{text: html[:end], kind: rawToken})
+			}
+			html = html[end:]
+		}
+	}
+	return tokens
+}
+
+// tagEnd returns the length of the tag html starts with, skipping '>' in
+// quoted attribute values, or -1 when it is not closed
+func tagEnd(html string) int {
+	quote := byte(0)
+	for i := 1; i < len(html); i++ {
+		switch c := html[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// tagToken classifies a complete tag such as <a href="/">, </a> or <br/>
+func tagToken(text string) htmlToken {
+	inner := strings.TrimSuffix(strings.TrimPrefix(text, "<"), ">")
+	kind := openToken
+	switch {
+	case strings.HasPrefix(inner, "/"):
+		kind = closeToken
+		inner = inner[1:]
+	case strings.HasPrefix(inner, "!") || strings.HasPrefix(inner, "?"):
+		// Doctype and processing instructions do not nest
+		return htmlToken{text: text, kind: commentToken}
+	case strings.HasSuffix(inner, "/"):
+		kind = selfClosingToken
+	}
+	name := inner
+	if i := strings.IndexAny(name, " \t\r\n/"); i >= 0 {
+		name = name[:i]
+	}
+	return htmlToken{text: text, tag: strings.ToLower(name), kind: kind}
+}
+
+// sortAttributes rewrites an opening tag with its attributes ordered by name
+func sortAttributes(tok htmlToken) string {
+	inner := strings.TrimSuffix(strings.TrimPrefix(tok.text, "<"), ">")
+	selfClosing := strings.HasSuffix(inner, "/")
+	inner = strings.TrimSuffix(inner, "/")
+
+	nameEnd := strings.IndexAny(inner, " \t\r\n")
+	if nameEnd < 0 {
+		return tok.text
+	}
+	attrs := attribute.FindAllString(inner[nameEnd:], -1)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return attrName(attrs[i]) < attrName(attrs[j])
+	})
+
+	var sb strings.Builder
+	sb.WriteString("<" + inner[:nameEnd])
+	for _, attr := range attrs {
+		sb.WriteString(" " + attr)
+	}
+	if selfClosing {
+		sb.WriteString(" /")
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// attrName returns the name of an attribute such as class="card"
+func attrName(attr string) string {
+	if i := strings.IndexAny(attr, "= \t\r\n"); i >= 0 {
+		return attr[:i]
+	}
+	return attr
+}
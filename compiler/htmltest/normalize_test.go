@@ -0,0 +1,56 @@
+package htmltest
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		n    Normalization
+		want string
+	}{
+		{
+			name: "no normalization",
+			html: "<p>  a  </p>\n",
+			want: "<p>  a  </p>\n",
+		},
+		{
+			name: "whitespace",
+			html: "<div>\n  <p>Hello,\n  world</p>\n</div>\n",
+			n:    Normalization{Whitespace: true},
+			want: "<div><p>Hello, world</p></div>",
+		},
+		{
+			name: "sorted attributes",
+			html: `<a id="x" href='/' class=link data-x="a > b"><input type="text" disabled /></a>`,
+			n:    Normalization{SortAttributes: true},
+			want: `<a class=link data-x="a > b" href='/' id="x"><input disabled type="text" /></a>`,
+		},
+		{
+			name: "indent",
+			html: `<!DOCTYPE html><ul><li>One <b>two</b></li><li><br>three</li></ul>`,
+			n:    Normalization{Indent: true},
+			want: "<!DOCTYPE html>\n<ul>\n  <li>\n    One\n    <b>\n      two\n    </b>\n  </li>\n  <li>\n    <br>\n    three\n  </li>\n</ul>\n",
+		},
+		{
+			name: "raw text kept",
+			html: "<div>  <pre>  a\n  b</pre><script>if (a<b) {}</script>  </div>",
+			n:    Normalization{Whitespace: true},
+			want: "<div><pre>  a\n  b</pre><script>if (a<b) {}</script></div>",
+		},
+		{
+			name: "comments",
+			html: "<p><!-- a <b> --> x</p>",
+			n:    Normalization{Indent: true},
+			want: "<p>\n  <!-- a <b> -->\n  x\n</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.html, tt.n); got != tt.want {
+				t.Errorf("Normalize() =\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package htmltest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/diff"
+)
+
+// SnapshotDir is where snapshots are stored, relative to the package under
+// test
+const SnapshotDir = "testdata/snapshots"
+
+// UpdateEnv names the environment variable that rewrites snapshots instead
+// of comparing them when set to 1, like the golden files of the compiler
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// MatchSnapshot compares html, normalized by n, with the snapshot
+// SnapshotDir/name.html and fails t with a diff when they differ. With
+// UPDATE_GOLDEN=1 the snapshot is written instead.
+func MatchSnapshot(t testing.TB, name, html string, n Normalization) {
+	t.Helper()
+	path := filepath.Join(SnapshotDir, name+".html")
+	got := Normalize(html, n)
+
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("cannot create snapshot directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("cannot write snapshot: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("snapshot %s does not exist; run with %s=1 to create it", path, UpdateEnv)
+	}
+	if err != nil {
+		t.Fatalf("cannot read snapshot: %v", err)
+	}
+	if d := diff.Unified(path, "rendered", want, []byte(got)); d != "" {
+		t.Errorf("rendered HTML differs from snapshot %s:\n%s", path, d)
+	}
+}
@@ -0,0 +1,12 @@
+package htmltest
+
+import "testing"
+
+func TestMatchSnapshot(t *testing.T) {
+	html := RenderWith(t, runtime, View{
+		File:  "testdata/project/card.psx",
+		Name:  "Card",
+		Props: map[string]any{"title": "Menu", "tags": []string{"vegan"}},
+	})
+	MatchSnapshot(t, "card", html, Normalization{Whitespace: true, SortAttributes: true, Indent: true})
+}
@@ -0,0 +1,257 @@
+package compiler
+
+import (
+	"context"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+// ProjectIndex lists the top-level definitions of a project and the names
+// and view tags that refer to them, for code navigation outside the
+// language server
+type ProjectIndex struct {
+	Definitions []*IndexDefinition  // Sorted by file, then position
+	References  []IndexReference    // Sorted by file, then position
+	Errors      []*CompilationError // Files that could not be parsed or resolved; their entries may be missing
+}
+
+// IndexDefinition is a view, function, class or variable defined at the top
+// level of a file
+type IndexDefinition struct {
+	Name    string
+	Kind    string // view, function, class or variable
+	File    string
+	Span    lexer.Span // The defined name
+	Private bool       // Underscore-prefixed, not meant to be imported
+}
+
+// IndexReference is a use of a definition: a name, or the tag of an element
+// composing a view
+type IndexReference struct {
+	File       string
+	Span       lexer.Span
+	Definition *IndexDefinition
+}
+
+// Index resolves every file of the project and returns its definitions and
+// the references to them, across files and through imports and re-exports.
+// Definitions of vendored packages and shared libraries are only listed when
+// the project refers to them. Local variables and parameters are left out.
+func (c *MultiFileCompiler) Index(ctx context.Context, opts MultiFileOptions) (*ProjectIndex, error) {
+	p, err := c.resolveProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	index := &ProjectIndex{Errors: p.errors}
+
+	// Symbols are registered dependencies first, so the first file
+	// registering a definition is the one defining it; later ones re-export it
+	definitions := make(map[definitionKey]*IndexDefinition)
+	for _, filePath := range p.order {
+		symbols, err := c.symbolRegistry.GetModuleSymbols(filePath)
+		if err != nil {
+			continue
+		}
+		for _, sym := range sortedSymbols(symbols) {
+			key := keyOf(sym)
+			if _, seen := definitions[key]; seen || (sym.Type == symbol.SymbolVariable && sym.Location.File != filePath) {
+				continue
+			}
+			definitions[key] = &IndexDefinition{
+				Name:    sym.Name,
+				Kind:    sym.Type.String(),
+				File:    filePath,
+				Span:    definitionSpan(sym),
+				Private: sym.Visibility == symbol.Private,
+			}
+		}
+	}
+
+	listed := make(map[*IndexDefinition]bool)
+	list := func(def *IndexDefinition) {
+		if !listed[def] {
+			listed[def] = true
+			index.Definitions = append(index.Definitions, def)
+		}
+	}
+	for _, filePath := range p.order {
+		if !p.files[filePath] {
+			continue
+		}
+		for _, def := range definitions {
+			if def.File == filePath {
+				list(def)
+			}
+		}
+	}
+
+	for _, filePath := range p.order {
+		table, ok := p.tables[filePath]
+		if !ok || !p.files[filePath] {
+			continue
+		}
+		refer := func(span lexer.Span, def *IndexDefinition) {
+			if def == nil || (def.File == filePath && def.Span == span) {
+				return
+			}
+			list(def)
+			index.References = append(index.References, IndexReference{File: filePath, Span: span, Definition: def})
+		}
+
+		for element, view := range table.ViewElements {
+			refer(element.TagName.Span, definitions[definitionKey{node: view}])
+		}
+		aliases := importAliases(p.modules[filePath])
+		for name, v := range table.Variables {
+			refer(name.Span, c.referencedDefinition(filePath, v, aliases, definitions))
+		}
+	}
+
+	sortByPosition := func(fileA, fileB string, a, b lexer.Position) bool {
+		if fileA != fileB {
+			return fileA < fileB
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	}
+	sort.Slice(index.Definitions, func(i, j int) bool {
+		a, b := index.Definitions[i], index.Definitions[j]
+		return sortByPosition(a.File, b.File, a.Span.Start, b.Span.Start)
+	})
+	sort.Slice(index.References, func(i, j int) bool {
+		a, b := index.References[i], index.References[j]
+		return sortByPosition(a.File, b.File, a.Span.Start, b.Span.Start)
+	})
+	sort.Slice(index.Errors, func(i, j int) bool { return index.Errors[i].File < index.Errors[j].File })
+
+	return index, nil
+}
+
+// definitionKey identifies a definition: the statement defining a view,
+// function or class, or one of the names an assignment binds
+type definitionKey struct {
+	node ast.Node
+	name string
+}
+
+// keyOf returns the key of the definition a symbol names; re-exports of a
+// view, function or class share it
+func keyOf(sym *symbol.Symbol) definitionKey {
+	if sym.Type == symbol.SymbolVariable {
+		return definitionKey{node: sym.Node, name: sym.Name}
+	}
+	return definitionKey{node: sym.Node}
+}
+
+// referencedDefinition returns the top-level definition a resolved name
+// refers to, in its own file or imported, or nil for other names
+func (c *MultiFileCompiler) referencedDefinition(filePath string, v *resolver.Variable, aliases map[string]string, definitions map[definitionKey]*IndexDefinition) *IndexDefinition {
+	if v == nil {
+		return nil
+	}
+	source, name := filePath, v.Name
+	switch {
+	case v.IsImported && v.ImportSource != "":
+		source = v.ImportSource
+		if original, ok := aliases[v.Name]; ok {
+			name = original
+		}
+	case v.DefinitionDepth != 0 || v.IsParameter:
+		return nil
+	}
+
+	sym, err := c.symbolRegistry.LookupSymbol(source, name)
+	if err != nil {
+		return nil
+	}
+	return definitions[keyOf(sym)]
+}
+
+// importAliases maps the local names of "from x import a as b" to the
+// imported ones
+func importAliases(mod *ast.Module) map[string]string {
+	aliases := make(map[string]string)
+	if mod == nil {
+		return aliases
+	}
+	for _, stmt := range mod.Body {
+		imp, ok := stmt.(*ast.ImportFromStmt)
+		if !ok {
+			continue
+		}
+		for _, name := range imp.Names {
+			if name.AsName != nil && name.DottedName != nil && len(name.DottedName.Names) > 0 {
+				aliases[name.AsName.Token.Lexeme] = name.DottedName.Names[0].Token.Lexeme
+			}
+		}
+	}
+	return aliases
+}
+
+// sortedSymbols returns the symbols of a module by position, so listings
+// do not depend on map order
+func sortedSymbols(symbols *symbol.ModuleSymbols) []*symbol.Symbol {
+	all := symbols.GetAllSymbols()
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i].Location, all[j].Location
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// definitionSpan returns the span of the name a symbol defines
+func definitionSpan(sym *symbol.Symbol) lexer.Span {
+	switch node := sym.Node.(type) {
+	case *ast.ViewStmt:
+		return node.Name.Span
+	case *ast.Function:
+		return node.Name.Span
+	case *ast.Class:
+		return node.Name.Span
+	case *ast.AnnotationStmt:
+		return node.Target.GetSpan()
+	case *ast.AssignStmt:
+		for _, target := range node.Targets {
+			if name := findTargetName(target, sym.Name); name != nil {
+				return name.Span
+			}
+		}
+	}
+	return sym.Node.GetSpan()
+}
+
+// findTargetName finds the name bound by an assignment target, looking into
+// tuple and list unpacking
+func findTargetName(target ast.Expr, name string) *ast.Name {
+	switch t := target.(type) {
+	case *ast.Name:
+		if t.Token.Lexeme == name {
+			return t
+		}
+	case *ast.TupleExpr:
+		for _, elem := range t.Elements {
+			if found := findTargetName(elem, name); found != nil {
+				return found
+			}
+		}
+	case *ast.ListExpr:
+		for _, elem := range t.Elements {
+			if found := findTargetName(elem, name); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package compiler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// indexProject indexes the files of a test project
+func indexProject(t *testing.T, files map[string]string) (string, *ProjectIndex) {
+	t.Helper()
+	tmpDir := setupTestFiles(t, files)
+	index, err := NewMultiFileCompiler(nil).Index(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if len(index.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", index.Errors[0].Details)
+	}
+	return tmpDir, index
+}
+
+var indexFiles = map[string]string{
+	"components/button.psx": `def _cls(kind):
+    return "btn-" + kind
+
+PRIMARY, SECONDARY = "primary", "secondary"
+
+view Button(label: str, kind: str = "primary"):
+    <button class={_cls(kind)}>{label}</button>
+`,
+	"components/__init__.psx": `from .button import Button as Btn
+`,
+	"home.psx": `from components.button import Button, PRIMARY
+from components import Btn
+
+view Home(title: str):
+    <div>
+        <Button label={title} kind={PRIMARY} />
+        <Btn label="/path" />
+    </div>
+`,
+}
+
+func TestIndex(t *testing.T) {
+	tmpDir, index := indexProject(t, indexFiles)
+	button := filepath.Join(tmpDir, "components/button.psx")
+	home := filepath.Join(tmpDir, "home.psx")
+
+	var defs []string
+	for _, def := range index.Definitions {
+		rel, _ := filepath.Rel(tmpDir, def.File)
+		defs = append(defs, def.Kind+" "+def.Name+" "+filepath.ToSlash(rel))
+	}
+	want := []string{
+		"function _cls components/button.psx",
+		"variable PRIMARY components/button.psx",
+		"variable SECONDARY components/button.psx",
+		"view Button components/button.psx",
+		"view Home home.psx",
+	}
+	if strings.Join(defs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("definitions:\n%s\nwant:\n%s", strings.Join(defs, "\n"), strings.Join(want, "\n"))
+	}
+	if !index.Definitions[0].Private || index.Definitions[3].Private {
+		t.Errorf("expected only _cls to be private")
+	}
+	if span := index.Definitions[2].Span; span.Start.Line != 4 || span.Start.Column != 10 {
+		t.Errorf("expected SECONDARY at its name in the unpacking, got %v", span)
+	}
+
+	// References through imports, aliases, re-exports and view tags
+	count := make(map[string]int)
+	for _, ref := range index.References {
+		if ref.File == home || ref.File == button {
+			count[ref.Definition.Name]++
+		}
+	}
+	// Button: the import, the tag, the tag of the Btn re-export and its import
+	if count["Button"] != 4 || count["PRIMARY"] != 2 || count["_cls"] != 1 || count["Home"] != 0 {
+		t.Errorf("unexpected reference counts %v", count)
+	}
+}
+
+func TestIndexCtags(t *testing.T) {
+	tmpDir, index := indexProject(t, indexFiles)
+
+	var out bytes.Buffer
+	if err := index.WriteCtags(&out, tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 8 || !strings.HasPrefix(lines[0], "!_TAG_FILE_FORMAT\t2\t") {
+		t.Fatalf("unexpected tags file:\n%s", out.String())
+	}
+	want := []string{
+		"Button\tcomponents/button.psx\t/^view Button(label: str, kind: str = \"primary\"):$/;\"\tkind:view\tline:6",
+		"Home\thome.psx\t/^view Home(title: str):$/;\"\tkind:view\tline:4",
+		"PRIMARY\tcomponents/button.psx\t/^PRIMARY, SECONDARY = \"primary\", \"secondary\"$/;\"\tkind:variable\tline:4",
+		"SECONDARY\tcomponents/button.psx\t/^PRIMARY, SECONDARY = \"primary\", \"secondary\"$/;\"\tkind:variable\tline:4",
+		"_cls\tcomponents/button.psx\t/^def _cls(kind):$/;\"\tkind:function\tline:1\taccess:private",
+	}
+	if got := strings.Join(lines[3:], "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("tags:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+	if got := ctagsPattern(`a/b\c`); got != `a\/b\\c` {
+		t.Errorf("ctagsPattern() = %q", got)
+	}
+}
+
+func TestIndexLSIF(t *testing.T) {
+	tmpDir, index := indexProject(t, indexFiles)
+
+	var out bytes.Buffer
+	if err := index.WriteLSIF(&out, tmpDir, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	vertices := make(map[int]lsifElement)
+	var edges []lsifElement
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var e lsifElement
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		if e.Type == "vertex" {
+			vertices[e.ID] = e
+		} else {
+			edges = append(edges, e)
+			// Elements refer only to elements written before them
+			for _, in := range append([]int{e.OutV, e.InV}, e.InVs...) {
+				if _, ok := vertices[in]; in != 0 && !ok {
+					t.Errorf("edge %d refers to unknown vertex %d", e.ID, in)
+				}
+			}
+		}
+	}
+	if vertices[1].Label != "metaData" || vertices[1].ProjectRoot != fileURI(tmpDir) || vertices[1].ToolInfo.Version != "test" {
+		t.Errorf("unexpected metadata %+v", vertices[1])
+	}
+
+	// Follow the reference of the Button tag in home.psx to its definition
+	homeURI := fileURI(filepath.Join(tmpDir, "home.psx"))
+	var tag int
+	for id, v := range vertices {
+		if v.Label == "range" && v.Start.Line == 5 && v.Start.Character == 9 {
+			tag = id
+		}
+	}
+	follow := func(out int, label string) lsifElement {
+		for _, e := range edges {
+			if e.OutV == out && e.Label == label {
+				return e
+			}
+		}
+		t.Fatalf("no %s edge from %d", label, out)
+		return lsifElement{}
+	}
+	set := follow(tag, "next").InV
+	item := follow(follow(set, "textDocument/definition").InV, "item")
+	def := vertices[item.InVs[0]]
+	if doc := vertices[item.Document]; !strings.HasSuffix(doc.URI, "/components/button.psx") || def.Start.Line != 5 || def.Start.Character != 5 || def.End.Character != 11 {
+		t.Errorf("unexpected definition %+v in %+v", def, doc)
+	}
+
+	// The tag's document contains it
+	for _, e := range edges {
+		if e.Label == "contains" && vertices[e.OutV].URI == homeURI {
+			found := false
+			for _, in := range e.InVs {
+				found = found || in == tag
+			}
+			if !found {
+				t.Errorf("home.psx does not contain the tag range")
+			}
+		}
+	}
+}
+
+func TestLSIFPositions(t *testing.T) {
+	// "é" is one UTF-16 unit, "😀" two
+	src := []byte("x = \"é😀\" + y\n")
+	pos := lsifPositionOf(src, lexer.Position{Line: 1, Column: 12})
+	if pos.Line != 0 || pos.Character != 12 {
+		t.Errorf("lsifPositionOf() = %+v, want 0:12", pos)
+	}
+}
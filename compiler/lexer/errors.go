@@ -1,6 +1,11 @@
 package lexer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
 
 // ScannerError is an error that occurs in the scanner.
 // Line and Column use the same indexing system as configured in the Scanner.
@@ -8,9 +13,19 @@ type ScannerError struct {
 	Message string
 	Line    int
 	Column  int
+	Range   Span     // Source range of the offending text
+	Hint    string   // Optional suggestion shown below the code frame
+	Notes   []string // Context shown below the code frame
+	Code    diagnostics.Code
+
+	Mode      LexMode  // Lexing mode the scanner was in
+	ModeSince Position // Where the scanner entered Mode
 }
 
 func (e *ScannerError) Error() string {
+	if e.Mode != PythonMode {
+		return fmt.Sprintf("Error: %s at position %s (in %s mode since L%d:%d)", e.Message, e.Span(), e.Mode, e.ModeSince.Line, e.ModeSince.Column)
+	}
 	return fmt.Sprintf("Error: %s at position %s", e.Message, e.Span())
 }
 
@@ -21,5 +36,71 @@ func (e *ScannerError) Span() string {
 
 // NewScannerError creates a new ScannerError.
 func NewScannerError(message string, line int, column int) *ScannerError {
-	return &ScannerError{Message: message, Line: line, Column: column}
+	pos := Position{Line: line, Column: column}
+	return &ScannerError{Message: message, Line: line, Column: column, Range: Span{Start: pos, End: pos}, Code: diagnostics.CodeInvalidCharacter}
+}
+
+// NewScannerErrorSpan creates a ScannerError covering a source range.
+func NewScannerErrorSpan(code diagnostics.Code, message string, span Span) *ScannerError {
+	return &ScannerError{
+		Message: message,
+		Line:    span.Start.Line,
+		Column:  span.Start.Column,
+		Range:   span,
+		Code:    code,
+	}
+}
+
+// Diagnostic returns the structured form of the error. Errors raised inside
+// view markup get a note naming the lexing mode and showing where the
+// scanner switched into it.
+func (e *ScannerError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     e.Code,
+		Message:  e.Message,
+		Span:     DiagnosticSpan(e.Range),
+	}
+	if e.Mode != PythonMode {
+		d.Labels = append(d.Labels, diagnostics.Label{
+			Span:    DiagnosticSpan(Span{Start: e.ModeSince, End: e.ModeSince}),
+			Message: fmt.Sprintf("scanner entered %s mode here", e.Mode),
+		})
+	}
+	d.Notes = append(d.Notes, e.Notes...)
+	if e.Hint != "" {
+		d.Hints = append(d.Hints, e.Hint)
+	}
+	return d
+}
+
+// CodeFrame renders the error with the offending source line and a caret
+// marker underneath, e.g.
+//
+//	error[E0100]: unexpected '!' – only '!=' is valid in Python
+//	 --> app.psx:3:5
+//	  |
+//	3 | x = !y
+//	  |     ^
+func (e *ScannerError) CodeFrame(src []byte, filename string) string {
+	d := e.Diagnostic()
+	d.File = filename
+	return diagnostics.Renderer{}.Render(d, src)
+}
+
+// looksLikeMarkup reports whether the given 1-based line of src starts with a tag
+func looksLikeMarkup(src []byte, line int) bool {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(lines[line-1]), "<")
+}
+
+// DiagnosticSpan converts a source span for a diagnostic
+func DiagnosticSpan(span Span) diagnostics.Span {
+	return diagnostics.Span{
+		Start: diagnostics.Position{Line: span.Start.Line, Column: span.Start.Column},
+		End:   diagnostics.Position{Line: span.End.Line, Column: span.End.Column},
+	}
 }
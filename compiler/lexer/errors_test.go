@@ -0,0 +1,220 @@
+package lexer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+func TestScannerReportsAllErrorsInOnePass(t *testing.T) {
+	input := "a = !b\n" +
+		"c = \"bad \\x4 escape\"\n" +
+		"d = 'ok \\n'\n" +
+		"e = \"\\N{}\"\n" +
+		"f = 1 $ 2\n"
+
+	scanner := NewScanner([]byte(input))
+	tokens := scanner.ScanTokens()
+
+	if len(scanner.Errors) != 4 {
+		t.Fatalf("expected 4 errors, got %d: %v", len(scanner.Errors), scanner.Errors)
+	}
+
+	wantLines := []int{1, 2, 4, 5}
+	wantMessages := []string{"unexpected '!'", "truncated \\xXX escape", "malformed \\N character escape", "unexpected character '$'"}
+	wantCodes := []diagnostics.Code{diagnostics.CodeInvalidCharacter, diagnostics.CodeInvalidEscape, diagnostics.CodeInvalidEscape, diagnostics.CodeInvalidCharacter}
+	for i, err := range scanner.Errors {
+		scanErr, ok := err.(*ScannerError)
+		if !ok {
+			t.Fatalf("error %d is %T, want *ScannerError", i, err)
+		}
+		if scanErr.Range.Start.Line != wantLines[i] {
+			t.Errorf("error %d line = %d, want %d", i, scanErr.Range.Start.Line, wantLines[i])
+		}
+		if !strings.Contains(scanErr.Message, wantMessages[i]) {
+			t.Errorf("error %d message = %q, want it to contain %q", i, scanErr.Message, wantMessages[i])
+		}
+		if scanErr.Code != wantCodes[i] {
+			t.Errorf("error %d code = %s, want %s", i, scanErr.Code, wantCodes[i])
+		}
+	}
+
+	// Strings with bad escapes are still emitted so parsing can continue
+	strCount := 0
+	for _, tok := range tokens {
+		if tok.Type == String {
+			strCount++
+		}
+	}
+	if strCount != 3 {
+		t.Errorf("expected 3 string tokens after recovery, got %d", strCount)
+	}
+}
+
+func TestEscapeSequenceValidation(t *testing.T) {
+	tests := []struct {
+		input    string
+		hasError bool
+	}{
+		{`"\x41"`, false},
+		{`"\u00e9"`, false},
+		{`"\U0001F600"`, false},
+		{`"\N{BULLET}"`, false},
+		{`"\d unknown escapes are allowed"`, false},
+		{`r"\x"`, false},
+		{`"\x"`, true},
+		{`"\u12"`, true},
+		{`"\U1234567"`, true},
+		{`"\N"`, true},
+		{`"""\x4"""`, true},
+		{`"""escaped \""" quote"""`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input))
+			scanner.ScanTokens()
+			if got := len(scanner.Errors) > 0; got != tt.hasError {
+				t.Errorf("hasError = %v, want %v (errors: %v)", got, tt.hasError, scanner.Errors)
+			}
+		})
+	}
+}
+
+func TestCodeFrame(t *testing.T) {
+	src := []byte("x = 1\ny = \"\\x4\"\n")
+	scanner := NewScanner(src)
+	scanner.ScanTokens()
+	if len(scanner.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", scanner.Errors)
+	}
+
+	frame := scanner.Errors[0].(*ScannerError).CodeFrame(src, "app.psx")
+	want := "error[E0102]: truncated \\xXX escape\n" +
+		" --> app.psx:2:6\n" +
+		"  |\n" +
+		"2 | y = \"\\x4\"\n" +
+		"  |      ^^^\n" +
+		"  = hint: use a raw string (r\"...\") or escape the backslash as \\\\\n"
+	if frame != want {
+		t.Errorf("CodeFrame mismatch\ngot:\n%s\nwant:\n%s", frame, want)
+	}
+}
+
+func TestHTMLTagNewlinePositions(t *testing.T) {
+	input := "view V():\n    <div\n        id=\"x\"\n    >hi</div>\n"
+	scanner := NewScanner([]byte(input))
+	tokens := scanner.ScanTokens()
+
+	for _, tok := range tokens {
+		if tok.Type == String && tok.Literal == "x" && tok.Span.Start.Line != 3 {
+			t.Errorf("attribute value on line %d, want 3", tok.Span.Start.Line)
+		}
+	}
+}
+
+func TestScannerErrorMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantMode  LexMode
+		wantSince Position
+		wantNote  string
+	}{
+		{
+			name:      "interpolation in content",
+			input:     "view V():\n    <p>{a} and {b $ c}</p>\n",
+			wantMode:  HTMLInterpolationMode,
+			wantSince: Position{Line: 2, Column: 16},
+			wantNote:  "note: scanner entered interpolation mode here\n --> app.psx:2:16",
+		},
+		{
+			name:      "tag attributes",
+			input:     "view V():\n    <div class=\"x\" @click>hi</div>\n",
+			wantMode:  HTMLTagMode,
+			wantSince: Position{Line: 2, Column: 5},
+			wantNote:  "note: scanner entered HTML tag mode here\n --> app.psx:2:5",
+		},
+		{
+			name:     "markup outside a view",
+			input:    "def f():\n    <div>$</div>\n",
+			wantMode: PythonMode,
+			wantNote: "note: this line was scanned as Python",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input))
+			scanner.ScanTokens()
+			if len(scanner.Errors) == 0 {
+				t.Fatalf("expected an error")
+			}
+
+			scanErr := scanner.Errors[0].(*ScannerError)
+			if scanErr.Mode != tt.wantMode {
+				t.Errorf("Mode = %v, want %v", scanErr.Mode, tt.wantMode)
+			}
+			if tt.wantMode != PythonMode && scanErr.ModeSince != tt.wantSince {
+				t.Errorf("ModeSince = %v, want %v", scanErr.ModeSince, tt.wantSince)
+			}
+			if frame := scanErr.CodeFrame([]byte(tt.input), "app.psx"); !strings.Contains(frame, tt.wantNote) {
+				t.Errorf("CodeFrame missing %q:\n%s", tt.wantNote, frame)
+			}
+		})
+	}
+}
+
+func TestMalformedFStringRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantMsg string
+	}{
+		{"single closing brace", "x = f\"w}px\"\ny = 1\n", "single '}' is not allowed"},
+		{"single closing brace in an attribute", "view V():\n    <div style={f\"w}px\"}>\n    </div>\ny = 1\n", "single '}' is not allowed"},
+		{"newline in a single-quoted f-string", "x = f\"abc\ny = 1\n", "unterminated string literal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) != 1 || !strings.Contains(scanner.Errors[0].Error(), tt.wantMsg) {
+				t.Fatalf("expected one error containing %q, got %v", tt.wantMsg, scanner.Errors)
+			}
+			if tokens[len(tokens)-1].Type != EOF {
+				t.Fatalf("token stream does not end with EOF")
+			}
+			// The line after the f-string is scanned as usual
+			found := false
+			for _, tok := range tokens {
+				if tok.Type == Identifier && tok.Lexeme == "y" && tok.Span.Start.Line == strings.Count(tt.input, "\n") {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected the last line to be scanned, got %v", tokens)
+			}
+		})
+	}
+}
+
+func TestWhitespaceAfterLineContinuation(t *testing.T) {
+	scanner := NewScanner([]byte("x = 1 + \\  \n    2\ny = 3\n"))
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) != 1 || !strings.Contains(scanner.Errors[0].Error(), "whitespace after line continuation") {
+		t.Fatalf("expected one whitespace error, got %v", scanner.Errors)
+	}
+	// The lines are joined anyway, so the statement scans as intended
+	var types []TokenType
+	for _, tok := range tokens {
+		types = append(types, tok.Type)
+	}
+	want := []TokenType{Identifier, Equal, Number, Plus, Number, Newline, Identifier, Equal, Number, Newline, EOF}
+	if !slices.Equal(types, want) {
+		t.Errorf("expected %v, got %v", want, types)
+	}
+}
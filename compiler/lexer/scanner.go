@@ -9,11 +9,16 @@ package lexer
 // consumption.  Any diagnostics are placed in the public Errors slice.
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 )
 
 // ── configuration helper ──────────────────────────────────────────────
@@ -21,8 +26,30 @@ import (
 type ScannerConfig struct {
 	StartLine   int // usually 1
 	StartColumn int // usually 1
+
+	// PreserveHTMLComments emits <!-- ... --> as HTMLComment tokens instead of discarding them
+	PreserveHTMLComments bool
+
+	// RawTextElements are tag names whose content is not lexed: everything up
+	// to the closing tag becomes a single HTMLTextInline token
+	RawTextElements []string
+
+	// PreserveWhitespace keeps whitespace-only text between tags and
+	// interpolations, such as the space in <b>a</b> <i>b</i>, instead of
+	// discarding it. It is always kept inside WhitespaceElements.
+	PreserveWhitespace bool
 }
 
+// WhitespaceElements are the elements whose text is kept as written,
+// newlines and whitespace-only text included, since browsers render it so.
+// Their content may span lines without being indented as a block.
+var WhitespaceElements = []string{"pre", "textarea"}
+
+// ScriptElements are the raw text elements of HTML: their content is not
+// lexed, like that of RawTextElements, so scripts and style sheets keep their
+// braces and angle brackets
+var ScriptElements = []string{"script", "style"}
+
 func DefaultScannerConfig() ScannerConfig {
 	return ScannerConfig{StartLine: 1, StartColumn: 1}
 }
@@ -33,6 +60,7 @@ type fstringContext struct {
 	braceDepth   int  // Depth of nested braces in expressions
 	inExpression bool // Whether we're currently parsing an expression inside {}
 	inFormatSpec bool // Whether we're currently parsing a format specification
+	parenDepth   int  // Bracket nesting where the current replacement field started
 	nestingLevel int  // Level of f-string nesting (for nested f-strings)
 	isRaw        bool // Whether this is a raw f-string
 	isTriple     bool // Whether this is a triple-quoted f-string
@@ -42,23 +70,52 @@ type fstringContext struct {
 type LexMode int
 
 const (
-	PythonMode            LexMode = iota // Normal Python tokenization
-	HTMLTagMode                          // Inside <tag attributes> - handles tag names, attributes, and >
-	HTMLContentMode                      // Inside tag content, can contain text
-	HTMLInterpolationMode                // Inside {expression} within HTML
+	PythonMode             LexMode = iota // Normal Python tokenization
+	HTMLTagMode                           // Inside <tag attributes> - handles tag names, attributes, and >
+	HTMLContentMode                       // Inside tag content, can contain text
+	HTMLInterpolationMode                 // Inside {expression} within HTML
+	HTMLAttributeValueMode                // Inside a quoted attribute value with {expression} parts
 )
 
+// String returns a human readable name for the mode, used in diagnostics
+func (m LexMode) String() string {
+	switch m {
+	case PythonMode:
+		return "Python"
+	case HTMLTagMode:
+		return "HTML tag"
+	case HTMLContentMode:
+		return "HTML content"
+	case HTMLInterpolationMode:
+		return "interpolation"
+	case HTMLAttributeValueMode:
+		return "attribute value"
+	default:
+		return fmt.Sprintf("LexMode(%d)", int(m))
+	}
+}
+
+// viewScope tracks a view whose body the scanner may be in
+type viewScope struct {
+	indent    int  // Indentation of the view header
+	decorated bool // Entered through @view; the decorated def header is still ahead
+}
+
 // LexerContext tracks the state for context-aware lexing
 type LexerContext struct {
-	viewDepth       int       // Depth of nested view functions (0 = not in view)
-	mode            LexMode   // Current lexing mode
-	htmlTagDepth    int       // Track HTML tag nesting
-	atLineStart     bool      // Are we at the start of a logical line?
-	pendingIndent   bool      // Waiting to process indentation?
-	htmlTagName     string    // Current HTML tag being processed
-	isClosingTag    bool      // Whether we're parsing a closing tag
-	inHTMLAttribute bool      // Whether we're inside an HTML attribute
-	modeStack       []LexMode // Stack to track mode before interpolations
+	views           []viewScope // Enclosing views, innermost last
+	mode            LexMode     // Current lexing mode
+	htmlTagDepth    int         // Track HTML tag nesting
+	atLineStart     bool        // Are we at the start of a logical line?
+	pendingIndent   bool        // Waiting to process indentation?
+	htmlTagName     string      // Current HTML tag being processed
+	isClosingTag    bool        // Whether we're parsing a closing tag
+	inHTMLAttribute bool        // Whether we're inside an HTML attribute
+	modeStack       []LexMode   // Stack to track mode before interpolations
+	interpBraces    []int       // Open Python braces inside each active interpolation
+	attrQuote       rune        // Quote character of the attribute value being scanned
+	preformatted    int         // Open WhitespaceElements
+	inlineTags      int         // Elements opened on the current line and still open: their content is inline
 }
 
 // ── scanner object ───────────────────────────────────────────────────
@@ -70,8 +127,12 @@ type Scanner struct {
 	// location of *start* of current lexeme:
 	lexLine, lexCol int
 
-	tokens []Token
-	Errors []error
+	tokens   []Token
+	comments []Token // # comments, kept out of the token stream
+	next     int     // Index in tokens of the token NextToken returns next
+	finished bool    // The dedents closing the source and EOF were added
+	stalls   int     // Scanning steps in a row that consumed no source
+	Errors   []error
 
 	indentStack []int // stack[0] == 0  (invariant)
 	parenDepth  int   // (),[],{} nesting ⇒ lines may continue
@@ -82,6 +143,18 @@ type Scanner struct {
 
 	// Lexer context for HTML/Python mode switching
 	ctx LexerContext
+
+	// Mode the scanner was last seen in and where it entered that mode,
+	// reported with errors so users can tell how their source was lexed
+	lastMode  LexMode
+	modeSince Position
+
+	// Cancellation of ScanTokensContext, checked once per source line
+	cancel      context.Context
+	checkedLine int
+
+	// Grammar selected by the file's syntax pragma
+	syntax SyntaxVersion
 }
 
 // NewScanner returns a default-configured scanner.
@@ -97,29 +170,184 @@ func NewScannerWithConfig(src []byte, cfg ScannerConfig) *Scanner {
 		lexLine:     cfg.StartLine,
 		lexCol:      cfg.StartColumn,
 		cfg:         cfg,
+		modeSince:   Position{Line: cfg.StartLine, Column: cfg.StartColumn},
 		indentStack: []int{0}, // invariant bottom = 0
 		ctx: LexerContext{
 			mode:        PythonMode,
 			atLineStart: true,
 		},
 	}
+	sc.selectSyntax()
 	return sc
 }
 
+// selectSyntax applies the "# topple: syntax=X.Y" pragma on the first line,
+// falling back to the current syntax when there is none or it is invalid.
+func (s *Scanner) selectSyntax() {
+	version, found, err := syntaxPragma(s.src)
+	s.syntax = version
+	if found && err != nil {
+		line, _, _ := strings.Cut(string(s.src), "\n")
+		s.errorAt(Span{
+			Start: Position{Line: s.cfg.StartLine, Column: s.cfg.StartColumn},
+			End:   Position{Line: s.cfg.StartLine, Column: s.cfg.StartColumn + utf8.RuneCountInString(line)},
+		}, diagnostics.CodeInvalidCharacter, "", "invalid syntax pragma: %v", err)
+	}
+}
+
+// Position returns the location the scanner has reached.
+func (s *Scanner) Position() Position {
+	return Position{Line: s.line, Column: s.col}
+}
+
+// Syntax returns the grammar version the source is scanned with.
+func (s *Scanner) Syntax() SyntaxVersion { return s.syntax }
+
+// Comments returns the # comments skipped while scanning, in source order.
+// They are not part of the token stream; tools that reprint the source,
+// like the formatter, use them to put the comments back.
+func (s *Scanner) Comments() []Token { return s.comments }
+
 // ── public entrypoint ────────────────────────────────────────────────
 
 func (s *Scanner) ScanTokens() []Token {
 	for !s.atEnd() {
-		s.lexLine, s.lexCol = s.line, s.col
-		s.start = s.cur
-		s.scanToken()
+		if s.cancelled() {
+			return s.tokens
+		}
+		s.scanNext()
+	}
+	s.finish()
+
+	// Post-process tokens to detect composite tokens
+	s.processCompositeTokens()
+
+	return s.tokens
+}
+
+// NextToken scans and returns the next token, reading only as much of the
+// source as it takes. The tokens are those ScanTokens returns, one at a
+// time; after the last, EOF is returned again on every call. Errors are
+// appended to Errors as they are found, so a caller can stop at the first.
+// Returned tokens are released, so the token buffer does not grow with the
+// file. A scanner is used either through NextToken or through ScanTokens.
+func (s *Scanner) NextToken() Token {
+	// Composite tokens like "is not" take a token of lookahead
+	for len(s.tokens)-s.next < 2 && !s.finished {
+		if s.atEnd() {
+			s.finish()
+		} else {
+			s.scanNext()
+		}
+	}
+	if s.next == len(s.tokens) {
+		return s.tokens[len(s.tokens)-1] // EOF
+	}
+
+	tok := s.tokens[s.next]
+	s.next++
+	if s.next < len(s.tokens) {
+		if composite, ok := compositeToken(tok, s.tokens[s.next]); ok {
+			tok = composite
+			s.next++
+		}
+	}
+	s.release()
+	return tok
+}
+
+// releaseThreshold is how many returned tokens NextToken lets pile up before
+// releasing them
+const releaseThreshold = 256
+
+// release drops the tokens NextToken returned, keeping those the scanner
+// still looks back at: the last token, and the tokens of a tag still open,
+// from its '<'
+func (s *Scanner) release() {
+	keep := min(s.next, len(s.tokens)-1)
+	if keep < releaseThreshold {
+		return
+	}
+	for i := len(s.tokens) - 1; i >= 0; i-- {
+		t := s.tokens[i].Type
+		if t == TagClose || t == TagSelfClose {
+			break
+		}
+		if t == TagOpen || t == TagCloseStart {
+			keep = min(keep, i)
+			break
+		}
+	}
+	if keep == 0 {
+		return
 	}
+	n := copy(s.tokens, s.tokens[keep:])
+	clear(s.tokens[n:])
+	s.tokens = s.tokens[:n]
+	s.next -= keep
+}
+
+// maxStalls is how many scanning steps in a row may consume no source, such
+// as steps that only switch modes, before the scanner skips a character
+const maxStalls = 64
+
+// scanNext scans the token, or tokens, starting at the cursor. Whatever the
+// input, it never panics and the scan always moves on: a bug that panics or
+// stops consuming source is reported as an error and scanning resumes at
+// the next character.
+func (s *Scanner) scanNext() {
+	s.lexLine, s.lexCol = s.line, s.col
+	s.start = s.cur
+	from := s.cur
+	defer func() {
+		if r := recover(); r != nil {
+			s.errorAt(s.currentSpan(), diagnostics.CodeInvalidCharacter, "", "internal scanner error: %v", r)
+			s.recoverAt(from)
+			return
+		}
+		if s.cur > from {
+			s.stalls = 0
+			return
+		}
+		if s.stalls++; s.stalls >= maxStalls && !s.atEnd() {
+			s.errorAt(s.currentSpan(), diagnostics.CodeInvalidCharacter, "", "internal scanner error: no progress at %q", s.peek())
+			s.recoverAt(from)
+		}
+	}()
+	s.scanToken()
+}
+
+// recoverAt resets the scanner after an internal error in the step that
+// started at offset from, skipping at least one character: open f-strings
+// are dropped and scanning resumes in the mode of the enclosing block
+func (s *Scanner) recoverAt(from int) {
+	s.stalls = 0
+	s.fstringStack = nil
+	s.ctx.modeStack = nil
+	s.ctx.interpBraces = nil
+	s.ctx.inHTMLAttribute = false
+	if s.cur <= from {
+		s.cur = from
+		s.advance()
+	}
+	if s.inView() {
+		s.ctx.mode = HTMLContentMode
+	} else {
+		s.ctx.mode = PythonMode
+	}
+	s.start = s.cur
+	s.lexLine, s.lexCol = s.line, s.col
+}
 
+// finish ends the token stream at the end of the source: the dedents of
+// the blocks still open, then EOF
+func (s *Scanner) finish() {
 	// flush pending dedents (PEP Tokenizer rule 3)
 	for len(s.indentStack) > 1 {
 		s.indentStack = s.indentStack[:len(s.indentStack)-1]
 		// Set proper position for dedent token at EOF
 		s.start = s.cur
+		s.lexLine, s.lexCol = s.line, s.col
 		s.addToken(Dedent)
 	}
 
@@ -130,11 +358,30 @@ func (s *Scanner) ScanTokens() []Token {
 			End:   Position{Line: s.line, Column: s.col},
 		},
 	})
+	s.finished = true
+}
 
-	// Post-process tokens to detect composite tokens
-	s.processCompositeTokens()
+// ScanTokensContext scans like ScanTokens but stops at the next line boundary
+// once ctx is cancelled, returning the tokens scanned so far and ctx's error.
+func (s *Scanner) ScanTokensContext(ctx context.Context) ([]Token, error) {
+	s.cancel = ctx
+	defer func() { s.cancel = nil }()
 
-	return s.tokens
+	tokens := s.ScanTokens()
+	if err := ctx.Err(); err != nil {
+		return tokens, err
+	}
+	return tokens, nil
+}
+
+// cancelled reports whether the scan context is done. It is only consulted
+// when the scanner reaches a new line to keep the per-token cost negligible.
+func (s *Scanner) cancelled() bool {
+	if s.cancel == nil || s.line == s.checkedLine {
+		return false
+	}
+	s.checkedLine = s.line
+	return s.cancel.Err() != nil
 }
 
 // Process tokens to detect and generate composite tokens like "is not" and "not in"
@@ -147,38 +394,12 @@ func (s *Scanner) processCompositeTokens() {
 	i := 0
 
 	for i < len(s.tokens) {
-		// Check for "is not" sequence
-		if i+1 < len(s.tokens) &&
-			s.tokens[i].Type == Is && s.tokens[i+1].Type == Not {
-			// Create a composite "is not" token
-			isNotToken := Token{
-				Type:   IsNot,
-				Lexeme: "is not",
-				Span: Span{
-					Start: s.tokens[i].Start(),
-					End:   s.tokens[i+1].End(),
-				},
-			}
-			processed = append(processed, isNotToken)
-			i += 2 // Skip both tokens
-			continue
-		}
-
-		// Check for "not in" sequence
-		if i+1 < len(s.tokens) &&
-			s.tokens[i].Type == Not && s.tokens[i+1].Type == In {
-			// Create a composite "not in" token
-			notInToken := Token{
-				Type:   NotIn,
-				Lexeme: "not in",
-				Span: Span{
-					Start: s.tokens[i].Start(),
-					End:   s.tokens[i+1].End(),
-				},
+		if i+1 < len(s.tokens) {
+			if composite, ok := compositeToken(s.tokens[i], s.tokens[i+1]); ok {
+				processed = append(processed, composite)
+				i += 2 // Skip both tokens
+				continue
 			}
-			processed = append(processed, notInToken)
-			i += 2 // Skip both tokens
-			continue
 		}
 
 		// Regular token, just add it
@@ -189,6 +410,22 @@ func (s *Scanner) processCompositeTokens() {
 	s.tokens = processed
 }
 
+// compositeToken combines two adjacent tokens into one when they form "is
+// not" or "not in"
+func compositeToken(first, second Token) (Token, bool) {
+	var composite Token
+	switch {
+	case first.Type == Is && second.Type == Not:
+		composite = Token{Type: IsNot, Lexeme: "is not"}
+	case first.Type == Not && second.Type == In:
+		composite = Token{Type: NotIn, Lexeme: "not in"}
+	default:
+		return Token{}, false
+	}
+	composite.Span = Span{Start: first.Start(), End: second.End()}
+	return composite, true
+}
+
 // ── low-level helpers ────────────────────────────────────────────────
 
 func (s *Scanner) atEnd() bool { return s.cur >= len(s.src) }
@@ -249,6 +486,18 @@ func (s *Scanner) addToken(tt TokenType) {
 	})
 }
 
+// addComment records the current lexeme as a comment
+func (s *Scanner) addComment() {
+	s.comments = append(s.comments, Token{
+		Type:   Comment,
+		Lexeme: strings.TrimRight(string(s.src[s.start:s.cur]), "\r"),
+		Span: Span{
+			Start: Position{Line: s.lexLine, Column: s.lexCol},
+			End:   Position{Line: s.line, Column: s.col},
+		},
+	})
+}
+
 func (s *Scanner) addTokenLit(tt TokenType, lit any) {
 	s.tokens = append(s.tokens, Token{
 		Type:    tt,
@@ -261,15 +510,67 @@ func (s *Scanner) addTokenLit(tt TokenType, lit any) {
 	})
 }
 
-func (s *Scanner) errorf(format string, args ...any) {
-	s.Errors = append(s.Errors, NewScannerError(fmt.Sprintf(format, args...), s.lexLine, s.lexCol))
+// errorf records an error spanning the current lexeme. Scanning always
+// continues afterwards so a single pass reports every lexical problem.
+func (s *Scanner) errorf(code diagnostics.Code, format string, args ...any) {
+	s.errorAt(s.currentSpan(), code, "", format, args...)
+}
+
+// errorHintf records an error spanning the current lexeme with a suggestion.
+func (s *Scanner) errorHintf(code diagnostics.Code, hint string, format string, args ...any) {
+	s.errorAt(s.currentSpan(), code, hint, format, args...)
+}
+
+// errorAt records an error covering an explicit source range. Markup that
+// was lexed as Python gets a note saying so, since that usually means no
+// view was detected.
+func (s *Scanner) errorAt(span Span, code diagnostics.Code, hint string, format string, args ...any) {
+	err := NewScannerErrorSpan(code, fmt.Sprintf(format, args...), span)
+	err.Hint = hint
+	s.trackMode(Position{Line: s.lexLine, Column: s.lexCol})
+	err.Mode = s.ctx.mode
+	err.ModeSince = s.modeSince
+	if err.Mode == PythonMode && looksLikeMarkup(s.src, span.Start.Line-s.cfg.StartLine+1) {
+		err.Notes = append(err.Notes, "this line was scanned as Python; HTML markup is only recognized inside a view body")
+	}
+	s.Errors = append(s.Errors, err)
+}
+
+// trackMode records the transition point when the lexing mode changed since
+// the last call: the token that switched modes (such as '{' or '>') if there
+// is one right before pos, otherwise pos itself.
+func (s *Scanner) trackMode(pos Position) {
+	if s.ctx.mode == s.lastMode {
+		return
+	}
+	s.lastMode = s.ctx.mode
+	s.modeSince = pos
+
+	if len(s.tokens) > 0 {
+		last := s.tokens[len(s.tokens)-1]
+		switch last.Type {
+		case TagOpen, TagCloseStart, TagClose, TagSelfClose,
+			HTMLInterpolationStart, HTMLInterpolationEnd, FStringStart, FStringEnd:
+			if last.Span.End == pos {
+				s.modeSince = last.Span.Start
+			}
+		}
+	}
+}
+
+// currentSpan returns the span from the start of the current lexeme to the cursor.
+func (s *Scanner) currentSpan() Span {
+	return Span{
+		Start: Position{Line: s.lexLine, Column: s.lexCol},
+		End:   Position{Line: s.line, Column: s.col},
+	}
 }
 
 // ── context-aware lexing helpers ────────────────────────────────────
 
 // handleLineStart determines the lexing mode based on the first non-whitespace character
 func (s *Scanner) handleLineStart() {
-	if s.ctx.viewDepth == 0 {
+	if !s.inView() {
 		// Outside view functions, always Python mode
 		s.ctx.mode = PythonMode
 		s.ctx.atLineStart = false
@@ -369,10 +670,54 @@ func (s *Scanner) isKeywordAtPosition() bool {
 	return isKeyword
 }
 
-// detectViewFunction checks if we're entering a view function
+// detectViewFunction enters a view when the 'view' keyword just scanned starts
+// a view definition ("view Name") or decorates a function ("@view"). The view
+// lasts until a later logical line is indented no deeper than the header, so
+// markup scanning stops at the end of the view body, for nested views as well
+// as decorated ones.
 func (s *Scanner) detectViewFunction() {
-	// Increment view depth for nested views
-	s.ctx.viewDepth++
+	indent := s.indentStack[len(s.indentStack)-1]
+	if n := len(s.tokens); n > 0 && s.tokens[n-1].Type == At {
+		s.ctx.views = append(s.ctx.views, viewScope{indent: indent, decorated: true})
+		return
+	}
+
+	i := s.cur
+	for i < len(s.src) && (s.src[i] == ' ' || s.src[i] == '\t') {
+		i++
+	}
+	if i == s.cur || i >= len(s.src) {
+		return
+	}
+	if r, _ := utf8.DecodeRune(s.src[i:]); !isIdentifierStart(r) {
+		return
+	}
+
+	s.ctx.views = append(s.ctx.views, viewScope{indent: indent})
+}
+
+// inView reports whether the scanner is inside the body of a view
+func (s *Scanner) inView() bool {
+	return len(s.ctx.views) > 0
+}
+
+// leaveViews pops the views whose body ended before a logical line with the
+// given indentation. The cursor is at the first character of that line.
+func (s *Scanner) leaveViews(indent int) {
+	for len(s.ctx.views) > 0 {
+		view := &s.ctx.views[len(s.ctx.views)-1]
+		if indent > view.indent {
+			return
+		}
+		if view.decorated && indent == view.indent {
+			// Further decorators or the decorated header itself
+			if s.peek() != '@' {
+				view.decorated = false
+			}
+			return
+		}
+		s.ctx.views = s.ctx.views[:len(s.ctx.views)-1]
+	}
 }
 
 // ── main dispatcher ─────────────────────────────────────────────────
@@ -382,6 +727,7 @@ func (s *Scanner) scanToken() {
 	if s.ctx.atLineStart {
 		s.handleLineStart()
 	}
+	s.trackMode(Position{Line: s.line, Column: s.col})
 
 	// Check if we're inside an f-string and should continue f-string scanning
 	if len(s.fstringStack) > 0 {
@@ -399,6 +745,8 @@ func (s *Scanner) scanToken() {
 		s.scanHTMLContent()
 	case HTMLInterpolationMode:
 		s.scanPythonToken() // Python expressions inside {}
+	case HTMLAttributeValueMode:
+		s.scanHTMLAttributeValue()
 	}
 }
 
@@ -424,14 +772,27 @@ func (s *Scanner) scanPythonToken() {
 		s.addToken(RightBracket)
 	case '{':
 		s.parenDepth++
+		if s.ctx.mode == HTMLInterpolationMode && len(s.ctx.interpBraces) > 0 {
+			s.ctx.interpBraces[len(s.ctx.interpBraces)-1]++
+		}
 		s.addToken(LeftBrace)
 	case '}':
 		if s.parenDepth > 0 {
 			s.parenDepth--
 		}
 
+		// A '}' closing a dict/set inside the interpolation is ordinary Python
+		if s.ctx.mode == HTMLInterpolationMode && len(s.ctx.interpBraces) > 0 && s.ctx.interpBraces[len(s.ctx.interpBraces)-1] > 0 {
+			s.ctx.interpBraces[len(s.ctx.interpBraces)-1]--
+			s.addToken(RightBrace)
+			return
+		}
+
 		// Check if we're closing HTML interpolation
 		if s.ctx.mode == HTMLInterpolationMode {
+			if len(s.ctx.interpBraces) > 0 {
+				s.ctx.interpBraces = s.ctx.interpBraces[:len(s.ctx.interpBraces)-1]
+			}
 			s.addToken(HTMLInterpolationEnd)
 			// Restore previous mode from stack
 			if len(s.ctx.modeStack) > 0 {
@@ -531,10 +892,10 @@ func (s *Scanner) scanPythonToken() {
 		}
 	case '<':
 		// If we're in a view and could be starting an HTML tag
-		if s.ctx.viewDepth > 0 && s.ctx.mode == PythonMode {
-			// Check if this looks like an HTML tag
+		if s.inView() && s.ctx.mode == PythonMode {
+			// Check if this looks like an HTML tag, or the <> opening a fragment
 			nextChar := s.peek()
-			if isIdentifierStart(nextChar) || nextChar == '/' {
+			if isIdentifierStart(nextChar) || nextChar == '/' || nextChar == '>' {
 				s.ctx.mode = HTMLTagMode
 				s.addToken(TagOpen)
 				return
@@ -574,7 +935,7 @@ func (s *Scanner) scanPythonToken() {
 		if s.match('=') {
 			s.addToken(BangEqual)
 		} else {
-			s.errorf("unexpected '!' – only '!=' is valid in Python")
+			s.errorHintf(diagnostics.CodeInvalidCharacter, "use 'not' for logical negation", "unexpected '!' – only '!=' is valid in Python")
 		}
 	case '@':
 		if s.match('=') {
@@ -589,91 +950,30 @@ func (s *Scanner) scanPythonToken() {
 	case '\n':
 		s.handleNewline()        // emits NEWLINE + {INDENT,DEDENT}*
 		s.ctx.atLineStart = true // Mark that we're at line start after newline
+	case '\\':
+		s.lineContinuation()
 	case '#':
 		// consume comment until physical line break
 		for !s.atEnd() && s.peek() != '\n' {
 			s.advance()
 		}
+		s.addComment()
 		// newline will be consumed on next loop
 
 	// ── literals / identifiers ──
 	case '"', '\'':
-		// Check if this might be part of a nested f-string
-		// We need to look back to see if there was an 'f' or 'r' prefix
-		if s.start > 0 {
-			// Check for f-string prefix
-			prevStart := s.start - 1
-			if prevStart > 0 && (s.src[prevStart] == 'f' || s.src[prevStart] == 'F') {
-				// This is a nested f-string - handle it specially
-				s.cur = s.start     // Reset to before the quote
-				s.start = prevStart // Include the 'f' prefix
-				s.fstring(r)
-				return
-			}
-			// Check for raw f-string prefix (rf or fr)
-			if prevStart > 0 && (s.src[prevStart] == 'r' || s.src[prevStart] == 'R') {
-				prevPrevStart := prevStart - 1
-				if prevPrevStart >= 0 && (s.src[prevPrevStart] == 'f' || s.src[prevPrevStart] == 'F') {
-					// This is a raw f-string (fr")
-					s.cur = s.start         // Reset to before the quote
-					s.start = prevPrevStart // Include the 'fr' prefix
-					s.fstring(r)
-					return
-				}
-			}
-			if prevStart > 0 && (s.src[prevStart] == 'f' || s.src[prevStart] == 'F') {
-				prevPrevStart := prevStart - 1
-				if prevPrevStart >= 0 && (s.src[prevPrevStart] == 'r' || s.src[prevPrevStart] == 'R') {
-					// This is a raw f-string (rf")
-					s.cur = s.start         // Reset to before the quote
-					s.start = prevPrevStart // Include the 'rf' prefix
-					s.fstring(r)
-					return
-				}
-			}
-		}
-		s.string(r, false) // Regular string (not raw)
+		s.string(r, stringPrefix{})
 	default:
 		switch {
-		case r == 'f' || r == 'F':
-			// Check if this is a nested f-string (f" or f')
-			if s.peek() == '"' || s.peek() == '\'' {
-				quote := s.peek()
-				s.fstring(quote)
-				return
-			}
-			// If not an f-string, treat as identifier
-			s.identifier()
-		case r == 'r' || r == 'R':
-			// Check if this is a raw f-string (rf" or rf')
-			next := s.peek()
-			if next == 'f' || next == 'F' {
-				s.advance() // consume 'f' or 'F'
-				if s.peek() == '"' || s.peek() == '\'' {
-					quote := s.peek()
-					s.fstring(quote)
-					return
-				}
-				// If not an f-string, backtrack and treat as identifier
-				s.cur-- // backtrack the 'f'
-				s.identifier()
-				return
-			}
-			// Check if this is a raw string (r" or r')
-			if next == '"' || next == '\'' {
-				// Keep the 'r' in the lexeme (consistent with f-string handling)
-				s.advance()          // consume quote
-				s.string(next, true) // Raw string
+		case isIdentifierStart(r):
+			if s.prefixedString() {
 				return
 			}
-			// If not a raw string, treat as identifier
-			s.identifier()
-		case isIdentifierStart(r):
 			s.identifier()
 		case unicode.IsDigit(r):
 			s.number()
 		default:
-			s.errorf("unexpected character %q", r)
+			s.errorf(diagnostics.CodeInvalidCharacter, "unexpected character %q", r)
 		}
 	}
 }
@@ -686,7 +986,11 @@ func (s *Scanner) handleNewline() {
 		// inside (), [], {} ⇒ newline is whitespace
 		return
 	}
-	s.addToken(Newline)
+	// Like CPython, only a logical line with tokens ends in NEWLINE: blank
+	// and comment-only lines emit nothing
+	if !s.lineIsBlank() {
+		s.addToken(Newline)
+	}
 
 	// Measure indentation on following line:
 	indent := 0
@@ -713,6 +1017,7 @@ doneIndent:
 		// blank line – do *not* change indent level
 		return
 	}
+	s.leaveViews(indent)
 
 	// Compare to stack top
 	top := s.indentStack[len(s.indentStack)-1]
@@ -729,11 +1034,57 @@ doneIndent:
 			s.addToken(Dedent)
 		}
 		if indent != top {
-			s.errorf("inconsistent indentation")
+			s.errorf(diagnostics.CodeIndentation, "inconsistent indentation")
 		}
 	}
 }
 
+// lineIsBlank reports whether the logical line being scanned has no tokens
+// yet
+func (s *Scanner) lineIsBlank() bool {
+	if len(s.tokens) == 0 {
+		return true
+	}
+	switch s.tokens[len(s.tokens)-1].Type {
+	case Newline, Indent, Dedent:
+		return true
+	}
+	return false
+}
+
+// lineContinuation handles a backslash outside a string. Ending a physical
+// line, it joins the line to the next one: the newline is not a token and
+// the indentation of the next line is not measured. Anything else after
+// the backslash is an error.
+func (s *Scanner) lineContinuation() {
+	s.match('\r')
+	if s.match('\n') {
+		return
+	}
+	if s.atEnd() {
+		s.errorf(diagnostics.CodeInvalidCharacter, "unexpected end of file after line continuation character")
+		return
+	}
+
+	// Whitespace after the backslash is invisible in most editors; report
+	// it and join the lines anyway, so the rest of the statement scans as
+	// intended
+	i := s.cur
+	for i < len(s.src) && (s.src[i] == ' ' || s.src[i] == '\t' || s.src[i] == '\r') {
+		i++
+	}
+	if i > s.cur && (i == len(s.src) || s.src[i] == '\n') {
+		for s.cur < i {
+			s.advance()
+		}
+		s.errorHintf(diagnostics.CodeInvalidCharacter, "remove the whitespace after the backslash",
+			"unexpected whitespace after line continuation character")
+		s.match('\n')
+		return
+	}
+	s.errorf(diagnostics.CodeInvalidCharacter, "unexpected character after line continuation character")
+}
+
 // ── identifier / keyword ────────────────────────────────────────────
 
 func (s *Scanner) identifier() {
@@ -751,9 +1102,46 @@ func (s *Scanner) identifier() {
 		s.addToken(tok)
 		return
 	}
+	if lexeme == "layout" && s.layoutHeader() {
+		s.ctx.views = append(s.ctx.views, viewScope{indent: s.indentStack[len(s.indentStack)-1]})
+	}
 	s.addToken(Identifier)
 }
 
+// layoutHeader reports whether the 'layout' identifier just scanned starts a
+// layout declaration, 'layout NAME(' or 'layout NAME[', at the start of a
+// statement. 'layout' is a soft keyword, so other uses stay plain names.
+func (s *Scanner) layoutHeader() bool {
+	if n := len(s.tokens); n > 0 {
+		switch s.tokens[n-1].Type {
+		case Newline, Indent, Dedent, Async:
+		default:
+			return false
+		}
+	}
+
+	i := s.cur
+	for i < len(s.src) && (s.src[i] == ' ' || s.src[i] == '\t') {
+		i++
+	}
+	if i == s.cur || i >= len(s.src) {
+		return false
+	}
+	r, size := utf8.DecodeRune(s.src[i:])
+	if !isIdentifierStart(r) {
+		return false
+	}
+	for i += size; i < len(s.src); i += size {
+		if r, size = utf8.DecodeRune(s.src[i:]); !isIdentifierContinue(r) {
+			break
+		}
+	}
+	for i < len(s.src) && (s.src[i] == ' ' || s.src[i] == '\t') {
+		i++
+	}
+	return i < len(s.src) && (s.src[i] == '(' || s.src[i] == '[')
+}
+
 func isIdentifierStart(r rune) bool {
 	return r == '_' || unicode.IsLetter(r)
 }
@@ -817,14 +1205,14 @@ func (s *Scanner) number() {
 	if isFloat {
 		val, err := strconv.ParseFloat(lit, 64)
 		if err != nil {
-			s.errorf("invalid float literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid float literal: %v", err)
 			return
 		}
 		s.addTokenLit(Number, val)
 	} else {
 		val, err := strconv.ParseInt(lit, 10, 64)
 		if err != nil {
-			s.errorf("invalid int literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid int literal: %v", err)
 			return
 		}
 		s.addTokenLit(Number, val)
@@ -839,7 +1227,7 @@ func (s *Scanner) complexNumber(isFloat bool) {
 	if isFloat {
 		imagPart, err := strconv.ParseFloat(numStr, 64)
 		if err != nil {
-			s.errorf("invalid complex literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid complex literal: %v", err)
 			return
 		}
 		// Create complex number with 0 real part and parsed imaginary part
@@ -848,7 +1236,7 @@ func (s *Scanner) complexNumber(isFloat bool) {
 	} else {
 		imagPart, err := strconv.ParseInt(numStr, 10, 64)
 		if err != nil {
-			s.errorf("invalid complex literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid complex literal: %v", err)
 			return
 		}
 		// Create complex number with 0 real part and parsed imaginary part
@@ -872,7 +1260,7 @@ func (s *Scanner) binaryNumber() {
 	}
 
 	if s.cur == start {
-		s.errorf("invalid binary literal: no digits after 0b/0B")
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid binary literal: no digits after 0b/0B")
 		return
 	}
 
@@ -883,7 +1271,7 @@ func (s *Scanner) binaryNumber() {
 		binaryStr := string(s.src[s.start+2 : s.cur-1])
 		val, err := strconv.ParseInt(binaryStr, 2, 64)
 		if err != nil {
-			s.errorf("invalid binary complex literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid binary complex literal: %v", err)
 			return
 		}
 		complexVal := complex(0, float64(val))
@@ -895,7 +1283,7 @@ func (s *Scanner) binaryNumber() {
 	binaryStr := string(s.src[s.start+2 : s.cur])
 	val, err := strconv.ParseInt(binaryStr, 2, 64)
 	if err != nil {
-		s.errorf("invalid binary literal: %v", err)
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid binary literal: %v", err)
 		return
 	}
 	s.addTokenLit(Number, val)
@@ -916,7 +1304,7 @@ func (s *Scanner) octalNumber() {
 	}
 
 	if s.cur == start {
-		s.errorf("invalid octal literal: no digits after 0o/0O")
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid octal literal: no digits after 0o/0O")
 		return
 	}
 
@@ -927,7 +1315,7 @@ func (s *Scanner) octalNumber() {
 		octalStr := string(s.src[s.start+2 : s.cur-1])
 		val, err := strconv.ParseInt(octalStr, 8, 64)
 		if err != nil {
-			s.errorf("invalid octal complex literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid octal complex literal: %v", err)
 			return
 		}
 		complexVal := complex(0, float64(val))
@@ -939,7 +1327,7 @@ func (s *Scanner) octalNumber() {
 	octalStr := string(s.src[s.start+2 : s.cur])
 	val, err := strconv.ParseInt(octalStr, 8, 64)
 	if err != nil {
-		s.errorf("invalid octal literal: %v", err)
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid octal literal: %v", err)
 		return
 	}
 	s.addTokenLit(Number, val)
@@ -960,7 +1348,7 @@ func (s *Scanner) hexNumber() {
 	}
 
 	if s.cur == start {
-		s.errorf("invalid hexadecimal literal: no digits after 0x/0X")
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid hexadecimal literal: no digits after 0x/0X")
 		return
 	}
 
@@ -971,7 +1359,7 @@ func (s *Scanner) hexNumber() {
 		hexStr := string(s.src[s.start+2 : s.cur-1])
 		val, err := strconv.ParseInt(hexStr, 16, 64)
 		if err != nil {
-			s.errorf("invalid hexadecimal complex literal: %v", err)
+			s.errorf(diagnostics.CodeInvalidNumber, "invalid hexadecimal complex literal: %v", err)
 			return
 		}
 		complexVal := complex(0, float64(val))
@@ -983,88 +1371,272 @@ func (s *Scanner) hexNumber() {
 	hexStr := string(s.src[s.start+2 : s.cur])
 	val, err := strconv.ParseInt(hexStr, 16, 64)
 	if err != nil {
-		s.errorf("invalid hexadecimal literal: %v", err)
+		s.errorf(diagnostics.CodeInvalidNumber, "invalid hexadecimal literal: %v", err)
 		return
 	}
 	s.addTokenLit(Number, val)
 }
 
-// ── string literal (single / double; no prefixes yet) ───────────────
+// ── string literals ─────────────────────────────────────────────────
+
+// stringPrefix is what the prefix letters of a string literal ask for
+type stringPrefix struct {
+	raw    bool // r: backslashes are kept as written
+	bytes  bool // b: the literal is bytes, not str
+	format bool // f: the literal is an f-string
+}
+
+// parseStringPrefix returns the prefix spelled by letters, which Python
+// accepts in either case: r, u, b, f, and br or fr in either order
+func parseStringPrefix(letters []byte) (stringPrefix, bool) {
+	var p stringPrefix
+	for _, c := range letters {
+		switch c {
+		case 'r', 'R':
+			if p.raw {
+				return p, false
+			}
+			p.raw = true
+		case 'b', 'B':
+			if p.bytes || p.format {
+				return p, false
+			}
+			p.bytes = true
+		case 'f', 'F':
+			if p.bytes || p.format {
+				return p, false
+			}
+			p.format = true
+		case 'u', 'U':
+			if len(letters) != 1 {
+				return p, false
+			}
+		default:
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// prefixedString scans a string literal when the letter just consumed
+// starts its prefix, as in b"..." or Rb'...', and reports whether it did
+func (s *Scanner) prefixedString() bool {
+	end := s.cur
+	if end < len(s.src) && s.src[end] != '"' && s.src[end] != '\'' {
+		end++ // A second prefix letter
+	}
+	if end >= len(s.src) || (s.src[end] != '"' && s.src[end] != '\'') {
+		return false
+	}
+	prefix, ok := parseStringPrefix(s.src[s.start:end])
+	if !ok {
+		return false
+	}
+	for s.cur < end {
+		s.advance()
+	}
+	quote := s.advance()
+	if prefix.format {
+		s.fstring(quote, prefix.raw)
+	} else {
+		s.string(quote, prefix)
+	}
+	return true
+}
 
-func (s *Scanner) string(quote rune, isRaw bool) {
+// string scans a string or bytes literal whose opening quote was consumed.
+// The token's literal is the value: a string, or a []byte for bytes, with
+// escape sequences decoded unless the literal is raw.
+func (s *Scanner) string(quote rune, prefix stringPrefix) {
 	isTriple := s.peek() == quote && s.peekN(1) == quote
 	if isTriple {
 		// consume the two additional quotes
 		s.advance()
 		s.advance()
-		for {
-			if s.atEnd() {
-				s.errorf("unterminated triple-quoted string")
-				return
-			}
-			if s.peek() == quote && s.peekN(1) == quote && s.peekN(2) == quote {
-				// closing """
-				s.advance()
-				s.advance()
-				s.advance()
-				break
+	}
+
+	var value []byte
+	nonASCII := false
+	for {
+		if s.atEnd() {
+			if isTriple {
+				s.errorf(diagnostics.CodeUnterminatedString, "unterminated triple-quoted string")
+			} else {
+				s.errorf(diagnostics.CodeUnterminatedString, "unterminated string")
 			}
-			s.advance()
+			return
 		}
-		// For triple-quoted strings, skip 3 characters at start and end
-		body := s.src[s.start+3 : s.cur-3]
-		s.addTokenLit(String, string(body))
-	} else {
-		for {
-			if s.atEnd() {
-				s.errorf("unterminated string")
-				return
-			}
-			r := s.peek()
-			if r == '\n' {
-				s.errorf("string literal cannot span newline")
-				return
-			}
-			if r == '\\' && !isRaw { // escape (not processed in raw strings)
+		r := s.peek()
+		if r == quote && (!isTriple || (s.peekN(1) == quote && s.peekN(2) == quote)) {
+			s.advance()
+			if isTriple {
 				s.advance()
 				s.advance()
-				continue
 			}
-			if r == quote {
+			break
+		}
+		if r == '\n' && !isTriple {
+			s.errorf(diagnostics.CodeUnterminatedString, "string literal cannot span newline")
+			return
+		}
+		if r == '\\' {
+			if prefix.raw {
+				// A backslash keeps the character after it, even a quote
+				value = append(value, '\\')
 				s.advance()
-				break
+				if !s.atEnd() {
+					value = utf8.AppendRune(value, s.advance())
+				}
+			} else {
+				value = s.escapeSequence(value, prefix.bytes)
 			}
-			s.advance()
+			continue
 		}
-		// For regular strings, skip 1 character at start and end (the quotes)
-		// For raw strings, skip 2 characters at start (r and quote) and 1 at end (quote)
-		startOffset := 1
-		if isRaw {
-			startOffset = 2 // Skip 'r' and opening quote
+		if prefix.bytes && r >= utf8.RuneSelf && !nonASCII {
+			nonASCII = true
+			span := Span{Start: Position{Line: s.line, Column: s.col}, End: Position{Line: s.line, Column: s.col + 1}}
+			s.errorAt(span, diagnostics.CodeInvalidCharacter, "", "bytes can only contain ASCII literal characters")
 		}
-		body := s.src[s.start+startOffset : s.cur-1]
-		s.addTokenLit(String, string(body))
+		value = utf8.AppendRune(value, s.advance())
+	}
+
+	if prefix.bytes {
+		s.addTokenLit(String, value)
+	} else {
+		s.addTokenLit(String, string(value))
 	}
 }
 
-// ── f-string literal ──────────────────────────────────────────────
+// escapeSequence consumes a backslash escape inside a non-raw string and
+// appends what it stands for to value: a character for str, a byte for
+// bytes, where \u, \U and \N are not escapes. Forms Python rejects at
+// compile time (truncated \x, \u, \U and malformed \N{...}) are reported
+// and skipped so the string token is still produced. Unknown escapes such
+// as \d are kept as written. \N{...} is checked but kept as written too:
+// resolving character names takes the Unicode name table, and the
+// generated Python decodes it.
+func (s *Scanner) escapeSequence(value []byte, bytes bool) []byte {
+	startLine, startCol := s.line, s.col
+	from := s.cur
+	s.advance() // consume '\\'
+	if s.atEnd() {
+		return value
+	}
 
-func (s *Scanner) fstring(quote rune) {
-	// Check if this is a triple-quoted f-string
-	isTriple := s.peek() == quote && s.peekN(1) == quote
+	kind := s.advance()
+	digits := 0
+	switch kind {
+	case '\n':
+		return value // Line continuation
+	case '\r':
+		s.match('\n')
+		return value
+	case '\\', '\'', '"':
+		return append(value, byte(kind))
+	case 'a':
+		return append(value, '\a')
+	case 'b':
+		return append(value, '\b')
+	case 'f':
+		return append(value, '\f')
+	case 'n':
+		return append(value, '\n')
+	case 'r':
+		return append(value, '\r')
+	case 't':
+		return append(value, '\t')
+	case 'v':
+		return append(value, '\v')
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		code := kind - '0'
+		for i := 0; i < 2 && s.peek() >= '0' && s.peek() <= '7'; i++ {
+			code = code*8 + s.advance() - '0'
+		}
+		if bytes {
+			return append(value, byte(code))
+		}
+		return utf8.AppendRune(value, code)
+	case 'x':
+		digits = 2
+	case 'u':
+		digits = 4
+	case 'U':
+		digits = 8
+	case 'N':
+		if bytes {
+			return append(value, s.src[from:s.cur]...)
+		}
+		if s.peek() != '{' {
+			s.escapeError(startLine, startCol, "malformed \\N character escape")
+			return value
+		}
+		s.advance()
+		nameLen := 0
+		for !s.atEnd() && s.peek() != '}' && s.peek() != '\n' && s.peek() != '"' && s.peek() != '\'' {
+			s.advance()
+			nameLen++
+		}
+		if s.peek() != '}' || nameLen == 0 {
+			s.escapeError(startLine, startCol, "malformed \\N character escape")
+			return value
+		}
+		s.advance()
+		return append(value, s.src[from:s.cur]...)
+	default:
+		return append(value, s.src[from:s.cur]...)
+	}
+	if bytes && kind != 'x' {
+		return append(value, s.src[from:s.cur]...)
+	}
 
-	// Determine if this is a raw f-string by checking if we consumed 'r' before 'f'
-	isRaw := false
-	if s.start > 0 {
-		// Check if the character before 'f' was 'r' or 'R'
-		prevChar, _ := utf8.DecodeLastRune(s.src[:s.start])
-		if prevChar == 'r' || prevChar == 'R' {
-			isRaw = true
+	var code rune
+	for i := 0; i < digits; i++ {
+		if !isHexDigit(s.peek()) {
+			s.escapeError(startLine, startCol, "truncated \\%c%s escape", kind, strings.Repeat("X", digits))
+			return value
 		}
+		code = code*16 + hexValue(s.advance())
+	}
+	switch {
+	case bytes:
+		return append(value, byte(code))
+	case code > unicode.MaxRune:
+		s.escapeError(startLine, startCol, "illegal Unicode character in \\%c escape", kind)
+		return value
+	}
+	return utf8.AppendRune(value, code)
+}
+
+// escapeError reports an invalid escape sequence starting at the given position.
+func (s *Scanner) escapeError(line, col int, format string, args ...any) {
+	span := Span{
+		Start: Position{Line: line, Column: col},
+		End:   Position{Line: s.line, Column: s.col},
+	}
+	s.errorAt(span, diagnostics.CodeInvalidEscape, "use a raw string (r\"...\") or escape the backslash as \\\\", format, args...)
+}
+
+func isHexDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+// hexValue returns the value of a hex digit
+func hexValue(r rune) rune {
+	switch {
+	case r >= 'a':
+		return r - 'a' + 10
+	case r >= 'A':
+		return r - 'A' + 10
 	}
+	return r - '0'
+}
+
+// ── f-string literal ──────────────────────────────────────────────
 
-	// Consume the opening quote(s)
-	s.advance() // consume the first quote
+// fstring scans an f-string whose opening quote was consumed
+func (s *Scanner) fstring(quote rune, isRaw bool) {
+	// The opening quote was consumed; check for a triple-quoted f-string
+	isTriple := s.peek() == quote && s.peekN(1) == quote
 	if isTriple {
 		s.advance() // consume second quote
 		s.advance() // consume third quote
@@ -1092,6 +1664,7 @@ func (s *Scanner) fstring(quote rune) {
 
 	// Start tracking content after the opening quote(s)
 	s.start = s.cur
+	s.lexLine, s.lexCol = s.line, s.col
 
 	// Continue scanning in f-string mode
 	s.scanFStringContent()
@@ -1102,11 +1675,16 @@ func (s *Scanner) scanFStringContent() {
 	for !s.atEnd() && len(s.fstringStack) > 0 {
 		ctx := &s.fstringStack[len(s.fstringStack)-1]
 
+		before := s.cur
 		if ctx.inExpression {
 			s.scanFStringExpression()
 		} else {
 			s.scanFStringText()
 		}
+		// Leave a step that consumed nothing to the stall check of scanNext
+		if s.cur == before {
+			return
+		}
 	}
 }
 
@@ -1114,132 +1692,125 @@ func (s *Scanner) scanFStringContent() {
 func (s *Scanner) scanFStringText() {
 	// Check if we have a valid f-string context
 	if len(s.fstringStack) == 0 {
-		s.errorf("internal error: scanFStringText called without f-string context")
+		s.errorf(diagnostics.CodeInvalidCharacter, "internal error: scanFStringText called without f-string context")
 		return
 	}
 
 	ctx := &s.fstringStack[len(s.fstringStack)-1]
 
+	// The text as it reads, with escapes decoded and doubled braces single
+	var text []byte
+	emitText := func() {
+		if s.cur > s.start {
+			s.addTokenLit(FStringMiddle, string(text))
+		}
+	}
+
 	for !s.atEnd() {
 		r := s.peek()
 
 		// Check for end of f-string
-		if r == ctx.quote {
-			if ctx.isTriple {
-				// For triple-quoted f-strings, need to check for three consecutive quotes
-				if s.peekN(1) == ctx.quote && s.peekN(2) == ctx.quote {
-					// Emit any accumulated text
-					if s.cur > s.start {
-						text := string(s.src[s.start:s.cur])
-						s.addTokenLit(FStringMiddle, text)
-					}
-
-					// Consume closing quotes and emit FSTRING_END
-					s.start = s.cur
-					s.advance() // first quote
-					s.advance() // second quote
-					s.advance() // third quote
-					s.addToken(FStringEnd)
+		if r == ctx.quote && (!ctx.isTriple || (s.peekN(1) == ctx.quote && s.peekN(2) == ctx.quote)) {
+			emitText()
 
-					// Pop f-string context
-					s.fstringStack = s.fstringStack[:len(s.fstringStack)-1]
-
-					// Reset start position
-					s.start = s.cur
-					return
-				}
-			} else {
-				// Single quote - end of regular f-string
-				// Emit any accumulated text
-				if s.cur > s.start {
-					text := string(s.src[s.start:s.cur])
-					s.addTokenLit(FStringMiddle, text)
-				}
-
-				// Consume closing quote and emit FSTRING_END
-				s.start = s.cur
+			// Consume closing quote(s) and emit FSTRING_END
+			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
+			s.advance()
+			if ctx.isTriple {
 				s.advance()
-				s.addToken(FStringEnd)
+				s.advance()
+			}
+			s.addToken(FStringEnd)
 
-				// Pop f-string context
-				s.fstringStack = s.fstringStack[:len(s.fstringStack)-1]
+			// Pop f-string context
+			s.fstringStack = s.fstringStack[:len(s.fstringStack)-1]
 
-				// Reset start position
-				s.start = s.cur
-				return
-			}
+			// Reset start position
+			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
+			return
 		}
 
 		// Check for start of replacement field
 		if r == '{' {
 			// Check for escaped brace {{
 			if s.peekN(1) == '{' {
-				// Include the escaped brace in the text
 				s.advance() // consume first {
 				s.advance() // consume second {
+				text = append(text, '{')
 				continue
 			}
 
 			// Emit any accumulated text before the replacement field
-			if s.cur > s.start {
-				text := string(s.src[s.start:s.cur])
-				s.addTokenLit(FStringMiddle, text)
-			}
+			emitText()
 
 			// Set start position for the replacement field token
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			s.advance() // consume '{'
 			s.addToken(LeftBraceF)
 
 			// Set f-string context to expression mode
 			ctx.inExpression = true
 			ctx.braceDepth = 1
+			ctx.parenDepth = s.parenDepth
 
 			// Reset start position for the expression
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			return
 		}
 
-		// Check for unmatched closing brace (should not happen in valid f-strings)
+		// A single '}' is not allowed; report it and keep it as text, as
+		// a doubled '}}' is, so scanning goes on
 		if r == '}' {
-			s.errorf("f-string: single '}' is not allowed")
-			return
-		}
-
-		// Handle newlines in f-strings
-		if r == '\n' {
-			if !ctx.isTriple {
-				// Newlines not allowed in single-quoted f-strings
-				s.errorf("f-string: unterminated string literal (detected at line %d)", s.line)
-				return
+			if s.peekN(1) != '}' {
+				span := Span{Start: Position{Line: s.line, Column: s.col}, End: Position{Line: s.line, Column: s.col + 1}}
+				s.errorAt(span, diagnostics.CodeInvalidCharacter, "", "f-string: single '}' is not allowed")
+			} else {
+				s.advance()
 			}
 			s.advance()
+			text = append(text, '}')
 			continue
 		}
 
-		// Handle escape sequences
+		// Handle newlines in f-strings
+		if r == '\n' && !ctx.isTriple {
+			// Newlines not allowed in single-quoted f-strings: the f-string
+			// ends here and the newline is scanned as Python
+			s.errorf(diagnostics.CodeUnterminatedString, "f-string: unterminated string literal (detected at line %d)", s.line)
+			emitText()
+			s.fstringStack = s.fstringStack[:len(s.fstringStack)-1]
+			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
+			return
+		}
+
+		// Handle escape sequences. A backslash before a brace is kept, and
+		// the brace still opens or closes a replacement field.
 		if r == '\\' {
-			if ctx.isRaw {
-				// In raw f-strings, backslashes are literal
+			if next := s.peekN(1); ctx.isRaw || next == '{' || next == '}' {
 				s.advance()
+				text = append(text, '\\')
 			} else {
-				// In regular f-strings, handle escape sequences
-				s.advance() // consume backslash
-				if !s.atEnd() {
-					s.advance() // consume escaped character
-				}
+				text = s.escapeSequence(text, false)
 			}
 			continue
 		}
 
-		s.advance()
+		text = utf8.AppendRune(text, s.advance())
 	}
 
 	// If we get here, the f-string was not terminated
+	emitText()
+	s.start = s.cur
+	s.lexLine, s.lexCol = s.line, s.col
 	if ctx.isTriple {
-		s.errorf("unterminated triple-quoted f-string")
+		s.errorf(diagnostics.CodeUnterminatedString, "unterminated triple-quoted f-string")
 	} else {
-		s.errorf("unterminated f-string")
+		s.errorf(diagnostics.CodeUnterminatedString, "unterminated f-string")
 	}
 }
 
@@ -1247,7 +1818,7 @@ func (s *Scanner) scanFStringText() {
 func (s *Scanner) scanFStringExpression() {
 	// Check if we have a valid f-string context
 	if len(s.fstringStack) == 0 {
-		s.errorf("internal error: scanFStringExpression called without f-string context")
+		s.errorf(diagnostics.CodeInvalidCharacter, "internal error: scanFStringExpression called without f-string context")
 		return
 	}
 
@@ -1255,7 +1826,7 @@ func (s *Scanner) scanFStringExpression() {
 
 	// Additional safety check
 	if !ctx.inExpression {
-		s.errorf("internal error: scanFStringExpression called when not in expression mode")
+		s.errorf(diagnostics.CodeInvalidCharacter, "internal error: scanFStringExpression called when not in expression mode")
 		return
 	}
 
@@ -1288,6 +1859,7 @@ func (s *Scanner) scanFStringExpression() {
 				ctx.inExpression = false
 				ctx.inFormatSpec = false
 				s.start = s.cur
+				s.lexLine, s.lexCol = s.line, s.col
 				return
 			} else {
 				s.advance()
@@ -1297,6 +1869,7 @@ func (s *Scanner) scanFStringExpression() {
 					// After closing a nested replacement field in format spec,
 					// we need to continue scanning the format spec
 					s.start = s.cur
+					s.lexLine, s.lexCol = s.line, s.col
 					s.scanFStringFormatSpec()
 					return
 				} else {
@@ -1306,19 +1879,24 @@ func (s *Scanner) scanFStringExpression() {
 			}
 		}
 
+		// Inside brackets, '=', '!' and ':' belong to the expression, as in
+		// f"{(n := 10)}" or f"{d['a':'b']}"
+		nested := ctx.braceDepth > 1 || s.parenDepth > ctx.parenDepth
+
 		// Handle debugging equals (=)
-		if r == '=' && !ctx.inFormatSpec {
+		if r == '=' && !ctx.inFormatSpec && !nested {
 			s.advance()
 			s.addToken(FStringEqual)
 			continue
 		}
 
 		// Handle conversion specifier (!)
-		if r == '!' && !ctx.inFormatSpec {
+		if r == '!' && !ctx.inFormatSpec && !nested {
 			s.advance()
 			s.addToken(FStringConversionStart)
 			// Next should be a name (r, s, a)
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			if isIdentifierStart(s.peek()) {
 				s.identifier()
 			}
@@ -1326,11 +1904,12 @@ func (s *Scanner) scanFStringExpression() {
 		}
 
 		// Handle format specification (:)
-		if r == ':' && !ctx.inFormatSpec {
+		if r == ':' && !ctx.inFormatSpec && !nested {
 			s.advance()
 			s.addToken(Colon)
 			ctx.inFormatSpec = true
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			s.scanFStringFormatSpec()
 			continue
 		}
@@ -1363,6 +1942,12 @@ func (s *Scanner) scanExpressionToken() {
 		s.addToken(RightBracket)
 	case ',':
 		s.addToken(Comma)
+	case ':':
+		if s.match('=') {
+			s.addToken(Walrus)
+		} else {
+			s.addToken(Colon)
+		}
 	case ';':
 		s.addToken(Semicolon)
 	case '~':
@@ -1473,7 +2058,7 @@ func (s *Scanner) scanExpressionToken() {
 		if s.match('=') {
 			s.addToken(BangEqual)
 		} else {
-			s.errorf("unexpected '!' – only '!=' is valid in Python")
+			s.errorHintf(diagnostics.CodeInvalidCharacter, "use 'not' for logical negation", "unexpected '!' – only '!=' is valid in Python")
 		}
 	case '@':
 		if s.match('=') {
@@ -1492,85 +2077,22 @@ func (s *Scanner) scanExpressionToken() {
 		for !s.atEnd() && s.peek() != '\n' {
 			s.advance()
 		}
+		s.addComment()
 
 	// ── literals / identifiers ──
 	case '"', '\'':
-		// Check if this might be part of a nested f-string
-		// We need to look back to see if there was an 'f' or 'r' prefix
-		if s.start > 0 {
-			// Check for f-string prefix
-			prevStart := s.start - 1
-			if prevStart > 0 && (s.src[prevStart] == 'f' || s.src[prevStart] == 'F') {
-				// This is a nested f-string - handle it specially
-				s.cur = s.start     // Reset to before the quote
-				s.start = prevStart // Include the 'f' prefix
-				s.fstring(r)
-				return
-			}
-			// Check for raw f-string prefix (rf or fr)
-			if prevStart > 0 && (s.src[prevStart] == 'r' || s.src[prevStart] == 'R') {
-				prevPrevStart := prevStart - 1
-				if prevPrevStart >= 0 && (s.src[prevPrevStart] == 'f' || s.src[prevPrevStart] == 'F') {
-					// This is a raw f-string (fr")
-					s.cur = s.start         // Reset to before the quote
-					s.start = prevPrevStart // Include the 'fr' prefix
-					s.fstring(r)
-					return
-				}
-			}
-			if prevStart > 0 && (s.src[prevStart] == 'f' || s.src[prevStart] == 'F') {
-				prevPrevStart := prevStart - 1
-				if prevPrevStart >= 0 && (s.src[prevPrevStart] == 'r' || s.src[prevPrevStart] == 'R') {
-					// This is a raw f-string (rf")
-					s.cur = s.start         // Reset to before the quote
-					s.start = prevPrevStart // Include the 'rf' prefix
-					s.fstring(r)
-					return
-				}
-			}
-		}
-		s.string(r, false) // Regular string (not raw)
+		s.string(r, stringPrefix{})
 	default:
 		switch {
-		case r == 'f' || r == 'F':
-			// Check if this is a nested f-string (f" or f')
-			if s.peek() == '"' || s.peek() == '\'' {
-				quote := s.peek()
-				s.fstring(quote)
-				return
-			}
-			// If not an f-string, treat as identifier
-			s.identifier()
-		case r == 'r' || r == 'R':
-			// Check if this is a raw f-string (rf" or rf')
-			next := s.peek()
-			if next == 'f' || next == 'F' {
-				s.advance() // consume 'f' or 'F'
-				if s.peek() == '"' || s.peek() == '\'' {
-					quote := s.peek()
-					s.fstring(quote)
-					return
-				}
-				// If not an f-string, backtrack and treat as identifier
-				s.cur-- // backtrack the 'f'
-				s.identifier()
-				return
-			}
-			// Check if this is a raw string (r" or r')
-			if next == '"' || next == '\'' {
-				// Keep the 'r' in the lexeme (consistent with f-string handling)
-				s.advance()          // consume quote
-				s.string(next, true) // Raw string
+		case isIdentifierStart(r):
+			if s.prefixedString() {
 				return
 			}
-			// If not a raw string, treat as identifier
-			s.identifier()
-		case isIdentifierStart(r):
 			s.identifier()
 		case unicode.IsDigit(r):
 			s.number()
 		default:
-			s.errorf("unexpected character %q", r)
+			s.errorf(diagnostics.CodeInvalidCharacter, "unexpected character %q", r)
 		}
 	}
 }
@@ -1579,7 +2101,7 @@ func (s *Scanner) scanExpressionToken() {
 func (s *Scanner) scanFStringFormatSpec() {
 	// Check if we have a valid f-string context
 	if len(s.fstringStack) == 0 {
-		s.errorf("internal error: scanFStringFormatSpec called without f-string context")
+		s.errorf(diagnostics.CodeInvalidCharacter, "internal error: scanFStringFormatSpec called without f-string context")
 		return
 	}
 
@@ -1609,6 +2131,7 @@ func (s *Scanner) scanFStringFormatSpec() {
 
 			// Start nested replacement field
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			s.advance()
 			s.addToken(LeftBraceF)
 
@@ -1617,6 +2140,7 @@ func (s *Scanner) scanFStringFormatSpec() {
 
 			// Reset start position and continue scanning the expression
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 
 			// The nested replacement field will be handled by scanFStringExpression
 			// When it returns, we need to continue scanning the format spec
@@ -1660,20 +2184,36 @@ func (s *Scanner) scanHTMLTag() {
 	// We're inside a tag, handle tag content
 	for !s.atEnd() {
 		s.start = s.cur
+		s.lexLine, s.lexCol = s.line, s.col
 
 		switch r := s.advance(); r {
 		case ' ', '\t', '\r':
 			// Skip whitespace in tags
 			continue
 		case '\n':
-			// Newlines in tags are treated as whitespace
-			s.line++
-			s.col = s.cfg.StartColumn
+			// Newlines in tags are treated as whitespace (advance already
+			// moved the position to the next line)
 			continue
 		case '>':
 			// End of tag
 			s.addToken(TagClose)
 			s.ctx.mode = HTMLContentMode
+			if name, closing := s.lastTag(); !closing {
+				s.ctx.inlineTags++
+				if slices.Contains(WhitespaceElements, name) {
+					s.ctx.preformatted++
+				}
+				if slices.Contains(s.cfg.RawTextElements, name) || slices.Contains(ScriptElements, name) {
+					s.scanRawText(name)
+				}
+			} else {
+				if s.ctx.inlineTags > 0 {
+					s.ctx.inlineTags--
+				}
+				if slices.Contains(WhitespaceElements, name) && s.ctx.preformatted > 0 {
+					s.ctx.preformatted--
+				}
+			}
 			return
 		case '/':
 			// Check for self-closing tag
@@ -1683,15 +2223,25 @@ func (s *Scanner) scanHTMLTag() {
 				s.ctx.mode = HTMLContentMode
 				return
 			}
-			s.errorf("unexpected '/' in HTML tag")
+			s.errorf(diagnostics.CodeMalformedMarkup, "unexpected '/' in HTML tag")
 			return
 		case '=':
 			s.addToken(Equal)
 		case '"', '\'':
-			s.string(r, false) // Use regular string parsing (not raw)
+			if s.attributeHasInterpolation(r) {
+				// class="btn {variant}" is lexed like an f-string whose
+				// replacement fields are ordinary attribute interpolations
+				s.addToken(FStringStart)
+				s.ctx.attrQuote = r
+				s.ctx.mode = HTMLAttributeValueMode
+				return
+			}
+			// Backslashes mean nothing in markup: the value is kept as written
+			s.string(r, stringPrefix{raw: true})
 		case '{':
 			// Start of interpolation in attribute
 			s.ctx.modeStack = append(s.ctx.modeStack, s.ctx.mode) // Push current mode
+			s.ctx.interpBraces = append(s.ctx.interpBraces, 0)
 			s.addToken(HTMLInterpolationStart)
 			s.ctx.mode = HTMLInterpolationMode
 			return
@@ -1699,13 +2249,110 @@ func (s *Scanner) scanHTMLTag() {
 			if isIdentifierStart(r) {
 				s.scanHTMLIdentifier()
 			} else {
-				s.errorf("unexpected character %q in HTML tag", r)
+				s.errorf(diagnostics.CodeMalformedMarkup, "unexpected character %q in HTML tag", r)
 				return
 			}
 		}
 	}
 }
 
+// attributeHasInterpolation reports whether the quoted attribute value that
+// starts at the cursor contains a {expression} part. Escaped braces ({{ and }})
+// don't count, and quotes inside an expression don't end the value.
+func (s *Scanner) attributeHasInterpolation(quote rune) bool {
+	depth := 0
+	found := false
+	for i := s.cur; i < len(s.src); i++ {
+		c := rune(s.src[i])
+		switch {
+		case c == '\\' && depth == 0:
+			i++
+		case c == '\n':
+			return false
+		case depth == 0 && c == quote:
+			return found
+		case depth == 0 && c == '{' && i+1 < len(s.src) && s.src[i+1] == '{':
+			i++
+		case c == '{':
+			depth++
+			found = true
+		case c == '}' && depth > 0:
+			depth--
+		case depth > 0 && (c == '"' || c == '\''):
+			// Skip a string literal inside the expression
+			for i++; i < len(s.src) && rune(s.src[i]) != c && s.src[i] != '\n'; i++ {
+				if s.src[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return false
+}
+
+// scanHTMLAttributeValue scans the text of a quoted attribute value up to the
+// next interpolation or the closing quote. Text is emitted as FStringMiddle
+// tokens with escaped braces already collapsed, interpolations reuse the
+// HTMLInterpolationStart/End tokens and the closing quote emits FStringEnd.
+func (s *Scanner) scanHTMLAttributeValue() {
+	var text strings.Builder
+	emitText := func() {
+		if s.cur > s.start {
+			s.addTokenLit(FStringMiddle, text.String())
+		}
+		text.Reset()
+	}
+
+	for !s.atEnd() {
+		r := s.peek()
+		switch {
+		case r == s.ctx.attrQuote:
+			emitText()
+			s.lexLine, s.lexCol = s.line, s.col
+			s.start = s.cur
+			s.advance()
+			s.addToken(FStringEnd)
+			s.ctx.mode = HTMLTagMode
+			return
+		case r == '\n':
+			emitText()
+			s.errorf(diagnostics.CodeMalformedMarkup, "unterminated attribute value")
+			s.ctx.mode = HTMLTagMode
+			return
+		case (r == '{' || r == '}') && s.peekN(1) == r:
+			s.advance()
+			s.advance()
+			text.WriteRune(r)
+		case r == '{':
+			emitText()
+			s.lexLine, s.lexCol = s.line, s.col
+			s.start = s.cur
+			s.advance()
+			s.ctx.modeStack = append(s.ctx.modeStack, s.ctx.mode)
+			s.ctx.interpBraces = append(s.ctx.interpBraces, 0)
+			s.addToken(HTMLInterpolationStart)
+			s.ctx.mode = HTMLInterpolationMode
+			return
+		case r == '}':
+			brace := Span{Start: Position{Line: s.line, Column: s.col}}
+			text.WriteRune(s.advance())
+			brace.End = Position{Line: s.line, Column: s.col}
+			s.errorAt(brace, diagnostics.CodeMalformedMarkup, "write '}}' for a literal brace", "single '}' is not allowed in attribute value")
+		case r == '\\':
+			// Escapes are kept as written, like in plain attribute strings
+			text.WriteRune(s.advance())
+			if !s.atEnd() {
+				text.WriteRune(s.advance())
+			}
+		default:
+			text.WriteRune(s.advance())
+		}
+	}
+
+	emitText()
+	s.errorf(diagnostics.CodeMalformedMarkup, "unterminated attribute value")
+}
+
 // scanHTMLContent scans HTML content between tags
 func (s *Scanner) scanHTMLContent() {
 	textStart := s.cur
@@ -1733,13 +2380,21 @@ func (s *Scanner) scanHTMLContent() {
 
 			// Set start position for the interpolation token
 			s.start = s.cur
+			s.lexLine, s.lexCol = s.line, s.col
 			s.advance()                                           // consume '{'
 			s.ctx.modeStack = append(s.ctx.modeStack, s.ctx.mode) // Push current mode
+			s.ctx.interpBraces = append(s.ctx.interpBraces, 0)
 			s.addToken(HTMLInterpolationStart)
 			s.ctx.mode = HTMLInterpolationMode
 			return
 
 		case '\n':
+			// Text of a whitespace element goes on across lines
+			if s.ctx.preformatted > 0 {
+				s.advance()
+				continue
+			}
+
 			// Emit any accumulated text first
 			if s.cur > textStart {
 				s.addHTMLText(textStart, textStartLine, textStartCol)
@@ -1749,6 +2404,7 @@ func (s *Scanner) scanHTMLContent() {
 			// by returning to Python mode temporarily
 			s.ctx.mode = PythonMode
 			s.ctx.atLineStart = true
+			s.ctx.inlineTags = 0
 			return
 
 		default:
@@ -1770,29 +2426,71 @@ func (s *Scanner) isHTMLComment() bool {
 	return s.peek() == '!' && s.peekN(1) == '-' && s.peekN(2) == '-'
 }
 
-// scanHTMLComment scans and skips HTML comments
+// scanHTMLComment scans an HTML comment. It is skipped unless the scanner is
+// configured to preserve comments, in which case an HTMLComment token is
+// emitted with the comment body as its literal.
 func (s *Scanner) scanHTMLComment() {
 	// We've seen '<!' and confirmed next two chars are '--'
 	s.advance() // consume '!'
 	s.advance() // consume first '-'
 	s.advance() // consume second '-'
+	bodyStart := s.cur
 
 	// Consume until we find '-->'
 	for !s.atEnd() {
 		if s.peek() == '-' && s.peekN(1) == '-' && s.peekN(2) == '>' {
+			body := string(s.src[bodyStart:s.cur])
 			s.advance() // consume first '-'
 			s.advance() // consume second '-'
 			s.advance() // consume '>'
+			if s.cfg.PreserveHTMLComments {
+				s.addTokenLit(HTMLComment, body)
+			}
 			return
 		}
-		r := s.advance()
-		if r == '\n' {
-			s.line++
-			s.col = s.cfg.StartColumn
+		s.advance()
+	}
+
+	s.errorf(diagnostics.CodeMalformedMarkup, "unterminated HTML comment")
+}
+
+// lastTag returns the name of the tag whose '>' was just emitted, "" for a
+// {expression} tag name, and whether it is a closing tag
+func (s *Scanner) lastTag() (string, bool) {
+	for i := len(s.tokens) - 2; i >= 0; i-- {
+		switch s.tokens[i].Type {
+		case TagOpen, TagCloseStart:
+			closing := s.tokens[i].Type == TagCloseStart
+			if i+1 < len(s.tokens) && s.tokens[i+1].Type == Identifier {
+				return s.tokens[i+1].Lexeme, closing
+			}
+			return "", closing
+		}
+	}
+	return "", false
+}
+
+// scanRawText consumes the content of a raw text element up to its closing
+// tag, which is then scanned as usual
+func (s *Scanner) scanRawText(name string) {
+	s.start = s.cur
+	s.lexLine, s.lexCol = s.line, s.col
+	closing := []byte("</" + name)
+
+	for !s.atEnd() {
+		if bytes.HasPrefix(s.src[s.cur:], closing) {
+			next := s.cur + len(closing)
+			if next >= len(s.src) || !isIdentifierContinue(rune(s.src[next])) {
+				if s.cur > s.start {
+					s.addTokenLit(HTMLTextInline, string(s.src[s.start:s.cur]))
+				}
+				return
+			}
 		}
+		s.advance()
 	}
 
-	s.errorf("unterminated HTML comment")
+	s.errorHintf(diagnostics.CodeMalformedMarkup, fmt.Sprintf("close the element with </%s>", name), "unterminated <%s> element", name)
 }
 
 // isNextContentOnSameLine checks if content starts on the same line as the tag
@@ -1811,12 +2509,20 @@ func (s *Scanner) isNextContentOnSameLine() bool {
 	return true
 }
 
-// scanHTMLIdentifier scans an identifier in HTML context (tag name or attribute name)
+// scanHTMLIdentifier scans an identifier in HTML context (tag name or attribute name).
+// A colon followed by another name is part of it, for namespaced names such
+// as xlink:href and xmlns:xlink.
 func (s *Scanner) scanHTMLIdentifier() {
 	// We've already consumed the first character in scanHTMLTag
 	// Continue scanning the rest of the identifier
-	for isIdentifierContinue(s.peek()) || s.peek() == '-' {
-		s.advance()
+	for {
+		if isIdentifierContinue(s.peek()) || s.peek() == '-' {
+			s.advance()
+		} else if s.peek() == ':' && isIdentifierStart(s.peekN(1)) {
+			s.advance() // consume ':'
+		} else {
+			break
+		}
 	}
 
 	// This is a tag name or attribute name
@@ -1827,9 +2533,10 @@ func (s *Scanner) scanHTMLIdentifier() {
 func (s *Scanner) addHTMLText(textStart int, startLine int, startCol int) {
 	text := string(s.src[textStart:s.cur])
 	if len(text) > 0 {
-		// Skip tokens that are ENTIRELY whitespace (like newlines/indentation)
+		// Skip tokens that are ENTIRELY whitespace (like trailing spaces) unless
+		// they must be kept (see keepsWhitespace)
 		// But preserve PARTIAL whitespace (like "text: " or " text") as it's semantically significant
-		if len(strings.TrimSpace(text)) == 0 {
+		if len(strings.TrimSpace(text)) == 0 && !s.keepsWhitespace() {
 			return // Skip entirely-whitespace tokens
 		}
 		// Preserve the text exactly as-is - whitespace is meaningful in HTML
@@ -1846,6 +2553,16 @@ func (s *Scanner) addHTMLText(textStart int, startLine int, startCol int) {
 	}
 }
 
+// keepsWhitespace reports whether whitespace-only text ending at the current
+// position is kept. Only inline content keeps it: between the statements of
+// a block element and at the end of a line, whitespace is layout.
+func (s *Scanner) keepsWhitespace() bool {
+	if s.ctx.inlineTags == 0 || s.atEnd() || s.peek() == '\n' || s.peek() == '\r' {
+		return false
+	}
+	return s.cfg.PreserveWhitespace || s.ctx.preformatted > 0
+}
+
 // ── small utility ───────────────────────────────────────────────────
 
 func isDigit(r rune) bool { return unicode.IsDigit(r) }
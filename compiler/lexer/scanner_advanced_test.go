@@ -1,6 +1,10 @@
 package lexer
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -438,3 +442,497 @@ func TestEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// Test that dict braces inside an attribute interpolation don't end it early
+func TestNestedBracesInAttributeInterpolation(t *testing.T) {
+	input := "view V():\n    <div data={{\"a\": {\"b\": 1}}}>x</div>\n"
+	tokens := scanTokens(input)
+
+	var inside []TokenType
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Type {
+		case HTMLInterpolationStart:
+			depth++
+		case HTMLInterpolationEnd:
+			depth--
+		default:
+			if depth > 0 {
+				inside = append(inside, tok.Type)
+			}
+		}
+	}
+
+	if depth != 0 {
+		t.Fatalf("interpolation not closed, depth = %d", depth)
+	}
+	expected := []TokenType{
+		LeftBrace, String, Colon, LeftBrace, String, Colon, Number, RightBrace, RightBrace,
+	}
+	if len(inside) != len(expected) {
+		t.Fatalf("expected %d tokens inside interpolation, got %d: %v", len(expected), len(inside), inside)
+	}
+	for i, typ := range expected {
+		if inside[i] != typ {
+			t.Errorf("token %d: expected %v, got %v", i, typ, inside[i])
+		}
+	}
+}
+
+// Test that HTML comments are discarded by default and emitted when preserved
+func TestHTMLCommentPreservation(t *testing.T) {
+	input := "view V():\n    <p>a <!-- note --> b</p>\n"
+
+	countComments := func(tokens []Token) []Token {
+		var comments []Token
+		for _, tok := range tokens {
+			if tok.Type == HTMLComment {
+				comments = append(comments, tok)
+			}
+		}
+		return comments
+	}
+
+	if comments := countComments(scanTokens(input)); len(comments) != 0 {
+		t.Errorf("expected comments to be discarded by default, got %d", len(comments))
+	}
+
+	cfg := DefaultScannerConfig()
+	cfg.PreserveHTMLComments = true
+	scanner := NewScannerWithConfig([]byte(input), cfg)
+	comments := countComments(scanner.ScanTokens())
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", scanner.Errors)
+	}
+	if len(comments) != 1 {
+		t.Fatalf("expected 1 comment token, got %d", len(comments))
+	}
+	assertToken(t, comments[0], HTMLComment, "<!-- note -->")
+	if comments[0].Literal != " note " {
+		t.Errorf("expected literal %q, got %q", " note ", comments[0].Literal)
+	}
+	if comments[0].Span.Start != (Position{Line: 2, Column: 10}) {
+		t.Errorf("expected comment to start at L2:10, got %s", comments[0].Span.Start)
+	}
+}
+
+// Test that # comments stay out of the token stream but are recorded on the side
+func TestCommentsRecorded(t *testing.T) {
+	input := "# header\nx = 1  # trailing\nview V():\n    # inside\n    <p>#not a comment</p>\n"
+	scanner := NewScanner([]byte(input))
+	for _, tok := range scanner.ScanTokens() {
+		if tok.Type == Comment {
+			t.Fatalf("comment in token stream: %v", tok)
+		}
+	}
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", scanner.Errors)
+	}
+
+	comments := scanner.Comments()
+	expected := []struct {
+		lexeme string
+		start  Position
+	}{
+		{"# header", Position{Line: 1, Column: 1}},
+		{"# trailing", Position{Line: 2, Column: 8}},
+		{"# inside", Position{Line: 4, Column: 5}},
+	}
+	if len(comments) != len(expected) {
+		t.Fatalf("expected %d comments, got %v", len(expected), comments)
+	}
+	for i, want := range expected {
+		assertToken(t, comments[i], Comment, want.lexeme)
+		if comments[i].Span.Start != want.start {
+			t.Errorf("comment %d: expected start %s, got %s", i, want.start, comments[i].Span.Start)
+		}
+	}
+}
+
+// Test that quoted attribute values with {expression} parts are lexed as f-strings
+func TestAttributeValueInterpolation(t *testing.T) {
+	tests := []struct {
+		name     string
+		attr     string
+		expected []TokenType
+		middles  []string
+	}{
+		{
+			name: "text around expression",
+			attr: `class="btn {variant} large"`,
+			expected: []TokenType{
+				FStringStart, FStringMiddle, HTMLInterpolationStart, Identifier,
+				HTMLInterpolationEnd, FStringMiddle, FStringEnd,
+			},
+			middles: []string{"btn ", " large"},
+		},
+		{
+			name: "quotes and braces inside expression",
+			attr: `class="{styles["btn"]} {{raw}}"`,
+			expected: []TokenType{
+				FStringStart, HTMLInterpolationStart, Identifier, LeftBracket, String, RightBracket,
+				HTMLInterpolationEnd, FStringMiddle, FStringEnd,
+			},
+			middles: []string{" {raw}"},
+		},
+		{
+			name:     "escaped braces only",
+			attr:     `class="{{raw}}"`,
+			expected: []TokenType{String},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := scanTokens("view V():\n    <div " + tt.attr + ">x</div>\n")
+
+			// Collect the tokens between '=' and the closing '>' of the tag
+			var got []TokenType
+			var middles []string
+			for i := range tokens {
+				if tokens[i].Type != Equal {
+					continue
+				}
+				for _, tok := range tokens[i+1:] {
+					if tok.Type == TagClose {
+						break
+					}
+					got = append(got, tok.Type)
+					if tok.Type == FStringMiddle {
+						middles = append(middles, tok.Literal.(string))
+					}
+				}
+				break
+			}
+
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i, typ := range tt.expected {
+				if got[i] != typ {
+					t.Errorf("token %d: expected %v, got %v", i, typ, got[i])
+				}
+			}
+			for i, want := range tt.middles {
+				if i >= len(middles) || middles[i] != want {
+					t.Errorf("middle %d: expected %q, got %v", i, want, middles)
+				}
+			}
+		})
+	}
+}
+
+// Test that view mode ends with the view body and doesn't leak into later code
+func TestViewScopeEndsWithBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "top-level view",
+			input: "view A():\n    <p>a</p>\n\nok = x <y\n",
+		},
+		{
+			name:  "one-line view",
+			input: "view A(): <p>a</p>\nok = x <y\n",
+		},
+		{
+			name:  "nested view",
+			input: "def outer():\n    view Inner():\n        <p>a</p>\n    return x <y\n",
+		},
+		{
+			name:  "decorated view",
+			input: "@cache\n@view\ndef A():\n    <p>a</p>\nok = x <y\n",
+		},
+		{
+			name:  "decorated view keyword form",
+			input: "@cache\nview A():\n    <p>a</p>\n\n\nok = x <y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", scanner.Errors)
+			}
+
+			tagOpens, less := 0, 0
+			for _, tok := range tokens {
+				switch tok.Type {
+				case TagOpen:
+					tagOpens++
+				case Less:
+					less++
+				}
+			}
+			if tagOpens != 1 {
+				t.Errorf("expected the view body to be scanned as markup, got %d TagOpen tokens", tagOpens)
+			}
+			if less != 1 {
+				t.Errorf("expected '<' after the view to be a comparison, got %d Less tokens", less)
+			}
+		})
+	}
+}
+
+// Test that raw text elements keep their content as a single text token
+func TestRawTextElements(t *testing.T) {
+	input := "view V():\n    <Markdown>\n        # {Title} <b>\n    </Markdown>\n"
+
+	cfg := DefaultScannerConfig()
+	cfg.RawTextElements = []string{"Markdown"}
+	scanner := NewScannerWithConfig([]byte(input), cfg)
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", scanner.Errors)
+	}
+
+	var texts []Token
+	for _, tok := range tokens {
+		if tok.Type == HTMLTextInline {
+			texts = append(texts, tok)
+		}
+		if tok.Type == HTMLInterpolationStart {
+			t.Errorf("expected no interpolation inside a raw text element")
+		}
+	}
+	if len(texts) != 1 {
+		t.Fatalf("expected 1 text token, got %d", len(texts))
+	}
+	if want := "\n        # {Title} <b>\n    "; texts[0].Literal != want {
+		t.Errorf("expected literal %q, got %q", want, texts[0].Literal)
+	}
+
+	scanner = NewScannerWithConfig([]byte("view V():\n    <Markdown>\n        text\n"), cfg)
+	scanner.ScanTokens()
+	if len(scanner.Errors) == 0 || !strings.Contains(scanner.Errors[0].Error(), "unterminated <Markdown> element") {
+		t.Errorf("expected an unterminated element error, got %v", scanner.Errors)
+	}
+}
+
+func TestFragmentTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []TokenType
+	}{
+		{
+			name:  "line start",
+			input: "view V():\n    <><b>a</b>text</>\n",
+			want: []TokenType{TagOpen, TagClose, TagOpen, Identifier, TagClose, HTMLTextInline,
+				TagCloseStart, Identifier, TagClose, HTMLTextInline, TagCloseStart, TagClose},
+		},
+		{
+			name:  "after a colon",
+			input: "view V():\n    if ok: <>text</>\n",
+			want:  []TokenType{If, Identifier, Colon, TagOpen, TagClose, HTMLTextInline, TagCloseStart, TagClose},
+		},
+		{
+			name:  "multiline",
+			input: "view V():\n    <>\n        <i>a</i>\n    </>\n",
+			want: []TokenType{TagOpen, TagClose, Newline, Indent, TagOpen, Identifier, TagClose, HTMLTextInline,
+				TagCloseStart, Identifier, TagClose, Newline, Dedent, TagCloseStart, TagClose},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", scanner.Errors)
+			}
+			// Skip the view header and the indent of its body
+			assertTokenTypes(t, tokens[7:7+len(tt.want)], tt.want)
+		})
+	}
+}
+
+// Test which whitespace-only text tokens are kept
+func TestWhitespaceText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		preserve bool
+		expected []string
+	}{
+		{"dropped by default", "view V():\n    <p><b>a</b> <i>b</i> </p>\n", false, []string{"a", "b"}},
+		{"between inline elements", "view V():\n    <p><b>a</b> <i>b</i></p>\n", true, []string{"a", " ", "b"}},
+		{"between interpolations", "view V():\n    <p>{x} {y}</p>\n", true, []string{" "}},
+		{"inside pre", "view V():\n    <pre><b>a</b>  </pre>\n", false, []string{"a", "  "}},
+		{"inside textarea", "view V():\n    <textarea>   </textarea>\n", false, []string{"   "}},
+		{"after pre is closed", "view V():\n    <p><pre> </pre> <b>a</b></p>\n", false, []string{" ", "a"}},
+		{"end of line", "view V():\n    <p><b>a</b>   \n    </p>\n", true, []string{"a"}},
+		{"between block statements", "view V():\n    <div>\n        <b>a</b> <i>b</i>\n    </div>\n", true, []string{"a", "b"}},
+		{"pre across lines", "view V():\n    <pre>\n  a {x}\n\tb\n    </pre>\n", false, []string{"\n  a ", "\n\tb\n    "}},
+		{"textarea across lines", "view V():\n    <textarea>a\n</textarea>\n", false, []string{"a\n"}},
+		{"script", "view V():\n    <script>\n        if (a < b) { go(); }\n    </script>\n", false, []string{"\n        if (a < b) { go(); }\n    "}},
+		{"style", "view V():\n    <style>a > b {}</style>\n", false, []string{"a > b {}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultScannerConfig()
+			cfg.PreserveWhitespace = tt.preserve
+			scanner := NewScannerWithConfig([]byte(tt.input), cfg)
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", scanner.Errors)
+			}
+
+			var texts []string
+			for _, tok := range tokens {
+				if tok.Type == HTMLTextInline {
+					texts = append(texts, tok.Literal.(string))
+				}
+			}
+			if !reflect.DeepEqual(texts, tt.expected) {
+				t.Errorf("expected texts %q, got %q", tt.expected, texts)
+			}
+		})
+	}
+}
+
+func TestNamespacedAttributeNames(t *testing.T) {
+	input := "view V():\n    <svg xmlns:xlink=\"http://www.w3.org/1999/xlink\">\n        <use xlink:href={url} />\n    </svg>\n"
+
+	scanner := NewScanner([]byte(input))
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", scanner.Errors)
+	}
+
+	var names []string
+	for i, tok := range tokens {
+		if tok.Type == Identifier && i+1 < len(tokens) && tokens[i+1].Type == Equal {
+			names = append(names, tok.Lexeme)
+		}
+	}
+	if want := []string{"xmlns:xlink", "xlink:href"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected attribute names %v, got %v", want, names)
+	}
+
+	// A colon that does not join two names is still an error
+	scanner = NewScanner([]byte("view V():\n    <use xlink:=\"x\" />\n"))
+	scanner.ScanTokens()
+	if len(scanner.Errors) == 0 || !strings.Contains(scanner.Errors[0].Error(), "unexpected character ':' in HTML tag") {
+		t.Errorf("expected an unexpected character error, got %v", scanner.Errors)
+	}
+}
+
+func TestFStringBracketedColons(t *testing.T) {
+	tests := []struct {
+		input      string
+		formatSpec bool
+	}{
+		{`f"{(n := 3)}"`, false},
+		{`f"{x[1:2]}"`, false},
+		{`f"{(lambda v: v)(1)}"`, false},
+		{`f"{x:>10}"`, true},
+		{`f"{(a, b)!r:>10}"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.input + "\n"))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", scanner.Errors)
+			}
+			// A format spec is a ':' followed by its text
+			formatSpec := false
+			for i, tok := range tokens[:len(tokens)-1] {
+				if tok.Type == Colon && tokens[i+1].Type == FStringMiddle {
+					formatSpec = true
+				}
+			}
+			if formatSpec != tt.formatSpec {
+				t.Errorf("expected format spec %v, got tokens %v", tt.formatSpec, tokens)
+			}
+		})
+	}
+}
+
+// streamTokens reads every token of src through NextToken, up to EOF
+func streamTokens(t *testing.T, scanner *Scanner) []Token {
+	t.Helper()
+	var tokens []Token
+	for {
+		tok := scanner.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == EOF {
+			return tokens
+		}
+		if len(tokens) > 1_000_000 {
+			t.Fatal("NextToken never returned EOF")
+		}
+	}
+}
+
+func TestNextTokenMatchesScanTokens(t *testing.T) {
+	files, err := filepath.Glob("../testdata/input/*/*.psx")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No test inputs found: %v", err)
+	}
+	sources := map[string][]byte{
+		"composites": []byte("a is not b\nc not in d\ne is not not f\ng not not in h\n"),
+		"unfinished": []byte("view V():\n    <div class={x\n"),
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", file, err)
+		}
+		sources[file] = src
+	}
+
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			batch := NewScanner(src)
+			want := batch.ScanTokens()
+			stream := NewScanner(src)
+			got := streamTokens(t, stream)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Streamed tokens differ from ScanTokens:\n got: %v\nwant: %v", got, want)
+			}
+			if !reflect.DeepEqual(stream.Errors, batch.Errors) {
+				t.Errorf("Streamed errors %v differ from %v", stream.Errors, batch.Errors)
+			}
+			if !reflect.DeepEqual(stream.Comments(), batch.Comments()) {
+				t.Error("Streamed comments differ from ScanTokens")
+			}
+			if tok := stream.NextToken(); tok.Type != EOF {
+				t.Errorf("Expected EOF again after the end, got %s", tok.Type)
+			}
+		})
+	}
+}
+
+func TestNextTokenReleasesTokens(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("view Header():\n    <h1>Rows</h1>\n\n")
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&src, "def row_%d(item):\n    return [item.name, item.value, %d]\n\n", i, i)
+	}
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&src, "view Row%d(item):\n    <tr class=\"row\"><td>{item.name}</td><td>{item.value}</td></tr>\n\n", i)
+	}
+	scanner := NewScanner([]byte(src.String()))
+
+	count, buffered := 0, 0
+	for tok := scanner.NextToken(); tok.Type != EOF; tok = scanner.NextToken() {
+		count++
+		buffered = max(buffered, len(scanner.tokens))
+	}
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", scanner.Errors)
+	}
+	if count < 50_000 {
+		t.Fatalf("Expected a large token stream, got %d tokens", count)
+	}
+	if buffered > 2*releaseThreshold {
+		t.Errorf("Expected at most %d buffered tokens, got %d", 2*releaseThreshold, buffered)
+	}
+}
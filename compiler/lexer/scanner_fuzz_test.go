@@ -0,0 +1,64 @@
+package lexer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// FuzzScanner checks that the scanner never panics, always ends the token
+// stream with EOF, reports problems as *ScannerError and streams the same
+// tokens as ScanTokens. Inputs that once failed live in
+// testdata/fuzz/FuzzScanner.
+func FuzzScanner(f *testing.F) {
+	files, _ := filepath.Glob("../testdata/input/*/*.psx")
+	for _, file := range files {
+		if src, err := os.ReadFile(file); err == nil {
+			f.Add(src)
+		}
+	}
+	for _, seed := range []string{
+		"",
+		"\xff\xfe",
+		"x = '\xc3",
+		"f'{",
+		"f\"{x!r:{",
+		"rb'\\",
+		"view V():\n    <div class={",
+		"view V():\n    <a href=\"\xe2\x82\">",
+		"view V():\n    <!--",
+		"view V():\n    <script>",
+		"0x",
+		"1e",
+		"\t \n  \n\t",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		batch := NewScanner(src)
+		tokens := batch.ScanTokens()
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
+			t.Fatalf("Token stream does not end with EOF: %v", tokens)
+		}
+		for _, err := range batch.Errors {
+			if _, ok := err.(*ScannerError); !ok {
+				t.Fatalf("Expected a *ScannerError, got %T: %v", err, err)
+			}
+		}
+
+		stream := NewScanner(src)
+		var streamed []Token
+		for len(streamed) <= len(tokens) {
+			tok := stream.NextToken()
+			streamed = append(streamed, tok)
+			if tok.Type == EOF {
+				break
+			}
+		}
+		if !reflect.DeepEqual(streamed, tokens) {
+			t.Fatalf("NextToken streamed different tokens:\n got: %v\nwant: %v", streamed, tokens)
+		}
+	})
+}
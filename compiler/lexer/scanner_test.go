@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -155,15 +156,23 @@ func TestStrings(t *testing.T) {
 	}{
 		{`"hello"`, "hello"},
 		{`'world'`, "world"},
-		{`"hello\nworld"`, `hello\nworld`},
-		{`"hello\tworld"`, `hello\tworld`},
-		{`"hello\\world"`, `hello\\world`},
-		{`"hello\"world"`, `hello\"world`},
+		{`"hello\nworld"`, "hello\nworld"},
+		{`"hello\tworld"`, "hello\tworld"},
+		{`"hello\\world"`, `hello\world`},
+		{`"hello\"world"`, `hello"world`},
 		{`'''triple
 single'''`, "triple\nsingle"},
 		{`"""triple
 double"""`, "triple\ndouble"},
 		{`r"raw\nstring"`, `raw\nstring`},
+		{`R"\d+\""`, `\d+\"`},
+		{`u"unicode"`, "unicode"},
+		{`U'unicode'`, "unicode"},
+		{`"\x41\101\u00e9\U0001F600"`, "AAé😀"},
+		{`"\xff"`, "\u00ff"},
+		{`"\d\N{BULLET}"`, `\d\N{BULLET}`},
+		{"\"line \\\ncontinued\"", "line continued"},
+		{`""`, ""},
 	}
 
 	for _, test := range tests {
@@ -180,6 +189,86 @@ double"""`, "triple\ndouble"},
 	}
 }
 
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []byte
+	}{
+		{`b"bytes"`, []byte("bytes")},
+		{`B'\x00\xff\n\101'`, []byte{0, 0xff, '\n', 'A'}},
+		{`br"\x00"`, []byte(`\x00`)},
+		{`Rb"\d"`, []byte(`\d`)},
+		{`b"\u00e9\N{BULLET}"`, []byte(`\u00e9\N{BULLET}`)},
+		{`b""""triple" """`, []byte(`"triple" `)},
+	}
+
+	for _, test := range tests {
+		tokens := scanTokens(test.input)
+		if len(tokens) != 2 || tokens[0].Type != String {
+			t.Errorf("Expected String token for %s, got %v", test.input, tokens)
+			continue
+		}
+		if val, ok := tokens[0].Literal.([]byte); !ok {
+			t.Errorf("Expected []byte literal for %s, got %T: %v", test.input, tokens[0].Literal, tokens[0].Literal)
+		} else if string(val) != string(test.expected) {
+			t.Errorf("Expected %q for %s, got %q", test.expected, test.input, val)
+		}
+	}
+}
+
+func TestStringPrefixes(t *testing.T) {
+	tests := []struct {
+		input string
+		types []TokenType
+	}{
+		{`rb"x"`, []TokenType{String}},
+		{`fR"x"`, []TokenType{FStringStart, FStringMiddle, FStringEnd}},
+		{`Rf"x"`, []TokenType{FStringStart, FStringMiddle, FStringEnd}},
+		{`f""`, []TokenType{FStringStart, FStringEnd}},
+		// Not prefixes: names followed by a string
+		{`ub"x"`, []TokenType{Identifier, String}},
+		{`bf"x"`, []TokenType{Identifier, String}},
+		{`rr"x"`, []TokenType{Identifier, String}},
+		{`if"x"`, []TokenType{If, String}},
+	}
+
+	for _, test := range tests {
+		tokens := scanTokens(test.input)
+		var types []TokenType
+		for _, tok := range tokens[:len(tokens)-1] {
+			types = append(types, tok.Type)
+		}
+		if !reflect.DeepEqual(types, test.types) {
+			t.Errorf("Expected %v for %s, got %v", test.types, test.input, types)
+		}
+	}
+}
+
+func TestFStringTextDecoding(t *testing.T) {
+	tests := []struct {
+		input string
+		text  []string
+	}{
+		{`f"a\n{x}b"`, []string{"a\n", "b"}},
+		{`f"{{literal}} {x}"`, []string{"{literal} "}},
+		{`rf"\d{x}"`, []string{`\d`}},
+		{`f"\{x}"`, []string{`\`}},
+		{`f"\N{BULLET} {x}"`, []string{`\N{BULLET} `}},
+	}
+
+	for _, test := range tests {
+		var text []string
+		for _, tok := range scanTokens(test.input) {
+			if tok.Type == FStringMiddle {
+				text = append(text, tok.Literal.(string))
+			}
+		}
+		if !reflect.DeepEqual(text, test.text) {
+			t.Errorf("Expected text %q for %s, got %q", test.text, test.input, text)
+		}
+	}
+}
+
 // Test indentation and dedentation
 func TestIndentation(t *testing.T) {
 	input := `def foo():
@@ -283,6 +372,8 @@ func TestErrorHandling(t *testing.T) {
 		{"unterminated string", `"hello`, true},
 		{"invalid character", "x = 1 $ y", true},
 		{"mixed tabs and spaces", "if True:\n\tx = 1\n    y = 2", true},
+		{"character after line continuation", "x = 1 + \\ 2", true},
+		{"line continuation at end of file", "x = 1 + \\", true},
 		{"valid code", "x = 1 + 2", false},
 	}
 
@@ -313,6 +404,21 @@ func TestLineContinuations(t *testing.T) {
 			"x = (1 +\n     2)",
 			false,
 		},
+		{
+			"explicit continuation",
+			"x = 1 + \\\n    2",
+			false,
+		},
+		{
+			"explicit continuation with CRLF",
+			"x = 1 + \\\r\n    2",
+			false,
+		},
+		{
+			"explicit continuation of a block header",
+			"if a and \\\nb:",
+			false,
+		},
 		{
 			"no continuation",
 			"x = 1\ny = 2",
@@ -337,6 +443,29 @@ func TestLineContinuations(t *testing.T) {
 	}
 }
 
+// Continued lines neither end the logical line nor change the indentation
+func TestExplicitLineJoiningIndentation(t *testing.T) {
+	input := "if a:\n    x = 1 + \\\n2\n    y = 3\n"
+	assertTokenTypes(t, scanTokens(input), []TokenType{
+		If, Identifier, Colon, Newline,
+		Indent, Identifier, Equal, Number, Plus, Number, Newline,
+		Identifier, Equal, Number, Newline,
+		Dedent, EOF,
+	})
+}
+
+// Blank and comment-only lines emit no NEWLINE, as in CPython
+func TestBlankLines(t *testing.T) {
+	input := "# header\n\nx = 1\n\n\nif x:\n    y = 2\n\n    # note\n  \n    z = 3\n"
+	assertTokenTypes(t, scanTokens(input), []TokenType{
+		Identifier, Equal, Number, Newline,
+		If, Identifier, Colon, Newline,
+		Indent, Identifier, Equal, Number, Newline,
+		Identifier, Equal, Number, Newline,
+		Dedent, EOF,
+	})
+}
+
 // Test ellipsis
 func TestEllipsis(t *testing.T) {
 	input := "x = ... # ellipsis"
@@ -367,7 +496,7 @@ func TestPositionTracking(t *testing.T) {
 func TestHTMLMode(t *testing.T) {
 	// This tests basic HTML tokenization in view context
 	scanner := NewScanner([]byte("<div>"))
-	scanner.ctx.viewDepth = 1 // Simulate being inside a view
+	scanner.ctx.views = []viewScope{{indent: -1}} // Simulate being inside a view
 	tokens := scanner.ScanTokens()
 
 	// Should produce: TagOpen, Identifier (div), TagClose, EOF
@@ -457,3 +586,21 @@ for i in range(10):
 		scanner.ScanTokens()
 	}
 }
+
+func TestTokenTypeDescribe(t *testing.T) {
+	tests := map[TokenType]string{
+		Colon:         "':'",
+		EqualEqual:    "'=='",
+		TagCloseStart: "'</'",
+		If:            "'if'",
+		View:          "'view'",
+		Identifier:    "identifier",
+		EOF:           "end of file",
+		FStringMiddle: "FStringMiddle",
+	}
+	for tt, want := range tests {
+		if got := tt.Describe(); got != want {
+			t.Errorf("%s.Describe() = %q, want %q", tt, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// syntax.go
+//
+// Per-file syntax versions. A file opts into an older (or newer) grammar
+// with a pragma on its first line:
+//
+//	# topple: syntax=0.1
+//
+// Each version has a set of features, the constructs of the grammar it
+// introduced, so grammar changes can be rolled out across a codebase one
+// file at a time. The parser reports constructs newer than a file's version.
+
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SyntaxVersion identifies a revision of the PSX grammar
+type SyntaxVersion struct {
+	Major, Minor int
+}
+
+func (v SyntaxVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Known syntax versions, oldest first
+var (
+	Syntax01 = SyntaxVersion{0, 1}
+	Syntax02 = SyntaxVersion{0, 2}
+
+	// CurrentSyntax is used for files without a pragma
+	CurrentSyntax = Syntax02
+)
+
+var supportedSyntax = []SyntaxVersion{Syntax01, Syntax02}
+
+// Feature is a construct of the grammar introduced by a syntax version.
+// Files selecting an older version cannot use it.
+type Feature int
+
+const (
+	AttributeSpreads Feature = iota // {...mapping} and {...mapping if condition} attributes
+	DynamicTags                     // <{expression}> tag names
+	Fragments                       // <> and </>
+	WhitespaceTrim                  // {~} in inline content
+	Layouts                         // layout views and 'use layout'
+)
+
+var features = [...]struct {
+	name  string
+	since SyntaxVersion
+}{
+	AttributeSpreads: {"attribute spreads", Syntax02},
+	DynamicTags:      {"computed tag names", Syntax02},
+	Fragments:        {"fragments", Syntax02},
+	WhitespaceTrim:   {"{~} whitespace trims", Syntax02},
+	Layouts:          {"layouts", Syntax02},
+}
+
+func (f Feature) String() string {
+	return features[f].name
+}
+
+// Since returns the syntax version that introduced the feature
+func (f Feature) Since() SyntaxVersion {
+	return features[f].since
+}
+
+// Has reports whether the grammar of version v includes the feature
+func (v SyntaxVersion) Has(f Feature) bool {
+	return !v.before(f.Since())
+}
+
+func (v SyntaxVersion) before(o SyntaxVersion) bool {
+	return v.Major < o.Major || (v.Major == o.Major && v.Minor < o.Minor)
+}
+
+// ParseSyntaxVersion parses a version such as "0.2" and checks it is supported
+func ParseSyntaxVersion(s string) (SyntaxVersion, error) {
+	major, minor, ok := strings.Cut(s, ".")
+	if ok {
+		maj, errMaj := strconv.Atoi(major)
+		min, errMin := strconv.Atoi(minor)
+		if errMaj == nil && errMin == nil {
+			v := SyntaxVersion{maj, min}
+			for _, supported := range supportedSyntax {
+				if v == supported {
+					return v, nil
+				}
+			}
+		}
+	}
+
+	names := make([]string, len(supportedSyntax))
+	for i, supported := range supportedSyntax {
+		names[i] = supported.String()
+	}
+	return SyntaxVersion{}, fmt.Errorf("unsupported syntax version %q (supported: %s)", s, strings.Join(names, ", "))
+}
+
+// SyntaxOf returns the syntax version the pragma of src selects, the current
+// one when it has none or an invalid one, which scanning src reports
+func SyntaxOf(src []byte) SyntaxVersion {
+	version, _, _ := syntaxPragma(src)
+	return version
+}
+
+const pragmaPrefix = "topple:"
+
+// syntaxPragma reads the "# topple: syntax=X.Y" pragma from the first line of
+// src. It reports whether the line is a topple pragma at all; settings other
+// than syntax are rejected so typos don't silently select the default.
+func syntaxPragma(src []byte) (version SyntaxVersion, found bool, err error) {
+	line, _, _ := strings.Cut(string(src), "\n")
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+	if !ok {
+		return CurrentSyntax, false, nil
+	}
+	settings, ok := strings.CutPrefix(strings.TrimSpace(comment), pragmaPrefix)
+	if !ok {
+		return CurrentSyntax, false, nil
+	}
+
+	version = CurrentSyntax
+	for _, setting := range strings.Fields(settings) {
+		key, value, _ := strings.Cut(setting, "=")
+		switch key {
+		case "syntax":
+			version, err = ParseSyntaxVersion(value)
+			if err != nil {
+				return CurrentSyntax, true, err
+			}
+		default:
+			return CurrentSyntax, true, fmt.Errorf("unknown pragma setting %q", key)
+		}
+	}
+	return version, true, nil
+}
@@ -0,0 +1,81 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyntaxPragma(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected SyntaxVersion
+		errMsg   string
+	}{
+		{name: "no pragma", src: "x = 1\n", expected: CurrentSyntax},
+		{name: "ordinary comment", src: "# a comment\nx = 1\n", expected: CurrentSyntax},
+		{name: "older syntax", src: "# topple: syntax=0.1\nx = 1\n", expected: Syntax01},
+		{name: "current syntax", src: "#topple: syntax=0.2\n", expected: Syntax02},
+		{name: "pragma not on first line", src: "x = 1\n# topple: syntax=0.1\n", expected: CurrentSyntax},
+		{name: "unknown version", src: "# topple: syntax=9.9\n", expected: CurrentSyntax, errMsg: `unsupported syntax version "9.9" (supported: 0.1, 0.2)`},
+		{name: "unknown setting", src: "# topple: sytnax=0.1\n", expected: CurrentSyntax, errMsg: `unknown pragma setting "sytnax"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := NewScanner([]byte(tt.src))
+			scanner.ScanTokens()
+
+			if scanner.Syntax() != tt.expected {
+				t.Errorf("expected syntax %s, got %s", tt.expected, scanner.Syntax())
+			}
+			if tt.errMsg == "" {
+				if len(scanner.Errors) != 0 {
+					t.Fatalf("unexpected errors: %v", scanner.Errors)
+				}
+				return
+			}
+			if len(scanner.Errors) != 1 {
+				t.Fatalf("expected 1 error, got %v", scanner.Errors)
+			}
+			if !strings.Contains(scanner.Errors[0].Error(), tt.errMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errMsg, scanner.Errors[0].Error())
+			}
+		})
+	}
+}
+
+func TestSyntaxFeatures(t *testing.T) {
+	attributeTokens := func(src string) []TokenType {
+		var types []TokenType
+		inTag := false
+		for _, tok := range scanTokens(src) {
+			switch {
+			case tok.Type == TagOpen:
+				inTag = true
+			case tok.Type == TagClose:
+				return types
+			case inTag && tok.Type != Identifier:
+				types = append(types, tok.Type)
+			}
+		}
+		return types
+	}
+
+	// Attribute interpolation predates the pragma: every version has it
+	body := "view V():\n    <div class=\"btn {variant}\">x</div>\n"
+	for _, src := range []string{body, "# topple: syntax=0.1\n" + body} {
+		if got := attributeTokens(src); len(got) < 2 || got[1] != FStringStart {
+			t.Errorf("expected interpolated attribute in %q, got %v", src, got)
+		}
+	}
+
+	for _, feature := range []Feature{AttributeSpreads, DynamicTags, Fragments, WhitespaceTrim, Layouts} {
+		if Syntax01.Has(feature) || !Syntax02.Has(feature) {
+			t.Errorf("expected %s to be introduced by syntax 0.2", feature)
+		}
+		if feature.Since() != Syntax02 {
+			t.Errorf("%s: Since() = %s, want 0.2", feature, feature.Since())
+		}
+	}
+}
@@ -91,6 +91,10 @@ const (
 	HTMLTextInline         // text content (only in inline mode)
 	HTMLInterpolationStart // { in HTML context
 	HTMLInterpolationEnd   // } in HTML context
+	HTMLComment            // <!-- ... --> (only when comments are preserved)
+
+	// ── trivia ──────────────────────────────────────────────────
+	Comment // # ... (never in the token stream, see Scanner.Comments)
 
 	// ── layout / structural tokens ──────────────────────────────
 	Newline
@@ -219,6 +223,9 @@ var tokenTypeNames = [...]string{
 	"HTMLTextInline",
 	"HTMLInterpolationStart",
 	"HTMLInterpolationEnd",
+	"HTMLComment",
+
+	"Comment",
 
 	"Newline",
 	"Indent",
@@ -280,6 +287,56 @@ func (tt TokenType) String() string {
 	return tokenTypeNames[tt]
 }
 
+// tokenTypeTexts holds the fixed source text of punctuation and operator
+// token types, for messages
+var tokenTypeTexts = map[TokenType]string{
+	LeftParen: "(", RightParen: ")", LeftBracket: "[", RightBracket: "]",
+	LeftBrace: "{", RightBrace: "}", Comma: ",", Colon: ":", Dot: ".",
+	Semicolon: ";", Plus: "+", Minus: "-", Star: "*", Slash: "/",
+	Percent: "%", Pipe: "|", Ampersand: "&", Caret: "^", Tilde: "~", At: "@",
+	Equal: "=", PlusEqual: "+=", MinusEqual: "-=", StarEqual: "*=",
+	SlashEqual: "/=", PercentEqual: "%=", PipeEqual: "|=", AmpEqual: "&=",
+	CaretEqual: "^=", Arrow: "->", AtEqual: "@=", SlashSlash: "//",
+	SlashSlashEqual: "//=", StarStar: "**", StarStarEqual: "**=",
+	LessLess: "<<", GreaterGreater: ">>", LessLessEqual: "<<=",
+	GreaterGreaterEqual: ">>=", BangEqual: "!=", EqualEqual: "==", Less: "<",
+	LessEqual: "<=", Greater: ">", GreaterEqual: ">=", Walrus: ":=",
+	IsNot: "is not", NotIn: "not in", Ellipsis: "...",
+	TagOpen: "<", TagClose: ">", TagCloseStart: "</", TagSelfClose: "/>",
+	HTMLInterpolationStart: "{", HTMLInterpolationEnd: "}",
+}
+
+// Describe returns how the token type is named in messages: its source
+// text in quotes for punctuation, operators and keywords, such as "':'" or
+// "'if'", and its kind otherwise, such as "identifier" or "end of file".
+func (tt TokenType) Describe() string {
+	if text, ok := tokenTypeTexts[tt]; ok {
+		return "'" + text + "'"
+	}
+	for keyword, t := range Keywords {
+		if t == tt {
+			return "'" + keyword + "'"
+		}
+	}
+	switch tt {
+	case Identifier:
+		return "identifier"
+	case String:
+		return "string"
+	case Number:
+		return "number"
+	case Newline:
+		return "newline"
+	case Indent:
+		return "indented block"
+	case Dedent:
+		return "dedent"
+	case EOF:
+		return "end of file"
+	}
+	return tt.String()
+}
+
 // Position is a helper type for representing a position in a file.
 type Position struct {
 	Line   int
@@ -0,0 +1,305 @@
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PyTokenName returns the name CPython's tokenize module gives tokens of
+// this type, such as NAME, OP or FSTRING_START, so token streams can be
+// compared with Python's. Keywords are NAME, as in Python, and EOF is
+// ENDMARKER; "is not" and "not in", two NAME tokens in Python, are NAME
+// too. PSX markup tokens have no Python counterpart and get names in
+// the same style, such as TAG_OPEN and HTML_TEXT.
+func (tt TokenType) PyTokenName() string {
+	if _, ok := tokenTypeTexts[tt]; ok {
+		switch tt {
+		case IsNot, NotIn, TagOpen, TagClose, TagCloseStart, TagSelfClose,
+			HTMLInterpolationStart, HTMLInterpolationEnd:
+		default:
+			return "OP"
+		}
+	}
+	switch tt {
+	case Identifier, View, Component, IsNot, NotIn:
+		return "NAME"
+	case String:
+		return "STRING"
+	case Number:
+		return "NUMBER"
+	case FStringStart:
+		return "FSTRING_START"
+	case FStringMiddle:
+		return "FSTRING_MIDDLE"
+	case FStringEnd:
+		return "FSTRING_END"
+	case LeftBraceF, RightBraceF, FStringEqual, FStringConversionStart:
+		return "OP"
+	case TagOpen:
+		return "TAG_OPEN"
+	case TagClose:
+		return "TAG_CLOSE"
+	case TagCloseStart:
+		return "TAG_CLOSE_START"
+	case TagSelfClose:
+		return "TAG_SELF_CLOSE"
+	case HTMLTextInline:
+		return "HTML_TEXT"
+	case HTMLInterpolationStart:
+		return "HTML_INTERPOLATION_START"
+	case HTMLInterpolationEnd:
+		return "HTML_INTERPOLATION_END"
+	case HTMLComment:
+		return "HTML_COMMENT"
+	case Comment:
+		return "COMMENT"
+	case Newline:
+		return "NEWLINE"
+	case Indent:
+		return "INDENT"
+	case Dedent:
+		return "DEDENT"
+	case EOF:
+		return "ENDMARKER"
+	case Illegal:
+		return "ERRORTOKEN"
+	}
+	for _, t := range Keywords {
+		if t == tt {
+			return "NAME"
+		}
+	}
+	return tt.String()
+}
+
+// PyToken is a token as CPython's tokenize module reports it: a type
+// name, the token's text, and positions with 0-based columns
+type PyToken struct {
+	Type  string
+	Text  string
+	Start Position
+	End   Position
+}
+
+// String formats the token like a line of "python -m tokenize" output
+func (t PyToken) String() string {
+	span := fmt.Sprintf("%d,%d-%d,%d:", t.Start.Line, t.Start.Column, t.End.Line, t.End.Column)
+	return fmt.Sprintf("%-20s%-15s%-15s", span, t.Type, pyRepr(t.Text))
+}
+
+// PyTokens converts tokens and comments to CPython's tokenize conventions.
+// Comments are merged in by position, "is not" and "not in" are two NAME
+// tokens, NEWLINE covers just the line break, INDENT is the new line's
+// indentation and DEDENT is empty, at the start of the first token after
+// it. NL tokens, which CPython emits for line breaks that do not end a
+// statement, are not produced.
+func PyTokens(tokens, comments []Token) []PyToken {
+	all := make([]Token, 0, len(tokens)+len(comments))
+	all = append(all, tokens...)
+	all = append(all, comments...)
+	sort.SliceStable(all, func(i, j int) bool {
+		return positionBefore(all[i].Start(), all[j].Start())
+	})
+
+	col0 := func(p Position) Position { return Position{Line: p.Line, Column: p.Column - 1} }
+	var out []PyToken
+	for _, tok := range all {
+		t := PyToken{Type: tok.Type.PyTokenName(), Text: tok.Lexeme, Start: col0(tok.Start()), End: col0(tok.End())}
+		switch tok.Type {
+		case IsNot, NotIn:
+			words := strings.Fields(tok.Lexeme)
+			first, second := words[0], words[1]
+			out = append(out, PyToken{Type: "NAME", Text: first, Start: t.Start,
+				End: Position{Line: t.Start.Line, Column: t.Start.Column + len(first)}})
+			t = PyToken{Type: "NAME", Text: second, End: t.End,
+				Start: Position{Line: t.End.Line, Column: t.End.Column - len(second)}}
+		case Newline:
+			t.End = Position{Line: t.Start.Line, Column: t.Start.Column + utf8.RuneCountInString(tok.Lexeme)}
+		case Indent:
+			t.Text = tok.Lexeme[strings.LastIndexByte(tok.Lexeme, '\n')+1:]
+			t.Start = Position{Line: t.End.Line}
+		case Dedent:
+			t.Text = ""
+			t.Start = t.End
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// pyRepr quotes s the way Python's repr quotes a str
+func pyRepr(s string) string {
+	quote := byte('\'')
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+	var b strings.Builder
+	b.WriteByte(quote)
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == rune(quote):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r < 0x100:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r < 0x10000:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08x`, r)
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
+}
+
+// CheckRoundtrip verifies that tokens and comments, as scanned from src
+// with the default start position, reproduce src: each lexeme is the
+// source text at its span, in order, and only whitespace and line
+// continuations lie between them. INDENT, DEDENT and EOF are layout and
+// cover no text. Markup comments are only tokens when the scanner preserves
+// them. The error describes the first mismatch.
+func CheckRoundtrip(src []byte, tokens, comments []Token) error {
+	all := make([]Token, 0, len(tokens)+len(comments))
+	for _, tok := range tokens {
+		switch tok.Type {
+		case Indent, Dedent, EOF:
+			continue
+		}
+		all = append(all, tok)
+	}
+	all = append(all, comments...)
+	sort.SliceStable(all, func(i, j int) bool {
+		return positionBefore(all[i].Start(), all[j].Start())
+	})
+
+	c := roundtripCursor{src: src, pos: Position{Line: 1, Column: 1}}
+	for _, tok := range all {
+		if err := c.skipGap(tok.Start()); err != nil {
+			return err
+		}
+		if c.pos != tok.Start() {
+			return fmt.Errorf("%s %q at %s overlaps the text before it, which ends at %s", tok.Type, tok.Lexeme, tok.Start(), c.pos)
+		}
+		if !c.consume(tok) {
+			return fmt.Errorf("%s %q at %s does not match the source %q", tok.Type, tok.Lexeme, tok.Start(), c.excerpt())
+		}
+		// A comment's lexeme leaves out the carriage return its span covers
+		if tok.Type == Comment {
+			c.skipCarriageReturn()
+		}
+		if c.pos != tok.End() {
+			return fmt.Errorf("%s %q at %s ends at %s, but its span ends at %s", tok.Type, tok.Lexeme, tok.Start(), c.pos, tok.End())
+		}
+	}
+	return c.skipGap(endOfSource)
+}
+
+// endOfSource is a position after any source
+var endOfSource = Position{Line: int(^uint(0) >> 1)}
+
+// positionBefore reports whether a comes before b
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+}
+
+// roundtripCursor walks the source the way the scanner counts positions:
+// a column per character, with lines and columns starting at 1
+type roundtripCursor struct {
+	src []byte
+	off int
+	pos Position
+}
+
+func (c *roundtripCursor) advance() {
+	r, size := utf8.DecodeRune(c.src[c.off:])
+	c.off += size
+	c.pos.Column++
+	if r == '\n' {
+		c.pos.Line++
+		c.pos.Column = 1
+	}
+}
+
+// skipGap moves to end, or to the end of the source if it comes first,
+// over text that is not part of any token
+func (c *roundtripCursor) skipGap(end Position) error {
+	c.skipSpace(end)
+	if c.off < len(c.src) && positionBefore(c.pos, end) {
+		return fmt.Errorf("source text %q at %s is not part of a token", c.excerpt(), c.pos)
+	}
+	return nil
+}
+
+// skipSpace moves towards end over whitespace and line continuations
+func (c *roundtripCursor) skipSpace(end Position) {
+	for c.off < len(c.src) && positionBefore(c.pos, end) {
+		switch c.src[c.off] {
+		case ' ', '\t', '\f', '\r', '\n':
+			c.advance()
+		case '\\':
+			rest := c.src[c.off+1:]
+			if !(len(rest) > 0 && rest[0] == '\n' || len(rest) > 1 && rest[0] == '\r' && rest[1] == '\n') {
+				return
+			}
+			c.advance()
+			c.skipCarriageReturn()
+			c.advance()
+		default:
+			return
+		}
+	}
+}
+
+func (c *roundtripCursor) skipCarriageReturn() {
+	if c.off < len(c.src) && c.src[c.off] == '\r' {
+		c.advance()
+	}
+}
+
+// consume moves past the token's lexeme if the source continues with it.
+// The words of "is not" and "not in" may be separated by any whitespace.
+func (c *roundtripCursor) consume(tok Token) bool {
+	words := []string{tok.Lexeme}
+	if tok.Type == IsNot || tok.Type == NotIn {
+		words = strings.Fields(tok.Lexeme)
+	}
+	for i, word := range words {
+		if i > 0 {
+			start := c.off
+			c.skipSpace(endOfSource)
+			if c.off == start {
+				return false
+			}
+		}
+		if !strings.HasPrefix(string(c.src[c.off:]), word) {
+			return false
+		}
+		for end := c.off + len(word); c.off < end; {
+			c.advance()
+		}
+	}
+	return true
+}
+
+// excerpt returns the source at the cursor, up to the end of its line
+func (c *roundtripCursor) excerpt() string {
+	rest := c.src[c.off:]
+	if i := strings.IndexByte(string(rest), '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	if len(rest) > 40 {
+		rest = rest[:40]
+	}
+	return string(rest)
+}
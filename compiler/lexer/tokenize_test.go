@@ -0,0 +1,144 @@
+package lexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPyTokenName(t *testing.T) {
+	tests := map[TokenType]string{
+		Identifier:     "NAME",
+		If:             "NAME",
+		True:           "NAME",
+		View:           "NAME",
+		Number:         "NUMBER",
+		String:         "STRING",
+		Plus:           "OP",
+		IsNot:          "NAME",
+		Ellipsis:       "OP",
+		LeftBraceF:     "OP",
+		FStringStart:   "FSTRING_START",
+		Newline:        "NEWLINE",
+		Dedent:         "DEDENT",
+		EOF:            "ENDMARKER",
+		Comment:        "COMMENT",
+		TagOpen:        "TAG_OPEN",
+		HTMLTextInline: "HTML_TEXT",
+	}
+	for tt, want := range tests {
+		if got := tt.PyTokenName(); got != want {
+			t.Errorf("%s: expected %s, got %s", tt, want, got)
+		}
+	}
+}
+
+func checkRoundtrip(src string) error {
+	scanner := NewScannerWithConfig([]byte(src), ScannerConfig{StartLine: 1, StartColumn: 1, PreserveHTMLComments: true})
+	tokens := scanner.ScanTokens()
+	return CheckRoundtrip([]byte(src), tokens, scanner.Comments())
+}
+
+func TestCheckRoundtrip(t *testing.T) {
+	sources := []string{
+		"x = 1  # one\r\ny = 'a' \\\n    + f\"{x!r:>{w}}\"\n",
+		"if a is   not b and c not\tin d:\n    pass\n",
+		"view V(items: list):\n    <ul class=\"list\">\n        <!-- items -->\n        for item in items:\n            <li>{item} left</li>\n    </ul>\n",
+	}
+	for _, src := range sources {
+		if err := checkRoundtrip(src); err != nil {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+}
+
+func TestCheckRoundtripMismatch(t *testing.T) {
+	src := []byte("x = 1\n")
+	tokens := NewScanner(src).ScanTokens()
+
+	dropped := append([]Token{}, tokens[:1]...)
+	dropped = append(dropped, tokens[2:]...)
+	if err := CheckRoundtrip(src, dropped, nil); err == nil || !strings.Contains(err.Error(), "not part of a token") {
+		t.Errorf("expected an error for a missing token, got %v", err)
+	}
+
+	changed := append([]Token{}, tokens...)
+	changed[2].Lexeme = "2"
+	if err := CheckRoundtrip(src, changed, nil); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected an error for a changed lexeme, got %v", err)
+	}
+}
+
+// Every golden input that scans cleanly roundtrips
+func TestCheckRoundtripTestdata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "input", "*", "*.psx"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no test inputs found: %v", err)
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := NewScannerWithConfig(src, ScannerConfig{StartLine: 1, StartColumn: 1, PreserveHTMLComments: true})
+		tokens := scanner.ScanTokens()
+		if len(scanner.Errors) > 0 {
+			continue
+		}
+		if err := CheckRoundtrip(src, tokens, scanner.Comments()); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+// The expected tokens are CPython's, from "python -m tokenize"
+func TestPyTokens(t *testing.T) {
+	src := "if a is not b:  # c\n    x = 'v'\ny = 2\n"
+	scanner := NewScanner([]byte(src))
+	tokens := scanner.ScanTokens()
+
+	var got []string
+	for _, tok := range PyTokens(tokens, scanner.Comments()) {
+		got = append(got, strings.TrimRight(tok.String(), " "))
+	}
+	want := []string{
+		"1,0-1,2:            NAME           'if'",
+		"1,3-1,4:            NAME           'a'",
+		"1,5-1,7:            NAME           'is'",
+		"1,8-1,11:           NAME           'not'",
+		"1,12-1,13:          NAME           'b'",
+		"1,13-1,14:          OP             ':'",
+		"1,16-1,19:          COMMENT        '# c'",
+		"1,19-1,20:          NEWLINE        '\\n'",
+		"2,0-2,4:            INDENT         '    '",
+		"2,4-2,5:            NAME           'x'",
+		"2,6-2,7:            OP             '='",
+		"2,8-2,11:           STRING         \"'v'\"",
+		"2,11-2,12:          NEWLINE        '\\n'",
+		"3,0-3,0:            DEDENT         ''",
+		"3,0-3,1:            NAME           'y'",
+		"3,2-3,3:            OP             '='",
+		"3,4-3,5:            NUMBER         '2'",
+		"3,5-3,6:            NEWLINE        '\\n'",
+		"4,0-4,0:            ENDMARKER      ''",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPyRepr(t *testing.T) {
+	tests := map[string]string{
+		"abc":               `'abc'`,
+		"it's":              `"it's"`,
+		`'"`:                `'\'"'`,
+		"a\\b\t\n":          `'a\\b\t\n'`,
+		"\x00é\u00a0\u200b": `'\x00é\xa0\u200b'`,
+	}
+	for in, want := range tests {
+		if got := pyRepr(in); got != want {
+			t.Errorf("pyRepr(%q): expected %s, got %s", in, want, got)
+		}
+	}
+}
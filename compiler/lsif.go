@@ -0,0 +1,227 @@
+package compiler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// lsifVersion is the LSIF specification version WriteLSIF follows
+const lsifVersion = "0.4.3"
+
+// lsifElement is a vertex or an edge of an LSIF dump; fields are set per label
+type lsifElement struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+
+	// metaData
+	Version          string    `json:"version,omitempty"`
+	ProjectRoot      string    `json:"projectRoot,omitempty"`
+	PositionEncoding string    `json:"positionEncoding,omitempty"`
+	ToolInfo         *lsifTool `json:"toolInfo,omitempty"`
+
+	// project, document and $event
+	Kind       string `json:"kind,omitempty"`
+	URI        string `json:"uri,omitempty"`
+	LanguageID string `json:"languageId,omitempty"`
+	Scope      string `json:"scope,omitempty"`
+	Data       int    `json:"data,omitempty"`
+
+	// range
+	Start *lsifPosition `json:"start,omitempty"`
+	End   *lsifPosition `json:"end,omitempty"`
+
+	// edges
+	OutV     int    `json:"outV,omitempty"`
+	InV      int    `json:"inV,omitempty"`
+	InVs     []int  `json:"inVs,omitempty"`
+	Document int    `json:"document,omitempty"`
+	Property string `json:"property,omitempty"`
+}
+
+// lsifTool names the program that wrote a dump
+type lsifTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// lsifPosition is zero-based and counts UTF-16 code units, like LSP
+type lsifPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lsifWriter numbers and writes the elements of a dump
+type lsifWriter struct {
+	enc    *json.Encoder
+	nextID int
+	err    error
+}
+
+func (w *lsifWriter) emit(e lsifElement) int {
+	w.nextID++
+	e.ID = w.nextID
+	if w.err == nil {
+		w.err = w.enc.Encode(e)
+	}
+	return e.ID
+}
+
+func (w *lsifWriter) vertex(label string, e lsifElement) int {
+	e.Type, e.Label = "vertex", label
+	return w.emit(e)
+}
+
+// edge links two vertices
+func (w *lsifWriter) edge(label string, outV, inV int) {
+	w.emit(lsifElement{Type: "edge", Label: label, OutV: outV, InV: inV})
+}
+
+// edges links a vertex to several; item edges also name the document of the
+// ranges and the property they answer
+func (w *lsifWriter) edges(label string, outV int, inVs []int, document int, property string) {
+	w.emit(lsifElement{Type: "edge", Label: label, OutV: outV, InVs: inVs, Document: document, Property: property})
+}
+
+// WriteLSIF writes the index as an LSIF dump, one JSON element per line,
+// answering go-to-definition and find-references for the project's
+// documents. rootDir is the project root and version the tool version
+// recorded in the dump.
+func (x *ProjectIndex) WriteLSIF(w io.Writer, rootDir, version string) error {
+	sources := sourceCache{}
+	buf := bufio.NewWriter(w)
+	lw := &lsifWriter{enc: json.NewEncoder(buf)}
+
+	lw.vertex("metaData", lsifElement{
+		Version:          lsifVersion,
+		ProjectRoot:      fileURI(rootDir),
+		PositionEncoding: "utf-16",
+		ToolInfo:         &lsifTool{Name: "topple", Version: version},
+	})
+	project := lw.vertex("project", lsifElement{Kind: "psx"})
+	lw.vertex("$event", lsifElement{Kind: "begin", Scope: "project", Data: project})
+
+	// Documents and their ranges, in file order
+	type document struct {
+		id     int
+		ranges []int
+	}
+	documents := make(map[string]*document)
+	var order []string
+	documentOf := func(file string) *document {
+		if doc, ok := documents[file]; ok {
+			return doc
+		}
+		doc := &document{id: lw.vertex("document", lsifElement{URI: fileURI(file), LanguageID: "psx"})}
+		documents[file] = doc
+		order = append(order, file)
+		return doc
+	}
+	rangeOf := func(file string, span lexer.Span) (int, error) {
+		src, err := sources.read(file)
+		if err != nil {
+			return 0, err
+		}
+		start, end := lsifPositionOf(src, span.Start), lsifPositionOf(src, span.End)
+		doc := documentOf(file)
+		id := lw.vertex("range", lsifElement{Start: &start, End: &end})
+		doc.ranges = append(doc.ranges, id)
+		return id, nil
+	}
+
+	// Each definition gets a result set that its own range and its
+	// references point to
+	type result struct {
+		set         int
+		definition  int // Range of the definition
+		references  map[string][]int
+		referencing []string // Files with references, in order
+	}
+	results := make(map[*IndexDefinition]*result)
+	for _, def := range x.Definitions {
+		id, err := rangeOf(def.File, def.Span)
+		if err != nil {
+			return err
+		}
+		set := lw.vertex("resultSet", lsifElement{})
+		lw.edge("next", id, set)
+		results[def] = &result{set: set, definition: id, references: make(map[string][]int)}
+	}
+	for _, ref := range x.References {
+		res, ok := results[ref.Definition]
+		if !ok {
+			continue
+		}
+		id, err := rangeOf(ref.File, ref.Span)
+		if err != nil {
+			return err
+		}
+		lw.edge("next", id, res.set)
+		if _, seen := res.references[ref.File]; !seen {
+			res.referencing = append(res.referencing, ref.File)
+		}
+		res.references[ref.File] = append(res.references[ref.File], id)
+	}
+
+	for _, def := range x.Definitions {
+		res := results[def]
+		definitionResult := lw.vertex("definitionResult", lsifElement{})
+		lw.edge("textDocument/definition", res.set, definitionResult)
+		lw.edges("item", definitionResult, []int{res.definition}, documents[def.File].id, "")
+
+		referenceResult := lw.vertex("referenceResult", lsifElement{})
+		lw.edge("textDocument/references", res.set, referenceResult)
+		lw.edges("item", referenceResult, []int{res.definition}, documents[def.File].id, "definitions")
+		for _, file := range res.referencing {
+			lw.edges("item", referenceResult, res.references[file], documents[file].id, "references")
+		}
+	}
+
+	var documentIDs []int
+	for _, file := range order {
+		doc := documents[file]
+		documentIDs = append(documentIDs, doc.id)
+		lw.edges("contains", doc.id, doc.ranges, 0, "")
+	}
+	if len(documentIDs) > 0 {
+		lw.edges("contains", project, documentIDs, 0, "")
+	}
+	lw.vertex("$event", lsifElement{Kind: "end", Scope: "project", Data: project})
+
+	if lw.err != nil {
+		return lw.err
+	}
+	return buf.Flush()
+}
+
+// lsifPositionOf converts a one-based lexer position, counting runes, to a
+// zero-based position counting UTF-16 code units
+func lsifPositionOf(src []byte, pos lexer.Position) lsifPosition {
+	if pos.Line < 1 {
+		return lsifPosition{}
+	}
+	units, column := 0, 1
+	for _, r := range sourceLine(src, pos.Line) {
+		if column >= pos.Column {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	units += max(pos.Column-column, 0)
+	return lsifPosition{Line: pos.Line - 1, Character: units}
+}
+
+// fileURI returns the file URI of a path, made absolute
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,148 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// renderInline renders the inline forms of a block's text
+func renderInline(text string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isPunctuation(text[i+1]):
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+
+		case c == '`':
+			if code, n, ok := codeSpan(text[i:]); ok {
+				out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == '!' && strings.HasPrefix(text[i+1:], "["):
+			if label, dest, n, ok := link(text[i+1:]); ok {
+				out.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(label) + `" />`)
+				i += n + 1
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == '[':
+			if label, dest, n, ok := link(text[i:]); ok {
+				out.WriteString(`<a href="` + html.EscapeString(dest) + `">` + renderInline(label) + "</a>")
+				i += n
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == '*' || c == '_':
+			if inner, tag, n, ok := emphasis(text[i:]); ok {
+				out.WriteString("<" + tag + ">" + renderInline(inner) + "</" + tag + ">")
+				i += n
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		default:
+			out.WriteString(html.EscapeString(text[i : i+1]))
+			i++
+		}
+	}
+	return out.String()
+}
+
+// codeSpan matches a code span at the start of s, returning its content and
+// length
+func codeSpan(s string) (string, int, bool) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	fence := s[:ticks]
+	end := strings.Index(s[ticks:], fence)
+	if end < 0 {
+		return "", 0, false
+	}
+	code := s[ticks : ticks+end]
+	if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = code[1 : len(code)-1]
+	}
+	return code, ticks + end + ticks, true
+}
+
+// link matches [label](destination) at the start of s
+func link(s string) (label, dest string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(s) || s[i+1] != '(' {
+				return "", "", 0, false
+			}
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 {
+				return "", "", 0, false
+			}
+			dest = strings.TrimSpace(s[i+2 : i+2+end])
+			if strings.ContainsAny(dest, " \n") {
+				return "", "", 0, false
+			}
+			return s[1:i], dest, i + 2 + end + 1, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// emphasis matches *em*, **strong** (or the '_' forms) at the start of s
+func emphasis(s string) (inner, tag string, n int, ok bool) {
+	delim := s[:1]
+	tag = "em"
+	if strings.HasPrefix(s, delim+delim) {
+		delim += delim
+		tag = "strong"
+	}
+
+	rest := s[len(delim):]
+	if rest == "" || rest[0] == ' ' || rest[0] == '\n' {
+		return "", "", 0, false
+	}
+	for i := 0; i+len(delim) <= len(rest); i++ {
+		if rest[i] == '\\' {
+			i++
+			continue
+		}
+		if rest[i] == '`' {
+			if _, skip, isCode := codeSpan(rest[i:]); isCode {
+				i += skip - 1
+				continue
+			}
+		}
+		if strings.HasPrefix(rest[i:], delim) && i > 0 && rest[i-1] != ' ' {
+			// A single delimiter must not be half of a double one
+			if len(delim) == 1 && strings.HasPrefix(rest[i+1:], delim) {
+				i++
+				continue
+			}
+			return rest[:i], tag, len(delim) + i + len(delim), true
+		}
+	}
+	return "", "", 0, false
+}
+
+func isPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
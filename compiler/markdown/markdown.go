@@ -0,0 +1,222 @@
+// Package markdown renders Markdown to HTML at compile time, for the
+// <Markdown> element of docs-heavy views.
+//
+// It covers the commonly used subset of CommonMark: ATX headings,
+// paragraphs, fenced code blocks, block quotes, flat bullet and ordered
+// lists, thematic breaks, and the inline forms code spans, emphasis, strong
+// emphasis, links, images and backslash escapes. Raw HTML in the source is
+// escaped, never passed through.
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// Render converts Markdown source to HTML
+func Render(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	renderBlocks(&out, lines)
+	return out.String()
+}
+
+// renderBlocks renders a sequence of lines as block elements
+func renderBlocks(out *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case isFence(trimmed):
+			i = renderCodeBlock(out, lines, i)
+
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+			writeTag(out, "h"+string(rune('0'+level)), renderInline(text))
+			i++
+
+		case isThematicBreak(trimmed):
+			out.WriteString("<hr />\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				rest := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(rest, " "))
+				i++
+			}
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, quoted)
+			out.WriteString("</blockquote>\n")
+
+		case listMarker(trimmed) != "":
+			i = renderList(out, lines, i)
+
+		default:
+			i = renderParagraph(out, lines, i)
+		}
+	}
+}
+
+// renderParagraph renders consecutive text lines as one paragraph
+func renderParagraph(out *strings.Builder, lines []string, i int) int {
+	var text []string
+	for i < len(lines) && !startsBlock(lines[i]) {
+		text = append(text, strings.TrimSpace(lines[i]))
+		i++
+	}
+	writeTag(out, "p", renderInline(strings.Join(text, "\n")))
+	return i
+}
+
+// startsBlock reports whether line ends a paragraph
+func startsBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || isFence(trimmed) || headingLevel(trimmed) > 0 ||
+		isThematicBreak(trimmed) || strings.HasPrefix(trimmed, ">") || listMarker(trimmed) != ""
+}
+
+// renderCodeBlock renders a fenced code block starting at lines[i]
+func renderCodeBlock(out *strings.Builder, lines []string, i int) int {
+	opening := strings.TrimSpace(lines[i])
+	fence := opening[:3]
+	indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+	info := strings.TrimSpace(strings.TrimLeft(opening, string(fence[0])))
+	i++
+
+	var code []string
+	for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+		code = append(code, trimIndent(lines[i], indent))
+		i++
+	}
+	if i < len(lines) {
+		i++ // closing fence
+	}
+
+	out.WriteString("<pre><code")
+	if lang, _, _ := strings.Cut(info, " "); lang != "" {
+		out.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	out.WriteString(">")
+	for _, line := range code {
+		out.WriteString(html.EscapeString(line))
+		out.WriteString("\n")
+	}
+	out.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderList renders consecutive items of one kind of list. Indented lines
+// continue the previous item.
+func renderList(out *strings.Builder, lines []string, i int) int {
+	ordered := isOrderedMarker(listMarker(strings.TrimSpace(lines[i])))
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	out.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		trimmed := strings.TrimSpace(lines[i])
+		marker := listMarker(trimmed)
+		if marker == "" || isOrderedMarker(marker) != ordered {
+			break
+		}
+
+		item := []string{strings.TrimSpace(trimmed[len(marker):])}
+		i++
+		for i < len(lines) {
+			next := lines[i]
+			if strings.TrimSpace(next) == "" || !strings.HasPrefix(next, "  ") && startsBlock(next) {
+				break
+			}
+			if listMarker(strings.TrimSpace(next)) != "" {
+				break
+			}
+			item = append(item, strings.TrimSpace(next))
+			i++
+		}
+		writeTag(out, "li", renderInline(strings.Join(item, "\n")))
+
+		// A blank line between items keeps the list going
+		if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			if next := listMarker(strings.TrimSpace(lines[i+1])); next != "" && isOrderedMarker(next) == ordered {
+				i++
+			}
+		}
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func writeTag(out *strings.Builder, tag, content string) {
+	out.WriteString("<" + tag + ">" + content + "</" + tag + ">\n")
+}
+
+// headingLevel returns the level of an ATX heading line, or 0
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0
+	}
+	return level
+}
+
+func isFence(line string) bool {
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")
+}
+
+// isThematicBreak reports whether line is three or more '-', '*' or '_'
+func isThematicBreak(line string) bool {
+	compact := strings.ReplaceAll(line, " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	for _, c := range []string{"-", "*", "_"} {
+		if strings.Count(compact, c) == len(compact) {
+			return true
+		}
+	}
+	return false
+}
+
+// listMarker returns the marker of a list item line, including the space
+// after it ("- ", "1. "), or "" if the line is not a list item
+func listMarker(line string) string {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return line[:2]
+	}
+	digits := 0
+	for digits < len(line) && digits < 9 && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits+1 < len(line) && (line[digits] == '.' || line[digits] == ')') && line[digits+1] == ' ' {
+		return line[:digits+2]
+	}
+	return ""
+}
+
+func isOrderedMarker(marker string) bool {
+	return marker != "" && marker[0] >= '0' && marker[0] <= '9'
+}
+
+// trimIndent removes up to n leading spaces
+func trimIndent(line string, n int) string {
+	for n > 0 && strings.HasPrefix(line, " ") {
+		line = line[1:]
+		n--
+	}
+	return line
+}
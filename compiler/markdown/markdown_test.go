@@ -0,0 +1,60 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "headings",
+			input:    "# Title\n### Sub ###",
+			expected: "<h1>Title</h1>\n<h3>Sub</h3>\n",
+		},
+		{
+			name:     "paragraphs",
+			input:    "one\ntwo\n\nthree",
+			expected: "<p>one\ntwo</p>\n<p>three</p>\n",
+		},
+		{
+			name:     "inline forms",
+			input:    "*em* **strong** _em_ `a < b` [link](/docs) ![logo](/logo.png)",
+			expected: `<p><em>em</em> <strong>strong</strong> <em>em</em> <code>a &lt; b</code> <a href="/docs">link</a> <img src="/logo.png" alt="logo" /></p>` + "\n",
+		},
+		{
+			name:     "escapes and raw html",
+			input:    `\*not em\* <script>&`,
+			expected: "<p>*not em* &lt;script&gt;&amp;</p>\n",
+		},
+		{
+			name:     "fenced code",
+			input:    "```python\nif a < b:\n    pass\n```",
+			expected: "<pre><code class=\"language-python\">if a &lt; b:\n    pass\n</code></pre>\n",
+		},
+		{
+			name:     "lists",
+			input:    "- one\n- two\n\n1. first\n2. second",
+			expected: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		},
+		{
+			name:     "blockquote and rule",
+			input:    "> quoted *text*\n\n---",
+			expected: "<blockquote>\n<p>quoted <em>text</em></p>\n</blockquote>\n<hr />\n",
+		},
+		{
+			name:     "unmatched delimiters",
+			input:    "a * b and [x] (y)",
+			expected: "<p>a * b and [x] (y)</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.input); got != tt.expected {
+				t.Errorf("Render(%q)\n got: %q\nwant: %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package compiler
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+// countNodes returns the number of AST nodes reachable from node, including node itself
+func countNodes(node ast.Node) int64 {
+	var count int64
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n != nil {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// nodeKinds returns the number of AST nodes reachable from node by kind, and
+// their deepest nesting; node itself is at depth 1
+func nodeKinds(node ast.Node) (map[string]int, int) {
+	kinds := make(map[string]int)
+	depth, maxDepth := 0, 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return true
+		}
+		depth++
+		maxDepth = max(maxDepth, depth)
+		kinds[reflect.TypeOf(n).Elem().Name()]++
+		return true
+	})
+	return kinds, maxDepth
+}
+
+// logInputStats logs the shape of a parsed file at debug level: its tokens
+// by type, its AST nodes by kind and their deepest nesting. Pathological
+// inputs stand out here before they show up as slow builds.
+func logInputStats(logger observe.Logger, file string, tokens []lexer.Token, module *ast.Module) {
+	if !observe.DebugEnabled(logger) {
+		return
+	}
+
+	tokenTypes := make(map[string]int)
+	for _, token := range tokens {
+		tokenTypes[token.Type.String()]++
+	}
+
+	var kinds map[string]int
+	nodes, maxDepth := 0, 0
+	if module != nil {
+		kinds, maxDepth = nodeKinds(module)
+		for _, n := range kinds {
+			nodes += n
+		}
+	}
+
+	logger.Debug("Input statistics",
+		"file", file,
+		"tokens", len(tokens),
+		"tokens_by_type", tokenTypes,
+		"nodes", nodes,
+		"nodes_by_kind", kinds,
+		"max_depth", maxDepth,
+	)
+}
+
+// stageClock times the stages of the pipeline on one file, reporting each
+// stage to a TimingSink when the next one starts or the clock stops
+type stageClock struct {
+	sink  observe.TimingSink
+	file  string
+	stage string // Stage being timed; "" once stopped
+	start time.Time
+}
+
+// startClock starts timing the first stage of file
+func startClock(sink observe.TimingSink, file, stage string) *stageClock {
+	return &stageClock{sink: sink, file: file, stage: stage, start: time.Now()}
+}
+
+// next reports the current stage and starts timing the given one
+func (c *stageClock) next(stage string) {
+	c.stop()
+	c.stage, c.start = stage, time.Now()
+}
+
+// stop reports the current stage
+func (c *stageClock) stop() {
+	if c.stage != "" {
+		c.sink.Time(c.file, c.stage, time.Since(c.start))
+		c.stage = ""
+	}
+}
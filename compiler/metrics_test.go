@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/observe"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestInputStatsLogging(t *testing.T) {
+	src := []byte(`view Home(title: str):
+    <div>
+        <h1>{title}</h1>
+    </div>
+`)
+	compile := func(level slog.Level) string {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+		if _, errs := NewCompiler(logger).Compile(context.Background(), File{Name: "home.psx", Content: src}); len(errs) > 0 {
+			t.Fatalf("compile errors: %v", errs)
+		}
+		return buf.String()
+	}
+
+	out := compile(slog.LevelDebug)
+	for _, want := range []string{
+		`msg="Input statistics" file=home.psx`,
+		`tokens_by_type="map[`,
+		"Identifier:",
+		`nodes_by_kind="map[`,
+		"HTMLElement:2",
+		"ViewStmt:1",
+		"max_depth=",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in debug log:\n%s", want, out)
+		}
+	}
+
+	// Statistics are not collected without debug logging
+	if out := compile(slog.LevelInfo); strings.Contains(out, "Input statistics") {
+		t.Errorf("expected no statistics at info level, got:\n%s", out)
+	}
+}
+
+func TestNodeKinds(t *testing.T) {
+	tokens, err := lexer.NewScanner([]byte("x = [[1]]\n")).ScanTokensContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, errs := parser.NewParser(tokens).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	kinds, maxDepth := nodeKinds(module)
+
+	// Module > AssignStmt > ListExpr > ListExpr > Literal
+	if maxDepth != 5 {
+		t.Errorf("expected max depth 5, got %d (kinds %v)", maxDepth, kinds)
+	}
+	if kinds["ListExpr"] != 2 {
+		t.Errorf("expected 2 ListExpr nodes, got %v", kinds)
+	}
+	var visited int64
+	for _, n := range kinds {
+		visited += int64(n)
+	}
+	if total := countNodes(module); total != visited {
+		t.Errorf("countNodes = %d, nodeKinds counted %d", total, visited)
+	}
+}
+
+func TestStageTimings(t *testing.T) {
+	src := []byte("view Home(title: str):\n    <h1>{title}</h1>\n")
+
+	timings := observe.NewTimings()
+	compiler := NewCompilerWithOptions(nil, Options{Timings: timings})
+	if _, errs := compiler.Compile(context.Background(), File{Name: "home.psx", Content: src}); len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	if files := timings.Files(); !reflect.DeepEqual(files, []string{"home.psx"}) {
+		t.Fatalf("expected home.psx to be timed, got %v", files)
+	}
+	var sum time.Duration
+	for _, stage := range observe.Stages {
+		sum += timings.Stage("home.psx", stage)
+	}
+	if total := timings.Total("home.psx"); total <= 0 || total != sum {
+		t.Errorf("expected the total to be the sum of the stages, got %s and %s", total, sum)
+	}
+
+	// Each compilation adds to the times of its file
+	before := timings.Total("home.psx")
+	if _, errs := compiler.Compile(context.Background(), File{Name: "home.psx", Content: src}); len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	if timings.Total("home.psx") <= before {
+		t.Errorf("expected a second compilation to add to the times")
+	}
+
+	// Multi-file compilations time every compiled file
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"button.psx": "view Button():\n    <button>Go</button>\n",
+		"page.psx":   "from button import Button\n\nview Page():\n    <Button />\n",
+	} {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timings = observe.NewTimings()
+	opts := MultiFileOptions{RootDir: tmpDir, Files: []string{tmpDir}, Options: Options{Timings: timings}}
+	if _, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), opts); err != nil {
+		t.Fatalf("CompileProject failed: %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "button.psx"), filepath.Join(tmpDir, "page.psx")}
+	if files := timings.Files(); !reflect.DeepEqual(files, want) {
+		t.Errorf("expected %v to be timed, got %v", want, files)
+	}
+}
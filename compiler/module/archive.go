@@ -0,0 +1,165 @@
+package module
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+const (
+	// ArchiveExt is the extension of package archives
+	ArchiveExt = ".tgz"
+
+	// SymbolsFileName is the file of a packed package describing the views
+	// of its modules
+	SymbolsFileName = "topple-symbols.json"
+
+	// maxArchiveSize bounds the unpacked content of an archive
+	maxArchiveSize = 256 << 20
+)
+
+// ArchiveName returns the file name of the archive of a package version,
+// such as ui_kit-1.2.0.tgz
+func ArchiveName(manifest *PackageManifest) string {
+	return manifest.Name + "-" + manifest.Version + ArchiveExt
+}
+
+// Archive is the content of a package archive
+type Archive struct {
+	Manifest *PackageManifest
+	Files    map[string][]byte // Slash-separated path relative to the package directory -> content
+}
+
+// WriteArchive writes files, keyed by slash-separated paths relative to the
+// package directory, as a gzipped tar of a directory named after the
+// package. Entries are sorted and carry no timestamps or owners, so packing
+// the same files produces the same archive.
+func WriteArchive(w io.Writer, name string, files map[string][]byte) error {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, p := range paths {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name + "/" + p,
+			Mode:     0644,
+			Size:     int64(len(files[p])),
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[p]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadArchive reads a package archive. Every entry must be a file or
+// directory inside one directory named after the package, holding a valid
+// manifest; links and paths escaping it are rejected.
+func ReadArchive(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a package archive: %w", err)
+	}
+	defer gz.Close()
+
+	var root string
+	files := make(map[string][]byte)
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading package archive: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("package archive entry %q is outside the package directory", header.Name)
+		}
+		dir, rel, _ := strings.Cut(name, "/")
+		if root == "" {
+			root = dir
+		} else if dir != root {
+			return nil, fmt.Errorf("package archive holds more than one package directory: %s and %s", root, dir)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("package archive entry %q is not a regular file", header.Name)
+		}
+		if rel == "" {
+			return nil, fmt.Errorf("package archive entry %q is not inside the package directory", header.Name)
+		}
+
+		total += header.Size
+		if total > maxArchiveSize {
+			return nil, fmt.Errorf("package archive is larger than %d MB", maxArchiveSize>>20)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from package archive: %w", header.Name, err)
+		}
+		files[rel] = content
+	}
+
+	data, ok := files[PackageManifestName]
+	if !ok {
+		return nil, fmt.Errorf("package archive has no %s in its package directory", PackageManifestName)
+	}
+	manifest, err := parsePackageManifest(data, root, path.Join(root, PackageManifestName))
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{Manifest: manifest, Files: files}, nil
+}
+
+// Install unpacks the archive into vendorDir, replacing the installed copy
+// of the package if any, and returns the package directory
+func (a *Archive) Install(fs filesystem.FileSystem, vendorDir string) (string, error) {
+	pkgDir := filepath.Join(vendorDir, a.Manifest.Name)
+	if err := fs.RemoveAll(pkgDir); err != nil {
+		return "", fmt.Errorf("removing the installed copy of %s: %w", a.Manifest.Name, err)
+	}
+
+	paths := make([]string, 0, len(a.Files))
+	for p := range a.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		target := filepath.Join(pkgDir, filepath.FromSlash(p))
+		if err := fs.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return "", err
+		}
+		if err := fs.WriteFile(target, a.Files[p], 0644); err != nil {
+			return "", err
+		}
+	}
+	return pkgDir, nil
+}
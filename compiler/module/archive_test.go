@@ -0,0 +1,206 @@
+package module
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// tarball builds a gzipped tar from headers and their contents
+func tarball(t *testing.T, entries []*tar.Header, contents []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for i, header := range entries {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(contents[i]))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(contents[i])); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		PackageManifestName:     []byte(manifest("ui_kit", "1.2.0", "")),
+		"button.psx":            []byte("view Button():\n    <button />\n"),
+		"button.py":             []byte("# compiled\n"),
+		"forms/__init__.psx":    []byte(""),
+		"forms/__init__.py":     []byte(""),
+		SymbolsFileName:         []byte("{}\n"),
+		"forms/nested/deep.psx": []byte("x = 1\n"),
+	}
+
+	var first, second bytes.Buffer
+	if err := WriteArchive(&first, "ui_kit", files); err != nil {
+		t.Fatalf("WriteArchive error: %v", err)
+	}
+	if err := WriteArchive(&second, "ui_kit", files); err != nil {
+		t.Fatalf("WriteArchive error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected packing the same files twice to produce the same archive")
+	}
+
+	archive, err := ReadArchive(&first)
+	if err != nil {
+		t.Fatalf("ReadArchive error: %v", err)
+	}
+	if archive.Manifest.Name != "ui_kit" || archive.Manifest.Version != "1.2.0" {
+		t.Errorf("Unexpected manifest %+v", archive.Manifest)
+	}
+	if len(archive.Files) != len(files) {
+		t.Errorf("Expected %d files, got %d", len(files), len(archive.Files))
+	}
+	for name, content := range files {
+		if !bytes.Equal(archive.Files[name], content) {
+			t.Errorf("File %s: expected %q, got %q", name, content, archive.Files[name])
+		}
+	}
+	if got := ArchiveName(archive.Manifest); got != "ui_kit-1.2.0.tgz" {
+		t.Errorf("Expected archive name ui_kit-1.2.0.tgz, got %s", got)
+	}
+}
+
+func TestReadArchiveRejects(t *testing.T) {
+	validManifest := manifest("ui_kit", "1.0.0", "")
+	file := func(name string) *tar.Header {
+		return &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0644}
+	}
+
+	tests := []struct {
+		name     string
+		entries  []*tar.Header
+		contents []string
+		want     string
+	}{
+		{
+			name:     "parent directory",
+			entries:  []*tar.Header{file("ui_kit/" + PackageManifestName), file("ui_kit/../../evil.py")},
+			contents: []string{validManifest, "x"},
+			want:     "outside the package directory",
+		},
+		{
+			name:     "absolute path",
+			entries:  []*tar.Header{file("/etc/evil")},
+			contents: []string{"x"},
+			want:     "outside the package directory",
+		},
+		{
+			name: "symlink",
+			entries: []*tar.Header{
+				file("ui_kit/" + PackageManifestName),
+				{Typeflag: tar.TypeSymlink, Name: "ui_kit/link", Linkname: "/etc/passwd"},
+			},
+			contents: []string{validManifest, ""},
+			want:     "not a regular file",
+		},
+		{
+			name:     "two packages",
+			entries:  []*tar.Header{file("ui_kit/" + PackageManifestName), file("other/a.psx")},
+			contents: []string{validManifest, ""},
+			want:     "more than one package directory",
+		},
+		{
+			name:     "no manifest",
+			entries:  []*tar.Header{file("ui_kit/a.psx")},
+			contents: []string{""},
+			want:     "has no " + PackageManifestName,
+		},
+		{
+			name:     "manifest for another package",
+			entries:  []*tar.Header{file("ui_kit/" + PackageManifestName)},
+			contents: []string{manifest("icons", "1.0.0", "")},
+			want:     "does not match directory name",
+		},
+		{
+			name:     "invalid export",
+			entries:  []*tar.Header{file("ui_kit/" + PackageManifestName)},
+			contents: []string{`{"name": "ui_kit", "version": "1.0.0", "exports": ["Button"]}`},
+			want:     "must name a view of a module",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(bytes.NewReader(tarball(t, tt.entries, tt.contents)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := ReadArchive(strings.NewReader("not gzip")); err == nil {
+		t.Error("Expected an error for data that is not an archive")
+	}
+}
+
+func TestArchiveInstall(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app.psx": "",
+		"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", ""),
+		"topple_modules/ui_kit/removed.psx":         "",
+	})
+
+	var buf bytes.Buffer
+	err := WriteArchive(&buf, "ui_kit", map[string][]byte{
+		PackageManifestName: []byte(manifest("ui_kit", "2.0.0", "")),
+		"forms/input.psx":   []byte("view Input():\n    <input />\n"),
+	})
+	if err != nil {
+		t.Fatalf("WriteArchive error: %v", err)
+	}
+	archive, err := ReadArchive(&buf)
+	if err != nil {
+		t.Fatalf("ReadArchive error: %v", err)
+	}
+
+	fs := filesystem.NewFileSystem(nil)
+	vendorDir := filepath.Join(root, VendorDirName)
+	pkgDir, err := archive.Install(fs, vendorDir)
+	if err != nil {
+		t.Fatalf("Install error: %v", err)
+	}
+	if pkgDir != filepath.Join(vendorDir, "ui_kit") {
+		t.Errorf("Unexpected package directory %s", pkgDir)
+	}
+	if _, err := os.Stat(filepath.Join(pkgDir, "removed.psx")); !os.IsNotExist(err) {
+		t.Error("Expected installing to replace the previous copy of the package")
+	}
+
+	// The installed package takes part in import resolution
+	r := NewResolver(Config{RootDir: root, FileSystem: fs})
+	index, err := r.Vendor()
+	if err != nil {
+		t.Fatalf("Vendor() error: %v", err)
+	}
+	if pkg, ok := index.Lookup("ui_kit"); !ok || pkg.Version != "2.0.0" {
+		t.Fatalf("Expected ui_kit 2.0.0 to be vendored, got %+v", pkg)
+	}
+	resolved, err := r.ResolveAbsolute(context.Background(), "ui_kit.forms.input")
+	if err != nil {
+		t.Fatalf("ResolveAbsolute error: %v", err)
+	}
+	if resolved != filepath.Join(pkgDir, "forms", "input.psx") {
+		t.Errorf("Unexpected resolution %s", resolved)
+	}
+}
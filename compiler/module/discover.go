@@ -0,0 +1,72 @@
+package module
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// DiscoverModules walks rootDir and returns the modules importable from it,
+// by dotted name, with the file each one resolves to. Regular packages are
+// listed under their own name with their __init__.psx. Files that cannot be
+// imported are left out: those whose path is not made of identifiers, those
+// hidden by a module or package of the same name, and those in topple_modules
+// directories, which are imported by package name instead. Namespace packages
+// have no file and are not listed, but their modules are.
+func (r *StandardResolver) DiscoverModules(ctx context.Context, rootDir string) (map[string]string, error) {
+	fs := r.config.FileSystem
+	absRoot, err := fs.AbsolutePath(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := fs.ListPSXFiles(absRoot, true)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string]string)
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		absFile, err := fs.AbsolutePath(file)
+		if err != nil {
+			continue
+		}
+		rel, err := fs.RelativePath(absRoot, absFile)
+		if err != nil || IsVendoredPath(rel) {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(rel, ".psx")), "/")
+		if parts[len(parts)-1] == "__init__" {
+			parts = parts[:len(parts)-1]
+		}
+		if len(parts) == 0 || !allIdentifiers(parts) {
+			continue
+		}
+
+		// Resolving the name tells whether another file hides this one
+		var l lookup
+		if resolved, _ := r.findModule([]string{absRoot}, parts, &l); resolved == absFile {
+			modules[strings.Join(parts, ".")] = absFile
+		}
+	}
+	return modules, nil
+}
+
+// allIdentifiers reports whether every part of a module path is a Python
+// identifier
+func allIdentifiers(parts []string) bool {
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+		for i, c := range part {
+			if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+				return false
+			}
+		}
+	}
+	return true
+}
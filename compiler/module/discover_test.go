@@ -0,0 +1,66 @@
+package module
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+func TestDiscoverModules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app.psx":                         "",
+		"__init__.psx":                    "",
+		"ui/__init__.psx":                 "",
+		"ui/button.psx":                   "",
+		"widgets/card.psx":                "",
+		"widgets/forms/input.psx":         "",
+		"layout.psx":                      "",
+		"layout/grid.psx":                 "",
+		"my-page.psx":                     "",
+		"2fa/code.psx":                    "",
+		"topple_modules/kit/button.psx":   "",
+		"notes.txt":                       "",
+		"widgets/forms/__pycache__/x.psx": "",
+	})
+
+	r := NewResolver(Config{RootDir: root, FileSystem: filesystem.NewFileSystem(nil)})
+	modules, err := r.DiscoverModules(context.Background(), root)
+	if err != nil {
+		t.Fatalf("DiscoverModules: %v", err)
+	}
+
+	want := map[string]string{
+		"app":                         filepath.Join(root, "app.psx"),
+		"ui":                          filepath.Join(root, "ui", "__init__.psx"),
+		"ui.button":                   filepath.Join(root, "ui", "button.psx"),
+		"widgets.card":                filepath.Join(root, "widgets", "card.psx"),
+		"widgets.forms.input":         filepath.Join(root, "widgets", "forms", "input.psx"),
+		"widgets.forms.__pycache__.x": filepath.Join(root, "widgets", "forms", "__pycache__", "x.psx"),
+		"layout":                      filepath.Join(root, "layout.psx"),
+	}
+	if !reflect.DeepEqual(modules, want) {
+		t.Errorf("DiscoverModules() = %v, want %v", modules, want)
+	}
+
+	// Every discovered module resolves to its file
+	for name, path := range modules {
+		resolved, err := r.ResolveAbsolute(context.Background(), name)
+		if err != nil || resolved != path {
+			t.Errorf("ResolveAbsolute(%q) = %q, %v, want %q", name, resolved, err, path)
+		}
+	}
+}
+
+func TestDiscoverModules_Cancelled(t *testing.T) {
+	root := writeTree(t, map[string]string{"app.psx": ""})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := NewResolver(Config{RootDir: root, FileSystem: filesystem.NewFileSystem(nil)})
+	if _, err := r.DiscoverModules(ctx, root); err == nil {
+		t.Error("DiscoverModules() with a cancelled context should fail")
+	}
+}
@@ -6,10 +6,82 @@
 //   - Absolute imports: "my_module" -> "./my_module.psx"
 //   - Relative imports: ".sibling" -> "../sibling.psx"
 //   - Package imports: "pkg" -> "./pkg/__init__.psx"
+//   - Namespace packages: "pkg.module" -> "./pkg/module.psx", without "./pkg/__init__.psx"
+//   - Vendored packages: "ui_kit.button" -> "./topple_modules/ui_kit/button.psx"
 //
 // The resolver respects Python's import semantics while working with
 // .psx file extensions instead of .py.
 //
+// # Search Paths
+//
+// Absolute imports are searched in RootDir, then in Config.SearchPaths in
+// order, then in vendored packages; the first match wins. Shared component
+// libraries outside the project are added as search paths, typically from
+// the TOPPLEPATH environment variable (see SearchPathsFromEnv). With a
+// Config.Logger, each resolution is traced at debug level with the search
+// path it was found under.
+//
+// Imports must match the casing of the files and directories they name:
+// on case-insensitive filesystems a mismatch is reported as a CaseMismatch
+// error rather than found, so that resolution does not depend on the
+// platform. Resolved files have their symlinks resolved below the search
+// path they were found in.
+//
+// # Namespace Packages
+//
+// As in Python (PEP 420), a directory without __init__.psx is a portion of a
+// namespace package, and the portions found in every search path are merged
+// when looking up its modules. A module file or a regular package found in
+// any search path takes precedence, and its submodules are only looked up
+// in the directory holding it. A namespace package has no file: resolving
+// it fails with a NamespacePackage error listing its portions (see
+// IsNamespacePackage), and callers look up the names imported from it as
+// modules.
+//
+// # Import Policy
+//
+// An absolute import that resolves to no PSX module is taken for a Python
+// module, emitted as written. Config.Imports makes this explicit for mixed
+// projects: modules under its External prefixes are not looked up at all
+// (ResolveAbsolute fails with an ExternalModule error), so files of the
+// project cannot shadow them, and RequiresPSX reports the modules its
+// Internal prefixes, or a Strict policy, require to resolve to PSX modules.
+// Callers report those that do not.
+//
+// DiscoverModules lists the modules importable from a directory, as
+// resolution would find them, for tools that work on a whole project.
+//
+// # Vendored Packages
+//
+// Third-party component libraries live under topple_modules/, one directory
+// per package, each with a topple-package.json manifest declaring its name,
+// version and dependencies. Packages may carry their own nested
+// topple_modules/ directories; these are flattened into a single index
+// because generated Python shares one module namespace, and differing
+// versions of the same package are reported as conflicts:
+//
+//	index, err := resolver.Vendor()
+//	for _, conflict := range index.Errors {
+//		// *PackageError: VersionConflict, MissingDependency, InvalidManifest
+//	}
+//
+// The installed packages are pinned in topple-lock.json by version and a
+// sha256 hash of their contents. VerifyLockfile reports version drift,
+// tampered package contents, and packages added or removed since locking.
+//
+// # Package Archives
+//
+// A package is distributed as a gzipped tar of its directory, named
+// <name>-<version>.tgz: the manifest, the .psx sources, the Python code and
+// stubs compiled from them and topple-symbols.json describing its views.
+// The manifest may list the views the package offers under "exports", as
+// module.View. ReadArchive rejects archives holding links, paths outside
+// the package directory or an invalid manifest, and Install unpacks one
+// into topple_modules/, replacing the installed copy:
+//
+//	archive, err := module.ReadArchive(f)
+//	pkgDir, err := archive.Install(fs, filepath.Join(root, module.VendorDirName))
+//
 // # Example Usage
 //
 //	config := module.Config{
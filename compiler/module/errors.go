@@ -1,8 +1,11 @@
 package module
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 )
 
 // ErrorType categorizes module resolution errors
@@ -13,6 +16,9 @@ const (
 	InvalidRelativeImport
 	InvalidPath
 	TooManyDots
+	NamespacePackage // The import names a namespace package, which has no file
+	CaseMismatch     // The import names a file or directory with different casing
+	ExternalModule   // The import policy declares the module an external Python module
 )
 
 // ResolutionError represents a module resolution failure
@@ -22,6 +28,9 @@ type ResolutionError struct {
 	SearchedPaths []string
 	ErrorType     ErrorType
 	Details       string
+	Portions      []string // Directories making up a namespace package
+	OnDisk        string   // File or directory a CaseMismatch import names with other casing
+	Suggestion    string   // Import path cased as on disk, for a CaseMismatch
 }
 
 func (e *ResolutionError) Error() string {
@@ -61,11 +70,76 @@ func (e *ResolutionError) Error() string {
 		if e.Details != "" {
 			sb.WriteString(fmt.Sprintf("\n  %s", e.Details))
 		}
+
+	case NamespacePackage:
+		sb.WriteString(fmt.Sprintf("'%s' is a namespace package with no __init__.psx", e.ImportPath))
+		if e.SourceFile != "" {
+			sb.WriteString(fmt.Sprintf("\n  in file: %s", e.SourceFile))
+		}
+		if len(e.Portions) > 0 {
+			sb.WriteString("\n  portions:")
+			for _, path := range e.Portions {
+				sb.WriteString(fmt.Sprintf("\n    - %s", path))
+			}
+		}
+
+	case CaseMismatch:
+		sb.WriteString(fmt.Sprintf("import '%s' does not match the casing of %s", e.ImportPath, e.OnDisk))
+		if e.SourceFile != "" {
+			sb.WriteString(fmt.Sprintf("\n  in file: %s", e.SourceFile))
+		}
+		sb.WriteString(fmt.Sprintf("\n  import it as '%s'", e.Suggestion))
+
+	case ExternalModule:
+		sb.WriteString(fmt.Sprintf("'%s' is an external Python module", e.ImportPath))
 	}
 
 	return sb.String()
 }
 
+// Diagnostic returns the structured form of the error, located in the
+// importing file
+func (e *ResolutionError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{Severity: diagnostics.Error, File: e.SourceFile}
+	switch e.ErrorType {
+	case ModuleNotFound:
+		d.Code = diagnostics.CodeModuleNotFound
+		d.Message = fmt.Sprintf("cannot resolve import '%s'", e.ImportPath)
+		for _, path := range e.SearchedPaths {
+			d.Notes = append(d.Notes, "searched "+path)
+		}
+	case InvalidRelativeImport:
+		d.Code = diagnostics.CodeInvalidRelativeImport
+		d.Message = fmt.Sprintf("invalid relative import '%s'", e.ImportPath)
+	case TooManyDots:
+		d.Code = diagnostics.CodeImportAboveRoot
+		d.Message = fmt.Sprintf("relative import has too many dots: %s", e.ImportPath)
+		d.Notes = append(d.Notes, "cannot navigate above root directory")
+	case InvalidPath:
+		d.Code = diagnostics.CodeInvalidImportPath
+		d.Message = fmt.Sprintf("invalid import path: %s", e.ImportPath)
+	case NamespacePackage:
+		d.Code = diagnostics.CodeModuleNotFound
+		d.Message = fmt.Sprintf("'%s' is a namespace package with no __init__.psx", e.ImportPath)
+		for _, path := range e.Portions {
+			d.Notes = append(d.Notes, "portion "+path)
+		}
+		d.Hints = append(d.Hints, "import its modules, such as 'from "+e.ImportPath+" import <module>'")
+	case CaseMismatch:
+		d.Code = diagnostics.CodeImportCase
+		d.Message = fmt.Sprintf("import '%s' does not match the casing of %s", e.ImportPath, e.OnDisk)
+		d.Notes = append(d.Notes, "case-insensitive filesystems find it, but case-sensitive ones do not")
+		d.Hints = append(d.Hints, fmt.Sprintf("import it as '%s'", e.Suggestion))
+	case ExternalModule:
+		d.Code = diagnostics.CodeModuleNotFound
+		d.Message = fmt.Sprintf("'%s' is an external Python module", e.ImportPath)
+	}
+	if e.Details != "" {
+		d.Notes = append(d.Notes, e.Details)
+	}
+	return d
+}
+
 func newModuleNotFoundError(importPath, sourceFile string, searchedPaths []string) error {
 	return &ResolutionError{
 		ImportPath:    importPath,
@@ -75,6 +149,43 @@ func newModuleNotFoundError(importPath, sourceFile string, searchedPaths []strin
 	}
 }
 
+func newNamespacePackageError(importPath, sourceFile string, portions []string) error {
+	return &ResolutionError{
+		ImportPath: importPath,
+		SourceFile: sourceFile,
+		Portions:   portions,
+		ErrorType:  NamespacePackage,
+	}
+}
+
+// IsNamespacePackage reports whether err is the resolution of an import
+// naming a namespace package, whose modules can still be imported
+func IsNamespacePackage(err error) bool {
+	var resErr *ResolutionError
+	return errors.As(err, &resErr) && resErr.ErrorType == NamespacePackage
+}
+
+// IsModuleNotFound reports whether err is the resolution of an import
+// naming a module found on no search path
+func IsModuleNotFound(err error) bool {
+	var resErr *ResolutionError
+	return errors.As(err, &resErr) && resErr.ErrorType == ModuleNotFound
+}
+
+func newExternalModuleError(importPath string) error {
+	return &ResolutionError{ImportPath: importPath, ErrorType: ExternalModule}
+}
+
+func newCaseMismatchError(importPath, sourceFile, suggestion, onDisk string) error {
+	return &ResolutionError{
+		ImportPath: importPath,
+		SourceFile: sourceFile,
+		OnDisk:     onDisk,
+		Suggestion: suggestion,
+		ErrorType:  CaseMismatch,
+	}
+}
+
 func newInvalidRelativeImportError(importPath, sourceFile, details string) error {
 	return &ResolutionError{
 		ImportPath: importPath,
@@ -91,3 +202,71 @@ func newTooManyDotsError(importPath, sourceFile string) error {
 		ErrorType:  TooManyDots,
 	}
 }
+
+// PackageErrorType categorizes vendored package errors
+type PackageErrorType int
+
+const (
+	InvalidManifest PackageErrorType = iota
+	MissingDependency
+	VersionConflict
+)
+
+// PackageError represents a problem with the vendored packages under topple_modules
+type PackageError struct {
+	Package   string // Package name
+	ErrorType PackageErrorType
+	Manifest  string   // Manifest file the problem was found in
+	Versions  []string // Conflicting versions (VersionConflict) or the unmet requirement
+	Details   string
+}
+
+func (e *PackageError) Error() string {
+	var sb strings.Builder
+
+	switch e.ErrorType {
+	case InvalidManifest:
+		sb.WriteString(fmt.Sprintf("invalid package manifest for '%s'", e.Package))
+		if e.Manifest != "" {
+			sb.WriteString(fmt.Sprintf("\n  manifest: %s", e.Manifest))
+		}
+	case MissingDependency:
+		sb.WriteString(fmt.Sprintf("missing vendored dependency '%s'", e.Package))
+		if e.Manifest != "" {
+			sb.WriteString(fmt.Sprintf("\n  required by: %s", e.Manifest))
+		}
+	case VersionConflict:
+		sb.WriteString(fmt.Sprintf("version conflict for package '%s'", e.Package))
+		for _, v := range e.Versions {
+			sb.WriteString(fmt.Sprintf("\n    - %s", v))
+		}
+	}
+
+	if e.Details != "" {
+		sb.WriteString(fmt.Sprintf("\n  %s", e.Details))
+	}
+
+	return sb.String()
+}
+
+// Diagnostic returns the structured form of the error, located in the
+// manifest the problem was found in
+func (e *PackageError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{Severity: diagnostics.Error, File: e.Manifest}
+	switch e.ErrorType {
+	case InvalidManifest:
+		d.Code = diagnostics.CodeInvalidManifest
+		d.Message = fmt.Sprintf("invalid package manifest for '%s'", e.Package)
+	case MissingDependency:
+		d.Code = diagnostics.CodeMissingDependency
+		d.Message = fmt.Sprintf("missing vendored dependency '%s'", e.Package)
+	case VersionConflict:
+		d.Code = diagnostics.CodeVersionConflict
+		d.Message = fmt.Sprintf("version conflict for package '%s'", e.Package)
+		d.Notes = append(d.Notes, e.Versions...)
+	}
+	if e.Details != "" {
+		d.Notes = append(d.Notes, e.Details)
+	}
+	return d
+}
@@ -0,0 +1,254 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+const (
+	// LockfileName is the lockfile written next to the topple_modules directory
+	LockfileName = "topple-lock.json"
+
+	// lockfileFormat is bumped when the lockfile layout changes
+	lockfileFormat = 1
+
+	integrityPrefix = "sha256-"
+)
+
+// LockMode selects how the compiler treats the lockfile
+type LockMode int
+
+const (
+	// LockAuto verifies an existing lockfile and creates one when missing
+	LockAuto LockMode = iota
+	// LockFrozen requires an up-to-date lockfile and never writes it (CI)
+	LockFrozen
+	// LockUpdate regenerates the lockfile from the installed packages
+	LockUpdate
+)
+
+// ParseLockMode parses "auto", "frozen" or "update"
+func ParseLockMode(s string) (LockMode, error) {
+	switch s {
+	case "", "auto":
+		return LockAuto, nil
+	case "frozen":
+		return LockFrozen, nil
+	case "update":
+		return LockUpdate, nil
+	default:
+		return LockAuto, fmt.Errorf("unknown lockfile mode %q (valid: auto, frozen, update)", s)
+	}
+}
+
+// Lockfile records the exact vendored packages a project was built with
+type Lockfile struct {
+	LockfileVersion int                      `json:"lockfileVersion"`
+	Packages        map[string]LockedPackage `json:"packages"`
+}
+
+// LockedPackage pins a package version and content hash
+type LockedPackage struct {
+	Version      string            `json:"version"`
+	Integrity    string            `json:"integrity"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// LockErrorType categorizes lockfile verification failures
+type LockErrorType int
+
+const (
+	IntegrityMismatch LockErrorType = iota
+	VersionDrift
+	UnlockedPackage
+	StalePackage
+	UnsupportedLockfile
+)
+
+// LockError reports a difference between the lockfile and the installed packages
+type LockError struct {
+	Package   string
+	ErrorType LockErrorType
+	Locked    string // Value recorded in the lockfile
+	Installed string // Value computed from topple_modules
+}
+
+func (e *LockError) Error() string {
+	switch e.ErrorType {
+	case IntegrityMismatch:
+		return fmt.Sprintf("package '%s' content does not match lockfile (locked %s, installed %s); it was modified after locking", e.Package, shortIntegrity(e.Locked), shortIntegrity(e.Installed))
+	case VersionDrift:
+		return fmt.Sprintf("package '%s' version drifted from lockfile (locked %s, installed %s)", e.Package, e.Locked, e.Installed)
+	case UnlockedPackage:
+		return fmt.Sprintf("package '%s@%s' is installed but not in the lockfile", e.Package, e.Installed)
+	case StalePackage:
+		return fmt.Sprintf("package '%s@%s' is in the lockfile but not installed", e.Package, e.Locked)
+	case UnsupportedLockfile:
+		return fmt.Sprintf("unsupported lockfile version %s (expected %s)", e.Locked, e.Installed)
+	default:
+		return fmt.Sprintf("lockfile error for package '%s'", e.Package)
+	}
+}
+
+// GenerateLockfile hashes every selected package in the index
+func GenerateLockfile(fs filesystem.FileSystem, index *VendorIndex) (*Lockfile, error) {
+	lock := &Lockfile{
+		LockfileVersion: lockfileFormat,
+		Packages:        make(map[string]LockedPackage),
+	}
+
+	for _, pkg := range index.SortedPackages() {
+		integrity, err := HashPackage(fs, pkg)
+		if err != nil {
+			return nil, fmt.Errorf("hashing package %s: %w", pkg.Name, err)
+		}
+		lock.Packages[pkg.Name] = LockedPackage{
+			Version:      pkg.Version,
+			Integrity:    integrity,
+			Dependencies: pkg.Dependencies,
+		}
+	}
+
+	return lock, nil
+}
+
+// HashPackage computes a content hash over the package's files.
+// Nested topple_modules directories are excluded; those packages are hashed separately.
+func HashPackage(fs filesystem.FileSystem, pkg *VendoredPackage) (string, error) {
+	files, err := fs.ListFiles(pkg.Dir, true)
+	if err != nil {
+		return "", err
+	}
+
+	relPaths := make([]string, 0, len(files))
+	byRel := make(map[string]string, len(files))
+	for _, file := range files {
+		rel, err := filepath.Rel(pkg.Dir, file)
+		if err != nil {
+			return "", err
+		}
+		rel = filepath.ToSlash(rel)
+		if IsVendoredPath(rel) {
+			continue
+		}
+		relPaths = append(relPaths, rel)
+		byRel[rel] = file
+	}
+	sort.Strings(relPaths)
+
+	h := sha256.New()
+	for _, rel := range relPaths {
+		content, err := fs.ReadFile(byRel[rel])
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", rel, len(content))
+		h.Write(content)
+	}
+
+	return integrityPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyLockfile compares the installed packages against a lockfile
+func VerifyLockfile(fs filesystem.FileSystem, index *VendorIndex, lock *Lockfile) ([]error, error) {
+	if lock.LockfileVersion != lockfileFormat {
+		return []error{&LockError{
+			ErrorType: UnsupportedLockfile,
+			Locked:    fmt.Sprint(lock.LockfileVersion),
+			Installed: fmt.Sprint(lockfileFormat),
+		}}, nil
+	}
+
+	current, err := GenerateLockfile(fs, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffLockfiles(lock, current), nil
+}
+
+// DiffLockfiles reports how installed differs from locked
+func DiffLockfiles(locked, installed *Lockfile) []error {
+	var errs []error
+
+	for _, name := range sortedLockNames(installed) {
+		inst := installed.Packages[name]
+		want, ok := locked.Packages[name]
+		switch {
+		case !ok:
+			errs = append(errs, &LockError{Package: name, ErrorType: UnlockedPackage, Installed: inst.Version})
+		case want.Version != inst.Version:
+			errs = append(errs, &LockError{Package: name, ErrorType: VersionDrift, Locked: want.Version, Installed: inst.Version})
+		case want.Integrity != inst.Integrity:
+			errs = append(errs, &LockError{Package: name, ErrorType: IntegrityMismatch, Locked: want.Integrity, Installed: inst.Integrity})
+		}
+	}
+
+	for _, name := range sortedLockNames(locked) {
+		if _, ok := installed.Packages[name]; !ok {
+			errs = append(errs, &LockError{Package: name, ErrorType: StalePackage, Locked: locked.Packages[name].Version})
+		}
+	}
+
+	return errs
+}
+
+// ReadLockfile loads a lockfile from disk
+func ReadLockfile(fs filesystem.FileSystem, path string) (*Lockfile, error) {
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Packages == nil {
+		lock.Packages = make(map[string]LockedPackage)
+	}
+	return &lock, nil
+}
+
+// Marshal encodes the lockfile deterministically (map keys are sorted by encoding/json)
+func (l *Lockfile) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// WriteLockfile writes the lockfile to disk
+func WriteLockfile(fs filesystem.FileSystem, path string, lock *Lockfile) error {
+	data, err := lock.Marshal()
+	if err != nil {
+		return err
+	}
+	return fs.WriteFile(path, data, 0644)
+}
+
+func sortedLockNames(l *Lockfile) []string {
+	names := make([]string, 0, len(l.Packages))
+	for name := range l.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// shortIntegrity abbreviates an integrity string for log output
+func shortIntegrity(integrity string) string {
+	hash := strings.TrimPrefix(integrity, integrityPrefix)
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return hash
+}
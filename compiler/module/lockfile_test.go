@@ -0,0 +1,121 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+func TestLockfile_RoundTrip(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", `"icons": "^1.0.0"`),
+		"topple_modules/ui_kit/button.psx":          "view Button():\n    <button/>\n",
+		"topple_modules/icons/topple-package.json":  manifest("icons", "1.1.0", ""),
+		"topple_modules/icons/check.psx":            "view Check():\n    <svg/>\n",
+	})
+
+	fs := filesystem.NewFileSystem(nil)
+	index, err := LoadVendorIndex(fs, filepath.Join(root, VendorDirName))
+	if err != nil {
+		t.Fatalf("LoadVendorIndex error: %v", err)
+	}
+
+	lock, err := GenerateLockfile(fs, index)
+	if err != nil {
+		t.Fatalf("GenerateLockfile error: %v", err)
+	}
+	if len(lock.Packages) != 2 {
+		t.Fatalf("expected 2 locked packages, got %d", len(lock.Packages))
+	}
+
+	lockPath := filepath.Join(root, LockfileName)
+	if err := WriteLockfile(fs, lockPath, lock); err != nil {
+		t.Fatalf("WriteLockfile error: %v", err)
+	}
+
+	first, _ := os.ReadFile(lockPath)
+	again, _ := GenerateLockfile(fs, index)
+	second, _ := again.Marshal()
+	if string(first) != string(second) {
+		t.Errorf("lockfile output is not deterministic:\n%s\nvs\n%s", first, second)
+	}
+
+	read, err := ReadLockfile(fs, lockPath)
+	if err != nil {
+		t.Fatalf("ReadLockfile error: %v", err)
+	}
+	errs, err := VerifyLockfile(fs, index, read)
+	if err != nil {
+		t.Fatalf("VerifyLockfile error: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("expected clean verification, got %v", errs)
+	}
+}
+
+func TestLockfile_DetectsTampering(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", ""),
+		"topple_modules/ui_kit/button.psx":          "view Button():\n    <button/>\n",
+	})
+
+	fs := filesystem.NewFileSystem(nil)
+	index, _ := LoadVendorIndex(fs, filepath.Join(root, VendorDirName))
+	lock, err := GenerateLockfile(fs, index)
+	if err != nil {
+		t.Fatalf("GenerateLockfile error: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "topple_modules/ui_kit/button.psx"), []byte("view Button():\n    <a/>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := VerifyLockfile(fs, index, lock)
+	if err != nil {
+		t.Fatalf("VerifyLockfile error: %v", err)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if lockErr, ok := errs[0].(*LockError); !ok || lockErr.ErrorType != IntegrityMismatch {
+		t.Errorf("expected IntegrityMismatch, got %v", errs[0])
+	}
+}
+
+func TestDiffLockfiles(t *testing.T) {
+	locked := &Lockfile{LockfileVersion: 1, Packages: map[string]LockedPackage{
+		"a": {Version: "1.0.0", Integrity: "sha256-aa"},
+		"b": {Version: "1.0.0", Integrity: "sha256-bb"},
+		"c": {Version: "1.0.0", Integrity: "sha256-cc"},
+	}}
+	installed := &Lockfile{LockfileVersion: 1, Packages: map[string]LockedPackage{
+		"a": {Version: "1.0.0", Integrity: "sha256-aa"},
+		"b": {Version: "1.1.0", Integrity: "sha256-b2"},
+		"d": {Version: "0.1.0", Integrity: "sha256-dd"},
+	}}
+
+	errs := DiffLockfiles(locked, installed)
+	want := []LockErrorType{VersionDrift, UnlockedPackage, StalePackage}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, err := range errs {
+		if got := err.(*LockError).ErrorType; got != want[i] {
+			t.Errorf("error %d: type = %v, want %v (%v)", i, got, want[i], err)
+		}
+	}
+}
+
+func TestParseLockMode(t *testing.T) {
+	for input, want := range map[string]LockMode{"": LockAuto, "auto": LockAuto, "frozen": LockFrozen, "update": LockUpdate} {
+		got, err := ParseLockMode(input)
+		if err != nil || got != want {
+			t.Errorf("ParseLockMode(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLockMode("sometimes"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,69 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImportPolicy decides which absolute imports name Python modules outside
+// the project instead of PSX modules. Prefixes match whole dotted segments:
+// "fastapi" covers "fastapi" and "fastapi.responses", not "fastapi_users".
+// When a module matches prefixes of both lists, the longest one wins, so
+// "app" can be external while "app.views" is not.
+type ImportPolicy struct {
+	External []string // Emitted verbatim, without looking for a PSX module
+	Internal []string // Must resolve to a PSX module
+	Strict   bool     // Imports matching neither list must resolve to a PSX module too
+}
+
+// Validate checks that every prefix is a dotted module path
+func (p ImportPolicy) Validate() error {
+	for _, prefix := range append(append([]string{}, p.External...), p.Internal...) {
+		if !allIdentifiers(strings.Split(prefix, ".")) {
+			return fmt.Errorf("invalid module prefix %q: expected a dotted module path such as fastapi.responses", prefix)
+		}
+	}
+	return nil
+}
+
+// IsExternal reports whether modulePath names a Python module that is not
+// looked up as a PSX module
+func (p ImportPolicy) IsExternal(modulePath string) bool {
+	external, _ := p.match(modulePath)
+	return external
+}
+
+// RequiresPSX reports whether modulePath must resolve to a PSX module, and
+// the internal prefix requiring it, "" when the policy is strict
+func (p ImportPolicy) RequiresPSX(modulePath string) (bool, string) {
+	external, internal := p.match(modulePath)
+	if external {
+		return false, ""
+	}
+	if internal != "" {
+		return true, internal
+	}
+	return p.Strict, ""
+}
+
+// match returns whether the longest prefix matching modulePath is external,
+// or the internal prefix when it is internal
+func (p ImportPolicy) match(modulePath string) (bool, string) {
+	external, internal := longestPrefix(p.External, modulePath), longestPrefix(p.Internal, modulePath)
+	if len(external) > len(internal) {
+		return true, ""
+	}
+	return false, internal
+}
+
+// longestPrefix returns the longest of prefixes that is modulePath or one of
+// its parent packages, or ""
+func longestPrefix(prefixes []string, modulePath string) string {
+	best := ""
+	for _, prefix := range prefixes {
+		if len(prefix) > len(best) && (modulePath == prefix || strings.HasPrefix(modulePath, prefix+".")) {
+			best = prefix
+		}
+	}
+	return best
+}
@@ -0,0 +1,64 @@
+package module
+
+import (
+	"context"
+	"testing"
+)
+
+func TestImportPolicy(t *testing.T) {
+	policy := ImportPolicy{
+		External: []string{"datetime", "app"},
+		Internal: []string{"app.views", "ui"},
+	}
+	tests := []struct {
+		module   string
+		external bool
+		required bool
+		prefix   string
+	}{
+		{module: "datetime", external: true},
+		{module: "datetime.date", external: true},
+		{module: "datetime_utils"},
+		{module: "app.models", external: true},
+		{module: "app.views.home", required: true, prefix: "app.views"},
+		{module: "ui", required: true, prefix: "ui"},
+		{module: "requests"},
+	}
+	for _, tt := range tests {
+		if got := policy.IsExternal(tt.module); got != tt.external {
+			t.Errorf("IsExternal(%q) = %v, want %v", tt.module, got, tt.external)
+		}
+		required, prefix := policy.RequiresPSX(tt.module)
+		if required != tt.required || prefix != tt.prefix {
+			t.Errorf("RequiresPSX(%q) = %v, %q, want %v, %q", tt.module, required, prefix, tt.required, tt.prefix)
+		}
+	}
+
+	policy.Strict = true
+	if required, prefix := policy.RequiresPSX("requests"); !required || prefix != "" {
+		t.Errorf("expected strict imports to require a PSX module, got %v, %q", required, prefix)
+	}
+	if required, _ := policy.RequiresPSX("datetime"); required {
+		t.Errorf("expected an external module not to require a PSX module under strict imports")
+	}
+
+	if err := (ImportPolicy{External: []string{"fastapi.responses"}}).Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+	for _, invalid := range []string{"", "a..b", "my-lib", "1st"} {
+		if err := (ImportPolicy{Internal: []string{invalid}}).Validate(); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+
+	// An external module is not looked up, even when a PSX module shadows it
+	resolver := NewResolver(Config{
+		RootDir:    "/project",
+		FileSystem: newMockFS(map[string]bool{"/project/logging.psx": true}),
+		Imports:    ImportPolicy{External: []string{"logging"}},
+	})
+	_, err := resolver.ResolveAbsolute(context.Background(), "logging")
+	if resErr, ok := err.(*ResolutionError); !ok || resErr.ErrorType != ExternalModule {
+		t.Errorf("expected an ExternalModule error, got %v", err)
+	}
+}
@@ -2,12 +2,30 @@ package module
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/fjvillamarin/topple/compiler/observe"
 	"github.com/fjvillamarin/topple/internal/filesystem"
 )
 
+// SearchPathEnv names the environment variable listing extra search paths,
+// separated like PATH
+const SearchPathEnv = "TOPPLEPATH"
+
+// SearchPathsFromEnv returns the search paths listed in $TOPPLEPATH, in order
+func SearchPathsFromEnv() []string {
+	var paths []string
+	for _, path := range filepath.SplitList(os.Getenv(SearchPathEnv)) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
 // Resolver translates import paths to filesystem paths
 type Resolver interface {
 	// ResolveAbsolute resolves an absolute import path
@@ -24,6 +42,10 @@ type Resolver interface {
 
 	// SearchPaths returns the configured search paths
 	SearchPaths() []string
+
+	// DiscoverModules lists the modules importable from a root directory
+	// Example: "/proj" -> {"app": "/proj/app.psx", "ui": "/proj/ui/__init__.psx"}
+	DiscoverModules(ctx context.Context, rootDir string) (map[string]string, error)
 }
 
 // Config holds configuration for module resolution
@@ -31,21 +53,41 @@ type Config struct {
 	// RootDir is the base directory for resolution (usually cwd or project root)
 	RootDir string
 
-	// SearchPaths are additional directories to search (for future use)
+	// SearchPaths are directories searched for absolute imports after
+	// RootDir, in order; the first one holding the module wins
 	SearchPaths []string
 
 	// FileSystem abstraction for testing
 	FileSystem filesystem.FileSystem
+
+	// VendorDir holds vendored component libraries (default: RootDir/topple_modules)
+	VendorDir string
+
+	// Logger receives a debug trace of where each module was resolved from;
+	// nil discards it
+	Logger observe.Logger
+
+	// Imports declares the absolute imports that name Python modules
+	// outside the project, and those that must resolve to PSX modules
+	Imports ImportPolicy
 }
 
-// StandardResolver implements Resolver
+// StandardResolver implements Resolver. It is safe for concurrent use.
 type StandardResolver struct {
 	config Config
-	cache  map[string]string // Import path -> resolved file path
+
+	mu    sync.Mutex        // Guards cache and hits
+	cache map[string]string // Import path -> resolved file path
+	hits  int               // Resolutions answered from cache
+
+	vendorOnce sync.Once
+	vendor     *VendorIndex // Lazily loaded vendored packages
+	vendorErr  error
 }
 
 // NewResolver creates a new StandardResolver
 func NewResolver(config Config) *StandardResolver {
+	config.Logger = observe.LoggerOrNop(config.Logger)
 	return &StandardResolver{
 		config: config,
 		cache:  make(map[string]string),
@@ -54,49 +96,277 @@ func NewResolver(config Config) *StandardResolver {
 
 // ResolveAbsolute resolves an absolute import path to a file
 func (r *StandardResolver) ResolveAbsolute(ctx context.Context, modulePath string) (string, error) {
+	// External modules are not looked up, so a directory or file of the
+	// same name cannot shadow them
+	if r.config.Imports.IsExternal(modulePath) {
+		return "", newExternalModuleError(modulePath)
+	}
+
 	// Check cache first
-	if cached, ok := r.cache[modulePath]; ok {
+	r.mu.Lock()
+	cached, ok := r.cache[modulePath]
+	if ok {
+		r.hits++
+	}
+	r.mu.Unlock()
+	if ok {
 		return cached, nil
 	}
 
-	// Build search paths: root dir first, then additional search paths
-	searchPaths := []string{r.config.RootDir}
-	searchPaths = append(searchPaths, r.config.SearchPaths...)
+	var l lookup
+
+	// Try each search path, root dir first
+	filePath, portions := r.findModule(r.SearchPaths(), strings.Split(modulePath, "."), &l)
+	if filePath != "" {
+		from := r.searchPathOf(filePath)
+		filePath = r.canonicalPath(filePath, from)
+		r.remember(modulePath, filePath, from)
+		return filePath, nil
+	}
+
+	// Fall back to vendored packages: the first segment names the package
+	if path, ok := r.resolveVendored(modulePath, &l.attempted); ok {
+		r.remember(modulePath, path, r.vendor.Root)
+		return path, nil
+	}
+
+	if len(portions) > 0 {
+		return "", newNamespacePackageError(modulePath, "", portions)
+	}
+	if l.mismatch != nil {
+		return "", newCaseMismatchError(modulePath, "", l.mismatch.correct(modulePath), l.mismatch.path)
+	}
+	return "", newModuleNotFoundError(modulePath, "", l.attempted)
+}
+
+// lookup records what a module lookup tried, for its error
+type lookup struct {
+	attempted []string      // Candidate files, in the order they were tried
+	mismatch  *caseMismatch // First candidate found only by ignoring case
+}
+
+// caseMismatch is a path segment of an import that names a file or
+// directory with different casing. Case-insensitive filesystems find it,
+// but the import breaks on case-sensitive ones.
+type caseMismatch struct {
+	index  int    // Index of the segment in the module path
+	onDisk string // Segment as named on disk
+	path   string // File or directory found
+}
+
+// correct returns modulePath with the mismatched segment cased as on disk
+func (m *caseMismatch) correct(modulePath string) string {
+	parts := strings.Split(modulePath, ".")
+	parts[m.index] = m.onDisk
+	return strings.Join(parts, ".")
+}
+
+// onDiskName returns the name dir holds name under when it differs only in
+// case, or false when it holds name as written or cannot be listed
+func (r *StandardResolver) onDiskName(dir, name string) (string, bool) {
+	entries, err := r.config.FileSystem.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var folded string
+	for _, entry := range entries {
+		if entry == name {
+			return "", false
+		}
+		if folded == "" && strings.EqualFold(entry, name) {
+			folded = entry
+		}
+	}
+	return folded, folded != ""
+}
+
+// canonicalPath resolves the symlinks of a file found under searchPath, so
+// that a file reached through several links has one path. The file keeps
+// the search path as written when it lies inside it once resolved, so that
+// paths stay comparable to the project's own file paths.
+func (r *StandardResolver) canonicalPath(filePath, searchPath string) string {
+	fs := r.config.FileSystem
+	realPath, err := fs.ResolvePath(filePath)
+	if err != nil || realPath == filePath {
+		return filePath
+	}
+	if searchPath != "" {
+		absSearchPath, err := fs.AbsolutePath(searchPath)
+		if err != nil {
+			return realPath
+		}
+		realSearchPath, err := fs.ResolvePath(absSearchPath)
+		if err != nil {
+			return realPath
+		}
+		rel, err := fs.RelativePath(realSearchPath, realPath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fs.JoinPaths(absSearchPath, rel)
+		}
+	}
+	return realPath
+}
 
-	var attemptedPaths []string
+// findModule looks up the parts of a dotted module path under dirs the way
+// Python finds modules (PEP 420). Each part is looked for in every directory
+// in order. A module file or a regular package, a directory with an
+// __init__.psx, is taken from the first directory holding one, and the next
+// part is only looked for in that package. Directories without __init__.psx
+// are portions of a namespace package: when no directory holds a module or
+// regular package, the portions found in all of them are merged and the
+// next part is looked for in each. It returns the file the path names, or
+// the portions when it names a namespace package, or neither.
+//
+// Files and directories are matched with the casing of the import: those
+// that differ in case are skipped, as a case-sensitive filesystem would not
+// find them, and the first one is recorded in l.
+func (r *StandardResolver) findModule(dirs []string, parts []string, l *lookup) (string, []string) {
+	fs := r.config.FileSystem
+	for i, part := range parts {
+		last := i == len(parts)-1
+		var next, portions []string
+
+		for _, dir := range dirs {
+			// Try as file: dir/part.psx
+			if filePath, err := fs.AbsolutePath(fs.JoinPaths(dir, part+".psx")); err == nil {
+				l.attempted = append(l.attempted, filePath)
+				if exists, _ := fs.Exists(filePath); exists && r.sameCase(dir, part+".psx", i, l) {
+					if last {
+						return filePath, nil
+					}
+					// A module has no submodules
+					return "", nil
+				}
+			}
 
-	// Try each search path
-	for _, searchPath := range searchPaths {
-		// Convert dotted path to filesystem path
-		// "my.module" -> "my/module"
-		fsPath := strings.ReplaceAll(modulePath, ".", string(filepath.Separator))
+			// Try as package: dir/part/__init__.psx
+			pkgDir := fs.JoinPaths(dir, part)
+			if pkgPath, err := fs.AbsolutePath(fs.JoinPaths(pkgDir, "__init__.psx")); err == nil {
+				l.attempted = append(l.attempted, pkgPath)
+				if exists, _ := fs.Exists(pkgPath); exists && r.sameCase(dir, part, i, l) {
+					if last {
+						return pkgPath, nil
+					}
+					next = []string{pkgDir}
+					break
+				}
+			}
 
-		// Try as file: searchPath/my/module.psx
-		filePath := r.config.FileSystem.JoinPaths(searchPath, fsPath+".psx")
-		absFilePath, err := r.config.FileSystem.AbsolutePath(filePath)
-		if err == nil {
-			attemptedPaths = append(attemptedPaths, absFilePath)
-			exists, _ := r.config.FileSystem.Exists(absFilePath)
-			if exists {
-				r.cache[modulePath] = absFilePath
-				return absFilePath, nil
+			// Otherwise a directory is a portion of a namespace package
+			if isDir, _ := fs.IsDir(pkgDir); isDir && r.sameCase(dir, part, i, l) {
+				if absDir, err := fs.AbsolutePath(pkgDir); err == nil {
+					portions = append(portions, absDir)
+				}
 			}
 		}
 
-		// Try as package: searchPath/my/module/__init__.psx
-		pkgPath := r.config.FileSystem.JoinPaths(searchPath, fsPath, "__init__.psx")
-		absPkgPath, err := r.config.FileSystem.AbsolutePath(pkgPath)
-		if err == nil {
-			attemptedPaths = append(attemptedPaths, absPkgPath)
-			exists, _ := r.config.FileSystem.Exists(absPkgPath)
-			if exists {
-				r.cache[modulePath] = absPkgPath
-				return absPkgPath, nil
+		if next == nil {
+			if len(portions) == 0 {
+				return "", nil
 			}
+			if last {
+				return "", portions
+			}
+			next = portions
+		}
+		dirs = next
+	}
+	return "", nil
+}
+
+// sameCase reports whether dir holds name with the casing of the import,
+// recording a mismatch in l otherwise
+func (r *StandardResolver) sameCase(dir, name string, index int, l *lookup) bool {
+	onDisk, mismatched := r.onDiskName(dir, name)
+	if !mismatched {
+		return true
+	}
+	if l.mismatch == nil {
+		path := r.config.FileSystem.JoinPaths(dir, onDisk)
+		if absPath, err := r.config.FileSystem.AbsolutePath(path); err == nil {
+			path = absPath
+		}
+		l.mismatch = &caseMismatch{index: index, onDisk: strings.TrimSuffix(onDisk, ".psx"), path: path}
+	}
+	return false
+}
+
+// searchPathOf returns the search path holding a resolved file, for the
+// resolution trace
+func (r *StandardResolver) searchPathOf(filePath string) string {
+	for _, searchPath := range r.SearchPaths() {
+		absSearchPath, err := r.config.FileSystem.AbsolutePath(searchPath)
+		if err != nil {
+			continue
+		}
+		rel, err := r.config.FileSystem.RelativePath(absSearchPath, filePath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return searchPath
+		}
+	}
+	return ""
+}
+
+// remember caches the resolution of an absolute import and traces the
+// search path it was found under
+func (r *StandardResolver) remember(modulePath, filePath, from string) {
+	r.mu.Lock()
+	r.cache[modulePath] = filePath
+	r.mu.Unlock()
+	r.config.Logger.Debug("Resolved module", "module", modulePath, "path", filePath, "from", from)
+}
+
+// CacheHits returns how many resolutions were answered from the cache
+func (r *StandardResolver) CacheHits() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hits
+}
+
+// Vendor returns the index of vendored packages, loading it on first use
+func (r *StandardResolver) Vendor() (*VendorIndex, error) {
+	r.vendorOnce.Do(func() {
+		vendorDir := r.config.VendorDir
+		if vendorDir == "" {
+			vendorDir = r.config.FileSystem.JoinPaths(r.config.RootDir, VendorDirName)
 		}
+		r.vendor, r.vendorErr = LoadVendorIndex(r.config.FileSystem, vendorDir)
+	})
+	return r.vendor, r.vendorErr
+}
+
+// resolveVendored resolves an absolute import against the vendored packages
+func (r *StandardResolver) resolveVendored(modulePath string, attemptedPaths *[]string) (string, bool) {
+	index, err := r.Vendor()
+	if err != nil || index == nil {
+		return "", false
 	}
 
-	return "", newModuleNotFoundError(modulePath, "", attemptedPaths)
+	parts := strings.SplitN(modulePath, ".", 2)
+	pkg, ok := index.Lookup(parts[0])
+	if !ok {
+		return "", false
+	}
+
+	var candidates []string
+	if len(parts) == 1 {
+		candidates = []string{r.config.FileSystem.JoinPaths(pkg.Dir, "__init__.psx")}
+	} else {
+		fsPath := strings.ReplaceAll(parts[1], ".", string(filepath.Separator))
+		candidates = []string{
+			r.config.FileSystem.JoinPaths(pkg.Dir, fsPath+".psx"),
+			r.config.FileSystem.JoinPaths(pkg.Dir, fsPath, "__init__.psx"),
+		}
+	}
+
+	for _, candidate := range candidates {
+		*attemptedPaths = append(*attemptedPaths, candidate)
+		exists, _ := r.config.FileSystem.Exists(candidate)
+		if exists {
+			return candidate, true
+		}
+	}
+	return "", false
 }
 
 // ResolveRelative resolves a relative import from a source file
@@ -157,7 +427,8 @@ func (r *StandardResolver) ResolveRelative(ctx context.Context, dotCount int, mo
 	}
 
 	// If no modulePath, we're importing from a package itself
-	// e.g., "from . import x" means import from current package's __init__.psx
+	// e.g., "from . import x" means import from current package's __init__.psx,
+	// or from a namespace package when the directory has none
 	if modulePath == "" {
 		initPath := r.config.FileSystem.JoinPaths(targetDir, "__init__.psx")
 		absInitPath, err := r.config.FileSystem.AbsolutePath(initPath)
@@ -170,6 +441,9 @@ func (r *StandardResolver) ResolveRelative(ctx context.Context, dotCount int, mo
 			return absInitPath, nil
 		}
 
+		if isDir, _ := r.config.FileSystem.IsDir(absTargetDir); isDir {
+			return "", newNamespacePackageError(strings.Repeat(".", dotCount), sourceFile, []string{absTargetDir})
+		}
 		return "", newModuleNotFoundError(
 			strings.Repeat(".", dotCount),
 			sourceFile,
@@ -177,35 +451,30 @@ func (r *StandardResolver) ResolveRelative(ctx context.Context, dotCount int, mo
 		)
 	}
 
-	// Convert dotted module path to filesystem path
-	fsPath := strings.ReplaceAll(modulePath, ".", string(filepath.Separator))
-
-	var attemptedPaths []string
+	var l lookup
 
-	// Try as file: targetDir/module.psx
-	filePath := r.config.FileSystem.JoinPaths(targetDir, fsPath+".psx")
-	absFilePath, err := r.config.FileSystem.AbsolutePath(filePath)
-	if err == nil {
-		attemptedPaths = append(attemptedPaths, absFilePath)
-		exists, _ := r.config.FileSystem.Exists(absFilePath)
-		if exists {
-			return absFilePath, nil
-		}
+	// Try targetDir/module.psx, then targetDir/module/__init__.psx, looking
+	// through namespace packages for dotted paths
+	importPath := strings.Repeat(".", dotCount) + modulePath
+	filePath, portions := r.findModule([]string{targetDir}, strings.Split(modulePath, "."), &l)
+	if filePath != "" {
+		return r.canonicalPath(filePath, r.config.RootDir), nil
 	}
-
-	// Try as package: targetDir/module/__init__.psx
-	pkgPath := r.config.FileSystem.JoinPaths(targetDir, fsPath, "__init__.psx")
-	absPkgPath, err := r.config.FileSystem.AbsolutePath(pkgPath)
-	if err == nil {
-		attemptedPaths = append(attemptedPaths, absPkgPath)
-		exists, _ := r.config.FileSystem.Exists(absPkgPath)
-		if exists {
-			return absPkgPath, nil
-		}
+	if len(portions) > 0 {
+		return "", newNamespacePackageError(importPath, sourceFile, portions)
+	}
+	if l.mismatch != nil {
+		suggestion := strings.Repeat(".", dotCount) + l.mismatch.correct(modulePath)
+		return "", newCaseMismatchError(importPath, sourceFile, suggestion, l.mismatch.path)
 	}
+	return "", newModuleNotFoundError(importPath, sourceFile, l.attempted)
+}
 
-	importPath := strings.Repeat(".", dotCount) + modulePath
-	return "", newModuleNotFoundError(importPath, sourceFile, attemptedPaths)
+// RequiresPSX reports whether the import policy requires modulePath to
+// resolve to a PSX module, and the internal prefix requiring it, "" when the
+// policy is strict
+func (r *StandardResolver) RequiresPSX(modulePath string) (bool, string) {
+	return r.config.Imports.RequiresPSX(modulePath)
 }
 
 // Exists checks if a module exists (without caching)
@@ -214,7 +483,8 @@ func (r *StandardResolver) Exists(ctx context.Context, modulePath string) bool {
 	return err == nil
 }
 
-// SearchPaths returns the configured search paths
+// SearchPaths returns the directories absolute imports are searched in, in
+// order: the root dir, then the configured search paths
 func (r *StandardResolver) SearchPaths() []string {
 	paths := []string{r.config.RootDir}
 	paths = append(paths, r.config.SearchPaths...)
@@ -1,18 +1,22 @@
 package module
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/internal/filesystem"
 )
 
 // mockFileSystem implements filesystem.FileSystem for testing
 type mockFileSystem struct {
-	files map[string]bool // path -> exists
+	files    map[string]bool // path -> exists
+	foldCase bool            // Match paths ignoring case, as on macOS and Windows
 }
 
 func newMockFS(files map[string]bool) *mockFileSystem {
@@ -28,6 +32,13 @@ func (m *mockFileSystem) WriteFile(path string, data []byte, perm os.FileMode) e
 }
 
 func (m *mockFileSystem) Exists(path string) (bool, error) {
+	if m.foldCase {
+		for p, exists := range m.files {
+			if strings.EqualFold(p, path) {
+				return exists, nil
+			}
+		}
+	}
 	exists, ok := m.files[path]
 	if !ok {
 		return false, nil
@@ -39,7 +50,7 @@ func (m *mockFileSystem) IsDir(path string) (bool, error) {
 	// Check if any file starts with this path + "/"
 	prefix := path + string(filepath.Separator)
 	for p := range m.files {
-		if strings.HasPrefix(p, prefix) {
+		if strings.HasPrefix(p, prefix) || m.foldCase && strings.HasPrefix(strings.ToLower(p), strings.ToLower(prefix)) {
 			return true, nil
 		}
 	}
@@ -54,10 +65,31 @@ func (m *mockFileSystem) ListPSXFiles(dir string, recursive bool) ([]string, err
 	return nil, nil
 }
 
+func (m *mockFileSystem) ReadDir(dir string) ([]string, error) {
+	prefix := dir + string(filepath.Separator)
+	seen := make(map[string]bool)
+	var names []string
+	for p := range m.files {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name := strings.SplitN(p[len(prefix):], string(filepath.Separator), 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
 func (m *mockFileSystem) MkdirAll(path string, perm os.FileMode) error {
 	return nil
 }
 
+func (m *mockFileSystem) RemoveAll(path string) error {
+	return nil
+}
+
 func (m *mockFileSystem) ResolvePath(path string) (string, error) {
 	return filepath.Abs(path)
 }
@@ -590,6 +622,227 @@ func TestMultipleSearchPaths(t *testing.T) {
 	}
 }
 
+func TestNamespacePackages(t *testing.T) {
+	fs := newMockFS(map[string]bool{
+		"/proj/ui/card.psx":            true, // ui is a namespace package in the root...
+		"/lib/ui/button.psx":           true, // ...merged with the one in /lib
+		"/lib/ui/forms/input.psx":      true,
+		"/proj/core/__init__.psx":      true, // A regular package hides later roots
+		"/lib/core/extra.psx":          true,
+		"/proj/tools.psx":              true, // So does a module
+		"/lib/tools/cli.psx":           true,
+		"/proj/pages/home.psx":         true,
+		"/proj/pages/parts/header.psx": true,
+	})
+	resolver := NewResolver(Config{
+		RootDir:     "/proj",
+		SearchPaths: []string{"/lib"},
+		FileSystem:  fs,
+	})
+
+	tests := []struct {
+		name       string
+		modulePath string
+		want       string
+		portions   []string // Of a namespace package, instead of a file
+	}{
+		{name: "module of a namespace package", modulePath: "ui.card", want: "/proj/ui/card.psx"},
+		{name: "portion in a later root", modulePath: "ui.button", want: "/lib/ui/button.psx"},
+		{name: "nested namespace package", modulePath: "ui.forms.input", want: "/lib/ui/forms/input.psx"},
+		{name: "merged namespace package", modulePath: "ui", portions: []string{"/proj/ui", "/lib/ui"}},
+		{name: "namespace package in one root", modulePath: "ui.forms", portions: []string{"/lib/ui/forms"}},
+		{name: "regular package hides later roots", modulePath: "core.extra"},
+		{name: "module hides later roots", modulePath: "tools.cli"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.ResolveAbsolute(context.Background(), tt.modulePath)
+			switch {
+			case tt.want != "":
+				if err != nil || got != tt.want {
+					t.Errorf("ResolveAbsolute(%q) = %q, %v; want %q", tt.modulePath, got, err, tt.want)
+				}
+			case tt.portions != nil:
+				resErr, ok := err.(*ResolutionError)
+				if !ok || !IsNamespacePackage(err) {
+					t.Fatalf("ResolveAbsolute(%q) = %q, %v; want a namespace package", tt.modulePath, got, err)
+				}
+				if strings.Join(resErr.Portions, ",") != strings.Join(tt.portions, ",") {
+					t.Errorf("Portions = %v, want %v", resErr.Portions, tt.portions)
+				}
+			default:
+				resErr, ok := err.(*ResolutionError)
+				if !ok || resErr.ErrorType != ModuleNotFound {
+					t.Errorf("ResolveAbsolute(%q) = %q, %v; want it not found", tt.modulePath, got, err)
+				}
+			}
+		})
+	}
+
+	t.Run("relative imports", func(t *testing.T) {
+		got, err := resolver.ResolveRelative(context.Background(), 1, "parts.header", "/proj/pages/home.psx")
+		if err != nil || got != "/proj/pages/parts/header.psx" {
+			t.Errorf("ResolveRelative(.parts.header) = %q, %v", got, err)
+		}
+		if _, err := resolver.ResolveRelative(context.Background(), 1, "", "/proj/pages/home.psx"); !IsNamespacePackage(err) {
+			t.Errorf("expected 'from . import' in a directory without __init__.psx to name a namespace package, got %v", err)
+		}
+		if _, err := resolver.ResolveRelative(context.Background(), 1, "parts", "/proj/pages/home.psx"); !IsNamespacePackage(err) {
+			t.Errorf("expected .parts to be a namespace package, got %v", err)
+		}
+	})
+
+	t.Run("diagnostic", func(t *testing.T) {
+		_, err := resolver.ResolveAbsolute(context.Background(), "ui")
+		d := diagnostics.From(err)
+		if d.Code != diagnostics.CodeModuleNotFound || !strings.Contains(d.Message, "namespace package") {
+			t.Errorf("unexpected diagnostic %+v", d)
+		}
+		if strings.Join(d.Notes, "\n") != "portion /proj/ui\nportion /lib/ui" {
+			t.Errorf("Notes = %v", d.Notes)
+		}
+	})
+}
+
+func TestCaseMismatch(t *testing.T) {
+	fs := newMockFS(map[string]bool{
+		"/proj/components/button.psx": true,
+		"/proj/components/page.psx":   true,
+		"/proj/ui/__init__.psx":       true,
+		"/proj/Forms/input.psx":       true,
+	})
+	fs.foldCase = true
+	resolver := NewResolver(Config{RootDir: "/proj", FileSystem: fs})
+
+	tests := []struct {
+		name       string
+		modulePath string
+		want       string // Resolved file, or the suggested import
+		onDisk     string
+	}{
+		{name: "exact casing", modulePath: "components.button", want: "/proj/components/button.psx"},
+		{name: "exact casing of a namespace package", modulePath: "Forms.input", want: "/proj/Forms/input.psx"},
+		{name: "module", modulePath: "components.Button", want: "components.button", onDisk: "/proj/components/button.psx"},
+		{name: "namespace package", modulePath: "Components.button", want: "components.button", onDisk: "/proj/components"},
+		{name: "regular package", modulePath: "UI", want: "ui", onDisk: "/proj/ui"},
+		{name: "first mismatch", modulePath: "forms.Input", want: "Forms.Input", onDisk: "/proj/Forms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolver.ResolveAbsolute(context.Background(), tt.modulePath)
+			if tt.onDisk == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("ResolveAbsolute(%q) = %q, %v; want %q", tt.modulePath, got, err, tt.want)
+				}
+				return
+			}
+			resErr, ok := err.(*ResolutionError)
+			if !ok || resErr.ErrorType != CaseMismatch {
+				t.Fatalf("ResolveAbsolute(%q) = %q, %v; want a case mismatch", tt.modulePath, got, err)
+			}
+			if resErr.Suggestion != tt.want || resErr.OnDisk != tt.onDisk {
+				t.Errorf("Suggestion, OnDisk = %q, %q; want %q, %q", resErr.Suggestion, resErr.OnDisk, tt.want, tt.onDisk)
+			}
+		})
+	}
+
+	t.Run("relative import", func(t *testing.T) {
+		_, err := resolver.ResolveRelative(context.Background(), 1, "Button", "/proj/components/page.psx")
+		resErr, ok := err.(*ResolutionError)
+		if !ok || resErr.ErrorType != CaseMismatch || resErr.Suggestion != ".button" {
+			t.Fatalf("ResolveRelative(.Button) = %v; want a case mismatch suggesting .button", err)
+		}
+	})
+
+	t.Run("diagnostic", func(t *testing.T) {
+		_, err := resolver.ResolveAbsolute(context.Background(), "components.Button")
+		d := diagnostics.From(err)
+		if d.Code != diagnostics.CodeImportCase {
+			t.Errorf("Code = %s, want %s", d.Code, diagnostics.CodeImportCase)
+		}
+		if d.Message != "import 'components.Button' does not match the casing of /proj/components/button.psx" {
+			t.Errorf("Message = %q", d.Message)
+		}
+		if len(d.Hints) != 1 || d.Hints[0] != "import it as 'components.button'" {
+			t.Errorf("Hints = %v", d.Hints)
+		}
+	})
+}
+
+func TestSymlinkedModules(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app.psx":           "",
+		"shared/button.psx": "",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "components"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("..", "shared", "button.psx"), filepath.Join(root, "components", "button.psx")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	link := filepath.Join(t.TempDir(), "project")
+	if err := os.Symlink(root, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	resolver := NewResolver(Config{RootDir: link, FileSystem: filesystem.NewFileSystem(nil)})
+
+	// A linked module resolves to its target...
+	got, err := resolver.ResolveAbsolute(context.Background(), "components.button")
+	if want := filepath.Join(link, "shared", "button.psx"); err != nil || got != want {
+		t.Errorf("ResolveAbsolute(components.button) = %q, %v; want %q", got, err, want)
+	}
+	got, err = resolver.ResolveRelative(context.Background(), 1, "button", filepath.Join(link, "components", "page.psx"))
+	if want := filepath.Join(link, "shared", "button.psx"); err != nil || got != want {
+		t.Errorf("ResolveRelative(.button) = %q, %v; want %q", got, err, want)
+	}
+
+	// ...and files keep the search path as written, even when it is a link
+	got, err = resolver.ResolveAbsolute(context.Background(), "app")
+	if want := filepath.Join(link, "app.psx"); err != nil || got != want {
+		t.Errorf("ResolveAbsolute(app) = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestSearchPathsFromEnv(t *testing.T) {
+	t.Setenv(SearchPathEnv, strings.Join([]string{"/lib1", "", "/lib2"}, string(os.PathListSeparator)))
+	paths := SearchPathsFromEnv()
+	if len(paths) != 2 || paths[0] != "/lib1" || paths[1] != "/lib2" {
+		t.Errorf("SearchPathsFromEnv() = %v, want [/lib1 /lib2]", paths)
+	}
+
+	t.Setenv(SearchPathEnv, "")
+	if paths := SearchPathsFromEnv(); len(paths) != 0 {
+		t.Errorf("SearchPathsFromEnv() = %v, want none", paths)
+	}
+}
+
+func TestResolutionTrace(t *testing.T) {
+	var logs bytes.Buffer
+	resolver := NewResolver(Config{
+		RootDir:     "/proj",
+		SearchPaths: []string{"/lib1", "/lib2"},
+		FileSystem:  newMockFS(map[string]bool{"/lib1/shared.psx": true, "/lib2/shared.psx": true}),
+		Logger:      slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.ResolveAbsolute(context.Background(), "shared"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Cached resolutions are traced once
+	if got := strings.Count(logs.String(), "Resolved module"); got != 1 {
+		t.Errorf("expected one trace line, got %d:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "path=/lib1/shared.psx from=/lib1") {
+		t.Errorf("expected the first search path to win, got:\n%s", logs.String())
+	}
+}
+
 func TestErrorMessages(t *testing.T) {
 	fs := newMockFS(map[string]bool{})
 	resolver := NewResolver(Config{
@@ -639,3 +892,29 @@ func TestErrorMessages(t *testing.T) {
 		}
 	})
 }
+
+func TestResolutionError_Diagnostic(t *testing.T) {
+	err := &ResolutionError{
+		ImportPath:    "widgets",
+		SourceFile:    "/project/app.psx",
+		SearchedPaths: []string{"/project/widgets.psx", "/project/widgets/__init__.psx"},
+		ErrorType:     ModuleNotFound,
+	}
+
+	d := diagnostics.From(err)
+	if d.Code != diagnostics.CodeModuleNotFound {
+		t.Errorf("Code = %s, want %s", d.Code, diagnostics.CodeModuleNotFound)
+	}
+	if d.File != "/project/app.psx" {
+		t.Errorf("File = %q, want the importing file", d.File)
+	}
+	want := []string{"searched /project/widgets.psx", "searched /project/widgets/__init__.psx"}
+	if strings.Join(d.Notes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Notes = %v, want %v", d.Notes, want)
+	}
+
+	tooMany := diagnostics.From(&ResolutionError{ImportPath: "....x", ErrorType: TooManyDots})
+	if tooMany.Code != diagnostics.CodeImportAboveRoot {
+		t.Errorf("Code = %s, want %s", tooMany.Code, diagnostics.CodeImportAboveRoot)
+	}
+}
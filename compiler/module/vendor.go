@@ -0,0 +1,386 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+const (
+	// VendorDirName is the directory holding vendored component libraries
+	VendorDirName = "topple_modules"
+
+	// PackageManifestName is the manifest file every vendored package must contain
+	PackageManifestName = "topple-package.json"
+)
+
+// PackageManifest describes a vendored component library
+type PackageManifest struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies,omitempty"` // package name -> version constraint
+	Exports      []string          `json:"exports,omitempty"`      // Views the package offers, as module.View relative to the package; every public view when empty
+}
+
+// VendoredPackage is a package found under a topple_modules directory
+type VendoredPackage struct {
+	Name         string
+	Version      string
+	Dir          string            // Absolute package directory (the importable package root)
+	ManifestPath string            // Absolute path to topple-package.json
+	Dependencies map[string]string // package name -> version constraint
+	Depth        int               // 0 for <root>/topple_modules, 1 for nested vendor dirs, ...
+}
+
+// VendorIndex is the flattened set of vendored packages for a project.
+//
+// Python has a single module namespace, so every package name maps to
+// exactly one installed copy. Nested topple_modules directories are
+// merged into the index; differing versions of the same package are
+// reported as conflicts in Errors.
+type VendorIndex struct {
+	Root     string                      // Absolute path of the top-level vendor directory
+	Packages map[string]*VendoredPackage // package name -> selected package
+	Errors   []error                     // Manifest, missing dependency and version conflict errors
+}
+
+// LoadVendorIndex scans vendorDir (and nested vendor directories) for packages.
+// A missing vendor directory yields an empty index. The returned error is only
+// set for filesystem failures; package problems are collected in Errors.
+func LoadVendorIndex(fs filesystem.FileSystem, vendorDir string) (*VendorIndex, error) {
+	absVendorDir, err := fs.AbsolutePath(vendorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &VendorIndex{
+		Root:     absVendorDir,
+		Packages: make(map[string]*VendoredPackage),
+	}
+
+	exists, _ := fs.Exists(absVendorDir)
+	if !exists {
+		return index, nil
+	}
+
+	files, err := fs.ListFiles(absVendorDir, true)
+	if err != nil {
+		return nil, fmt.Errorf("listing vendored packages in %s: %w", absVendorDir, err)
+	}
+	sort.Strings(files)
+
+	// Group every installed copy by package name
+	candidates := make(map[string][]*VendoredPackage)
+	var all []*VendoredPackage
+	for _, file := range files {
+		if filepath.Base(file) != PackageManifestName {
+			continue
+		}
+		pkgDir := filepath.Dir(file)
+		if filepath.Base(filepath.Dir(pkgDir)) != VendorDirName {
+			// Manifest nested inside a package's own sources, not a vendored package
+			continue
+		}
+
+		pkg, err := loadVendoredPackage(fs, absVendorDir, pkgDir, file)
+		if err != nil {
+			index.Errors = append(index.Errors, err)
+			continue
+		}
+		candidates[pkg.Name] = append(candidates[pkg.Name], pkg)
+		all = append(all, pkg)
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Select one copy per package; differing versions cannot share a namespace
+	for _, name := range names {
+		copies := candidates[name]
+		sort.SliceStable(copies, func(i, j int) bool {
+			return copies[i].Depth < copies[j].Depth
+		})
+
+		selected := copies[0]
+		var versions []string
+		conflict := false
+		for _, c := range copies {
+			versions = append(versions, fmt.Sprintf("%s (%s)", c.Version, c.Dir))
+			if compareVersions(c.Version, selected.Version) != 0 {
+				conflict = true
+			}
+		}
+		if conflict {
+			index.Errors = append(index.Errors, &PackageError{
+				Package:   name,
+				ErrorType: VersionConflict,
+				Versions:  versions,
+				Details:   "only one version of a package can be installed",
+			})
+		}
+		index.Packages[name] = selected
+	}
+
+	// Verify declared dependencies against the selected copies
+	for _, pkg := range all {
+		depNames := make([]string, 0, len(pkg.Dependencies))
+		for dep := range pkg.Dependencies {
+			depNames = append(depNames, dep)
+		}
+		sort.Strings(depNames)
+
+		for _, dep := range depNames {
+			constraint := pkg.Dependencies[dep]
+			installed, ok := index.Packages[dep]
+			if !ok {
+				index.Errors = append(index.Errors, &PackageError{
+					Package:   dep,
+					ErrorType: MissingDependency,
+					Manifest:  pkg.ManifestPath,
+					Details:   fmt.Sprintf("%s@%s requires %s %s", pkg.Name, pkg.Version, dep, constraint),
+				})
+				continue
+			}
+			if !SatisfiesVersion(installed.Version, constraint) {
+				index.Errors = append(index.Errors, &PackageError{
+					Package:   dep,
+					ErrorType: VersionConflict,
+					Manifest:  pkg.ManifestPath,
+					Versions: []string{
+						fmt.Sprintf("%s (installed at %s)", installed.Version, installed.Dir),
+						fmt.Sprintf("%s (required by %s@%s)", constraint, pkg.Name, pkg.Version),
+					},
+				})
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// loadVendoredPackage reads and validates a single package manifest
+func loadVendoredPackage(fs filesystem.FileSystem, vendorRoot, pkgDir, manifestPath string) (*VendoredPackage, error) {
+	manifest, err := ReadPackageManifest(fs, pkgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(vendorRoot, pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	depth := strings.Count(filepath.ToSlash(rel), VendorDirName+"/")
+
+	return &VendoredPackage{
+		Name:         manifest.Name,
+		Version:      manifest.Version,
+		Dir:          pkgDir,
+		ManifestPath: manifestPath,
+		Dependencies: manifest.Dependencies,
+		Depth:        depth,
+	}, nil
+}
+
+// ReadPackageManifest reads and validates the manifest of the package in
+// pkgDir, whose directory must be named after the package
+func ReadPackageManifest(fs filesystem.FileSystem, pkgDir string) (*PackageManifest, error) {
+	manifestPath := filepath.Join(pkgDir, PackageManifestName)
+	data, err := fs.ReadFile(manifestPath)
+	if err != nil {
+		return nil, &PackageError{
+			Package:   filepath.Base(pkgDir),
+			ErrorType: InvalidManifest,
+			Manifest:  manifestPath,
+			Details:   err.Error(),
+		}
+	}
+	return parsePackageManifest(data, filepath.Base(pkgDir), manifestPath)
+}
+
+// parsePackageManifest parses and validates a manifest found in a directory
+// named dirName
+func parsePackageManifest(data []byte, dirName, manifestPath string) (*PackageManifest, error) {
+	var manifest PackageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, &PackageError{
+			Package:   dirName,
+			ErrorType: InvalidManifest,
+			Manifest:  manifestPath,
+			Details:   err.Error(),
+		}
+	}
+
+	if manifest.Name != dirName {
+		return nil, &PackageError{
+			Package:   dirName,
+			ErrorType: InvalidManifest,
+			Manifest:  manifestPath,
+			Details:   fmt.Sprintf("manifest name %q does not match directory name %q", manifest.Name, dirName),
+		}
+	}
+
+	if _, ok := parseVersion(manifest.Version); !ok {
+		return nil, &PackageError{
+			Package:   dirName,
+			ErrorType: InvalidManifest,
+			Manifest:  manifestPath,
+			Details:   fmt.Sprintf("invalid version %q", manifest.Version),
+		}
+	}
+
+	for _, export := range manifest.Exports {
+		if i := strings.LastIndex(export, "."); i <= 0 || i == len(export)-1 {
+			return nil, &PackageError{
+				Package:   dirName,
+				ErrorType: InvalidManifest,
+				Manifest:  manifestPath,
+				Details:   fmt.Sprintf("export %q must name a view of a module, as module.View", export),
+			}
+		}
+	}
+	return &manifest, nil
+}
+
+// Lookup returns the selected package with the given name
+func (v *VendorIndex) Lookup(name string) (*VendoredPackage, bool) {
+	pkg, ok := v.Packages[name]
+	return pkg, ok
+}
+
+// PackageFor returns the selected package containing filePath, if any
+func (v *VendorIndex) PackageFor(filePath string) (*VendoredPackage, bool) {
+	for _, pkg := range v.Packages {
+		rel, err := filepath.Rel(pkg.Dir, filePath)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return pkg, true
+		}
+	}
+	return nil, false
+}
+
+// SortedPackages returns the selected packages ordered by name
+func (v *VendorIndex) SortedPackages() []*VendoredPackage {
+	pkgs := make([]*VendoredPackage, 0, len(v.Packages))
+	for _, pkg := range v.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	return pkgs
+}
+
+// IsVendoredPath reports whether path lies inside a topple_modules directory
+func IsVendoredPath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == VendorDirName {
+			return true
+		}
+	}
+	return false
+}
+
+// SatisfiesVersion reports whether version matches constraint.
+//
+// Supported constraints: "" and "*" (any), exact versions ("1.2.3"),
+// caret ("^1.2.3": same major, or same minor for 0.x), tilde
+// ("~1.2.3": same major.minor) and comparisons (">=", ">", "<=", "<", "=").
+func SatisfiesVersion(version, constraint string) bool {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == "*" {
+		return true
+	}
+
+	v, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if !strings.HasPrefix(constraint, op) {
+			continue
+		}
+		base, ok := parseVersion(strings.TrimSpace(constraint[len(op):]))
+		if !ok {
+			return false
+		}
+		cmp := compareParsed(v, base)
+		switch op {
+		case ">=":
+			return cmp >= 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		case "<":
+			return cmp < 0
+		case "=":
+			return cmp == 0
+		case "^":
+			if cmp < 0 || v[0] != base[0] {
+				return false
+			}
+			return base[0] != 0 || v[1] == base[1]
+		case "~":
+			return cmp >= 0 && v[0] == base[0] && v[1] == base[1]
+		}
+	}
+
+	base, ok := parseVersion(constraint)
+	return ok && compareParsed(v, base) == 0
+}
+
+// parseVersion parses "1.2.3" (optionally "v"-prefixed, with missing parts as 0)
+func parseVersion(s string) ([3]int, bool) {
+	var parts [3]int
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return parts, false
+	}
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// compareVersions compares two version strings, treating unparsable versions as equal only to themselves
+func compareVersions(a, b string) int {
+	pa, okA := parseVersion(a)
+	pb, okB := parseVersion(b)
+	if !okA || !okB {
+		return strings.Compare(a, b)
+	}
+	return compareParsed(pa, pb)
+}
+
+func compareParsed(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,216 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// writeTree creates files (relative path -> content) under a temp directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		full := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	return root
+}
+
+func manifest(name, version string, deps string) string {
+	if deps == "" {
+		return `{"name": "` + name + `", "version": "` + version + `"}`
+	}
+	return `{"name": "` + name + `", "version": "` + version + `", "dependencies": {` + deps + `}}`
+}
+
+func TestVendoredResolution(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app.psx": "",
+		"topple_modules/ui_kit/topple-package.json":                      manifest("ui_kit", "1.2.0", `"icons": "^2.0.0"`),
+		"topple_modules/ui_kit/__init__.psx":                             "",
+		"topple_modules/ui_kit/button.psx":                               "",
+		"topple_modules/ui_kit/forms/__init__.psx":                       "",
+		"topple_modules/ui_kit/topple_modules/icons/topple-package.json": manifest("icons", "2.1.0", ""),
+		"topple_modules/ui_kit/topple_modules/icons/check.psx":           "",
+	})
+
+	fs := filesystem.NewFileSystem(nil)
+	r := NewResolver(Config{RootDir: root, FileSystem: fs})
+
+	index, err := r.Vendor()
+	if err != nil {
+		t.Fatalf("Vendor() error: %v", err)
+	}
+	if len(index.Errors) > 0 {
+		t.Fatalf("unexpected vendor errors: %v", index.Errors)
+	}
+
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{"ui_kit", "topple_modules/ui_kit/__init__.psx"},
+		{"ui_kit.button", "topple_modules/ui_kit/button.psx"},
+		{"ui_kit.forms", "topple_modules/ui_kit/forms/__init__.psx"},
+		{"icons.check", "topple_modules/ui_kit/topple_modules/icons/check.psx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			got, err := r.ResolveAbsolute(context.Background(), tt.module)
+			if err != nil {
+				t.Fatalf("ResolveAbsolute(%q) error: %v", tt.module, err)
+			}
+			if want := filepath.Join(root, tt.want); got != want {
+				t.Errorf("ResolveAbsolute(%q) = %s, want %s", tt.module, got, want)
+			}
+		})
+	}
+}
+
+func TestVendoredResolution_ProjectTakesPrecedence(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"ui_kit/button.psx":                         "",
+		"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", ""),
+		"topple_modules/ui_kit/button.psx":          "",
+	})
+
+	r := NewResolver(Config{RootDir: root, FileSystem: filesystem.NewFileSystem(nil)})
+	got, err := r.ResolveAbsolute(context.Background(), "ui_kit.button")
+	if err != nil {
+		t.Fatalf("ResolveAbsolute error: %v", err)
+	}
+	if want := filepath.Join(root, "ui_kit/button.psx"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLoadVendorIndex_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		wantType PackageErrorType
+		wantMsg  string
+	}{
+		{
+			name: "nested version conflict",
+			files: map[string]string{
+				"topple_modules/icons/topple-package.json":                       manifest("icons", "1.0.0", ""),
+				"topple_modules/ui_kit/topple-package.json":                      manifest("ui_kit", "1.0.0", `"icons": "^2.0.0"`),
+				"topple_modules/ui_kit/topple_modules/icons/topple-package.json": manifest("icons", "2.0.0", ""),
+			},
+			wantType: VersionConflict,
+			wantMsg:  "version conflict for package 'icons'",
+		},
+		{
+			name: "unsatisfied constraint",
+			files: map[string]string{
+				"topple_modules/icons/topple-package.json":  manifest("icons", "1.4.0", ""),
+				"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", `"icons": "~1.3.0"`),
+			},
+			wantType: VersionConflict,
+			wantMsg:  "~1.3.0 (required by ui_kit@1.0.0)",
+		},
+		{
+			name: "missing dependency",
+			files: map[string]string{
+				"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "1.0.0", `"icons": "*"`),
+			},
+			wantType: MissingDependency,
+			wantMsg:  "missing vendored dependency 'icons'",
+		},
+		{
+			name: "name mismatch",
+			files: map[string]string{
+				"topple_modules/ui_kit/topple-package.json": manifest("other", "1.0.0", ""),
+			},
+			wantType: InvalidManifest,
+			wantMsg:  "does not match directory name",
+		},
+		{
+			name: "invalid version",
+			files: map[string]string{
+				"topple_modules/ui_kit/topple-package.json": manifest("ui_kit", "latest", ""),
+			},
+			wantType: InvalidManifest,
+			wantMsg:  `invalid version "latest"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			index, err := LoadVendorIndex(filesystem.NewFileSystem(nil), filepath.Join(root, VendorDirName))
+			if err != nil {
+				t.Fatalf("LoadVendorIndex error: %v", err)
+			}
+			if len(index.Errors) == 0 {
+				t.Fatalf("expected errors, got none")
+			}
+
+			pkgErr, ok := index.Errors[0].(*PackageError)
+			if !ok {
+				t.Fatalf("expected *PackageError, got %T", index.Errors[0])
+			}
+			if pkgErr.ErrorType != tt.wantType {
+				t.Errorf("ErrorType = %v, want %v", pkgErr.ErrorType, tt.wantType)
+			}
+			if !strings.Contains(pkgErr.Error(), tt.wantMsg) {
+				t.Errorf("error %q does not contain %q", pkgErr.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestLoadVendorIndex_MissingDirectory(t *testing.T) {
+	index, err := LoadVendorIndex(filesystem.NewFileSystem(nil), filepath.Join(t.TempDir(), VendorDirName))
+	if err != nil {
+		t.Fatalf("LoadVendorIndex error: %v", err)
+	}
+	if len(index.Packages) != 0 || len(index.Errors) != 0 {
+		t.Errorf("expected empty index, got %d packages, %d errors", len(index.Packages), len(index.Errors))
+	}
+}
+
+func TestSatisfiesVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "*", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.2.4", false},
+		{"1.5.0", "^1.2.0", true},
+		{"2.0.0", "^1.2.0", false},
+		{"1.1.0", "^1.2.0", false},
+		{"0.2.5", "^0.2.0", true},
+		{"0.3.0", "^0.2.0", false},
+		{"1.2.9", "~1.2.0", true},
+		{"1.3.0", "~1.2.0", false},
+		{"2.0.0", ">=1.0", true},
+		{"1.0.0", ">1.0.0", false},
+		{"1.0.0", "<2", true},
+		{"v1.0.0", "=1.0.0", true},
+		{"1.0.0-beta", "1.0.0", true},
+		{"bogus", "*", true},
+		{"bogus", "1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := SatisfiesVersion(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("SatisfiesVersion(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
@@ -3,18 +3,24 @@ package compiler
 import (
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/codegen"
 	"github.com/fjvillamarin/topple/compiler/depgraph"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/htmlcheck"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/observe"
 	"github.com/fjvillamarin/topple/compiler/parser"
 	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/sourcemap"
 	"github.com/fjvillamarin/topple/compiler/symbol"
 	"github.com/fjvillamarin/topple/compiler/transformers"
 	"github.com/fjvillamarin/topple/internal/filesystem"
@@ -24,7 +30,30 @@ import (
 type MultiFileOptions struct {
 	RootDir     string   // Project root for module resolution
 	Files       []string // Explicit file list (absolute paths)
-	SearchPaths []string // Additional search paths for imports
+	SearchPaths []string // Directories searched for absolute imports after RootDir, in order
+
+	LockMode module.LockMode // How the vendored package lockfile is verified or updated
+	Options  Options         // Scanner and transformer options applied to every file
+	Cache    *BuildCache     // Reuses outputs of unchanged files; nil compiles everything
+
+	// Workers is how many files of a dependency layer are compiled
+	// concurrently; 0 uses GOMAXPROCS
+	Workers int
+
+	// EntryPoints enables dead-code elimination for release builds: only
+	// definitions reachable from them are generated, and files that are
+	// never imported are not output at all
+	EntryPoints []EntryPoint
+
+	// Rebuild restricts an incremental build to these files, compiled in
+	// dependency order; the other files are only parsed for their symbols
+	// and are left out of the output. nil compiles every file.
+	Rebuild []string
+
+	// Graph is the dependency graph of a previous build of the project,
+	// patched in place with the changes instead of being rebuilt from
+	// scratch; nil builds a new graph
+	Graph *depgraph.DependencyGraph
 }
 
 // CompilationError represents an error during multi-file compilation
@@ -42,27 +71,59 @@ func (e *CompilationError) Error() string {
 	return fmt.Sprintf("%s [%s]: %s", e.File, e.Stage, e.Message)
 }
 
+func (e *CompilationError) Unwrap() error {
+	return e.Details
+}
+
 // MultiFileOutput contains the results of multi-file compilation
 type MultiFileOutput struct {
 	CompiledFiles map[string][]byte         // filepath -> generated Python code
 	Registry      *symbol.Registry          // Symbol registry with all exports
 	Graph         *depgraph.DependencyGraph // Dependency graph
 	Errors        []*CompilationError       // All compilation errors
+	Warnings      []*CompilationError       // Diagnostics that did not fail compilation, such as uses of deprecated views
+
+	Lockfile        *module.Lockfile // Lockfile for the vendored packages (nil without packages)
+	LockfilePath    string           // Where the lockfile lives
+	LockfileChanged bool             // Lockfile must be (re)written by the caller
+
+	// Definitions dropped by dead-code elimination, per file; files dropped
+	// entirely map to nil
+	Eliminated map[string][]string
+
+	// Source maps of the generated files, with Options.SourceMaps; the
+	// caller locates them where it writes the code
+	SourceMaps map[string]*sourcemap.Map
+
+	// .pyi stubs of the generated files, with Options.Stubs
+	Stubs map[string][]byte
 }
 
 // MultiFileCompiler compiles multiple interdependent PSX files
 type MultiFileCompiler struct {
-	logger         *slog.Logger
+	logger         observe.Logger
+	metrics        observe.MetricsSink
 	fs             filesystem.FileSystem
 	moduleResolver *module.StandardResolver
 	symbolRegistry *symbol.Registry
 	depGraph       *depgraph.DependencyGraph
+	options        Options
+	cache          *BuildCache
+	build          *Build // In-memory build the compiler belongs to, if any
+
+	// Search paths outside RootDir, and the files imported from them: shared
+	// libraries are parsed for their symbols but compiled by their own builds
+	libraryRoots []string
+	libraries    map[string]bool
+
+	rootDir string // Project root, which generated headers name sources relative to
 }
 
-// NewMultiFileCompiler creates a new multi-file compiler
-func NewMultiFileCompiler(logger *slog.Logger) *MultiFileCompiler {
+// NewMultiFileCompiler creates a new multi-file compiler.
+// A nil logger discards all output.
+func NewMultiFileCompiler(logger observe.Logger) *MultiFileCompiler {
 	return &MultiFileCompiler{
-		logger:         logger,
+		logger:         observe.LoggerOrNop(logger),
 		fs:             nil, // Will be initialized in CompileProject
 		moduleResolver: nil, // Will be initialized in CompileProject
 		symbolRegistry: symbol.NewRegistry(),
@@ -72,6 +133,27 @@ func NewMultiFileCompiler(logger *slog.Logger) *MultiFileCompiler {
 
 // CompileProject compiles multiple PSX files with import resolution
 func (c *MultiFileCompiler) CompileProject(ctx context.Context, opts MultiFileOptions) (*MultiFileOutput, error) {
+	opts.Options.event(observe.Event{Kind: observe.EventBuildStart, Files: len(opts.Files)})
+	output, err := c.compileProject(ctx, opts)
+
+	end := observe.Event{Kind: observe.EventBuildEnd}
+	if output != nil {
+		opts.Options.compilationEvents(output.Errors, diagnostics.Error)
+		opts.Options.compilationEvents(output.Warnings, diagnostics.Warning)
+		end.Files, end.Errors = len(output.CompiledFiles), len(output.Errors)
+	}
+	if err != nil {
+		end.Message = err.Error()
+	}
+	opts.Options.event(end)
+	return output, err
+}
+
+// compileProject runs the stages of CompileProject
+func (c *MultiFileCompiler) compileProject(ctx context.Context, opts MultiFileOptions) (*MultiFileOutput, error) {
+	if opts.Graph != nil {
+		c.depGraph = opts.Graph
+	}
 	output := &MultiFileOutput{
 		CompiledFiles: make(map[string][]byte),
 		Registry:      c.symbolRegistry,
@@ -85,14 +167,67 @@ func (c *MultiFileCompiler) CompileProject(ctx context.Context, opts MultiFileOp
 	}
 
 	c.fs = filesystem.NewFileSystem(c.logger)
+	c.options = opts.Options
+	c.metrics = opts.Options.metrics()
+	c.cache = opts.Cache
+	if opts.Options.Assets != nil || len(opts.EntryPoints) > 0 || opts.Options.SourceMaps || opts.Options.Stubs {
+		// Assets are checked and copied while files are transformed,
+		// dead-code elimination needs every AST, and source maps and stubs
+		// are made by code generation: cached files skip them all
+		c.cache = nil
+	}
+	if opts.Options.SourceMaps {
+		output.SourceMaps = make(map[string]*sourcemap.Map)
+	}
+	if opts.Options.Stubs {
+		output.Stubs = make(map[string][]byte)
+	}
+	c.cache.begin(opts.Options)
 
 	// Create module resolver config
 	resolverConfig := module.Config{
 		RootDir:     opts.RootDir,
 		SearchPaths: opts.SearchPaths,
+		Logger:      c.logger,
 		FileSystem:  c.fs,
+		Imports:     opts.Options.Imports,
 	}
 	c.moduleResolver = module.NewResolver(resolverConfig)
+	c.setLibraryRoots(opts.RootDir, opts.SearchPaths)
+	c.rootDir = opts.RootDir
+	defer func() {
+		c.metrics.Count(observe.CacheHits, int64(c.moduleResolver.CacheHits()))
+	}()
+
+	// Vendored packages must be consistent before anything is resolved against them
+	vendor, err := c.moduleResolver.Vendor()
+	if err != nil {
+		return nil, fmt.Errorf("loading vendored packages failed: %w", err)
+	}
+	if len(vendor.Errors) > 0 {
+		for _, vendorErr := range vendor.Errors {
+			output.Errors = append(output.Errors, &CompilationError{
+				File:    vendor.Root,
+				Stage:   "vendor",
+				Message: "vendored package error",
+				Details: vendorErr,
+			})
+		}
+		return output, fmt.Errorf("vendored packages have %d errors", len(vendor.Errors))
+	}
+
+	lockErrs, err := c.checkLockfile(opts, vendor, output)
+	if err != nil {
+		return nil, fmt.Errorf("lockfile check failed: %w", err)
+	}
+	if len(lockErrs) > 0 {
+		output.Errors = append(output.Errors, lockErrs...)
+		return output, fmt.Errorf("lockfile verification failed with %d errors", len(lockErrs))
+	}
+
+	if err := ctx.Err(); err != nil {
+		return output, cancelledError(err)
+	}
 
 	// Stage 1: Collect all files
 	c.logger.Info("Stage 1: Collecting files")
@@ -101,19 +236,75 @@ func (c *MultiFileCompiler) CompileProject(ctx context.Context, opts MultiFileOp
 		return nil, fmt.Errorf("file collection failed: %w", err)
 	}
 	c.logger.Info("Collected files", "count", len(files))
+	for _, filePath := range files {
+		c.options.event(observe.Event{Kind: observe.EventDiscover, File: filePath})
+	}
+
+	// Unchanged files are only parsed when a changed file needs their symbols
+	cached := make(map[string]layerResult)
+	var changed []string
+	for _, filePath := range files {
+		if result, ok := c.cache.lookup(filePath); ok {
+			cached[filePath] = result
+			c.options.event(observe.Event{Kind: observe.EventCacheHit, File: filePath})
+		} else {
+			changed = append(changed, filePath)
+			if c.cache != nil {
+				c.options.event(observe.Event{Kind: observe.EventCacheMiss, File: filePath})
+			}
+		}
+	}
+	// Vendored modules imported by unchanged files are outputs too
+	pending := make([]string, 0, len(cached))
+	for filePath := range cached {
+		pending = append(pending, filePath)
+	}
+	for len(pending) > 0 {
+		filePath := pending[0]
+		pending = pending[1:]
+		for _, dep := range c.cache.deps(filePath) {
+			if _, seen := cached[dep]; seen {
+				continue
+			}
+			if result, ok := c.cache.lookup(dep); ok {
+				cached[dep] = result
+				pending = append(pending, dep)
+				c.options.event(observe.Event{Kind: observe.EventCacheHit, File: dep})
+			}
+		}
+	}
+	if c.cache != nil {
+		c.logger.Info("Checked build cache", "unchanged", len(cached), "changed", len(changed))
+	}
 
 	// Stage 2: Parse all files to AST
 	c.logger.Info("Stage 2: Parsing all files")
-	astMap, parseErrs := c.parseAllFiles(ctx, files)
+	astMap, parseErrs := c.parseAllFiles(ctx, changed)
+	if err := ctx.Err(); err != nil {
+		return output, cancelledError(err)
+	}
 	if len(parseErrs) > 0 {
 		output.Errors = append(output.Errors, parseErrs...)
 		return output, fmt.Errorf("parsing failed with %d errors", len(parseErrs))
 	}
 	c.logger.Info("Parsed all files", "count", len(astMap))
 
+	// Pull in vendored modules and unchanged files reachable from the changed sources
+	vendorErrs := c.parseDependencies(ctx, astMap, cached)
+	if err := ctx.Err(); err != nil {
+		return output, cancelledError(err)
+	}
+	if len(vendorErrs) > 0 {
+		output.Errors = append(output.Errors, vendorErrs...)
+		return output, fmt.Errorf("parsing vendored modules failed with %d errors", len(vendorErrs))
+	}
+
 	// Stage 3: Build dependency graph
 	c.logger.Info("Stage 3: Building dependency graph")
 	graphErrs := c.buildDependencyGraph(ctx, astMap)
+	if err := ctx.Err(); err != nil {
+		return output, cancelledError(err)
+	}
 	if len(graphErrs) > 0 {
 		output.Errors = append(output.Errors, graphErrs...)
 		return output, fmt.Errorf("dependency graph failed with %d errors", len(graphErrs))
@@ -122,24 +313,77 @@ func (c *MultiFileCompiler) CompileProject(ctx context.Context, opts MultiFileOp
 
 	// Stage 4: Get compilation order (topological sort)
 	c.logger.Info("Stage 4: Computing compilation order")
-	compilationOrder, err := c.depGraph.GetCompilationOrder()
+	layers, err := c.depGraph.GetCompilationLayers()
 	if err != nil {
 		// Circular dependency is fatal
 		return output, fmt.Errorf("circular dependency detected: %w", err)
 	}
-	c.logger.Info("Compilation order computed", "order", compilationOrder)
+	var compilationOrder []string
+	for _, layer := range layers {
+		compilationOrder = append(compilationOrder, layer...)
+	}
+	c.logger.Info("Compilation order computed", "order", compilationOrder, "layers", len(layers))
+	c.build.plan(c.depGraph)
 
 	// Stage 5: Collect symbols from all files (first pass)
 	c.logger.Info("Stage 5: Collecting symbols")
 	c.collectSymbols(ctx, astMap, compilationOrder)
+	if err := ctx.Err(); err != nil {
+		return output, cancelledError(err)
+	}
 	c.logger.Info("Symbols collected")
 
+	if len(opts.EntryPoints) > 0 {
+		c.logger.Info("Eliminating dead code", "entryPoints", len(opts.EntryPoints))
+		pruned, eliminated, err := c.eliminateDeadCode(astMap, opts.EntryPoints)
+		if err != nil {
+			return output, fmt.Errorf("dead-code elimination failed: %w", err)
+		}
+		astMap = pruned
+		output.Eliminated = eliminated
+		c.logger.Info("Dead code eliminated", "files", len(pruned))
+	}
+
+	if len(c.libraries) > 0 {
+		for _, filePath := range compilationOrder {
+			if c.libraries[filePath] {
+				c.cache.track(filePath, c.depGraph.GetDependencies(filePath))
+			}
+		}
+		layers = filterLayers(layers, func(filePath string) bool { return !c.libraries[filePath] })
+	}
+	if opts.Rebuild != nil {
+		layers = restrictLayers(layers, opts.Rebuild)
+		cached = nil
+		c.logger.Info("Restricted build to affected files", "files", len(opts.Rebuild))
+	}
+
 	// Stage 6: Resolve and generate code for each file (second pass)
 	c.logger.Info("Stage 6: Resolving and generating code")
-	compileErrs := c.resolveAndGenerate(ctx, astMap, compilationOrder, output.CompiledFiles)
+	compileErrs := c.resolveAndGenerate(ctx, astMap, layers, opts.Workers, output)
+	if err := ctx.Err(); err != nil {
+		// Partial output of a cancelled build must not be written
+		output.CompiledFiles = make(map[string][]byte)
+		return output, cancelledError(err)
+	}
 	if len(compileErrs) > 0 {
 		output.Errors = append(output.Errors, compileErrs...)
 	}
+	c.build.finish(c.depGraph, c.symbolRegistry)
+	// Sorted, so the warnings they replay come in the same order every time
+	cachedFiles := make([]string, 0, len(cached))
+	for filePath := range cached {
+		cachedFiles = append(cachedFiles, filePath)
+	}
+	sort.Strings(cachedFiles)
+	for _, filePath := range cachedFiles {
+		if _, compiled := output.CompiledFiles[filePath]; !compiled {
+			result := cached[filePath]
+			c.addResult(output, filePath, result)
+			c.metrics.Count(observe.Cached, 1)
+			c.options.event(observe.Event{Kind: observe.EventEmit, File: filePath, Bytes: len(result.code), Cached: true})
+		}
+	}
 	c.logger.Info("Code generation complete", "files", len(output.CompiledFiles))
 
 	if len(output.Errors) > 0 {
@@ -149,6 +393,158 @@ func (c *MultiFileCompiler) CompileProject(ctx context.Context, opts MultiFileOp
 	return output, nil
 }
 
+// restrictLayers keeps the files of each compilation layer that are listed,
+// dropping layers left empty
+func restrictLayers(layers [][]string, files []string) [][]string {
+	keep := make(map[string]bool, len(files))
+	for _, filePath := range files {
+		keep[filePath] = true
+	}
+	return filterLayers(layers, func(filePath string) bool { return keep[filePath] })
+}
+
+// filterLayers keeps the files of each compilation layer that keep accepts,
+// dropping layers left empty
+func filterLayers(layers [][]string, keep func(string) bool) [][]string {
+	var filtered [][]string
+	for _, layer := range layers {
+		var kept []string
+		for _, filePath := range layer {
+			if keep(filePath) {
+				kept = append(kept, filePath)
+			}
+		}
+		if len(kept) > 0 {
+			filtered = append(filtered, kept)
+		}
+	}
+	return filtered
+}
+
+// setLibraryRoots records the search paths that lie outside the project
+// root; modules resolved under them are shared libraries
+func (c *MultiFileCompiler) setLibraryRoots(rootDir string, searchPaths []string) {
+	c.libraryRoots, c.libraries = nil, make(map[string]bool)
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return
+	}
+	for _, searchPath := range searchPaths {
+		absPath, err := filepath.Abs(searchPath)
+		if err != nil || isWithin(absRoot, absPath) {
+			continue
+		}
+		c.libraryRoots = append(c.libraryRoots, absPath)
+	}
+}
+
+// relativeSource returns the path of a source file relative to the project
+// root, or its name when it is outside it
+func (c *MultiFileCompiler) relativeSource(filePath string) string {
+	root, err := filepath.Abs(c.rootDir)
+	if err != nil {
+		return filepath.Base(filePath)
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.Base(filePath)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Base(filePath)
+	}
+	return rel
+}
+
+// isLibrary reports whether filePath lies under a search path outside the
+// project root
+func (c *MultiFileCompiler) isLibrary(filePath string) bool {
+	for _, root := range c.libraryRoots {
+		if isWithin(root, filePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExternal reports whether filePath comes from outside the project: a
+// vendored package or a library on a search path
+func (c *MultiFileCompiler) isExternal(filePath string) bool {
+	return c.isLibrary(filePath) || module.IsVendoredPath(filePath)
+}
+
+// isWithin reports whether path is dir or lies under it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cancelledError wraps the context error of an aborted build
+func cancelledError(err error) error {
+	return fmt.Errorf("compilation cancelled: %w", err)
+}
+
+// checkLockfile verifies the vendored packages against the project lockfile,
+// or prepares a new lockfile for the caller to write, depending on opts.LockMode.
+func (c *MultiFileCompiler) checkLockfile(opts MultiFileOptions, vendor *module.VendorIndex, output *MultiFileOutput) ([]*CompilationError, error) {
+	lockPath := c.fs.JoinPaths(opts.RootDir, module.LockfileName)
+	output.LockfilePath = lockPath
+
+	exists, err := c.fs.Exists(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	if !exists && len(vendor.Packages) == 0 {
+		return nil, nil
+	}
+
+	current, err := module.GenerateLockfile(c.fs, vendor)
+	if err != nil {
+		return nil, err
+	}
+	output.Lockfile = current
+
+	if opts.LockMode == module.LockUpdate || (!exists && opts.LockMode == module.LockAuto) {
+		output.LockfileChanged = true
+		c.logger.Info("Lockfile will be written", "path", lockPath, "packages", len(current.Packages))
+		return nil, nil
+	}
+
+	if !exists {
+		return []*CompilationError{{
+			File:    lockPath,
+			Stage:   "lockfile",
+			Message: "lockfile is missing; run with --lockfile=update to create it",
+		}}, nil
+	}
+
+	locked, err := module.ReadLockfile(c.fs, lockPath)
+	if err != nil {
+		return []*CompilationError{{
+			File:    lockPath,
+			Stage:   "lockfile",
+			Message: "failed to read lockfile",
+			Details: err,
+		}}, nil
+	}
+
+	verifyErrs, err := module.VerifyLockfile(c.fs, vendor, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	errors := make([]*CompilationError, 0, len(verifyErrs))
+	for _, verifyErr := range verifyErrs {
+		errors = append(errors, &CompilationError{
+			File:    lockPath,
+			Stage:   "lockfile",
+			Message: "lockfile mismatch",
+			Details: verifyErr,
+		})
+	}
+	return errors, nil
+}
+
 // collectAllFiles expands file paths and directories to a list of .psx files
 func (c *MultiFileCompiler) collectAllFiles(files []string) ([]string, error) {
 	result := []string{}
@@ -171,6 +567,10 @@ func (c *MultiFileCompiler) collectAllFiles(files []string) ([]string, error) {
 				if err != nil {
 					return err
 				}
+				// Vendored packages are only compiled when imported
+				if info.IsDir() && info.Name() == module.VendorDirName {
+					return filepath.SkipDir
+				}
 				if !info.IsDir() && strings.HasSuffix(p, ".psx") {
 					if !seen[p] {
 						result = append(result, p)
@@ -182,7 +582,7 @@ func (c *MultiFileCompiler) collectAllFiles(files []string) ([]string, error) {
 			if err != nil {
 				return nil, fmt.Errorf("walking directory %s: %w", absPath, err)
 			}
-		} else if strings.HasSuffix(absPath, ".psx") {
+		} else if strings.HasSuffix(absPath, ".psx") && !module.IsVendoredPath(absPath) {
 			if !seen[absPath] {
 				result = append(result, absPath)
 				seen[absPath] = true
@@ -203,50 +603,33 @@ func (c *MultiFileCompiler) parseAllFiles(ctx context.Context, files []string) (
 	errors := []*CompilationError{}
 
 	for _, filePath := range files {
-		// Read file
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			errors = append(errors, &CompilationError{
-				File:    filePath,
-				Stage:   "parse",
-				Message: "failed to read file",
-				Details: err,
-			})
-			continue
+		if ctx.Err() != nil {
+			break
 		}
 
-		// Lex
-		scanner := lexer.NewScanner(content)
-		tokens := scanner.ScanTokens()
-		if len(scanner.Errors) > 0 {
-			for _, lexErr := range scanner.Errors {
-				errors = append(errors, &CompilationError{
-					File:    filePath,
-					Stage:   "parse",
-					Message: "lexer error",
-					Details: lexErr,
-				})
+		module, kept := c.build.module(filePath)
+		if !kept {
+			var fileErrors []*CompilationError
+			module, fileErrors = c.parseFile(ctx, filePath)
+			if ctx.Err() != nil {
+				break
 			}
-			continue
+			if len(fileErrors) > 0 {
+				errors = append(errors, fileErrors...)
+				continue
+			}
+
+			c.metrics.Count(observe.Nodes, countNodes(module))
+			c.build.parsed(filePath, module)
 		}
 
-		// Parse
-		p := parser.NewParser(tokens)
-		module, parseErrors := p.Parse()
-		if len(parseErrors) > 0 {
-			for _, parseErr := range parseErrors {
-				errors = append(errors, &CompilationError{
-					File:    filePath,
-					Stage:   "parse",
-					Message: "parser error",
-					Details: parseErr,
-				})
-			}
+		// Add to graph; a file kept in the graph of a previous build is
+		// updated with its imports once every file is parsed
+		if c.depGraph.HasFile(filePath) {
+			astMap[filePath] = module
 			continue
 		}
-
-		// Add to graph
-		err = c.depGraph.AddFile(filePath, module)
+		err := c.depGraph.AddFile(filePath, module)
 		if err != nil {
 			errors = append(errors, &CompilationError{
 				File:    filePath,
@@ -263,14 +646,164 @@ func (c *MultiFileCompiler) parseAllFiles(ctx context.Context, files []string) (
 	return astMap, errors
 }
 
-// buildDependencyGraph extracts imports and builds the dependency graph
+// parseFile reads, scans and parses a single file. A panic in the scanner
+// or parser is reported as an internal error of that file.
+func (c *MultiFileCompiler) parseFile(ctx context.Context, filePath string) (module *ast.Module, errors []*CompilationError) {
+	site := &crashSite{file: filePath, stage: "scan"}
+	clock := startClock(c.options.timings(), filePath, observe.StageScan)
+	defer clock.stop()
+	defer func() {
+		if r := recover(); r != nil {
+			module, errors = nil, []*CompilationError{{
+				File:    filePath,
+				Stage:   "parse",
+				Message: "internal compiler error",
+				Details: site.internalError(r),
+			}}
+		}
+	}()
+
+	// Read file
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, []*CompilationError{{
+			File:    filePath,
+			Stage:   "parse",
+			Message: "failed to read file",
+			Details: err,
+		}}
+	}
+	site.content = content
+
+	// Lex
+	scanner := lexer.NewScannerWithConfig(content, c.options.ScannerConfig())
+	site.where = func() lexer.Span {
+		pos := scanner.Position()
+		return lexer.Span{Start: pos, End: pos}
+	}
+	tokens, err := scanner.ScanTokensContext(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	c.metrics.Count(observe.Tokens, int64(len(tokens)))
+	if len(scanner.Errors) > 0 {
+		for _, lexErr := range scanner.Errors {
+			errors = append(errors, &CompilationError{
+				File:    filePath,
+				Stage:   "parse",
+				Message: "lexer error",
+				Details: lexErr,
+			})
+		}
+		return nil, errors
+	}
+
+	// Parse
+	site.stage, site.tokens = "parse", tokens
+	clock.next(observe.StageParse)
+	p := parser.NewParser(tokens)
+	p.Syntax = scanner.Syntax()
+	site.where = func() lexer.Span { return tokenSpan(p.Tokens, p.Current) }
+	module, parseErrors := p.ParseContext(ctx)
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	for _, parseErr := range parseErrors {
+		errors = append(errors, &CompilationError{
+			File:    filePath,
+			Stage:   "parse",
+			Message: "parser error",
+			Details: parseErr,
+		})
+	}
+	if len(errors) > 0 {
+		return nil, errors
+	}
+	logInputStats(c.logger, filePath, tokens, module)
+	return module, nil
+}
+
+// parseDependencies parses the files imported (transitively) by the files
+// in astMap that were not parsed yet, and adds them to astMap and the
+// dependency graph: vendored modules, modules of shared libraries on the
+// search paths, and cached project files whose symbols the changed files
+// need.
+func (c *MultiFileCompiler) parseDependencies(ctx context.Context, astMap map[string]*ast.Module, cached map[string]layerResult) []*CompilationError {
+	vendor, err := c.moduleResolver.Vendor()
+	if err != nil || vendor == nil {
+		vendor = &module.VendorIndex{}
+	}
+	if len(vendor.Packages) == 0 && len(cached) == 0 && len(c.libraryRoots) == 0 {
+		return nil
+	}
+
+	errors := []*CompilationError{}
+	pending := make([]string, 0, len(astMap))
+	for filePath := range astMap {
+		pending = append(pending, filePath)
+	}
+	sort.Strings(pending)
+
+	for len(pending) > 0 {
+		filePath := pending[0]
+		pending = pending[1:]
+
+		imports, err := depgraph.ExtractImports(astMap[filePath], filePath, c.moduleResolver)
+		if err != nil {
+			continue
+		}
+
+		var needed []string
+		seen := make(map[string]bool)
+		for _, imp := range imports {
+			if _, parsed := astMap[imp.ModulePath]; parsed || seen[imp.ModulePath] {
+				continue
+			}
+			_, isCached := cached[imp.ModulePath]
+			isLibrary := c.isLibrary(imp.ModulePath)
+			if _, isVendored := vendor.PackageFor(imp.ModulePath); isVendored || isCached || isLibrary {
+				needed = append(needed, imp.ModulePath)
+				seen[imp.ModulePath] = true
+			}
+			if isLibrary {
+				c.libraries[imp.ModulePath] = true
+			}
+		}
+		if len(needed) == 0 {
+			continue
+		}
+
+		parsed, parseErrs := c.parseAllFiles(ctx, needed)
+		errors = append(errors, parseErrs...)
+		for vendoredPath, mod := range parsed {
+			astMap[vendoredPath] = mod
+			pending = append(pending, vendoredPath)
+		}
+	}
+
+	return errors
+}
+
+// buildDependencyGraph extracts imports and builds the dependency graph.
+// A graph kept from a previous build is updated in place: files no longer
+// in astMap are removed, and the others get their current imports.
 func (c *MultiFileCompiler) buildDependencyGraph(ctx context.Context, astMap map[string]*ast.Module) []*CompilationError {
 	errors := []*CompilationError{}
+	c.depGraph.SetResolver(c.moduleResolver)
+
+	for _, filePath := range c.depGraph.GetAllFiles() {
+		if _, exists := astMap[filePath]; !exists {
+			c.depGraph.RemoveFile(filePath)
+		}
+	}
 
 	for filePath, module := range astMap {
-		// Extract imports from AST
-		imports, err := depgraph.ExtractImports(module, filePath, c.moduleResolver)
-		if err != nil {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Extract imports from AST and replace the file's dependencies
+		if err := c.depGraph.UpdateFile(filePath, module); err != nil {
 			errors = append(errors, &CompilationError{
 				File:    filePath,
 				Stage:   "dependency",
@@ -279,24 +812,37 @@ func (c *MultiFileCompiler) buildDependencyGraph(ctx context.Context, astMap map
 			})
 			continue
 		}
+	}
 
-		// Add dependencies to graph
-		for _, imp := range imports {
-			err := c.depGraph.AddDependency(filePath, imp.ModulePath)
-			if err != nil {
+	// Every import must resolve to a file of the graph
+	filePaths := make([]string, 0, len(astMap))
+	for filePath := range astMap {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		node, exists := c.depGraph.GetFileNode(filePath)
+		if !exists {
+			continue
+		}
+		for _, imp := range node.Imports {
+			if !c.depGraph.HasFile(imp.ModulePath) {
 				errors = append(errors, &CompilationError{
 					File:    filePath,
 					Stage:   "dependency",
 					Message: fmt.Sprintf("failed to add dependency to %s", imp.ModulePath),
-					Details: err,
+					Details: fmt.Errorf("target file not in graph: %s", imp.ModulePath),
 				})
+				continue
 			}
-		}
-
-		// Store imports in file node
-		node, exists := c.depGraph.GetFileNode(filePath)
-		if exists {
-			node.Imports = imports
+			c.options.event(observe.Event{
+				Kind:     observe.EventResolve,
+				File:     filePath,
+				Import:   imp.ModulePath,
+				Deferred: imp.Deferred,
+				Line:     imp.Location.Line,
+				Column:   imp.Location.Column,
+			})
 		}
 	}
 
@@ -307,8 +853,12 @@ func (c *MultiFileCompiler) buildDependencyGraph(ctx context.Context, astMap map
 // Symbol collection is infallible - AST is validated by parser, conflicts caught by resolver
 func (c *MultiFileCompiler) collectSymbols(ctx context.Context, astMap map[string]*ast.Module, compilationOrder []string) {
 	for _, filePath := range compilationOrder {
+		if ctx.Err() != nil {
+			return
+		}
+
 		module, exists := astMap[filePath]
-		if !exists {
+		if !exists || c.build.unchanged(filePath) {
 			continue
 		}
 
@@ -321,81 +871,294 @@ func (c *MultiFileCompiler) collectSymbols(ctx context.Context, astMap map[strin
 	}
 }
 
-// resolveAndGenerate resolves and generates code for each file
+// resolveAndGenerate resolves and generates code for each file. The files
+// of a layer do not depend on each other and are compiled concurrently by up
+// to workers goroutines; results are collected in layer order, so outputs
+// and diagnostics do not depend on scheduling.
 func (c *MultiFileCompiler) resolveAndGenerate(
 	ctx context.Context,
 	astMap map[string]*ast.Module,
-	compilationOrder []string,
-	output map[string][]byte,
+	layers [][]string,
+	workers int,
+	output *MultiFileOutput,
 ) []*CompilationError {
 	errors := []*CompilationError{}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// Files whose deferred imports lead back to them are cached once the
+	// whole cycle is compiled
+	var unstored []string
+	unstoredResults := make(map[string]layerResult)
 
-	for _, filePath := range compilationOrder {
-		module, exists := astMap[filePath]
-		if !exists {
-			continue
+	for _, layer := range layers {
+		if ctx.Err() != nil {
+			break
 		}
 
-		// Compile this file with full import context
-		code, err := c.compileFile(ctx, filePath, module)
-		if err != nil {
-			errors = append(errors, err)
-			continue
+		var pending []string
+		for _, filePath := range layer {
+			if _, exists := astMap[filePath]; !exists {
+				continue
+			}
+			if result, ok := c.cache.lookup(filePath); ok {
+				c.addResult(output, filePath, result)
+				c.metrics.Count(observe.Cached, 1)
+				c.options.event(observe.Event{Kind: observe.EventEmit, File: filePath, Bytes: len(result.code), Cached: true})
+				continue
+			}
+			if result, ok := c.build.lookup(filePath); ok {
+				c.options.event(observe.Event{Kind: observe.EventCacheHit, File: filePath})
+				c.addResult(output, filePath, result)
+				c.metrics.Count(observe.Cached, 1)
+				c.options.event(observe.Event{Kind: observe.EventEmit, File: filePath, Bytes: len(result.code), Cached: true})
+				continue
+			}
+			pending = append(pending, filePath)
+		}
+
+		results := c.compileLayer(ctx, astMap, pending, workers)
+		for i, filePath := range pending {
+			result := results[i]
+			if result.err != nil {
+				c.cache.forget(filePath)
+				c.build.forget(filePath)
+				errors = append(errors, result.err)
+				continue
+			}
+
+			c.addResult(output, filePath, result)
+			c.metrics.Count(observe.Files, 1)
+			c.options.event(observe.Event{Kind: observe.EventEmit, File: filePath, Bytes: len(result.code)})
+			if err := c.cache.store(filePath, c.depGraph.GetDependencies(filePath), result); err != nil {
+				unstored = append(unstored, filePath)
+				unstoredResults[filePath] = result
+			}
+			c.build.store(filePath, sortedDependencies(c.depGraph, filePath), result)
 		}
+	}
 
-		output[filePath] = code
+	for _, filePath := range unstored {
+		if err := c.cache.store(filePath, c.depGraph.GetDependencies(filePath), unstoredResults[filePath]); err != nil {
+			c.logger.Warn("Could not cache output", "file", filePath, "error", err)
+		}
 	}
 
 	return errors
 }
 
-// compileFile compiles a single file with full import context
-func (c *MultiFileCompiler) compileFile(ctx context.Context, filePath string, module *ast.Module) ([]byte, *CompilationError) {
+// addResult adds the code, source map, stub and warnings of a compiled file
+// to output
+func (c *MultiFileCompiler) addResult(output *MultiFileOutput, filePath string, result layerResult) {
+	for _, warning := range result.warnings {
+		c.logger.Warn("Compilation warning", "file", filePath, "warning", warning)
+		output.Warnings = append(output.Warnings, &CompilationError{
+			File:    filePath,
+			Stage:   warningStage(warning),
+			Message: "warning",
+			Details: warning,
+		})
+	}
+	output.CompiledFiles[filePath] = result.code
+	if output.SourceMaps != nil {
+		output.SourceMaps[filePath] = result.sourceMap
+	}
+	if output.Stubs != nil {
+		output.Stubs[filePath] = result.stub
+	}
+}
+
+// warningStage returns the stage that reported a warning
+func warningStage(warning error) string {
+	switch w := warning.(type) {
+	case *htmlcheck.Problem:
+		return "parse"
+	case *cachedWarning:
+		return w.Stage
+	}
+	return "resolve"
+}
+
+// layerResult is the outcome of compiling one file of a layer
+type layerResult struct {
+	code      []byte
+	sourceMap *sourcemap.Map
+	stub      []byte // With Options.Stubs
+	warnings  []error
+	err       *CompilationError
+}
+
+// compileLayer compiles files concurrently and returns their results in the
+// order of files
+func (c *MultiFileCompiler) compileLayer(ctx context.Context, astMap map[string]*ast.Module, files []string, workers int) []layerResult {
+	results := make([]layerResult, len(files))
+	if len(files) == 0 {
+		return results
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = c.compileFile(ctx, files[i], astMap[files[i]])
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
+
+// compileFile compiles a single file with full import context, returning
+// the generated code, its source map and stub, and the warnings of
+// resolution
+func (c *MultiFileCompiler) compileFile(ctx context.Context, filePath string, module *ast.Module) (result layerResult) {
+	site := &crashSite{file: filePath, stage: "resolve"}
+	clock := startClock(c.options.timings(), filePath, observe.StageResolve)
+	defer clock.stop()
+	defer func() {
+		if r := recover(); r != nil {
+			result = layerResult{err: &CompilationError{
+				File:    filePath,
+				Stage:   site.stage,
+				Message: "internal compiler error",
+				Details: site.internalError(r),
+			}}
+		}
+	}()
+
 	// Create resolver with import context
 	res := resolver.NewResolverWithDeps(c.moduleResolver, c.symbolRegistry, filePath)
+	res.StrictProps = c.options.StrictProps
+	if !c.isExternal(filePath) {
+		// Dependencies from outside the project follow their own conventions
+		res.Naming = c.options.Naming
+	}
+
+	// Check markup; dependencies from outside the project are not checked
+	var warnings []error
+	if !c.options.SkipHTMLCheck && !c.isExternal(filePath) {
+		errs, markupWarnings := htmlcheck.Check(module)
+		if len(errs) > 0 {
+			return layerResult{err: &CompilationError{
+				File:    filePath,
+				Stage:   "parse",
+				Message: fmt.Sprintf("markup check failed with %d errors", len(errs)),
+				Details: errorList(errs),
+			}}
+		}
+		warnings = markupWarnings
+	}
 
 	// Resolve
-	resolutionTable, err := res.Resolve(module)
+	resolutionTable, err := res.ResolveContext(ctx, module)
 	if err != nil || (resolutionTable != nil && len(resolutionTable.Errors) > 0) {
 		// Aggregate all resolution errors
 		if resolutionTable != nil && len(resolutionTable.Errors) > 0 {
-			var errMsg strings.Builder
-			for i, resErr := range resolutionTable.Errors {
-				if i > 0 {
-					errMsg.WriteString("; ")
-				}
-				errMsg.WriteString(resErr.Error())
-			}
-			return nil, &CompilationError{
+			return layerResult{err: &CompilationError{
 				File:    filePath,
 				Stage:   "resolve",
 				Message: fmt.Sprintf("resolution failed with %d errors", len(resolutionTable.Errors)),
-				Details: fmt.Errorf("%s", errMsg.String()),
-			}
+				Details: errorList(resolutionTable.Errors),
+			}}
 		}
-		return nil, &CompilationError{
+		return layerResult{err: &CompilationError{
 			File:    filePath,
 			Stage:   "resolve",
 			Message: "resolution failed",
 			Details: err,
-		}
+		}}
 	}
 
 	// Transform
-	transformer := transformers.NewTransformerVisitor()
-	transformedModule, err := transformer.TransformModule(module, resolutionTable)
+	site.stage = "transform"
+	clock.next(observe.StageTransform)
+	transformerOptions := c.options.TransformerOptions()
+	transformerOptions.SourceFile = filePath
+	transformer := transformers.NewTransformerVisitorWithOptions(transformerOptions)
+	transformedModule, err := transformer.TransformModuleContext(ctx, module, resolutionTable)
 	if err != nil {
-		return nil, &CompilationError{
+		return layerResult{err: &CompilationError{
 			File:    filePath,
 			Stage:   "transform",
 			Message: "transformation failed",
 			Details: err,
-		}
+		}}
 	}
 
-	// Generate code
+	// Point imports of other compiled files at their outputs
+	site.stage = "relocate"
+	clock.next(observe.StageRelocate)
+	transformedModule, err = c.relocateImports(filePath, transformedModule)
+	if err != nil {
+		return layerResult{err: &CompilationError{
+			File:    filePath,
+			Stage:   "relocate",
+			Message: "import relocation failed",
+			Details: err,
+		}}
+	}
+
+	// Generate code for the target Python version, and the stub of the
+	// module as written
+	site.stage = "codegen"
+	clock.next(observe.StageCodegen)
+	var stub []byte
+	if c.options.Stubs {
+		if stub, err = GenerateStub(transformedModule, c.options.Target); err != nil {
+			return layerResult{err: &CompilationError{
+				File:    filePath,
+				Stage:   "codegen",
+				Message: "stub generation failed",
+				Details: err,
+			}}
+		}
+	}
+	transformedModule, err = codegen.Lower(transformedModule, c.options.Target)
+	if err != nil {
+		return layerResult{err: &CompilationError{
+			File:    filePath,
+			Stage:   "codegen",
+			Message: "lowering for " + c.options.Target.String() + " failed",
+			Details: err,
+		}}
+	}
 	generator := codegen.NewCodeGenerator()
-	code := generator.Generate(transformedModule)
+	generator.LineDirectives = c.options.lineDirectives(filePath)
+	generator.Target = c.options.Target
+	generated, err := generator.GenerateContext(ctx, transformedModule)
+	if err != nil {
+		return layerResult{err: &CompilationError{
+			File:    filePath,
+			Stage:   "codegen",
+			Message: "code generation failed",
+			Details: err,
+		}}
+	}
 
-	return []byte(code), nil
+	sourceMap := generator.SourceMap()
+	code := []byte(generated)
+	if len(c.options.Header.Lines) > 0 {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return layerResult{err: &CompilationError{
+				File:    filePath,
+				Stage:   "codegen",
+				Message: "failed to read file for its header",
+				Details: err,
+			}}
+		}
+		code = c.options.WithHeader(code, sourceMap, c.relativeSource(filePath), content)
+		if stub != nil {
+			stub = c.options.WithHeader(stub, nil, c.relativeSource(filePath), content)
+		}
+	}
+	return layerResult{code: code, sourceMap: sourceMap, stub: stub, warnings: append(warnings, resolutionTable.Warnings...)}
 }
@@ -2,11 +2,16 @@ package compiler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/observe"
 )
 
 // setupTestFiles creates temporary test files for multi-file compilation tests
@@ -329,6 +334,48 @@ def func_b():
 	}
 }
 
+func TestMultiFileCompiler_FunctionLocalCircularImport(t *testing.T) {
+	// a.psx imports b at the top level
+	// b.psx imports a inside a function, which Python only runs when called
+	files := map[string]string{
+		"a.psx": `
+import b
+
+def func_a():
+    return b.func_b()
+`,
+		"b.psx": `
+def func_b():
+    import a
+    return a.func_a
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	compiler := NewMultiFileCompiler(logger)
+
+	output, err := compiler.CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files: []string{
+			filepath.Join(tmpDir, "a.psx"),
+			filepath.Join(tmpDir, "b.psx"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("function-local circular import should compile, got: %v", err)
+	}
+	if len(output.CompiledFiles) != 2 {
+		t.Errorf("expected 2 compiled files, got %d", len(output.CompiledFiles))
+	}
+
+	// b.psx is still recompiled when a.psx changes
+	affected := output.Graph.GetAffected([]string{filepath.Join(tmpDir, "a.psx")})
+	if _, ok := affected[filepath.Join(tmpDir, "b.psx")]; !ok {
+		t.Errorf("b.psx should be affected by a change to a.psx, got %v", affected)
+	}
+}
+
 func TestMultiFileCompiler_ThreeWayCircular(t *testing.T) {
 	// a → b → c → a (circular)
 	files := map[string]string{
@@ -452,6 +499,45 @@ def main():
 	}
 }
 
+func TestMultiFileCompiler_ParallelLayers(t *testing.T) {
+	files := map[string]string{
+		"base.psx":    "def base_func():\n    return \"base\"\n",
+		"broken1.psx": "view Broken():\n    <Markdown class=\"x\">*text*</Markdown>\n",
+		"broken2.psx": "view Broken():\n    <Markdown id=\"y\">*text*</Markdown>\n",
+		"main.psx":    "import a\nimport b\nimport c\n",
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		files[name+".psx"] = "import base\n\nview " + strings.ToUpper(name) + "():\n    <p>{base.base_func()}</p>\n"
+	}
+	tmpDir := setupTestFiles(t, files)
+
+	compile := func(workers int) (*MultiFileOutput, error) {
+		return NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{Markdown: true},
+			Workers: workers,
+		})
+	}
+
+	sequential, seqErr := compile(1)
+	parallel, parErr := compile(8)
+	if seqErr == nil || parErr == nil {
+		t.Fatal("expected the broken files to fail both builds")
+	}
+	if seqErr.Error() != parErr.Error() {
+		t.Errorf("diagnostics depend on the worker count:\n%v\n%v", seqErr, parErr)
+	}
+	if len(parallel.CompiledFiles) != 5 {
+		t.Errorf("expected 5 compiled files, got %d", len(parallel.CompiledFiles))
+	}
+	for path, code := range sequential.CompiledFiles {
+		if string(parallel.CompiledFiles[path]) != string(code) {
+			t.Errorf("parallel output of %s differs from the sequential one", path)
+		}
+	}
+}
+
 func TestMultiFileCompiler_DirectoryInput(t *testing.T) {
 	files := map[string]string{
 		"file1.psx":        `def func1(): return 1`,
@@ -653,6 +739,204 @@ view RepoCard(repo: dict):
 	}
 }
 
+func TestMultiFileCompiler_DeprecatedViews(t *testing.T) {
+	files := map[string]string{
+		"components.psx": `
+@deprecated("use NewButton")
+view Button(label: str):
+    <button>{label}</button>
+
+view NewButton(label: str):
+    <button class="btn">{label}</button>
+
+@deprecated
+def old_format(value):
+    return str(value)
+
+view Toolbar():
+    <Button label="Save" />
+`,
+		"page.psx": `
+from components import Button, old_format
+
+view Page():
+    <Button label="Go" />
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	compiler := NewMultiFileCompiler(logger)
+
+	componentsPath := filepath.Join(tmpDir, "components.psx")
+	pagePath := filepath.Join(tmpDir, "page.psx")
+	output, err := compiler.CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{componentsPath, pagePath},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v", err)
+	}
+
+	var warnings []string
+	for _, warning := range output.Warnings {
+		warnings = append(warnings, filepath.Base(warning.File)+": "+warning.Details.Error())
+	}
+	expected := []string{
+		"components.psx: 'Button' is deprecated: use NewButton (position L14:6-L14:12)",
+		"page.psx: 'Button' is deprecated: use NewButton (position L2:24-L2:30)",
+		"page.psx: 'old_format' is deprecated (position L2:32-L2:42)",
+		"page.psx: 'Button' is deprecated: use NewButton (position L5:6-L5:12)",
+	}
+	if strings.Join(warnings, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected warnings:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(warnings, "\n"))
+	}
+
+	componentsCode := string(output.CompiledFiles[componentsPath])
+	if strings.Contains(componentsCode, "deprecated") {
+		t.Errorf("Expected the @deprecated marker to be stripped, got:\n%s", componentsCode)
+	}
+	if !strings.Contains(componentsCode, "class Button(BaseView)") || !strings.Contains(componentsCode, "def old_format(value)") {
+		t.Errorf("Expected deprecated definitions to be compiled, got:\n%s", componentsCode)
+	}
+}
+
+// A module binding deprecated, such as PEP 702's, calls it at import time:
+// its @deprecated decorators are kept and warn nothing at compile time
+func TestMultiFileCompiler_ImportedDeprecated(t *testing.T) {
+	files := map[string]string{
+		"helpers.psx": `
+from typing_extensions import deprecated
+
+@deprecated("use g")
+def f():
+    return 1
+
+def g():
+    return 2
+`,
+		"page.psx": `
+from helpers import f
+
+view Page():
+    <p>{f()}</p>
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	helpersPath := filepath.Join(tmpDir, "helpers.psx")
+	output, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{helpersPath, filepath.Join(tmpDir, "page.psx")},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected no deprecation warnings, got %v", output.Warnings)
+	}
+	code := string(output.CompiledFiles[helpersPath])
+	for _, expected := range []string{"from typing_extensions import deprecated\n", "@deprecated(\"use g\")\ndef f():"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, code)
+		}
+	}
+}
+
+func TestMultiFileCompiler_HTMLCheck(t *testing.T) {
+	files := map[string]string{
+		"page.psx": `
+view Page():
+    <p>
+        <div>block</div>
+    </p>
+`,
+		"broken.psx": `
+view Broken():
+    <img src="a.png">caption</img>
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	opts := MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{filepath.Join(tmpDir, "page.psx"), filepath.Join(tmpDir, "broken.psx")},
+	}
+
+	output, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), opts)
+	if err == nil {
+		t.Fatal("Expected an error for the void element with content")
+	}
+	if len(output.Errors) != 1 || filepath.Base(output.Errors[0].File) != "broken.psx" || output.Errors[0].Stage != "parse" {
+		t.Fatalf("Expected one markup error in broken.psx, got %v", output.Errors)
+	}
+	found := diagnostics.Collect(output.Errors[0])
+	if len(found) != 1 || found[0].Code != diagnostics.CodeVoidContent || found[0].Span.Start.Line != 3 {
+		t.Fatalf("Expected an %s diagnostic on line 3, got %v", diagnostics.CodeVoidContent, found)
+	}
+	if len(output.Warnings) != 1 || output.Warnings[0].Stage != "parse" {
+		t.Fatalf("Expected one markup warning, got %v", output.Warnings)
+	}
+	found = diagnostics.Collect(output.Warnings[0])
+	if len(found) != 1 || found[0].Code != diagnostics.CodeInvalidNesting || found[0].Span.Start.Line != 4 {
+		t.Fatalf("Expected an %s diagnostic on line 4, got %v", diagnostics.CodeInvalidNesting, found)
+	}
+
+	opts.Options.SkipHTMLCheck = true
+	output, err = NewMultiFileCompiler(logger).CompileProject(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Expected SkipHTMLCheck to accept the markup, got: %v", err)
+	}
+	if len(output.Warnings) != 0 {
+		t.Errorf("Expected no warnings with SkipHTMLCheck, got %v", output.Warnings)
+	}
+}
+
+func TestMultiFileCompiler_StrictProps(t *testing.T) {
+	files := map[string]string{
+		"components.psx": `
+view Counter(label: str, count: int = 0):
+    <span>{label}: {count}</span>
+`,
+		"page.psx": `
+from components import Counter
+
+view Page():
+    <Counter label="Items" count="5" />
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	opts := MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{filepath.Join(tmpDir, "components.psx"), filepath.Join(tmpDir, "page.psx")},
+	}
+
+	if _, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), opts); err != nil {
+		t.Fatalf("Expected the mismatch to be accepted without StrictProps, got: %v", err)
+	}
+
+	opts.Options.StrictProps = true
+	output, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), opts)
+	if err == nil {
+		t.Fatal("Expected a prop type error with StrictProps")
+	}
+	if len(output.Errors) != 1 || filepath.Base(output.Errors[0].File) != "page.psx" || output.Errors[0].Stage != "resolve" {
+		t.Fatalf("Expected one resolution error in page.psx, got %v", output.Errors)
+	}
+	found := diagnostics.Collect(output.Errors[0])
+	if len(found) != 1 || found[0].Code != diagnostics.CodePropType || found[0].Span.Start.Line != 5 {
+		t.Fatalf("Expected an %s diagnostic on line 5, got %v", diagnostics.CodePropType, found)
+	}
+	if want := "prop 'count' of Counter expects int, got str"; found[0].Message != want {
+		t.Errorf("Expected %q, got %q", want, found[0].Message)
+	}
+}
+
 func TestMultiFileCompiler_CrossFileViewImport_MultipleViews(t *testing.T) {
 	// R2: Multiple view imports from the same file
 	files := map[string]string{
@@ -763,6 +1047,78 @@ view LoginPage():
 	}
 }
 
+func TestMultiFileCompiler_WildcardImportReexports(t *testing.T) {
+	// A package re-exports views of its modules, one of them through a
+	// wildcard import, and limits what "from ui import *" binds with __all__
+	files := map[string]string{
+		"ui/button.psx": `
+view Button(label: str):
+    <button>{label}</button>
+`,
+		"ui/card.psx": `
+from .button import Button
+
+view Card():
+    <div><Button label="More" /></div>
+`,
+		"ui/__init__.psx": `
+from .button import *
+from .card import Card
+
+__all__ = ["Button", "Card"]
+__all__ += ["_Badge"]
+
+view Hidden():
+    <p>hidden</p>
+
+view _Badge():
+    <span>new</span>
+`,
+		"page.psx": `
+from ui import *
+
+view Page():
+    <div>
+        <Button label="Go" />
+        <Card />
+        <_Badge />
+    </div>
+`,
+		"broken.psx": `
+from ui import *
+
+view Broken():
+    <Hidden />
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	compile := func(files ...string) (*MultiFileOutput, error) {
+		opts := MultiFileOptions{RootDir: tmpDir}
+		for _, file := range files {
+			opts.Files = append(opts.Files, filepath.Join(tmpDir, file))
+		}
+		return NewMultiFileCompiler(nil).CompileProject(context.Background(), opts)
+	}
+
+	output, err := compile("ui/button.psx", "ui/card.psx", "ui/__init__.psx", "page.psx")
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v", err)
+	}
+	pageCode := string(output.CompiledFiles[filepath.Join(tmpDir, "page.psx")])
+	for _, want := range []string{"Button(label=\"Go\")", "Card()", "_Badge()"} {
+		if !strings.Contains(pageCode, want) {
+			t.Errorf("Expected %s in output, got:\n%s", want, pageCode)
+		}
+	}
+
+	// Hidden is public but not listed by __all__, so the wildcard does not bind it
+	output, err = compile("ui/button.psx", "ui/card.psx", "ui/__init__.psx", "broken.psx")
+	if err == nil || len(output.Errors) != 1 || !strings.Contains(output.Errors[0].Error(), "undefined view component 'Hidden'") {
+		t.Fatalf("Expected Hidden to be undefined in broken.psx, got %v", err)
+	}
+}
+
 func TestMultiFileCompiler_CrossFileViewImport_RelativeImport(t *testing.T) {
 	// R3: Relative imports (from .components import StatusBadge)
 	files := map[string]string{
@@ -992,3 +1348,312 @@ view ViewB():
 		t.Errorf("Error should mention circular dependency, got: %v", err)
 	}
 }
+
+func TestMultiFileCompiler_VendoredPackage(t *testing.T) {
+	files := map[string]string{
+		"app.psx": `from ui_kit.button import Button
+
+view Page():
+    <Button label="Go"/>
+`,
+		"topple_modules/ui_kit/topple-package.json": `{"name": "ui_kit", "version": "1.0.0"}`,
+		"topple_modules/ui_kit/__init__.psx":        "",
+		"topple_modules/ui_kit/button.psx": `view Button(label: str):
+    <button>{label}</button>
+`,
+		"topple_modules/ui_kit/unused.psx": `this is not valid psx (
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	compiler := NewMultiFileCompiler(logger)
+
+	output, err := compiler.CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	buttonPath := filepath.Join(tmpDir, "topple_modules", "ui_kit", "button.psx")
+	if _, ok := output.CompiledFiles[buttonPath]; !ok {
+		t.Errorf("imported vendored module was not compiled")
+	}
+	if _, ok := output.CompiledFiles[filepath.Join(tmpDir, "topple_modules", "ui_kit", "unused.psx")]; ok {
+		t.Errorf("unreferenced vendored module should not be compiled")
+	}
+
+	appCode := string(output.CompiledFiles[filepath.Join(tmpDir, "app.psx")])
+	if !strings.Contains(appCode, "Button(label=") {
+		t.Errorf("vendored view composition not resolved:\n%s", appCode)
+	}
+}
+
+func TestMultiFileCompiler_SearchPathLibrary(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"app/page.psx": `from ui.badge import Badge
+
+view Page():
+    <Badge text="new"/>
+`,
+		"shared/ui/__init__.psx": "",
+		"shared/ui/badge.psx": `view Badge(text: str):
+    <span>{text}</span>
+`,
+		"fallback/ui/badge.psx": `this is not valid psx (
+`,
+	})
+	appDir := filepath.Join(tmpDir, "app")
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	output, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: appDir,
+		Files:   []string{appDir},
+		// The first search path holding the module wins
+		SearchPaths: []string{filepath.Join(tmpDir, "shared"), filepath.Join(tmpDir, "fallback")},
+		Options:     Options{OutputDir: filepath.Join(tmpDir, "out")},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	// Libraries are compiled by their own builds
+	if len(output.CompiledFiles) != 1 {
+		t.Errorf("expected only the project file to be compiled, got %d files", len(output.CompiledFiles))
+	}
+	pageCode := string(output.CompiledFiles[filepath.Join(appDir, "page.psx")])
+	if !strings.Contains(pageCode, "from ui.badge import Badge") || !strings.Contains(pageCode, "Badge(text=") {
+		t.Errorf("library view composition not resolved:\n%s", pageCode)
+	}
+}
+
+func TestMultiFileCompiler_VendoredVersionConflict(t *testing.T) {
+	files := map[string]string{
+		"app.psx": "x = 1\n",
+		"topple_modules/icons/topple-package.json":                       `{"name": "icons", "version": "1.0.0"}`,
+		"topple_modules/ui_kit/topple-package.json":                      `{"name": "ui_kit", "version": "1.0.0", "dependencies": {"icons": "^2.0.0"}}`,
+		"topple_modules/ui_kit/topple_modules/icons/topple-package.json": `{"name": "icons", "version": "2.0.0"}`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	compiler := NewMultiFileCompiler(logger)
+
+	output, err := compiler.CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{filepath.Join(tmpDir, "app.psx")},
+	})
+	if err == nil {
+		t.Fatal("expected version conflict error")
+	}
+	if len(output.Errors) == 0 || output.Errors[0].Stage != "vendor" {
+		t.Fatalf("expected vendor stage errors, got %v", output.Errors)
+	}
+}
+
+func TestMultiFileCompiler_Lockfile(t *testing.T) {
+	files := map[string]string{
+		"app.psx": "from ui_kit.button import Button\n",
+		"topple_modules/ui_kit/topple-package.json": `{"name": "ui_kit", "version": "1.0.0"}`,
+		"topple_modules/ui_kit/button.psx":          "view Button():\n    <button/>\n",
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	opts := MultiFileOptions{
+		RootDir:  tmpDir,
+		Files:    []string{filepath.Join(tmpDir, "app.psx")},
+		LockMode: module.LockFrozen,
+	}
+
+	// Frozen mode refuses to run without a lockfile
+	if _, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), opts); err == nil {
+		t.Fatal("expected frozen mode to fail without a lockfile")
+	}
+
+	// Auto mode produces a lockfile for the caller to write
+	opts.LockMode = module.LockAuto
+	output, err := NewMultiFileCompiler(logger).CompileProject(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v", err)
+	}
+	if !output.LockfileChanged || output.Lockfile == nil {
+		t.Fatal("expected a new lockfile")
+	}
+	if err := os.WriteFile(output.LockfilePath, mustMarshal(t, output.Lockfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tampering with a vendored file is detected
+	if err := os.WriteFile(filepath.Join(tmpDir, "topple_modules/ui_kit/button.psx"), []byte("view Button():\n    <a/>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err = NewMultiFileCompiler(logger).CompileProject(context.Background(), opts)
+	if err == nil {
+		t.Fatal("expected lockfile verification to fail")
+	}
+	if len(output.Errors) != 1 || output.Errors[0].Stage != "lockfile" {
+		t.Errorf("expected one lockfile error, got %v", output.Errors)
+	}
+}
+
+func mustMarshal(t *testing.T, lock *module.Lockfile) []byte {
+	t.Helper()
+	data, err := lock.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMultiFileCompiler_Metrics(t *testing.T) {
+	files := map[string]string{
+		"utils.psx": `
+def greet(name):
+    return f"Hello, {name}!"
+`,
+		"main.psx": `
+import utils
+from utils import greet
+
+def main():
+    return greet("World")
+`,
+	}
+
+	tmpDir := setupTestFiles(t, files)
+	counters := observe.NewCounters()
+
+	// A nil logger must not panic or print
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Options: Options{Metrics: counters},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	if got := counters.Get(observe.Files); got != 2 {
+		t.Errorf("files = %d, want 2", got)
+	}
+	if counters.Get(observe.Tokens) == 0 {
+		t.Error("expected tokens to be counted")
+	}
+	if counters.Get(observe.Nodes) == 0 {
+		t.Error("expected nodes to be counted")
+	}
+	if counters.Get(observe.CacheHits) == 0 {
+		t.Error("expected repeated imports of utils to hit the resolver cache")
+	}
+}
+
+func TestMultiFileCompiler_SourceMaps(t *testing.T) {
+	files := map[string]string{
+		"card.psx": "view Card(title: str):\n    <h2>{title}</h2>\n",
+		"page.psx": "from card import Card\n\nview Page(titles: list):\n    <main>\n        for title in titles:\n            <Card title={title} />\n    </main>\n",
+	}
+	tmpDir := setupTestFiles(t, files)
+	cache := OpenBuildCache(filepath.Join(tmpDir, BuildCacheDirName), "test")
+
+	compile := func(sourceMaps bool) *MultiFileOutput {
+		output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{SourceMaps: sourceMaps},
+			Cache:   cache,
+		})
+		if err != nil {
+			t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+		}
+		return output
+	}
+
+	if output := compile(false); output.SourceMaps != nil {
+		t.Errorf("expected no source maps by default, got %v", output.SourceMaps)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cached outputs have no map, so the cache is bypassed
+	output := compile(true)
+	page := filepath.Join(tmpDir, "page.psx")
+	if len(output.SourceMaps) != 2 || output.SourceMaps[page] == nil {
+		t.Fatalf("expected a source map per file, got %v", output.SourceMaps)
+	}
+
+	code := strings.Split(string(output.CompiledFiles[page]), "\n")
+	for i, line := range code {
+		if !strings.Contains(line, "Card(title=") {
+			continue
+		}
+		mapping, ok := output.SourceMaps[page].Lookup(i + 1)
+		if !ok || mapping.Original.Start.Line != 6 {
+			t.Errorf("expected line %d to map to the <Card> on line 6, got %+v", i+1, mapping)
+		}
+		return
+	}
+	t.Fatalf("no Card call in:\n%s", output.CompiledFiles[page])
+}
+
+func TestMultiFileCompiler_Rebuild(t *testing.T) {
+	files := map[string]string{
+		"card.psx":  "view Card(title: str):\n    <h2>{title}</h2>\n",
+		"page.psx":  "from card import Card\n\nview Page():\n    <Card title=\"home\" />\n",
+		"other.psx": "view Other():\n    <p>other</p>\n",
+	}
+	tmpDir := setupTestFiles(t, files)
+	card := filepath.Join(tmpDir, "card.psx")
+	page := filepath.Join(tmpDir, "page.psx")
+
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Rebuild: []string{page},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	if len(output.CompiledFiles) != 1 {
+		t.Fatalf("expected only the rebuilt file in the output, got %d", len(output.CompiledFiles))
+	}
+	// The page still resolves Card as a view from the file that was only parsed
+	if !strings.Contains(string(output.CompiledFiles[page]), "Card(title=") {
+		t.Errorf("expected page.psx to call Card, got:\n%s", output.CompiledFiles[page])
+	}
+
+	layers := restrictLayers([][]string{{card, filepath.Join(tmpDir, "other.psx")}, {page}}, []string{page, card})
+	if len(layers) != 2 || len(layers[0]) != 1 || layers[0][0] != card || layers[1][0] != page {
+		t.Errorf("expected card before page, got %v", layers)
+	}
+}
+
+func TestMultiFileCompiler_Cancelled(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"main.psx": "def main():\n    return 1\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	output, err := NewMultiFileCompiler(nil).CompileProject(ctx, MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if output != nil && len(output.CompiledFiles) != 0 {
+		t.Errorf("expected no output from a cancelled build, got %d files", len(output.CompiledFiles))
+	}
+
+	// The single-file compiler honors cancellation too
+	_, errs := NewCompiler(nil).Compile(ctx, File{Name: "main.psx", Content: []byte("x = 1\n")})
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected context.Canceled from Compile, got %v", errs)
+	}
+}
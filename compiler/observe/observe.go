@@ -0,0 +1,297 @@
+// Package observe defines the logging, metrics and event hooks of the
+// compile pipeline. Embedders pass their own implementations to the compiler
+// entry points; the defaults discard everything so library use stays quiet.
+package observe
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Logger receives the pipeline's log output. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var _ Logger = (*slog.Logger)(nil)
+
+// MetricsSink receives pipeline counters. Count may be called from several
+// goroutines at once.
+type MetricsSink interface {
+	Count(name string, delta int64)
+}
+
+// Counter names reported by the pipeline
+const (
+	Files     = "files"      // Source files compiled
+	Tokens    = "tokens"     // Tokens produced by the scanner
+	Nodes     = "nodes"      // AST nodes produced by the parser
+	CacheHits = "cache_hits" // Module resolutions answered from the resolver cache
+	Cached    = "cached"     // Outputs reused from the build cache instead of compiled
+)
+
+// TimingSink receives how long each stage of the pipeline took on each file.
+// Time may be called from several goroutines at once.
+type TimingSink interface {
+	Time(file, stage string, elapsed time.Duration)
+}
+
+// Stage names reported by the pipeline, in pipeline order
+const (
+	StageScan      = "scan"
+	StageParse     = "parse"
+	StageResolve   = "resolve"
+	StageTransform = "transform"
+	StageRelocate  = "relocate"
+	StageCodegen   = "codegen"
+)
+
+// Stages lists the stage names in pipeline order
+var Stages = []string{StageScan, StageParse, StageResolve, StageTransform, StageRelocate, StageCodegen}
+
+// EventSink receives the steps of a build as they happen. Event may be
+// called from several goroutines at once.
+type EventSink interface {
+	Event(e Event)
+}
+
+// Event is one step of a build. Fields that do not apply to its Kind are
+// left zero.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"event"`
+	File     string    `json:"file,omitempty"`     // Source file the event is about
+	Import   string    `json:"import,omitempty"`   // EventResolve: file the import resolved to
+	Deferred bool      `json:"deferred,omitempty"` // EventResolve: import inside a function or view body
+	Line     int       `json:"line,omitempty"`     // EventResolve, EventDiagnostic: 1-based source line
+	Column   int       `json:"column,omitempty"`   // EventResolve, EventDiagnostic: 1-based source column
+	Stage    string    `json:"stage,omitempty"`    // EventDiagnostic: pipeline stage that reported it
+	Severity string    `json:"severity,omitempty"` // EventDiagnostic: "error" or "warning"
+	Code     string    `json:"code,omitempty"`     // EventDiagnostic: error code, when it has one
+	Message  string    `json:"message,omitempty"`  // EventDiagnostic; EventBuildEnd: why the build failed
+	Bytes    int       `json:"bytes,omitempty"`    // EventEmit: size of the generated code
+	Cached   bool      `json:"cached,omitempty"`   // EventEmit: code reused from a cache instead of compiled
+	Files    int       `json:"files,omitempty"`    // EventBuildStart: files given; EventBuildEnd: files output
+	Errors   int       `json:"errors,omitempty"`   // EventBuildEnd: errors reported
+}
+
+// Event kinds reported by the pipeline
+const (
+	EventBuildStart = "build_start" // A build begins
+	EventDiscover   = "discover"    // A source file is part of the build
+	EventCacheHit   = "cache_hit"   // The output of an unchanged file is reused
+	EventCacheMiss  = "cache_miss"  // A file has no up-to-date cached output
+	EventResolve    = "resolve"     // An import of a file resolved to another file
+	EventDiagnostic = "diagnostic"  // An error or warning was reported
+	EventEmit       = "emit"        // The generated code of a file is output
+	EventBuildEnd   = "build_end"   // A build finished, failed or was cancelled
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// NopLogger returns a Logger that discards all output
+func NopLogger() Logger { return nopLogger{} }
+
+type nopMetrics struct{}
+
+func (nopMetrics) Count(string, int64) {}
+
+// NopMetrics returns a MetricsSink that discards all counters
+func NopMetrics() MetricsSink { return nopMetrics{} }
+
+// LoggerOrNop returns logger, or a discarding Logger when it is nil
+func LoggerOrNop(logger Logger) Logger {
+	if logger == nil {
+		return NopLogger()
+	}
+	if l, ok := logger.(*slog.Logger); ok && l == nil {
+		return NopLogger()
+	}
+	return logger
+}
+
+// DebugEnabled reports whether logger emits debug output, so callers can skip
+// building messages that would be discarded. Loggers that cannot tell, such
+// as NopLogger, report false.
+func DebugEnabled(logger Logger) bool {
+	l, ok := logger.(interface {
+		Enabled(context.Context, slog.Level) bool
+	})
+	return ok && l.Enabled(context.Background(), slog.LevelDebug)
+}
+
+type nopTimings struct{}
+
+func (nopTimings) Time(string, string, time.Duration) {}
+
+// NopTimings returns a TimingSink that discards all timings
+func NopTimings() TimingSink { return nopTimings{} }
+
+// TimingsOrNop returns timings, or a discarding TimingSink when it is nil
+func TimingsOrNop(timings TimingSink) TimingSink {
+	if timings == nil {
+		return NopTimings()
+	}
+	if t, ok := timings.(*Timings); ok && t == nil {
+		return NopTimings()
+	}
+	return timings
+}
+
+type nopEvents struct{}
+
+func (nopEvents) Event(Event) {}
+
+// NopEvents returns an EventSink that discards all events
+func NopEvents() EventSink { return nopEvents{} }
+
+// EventsOrNop returns events, or a discarding EventSink when it is nil
+func EventsOrNop(events EventSink) EventSink {
+	if events == nil {
+		return NopEvents()
+	}
+	if l, ok := events.(*EventLog); ok && l == nil {
+		return NopEvents()
+	}
+	return events
+}
+
+// MetricsOrNop returns metrics, or a discarding MetricsSink when it is nil
+func MetricsOrNop(metrics MetricsSink) MetricsSink {
+	if metrics == nil {
+		return NopMetrics()
+	}
+	return metrics
+}
+
+// Counters is an in-memory MetricsSink. It is safe for concurrent use.
+type Counters struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+// NewCounters creates an empty set of counters
+func NewCounters() *Counters {
+	return &Counters{values: make(map[string]int64)}
+}
+
+// Count adds delta to the named counter
+func (c *Counters) Count(name string, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[name] += delta
+}
+
+// Get returns the current value of the named counter
+func (c *Counters) Get(name string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[name]
+}
+
+// Names returns the names of all counters that were reported, sorted
+func (c *Counters) Names() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.values))
+	for name := range c.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Timings is an in-memory TimingSink. It is safe for concurrent use.
+type Timings struct {
+	mu    sync.Mutex
+	files map[string]map[string]time.Duration
+}
+
+// NewTimings creates an empty set of timings
+func NewTimings() *Timings {
+	return &Timings{files: make(map[string]map[string]time.Duration)}
+}
+
+// Time adds elapsed to the time spent on the stage of file
+func (t *Timings) Time(file, stage string, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stages, ok := t.files[file]
+	if !ok {
+		stages = make(map[string]time.Duration)
+		t.files[file] = stages
+	}
+	stages[stage] += elapsed
+}
+
+// Files returns the files that were timed, sorted
+func (t *Timings) Files() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	files := make([]string, 0, len(t.files))
+	for file := range t.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Stage returns the time spent on one stage of file
+func (t *Timings) Stage(file, stage string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.files[file][stage]
+}
+
+// Total returns the time spent on all stages of file
+func (t *Timings) Total(file string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var total time.Duration
+	for _, elapsed := range t.files[file] {
+		total += elapsed
+	}
+	return total
+}
+
+// EventLog is an EventSink writing each event as a line of JSON (NDJSON). It
+// is safe for concurrent use.
+type EventLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewEventLog creates an event log writing to w
+func NewEventLog(w io.Writer) *EventLog {
+	return &EventLog{enc: json.NewEncoder(w)}
+}
+
+// Event writes e as one line. Once a write fails, later events are dropped.
+func (l *EventLog) Event(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = l.enc.Encode(e)
+	}
+}
+
+// Err returns the first error writing the log
+func (l *EventLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
@@ -0,0 +1,224 @@
+package compiler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// PackedPackage is a component library compiled for distribution: its
+// manifest, its .psx sources, the Python code and stubs generated from them
+// and the symbols of its views
+type PackedPackage struct {
+	Manifest *module.PackageManifest
+	Symbols  *PackageSymbols
+	Files    map[string][]byte   // Slash-separated path relative to the package directory -> content
+	Errors   []*CompilationError // Files that did not compile, and exports that name no view
+}
+
+// PackageSymbols describes the views of a packed package, so that tools can
+// list what it offers without compiling it
+type PackageSymbols struct {
+	Name    string                   `json:"name"`
+	Version string                   `json:"version"`
+	Modules map[string][]PackageView `json:"modules"` // Module relative to the package, such as forms.input -> its views, sorted
+}
+
+// PackageView is a view defined by a module of a packed package
+type PackageView struct {
+	Name       string              `json:"name"`
+	Params     []PackageParam      `json:"params"`
+	Exported   bool                `json:"exported"`             // Listed by the manifest exports, or public when it lists none
+	Deprecated *PackageDeprecation `json:"deprecated,omitempty"` // Set by the @deprecated marker
+}
+
+// PackageParam is a parameter of a packed view
+type PackageParam struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind,omitempty"` // "*" or "**" for variadic parameters
+	Required bool   `json:"required"`
+}
+
+// PackageDeprecation records the message of a deprecated view
+type PackageDeprecation struct {
+	Message string `json:"message,omitempty"`
+}
+
+// Pack compiles the package in pkgDir, which holds a topple-package.json,
+// for distribution. Absolute imports resolve against opts.RootDir, the
+// parent of the package directory by default, so the package imports its
+// own modules by its name and its dependencies from the topple_modules
+// there. Dependencies are left out of the result: the project installing
+// the package installs them too.
+func (c *MultiFileCompiler) Pack(ctx context.Context, pkgDir string, opts MultiFileOptions) (*PackedPackage, error) {
+	pkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid package directory %s: %w", pkgDir, err)
+	}
+	manifest, err := module.ReadPackageManifest(filesystem.NewFileSystem(c.logger), pkgDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.RootDir == "" {
+		opts.RootDir = filepath.Dir(pkgDir)
+	}
+	opts.Files = []string{pkgDir}
+	opts.Options.Stubs = true
+	output, err := c.CompileProject(ctx, opts)
+	if err != nil {
+		if output != nil {
+			return &PackedPackage{Manifest: manifest, Errors: output.Errors}, err
+		}
+		return nil, err
+	}
+
+	packed := &PackedPackage{
+		Manifest: manifest,
+		Symbols: &PackageSymbols{
+			Name:    manifest.Name,
+			Version: manifest.Version,
+			Modules: make(map[string][]PackageView),
+		},
+		Files:  make(map[string][]byte),
+		Errors: output.Errors,
+	}
+
+	// Symbols are registered dependencies first, so the first file
+	// registering a view is the one defining it; later ones import it
+	order, err := output.Graph.GetCompilationOrder()
+	if err != nil {
+		return nil, err
+	}
+	definedIn := make(map[ast.Node]string)
+	for _, filePath := range order {
+		symbols, err := output.Registry.GetModuleSymbols(filePath)
+		if err != nil {
+			continue
+		}
+		for _, sym := range symbols.Symbols {
+			if _, seen := definedIn[sym.Node]; !seen && sym.Type == symbol.SymbolView {
+				definedIn[sym.Node] = filePath
+			}
+		}
+	}
+
+	exports := make(map[string]bool, len(manifest.Exports))
+	for _, export := range manifest.Exports {
+		exports[export] = true
+	}
+	for filePath, code := range output.CompiledFiles {
+		if !isWithin(pkgDir, filePath) || module.IsVendoredPath(filePath) {
+			continue
+		}
+		rel, err := filepath.Rel(pkgDir, filePath)
+		if err != nil {
+			return nil, err
+		}
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, ".psx"))
+
+		source, err := c.fs.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+		}
+		packed.Files[rel+".psx"] = source
+		packed.Files[rel+".py"] = code
+		if stub := output.Stubs[filePath]; stub != nil {
+			packed.Files[rel+".pyi"] = stub
+		}
+
+		moduleName := strings.TrimSuffix(strings.ReplaceAll(rel, "/", "."), ".__init__")
+		if views := packedViews(output.Registry, definedIn, filePath, moduleName, len(manifest.Exports) == 0, exports); len(views) > 0 {
+			packed.Symbols.Modules[moduleName] = views
+		}
+	}
+
+	for _, export := range manifest.Exports {
+		if !exports[export] {
+			continue
+		}
+		packed.Errors = append(packed.Errors, &CompilationError{
+			File:    filepath.Join(pkgDir, module.PackageManifestName),
+			Stage:   "pack",
+			Message: fmt.Sprintf("export %s names no public view of the package", export),
+		})
+	}
+	if len(packed.Errors) > 0 {
+		return packed, fmt.Errorf("packing %s failed with %d errors", pkgDir, len(packed.Errors))
+	}
+
+	manifestData, err := c.fs.ReadFile(filepath.Join(pkgDir, module.PackageManifestName))
+	if err != nil {
+		return nil, err
+	}
+	packed.Files[module.PackageManifestName] = manifestData
+	symbols, err := json.MarshalIndent(packed.Symbols, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	packed.Files[module.SymbolsFileName] = append(symbols, '\n')
+	return packed, nil
+}
+
+// packedViews returns the views moduleName defines, as opposed to those it
+// imports, sorted. Public views are
+// exported with exportAll, or when listed by exports; those found are
+// removed from exports, so that the ones left name no view.
+func packedViews(registry *symbol.Registry, definedIn map[ast.Node]string, filePath, moduleName string, exportAll bool, exports map[string]bool) []PackageView {
+	symbols, err := registry.GetModuleSymbols(filePath)
+	if err != nil {
+		return nil
+	}
+
+	var views []PackageView
+	for _, sym := range sortedSymbols(symbols) {
+		node, ok := sym.Node.(*ast.ViewStmt)
+		if !ok || sym.Type != symbol.SymbolView || definedIn[node] != filePath {
+			continue
+		}
+		view := PackageView{Name: sym.Name, Params: packedParams(node.Params)}
+		if sym.Visibility == symbol.Public {
+			qualified := moduleName + "." + sym.Name
+			view.Exported = exportAll || exports[qualified]
+			delete(exports, qualified)
+		}
+		if sym.Deprecated {
+			view.Deprecated = &PackageDeprecation{Message: sym.DeprecationMessage}
+		}
+		views = append(views, view)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// packedParams lists the named parameters of a view
+func packedParams(params *ast.ParameterList) []PackageParam {
+	result := []PackageParam{}
+	if params == nil {
+		return result
+	}
+	for _, param := range params.Parameters {
+		if param == nil || param.Name == nil {
+			continue
+		}
+		p := PackageParam{Name: param.Name.Token.Lexeme}
+		switch {
+		case param.IsStar:
+			p.Kind = "*"
+		case param.IsDoubleStar:
+			p.Kind = "**"
+		default:
+			p.Required = param.Default == nil
+		}
+		result = append(result, p)
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/module"
+)
+
+func TestPack(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"ui_kit/topple-package.json": `{"name": "ui_kit", "version": "1.2.0", "exports": ["button.Button", "forms.Field"]}`,
+		"ui_kit/button.psx": `
+view Button(label: str, kind: str = "primary", **attrs):
+    <button class={kind}>{label}</button>
+
+view _Icon():
+    <i />
+
+view Extra():
+    <span />
+`,
+		"ui_kit/forms/__init__.psx": `
+from ui_kit.button import Button
+
+view Field(name):
+    <label>{name}<Button label="ok" /></label>
+`,
+		"ui_kit/topple_modules/icons/topple-package.json": `{"name": "icons", "version": "1.0.0"}`,
+		"ui_kit/topple_modules/icons/check.psx": `
+view Check():
+    <i />
+`,
+	})
+
+	packed, err := NewMultiFileCompiler(nil).Pack(context.Background(), filepath.Join(tmpDir, "ui_kit"), MultiFileOptions{})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	var files []string
+	for name := range packed.Files {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	wantFiles := []string{
+		"button.psx", "button.py", "button.pyi",
+		"forms/__init__.psx", "forms/__init__.py", "forms/__init__.pyi",
+		module.PackageManifestName, module.SymbolsFileName,
+	}
+	sort.Strings(wantFiles)
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Errorf("Expected files %v, got %v", wantFiles, files)
+	}
+	if !strings.Contains(string(packed.Files["forms/__init__.py"]), "class Field(BaseView):") {
+		t.Errorf("Expected the compiled Field view, got:\n%s", packed.Files["forms/__init__.py"])
+	}
+
+	var symbols PackageSymbols
+	if err := json.Unmarshal(packed.Files[module.SymbolsFileName], &symbols); err != nil {
+		t.Fatalf("Invalid %s: %v", module.SymbolsFileName, err)
+	}
+	if symbols.Name != "ui_kit" || symbols.Version != "1.2.0" {
+		t.Errorf("Unexpected package %s %s", symbols.Name, symbols.Version)
+	}
+	exported := make(map[string]bool)
+	for moduleName, views := range symbols.Modules {
+		for _, view := range views {
+			exported[moduleName+"."+view.Name] = view.Exported
+		}
+	}
+	wantExported := map[string]bool{
+		"button.Button": true,
+		"button.Extra":  false,
+		"button._Icon":  false,
+		"forms.Field":   true,
+	}
+	if !reflect.DeepEqual(exported, wantExported) {
+		t.Errorf("Expected views %v, got %v", wantExported, exported)
+	}
+	wantParams := []PackageParam{
+		{Name: "label", Required: true},
+		{Name: "kind"},
+		{Name: "attrs", Kind: "**"},
+	}
+	if got := symbols.Modules["button"][0].Params; !reflect.DeepEqual(got, wantParams) {
+		t.Errorf("Expected Button params %+v, got %+v", wantParams, got)
+	}
+}
+
+func TestPack_Errors(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"ui_kit/topple-package.json": `{"name": "ui_kit", "version": "1.0.0", "exports": ["button.Button", "button.Missing", "button._Icon"]}`,
+		"ui_kit/button.psx": `
+view Button():
+    <button />
+
+view _Icon():
+    <i />
+`,
+		"nameless/button.psx": `
+view Button():
+    <button />
+`,
+	})
+	pack := func(pkg string) (*PackedPackage, error) {
+		return NewMultiFileCompiler(nil).Pack(context.Background(), filepath.Join(tmpDir, pkg), MultiFileOptions{})
+	}
+
+	packed, err := pack("ui_kit")
+	if err == nil {
+		t.Fatal("Expected exports naming no public view to fail")
+	}
+	var messages []string
+	for _, e := range packed.Errors {
+		messages = append(messages, e.Message)
+	}
+	want := []string{
+		"export button.Missing names no public view of the package",
+		"export button._Icon names no public view of the package",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected errors %q, got %q", want, messages)
+	}
+
+	if _, err := pack("nameless"); err == nil || !strings.Contains(err.Error(), module.PackageManifestName) {
+		t.Errorf("Expected a missing manifest error, got %v", err)
+	}
+}
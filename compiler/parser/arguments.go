@@ -18,7 +18,23 @@ func (p *Parser) arguments() ([]*ast.Argument, error) {
 		return []*ast.Argument{}, nil
 	}
 
-	return p.args()
+	args, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+
+	// A keyword may only be passed once
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if arg.Name == nil {
+			continue
+		}
+		if name := arg.Name.Token.Lexeme; seen[name] {
+			return nil, p.error(arg.Name.Token, "keyword argument repeated: "+name)
+		}
+		seen[arg.Name.Token.Lexeme] = true
+	}
+	return args, nil
 }
 
 // args parses a sequence of arguments according to the grammar:
@@ -97,6 +113,9 @@ func (p *Parser) kwargs() ([]*ast.Argument, error) {
 			if p.check(lexer.RightParen) {
 				break
 			}
+			if err := p.misplacedArgument(true); err != nil {
+				return nil, err
+			}
 
 			arg, err = p.parseKwargOrDoubleStar()
 			if err != nil {
@@ -119,6 +138,9 @@ func (p *Parser) kwargs() ([]*ast.Argument, error) {
 			if p.check(lexer.RightParen) {
 				break
 			}
+			if err := p.misplacedArgument(hasKwargOrDoubleStar); err != nil {
+				return nil, err
+			}
 
 			// Check if we've switched to kwarg_or_double_starred
 			if !hasKwargOrDoubleStar && p.check(lexer.StarStar) {
@@ -148,8 +170,26 @@ func (p *Parser) kwargs() ([]*ast.Argument, error) {
 	return args, nil
 }
 
+// misplacedArgument returns the error for an argument that cannot follow
+// keyword arguments, or nil when the next one may: after a keyword argument
+// only keyword arguments and unpacking follow, and after '**' no '*'.
+func (p *Parser) misplacedArgument(afterDoubleStar bool) error {
+	switch {
+	case p.check(lexer.StarStar) || (p.check(lexer.Identifier) && p.checkNext(lexer.Equal)):
+		return nil
+	case p.check(lexer.Star) && !afterDoubleStar:
+		return nil
+	case p.check(lexer.Star):
+		return p.error(p.peek(), "iterable argument unpacking follows keyword argument unpacking")
+	case afterDoubleStar:
+		return p.error(p.peek(), "positional argument follows keyword argument unpacking")
+	default:
+		return p.error(p.peek(), "positional argument follows keyword argument")
+	}
+}
+
 // parseArg parses a single argument (non-keyword)
-// This handles: starred_expression | expression | generator_expression
+// This handles: starred_expression | assignment_expression | expression | generator_expression
 func (p *Parser) parseArg() (*ast.Argument, error) {
 	startPos := p.peek().Start()
 
@@ -169,8 +209,8 @@ func (p *Parser) parseArg() (*ast.Argument, error) {
 		}, nil
 	}
 
-	// Regular expression
-	expr, err := p.expression()
+	// Regular or assignment expression
+	expr, err := p.namedExpression()
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +220,7 @@ func (p *Parser) parseArg() (*ast.Argument, error) {
 	// func(x for x in items) is equivalent to func((x for x in items))
 	if p.check(lexer.For) || (p.check(lexer.Async) && p.checkNext(lexer.For)) {
 		// Parse as generator expression
-		clauses, err := p.forIfClauses()
+		clauses, err := p.forIfClauses(startPos)
 		if err != nil {
 			return nil, err
 		}
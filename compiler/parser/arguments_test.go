@@ -304,6 +304,21 @@ func TestArgumentOrdering(t *testing.T) {
 			input:     "func(1, 2, *args, x=3, y=4, **kwargs)",
 			positions: []string{"pos", "pos", "star", "kw", "kw", "doublestar"},
 		},
+		{
+			name:      "unpacking after keyword",
+			input:     "func(x=1, *args, y=2)",
+			positions: []string{"kw", "star", "kw"},
+		},
+		{
+			name:      "keyword after double-starred",
+			input:     "func(*a, x=1, *b, **k, y=2)",
+			positions: []string{"star", "kw", "star", "doublestar", "kw"},
+		},
+		{
+			name:      "assignment expression",
+			input:     "func(y := 1, x=y)",
+			positions: []string{"pos", "kw"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -514,6 +529,35 @@ func TestArgumentErrors(t *testing.T) {
 	}
 }
 
+func TestArgumentOrderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"positional after keyword", "func(x=1, 2)", "positional argument follows keyword argument"},
+		{"starred expression after keyword", "func(x=1, *a, b)", "positional argument follows keyword argument"},
+		{"positional after double-starred", "func(**k, 2)", "positional argument follows keyword argument unpacking"},
+		{"starred after double-starred", "func(**k, *a)", "iterable argument unpacking follows keyword argument unpacking"},
+		{"repeated keyword", "func(x=1, y=2, x=3)", "keyword argument repeated: x"},
+		{"in an assignment", "v = func(x=1, 2)", "positional argument follows keyword argument"},
+		{"in class bases", "class C(metaclass=M, Base):\n    pass", "positional argument follows keyword argument"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := parseInput(t, tt.input+"\n")
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+			}
+			parseErr, ok := errs[0].(*ParseError)
+			if !ok || parseErr.Message != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, errs[0])
+			}
+		})
+	}
+}
+
 // TestGeneratorExpressionArguments tests generator expressions as function arguments
 func TestGeneratorExpressionArguments(t *testing.T) {
 	tests := []struct {
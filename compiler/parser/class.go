@@ -34,7 +34,7 @@ func (p *Parser) classStatement() (ast.Stmt, error) {
 
 	// Parse optional type parameters - converting from Expr to TypeParam
 	var typeParams []ast.TypeParam
-	if p.check(lexer.LeftBracket) {
+	if p.match(lexer.LeftBracket) {
 		// For simplicity, we'll assume typeParams returns compatible type params
 		// In a real implementation, this would need proper conversion or a dedicated type param parser
 		exprs, err := p.typeParams()
@@ -76,7 +76,7 @@ func (p *Parser) classStatement() (ast.Stmt, error) {
 	}
 
 	// Parse the class body
-	body, err := p.block()
+	body, err := p.scopeBody(p.block)
 	if err != nil {
 		return nil, err
 	}
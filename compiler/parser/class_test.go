@@ -87,6 +87,15 @@ func TestClassDef(t *testing.T) {
 			false,
 			true,
 		},
+		{
+			"generic class",
+			`class Box[T: Base, **P](Base):
+    pass`,
+			"Box",
+			false,
+			true,
+			true,
+		},
 		{
 			"class with methods",
 			`class Calculator:
@@ -329,7 +338,7 @@ func TestClassErrors(t *testing.T) {
 			parser := NewParser(tokens)
 
 			_, err := parser.classStatement()
-			if err == nil {
+			if err == nil && len(parser.Errors) == 0 {
 				t.Errorf("Expected error for %s, but got none", test.input)
 			}
 		})
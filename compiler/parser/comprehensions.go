@@ -1,13 +1,17 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
 // forIfClauses parses one or more for_if_clause according to the grammar:
 // for_if_clauses: for_if_clause+
-func (p *Parser) forIfClauses() ([]ast.ForIfClause, error) {
+// start is where the comprehension's element begins, so that assignment
+// expressions rebinding an iteration variable can be reported.
+func (p *Parser) forIfClauses(start lexer.Position) ([]ast.ForIfClause, error) {
 	var clauses []ast.ForIfClause
 
 	// Parse the first clause
@@ -26,9 +30,54 @@ func (p *Parser) forIfClauses() ([]ast.ForIfClause, error) {
 		clauses = append(clauses, clause)
 	}
 
+	if err := p.checkIterationRebinding(start, clauses); err != nil {
+		return nil, err
+	}
 	return clauses, nil
 }
 
+// checkIterationRebinding reports an assignment expression between start and
+// the current token that assigns to one of the iteration variables of
+// clauses, such as [i := 0 for i in items], which Python rejects.
+func (p *Parser) checkIterationRebinding(start lexer.Position, clauses []ast.ForIfClause) error {
+	targets := map[string]bool{}
+	for _, clause := range clauses {
+		collectTargetNames(clause.Target, targets)
+	}
+
+	for i := p.Current - 1; i > 0; i-- {
+		tok := p.Tokens[i]
+		if tok.Start().Line < start.Line || (tok.Start().Line == start.Line && tok.Start().Column < start.Column) {
+			break
+		}
+		name := p.Tokens[i-1]
+		if tok.Type == lexer.Walrus && name.Type == lexer.Identifier && targets[name.Lexeme] {
+			return p.error(name, fmt.Sprintf("assignment expression cannot rebind comprehension iteration variable '%s'", name.Lexeme))
+		}
+	}
+	return nil
+}
+
+// collectTargetNames adds the names bound by an assignment target to names
+func collectTargetNames(target ast.Expr, names map[string]bool) {
+	switch t := target.(type) {
+	case *ast.Name:
+		names[t.Token.Lexeme] = true
+	case *ast.TupleExpr:
+		for _, element := range t.Elements {
+			collectTargetNames(element, names)
+		}
+	case *ast.ListExpr:
+		for _, element := range t.Elements {
+			collectTargetNames(element, names)
+		}
+	case *ast.StarExpr:
+		collectTargetNames(t.Expr, names)
+	case *ast.GroupExpr:
+		collectTargetNames(t.Expression, names)
+	}
+}
+
 // forIfClause parses a single for_if_clause according to the grammar:
 // for_if_clause:
 //
@@ -54,8 +103,9 @@ func (p *Parser) forIfClause() (ast.ForIfClause, error) {
 		return clause, err
 	}
 
-	// Convert multiple targets to a tuple, or use single target directly
-	if len(targets) == 1 {
+	// Convert multiple targets to a tuple, or use single target directly.
+	// A trailing comma makes a one-element tuple: [x for x, in pairs]
+	if len(targets) == 1 && p.previous().Type != lexer.Comma {
 		clause.Target = targets[0]
 	} else {
 		// Multiple targets - create a tuple
@@ -72,10 +122,16 @@ func (p *Parser) forIfClause() (ast.ForIfClause, error) {
 	}
 
 	// Parse the iterable expression (disjunction)
+	iterStart := p.Current
 	iter, err := p.disjunction()
 	if err != nil {
 		return clause, err
 	}
+	for _, tok := range p.Tokens[iterStart:p.Current] {
+		if tok.Type == lexer.Walrus {
+			return clause, p.error(tok, "assignment expression cannot be used in a comprehension iterable expression")
+		}
+	}
 	clause.Iter = iter
 
 	// Parse zero or more 'if' conditions
@@ -84,6 +140,9 @@ func (p *Parser) forIfClause() (ast.ForIfClause, error) {
 		if err != nil {
 			return clause, err
 		}
+		if p.check(lexer.Walrus) {
+			return clause, p.error(p.peek(), "assignment expression in a comprehension condition must be parenthesized")
+		}
 		clause.Ifs = append(clause.Ifs, condition)
 	}
 
@@ -94,7 +153,7 @@ func (p *Parser) forIfClause() (ast.ForIfClause, error) {
 // listcomp: '[' named_expression for_if_clauses ']'
 func (p *Parser) listComp(element ast.Expr, leftBracket lexer.Token) (ast.Expr, error) {
 	// Parse for_if_clauses
-	clauses, err := p.forIfClauses()
+	clauses, err := p.forIfClauses(element.GetSpan().Start)
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +175,7 @@ func (p *Parser) listComp(element ast.Expr, leftBracket lexer.Token) (ast.Expr,
 // setcomp: '{' named_expression for_if_clauses '}'
 func (p *Parser) setComp(element ast.Expr, leftBrace lexer.Token) (ast.Expr, error) {
 	// Parse for_if_clauses
-	clauses, err := p.forIfClauses()
+	clauses, err := p.forIfClauses(element.GetSpan().Start)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +197,7 @@ func (p *Parser) setComp(element ast.Expr, leftBrace lexer.Token) (ast.Expr, err
 // dictcomp: '{' kvpair for_if_clauses '}'
 func (p *Parser) dictComp(key, value ast.Expr, leftBrace lexer.Token) (ast.Expr, error) {
 	// Parse for_if_clauses
-	clauses, err := p.forIfClauses()
+	clauses, err := p.forIfClauses(key.GetSpan().Start)
 	if err != nil {
 		return nil, err
 	}
@@ -238,7 +297,7 @@ func (p *Parser) listFromBracket(leftBracket lexer.Token) (ast.Expr, error) {
 // genexp: '(' ( assignment_expression | expression !':=') for_if_clauses ')'
 func (p *Parser) genExpr(element ast.Expr, leftParen lexer.Token) (ast.Expr, error) {
 	// Parse for_if_clauses
-	clauses, err := p.forIfClauses()
+	clauses, err := p.forIfClauses(element.GetSpan().Start)
 	if err != nil {
 		return nil, err
 	}
@@ -316,3 +316,54 @@ func TestComprehensionEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// Test the loop targets of comprehension clauses
+func TestComprehensionTargets(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantTuple int // Number of tuple elements, 0 for a single target
+	}{
+		{"single name", "[x for x in items]", 0},
+		{"tuple unpacking", "[x for x, y in pairs]", 2},
+		{"trailing comma", "[x for x, in pairs]", 1},
+		{"trailing comma after several", "{k: v for k, v, in triples}", 2},
+		{"parenthesized tuple", "(x for (x, y) in pairs)", 2},
+		{"starred target", "[xs for x, *xs in rows]", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := parseExpression(t, test.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var clauses []ast.ForIfClause
+			switch e := expr.(type) {
+			case *ast.ListComp:
+				clauses = e.Clauses
+			case *ast.DictComp:
+				clauses = e.Clauses
+			case *ast.GenExpr:
+				clauses = e.Clauses
+			default:
+				t.Fatalf("Expected a comprehension, got %T", expr)
+			}
+
+			tuple, ok := clauses[0].Target.(*ast.TupleExpr)
+			if test.wantTuple == 0 {
+				if ok {
+					t.Errorf("Expected a single target, got a tuple of %d", len(tuple.Elements))
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Expected a tuple target, got %T", clauses[0].Target)
+			}
+			if len(tuple.Elements) != test.wantTuple {
+				t.Errorf("Expected %d tuple elements, got %d", test.wantTuple, len(tuple.Elements))
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
@@ -30,24 +31,27 @@ func (p *Parser) decorator() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	// Only classes and functions can be decorated
-	if !isDecoratable(decorated) {
-		return nil, p.error(p.previous(), "only class and function definitions can be decorated")
-	}
-
 	// Create a decorator node that wraps the decorated statement
-	return &ast.Decorator{
+	decorator := &ast.Decorator{
 		Expr: expr,
 		Stmt: decorated,
 		Span: lexer.Span{Start: atToken.Start(), End: decorated.GetSpan().End},
-	}, nil
+	}
+
+	// Only definitions can be decorated; decorators of a view are applied to
+	// the class it compiles to
+	if !isDecoratable(decorated) {
+		return nil, &ParseError{Token: p.previous(), Message: "only class, function and view definitions can be decorated", Code: diagnostics.CodeInvalidDecoration}
+	}
+
+	return decorator, nil
 }
 
-// isDecoratable checks if a statement can be decorated (classes and functions)
+// isDecoratable checks if a statement can be decorated (classes, functions and views)
 func isDecoratable(stmt ast.Stmt) bool {
 	switch stmt.(type) {
-	case *ast.Class, *ast.Function, *ast.Decorator:
-		// Classes, functions, and other decorators can be decorated
+	case *ast.Class, *ast.Function, *ast.ViewStmt, *ast.Decorator:
+		// Definitions and other decorators can be decorated
 		return true
 	default:
 		return false
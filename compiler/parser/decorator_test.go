@@ -465,6 +465,34 @@ import module`,
 return value`,
 			hasError: true,
 		},
+		{
+			name: "deprecated view",
+			input: `@deprecated("use NewButton")
+view Button():
+    <button></button>`,
+			hasError: false,
+		},
+		{
+			name: "view with other decorator",
+			input: `@app.get("/")
+view Page():
+    <div></div>`,
+			hasError: false,
+		},
+		{
+			name: "deprecated view under other decorator",
+			input: `@cache
+@deprecated
+view Page():
+    <div></div>`,
+			hasError: false,
+		},
+		{
+			name: "decorated assignment (invalid)",
+			input: `@cache
+x = 1`,
+			hasError: true,
+		},
 		{
 			name: "nested decorators with calls",
 			input: `@outer_decorator(inner_decorator(value))
@@ -5,102 +5,112 @@ import (
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
-// dictOrSet determines whether to parse a dictionary or set by looking ahead
+// dictOrSet parses a brace display in a single pass, telling dictionaries
+// from sets by their first entry:
+//
+//	dict:     '{' [double_starred_kvpairs] '}'
+//	dictcomp: '{' kvpair for_if_clauses '}'
+//	set:      '{' star_named_expressions '}'
+//	setcomp:  '{' named_expression for_if_clauses '}'
+//
+// Empty braces and a leading '**' make a dictionary and a leading '*' a set;
+// otherwise the first expression is a key when a ':' follows it.
 func (p *Parser) dictOrSet() (ast.Expr, error) {
-	// Save current position
-	startPos := p.Current
-
-	// Consume the opening '{'
 	leftBrace, err := p.consume(lexer.LeftBrace, "expected '{'")
 	if err != nil {
 		return nil, err
 	}
 
-	// Check for empty braces - this is an empty dictionary
-	if p.check(lexer.RightBrace) {
-		// Restore position and parse as dictionary
-		p.Current = startPos
-		return p.dict()
-	}
-
-	// Check for ** (dictionary unpacking)
-	if p.check(lexer.StarStar) {
-		// Restore position and parse as dictionary
-		p.Current = startPos
-		return p.dict()
+	switch {
+	case p.check(lexer.RightBrace):
+		return p.dictFrom(leftBrace, nil)
+	case p.check(lexer.StarStar):
+		pair, err := p.doubleStarredKvpair()
+		if err != nil {
+			return nil, err
+		}
+		return p.dictFrom(leftBrace, pair)
+	case p.check(lexer.Star):
+		element, err := p.starNamedExpression()
+		if err != nil {
+			return nil, err
+		}
+		return p.setFrom(leftBrace, element)
 	}
 
-	// We need to look ahead to see what follows the first expression
-	// Try to parse the first expression
-	firstExpr, err := p.namedExpression()
+	first, err := p.namedExpression()
 	if err != nil {
-		// If we can't parse an expression, restore and try set
-		p.Current = startPos
-		return p.set()
+		return nil, err
 	}
 
-	// Check what follows the first expression
 	if p.check(lexer.Colon) {
-		// It's a dictionary pattern (key: value or comprehension)
-		// Consume the colon
-		p.match(lexer.Colon)
+		colon := p.advance()
+		if _, ok := first.(*ast.AssignExpr); ok {
+			return nil, p.error(colon, "assignment expression as dictionary key must be parenthesized")
+		}
 
-		// Parse the value expression
-		valueExpr, err := p.expression()
+		value, err := p.expression()
 		if err != nil {
-			p.Current = startPos
-			return p.set()
+			return nil, err
 		}
-
-		// Check if this is a dictionary comprehension
 		if p.check(lexer.For) || p.check(lexer.Async) {
-			// It's a dictionary comprehension
-			return p.dictComp(firstExpr, valueExpr, leftBrace)
+			return p.dictComp(first, value, leftBrace)
 		}
+		return p.dictFrom(leftBrace, &ast.KeyValuePair{
+			Key:   first,
+			Value: value,
+			Span:  lexer.Span{Start: first.GetSpan().Start, End: value.GetSpan().End},
+		})
+	}
 
-		// It's a regular dictionary
-		p.Current = startPos
-		return p.dict()
-	} else if p.check(lexer.For) || p.check(lexer.Async) {
-		// It's a set comprehension
-		return p.setComp(firstExpr, leftBrace)
-	} else {
-		// It's a regular set
-		p.Current = startPos
-		return p.set()
+	if p.check(lexer.For) || p.check(lexer.Async) {
+		return p.setComp(first, leftBrace)
 	}
+	return p.setFrom(leftBrace, first)
 }
 
 // dict parses a dictionary literal according to the grammar:
 // dict: '{' [double_starred_kvpairs] '}'
 func (p *Parser) dict() (ast.Expr, error) {
-	// Consume the opening '{'
 	leftBrace, err := p.consume(lexer.LeftBrace, "expected '{'")
 	if err != nil {
 		return nil, err
 	}
 
-	var pairs []ast.DictPair
-
-	// Check for empty dictionary
 	if p.check(lexer.RightBrace) {
-		// Empty dictionary
-		rightBrace, err := p.consume(lexer.RightBrace, "expected '}'")
-		if err != nil {
-			return nil, err
-		}
-
-		return &ast.DictExpr{
-			Pairs: pairs,
-			Span:  lexer.Span{Start: leftBrace.Start(), End: rightBrace.End()},
-		}, nil
+		return p.dictFrom(leftBrace, nil)
 	}
 
-	// Parse double_starred_kvpairs
-	pairs, err = p.doubleStarredKvpairs()
+	first, err := p.doubleStarredKvpair()
 	if err != nil {
 		return nil, err
 	}
+	return p.dictFrom(leftBrace, first)
+}
+
+// dictFrom parses the rest of a dictionary after its '{' and first pair, nil
+// for an empty dictionary:
+// double_starred_kvpairs: ','.double_starred_kvpair+ [',']
+func (p *Parser) dictFrom(leftBrace lexer.Token, first ast.DictPair) (ast.Expr, error) {
+	var pairs []ast.DictPair
+
+	if first != nil {
+		pairs = append(pairs, first)
+
+		// Parse additional pairs separated by commas
+		for p.match(lexer.Comma) {
+			// Allow trailing comma
+			if p.check(lexer.RightBrace) {
+				break
+			}
+
+			pair, err := p.doubleStarredKvpair()
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, pair)
+		}
+	}
 
 	// Consume the closing '}'
 	rightBrace, err := p.consume(lexer.RightBrace, "expected '}'")
@@ -114,35 +124,6 @@ func (p *Parser) dict() (ast.Expr, error) {
 	}, nil
 }
 
-// doubleStarredKvpairs parses a comma-separated list of dictionary pairs:
-// double_starred_kvpairs: ','.double_starred_kvpair+ [',']
-func (p *Parser) doubleStarredKvpairs() ([]ast.DictPair, error) {
-	var pairs []ast.DictPair
-
-	// Parse the first pair
-	pair, err := p.doubleStarredKvpair()
-	if err != nil {
-		return nil, err
-	}
-	pairs = append(pairs, pair)
-
-	// Parse additional pairs separated by commas
-	for p.match(lexer.Comma) {
-		// Allow trailing comma
-		if p.check(lexer.RightBrace) {
-			break
-		}
-
-		pair, err := p.doubleStarredKvpair()
-		if err != nil {
-			return nil, err
-		}
-		pairs = append(pairs, pair)
-	}
-
-	return pairs, nil
-}
-
 // doubleStarredKvpair parses either a starred expression or a key-value pair:
 // double_starred_kvpair: '**' bitwise_or | kvpair
 func (p *Parser) doubleStarredKvpair() (ast.DictPair, error) {
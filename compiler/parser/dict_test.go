@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
+	"strings"
 	"testing"
 )
 
@@ -197,3 +199,65 @@ func TestDictionaryEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// Test telling dictionaries from sets by their first entry
+func TestDictOrSetDisambiguation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string // Type of the parsed expression, or "" for an error
+	}{
+		{"empty braces", "{}", "*ast.DictExpr"},
+		{"spaced pair", "{ \"a\": 1 }", "*ast.DictExpr"},
+		{"leading unpack", "{**a, 'b': 2}", "*ast.DictExpr"},
+		{"lambda value", "{'k': lambda v: v}", "*ast.DictExpr"},
+		{"conditional key", "{a if b else c: 1}", "*ast.DictExpr"},
+		{"parenthesized walrus key", "{(a := 1): 2}", "*ast.DictExpr"},
+		{"single element", "{1}", "*ast.SetExpr"},
+		{"leading star", "{*a, b}", "*ast.SetExpr"},
+		{"walrus element", "{a := 1, 2}", "*ast.SetExpr"},
+		{"dict comprehension", "{k: v for k, v in items}", "*ast.DictComp"},
+		{"set comprehension", "{x for x in items}", "*ast.SetComp"},
+		{"unparenthesized walrus key", "{a := 1: 2}", ""},
+		{"key after elements", "{1, 2: 3}", ""},
+		{"element after pairs", "{1: 2, 3}", ""},
+		{"unpack in set", "{1, **a}", ""},
+		{"star in dict", "{1: 2, *a}", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expr, err := parseDictOrSet(t, test.input)
+			if test.expected == "" {
+				if err == nil {
+					t.Errorf("Expected error for %s, got %T", test.input, expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for %s: %v", test.input, err)
+			}
+			if got := fmt.Sprintf("%T", expr); got != test.expected {
+				t.Errorf("Expected %s for %s, got %s", test.expected, test.input, got)
+			}
+		})
+	}
+}
+
+// Test that nested displays are parsed once, not again after a lookahead
+func TestDictDeepNesting(t *testing.T) {
+	depth := 64
+	input := strings.Repeat("{'k': ", depth) + "1" + strings.Repeat("}", depth)
+
+	expr, err := parseDictOrSet(t, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < depth; i++ {
+		dict, ok := expr.(*ast.DictExpr)
+		if !ok || len(dict.Pairs) != 1 {
+			t.Fatalf("Expected a dict with one pair at depth %d, got %T", i, expr)
+		}
+		expr = dict.Pairs[0].(*ast.KeyValuePair).Value
+	}
+}
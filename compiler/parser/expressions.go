@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
@@ -290,7 +292,37 @@ func (p *Parser) namedExpression() (ast.Expr, error) {
 	}
 
 	// If not an assignment expression, parse a regular expression
-	return p.expression()
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.check(lexer.Walrus) {
+		return nil, p.walrusTargetError(expr)
+	}
+	return expr, nil
+}
+
+// walrusTargetError reports a ':=' whose target is not a plain name, such as
+// obj.attr := value, naming the kind of target as Python does.
+func (p *Parser) walrusTargetError(target ast.Expr) error {
+	kind := "expression"
+	switch target.(type) {
+	case *ast.Attribute:
+		kind = "attribute"
+	case *ast.Subscript:
+		kind = "subscript"
+	case *ast.TupleExpr:
+		kind = "tuple"
+	case *ast.ListExpr:
+		kind = "list"
+	case *ast.Literal:
+		kind = "literal"
+	case *ast.Call:
+		kind = "function call"
+	case *ast.StarExpr:
+		kind = "starred"
+	}
+	return p.error(p.peek(), fmt.Sprintf("cannot use assignment expressions with %s", kind))
 }
 
 func (p *Parser) starNamedExpression() (ast.Expr, error) {
@@ -3,6 +3,7 @@ package parser
 import (
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
+	"strings"
 	"testing"
 )
 
@@ -794,3 +795,55 @@ func containsString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestAssignmentExpressionPositions(t *testing.T) {
+	inputs := []string{
+		"ys = [y for x in data if (y := f(x))]\n",
+		"print(x, n := 10)\n",
+		"print(key=(n := 10))\n",
+		"if n := len(a):\n    pass\n",
+		"while chunk := read():\n    pass\n",
+		"x = a[n := 1]\n",
+		"s = {y := 1, 2}\n",
+		"c = f\"{(n := 3)}\"\n",
+		"view V(items):\n    <p>{n := len(items)}</p>\n",
+		"view V(items):\n    <p title={label := items[0]}>{label}</p>\n",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			module, errs := parseInput(t, input)
+			validateParseSuccess(t, module, errs, -1)
+		})
+	}
+}
+
+func TestAssignmentExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x := 1\n", "assignment expression cannot be a statement; use '=' or wrap it in parentheses"},
+		{"x.y := 1\n", "cannot use assignment expressions with attribute"},
+		{"x[0] := 1\n", "cannot use assignment expressions with subscript"},
+		{"(a, b) := 1\n", "cannot use assignment expressions with tuple"},
+		{"print(f() := 1)\n", "cannot use assignment expressions with function call"},
+		{"ys = [y for x in data if y := f(x)]\n", "assignment expression in a comprehension condition must be parenthesized"},
+		{"ys = [i := 0 for i in x]\n", "assignment expression cannot rebind comprehension iteration variable 'i'"},
+		{"ys = [[(j := 0) for k in y] for j in x]\n", "assignment expression cannot rebind comprehension iteration variable 'j'"},
+		{"ys = [a for a in (b := x)]\n", "assignment expression cannot be used in a comprehension iterable expression"},
+		{"view V(item):\n    <p>{item.name := 1}</p>\n", "cannot use assignment expressions with attribute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, errs := parseInput(t, tt.input)
+			if len(errs) == 0 {
+				t.Fatalf("Expected an error for %q", tt.input)
+			}
+			if !strings.Contains(errs[0].Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %q", tt.expected, errs[0].Error())
+			}
+		})
+	}
+}
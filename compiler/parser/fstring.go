@@ -1,10 +1,82 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
+// strings parses adjacent string literals, which Python joins into one:
+// strings: (fstring | string)+
+// Plain literals are folded into a single Literal, while a run with an
+// f-string becomes a ConcatenatedString. Bytes cannot be joined with str.
+func (p *Parser) strings() (ast.Expr, error) {
+	var parts []ast.Expr
+	var tokens []lexer.Token // The tokens of plain literals, in order
+	bytes := 0
+	for p.check(lexer.String) || p.check(lexer.FStringStart) {
+		if p.check(lexer.FStringStart) {
+			fstring, err := p.fstring()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, fstring)
+			continue
+		}
+		token := p.advance()
+		literalType := ast.LiteralTypeString
+		if _, ok := token.Literal.([]byte); ok {
+			literalType = ast.LiteralTypeBytes
+			bytes++
+		}
+		tokens = append(tokens, token)
+		parts = append(parts, &ast.Literal{
+			Token: token,
+			Value: token.Literal,
+			Type:  literalType,
+
+			Span: lexer.Span{Start: token.Start(), End: token.End()},
+		})
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+
+	span := lexer.Span{Start: parts[0].GetSpan().Start, End: parts[len(parts)-1].GetSpan().End}
+	if bytes > 0 && bytes < len(parts) {
+		return nil, p.error(lexer.Token{Type: lexer.String, Span: span}, "cannot mix bytes and nonbytes literals")
+	}
+	if len(tokens) < len(parts) {
+		return &ast.ConcatenatedString{Parts: parts, Span: span}, nil
+	}
+
+	// Only plain literals: fold them, keeping their lexemes for the source
+	lexemes := make([]string, len(tokens))
+	var text []byte
+	for i, token := range tokens {
+		lexemes[i] = token.Lexeme
+		switch value := token.Literal.(type) {
+		case string:
+			text = append(text, value...)
+		case []byte:
+			text = append(text, value...)
+		}
+	}
+	var value any = string(text)
+	literalType := ast.LiteralTypeString
+	if bytes > 0 {
+		value, literalType = text, ast.LiteralTypeBytes
+	}
+	return &ast.Literal{
+		Token: lexer.Token{Type: lexer.String, Lexeme: strings.Join(lexemes, " "), Literal: value, Span: span},
+		Value: value,
+		Type:  literalType,
+
+		Span: span,
+	}, nil
+}
+
 // fstring parses an f-string literal according to the grammar:
 // fstring: FSTRING_START fstring_middle* FSTRING_END
 func (p *Parser) fstring() (ast.Expr, error) {
@@ -25,8 +97,9 @@ func (p *Parser) fstring() (ast.Expr, error) {
 			}
 
 			middle := &ast.FStringMiddle{
-				Value: middleToken.Literal.(string),
-				Span:  lexer.Span{Start: middleToken.Start(), End: middleToken.End()},
+				Value:  middleToken.Literal.(string),
+				Lexeme: middleToken.Lexeme,
+				Span:   lexer.Span{Start: middleToken.Start(), End: middleToken.End()},
 			}
 			parts = append(parts, middle)
 		} else if p.check(lexer.LeftBraceF) {
@@ -49,6 +122,7 @@ func (p *Parser) fstring() (ast.Expr, error) {
 
 	return &ast.FString{
 		Parts: parts,
+		Quote: startToken.Lexeme,
 		Span:  lexer.Span{Start: startToken.Start(), End: endToken.End()},
 	}, nil
 }
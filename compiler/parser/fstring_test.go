@@ -449,3 +449,73 @@ func TestFStringEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestImplicitStringConcatenation(t *testing.T) {
+	t.Run("plain strings fold into one literal", func(t *testing.T) {
+		expr, err := parseFString(t, `"a\n" 'b' r"\d" u"c"`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lit, ok := expr.(*ast.Literal)
+		if !ok {
+			t.Fatalf("Expected *ast.Literal, got %T", expr)
+		}
+		if lit.Value != "a\nb\\dc" || lit.Type != ast.LiteralTypeString {
+			t.Errorf("Expected the joined string, got %q (type %v)", lit.Value, lit.Type)
+		}
+		if lit.Token.Lexeme != `"a\n" 'b' r"\d" u"c"` {
+			t.Errorf("Expected the lexemes to be kept, got %q", lit.Token.Lexeme)
+		}
+	})
+
+	t.Run("bytes fold into bytes", func(t *testing.T) {
+		expr, err := parseFString(t, `b"a" rb"\x"`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		lit, ok := expr.(*ast.Literal)
+		if !ok || lit.Type != ast.LiteralTypeBytes {
+			t.Fatalf("Expected a bytes literal, got %#v", expr)
+		}
+		if value, ok := lit.Value.([]byte); !ok || string(value) != `a\x` {
+			t.Errorf("Expected b\"a\\\\x\", got %#v", lit.Value)
+		}
+	})
+
+	t.Run("runs with an f-string keep their parts", func(t *testing.T) {
+		expr, err := parseFString(t, `"a" f"{b}" 'c'`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		concat, ok := expr.(*ast.ConcatenatedString)
+		if !ok {
+			t.Fatalf("Expected *ast.ConcatenatedString, got %T", expr)
+		}
+		if len(concat.Parts) != 3 {
+			t.Fatalf("Expected 3 parts, got %d", len(concat.Parts))
+		}
+		if _, ok := concat.Parts[1].(*ast.FString); !ok {
+			t.Errorf("Expected the f-string as the second part, got %T", concat.Parts[1])
+		}
+	})
+
+	t.Run("bytes and str cannot be mixed", func(t *testing.T) {
+		for _, input := range []string{`b"a" "b"`, `"a" b"b"`, `b"a" f"{b}"`} {
+			_, err := parseFString(t, input)
+			if err == nil || !strings.Contains(err.Error(), "cannot mix bytes and nonbytes literals") {
+				t.Errorf("Expected a mixing error for %s, got %v", input, err)
+			}
+		}
+	})
+
+	t.Run("f-string text keeps its lexeme", func(t *testing.T) {
+		expr, err := parseFString(t, `f"{{x}}\t{y}"`)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		middle := validateFString(t, expr, 2).Parts[0].(*ast.FStringMiddle)
+		if middle.Value != "{x}\t" || middle.Lexeme != `{{x}}\t` {
+			t.Errorf("Expected value %q and lexeme %q, got %q and %q", "{x}\t", `{{x}}\t`, middle.Value, middle.Lexeme)
+		}
+	})
+}
@@ -59,7 +59,7 @@ func (p *Parser) functionDefRaw() (ast.Stmt, error) {
 
 	// Parse optional type parameters
 	var typeParams []*ast.TypeParam
-	if p.check(lexer.LeftBracket) {
+	if p.match(lexer.LeftBracket) {
 		// Get the type parameters using the existing parser function
 		paramExprs, err := p.typeParams()
 		if err != nil {
@@ -123,7 +123,7 @@ func (p *Parser) functionDefRaw() (ast.Stmt, error) {
 	// Ignore func_type_comment as instructed
 
 	// Parse function body
-	body, err := p.block()
+	body, err := p.scopeBody(p.block)
 	if err != nil {
 		return nil, err
 	}
@@ -169,6 +169,16 @@ func TestFunctionDefinition(t *testing.T) {
 			expectedAnnotated: 2,
 			description:       "function with type hints",
 		},
+		{
+			name: "generic function",
+			input: `def first[T: (int, str)](items: list[T]) -> T:
+    return items[0]`,
+			expectedName:      "first",
+			expectedReturn:    true,
+			expectedParams:    1,
+			expectedAnnotated: 1,
+			description:       "function with PEP 695 type parameters",
+		},
 		{
 			name: "async function",
 			input: `async def fetch_data():
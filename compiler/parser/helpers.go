@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
@@ -9,11 +13,28 @@ func (p *Parser) consume(t lexer.TokenType, message string) (lexer.Token, error)
 		return p.advance(), nil
 	}
 
-	return lexer.Token{}, p.error(p.peek(), message)
+	return lexer.Token{}, &ParseError{Token: p.peek(), Message: message, Code: diagnostics.CodeSyntax, Expected: []lexer.TokenType{t}}
+}
+
+// consumeConditionColon consumes the ':' that ends the condition of an if,
+// elif or while. A '=' in its place is most likely a comparison written as an
+// assignment, so the error suggests '=='.
+func (p *Parser) consumeConditionColon(keyword string) (lexer.Token, error) {
+	token, err := p.consume(lexer.Colon, fmt.Sprintf("expected ':' after %s condition", keyword))
+	if err != nil && p.check(lexer.Equal) {
+		parseErr := err.(*ParseError)
+		parseErr.Message = fmt.Sprintf("cannot assign with '=' in %s %s condition", article(keyword), keyword)
+		parseErr.Suggestions = []Suggestion{{
+			Span:        p.peek().Span,
+			Replacement: "==",
+			Message:     "did you mean '=='?",
+		}}
+	}
+	return token, err
 }
 
 func (p *Parser) error(token lexer.Token, message string) error {
-	return &ParseError{Token: token, Message: message}
+	return &ParseError{Token: token, Message: message, Code: diagnostics.CodeSyntax}
 }
 
 // match checks if the current token is one of the given types.
@@ -53,10 +74,12 @@ func (p *Parser) isAtEnd() bool {
 }
 
 func (p *Parser) peek() lexer.Token {
+	p.load(p.Current)
 	return p.Tokens[p.Current]
 }
 
 func (p *Parser) peekN(n int) lexer.Token {
+	p.load(p.Current + n)
 	return p.Tokens[p.Current+n]
 }
 
@@ -106,3 +129,11 @@ func (p *Parser) isKeyword(t lexer.TokenType) bool {
 		return false
 	}
 }
+
+// article returns the indefinite article of a keyword: "an if", "a while"
+func article(keyword string) string {
+	if strings.ContainsRune("aeiou", rune(keyword[0])) {
+		return "an"
+	}
+	return "a"
+}
@@ -20,7 +20,7 @@ func (p *Parser) ifStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after if condition")
+	_, err = p.consumeConditionColon("if")
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +84,7 @@ func (p *Parser) elifStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after elif condition")
+	_, err = p.consumeConditionColon("elif")
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +95,9 @@ func (p *Parser) importFrom() (ast.Stmt, error) {
 
 	// Check for '*'
 	if p.match(lexer.Star) {
+		if p.scopeDepth > 0 {
+			return nil, p.error(p.previous(), "import * only allowed at module level")
+		}
 		isWildcard = true
 		names = []*ast.ImportName{}
 	} else if p.match(lexer.LeftParen) {
@@ -122,7 +125,7 @@ func (p *Parser) importFrom() (ast.Stmt, error) {
 
 	// Determine the end position
 	var endPos lexer.Position
-	if isWildcard {
+	if isWildcard || p.previous().Type == lexer.RightParen {
 		endPos = p.previous().End()
 	} else if len(names) > 0 {
 		endPos = names[len(names)-1].GetSpan().End
@@ -470,6 +470,78 @@ func TestWildcardImports(t *testing.T) {
 	}
 }
 
+func TestWildcardImportPlacement(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		errorLine int // Line of the expected error, or 0 for none
+	}{
+		{
+			name:  "module level",
+			input: "from math import *\n",
+		},
+		{
+			name:  "module level conditional",
+			input: "if DEBUG:\n    from debug import *\n",
+		},
+		{
+			name:      "function body",
+			input:     "def f():\n    from math import *\n",
+			errorLine: 2,
+		},
+		{
+			name:      "class body",
+			input:     "class A:\n    x = 1\n    from math import *\n",
+			errorLine: 3,
+		},
+		{
+			name:      "view body",
+			input:     "view Page():\n    from math import *\n    <p>hi</p>\n",
+			errorLine: 2,
+		},
+		{
+			name:      "nested in a function",
+			input:     "def f():\n    if x:\n        from math import *\n",
+			errorLine: 3,
+		},
+		{
+			name:  "after a function",
+			input: "def f():\n    pass\nfrom math import *\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := lexer.NewScanner([]byte(test.input))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Lexer errors encountered: %v", scanner.Errors)
+			}
+			_, errs := NewParser(tokens).Parse()
+
+			if test.errorLine == 0 {
+				if len(errs) > 0 {
+					t.Fatalf("Unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) == 0 {
+				t.Fatal("Expected an error but got none")
+			}
+			parseErr, ok := errs[0].(*ParseError)
+			if !ok {
+				t.Fatalf("Expected a *ParseError, got %T", errs[0])
+			}
+			if !strings.Contains(parseErr.Message, "import * only allowed at module level") {
+				t.Errorf("Expected the module level error, got %q", parseErr.Message)
+			}
+			if parseErr.Token.Start().Line != test.errorLine {
+				t.Errorf("Expected the error on line %d, got %d", test.errorLine, parseErr.Token.Start().Line)
+			}
+		})
+	}
+}
+
 // Test parenthesized imports
 func TestParenthesizedImports(t *testing.T) {
 	tests := []struct {
@@ -515,12 +515,16 @@ func (p *Parser) literalPattern() (ast.Pattern, error) {
 		}
 
 	case p.check(lexer.String):
-		token, _ := p.consume(lexer.String, "")
-		expr = &ast.Literal{
-			Value: token.Literal,
-			Token: token,
-			Span:  lexer.Span{Start: token.Start(), End: token.End()},
+		// Adjacent strings are joined, but f-strings are not literals
+		token := p.peek()
+		literal, err := p.strings()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := literal.(*ast.Literal); !ok {
+			return nil, p.error(token, "patterns may only match literals and attribute lookups")
 		}
+		expr = literal
 	case p.check(lexer.None):
 		token, _ := p.consume(lexer.None, "")
 		expr = &ast.Literal{
@@ -621,3 +621,21 @@ func TestMatchEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchConcatenatedStringPattern(t *testing.T) {
+	stmt, err := parseMatchStatement(t, "match x:\n    case \"a\" 'b':\n        pass\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pattern, ok := validateMatchStatement(t, stmt, 1, false).Cases[0].Patterns[0].(*ast.LiteralPattern)
+	if !ok {
+		t.Fatalf("Expected a literal pattern, got %T", stmt.(*ast.MatchStmt).Cases[0].Patterns[0])
+	}
+	if lit, ok := pattern.Value.(*ast.Literal); !ok || lit.Value != "ab" {
+		t.Errorf("Expected the joined string \"ab\", got %#v", pattern.Value)
+	}
+
+	if _, err := parseMatchStatement(t, "match x:\n    case \"a\" f\"{b}\":\n        pass\n"); err == nil {
+		t.Error("Expected an error for an f-string in a pattern")
+	}
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"context"
 	"fmt"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
@@ -14,6 +17,24 @@ type Parser struct {
 	Current        int
 	Errors         []error
 	tempVarCounter int
+	openTags       []lexer.Token // Tag names of the HTML elements being parsed, innermost last
+	scopeDepth     int           // Function, class and view bodies being parsed
+	ctx            context.Context
+
+	// Syntax is the grammar version of the file, from its pragma; constructs
+	// introduced by later versions are reported
+	Syntax lexer.SyntaxVersion
+
+	// Source of the tokens still to be read, nil once its EOF is in Tokens
+	// or when the parser was given every token up front
+	source   TokenSource
+	streamed bool // Tokens come from a source and may be released
+}
+
+// TokenSource produces tokens one at a time, ending with EOF. A
+// *lexer.Scanner is one.
+type TokenSource interface {
+	NextToken() lexer.Token
 }
 
 // NewParser returns a new parser instance.
@@ -23,11 +44,49 @@ func NewParser(tokens []lexer.Token) *Parser {
 		Current:        0,
 		Errors:         []error{},
 		tempVarCounter: 0,
+		ctx:            context.Background(),
+		Syntax:         lexer.CurrentSyntax,
 	}
 }
 
-// Parse parses the tokens and returns a list of statements.
-// It will attempt to recover from errors and return all encountered errors.
+// NewStreamingParser returns a parser reading tokens from source as it needs
+// them, instead of scanning the whole file first. Tokens of the statements
+// already parsed are released, so memory does not grow with the file, and
+// Tokens only holds those of the statement being parsed. Scanner errors
+// found so far can be checked while parsing, through the scanner.
+//
+// Streaming is opt-in, for tools parsing large files: the compiler scans
+// each file in full before parsing it, so that scanning and parsing are
+// timed as separate stages and scanner errors are reported on their own.
+func NewStreamingParser(source TokenSource) *Parser {
+	p := NewParser(nil)
+	p.source = source
+	p.streamed = true
+	if scanner, ok := source.(*lexer.Scanner); ok {
+		p.Syntax = scanner.Syntax()
+	}
+	return p
+}
+
+// ParseContext parses like Parse but stops before the next statement once
+// ctx is cancelled, reporting ctx's error.
+func (p *Parser) ParseContext(ctx context.Context) (*ast.Module, []error) {
+	p.ctx = ctx
+	return p.Parse()
+}
+
+// checkCancelled returns the context error once parsing has been cancelled
+func (p *Parser) checkCancelled() error {
+	if p.ctx == nil {
+		return nil
+	}
+	return p.ctx.Err()
+}
+
+// Parse parses the tokens and returns the module with every statement that
+// parsed. A statement with a syntax error is recorded and skipped up to the
+// next statement, so one run reports all the errors of a file; the partial
+// module is returned alongside them for tools that can work with it.
 func (p *Parser) Parse() (*ast.Module, []error) {
 	stmts := []ast.Stmt{}
 
@@ -46,10 +105,21 @@ func (p *Parser) Parse() (*ast.Module, []error) {
 			break
 		}
 
+		if err := p.checkCancelled(); err != nil {
+			p.Errors = append(p.Errors, err)
+			break
+		}
+
+		p.release()
+		start := p.Current
 		stmt, err := p.statement()
 		if err != nil {
-			p.Errors = append(p.Errors, err)
-			return nil, p.Errors
+			p.recoverStatement(start, err)
+			// A stray dedent ends no block at module level
+			if p.Current == start {
+				p.advance()
+			}
+			continue
 		}
 		// Unwrap MultiStmt nodes at module level
 		unwrapped := unwrapMultiStmt(stmt)
@@ -59,9 +129,148 @@ func (p *Parser) Parse() (*ast.Module, []error) {
 	return &ast.Module{Body: stmts}, p.Errors
 }
 
+// releaseThreshold is how many tokens of parsed statements a streaming
+// parser lets pile up before releasing them
+const releaseThreshold = 1024
+
+// release drops the tokens before the current one, at the start of a
+// module-level statement, when the tokens are streamed. The previous token
+// is kept for lookbehind.
+func (p *Parser) release() {
+	keep := p.Current - 1
+	if !p.streamed || keep < releaseThreshold {
+		return
+	}
+	n := copy(p.Tokens, p.Tokens[keep:])
+	clear(p.Tokens[n:])
+	p.Tokens = p.Tokens[:n]
+	p.Current -= keep
+}
+
+// load makes the token at index i available, reading tokens from the
+// source as needed, and reports whether there is one
+func (p *Parser) load(i int) bool {
+	for i >= len(p.Tokens) && p.source != nil {
+		token := p.source.NextToken()
+		p.Tokens = append(p.Tokens, token)
+		if token.Type == lexer.EOF {
+			p.source = nil
+		}
+	}
+	return i < len(p.Tokens)
+}
+
+// recoverStatement records err, raised by the statement starting at token
+// start, and skips the rest of that statement: its line, any indented block
+// and what continues it after the block (elif, else, except and finally
+// clauses, or the closing tag of an element). Parsing resumes at the next
+// statement of the same block, or at the dedent closing the block.
+func (p *Parser) recoverStatement(start int, err error) {
+	p.Errors = append(p.Errors, err)
+
+	// Blocks of the statement entered before the error
+	depth := 0
+	for _, token := range p.Tokens[start:p.Current] {
+		switch token.Type {
+		case lexer.Indent:
+			depth++
+		case lexer.Dedent:
+			depth--
+		}
+	}
+	depth = max(depth, 0)
+
+	// The error was raised after the whole statement was parsed
+	if depth == 0 && p.Current > start {
+		if previous := p.previous().Type; previous == lexer.Newline || previous == lexer.Dedent {
+			return
+		}
+	}
+
+	for !p.isAtEnd() {
+		tokenType := p.peek().Type
+		if tokenType == lexer.Dedent && depth == 0 {
+			return
+		}
+		p.advance()
+		switch tokenType {
+		case lexer.Indent:
+			depth++
+		case lexer.Dedent:
+			depth--
+		case lexer.Newline:
+		default:
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		switch p.peek().Type {
+		case lexer.Newline, lexer.Indent, lexer.Elif, lexer.Else, lexer.Except, lexer.Finally:
+			continue
+		case lexer.TagCloseStart:
+			// The closing tag of an element whose content was skipped
+			if tokenType == lexer.Dedent {
+				continue
+			}
+		}
+		return
+	}
+}
+
+// ParseExpression parses src as a single Python expression, without wrapping
+// it in a module. Scanner and parser diagnostics are both returned; the
+// expression is nil when it could not be parsed.
+func ParseExpression(src []byte) (ast.Expr, []error) {
+	scanner := lexer.NewScanner(src)
+	tokens := scanner.ScanTokens()
+	errs := append([]error{}, scanner.Errors...)
+
+	p := NewParser(tokens)
+	for p.check(lexer.Newline) || p.check(lexer.Indent) {
+		p.advance()
+	}
+	if p.isAtEnd() {
+		return nil, append(errs, p.error(p.peek(), "expected expression"))
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, append(errs, err)
+	}
+
+	for p.check(lexer.Newline) || p.check(lexer.Dedent) {
+		p.advance()
+	}
+	if !p.isAtEnd() {
+		return nil, append(errs, p.error(p.peek(), "unexpected token after expression"))
+	}
+	return expr, append(errs, p.Errors...)
+}
+
 // ParseError is an error that occurs in the parser.
 type ParseError struct {
-	Token   lexer.Token
+	Token       lexer.Token
+	Message     string
+	Hint        string            // Optional suggestion shown below the code frame
+	Related     []RelatedSpan     // Other source locations that explain the error
+	Code        diagnostics.Code  // Kind of error; syntax errors when empty
+	Expected    []lexer.TokenType // Token types that would have been accepted instead of Token
+	Suggestions []Suggestion      // Fixes a tool can apply, such as '==' for '=' in a condition
+}
+
+// Suggestion is a fix for a parse error: replacing the source text at Span
+// with Replacement.
+type Suggestion struct {
+	Span        lexer.Span
+	Replacement string
+	Message     string
+}
+
+// RelatedSpan points at a secondary source location of an error, such as the
+// opening tag of an element whose closing tag is wrong.
+type RelatedSpan struct {
+	Span    lexer.Span
 	Message string
 }
 
@@ -78,9 +287,48 @@ func (e *ParseError) Span() lexer.Span {
 	return e.Token.Span
 }
 
+// Diagnostic returns the structured form of the error, with a label for
+// each related span.
+func (e *ParseError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     e.Code,
+		Message:  e.Message,
+		Span:     lexer.DiagnosticSpan(e.Token.Span),
+	}
+	if d.Code == "" {
+		d.Code = diagnostics.CodeSyntax
+	}
+	for _, related := range e.Related {
+		d.Labels = append(d.Labels, diagnostics.Label{Span: lexer.DiagnosticSpan(related.Span), Message: related.Message})
+	}
+	if e.Hint != "" {
+		d.Hints = append(d.Hints, e.Hint)
+	}
+	for _, expected := range e.Expected {
+		d.Expected = append(d.Expected, expected.Describe())
+	}
+	for _, suggestion := range e.Suggestions {
+		d.Suggestions = append(d.Suggestions, diagnostics.Suggestion{
+			Span:        lexer.DiagnosticSpan(suggestion.Span),
+			Replacement: suggestion.Replacement,
+			Message:     suggestion.Message,
+		})
+	}
+	return d
+}
+
+// CodeFrame renders the error with a code frame for the offending token,
+// followed by a frame for each related span and the hint, if any.
+func (e *ParseError) CodeFrame(src []byte, filename string) string {
+	d := e.Diagnostic()
+	d.File = filename
+	return diagnostics.Renderer{}.Render(d, src)
+}
+
 // NewParseError creates a new ParseError.
 func NewParseError(token lexer.Token, message string) *ParseError {
-	return &ParseError{Token: token, Message: message}
+	return &ParseError{Token: token, Message: message, Code: diagnostics.CodeSyntax}
 }
 
 // unwrapMultiStmt takes a statement and returns a slice of statements.
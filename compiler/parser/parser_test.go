@@ -1,6 +1,13 @@
 package parser
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"strings"
@@ -61,8 +68,8 @@ func validateParseResultError(t *testing.T, module *ast.Module, errors []error,
 		t.Fatal("Expected error but got none")
 	}
 
-	if module != nil {
-		t.Error("Expected nil module on error but got module")
+	if module == nil {
+		t.Error("Expected the partial module alongside the errors but got nil")
 	}
 
 	if expectedErrorText != "" {
@@ -421,6 +428,87 @@ func TestParseRecovery(t *testing.T) {
 	}
 }
 
+func TestParseReportsAllErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		errorLines []int
+		body       []string // Types of the statements of the partial module
+	}{
+		{
+			name:       "top-level statements",
+			input:      "import 1\ny = 2\ndel )\nw = 3\n",
+			errorLines: []int{1, 3},
+			body:       []string{"*ast.AssignStmt", "*ast.AssignStmt"},
+		},
+		{
+			name: "statement in a block",
+			input: `def f():
+    x = 1
+    y = = 2
+    return x
+
+def g():
+    return ]
+`,
+			errorLines: []int{3, 7},
+			body:       []string{"*ast.Function", "*ast.Function"},
+		},
+		{
+			name: "compound statement with clauses",
+			input: `if x
+    a = 1
+else:
+    b = 2
+c = 3
+`,
+			errorLines: []int{1},
+			body:       []string{"*ast.AssignStmt"},
+		},
+		{
+			name: "element in a view",
+			input: `view V():
+    <div class=>
+        <p>text</p>
+    </div>
+    <span>{x</span>
+    <p>ok</p>
+`,
+			errorLines: []int{2, 5},
+			body:       []string{"*ast.ViewStmt"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, errs := parseInput(t, test.input)
+			if module == nil {
+				t.Fatal("expected the partial module, got nil")
+			}
+
+			var lines []int
+			for _, err := range errs {
+				parseErr, ok := err.(*ParseError)
+				if !ok {
+					t.Fatalf("expected *ParseError, got %T: %v", err, err)
+				}
+				lines = append(lines, parseErr.Span().Start.Line)
+			}
+			if fmt.Sprint(lines) != fmt.Sprint(test.errorLines) {
+				t.Errorf("expected errors on lines %v, got %v: %v", test.errorLines, lines, errs)
+			}
+
+			var body []string
+			for _, stmt := range module.Body {
+				body = append(body, fmt.Sprintf("%T", stmt))
+			}
+			if fmt.Sprint(body) != fmt.Sprint(test.body) {
+				t.Errorf("expected statements %v, got %v", test.body, body)
+			}
+		})
+	}
+}
+
 func TestParseComplexPrograms(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -595,3 +683,160 @@ func TestParserErrorAccumulation(t *testing.T) {
 		})
 	}
 }
+
+func TestParseContextCancelled(t *testing.T) {
+	parser, _ := createParserWithTokens(t, "x = 1\ny = 2\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	module, errs := parser.ParseContext(ctx)
+	if module == nil || len(module.Body) != 0 {
+		t.Errorf("expected an empty module from a parse cancelled before it started, got %v", module)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", errs)
+	}
+}
+
+func TestParseExpression(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string // Go type of the parsed expression
+		errMsg   string
+	}{
+		{name: "binary", input: "a + b * 2", expected: "*ast.Binary"},
+		{name: "call with trailing newline", input: "f(x, y=1)\n", expected: "*ast.Call"},
+		{name: "leading whitespace", input: "  items[0]", expected: "*ast.Subscript"},
+		{name: "multiline in brackets", input: "[\n  1,\n  2,\n]", expected: "*ast.ListExpr"},
+		{name: "empty", input: "", errMsg: "expected expression"},
+		{name: "statement", input: "x = 1", errMsg: "unexpected token after expression"},
+		{name: "two expressions", input: "a\nb", errMsg: "unexpected token after expression"},
+		{name: "incomplete", input: "a +", errMsg: "expected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, errs := ParseExpression([]byte(tt.input))
+
+			if tt.errMsg != "" {
+				if expr != nil {
+					t.Errorf("expected no expression, got %T", expr)
+				}
+				if len(errs) == 0 || !strings.Contains(errs[len(errs)-1].Error(), tt.errMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errMsg, errs)
+				}
+				return
+			}
+
+			if len(errs) != 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if got := fmt.Sprintf("%T", expr); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseExpressionScannerErrors(t *testing.T) {
+	_, errs := ParseExpression([]byte(`"unterminated`))
+	if len(errs) == 0 {
+		t.Fatal("expected scanner errors to be reported")
+	}
+	var scanErr *lexer.ScannerError
+	if !errors.As(errs[0], &scanErr) {
+		t.Errorf("expected first error to be a scanner error, got %T", errs[0])
+	}
+}
+
+func TestParseErrorStructure(t *testing.T) {
+	t.Run("expected token", func(t *testing.T) {
+		_, errs := parseInput(t, "def f()\n    pass\n")
+		if len(errs) == 0 {
+			t.Fatal("expected an error")
+		}
+		parseErr, ok := errs[0].(*ParseError)
+		if !ok {
+			t.Fatalf("expected *ParseError, got %T", errs[0])
+		}
+		if len(parseErr.Expected) != 1 || parseErr.Expected[0] != lexer.Colon {
+			t.Errorf("expected ':' to be recorded as the accepted token, got %v (%v)", parseErr.Expected, parseErr)
+		}
+	})
+
+	for _, input := range []string{
+		"if x = 1:\n    pass\n",
+		"if a:\n    pass\nelif x = 1:\n    pass\n",
+		"while x = 1:\n    pass\n",
+		"view V(x):\n    if x = 1:\n        <p>one</p>\n",
+	} {
+		t.Run(input, func(t *testing.T) {
+			_, errs := parseInput(t, input)
+			if len(errs) == 0 {
+				t.Fatal("expected an error")
+			}
+			d := errs[0].(*ParseError).Diagnostic()
+			if !strings.Contains(d.Message, "cannot assign with '=' in") {
+				t.Errorf("unexpected message %q", d.Message)
+			}
+			if len(d.Expected) != 1 || d.Expected[0] != "':'" {
+				t.Errorf("expected ':' to be the expected token, got %v", d.Expected)
+			}
+			if len(d.Suggestions) != 1 || d.Suggestions[0].Replacement != "==" || d.Suggestions[0].Span != d.Span {
+				t.Errorf("expected a '==' suggestion at the '=', got %+v (span %+v)", d.Suggestions, d.Span)
+			}
+		})
+	}
+}
+
+func TestStreamingParserMatchesParser(t *testing.T) {
+	files, err := filepath.Glob("../testdata/input/*/*.psx")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No test inputs found: %v", err)
+	}
+	sources := map[string]string{
+		"errors": "x = (1,\ndef f(:\n    pass\ny = 2\nview V():\n    <div>\n",
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Reading %s: %v", file, err)
+		}
+		sources[file] = string(src)
+	}
+
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			want, wantErrs := NewParser(lexer.NewScanner([]byte(src)).ScanTokens()).Parse()
+			got, gotErrs := NewStreamingParser(lexer.NewScanner([]byte(src))).Parse()
+			if !reflect.DeepEqual(got, want) {
+				t.Error("Streaming parser produced a different module")
+			}
+			if fmt.Sprint(gotErrs) != fmt.Sprint(wantErrs) {
+				t.Errorf("Streaming parser errors %v differ from %v", gotErrs, wantErrs)
+			}
+		})
+	}
+}
+
+func TestStreamingParserReleasesTokens(t *testing.T) {
+	var src strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&src, "view Row%d(item):\n    <tr><td>{item.name}</td></tr>\n\n", i)
+	}
+	scanner := lexer.NewScanner([]byte(src.String()))
+	parser := NewStreamingParser(scanner)
+
+	module, errs := parser.Parse()
+	if len(errs) > 0 || len(scanner.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v %v", errs, scanner.Errors)
+	}
+	if len(module.Body) != 1000 {
+		t.Fatalf("Expected 1000 views, got %d", len(module.Body))
+	}
+	if len(parser.Tokens) > 2*releaseThreshold {
+		t.Errorf("Expected at most %d tokens kept, got %d", 2*releaseThreshold, len(parser.Tokens))
+	}
+}
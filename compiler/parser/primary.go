@@ -128,7 +128,7 @@ func (p *Parser) atom() (ast.Expr, error) {
 		}, nil
 	}
 
-	if p.match(lexer.Number, lexer.String) {
+	if p.match(lexer.Number) {
 		return &ast.Literal{
 			Token: p.previous(),
 			Value: p.previous().Literal,
@@ -138,13 +138,13 @@ func (p *Parser) atom() (ast.Expr, error) {
 		}, nil
 	}
 
-	if p.check(lexer.FStringStart) {
-		// Backtrack since we consumed the FStringStart token
-		return p.fstring()
+	if p.check(lexer.String) || p.check(lexer.FStringStart) {
+		return p.strings()
 	}
 
 	if p.match(lexer.Ellipsis) {
 		return &ast.Literal{
+			Token: p.previous(),
 			Value: nil,
 			Type:  ast.LiteralTypeNone,
 
@@ -5,22 +5,24 @@ import (
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
+// set parses a set literal according to the grammar:
+// set: '{' star_named_expressions '}'
 func (p *Parser) set() (ast.Expr, error) {
-	// Expect opening brace
 	leftBrace, err := p.consume(lexer.LeftBrace, "expected '{'")
 	if err != nil {
 		return nil, err
 	}
 
-	elements := []ast.Expr{}
-
-	// Parse elements
-	// First element
-	expr, err := p.starNamedExpression()
+	first, err := p.starNamedExpression()
 	if err != nil {
 		return nil, err
 	}
-	elements = append(elements, expr)
+	return p.setFrom(leftBrace, first)
+}
+
+// setFrom parses the rest of a set after its '{' and first element
+func (p *Parser) setFrom(leftBrace lexer.Token, first ast.Expr) (ast.Expr, error) {
+	elements := []ast.Expr{first}
 
 	// Parse additional elements separated by commas
 	for p.match(lexer.Comma) {
@@ -36,6 +38,11 @@ func (p *Parser) set() (ast.Expr, error) {
 		elements = append(elements, expr)
 	}
 
+	// A key among set elements, as in {1, 2: 3}
+	if p.check(lexer.Colon) {
+		return nil, p.error(p.peek(), "cannot mix set elements and dictionary entries")
+	}
+
 	// Expect closing brace
 	rightBrace, err := p.consume(lexer.RightBrace, "expected '}'")
 	if err != nil {
@@ -44,7 +51,6 @@ func (p *Parser) set() (ast.Expr, error) {
 
 	return &ast.SetExpr{
 		Elements: elements,
-
-		Span: lexer.Span{Start: leftBrace.Start(), End: rightBrace.End()},
+		Span:     lexer.Span{Start: leftBrace.Start(), End: rightBrace.End()},
 	}, nil
 }
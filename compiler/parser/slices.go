@@ -59,6 +59,11 @@ func (p *Parser) slice() (ast.Expr, error) {
 		}, nil
 	}
 
+	// An index may be an assignment expression: items[i := i + 1]
+	if p.check(lexer.Identifier) && p.checkNext(lexer.Walrus) {
+		return p.namedExpression()
+	}
+
 	// There's an expression before any potential colon
 	expr, err := p.expression()
 	if err != nil {
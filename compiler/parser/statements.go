@@ -38,17 +38,32 @@ func (p *Parser) statement() (ast.Stmt, error) {
 		if p.checkNext(lexer.Def) {
 			return p.functionDef()
 		}
+		if p.checkNext(lexer.View) || p.checkLayout(1) {
+			return p.viewStatement()
+		}
 		// Other async statements will be handled here as they're implemented
 		// Fall through to simple statements for now
 	case lexer.At:
 		// Parse a decorator and the statement it decorates
 		return p.decorator()
+	case lexer.Identifier:
+		if p.checkLayout(0) {
+			return p.viewStatement()
+		}
 	}
 
 	// Fall back to simple statements
 	return p.simpleStatement()
 }
 
+// scopeBody parses the body of a function, class or view with parse, tracking
+// that statements in it are not at module level.
+func (p *Parser) scopeBody(parse func() ([]ast.Stmt, error)) ([]ast.Stmt, error) {
+	p.scopeDepth++
+	defer func() { p.scopeDepth-- }()
+	return parse()
+}
+
 // block parses a block of statements, taking into account the indentation level.
 func (p *Parser) block() ([]ast.Stmt, error) {
 	// Check if this is a simple statement block (single line)
@@ -79,9 +94,15 @@ func (p *Parser) block() ([]ast.Stmt, error) {
 
 	statements := []ast.Stmt{}
 	for !p.isAtEnd() && !p.check(lexer.Dedent) {
+		if err := p.checkCancelled(); err != nil {
+			return nil, err
+		}
+
+		start := p.Current
 		stmt, err := p.statement()
 		if err != nil {
-			return nil, err
+			p.recoverStatement(start, err)
+			continue
 		}
 
 		// Unwrap MultiStmt nodes
@@ -102,8 +123,40 @@ func (p *Parser) block() ([]ast.Stmt, error) {
 	return statements, nil
 }
 
-// simpleStatement parses an expression statement.
+// simpleStatement parses the simple statements of a line, separated by
+// semicolons with an optional trailing one. A line with several statements
+// yields a MultiStmt, which blocks unwrap into their statement lists.
 func (p *Parser) simpleStatement() (ast.Stmt, error) {
+	stmt, err := p.smallStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := []ast.Stmt{stmt}
+	for p.match(lexer.Semicolon) {
+		if p.isAtEnd() || p.check(lexer.Newline) || p.check(lexer.Dedent) {
+			break
+		}
+		next, err := p.smallStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, next)
+	}
+	if len(stmts) == 1 {
+		return stmt, nil
+	}
+
+	return &ast.MultiStmt{
+		Stmts: stmts,
+
+		Span: lexer.Span{Start: stmt.GetSpan().Start, End: stmts[len(stmts)-1].GetSpan().End},
+	}, nil
+}
+
+// smallStatement parses a single simple statement: an assignment, an
+// expression or a keyword statement such as return or import.
+func (p *Parser) smallStatement() (ast.Stmt, error) {
 	// Check for keywords first
 	switch p.peek().Type {
 	case lexer.Type:
@@ -133,6 +186,7 @@ func (p *Parser) simpleStatement() (ast.Stmt, error) {
 	}
 
 	// Check for assignment before expression
+	var assignErr error
 	if p.check(lexer.Identifier) || p.check(lexer.LeftParen) || p.check(lexer.LeftBracket) || p.check(lexer.Star) {
 		// Save current position
 		currentPos := p.Current
@@ -144,6 +198,7 @@ func (p *Parser) simpleStatement() (ast.Stmt, error) {
 		}
 
 		// If assignment parsing failed, restore position and try as expression
+		assignErr = err
 		p.Current = currentPos
 	}
 
@@ -152,6 +207,20 @@ func (p *Parser) simpleStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
+	// As in Python, 'x := 1' needs parentheses to stand as a statement
+	if p.check(lexer.Walrus) {
+		if _, ok := expr.(*ast.Name); ok {
+			return nil, p.error(p.peek(), "assignment expression cannot be a statement; use '=' or wrap it in parentheses")
+		}
+		return nil, p.walrusTargetError(expr)
+	}
+
+	// An expression followed by '=' is an assignment whose value did not
+	// parse: its error says why
+	if assignErr != nil && p.check(lexer.Equal) {
+		return nil, assignErr
+	}
+
 	return &ast.ExprStmt{
 		Expr: expr,
 
@@ -168,8 +237,7 @@ func (p *Parser) returnStatement() (ast.Stmt, error) {
 	}
 
 	// Exit early if there's no return expression
-	if p.isAtEnd() || p.check(lexer.Newline) || p.check(lexer.Semicolon) {
-		p.advance()
+	if p.isAtEnd() || p.check(lexer.Newline) || p.check(lexer.Semicolon) || p.check(lexer.Dedent) {
 		return &ast.ReturnStmt{
 			Value: nil,
 
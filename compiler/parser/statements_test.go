@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"strings"
@@ -1494,3 +1495,93 @@ func TestStatementEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestSemicolonStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, module *ast.Module)
+	}{
+		{
+			name:  "module level",
+			input: "x = 1; y = 2; print(x)",
+			check: func(t *testing.T, module *ast.Module) {
+				validateStatementTypes(t, module.Body, "*ast.AssignStmt", "*ast.AssignStmt", "*ast.ExprStmt")
+				span := module.Body[1].GetSpan()
+				if span.Start.Column != 8 || span.End.Column != 13 {
+					t.Errorf("Expected the second statement at columns 8-13, got %d-%d", span.Start.Column, span.End.Column)
+				}
+			},
+		},
+		{
+			name:  "trailing semicolon",
+			input: "import os; pass;\nx = 1",
+			check: func(t *testing.T, module *ast.Module) {
+				validateStatementTypes(t, module.Body, "*ast.ImportStmt", "*ast.PassStmt", "*ast.AssignStmt")
+			},
+		},
+		{
+			name:  "indented block",
+			input: "def f():\n    a = 1; return a\n",
+			check: func(t *testing.T, module *ast.Module) {
+				validateStatementTypes(t, module.Body[0].(*ast.Function).Body, "*ast.AssignStmt", "*ast.ReturnStmt")
+			},
+		},
+		{
+			name:  "single line block",
+			input: "if x: a = 1; b = 2\n",
+			check: func(t *testing.T, module *ast.Module) {
+				validateStatementTypes(t, module.Body[0].(*ast.If).Body, "*ast.AssignStmt", "*ast.AssignStmt")
+			},
+		},
+		{
+			name:  "bare return before another statement",
+			input: "def f():\n    return; x = 3\n",
+			check: func(t *testing.T, module *ast.Module) {
+				body := module.Body[0].(*ast.Function).Body
+				validateStatementTypes(t, body, "*ast.ReturnStmt", "*ast.AssignStmt")
+				if body[0].(*ast.ReturnStmt).Value != nil {
+					t.Error("Expected a bare return")
+				}
+			},
+		},
+		{
+			name:  "view body",
+			input: "view V():\n    a = 1; b = 2\n    <p>{a}</p>\n",
+			check: func(t *testing.T, module *ast.Module) {
+				validateStatementTypes(t, module.Body[0].(*ast.ViewStmt).Body, "*ast.AssignStmt", "*ast.AssignStmt", "*ast.HTMLElement")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, errs := parseInput(t, test.input)
+			validateParseSuccess(t, module, errs, -1)
+			test.check(t, module)
+		})
+	}
+}
+
+// validateStatementTypes checks the Go types of a statement list
+func validateStatementTypes(t *testing.T, stmts []ast.Stmt, types ...string) {
+	t.Helper()
+	var got []string
+	for _, stmt := range stmts {
+		got = append(got, fmt.Sprintf("%T", stmt))
+	}
+	if strings.Join(got, " ") != strings.Join(types, " ") {
+		t.Fatalf("Expected statements %v, got %v", types, got)
+	}
+}
+
+func TestSemicolonStatementErrors(t *testing.T) {
+	for _, input := range []string{"x = 1;; y = 2", "; x = 1", "x = 1; if y: pass"} {
+		t.Run(input, func(t *testing.T) {
+			_, errs := parseInput(t, input+"\n")
+			if len(errs) == 0 {
+				t.Errorf("Expected an error for %q", input)
+			}
+		})
+	}
+}
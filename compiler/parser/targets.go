@@ -255,7 +255,7 @@ func (p *Parser) starTargets() ([]ast.Expr, error) {
 	// We've consumed a comma
 	// Check if we're at the end (trailing comma case)
 	if p.check(lexer.RightParen) || p.check(lexer.RightBracket) ||
-		p.check(lexer.Colon) || p.check(lexer.Equal) || p.check(lexer.Newline) ||
+		p.check(lexer.Colon) || p.check(lexer.Equal) || p.check(lexer.In) || p.check(lexer.Newline) ||
 		p.check(lexer.Semicolon) || p.isAtEnd() {
 		// Just a trailing comma, we're done
 		return targets, nil
@@ -276,7 +276,7 @@ func (p *Parser) starTargets() ([]ast.Expr, error) {
 
 		// If we've found a trailing comma, we're done
 		if p.check(lexer.RightParen) || p.check(lexer.RightBracket) ||
-			p.check(lexer.Colon) || p.check(lexer.Equal) || p.check(lexer.Newline) ||
+			p.check(lexer.Colon) || p.check(lexer.Equal) || p.check(lexer.In) || p.check(lexer.Newline) ||
 			p.check(lexer.Semicolon) || p.isAtEnd() {
 			break
 		}
@@ -16,6 +16,10 @@ func parseTryStatement(t *testing.T, input string) (*ast.Try, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Errors in nested blocks are recovered from and recorded
+	if len(parser.Errors) > 0 {
+		return nil, parser.Errors[0]
+	}
 	tryStmt, ok := stmt.(*ast.Try)
 	if !ok {
 		return nil, nil
@@ -2,16 +2,29 @@ package parser
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
 // viewStatement parses a view statement according to the grammar:
-// view_def: 'view' NAME [type_params] '(' [params] ')' ['->' expression] ':' view_block
+// view_def: ['async'] ('view' | 'layout') NAME [type_params] '(' [params] ')' ['->' expression] ':' view_block
 func (p *Parser) viewStatement() (ast.Stmt, error) {
-	// Consume the 'view' keyword
-	viewToken, err := p.consume(lexer.View, "expected 'view'")
-	if err != nil {
+	// An async view renders in an async def _render
+	isAsync := p.check(lexer.Async)
+	startToken := p.peek()
+	if isAsync {
+		p.advance()
+	}
+
+	// Consume the 'view' keyword, or the 'layout' soft keyword
+	isLayout := p.checkLayout(0)
+	if isLayout {
+		p.requireFeature(lexer.Layouts, p.advance())
+	} else if _, err := p.consume(lexer.View, "expected 'view'"); err != nil {
 		return nil, err
 	}
 
@@ -27,7 +40,7 @@ func (p *Parser) viewStatement() (ast.Stmt, error) {
 
 	// Parse optional type parameters
 	var typeParams []*ast.TypeParam
-	if p.check(lexer.LeftBracket) {
+	if p.match(lexer.LeftBracket) {
 		// Get the type parameters using the existing parser function
 		paramExprs, err := p.typeParams()
 		if err != nil {
@@ -88,8 +101,11 @@ func (p *Parser) viewStatement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	// Parse the view body
-	body, err := p.viewBlock()
+	// Parse the view body, which may start with 'use layout'
+	var layout ast.Expr
+	body, err := p.scopeBody(func() ([]ast.Stmt, error) {
+		return p.layoutViewBlock(&layout)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -108,21 +124,38 @@ func (p *Parser) viewStatement() (ast.Stmt, error) {
 		Params:     parameterList,
 		ReturnType: returnType,
 		Body:       body,
-		IsAsync:    false, // Views are not async in this POC
+		IsAsync:    isAsync,
+		IsLayout:   isLayout,
+		Layout:     layout,
 		Kind:       ast.ViewKindServerView,
 
-		Span: lexer.Span{Start: viewToken.Start(), End: endPos},
+		Span: lexer.Span{Start: startToken.Start(), End: endPos},
 	}, nil
 }
 
 // viewBlock parses a view block which can contain Python statements and HTML elements
 func (p *Parser) viewBlock() ([]ast.Stmt, error) {
+	return p.layoutViewBlock(nil)
+}
+
+// layoutViewBlock parses a view block like viewBlock. When layout is not nil,
+// the block may start with a 'use layout' statement, the layout of which is
+// stored in layout.
+func (p *Parser) layoutViewBlock(layout *ast.Expr) ([]ast.Stmt, error) {
 	// Check if this is a simple statement block (single line)
 	if !p.check(lexer.Newline) {
+		if layout != nil && p.checkUseLayout() {
+			var err error
+			*layout, err = p.useLayout()
+			return []ast.Stmt{}, err
+		}
 		stmt, err := p.viewStatement_inner()
 		if err != nil {
 			return nil, err
 		}
+		if stmt == nil {
+			return []ast.Stmt{}, nil
+		}
 		// Unwrap MultiStmt nodes even in simple statement blocks
 		return unwrapMultiStmt(stmt), nil
 	}
@@ -143,16 +176,39 @@ func (p *Parser) viewBlock() ([]ast.Stmt, error) {
 		return nil, err
 	}
 
+	if layout != nil && p.checkUseLayout() {
+		start := p.Current
+		if *layout, err = p.useLayout(); err != nil {
+			p.recoverStatement(start, err)
+		}
+		for p.check(lexer.Newline) {
+			p.advance()
+		}
+	}
+
 	statements := []ast.Stmt{}
 	for !p.isAtEnd() && !p.check(lexer.Dedent) {
+		// Skip empty lines, e.g. lines that only held a discarded HTML comment
+		if p.check(lexer.Newline) {
+			p.advance()
+			continue
+		}
+
+		if err := p.checkCancelled(); err != nil {
+			return nil, err
+		}
+
+		start := p.Current
 		stmt, err := p.viewStatement_inner()
 		if err != nil {
-			return nil, err
+			p.recoverStatement(start, err)
+			continue
 		}
 
-		// Unwrap MultiStmt nodes
-		unwrapped := unwrapMultiStmt(stmt)
-		statements = append(statements, unwrapped...)
+		// Unwrap MultiStmt nodes; nil means a recovered error produced no statement
+		if stmt != nil {
+			statements = append(statements, unwrapMultiStmt(stmt)...)
+		}
 
 		// Consume all the newlines we see
 		for p.check(lexer.Newline) {
@@ -168,12 +224,79 @@ func (p *Parser) viewBlock() ([]ast.Stmt, error) {
 	return statements, nil
 }
 
+// checkLayout reports whether the tokens offset tokens ahead start a layout
+// declaration. 'layout' is a soft keyword: it only starts one when a name and
+// the parameters or type parameters follow, so it stays usable as a name.
+func (p *Parser) checkLayout(offset int) bool {
+	return p.tokenAt(offset).Type == lexer.Identifier && p.tokenAt(offset).Lexeme == "layout" &&
+		p.tokenAt(offset+1).Type == lexer.Identifier &&
+		(p.tokenAt(offset+2).Type == lexer.LeftParen || p.tokenAt(offset+2).Type == lexer.LeftBracket)
+}
+
+// checkUseLayout reports whether the next tokens start a 'use layout'
+// statement. Both words are soft keywords.
+func (p *Parser) checkUseLayout() bool {
+	return p.tokenAt(0).Type == lexer.Identifier && p.tokenAt(0).Lexeme == "use" &&
+		p.tokenAt(1).Type == lexer.Identifier && p.tokenAt(1).Lexeme == "layout" &&
+		p.tokenAt(2).Type == lexer.Identifier
+}
+
+// tokenAt returns the token offset tokens ahead, or the final EOF token past
+// the end of the input
+func (p *Parser) tokenAt(offset int) lexer.Token {
+	if !p.load(p.Current + offset) {
+		return p.Tokens[len(p.Tokens)-1]
+	}
+	return p.Tokens[p.Current+offset]
+}
+
+// useLayout parses the layout of a view according to the grammar:
+// use_layout: 'use' 'layout' (dotted_name | dotted_name '(' [arguments] ')') NEWLINE
+func (p *Parser) useLayout() (ast.Expr, error) {
+	p.requireFeature(lexer.Layouts, p.advance()) // use
+	p.advance()                                  // layout
+	start := p.peek()
+	layout, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	callee := layout
+	if call, ok := layout.(*ast.Call); ok {
+		callee = call.Callee
+	}
+	for {
+		attribute, ok := callee.(*ast.Attribute)
+		if !ok {
+			break
+		}
+		callee = attribute.Object
+	}
+	if _, ok := callee.(*ast.Name); !ok {
+		return nil, p.error(start, "expected a layout name or call after 'use layout'")
+	}
+	if !p.check(lexer.Dedent) && !p.isAtEnd() {
+		if _, err := p.consume(lexer.Newline, "expected newline after 'use layout'"); err != nil {
+			return nil, err
+		}
+	}
+	return layout, nil
+}
+
 // viewStatement_inner parses statements that can appear inside a view body
 func (p *Parser) viewStatement_inner() (ast.Stmt, error) {
 	// Check for HTML elements first
 	if p.check(lexer.TagOpen) {
 		return p.htmlElement()
 	}
+	if p.checkUseLayout() {
+		return nil, p.error(p.peek(), "'use layout' must be the first statement of a view")
+	}
+	if p.check(lexer.TagCloseStart) {
+		return nil, p.strayClosingTag()
+	}
+	if p.check(lexer.HTMLComment) {
+		return p.htmlComment(), nil
+	}
 
 	// Check for compound statements
 	switch p.peek().Type {
@@ -188,8 +311,7 @@ func (p *Parser) viewStatement_inner() (ast.Stmt, error) {
 			return p.viewForStatement()
 		}
 		if p.checkNext(lexer.With) {
-			// TODO: Implement async with in views when needed
-			return nil, p.error(p.peek(), "async with statements not implemented in views yet")
+			return p.withBlock(p.viewBlock)
 		}
 		// Fall through to simple statements for other async cases
 		return p.simpleStatement()
@@ -197,6 +319,8 @@ func (p *Parser) viewStatement_inner() (ast.Stmt, error) {
 		return p.viewIfStatement()
 	case lexer.While:
 		return p.viewWhileStatement()
+	case lexer.With:
+		return p.withBlock(p.viewBlock)
 	case lexer.For:
 		return p.viewForStatement()
 	case lexer.Try:
@@ -205,17 +329,8 @@ func (p *Parser) viewStatement_inner() (ast.Stmt, error) {
 		return p.viewMatchStatement()
 	}
 
-	// Check for simple statements
-	switch p.peek().Type {
-	case lexer.Return:
-		return p.returnStatement()
-	case lexer.Pass:
-		return p.passStatement()
-	// TODO: Add more simple statements as needed
-	default:
-		// Fall back to regular statement parsing for Python statements
-		return p.simpleStatement()
-	}
+	// Fall back to the simple statements of the line
+	return p.simpleStatement()
 }
 
 // htmlElement parses an HTML element
@@ -226,19 +341,35 @@ func (p *Parser) htmlElement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	// Parse tag name
-	tagNameToken, err := p.consume(lexer.Identifier, "expected tag name")
+	// Parse tag name, or the {expression} computing it
+	tagNameToken, tagExpr, err := p.tagName("expected tag name")
 	if err != nil {
 		return nil, err
 	}
+	if tagExpr != nil {
+		p.requireFeature(lexer.DynamicTags, openToken)
+	} else if tagNameToken.Lexeme == "" {
+		p.requireFeature(lexer.Fragments, openToken)
+	}
 
 	// Parse attributes
 	var attributes []ast.HTMLAttribute
-	for !p.check(lexer.TagSelfClose) && !p.check(lexer.TagClose) && p.check(lexer.Identifier) {
+	for !p.check(lexer.TagSelfClose) && !p.check(lexer.TagClose) && (p.check(lexer.Identifier) || p.checkSpread()) {
+		if p.checkSpread() {
+			attr, err := p.spreadAttribute()
+			if err != nil {
+				return nil, err
+			}
+			p.checkDuplicateAttribute(tagNameToken, attributes, attr)
+			attributes = append(attributes, attr)
+			continue
+		}
 		attr, err := p.htmlAttribute()
 		if err != nil {
 			return nil, err
 		}
+		p.checkDuplicateAttribute(tagNameToken, attributes, attr)
+		p.checkSlotParams(attr)
 		attributes = append(attributes, attr)
 	}
 
@@ -247,6 +378,7 @@ func (p *Parser) htmlElement() (ast.Stmt, error) {
 		return &ast.HTMLElement{
 			Type:       ast.HTMLSelfClosingTag,
 			TagName:    tagNameToken,
+			TagExpr:    tagExpr,
 			Attributes: attributes,
 			Content:    nil,
 			IsClosing:  false,
@@ -260,8 +392,10 @@ func (p *Parser) htmlElement() (ast.Stmt, error) {
 		return nil, err
 	}
 
-	// Parse element content
+	// Parse element content, tracking the open tag so mismatched closing tags can be diagnosed
+	p.openTags = append(p.openTags, tagNameToken)
 	content, elementType, err := p.htmlElementContent(tagNameToken)
+	p.openTags = p.openTags[:len(p.openTags)-1]
 	if err != nil {
 		return nil, err
 	}
@@ -269,6 +403,7 @@ func (p *Parser) htmlElement() (ast.Stmt, error) {
 	return &ast.HTMLElement{
 		Type:       elementType,
 		TagName:    tagNameToken,
+		TagExpr:    tagExpr,
 		Attributes: attributes,
 		Content:    content,
 		IsClosing:  false,
@@ -320,6 +455,7 @@ func (p *Parser) parseMultilineContent(tagNameToken lexer.Token) ([]ast.Stmt, as
 	}
 
 	// Parse content until dedent
+	closedEarly := false
 	for !p.isAtEnd() && !p.check(lexer.Dedent) {
 		// Skip empty lines
 		if p.check(lexer.Newline) {
@@ -327,15 +463,26 @@ func (p *Parser) parseMultilineContent(tagNameToken lexer.Token) ([]ast.Stmt, as
 			continue
 		}
 
+		if !closedEarly && p.closingTagAt(p.Current, tagNameToken.Lexeme) {
+			if err := p.overIndentedClosingTag(tagNameToken); err != nil {
+				return nil, ast.HTMLMultilineElement, err
+			}
+			closedEarly = true
+			continue
+		}
+
 		// Handle nested HTML elements or Python statements only
 		// Multiline HTML does NOT support raw HTML text
+		start := p.Current
 		stmt, err := p.viewStatement_inner()
 		if err != nil {
-			return nil, ast.HTMLMultilineElement, err
+			p.recoverStatement(start, err)
+			continue
 		}
 
+		// Unwrap MultiStmt nodes; nil means the statement produced no node
 		if stmt != nil {
-			content = append(content, stmt)
+			content = append(content, unwrapMultiStmt(stmt)...)
 		}
 
 		// Consume newlines after statements
@@ -350,10 +497,12 @@ func (p *Parser) parseMultilineContent(tagNameToken lexer.Token) ([]ast.Stmt, as
 		return nil, ast.HTMLMultilineElement, err
 	}
 
-	// Parse closing tag
-	err = p.consumeClosingTag(tagNameToken)
-	if err != nil {
-		return nil, ast.HTMLMultilineElement, err
+	// Parse closing tag, unless it was already written (over-indented) inside the content
+	if !closedEarly {
+		err = p.consumeClosingTag(tagNameToken)
+		if err != nil {
+			return nil, ast.HTMLMultilineElement, err
+		}
 	}
 
 	return content, ast.HTMLMultilineElement, nil
@@ -378,14 +527,20 @@ func (p *Parser) parseSingleLineContent(tagNameToken lexer.Token) ([]ast.Stmt, a
 		content = append(content, htmlContent)
 	}
 
-	// Check for nested HTML elements after text content
+	// Check for nested HTML elements and comments after text content
 	for !p.check(lexer.TagCloseStart) && !p.check(lexer.Newline) && !p.isAtEnd() {
-		if p.check(lexer.TagOpen) {
-			nestedElement, err := p.htmlElement()
-			if err != nil {
-				return nil, ast.HTMLSingleLineElement, err
+		if p.check(lexer.TagOpen) || p.check(lexer.HTMLComment) {
+			var nested ast.Stmt
+			if p.check(lexer.HTMLComment) {
+				nested = p.htmlComment()
+			} else {
+				nestedElement, err := p.htmlElement()
+				if err != nil {
+					return nil, ast.HTMLSingleLineElement, err
+				}
+				nested = nestedElement
 			}
-			content = append(content, nestedElement)
+			content = append(content, nested)
 
 			// Parse any additional HTML content after the nested element
 			moreParts, err := p.parseHTMLContentParts()
@@ -413,6 +568,16 @@ func (p *Parser) parseSingleLineContent(tagNameToken lexer.Token) ([]ast.Stmt, a
 	return content, ast.HTMLSingleLineElement, nil
 }
 
+// htmlComment parses a preserved <!-- ... --> comment
+func (p *Parser) htmlComment() ast.Stmt {
+	commentToken := p.advance()
+	text, _ := commentToken.Literal.(string)
+	return &ast.HTMLComment{
+		Text: text,
+		Span: commentToken.Span,
+	}
+}
+
 // parseHTMLContentParts parses consecutive HTML text and interpolations
 func (p *Parser) parseHTMLContentParts() ([]ast.HTMLContentPart, error) {
 	var parts []ast.HTMLContentPart
@@ -426,10 +591,20 @@ func (p *Parser) parseHTMLContentParts() ([]ast.HTMLContentPart, error) {
 				Span:  lexer.Span{Start: textToken.Start(), End: textToken.End()},
 			}
 			parts = append(parts, htmlText)
+		} else if p.check(lexer.HTMLInterpolationStart) && p.peekN(1).Type == lexer.Tilde && p.peekN(2).Type == lexer.HTMLInterpolationEnd {
+			// Handle a {~} marker, trimming the whitespace around it
+			startToken := p.advance()
+			p.requireFeature(lexer.WhitespaceTrim, startToken)
+			p.advance()
+			endToken := p.advance()
+			parts = append(parts, &ast.HTMLText{
+				Trim: true,
+				Span: lexer.Span{Start: startToken.Start(), End: endToken.End()},
+			})
 		} else if p.check(lexer.HTMLInterpolationStart) {
 			// Handle interpolation {expression}
 			startToken := p.advance() // consume '{'
-			expr, err := p.expression()
+			expr, err := p.namedExpression()
 			if err != nil {
 				return nil, err
 			}
@@ -451,27 +626,6 @@ func (p *Parser) parseHTMLContentParts() ([]ast.HTMLContentPart, error) {
 	return parts, nil
 }
 
-// consumeClosingTag parses and validates a closing tag
-func (p *Parser) consumeClosingTag(expectedTagName lexer.Token) error {
-	_, err := p.consume(lexer.TagCloseStart, "expected closing tag")
-	if err != nil {
-		return err
-	}
-
-	closingTagName, err := p.consume(lexer.Identifier, "expected closing tag name")
-	if err != nil {
-		return err
-	}
-
-	// Verify tag names match
-	if expectedTagName.Lexeme != closingTagName.Lexeme {
-		return p.error(closingTagName, "closing tag name doesn't match opening tag")
-	}
-
-	_, err = p.consume(lexer.TagClose, "expected '>' after closing tag")
-	return err
-}
-
 // htmlAttribute parses an HTML attribute
 func (p *Parser) htmlAttribute() (ast.HTMLAttribute, error) {
 	// Parse attribute name
@@ -503,19 +657,191 @@ func (p *Parser) htmlAttribute() (ast.HTMLAttribute, error) {
 	}, nil
 }
 
+// checkSpread reports whether the next attribute is a {...mapping} spread
+func (p *Parser) checkSpread() bool {
+	return p.check(lexer.HTMLInterpolationStart) && p.peekN(1).Type == lexer.Ellipsis
+}
+
+// spreadAttribute parses a {...mapping} spread, which passes every item of the
+// mapping as an attribute. "{...attrs if condition}" spreads attrs only when
+// the condition holds, like "{...attrs if condition else {}}".
+func (p *Parser) spreadAttribute() (ast.HTMLAttribute, error) {
+	startToken, err := p.consume(lexer.HTMLInterpolationStart, "expected '{'")
+	if err != nil {
+		return ast.HTMLAttribute{}, err
+	}
+	ellipsis, err := p.consume(lexer.Ellipsis, "expected '...'")
+	if err != nil {
+		return ast.HTMLAttribute{}, err
+	}
+	p.requireFeature(lexer.AttributeSpreads, startToken)
+
+	spread := func(value ast.Expr) ast.HTMLAttribute {
+		return ast.HTMLAttribute{
+			Name:   ellipsis,
+			Value:  value,
+			Spread: true,
+			Span:   lexer.Span{Start: startToken.Start(), End: p.previous().End()},
+		}
+	}
+	recoverSpread := func(err error) (ast.HTMLAttribute, error) {
+		value, err := p.recoverAttributeInterpolation(startToken, err)
+		if err != nil {
+			return ast.HTMLAttribute{}, err
+		}
+		return spread(value), nil
+	}
+
+	if p.check(lexer.HTMLInterpolationEnd) {
+		return recoverSpread(p.error(p.peek(), "empty attribute spread; expected a mapping after '...'"))
+	}
+
+	value, err := p.disjunction()
+	if err != nil {
+		return recoverSpread(err)
+	}
+	if p.match(lexer.If) {
+		condition, err := p.disjunction()
+		if err != nil {
+			return recoverSpread(err)
+		}
+		var otherwise ast.Expr
+		if p.match(lexer.Else) {
+			if otherwise, err = p.expression(); err != nil {
+				return recoverSpread(err)
+			}
+		} else {
+			otherwise = &ast.DictExpr{Span: lexer.Span{Start: condition.GetSpan().End, End: condition.GetSpan().End}}
+		}
+		value = &ast.TernaryExpr{
+			Condition: condition,
+			TrueExpr:  value,
+			FalseExpr: otherwise,
+			Span:      lexer.Span{Start: value.GetSpan().Start, End: p.previous().End()},
+		}
+	}
+
+	if !p.check(lexer.HTMLInterpolationEnd) {
+		return recoverSpread(p.trailingInterpolationError(p.peek()))
+	}
+	p.advance() // consume '}'
+
+	return spread(value), nil
+}
+
+// checkDuplicateAttribute records an error when attr repeats one of the
+// attributes before it, since only one of the values would take effect.
+// Spreads count with the keys of the literal dictionaries they pass, in
+// either branch of a conditional spread; other mappings are only known when
+// the view renders. The names of HTML elements' attributes are
+// case-insensitive; those passed to views and other capitalized tags are
+// keyword arguments and are not.
+func (p *Parser) checkDuplicateAttribute(tagName lexer.Token, attributes []ast.HTMLAttribute, attr ast.HTMLAttribute) {
+	fold := tagName.Lexeme != "" && unicode.IsLower(rune(tagName.Lexeme[0]))
+	for _, key := range attributeKeys(attr) {
+		name := key.name
+	previous:
+		for _, previous := range attributes {
+			for _, earlier := range attributeKeys(previous) {
+				if earlier.name != name && !(fold && strings.EqualFold(earlier.name, name)) {
+					continue
+				}
+				note := fmt.Sprintf("'%s' first given here", earlier.name)
+				if previous.Spread {
+					note = fmt.Sprintf("'%s' first given by this spread", earlier.name)
+				}
+				p.Errors = append(p.Errors, &ParseError{
+					Token:   key.token,
+					Code:    diagnostics.CodeDuplicateAttribute,
+					Message: fmt.Sprintf("duplicate attribute '%s' on <%s>", name, tagName.Lexeme),
+					Hint:    fmt.Sprintf("remove one of the '%s' attributes", name),
+					Related: []RelatedSpan{{Span: earlier.token.Span, Message: note}},
+				})
+				break previous
+			}
+		}
+	}
+}
+
+// attributeKey is a name an attribute gives, with the token naming it
+type attributeKey struct {
+	name  string
+	token lexer.Token
+}
+
+// attributeKeys returns the names attr gives: its own, or for a spread the
+// string keys of the literal dictionaries it may pass, each once
+func attributeKeys(attr ast.HTMLAttribute) []attributeKey {
+	if !attr.Spread {
+		return []attributeKey{{name: attr.Name.Lexeme, token: attr.Name}}
+	}
+	var keys []attributeKey
+	seen := make(map[string]bool)
+	var collect func(value ast.Expr)
+	collect = func(value ast.Expr) {
+		switch v := value.(type) {
+		case *ast.GroupExpr:
+			collect(v.Expression)
+		case *ast.TernaryExpr:
+			collect(v.TrueExpr)
+			collect(v.FalseExpr)
+		case *ast.DictExpr:
+			for _, pair := range v.Pairs {
+				kv, ok := pair.(*ast.KeyValuePair)
+				if !ok {
+					continue
+				}
+				literal, ok := kv.Key.(*ast.Literal)
+				if !ok {
+					continue
+				}
+				if name, ok := literal.Value.(string); ok && !seen[name] {
+					seen[name] = true
+					token := literal.Token
+					token.Span = literal.Span
+					keys = append(keys, attributeKey{name: name, token: token})
+				}
+			}
+		}
+	}
+	collect(attr.Value)
+	return keys
+}
+
+// requireFeature records an error at token when the file's syntax version
+// predates feature, naming the version that introduced it. Parsing goes on
+// as if the file allowed it.
+func (p *Parser) requireFeature(feature lexer.Feature, token lexer.Token) {
+	if p.Syntax.Has(feature) {
+		return
+	}
+	p.Errors = append(p.Errors, &ParseError{
+		Token:   token,
+		Code:    diagnostics.CodeSyntaxVersion,
+		Message: fmt.Sprintf("%s need syntax %s, but this file selects syntax %s", feature, feature.Since(), p.Syntax),
+		Hint:    fmt.Sprintf("change the file's '# topple: syntax=%s' pragma to syntax=%s or later", p.Syntax, feature.Since()),
+	})
+}
+
+// checkSlotParams records an error when a let attribute, which binds the
+// values a scoped slot passes to its content, does not name them
+func (p *Parser) checkSlotParams(attr ast.HTMLAttribute) {
+	if attr.Name.Lexeme != "let" || ast.LetNames(attr.Value) != nil {
+		return
+	}
+	p.Errors = append(p.Errors, &ParseError{
+		Token:   attr.Name,
+		Code:    diagnostics.CodeSlotParams,
+		Message: "let must name the values of a scoped slot",
+		Hint:    "write let={item}, or let={(item, index)} for several values",
+	})
+}
+
 // htmlAttributeValue parses the value part of an HTML attribute
 func (p *Parser) htmlAttributeValue() (ast.Expr, error) {
 	// Handle string literal values
 	if p.check(lexer.String) {
 		stringToken := p.advance()
-		stringValue := stringToken.Literal.(string)
-
-		// PSX syntax sugar: auto-convert strings with {var} to f-strings
-		// This only applies in HTML attribute context, not regular Python strings
-		if p.containsInterpolation(stringValue) {
-			return p.convertStringToFString(stringValue, stringToken)
-		}
-
 		return &ast.Literal{
 			Token: stringToken,
 			Value: stringToken.Literal,
@@ -524,17 +850,14 @@ func (p *Parser) htmlAttributeValue() (ast.Expr, error) {
 		}, nil
 	}
 
+	// PSX syntax sugar: "btn {variant} large" compiles to an f-string
+	if p.check(lexer.FStringStart) {
+		return p.attributeFString()
+	}
+
 	// Handle expression values {expression}
-	if p.match(lexer.HTMLInterpolationStart) {
-		expr, err := p.expression()
-		if err != nil {
-			return nil, err
-		}
-		_, err = p.consume(lexer.HTMLInterpolationEnd, "expected '}' after expression")
-		if err != nil {
-			return nil, err
-		}
-		return expr, nil
+	if p.check(lexer.HTMLInterpolationStart) {
+		return p.attributeInterpolation()
 	}
 
 	// Handle number literals
@@ -562,128 +885,137 @@ func (p *Parser) htmlAttributeValue() (ast.Expr, error) {
 	return nil, p.error(p.peek(), "expected string, number, boolean, or expression for attribute value")
 }
 
-// containsInterpolation checks if a string contains {var} interpolation patterns
-func (p *Parser) containsInterpolation(s string) bool {
-	inBraces := false
-	for i := 0; i < len(s); i++ {
-		if s[i] == '{' {
-			// Check if it's an escaped brace {{
-			if i+1 < len(s) && s[i+1] == '{' {
-				i++ // Skip the next brace
-				continue
-			}
-			inBraces = true
-		} else if s[i] == '}' && inBraces {
-			// Found a complete {var} pattern
-			return true
-		}
+// attributeInterpolation parses a {expression} attribute value. The braces must
+// hold exactly one expression; statements, assignments and stray trailing tokens
+// are reported with a targeted message. After such an error the parser records
+// it, resynchronizes at the closing brace and keeps parsing the tag, so one bad
+// attribute doesn't hide problems in the rest of the view.
+func (p *Parser) attributeInterpolation() (ast.Expr, error) {
+	startToken, err := p.consume(lexer.HTMLInterpolationStart, "expected '{'")
+	if err != nil {
+		return nil, err
 	}
-	return false
-}
 
-// convertStringToFString converts a string with {var} patterns into an f-string AST node
-// Input: "btn btn-{variant} active"  -> Output: FString with parts ["btn btn-", variant, " active"]
-func (p *Parser) convertStringToFString(s string, token lexer.Token) (*ast.FString, error) {
-	var parts []ast.FStringPart
-	current := ""
-	i := 0
-
-	for i < len(s) {
-		if s[i] == '{' {
-			// Check for escaped braces {{
-			if i+1 < len(s) && s[i+1] == '{' {
-				current += "{"
-				i += 2
-				continue
-			}
+	if p.check(lexer.HTMLInterpolationEnd) {
+		return p.recoverAttributeInterpolation(startToken, p.error(p.peek(), "empty attribute interpolation; expected an expression"))
+	}
 
-			// Save any accumulated literal text
-			if current != "" {
-				parts = append(parts, &ast.FStringMiddle{
-					Value: current,
-					Span:  token.Span,
-				})
-				current = ""
-			}
+	if keyword := p.peek(); isStatementKeyword(keyword.Type) {
+		return p.recoverAttributeInterpolation(startToken, p.error(keyword,
+			fmt.Sprintf("attribute interpolation must be a single expression, not a '%s' statement", keyword.Lexeme)))
+	}
 
-			// Find the closing }
-			i++ // Skip opening {
-			exprStart := i
-			braceDepth := 1
+	expr, err := p.namedExpression()
+	if err != nil {
+		return p.recoverAttributeInterpolation(startToken, err)
+	}
 
-			for i < len(s) && braceDepth > 0 {
-				if s[i] == '{' {
-					braceDepth++
-				} else if s[i] == '}' {
-					braceDepth--
-				}
-				if braceDepth > 0 {
-					i++
-				}
-			}
+	if !p.check(lexer.HTMLInterpolationEnd) {
+		return p.recoverAttributeInterpolation(startToken, p.trailingInterpolationError(p.peek()))
+	}
+	p.advance() // consume '}'
 
-			if braceDepth != 0 {
-				return nil, p.error(token, "unmatched '{' in attribute value")
-			}
+	return expr, nil
+}
 
-			// Parse the expression inside the braces
-			exprStr := s[exprStart:i]
-			expr, err := p.parseExpressionFromString(exprStr, token)
-			if err != nil {
-				return nil, err
-			}
+// attributeFString parses a quoted attribute value that mixes text with
+// {expression} parts. The scanner emits the text as FStringMiddle tokens and
+// each part as an attribute interpolation, so the parts get the same
+// validation and recovery as a bare {expression} value.
+func (p *Parser) attributeFString() (ast.Expr, error) {
+	startToken, err := p.consume(lexer.FStringStart, "expected attribute value")
+	if err != nil {
+		return nil, err
+	}
 
-			parts = append(parts, &ast.FStringReplacementField{
-				Expression: expr,
-				Span:       token.Span,
+	var parts []ast.FStringPart
+	for !p.check(lexer.FStringEnd) && !p.isAtEnd() {
+		if p.check(lexer.FStringMiddle) {
+			middleToken := p.advance()
+			parts = append(parts, &ast.FStringMiddle{
+				Value: middleToken.Literal.(string),
+				Span:  middleToken.Span,
 			})
+			continue
+		}
 
-			i++ // Skip closing }
-		} else if s[i] == '}' {
-			// Check for escaped braces }}
-			if i+1 < len(s) && s[i+1] == '}' {
-				current += "}"
-				i += 2
-				continue
-			}
-			return nil, p.error(token, "unmatched '}' in attribute value")
-		} else {
-			current += string(s[i])
-			i++
+		if !p.check(lexer.HTMLInterpolationStart) {
+			return nil, p.error(p.peek(), "unterminated attribute value")
+		}
+		braceToken := p.peek()
+		expr, err := p.attributeInterpolation()
+		if err != nil {
+			return nil, err
 		}
+		parts = append(parts, &ast.FStringReplacementField{
+			Expression: expr,
+			Span:       lexer.Span{Start: braceToken.Start(), End: p.previous().End()},
+		})
 	}
 
-	// Add any remaining literal text
-	if current != "" {
-		parts = append(parts, &ast.FStringMiddle{
-			Value: current,
-			Span:  token.Span,
-		})
+	endToken, err := p.consume(lexer.FStringEnd, "unterminated attribute value")
+	if err != nil {
+		return nil, err
 	}
 
 	return &ast.FString{
 		Parts: parts,
-		Span:  token.Span,
+		Quote: startToken.Lexeme,
+		Span:  lexer.Span{Start: startToken.Start(), End: endToken.End()},
 	}, nil
 }
 
-// parseExpressionFromString parses an expression from a string (used for {var} in attributes)
-func (p *Parser) parseExpressionFromString(exprStr string, originalToken lexer.Token) (ast.Expr, error) {
-	// Create a mini-lexer to tokenize the expression
-	miniScanner := lexer.NewScanner([]byte(exprStr))
-	tokens := miniScanner.ScanTokens()
-
-	// Create a mini-parser with these tokens
-	miniParser := &Parser{
-		Tokens:  tokens,
-		Current: 0,
+// trailingInterpolationError explains why a token cannot follow the expression in {…}
+func (p *Parser) trailingInterpolationError(tok lexer.Token) error {
+	switch tok.Type {
+	case lexer.Equal, lexer.PlusEqual, lexer.MinusEqual, lexer.StarEqual, lexer.SlashEqual,
+		lexer.SlashSlashEqual, lexer.PercentEqual, lexer.StarStarEqual, lexer.AtEqual,
+		lexer.AmpEqual, lexer.PipeEqual, lexer.CaretEqual, lexer.LessLessEqual, lexer.GreaterGreaterEqual:
+		return p.error(tok, "assignment is not allowed in attribute interpolation; assign in the view body or use ':='")
+	case lexer.Comma:
+		return p.error(tok, "attribute interpolation must be a single expression; wrap multiple values in parentheses to pass a tuple")
+	case lexer.Semicolon, lexer.Newline:
+		return p.error(tok, "attribute interpolation must be a single expression, not a statement list")
+	case lexer.TagClose, lexer.TagSelfClose, lexer.EOF:
+		return p.error(tok, "unterminated attribute interpolation; expected '}'")
+	default:
+		return p.error(tok, "expected '}' after expression")
 	}
+}
 
-	// Parse the expression
-	expr, err := miniParser.expression()
-	if err != nil {
-		return nil, p.error(originalToken, fmt.Sprintf("invalid expression in interpolation: %v", err))
+// recoverAttributeInterpolation records err and skips to the closing '}' of the
+// interpolation (or the end of the tag), returning a placeholder value.
+func (p *Parser) recoverAttributeInterpolation(startToken lexer.Token, err error) (ast.Expr, error) {
+	p.Errors = append(p.Errors, err)
+
+	for !p.isAtEnd() {
+		if p.check(lexer.HTMLInterpolationEnd) {
+			p.advance()
+			break
+		}
+		if p.check(lexer.TagClose) || p.check(lexer.TagSelfClose) || p.check(lexer.Newline) {
+			break
+		}
+		p.advance()
 	}
 
-	return expr, nil
+	return &ast.Literal{
+		Token: startToken,
+		Value: nil,
+		Type:  ast.LiteralTypeNone,
+		Span:  lexer.Span{Start: startToken.Start(), End: p.previous().End()},
+	}, nil
+}
+
+// isStatementKeyword reports whether a token can only start a statement
+func isStatementKeyword(t lexer.TokenType) bool {
+	switch t {
+	case lexer.Import, lexer.From, lexer.Def, lexer.Class, lexer.Return, lexer.Pass,
+		lexer.For, lexer.While, lexer.If, lexer.Del, lexer.Global, lexer.Nonlocal,
+		lexer.Raise, lexer.Assert, lexer.Try, lexer.With, lexer.Break, lexer.Continue,
+		lexer.View:
+		return true
+	default:
+		return false
+	}
 }
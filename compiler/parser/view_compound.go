@@ -21,7 +21,7 @@ func (p *Parser) viewIfStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after if condition")
+	_, err = p.consumeConditionColon("if")
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +85,7 @@ func (p *Parser) viewElifStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after elif condition")
+	_, err = p.consumeConditionColon("elif")
 	if err != nil {
 		return nil, err
 	}
@@ -261,7 +261,7 @@ func (p *Parser) viewWhileStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after while condition")
+	_, err = p.consumeConditionColon("while")
 	if err != nil {
 		return nil, err
 	}
@@ -671,7 +671,7 @@ func (p *Parser) viewFunctionDef() (ast.Stmt, error) {
 
 	// Parse function body using regular block() since function bodies are regular Python code
 	// The key difference from viewBlock() is that function bodies should NOT contain HTML
-	body, err := p.block()
+	body, err := p.scopeBody(p.block)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+func TestAttributeInterpolationValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		attr      string
+		wantError string
+	}{
+		{"simple expression", `href={url}`, ""},
+		{"ternary", `class={"a" if active else "b"}`, ""},
+		{"dict literal", `data={{"a": 1}}`, ""},
+		{"nested dict", `data={{"a": {"b": 2}}}`, ""},
+		{"call", `onclick={handler(1, 2)}`, ""},
+		{"assignment", `value={x = 1}`, "assignment is not allowed in attribute interpolation"},
+		{"augmented assignment", `value={x += 1}`, "assignment is not allowed in attribute interpolation"},
+		{"tuple without parens", `value={a, b}`, "wrap multiple values in parentheses"},
+		{"statement keyword", `value={import os}`, "not a 'import' statement"},
+		{"return statement", `value={return x}`, "not a 'return' statement"},
+		{"empty", `value={}`, "empty attribute interpolation"},
+		{"string sugar assignment", `class="btn {x = 1}"`, "assignment is not allowed in attribute interpolation"},
+		{"string sugar statement", `class="btn {pass}"`, "not a 'pass' statement"},
+		{"string sugar trailing tokens", `class="btn {a b}"`, "expected '}' after expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "view V():\n    <div " + tt.attr + ">x</div>\n"
+			module, errs := parseInput(t, input)
+
+			if tt.wantError == "" {
+				validateParseSuccess(t, module, errs, 1)
+				return
+			}
+
+			if len(errs) == 0 {
+				t.Fatalf("expected error containing %q, got none", tt.wantError)
+			}
+			if !strings.Contains(errs[0].Error(), tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", errs[0].Error(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAttributeInterpolationRecovery(t *testing.T) {
+	// Both bad attributes are reported; parsing resumes after each closing brace
+	input := "view V():\n    <div a={x = 1} b={y, z} c={ok}>text</div>\n"
+	module, errs := parseInput(t, input)
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "assignment") || !strings.Contains(errs[1].Error(), "parentheses") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	if module == nil || len(module.Body) != 1 {
+		t.Fatalf("expected the view to still be parsed")
+	}
+	view := module.Body[0].(*ast.ViewStmt)
+	element, ok := view.Body[0].(*ast.HTMLElement)
+	if !ok {
+		t.Fatalf("expected HTML element, got %T", view.Body[0])
+	}
+	if len(element.Attributes) != 3 {
+		t.Errorf("expected 3 attributes after recovery, got %d", len(element.Attributes))
+	}
+	if name, ok := element.Attributes[2].Value.(*ast.Name); !ok || name.Token.Lexeme != "ok" {
+		t.Errorf("expected third attribute to parse normally, got %v", element.Attributes[2].Value)
+	}
+}
+
+func TestAttributeValueConcatenation(t *testing.T) {
+	input := "view V(variant):\n    <button class=\"btn {variant} large\">x</button>\n"
+	module, errs := parseInput(t, input)
+	validateParseSuccess(t, module, errs, 1)
+
+	view := module.Body[0].(*ast.ViewStmt)
+	element := view.Body[0].(*ast.HTMLElement)
+	fstring, ok := element.Attributes[0].Value.(*ast.FString)
+	if !ok {
+		t.Fatalf("expected f-string attribute value, got %T", element.Attributes[0].Value)
+	}
+	if len(fstring.Parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(fstring.Parts))
+	}
+
+	if middle, ok := fstring.Parts[0].(*ast.FStringMiddle); !ok || middle.Value != "btn " {
+		t.Errorf("part 0: expected middle \"btn \", got %#v", fstring.Parts[0])
+	}
+	field, ok := fstring.Parts[1].(*ast.FStringReplacementField)
+	if !ok {
+		t.Fatalf("part 1: expected replacement field, got %T", fstring.Parts[1])
+	}
+	if name, ok := field.Expression.(*ast.Name); !ok || name.Token.Lexeme != "variant" {
+		t.Errorf("part 1: expected name 'variant', got %#v", field.Expression)
+	}
+	if middle, ok := fstring.Parts[2].(*ast.FStringMiddle); !ok || middle.Value != " large" {
+		t.Errorf("part 2: expected middle \" large\", got %#v", fstring.Parts[2])
+	}
+
+	// Spans point at the source text, not at the whole attribute value
+	if got := field.Span; got.Start.Column != 24 || got.End.Column != 33 {
+		t.Errorf("replacement field span = %v, want columns 24-33", got)
+	}
+	if got := fstring.Span; got.Start.Column != 19 || got.End.Column != 40 {
+		t.Errorf("f-string span = %v, want columns 19-40", got)
+	}
+}
+
+func TestAttributeValueConcatenationErrors(t *testing.T) {
+	// Each bad part is reported at its own position and the tag still parses
+	input := "view V():\n    <div class=\"a {x = 1} b {y, z}\" id=\"main\">x</div>\n"
+	module, errs := parseInput(t, input)
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	first := errs[0].(*ParseError)
+	if !strings.Contains(first.Message, "assignment") || first.Span().Start.Column != 22 {
+		t.Errorf("unexpected first error: %v", first)
+	}
+	if !strings.Contains(errs[1].Error(), "parentheses") {
+		t.Errorf("unexpected second error: %v", errs[1])
+	}
+
+	view := module.Body[0].(*ast.ViewStmt)
+	element := view.Body[0].(*ast.HTMLElement)
+	if len(element.Attributes) != 2 {
+		t.Errorf("expected 2 attributes after recovery, got %d", len(element.Attributes))
+	}
+}
@@ -0,0 +1,294 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// consumeClosingTag parses the closing tag of the innermost open element.
+//
+// A closing tag that doesn't match is recorded in p.Errors and the element is
+// closed anyway, so a single mistake doesn't cascade to the end of the view:
+//   - </outer> naming an enclosing element means this element's closing tag is
+//     missing; the element is auto-closed and </outer> is left for its owner.
+//   - a stray </extra> directly followed by the expected closing tag is skipped.
+//   - anything else is taken as a misspelled closing tag for this element.
+func (p *Parser) consumeClosingTag(expectedTagName lexer.Token) error {
+	if !p.check(lexer.TagCloseStart) {
+		message := fmt.Sprintf("expected closing tag </%s> for <%s> opened at %s",
+			expectedTagName.Lexeme, expectedTagName.Lexeme, expectedTagName.Start())
+		if len(p.openTags) < 2 || !p.check(lexer.Newline) {
+			return &ParseError{Token: p.peek(), Message: message, Code: diagnostics.CodeUnclosedElement}
+		}
+		// A nested element left open at the end of its line; auto-close it and
+		// let the enclosing element carry on
+		p.Errors = append(p.Errors, &ParseError{
+			Token:   p.peek(),
+			Code:    diagnostics.CodeUnclosedElement,
+			Message: "missing closing tag: " + message,
+			Hint:    fmt.Sprintf("add </%s> at the end of the line", expectedTagName.Lexeme),
+			Related: []RelatedSpan{
+				{Span: expectedTagName.Span, Message: fmt.Sprintf("<%s> opened here", expectedTagName.Lexeme)},
+			},
+		})
+		return nil
+	}
+	closeStart := p.Current
+	p.advance() // consume '</'
+
+	closingTagName, _, err := p.tagName("expected closing tag name")
+	if err != nil {
+		return err
+	}
+
+	if closingTagName.Lexeme != expectedTagName.Lexeme {
+		if opener, ok := p.enclosingOpenTag(closingTagName.Lexeme); ok {
+			p.Errors = append(p.Errors, &ParseError{
+				Token: closingTagName,
+				Code:  diagnostics.CodeUnclosedElement,
+				Message: fmt.Sprintf("missing closing tag </%s> for <%s> opened at %s; found </%s>",
+					expectedTagName.Lexeme, expectedTagName.Lexeme, expectedTagName.Start(), closingTagName.Lexeme),
+				Hint: fmt.Sprintf("add </%s> before </%s>", expectedTagName.Lexeme, closingTagName.Lexeme),
+				Related: []RelatedSpan{
+					{Span: expectedTagName.Span, Message: fmt.Sprintf("<%s> opened here", expectedTagName.Lexeme)},
+					{Span: opener.Span, Message: fmt.Sprintf("</%s> closes this <%s>", closingTagName.Lexeme, opener.Lexeme)},
+				},
+			})
+			// Auto-close this element and leave the closing tag for the enclosing one
+			p.Current = closeStart
+			return nil
+		}
+
+		if p.check(lexer.TagClose) && p.closingTagAt(p.Current+1, expectedTagName.Lexeme) {
+			p.Errors = append(p.Errors, &ParseError{
+				Token:   closingTagName,
+				Code:    diagnostics.CodeStrayClosingTag,
+				Message: fmt.Sprintf("unexpected closing tag </%s>; it has no matching opening tag", closingTagName.Lexeme),
+				Hint:    fmt.Sprintf("remove the extra </%s>", closingTagName.Lexeme),
+				Related: []RelatedSpan{
+					{Span: expectedTagName.Span, Message: fmt.Sprintf("innermost open element is <%s>", expectedTagName.Lexeme)},
+				},
+			})
+			p.advance() // skip the stray '>' and parse the real closing tag
+			return p.consumeClosingTag(expectedTagName)
+		}
+
+		hint := fmt.Sprintf("rename it to </%s> or add the missing <%s> opening tag", expectedTagName.Lexeme, closingTagName.Lexeme)
+		if similarTagNames(expectedTagName.Lexeme, closingTagName.Lexeme) {
+			hint = fmt.Sprintf("did you mean </%s>?", expectedTagName.Lexeme)
+		}
+		p.Errors = append(p.Errors, &ParseError{
+			Token: closingTagName,
+			Code:  diagnostics.CodeMismatchedTag,
+			Message: fmt.Sprintf("closing tag name doesn't match opening tag: expected </%s>, found </%s>",
+				expectedTagName.Lexeme, closingTagName.Lexeme),
+			Hint: hint,
+			Related: []RelatedSpan{
+				{Span: expectedTagName.Span, Message: fmt.Sprintf("<%s> opened here", expectedTagName.Lexeme)},
+			},
+		})
+	}
+
+	_, err = p.consume(lexer.TagClose, "expected '>' after closing tag")
+	return err
+}
+
+// strayClosingTag reports a closing tag on its own line that doesn't close any
+// open element, and skips it.
+func (p *Parser) strayClosingTag() error {
+	p.advance() // consume '</'
+
+	closingTagName, _, err := p.tagName("expected closing tag name")
+	if err != nil {
+		return err
+	}
+
+	p.Errors = append(p.Errors, &ParseError{
+		Token:   closingTagName,
+		Code:    diagnostics.CodeStrayClosingTag,
+		Message: fmt.Sprintf("unexpected closing tag </%s>; it has no matching opening tag", closingTagName.Lexeme),
+		Hint:    fmt.Sprintf("remove the extra </%s>", closingTagName.Lexeme),
+	})
+
+	for !p.isAtEnd() && !p.check(lexer.Newline) {
+		if p.match(lexer.TagClose) {
+			break
+		}
+		p.advance()
+	}
+	return nil
+}
+
+// overIndentedClosingTag reports the closing tag of a multiline element written
+// inside its indented content, and consumes it as the element's closing tag.
+func (p *Parser) overIndentedClosingTag(tagNameToken lexer.Token) error {
+	p.advance() // consume '</'
+	closingTagName, next, _ := p.tagNameAt(p.Current)
+	p.Current = next
+
+	p.Errors = append(p.Errors, &ParseError{
+		Token:   closingTagName,
+		Code:    diagnostics.CodeMismatchedTag,
+		Message: fmt.Sprintf("closing tag </%s> must be dedented to the level of its opening tag", closingTagName.Lexeme),
+		Related: []RelatedSpan{
+			{Span: tagNameToken.Span, Message: fmt.Sprintf("<%s> opened here", tagNameToken.Lexeme)},
+		},
+	})
+
+	_, err := p.consume(lexer.TagClose, "expected '>' after closing tag")
+	return err
+}
+
+// enclosingOpenTag finds the nearest open element named name, excluding the innermost one
+func (p *Parser) enclosingOpenTag(name string) (lexer.Token, bool) {
+	for i := len(p.openTags) - 2; i >= 0; i-- {
+		if p.openTags[i].Lexeme == name {
+			return p.openTags[i], true
+		}
+	}
+	return lexer.Token{}, false
+}
+
+// closingTagAt reports whether the tokens at index i form the closing tag </name>
+func (p *Parser) closingTagAt(i int, name string) bool {
+	if !p.load(i) || p.Tokens[i].Type != lexer.TagCloseStart {
+		return false
+	}
+	tagName, _, ok := p.tagNameAt(i + 1)
+	return ok && tagName.Lexeme == name
+}
+
+// tagName parses the name of a tag: an identifier, or a {expression}
+// computing it. The expression is returned along with its tag name token.
+// The tags of a fragment, <> and </>, have an empty name.
+func (p *Parser) tagName(message string) (lexer.Token, ast.Expr, error) {
+	if p.check(lexer.Identifier) {
+		return p.advance(), nil, nil
+	}
+	name, _, ok := p.tagNameAt(p.Current)
+	if !ok {
+		return lexer.Token{}, nil, p.error(p.peek(), message)
+	}
+	if p.check(lexer.TagClose) {
+		return name, nil, nil
+	}
+
+	p.advance() // consume '{'
+	if p.check(lexer.HTMLInterpolationEnd) {
+		return lexer.Token{}, nil, p.error(p.peek(), "empty tag name interpolation; expected an expression")
+	}
+	expr, err := p.expression()
+	if err != nil {
+		return lexer.Token{}, nil, err
+	}
+	if _, err := p.consume(lexer.HTMLInterpolationEnd, "expected '}' after tag name expression"); err != nil {
+		return lexer.Token{}, nil, err
+	}
+	return name, expr, nil
+}
+
+// tagNameAt returns the tag name starting at token i without consuming it,
+// and the index of the token after it. A {expression} tag name is returned
+// as an identifier spelling the expression, braces included, with
+// whitespace normalized: opening and closing tags computing their name
+// match when they are written alike. The missing name of a fragment tag is
+// an empty identifier at the '>'.
+func (p *Parser) tagNameAt(i int) (lexer.Token, int, bool) {
+	if !p.load(i) {
+		return lexer.Token{}, i, false
+	}
+	if p.Tokens[i].Type == lexer.Identifier {
+		return p.Tokens[i], i + 1, true
+	}
+	if p.Tokens[i].Type == lexer.TagClose {
+		start := p.Tokens[i].Start()
+		return lexer.Token{Type: lexer.Identifier, Span: lexer.Span{Start: start, End: start}}, i, true
+	}
+	if p.Tokens[i].Type != lexer.HTMLInterpolationStart {
+		return lexer.Token{}, i, false
+	}
+
+	var text strings.Builder
+	text.WriteString("{")
+	previous := p.Tokens[i]
+	for j := i + 1; p.load(j); j++ {
+		token := p.Tokens[j]
+		switch token.Type {
+		case lexer.HTMLInterpolationEnd:
+			text.WriteString("}")
+			return lexer.Token{
+				Type:   lexer.Identifier,
+				Lexeme: text.String(),
+				Span:   lexer.Span{Start: p.Tokens[i].Start(), End: token.End()},
+			}, j + 1, true
+		case lexer.Newline, lexer.TagClose, lexer.TagSelfClose, lexer.EOF:
+			return lexer.Token{}, i, false
+		}
+		if separateTokens(previous, token) {
+			text.WriteString(" ")
+		}
+		text.WriteString(token.Lexeme)
+		previous = token
+	}
+	return lexer.Token{}, i, false
+}
+
+// separateTokens reports whether a space must be written between two
+// tokens of an expression to keep words, numbers and strings apart
+func separateTokens(a, b lexer.Token) bool {
+	switch b.Type {
+	case lexer.FStringMiddle, lexer.LeftBraceF, lexer.RightBraceF, lexer.FStringEnd:
+		return false
+	}
+	switch a.Type {
+	case lexer.FStringStart, lexer.FStringMiddle, lexer.LeftBraceF:
+		return false
+	}
+	if a.Lexeme == "" || b.Lexeme == "" {
+		return false
+	}
+	endsWord := isWordByte(a.Lexeme[len(a.Lexeme)-1]) || a.Type == lexer.String || a.Type == lexer.FStringEnd
+	startsWord := isWordByte(b.Lexeme[0]) || b.Type == lexer.String
+	return endsWord && startsWord
+}
+
+// isWordByte reports whether c can be part of a name or number
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
+
+// similarTagNames reports whether b looks like a typo of a: an edit distance
+// (counting swapped neighbours as one edit) of one, or two for names longer
+// than four characters.
+func similarTagNames(a, b string) bool {
+	maxDist := 1
+	if max(len(a), len(b)) > 4 {
+		maxDist = 2
+	}
+
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)] <= maxDist
+}
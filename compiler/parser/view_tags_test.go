@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+func TestMismatchedClosingTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErrors []string
+		wantHint   string
+	}{
+		{
+			name:       "misspelled closing tag",
+			body:       "<div>text</dvi>",
+			wantErrors: []string{"closing tag name doesn't match opening tag: expected </div>, found </dvi>"},
+			wantHint:   "did you mean </div>?",
+		},
+		{
+			name:       "unrelated closing tag",
+			body:       "<div></span>",
+			wantErrors: []string{"expected </div>, found </span>"},
+			wantHint:   "rename it to </div> or add the missing <span> opening tag",
+		},
+		{
+			name:       "missing inner closing tag",
+			body:       "<div><span>text</div>",
+			wantErrors: []string{"missing closing tag </span> for <span> opened at L2:11; found </div>"},
+			wantHint:   "add </span> before </div>",
+		},
+		{
+			name:       "extra closing tag",
+			body:       "<div>text</p></div>",
+			wantErrors: []string{"unexpected closing tag </p>"},
+			wantHint:   "remove the extra </p>",
+		},
+		{
+			name: "missing closing tag in multiline element",
+			body: "<ul>\n        <li>one</li>\n        <li>two\n    </ul>",
+			wantErrors: []string{
+				"missing closing tag: expected closing tag </li> for <li> opened at L4:10",
+			},
+		},
+		{
+			name:       "stray closing tag on its own line",
+			body:       "<div>\n        <p>hi</p>\n        </p>\n    </div>",
+			wantErrors: []string{"unexpected closing tag </p>; it has no matching opening tag"},
+			wantHint:   "remove the extra </p>",
+		},
+		{
+			name:       "over-indented closing tag",
+			body:       "<div>\n        <p>hi</p>\n        </div>",
+			wantErrors: []string{"closing tag </div> must be dedented to the level of its opening tag"},
+		},
+		{
+			name:       "mismatched closing tag expression",
+			body:       "<{tag}>text</{other}>",
+			wantErrors: []string{"expected </{tag}>, found </{other}>"},
+		},
+		{
+			name: "errors in sibling elements are all reported",
+			body: "<div>\n        <b>one</i>\n        <em>two</en>\n    </div>",
+			wantErrors: []string{
+				"expected </b>, found </i>",
+				"expected </em>, found </en>",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "view V():\n    " + tt.body + "\n    <footer>after</footer>\n"
+			module, errs := parseInput(t, input)
+
+			if len(errs) != len(tt.wantErrors) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.wantErrors), len(errs), errs)
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(errs[i].Error(), want) {
+					t.Errorf("error %d = %q, want it to contain %q", i, errs[i].Error(), want)
+				}
+			}
+			if tt.wantHint != "" {
+				if parseErr, ok := errs[0].(*ParseError); !ok || parseErr.Hint != tt.wantHint {
+					t.Errorf("hint = %q, want %q", errs[0].(*ParseError).Hint, tt.wantHint)
+				}
+			}
+
+			// Recovery: the element after the bad markup is still parsed
+			if module == nil || len(module.Body) != 1 {
+				t.Fatalf("expected the view to be parsed")
+			}
+			body := module.Body[0].(*ast.ViewStmt).Body
+			last, ok := body[len(body)-1].(*ast.HTMLElement)
+			if !ok || last.TagName.Lexeme != "footer" {
+				t.Errorf("expected trailing <footer> to be parsed, got %T", body[len(body)-1])
+			}
+		})
+	}
+}
+
+func TestDynamicTagNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantTag string
+	}{
+		{name: "name", body: "<{tag}>text</{tag}>", wantTag: "{tag}"},
+		{name: "whitespace is ignored", body: "<{ tag }>text</{tag}>", wantTag: "{tag}"},
+		{name: "f-string", body: `<{f"h{level}"} class="x">text</{f"h{level}"}>`, wantTag: `{f"h{level}"}`},
+		{name: "keywords", body: `<{tag if ok else "div"}>text</{tag if ok else "div"}>`, wantTag: `{tag if ok else "div"}`},
+		{name: "self-closing", body: "<{tag} />", wantTag: "{tag}"},
+		{name: "multiline", body: "<{self.tag}>\n        <p>hi</p>\n    </{self.tag}>", wantTag: "{self.tag}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, errs := parseInput(t, "view V():\n    "+tt.body+"\n")
+			if len(errs) != 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			element, ok := module.Body[0].(*ast.ViewStmt).Body[0].(*ast.HTMLElement)
+			if !ok {
+				t.Fatalf("expected an HTML element, got %T", module.Body[0].(*ast.ViewStmt).Body[0])
+			}
+			if element.TagName.Lexeme != tt.wantTag {
+				t.Errorf("tag name = %q, want %q", element.TagName.Lexeme, tt.wantTag)
+			}
+			if element.TagExpr == nil {
+				t.Errorf("expected the tag name expression to be parsed")
+			}
+		})
+	}
+
+	_, errs := parseInput(t, "view V():\n    <{}>text</{}>\n")
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "empty tag name interpolation") {
+		t.Errorf("expected an empty tag name error, got %v", errs)
+	}
+}
+
+func TestFragments(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantType    ast.HTMLElementType
+		wantContent int
+	}{
+		{name: "single line", body: "<><b>a</b>text</>", wantType: ast.HTMLSingleLineElement, wantContent: 2},
+		{name: "empty", body: "<></>", wantType: ast.HTMLSingleLineElement, wantContent: 0},
+		{name: "multiline", body: "<>\n        <li>a</li>\n        <li>b</li>\n    </>", wantType: ast.HTMLMultilineElement, wantContent: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, errs := parseInput(t, "view V():\n    "+tt.body+"\n")
+			if len(errs) != 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			element, ok := module.Body[0].(*ast.ViewStmt).Body[0].(*ast.HTMLElement)
+			if !ok {
+				t.Fatalf("expected an HTML element, got %T", module.Body[0].(*ast.ViewStmt).Body[0])
+			}
+			if !element.IsFragment() {
+				t.Errorf("expected a fragment, got <%s>", element.TagName.Lexeme)
+			}
+			if element.Type != tt.wantType {
+				t.Errorf("element type = %v, want %v", element.Type, tt.wantType)
+			}
+			if len(element.Content) != tt.wantContent {
+				t.Errorf("expected %d content statements, got %d", tt.wantContent, len(element.Content))
+			}
+		})
+	}
+
+	_, errs := parseInput(t, "view V():\n    <>text</div>\n")
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "expected </>, found </div>") {
+		t.Errorf("expected a mismatched closing tag error, got %v", errs)
+	}
+	_, errs = parseInput(t, "view V():\n    <div>text</>\n")
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "expected </div>, found </>") {
+		t.Errorf("expected a mismatched closing tag error, got %v", errs)
+	}
+}
+
+func TestMismatchedClosingTagSpans(t *testing.T) {
+	input := "view V():\n    <div><span>text</div>\n"
+	_, errs := parseInput(t, input)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+
+	parseErr := errs[0].(*ParseError)
+	if got := parseErr.Span().Start; got != (lexer.Position{Line: 2, Column: 22}) {
+		t.Errorf("primary span starts at %s, want L2:22", got)
+	}
+	if len(parseErr.Related) != 2 {
+		t.Fatalf("expected 2 related spans, got %d", len(parseErr.Related))
+	}
+	if got := parseErr.Related[0].Span.Start; got != (lexer.Position{Line: 2, Column: 11}) {
+		t.Errorf("opening tag span starts at %s, want L2:11", got)
+	}
+	if got := parseErr.Related[1].Span.Start; got != (lexer.Position{Line: 2, Column: 6}) {
+		t.Errorf("enclosing tag span starts at %s, want L2:6", got)
+	}
+
+	frame := parseErr.CodeFrame([]byte(input), "app.psx")
+	for _, want := range []string{
+		"error[E0201]: missing closing tag </span>",
+		"--> app.psx:2:22",
+		"note: <span> opened here",
+		"--> app.psx:2:11",
+		"= hint: add </span> before </div>",
+	} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("code frame missing %q:\n%s", want, frame)
+		}
+	}
+}
+
+func TestSimilarTagNames(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"div", "dvi", true},
+		{"div", "dov", true},
+		{"section", "sectoin", true},
+		{"button", "buton", true},
+		{"p", "a", true},
+		{"div", "span", false},
+		{"ul", "li", false},
+		{"header", "footer", false},
+	}
+
+	for _, tt := range tests {
+		if got := similarTagNames(tt.a, tt.b); got != tt.want {
+			t.Errorf("similarTagNames(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
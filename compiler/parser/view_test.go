@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"errors"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"strings"
 	"testing"
@@ -16,6 +19,10 @@ func parseViewStatement(t *testing.T, input string) (*ast.ViewStmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Errors in nested blocks are recovered from and recorded
+	if len(parser.Errors) > 0 {
+		return nil, parser.Errors[0]
+	}
 	viewStmt, ok := stmt.(*ast.ViewStmt)
 	if !ok {
 		return nil, nil
@@ -310,12 +317,27 @@ func TestViewWithHTMLElements(t *testing.T) {
 			description: "view with unclosed HTML tag should fail",
 		},
 		{
-			name: "mismatched closing tag",
-			input: `view test():
-    <div></span>`,
+			name: "scoped slot content",
+			input: `view test(rows):
+    <Table rows={rows}><tr let={(row, index)}>{row}</tr></Table>`,
+			expectedTag: "Table",
+			description: "view with content naming the values of a scoped slot",
+		},
+		{
+			name: "let with an expression",
+			input: `view test(rows):
+    <Table rows={rows}><tr let={row.name}>{row}</tr></Table>`,
 			hasError:    true,
-			errorText:   "closing tag name doesn't match",
-			description: "view with mismatched HTML tag should fail",
+			errorText:   "let must name the values of a scoped slot",
+			description: "let naming something other than values should fail",
+		},
+		{
+			name: "let naming a value twice",
+			input: `view test(rows):
+    <Table rows={rows}><tr let={(row, row)}>{row}</tr></Table>`,
+			hasError:    true,
+			errorText:   "let must name the values of a scoped slot",
+			description: "let naming a value twice should fail",
 		},
 	}
 
@@ -547,3 +569,360 @@ func TestViewEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestViewHTMLComments(t *testing.T) {
+	input := "view V():\n    <!-- top -->\n    <div>\n        <!-- inner -->\n        <p>a <!-- inline --> b</p>\n    </div>\n"
+
+	cfg := lexer.DefaultScannerConfig()
+	cfg.PreserveHTMLComments = true
+	scanner := lexer.NewScannerWithConfig([]byte(input), cfg)
+	module, errs := NewParser(scanner.ScanTokens()).Parse()
+	validateParseSuccess(t, module, errs, 1)
+
+	body := module.Body[0].(*ast.ViewStmt).Body
+	if len(body) != 2 {
+		t.Fatalf("expected comment and element in view body, got %d statements", len(body))
+	}
+	if comment, ok := body[0].(*ast.HTMLComment); !ok || comment.Text != " top " {
+		t.Errorf("expected top-level comment, got %v", body[0])
+	}
+
+	div := body[1].(*ast.HTMLElement)
+	if comment, ok := div.Content[0].(*ast.HTMLComment); !ok || comment.Text != " inner " {
+		t.Errorf("expected comment inside <div>, got %v", div.Content[0])
+	}
+
+	p := div.Content[1].(*ast.HTMLElement)
+	if len(p.Content) != 3 {
+		t.Fatalf("expected text, comment, text inside <p>, got %d items", len(p.Content))
+	}
+	if comment, ok := p.Content[1].(*ast.HTMLComment); !ok || comment.Text != " inline " {
+		t.Errorf("expected inline comment, got %v", p.Content[1])
+	}
+
+	// Without preservation the comment-only lines are simply blank
+	module, errs = parseInput(t, input)
+	validateParseSuccess(t, module, errs, 1)
+	if body := module.Body[0].(*ast.ViewStmt).Body; len(body) != 1 {
+		t.Errorf("expected only the <div> without comment preservation, got %d statements", len(body))
+	}
+}
+
+func TestDuplicateAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		element   string
+		wantError string // Empty when the element is valid
+		wantFirst int    // Column of the first occurrence
+	}{
+		{"distinct attributes", `<div id="a" class="b" data-id="c"></div>`, "", 0},
+		{"repeated attribute", `<div class="a" id="x" class="b"></div>`, "duplicate attribute 'class' on <div>", 10},
+		{"repeated boolean attribute", `<input disabled disabled />`, "duplicate attribute 'disabled' on <input>", 12},
+		{"case-insensitive on HTML elements", `<div ID="a" id={b}></div>`, "duplicate attribute 'id' on <div>", 10},
+		{"repeated prop", `<Card title="a" title={b} />`, "duplicate attribute 'title' on <Card>", 11},
+		{"case-sensitive props", `<Card title="a" Title="b" />`, "", 0},
+		{"repeated slot", `<Card><p slot="a" slot="b">x</p></Card>`, "duplicate attribute 'slot' on <p>", 14},
+		{"spreads are not duplicates", `<div {...b} class="a" {...b}></div>`, "", 0},
+		{"repeated attribute around a spread", `<div class="a" {...b} class="c"></div>`, "duplicate attribute 'class' on <div>", 10},
+		{"attribute in a later literal spread", `<div class="a" {...{"class": "x"}}></div>`, "duplicate attribute 'class' on <div>", 10},
+		{"literal spread before an attribute", `<div {...{"class": "x"}} class="a"></div>`, "duplicate attribute 'class' on <div>", 15},
+		{"prop in a literal spread", `<Card title="a" {...{"title": "b"}} />`, "duplicate attribute 'title' on <Card>", 11},
+		{"either branch of a conditional spread", `<div {...{"id": "x"} if b else {"class": "y"}} class="a"></div>`, "duplicate attribute 'class' on <div>", 37},
+		{"two literal spreads", `<div {...{"a": 1}} {...{"a": 2} if b}></div>`, "duplicate attribute 'a' on <div>", 15},
+		{"branches of one spread are not duplicates", `<div {...{"a": 1} if b else {"a": 2}}></div>`, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, errs := parseInput(t, "view V(b):\n    "+tt.element+"\n")
+			if tt.wantError == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no errors, got %v", errs)
+				}
+				return
+			}
+
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+			}
+			parseErr, ok := errs[0].(*ParseError)
+			if !ok || !strings.Contains(parseErr.Message, tt.wantError) {
+				t.Fatalf("expected %q, got %v", tt.wantError, errs[0])
+			}
+			if parseErr.Code != diagnostics.CodeDuplicateAttribute {
+				t.Errorf("expected code %s, got %s", diagnostics.CodeDuplicateAttribute, parseErr.Code)
+			}
+			if len(parseErr.Related) != 1 || parseErr.Related[0].Span.Start.Column != tt.wantFirst {
+				t.Errorf("expected the first occurrence at column %d, got %v", tt.wantFirst, parseErr.Related)
+			}
+
+			// Parsing continues after the duplicate
+			if module == nil || len(module.Body) != 1 {
+				t.Fatalf("expected the view to be parsed")
+			}
+		})
+	}
+}
+
+func TestAttributeSpreads(t *testing.T) {
+	tests := []struct {
+		name      string
+		element   string
+		want      []string // Attributes in order, "..." + the mapping for spreads
+		wantError string
+	}{
+		{"spread", `<div {...attrs}></div>`, []string{"...attrs"}, ""},
+		{"spread among attributes", `<div id="a" {...attrs} class={b}></div>`, []string{"id", "...attrs", "class"}, ""},
+		{"conditional spread", `<div {...attrs if ok else {}}></div>`, []string{"...ok ? attrs : {}"}, ""},
+		{"conditional shorthand", `<div {...attrs if ok} />`, []string{"...ok ? attrs : {}"}, ""},
+		{"literal mapping", `<Card {...{"title": b}} />`, []string{"...{\"title\": b}"}, ""},
+		{"empty spread", `<div {...}></div>`, nil, "empty attribute spread"},
+		{"trailing tokens", `<div {...attrs x}></div>`, nil, "expected '}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, errs := parseInput(t, "view V(attrs, ok, b):\n    "+tt.element+"\n")
+			if tt.wantError != "" {
+				if len(errs) == 0 || !strings.Contains(errs[0].Error(), tt.wantError) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantError, errs)
+				}
+				// Parsing continues after the spread
+				if module == nil || len(module.Body) != 1 {
+					t.Fatalf("expected the view to be parsed")
+				}
+				return
+			}
+			if len(errs) != 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+
+			element := module.Body[0].(*ast.ViewStmt).Body[0].(*ast.HTMLElement)
+			var got []string
+			for _, attr := range element.Attributes {
+				if !attr.Spread {
+					got = append(got, attr.Name.Lexeme)
+					continue
+				}
+				if attr.Name.Type != lexer.Ellipsis {
+					t.Errorf("expected the spread to be named by its '...' token, got %v", attr.Name)
+				}
+				got = append(got, "..."+attr.Value.String())
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("expected attributes %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAsyncViews(t *testing.T) {
+	module, errs := parseInput(t, `async view Feed(items):
+    async with session() as s:
+        <p>{s}</p>
+    with open(path) as f:
+        <pre>{f.read()}</pre>
+    async for item in items:
+        <li>{item}</li>
+
+@deprecated("use Feed")
+async view Decorated():
+    <p>hi</p>
+
+view Plain():
+    <p>hi</p>
+`)
+	validateParseSuccess(t, module, errs, 3)
+
+	feed := module.Body[0].(*ast.ViewStmt)
+	if !feed.IsAsync {
+		t.Error("Expected Feed to be async")
+	}
+	if feed.Span.Start.Line != 1 || feed.Span.Start.Column != 1 {
+		t.Errorf("Expected the view to start at the 'async' keyword, got %v", feed.Span.Start)
+	}
+	validateStatementTypes(t, feed.Body, "*ast.With", "*ast.With", "*ast.For")
+	if !feed.Body[0].(*ast.With).IsAsync || feed.Body[1].(*ast.With).IsAsync {
+		t.Error("Expected only the first with statement to be async")
+	}
+	if _, ok := feed.Body[0].(*ast.With).Body[0].(*ast.HTMLElement); !ok {
+		t.Errorf("Expected markup in the with body, got %T", feed.Body[0].(*ast.With).Body[0])
+	}
+	if !feed.Body[2].(*ast.For).IsAsync {
+		t.Error("Expected an async for statement")
+	}
+
+	decorator, ok := module.Body[1].(*ast.Decorator)
+	if !ok {
+		t.Fatalf("Expected a decorator, got %T", module.Body[1])
+	}
+	if view, ok := decorator.Definition().(*ast.ViewStmt); !ok || !view.IsAsync {
+		t.Error("Expected the decorated view to be async")
+	}
+
+	if module.Body[2].(*ast.ViewStmt).IsAsync {
+		t.Error("Expected Plain not to be async")
+	}
+}
+
+func TestLayoutViews(t *testing.T) {
+	module, errs := parseInput(t, `layout Base(title):
+    <html>
+        <body><slot /></body>
+    </html>
+
+async layout Feed():
+    <main><slot /></main>
+
+view Page(name):
+    use layout Base(title=name)
+    <p>{name}</p>
+
+view Bare(): use layout layouts.Base
+
+layout = Base
+layout(Base)
+`)
+	validateParseSuccess(t, module, errs, 6)
+
+	base := module.Body[0].(*ast.ViewStmt)
+	if !base.IsLayout || base.IsAsync || base.Layout != nil {
+		t.Errorf("Expected Base to be a synchronous layout without layout, got %+v", base)
+	}
+	validateStatementTypes(t, base.Body, "*ast.HTMLElement")
+
+	feed := module.Body[1].(*ast.ViewStmt)
+	if !feed.IsLayout || !feed.IsAsync {
+		t.Errorf("Expected Feed to be an async layout, got %+v", feed)
+	}
+
+	page := module.Body[2].(*ast.ViewStmt)
+	if page.IsLayout {
+		t.Error("Expected Page not to be a layout")
+	}
+	call, ok := page.Layout.(*ast.Call)
+	if !ok || call.Callee.(*ast.Name).Token.Lexeme != "Base" || len(call.Arguments) != 1 {
+		t.Errorf("Expected the layout call Base(title=name), got %#v", page.Layout)
+	}
+	validateStatementTypes(t, page.Body, "*ast.HTMLElement")
+
+	bare := module.Body[3].(*ast.ViewStmt)
+	if _, ok := bare.Layout.(*ast.Attribute); !ok || len(bare.Body) != 0 {
+		t.Errorf("Expected the layout layouts.Base and an empty body, got %#v and %d statements", bare.Layout, len(bare.Body))
+	}
+
+	// 'layout' is a soft keyword, so it stays usable as a name
+	validateStatementTypes(t, module.Body[4:], "*ast.AssignStmt", "*ast.ExprStmt")
+}
+
+func TestLayoutViewErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "use layout after markup",
+			input: `view Page():
+    <p>hi</p>
+    use layout Base
+`,
+			want: "'use layout' must be the first statement of a view",
+		},
+		{
+			name: "use layout of an expression",
+			input: `view Page():
+    use layout Base + 1
+`,
+			want: "expected a layout name or call after 'use layout'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := parseInput(t, tt.input)
+			if len(errs) == 0 {
+				t.Fatal("Expected a parse error")
+			}
+			if !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, errs[0])
+			}
+		})
+	}
+}
+
+func TestTrimMarker(t *testing.T) {
+	viewStmt, err := parseViewStatement(t, "view test(name):\n    <p>Hello  {~}  {name} {~}</p>\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	element, ok := viewStmt.Body[0].(*ast.HTMLElement)
+	if !ok || len(element.Content) != 1 {
+		t.Fatalf("Expected a <p> with one content node, got %v", viewStmt.Body[0])
+	}
+	content, ok := element.Content[0].(*ast.HTMLContent)
+	if !ok {
+		t.Fatalf("Expected HTML content, got %T", element.Content[0])
+	}
+
+	// The markers are kept for the transformer, which trims the text around
+	// them; whitespace-only text is dropped as usual
+	var got []string
+	for _, part := range content.Parts {
+		got = append(got, part.String())
+	}
+	if want := "Hello  |{~}|{name}|{~}"; strings.Join(got, "|") != want {
+		t.Errorf("Expected parts %q, got %q", want, strings.Join(got, "|"))
+	}
+}
+
+func TestSyntaxVersionFeatures(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"spread", "view V(extra: dict):\n    <div {...extra}>x</div>\n", "attribute spreads need syntax 0.2"},
+		{"computed tag", "view V(tag: str):\n    <{tag}>x</{tag}>\n", "computed tag names need syntax 0.2"},
+		{"fragment", "view V():\n    <><p>a</p><p>b</p></>\n", "fragments need syntax 0.2"},
+		{"whitespace trim", "view V(n: int):\n    <p>{n} {~}: items</p>\n", "{~} whitespace trims need syntax 0.2"},
+		{"layout", "layout Page():\n    <main><slot /></main>\n", "layouts need syntax 0.2"},
+	}
+
+	parse := func(src string) []error {
+		scanner := lexer.NewScanner([]byte(src))
+		tokens := scanner.ScanTokens()
+		if len(scanner.Errors) > 0 {
+			t.Fatalf("scan errors: %v", scanner.Errors)
+		}
+		p := NewParser(tokens)
+		p.Syntax = scanner.Syntax()
+		_, errs := p.Parse()
+		return errs
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := parse(tt.body); len(errs) > 0 {
+				t.Fatalf("unexpected errors with the current syntax: %v", errs)
+			}
+
+			errs := parse("# topple: syntax=0.1\n" + tt.body)
+			if len(errs) != 1 {
+				t.Fatalf("expected one error with syntax 0.1, got %v", errs)
+			}
+			var parseErr *ParseError
+			if !errors.As(errs[0], &parseErr) || parseErr.Code != diagnostics.CodeSyntaxVersion {
+				t.Fatalf("expected an E0208 error, got %v", errs[0])
+			}
+			if !strings.Contains(parseErr.Message, tt.message) || !strings.Contains(parseErr.Message, "this file selects syntax 0.1") {
+				t.Errorf("expected %q in %q", tt.message, parseErr.Message)
+			}
+		})
+	}
+
+	// Attribute interpolation predates the pragma
+	if errs := parse("# topple: syntax=0.1\nview V(variant: str):\n    <a class=\"btn {variant}\">x</a>\n"); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
@@ -20,7 +20,7 @@ func (p *Parser) whileStatement() (ast.Stmt, error) {
 	}
 
 	// Expect colon
-	_, err = p.consume(lexer.Colon, "expected ':' after while condition")
+	_, err = p.consumeConditionColon("while")
 	if err != nil {
 		return nil, err
 	}
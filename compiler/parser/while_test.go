@@ -16,6 +16,10 @@ func parseWhileStatement(t *testing.T, input string) (*ast.While, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Errors in nested blocks are recovered from and recorded
+	if len(parser.Errors) > 0 {
+		return nil, parser.Errors[0]
+	}
 	whileStmt, ok := stmt.(*ast.While)
 	if !ok {
 		return nil, nil
@@ -298,7 +302,7 @@ func TestWhileErrors(t *testing.T) {
 		{
 			name:        "invalid condition",
 			input:       "while x = 1:\n    pass",
-			errorText:   "expected",
+			errorText:   "cannot assign with '=' in a while condition",
 			description: "while statement with assignment in condition should fail",
 		},
 		{
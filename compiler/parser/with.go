@@ -7,6 +7,12 @@ import (
 
 // withStatement parses a with statement.
 func (p *Parser) withStatement() (ast.Stmt, error) {
+	return p.withBlock(p.block)
+}
+
+// withBlock parses a with statement whose body is parsed by block, so views
+// can share it with a view block.
+func (p *Parser) withBlock(block func() ([]ast.Stmt, error)) (ast.Stmt, error) {
 	// Check for async
 	isAsync := false
 	var startToken lexer.Token
@@ -51,7 +57,7 @@ func (p *Parser) withStatement() (ast.Stmt, error) {
 	}
 
 	// Parse the body
-	body, err := p.block()
+	body, err := block()
 	if err != nil {
 		return nil, err
 	}
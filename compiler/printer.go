@@ -126,6 +126,21 @@ func (p *ASTPrinter) VisitFString(node *ast.FString) ast.Visitor {
 	return p
 }
 
+// VisitConcatenatedString handles ConcatenatedString nodes
+func (p *ASTPrinter) VisitConcatenatedString(node *ast.ConcatenatedString) ast.Visitor {
+	p.printNodeStart("ConcatenatedString", node)
+	p.result.WriteString(" (\n")
+
+	p.indentLevel++
+	for _, part := range node.Parts {
+		part.Accept(p)
+	}
+	p.indentLevel--
+
+	p.result.WriteString(fmt.Sprintf("%s)\n", p.indent()))
+	return p
+}
+
 // VisitFStringMiddle handles FStringMiddle nodes
 func (p *ASTPrinter) VisitFStringMiddle(node *ast.FStringMiddle) ast.Visitor {
 	p.printNodeStart("FStringMiddle", node)
@@ -2288,6 +2303,14 @@ func (p *ASTPrinter) VisitViewStmt(node *ast.ViewStmt) ast.Visitor {
 		p.indentLevel--
 	}
 
+	// Visit the layout of 'use layout' if any
+	if node.Layout != nil {
+		p.result.WriteString(fmt.Sprintf("%slayout:\n", p.indent()))
+		p.indentLevel++
+		node.Layout.Accept(p)
+		p.indentLevel--
+	}
+
 	// Visit body statements
 	if len(node.Body) > 0 {
 		p.result.WriteString(fmt.Sprintf("%sbody:\n", p.indent()))
@@ -2303,8 +2326,11 @@ func (p *ASTPrinter) VisitViewStmt(node *ast.ViewStmt) ast.Visitor {
 		p.indentLevel--
 	}
 
-	// Display async flag
+	// Display async and layout flags
 	p.result.WriteString(fmt.Sprintf("%sisAsync: %t\n", p.indent(), node.IsAsync))
+	if node.IsLayout {
+		p.result.WriteString(fmt.Sprintf("%sisLayout: true\n", p.indent()))
+	}
 
 	p.indentLevel--
 
@@ -2347,7 +2373,11 @@ func (p *ASTPrinter) VisitHTMLElement(node *ast.HTMLElement) ast.Visitor {
 		for i, attr := range node.Attributes {
 			p.result.WriteString(fmt.Sprintf("%sattr %d:\n", p.indent(), i))
 			p.indentLevel++
-			p.result.WriteString(fmt.Sprintf("%sname: %s\n", p.indent(), attr.Name.Lexeme))
+			if attr.Spread {
+				p.result.WriteString(fmt.Sprintf("%sspread: true\n", p.indent()))
+			} else {
+				p.result.WriteString(fmt.Sprintf("%sname: %s\n", p.indent(), attr.Name.Lexeme))
+			}
 			if attr.Value != nil {
 				p.result.WriteString(fmt.Sprintf("%svalue:\n", p.indent()))
 				p.indentLevel++
@@ -2490,7 +2520,7 @@ func (p *ASTPrinter) VisitHTMLContent(node *ast.HTMLContent) ast.Visitor {
 // VisitHTMLText handles HTMLText nodes
 func (p *ASTPrinter) VisitHTMLText(node *ast.HTMLText) ast.Visitor {
 	p.printNodeStart("HTMLText", node)
-	p.result.WriteString(fmt.Sprintf(" (%s)\n", node.Value))
+	p.result.WriteString(fmt.Sprintf(" (%s)\n", node.String()))
 	return p
 }
 
@@ -2514,3 +2544,10 @@ func (p *ASTPrinter) VisitHTMLInterpolation(node *ast.HTMLInterpolation) ast.Vis
 	p.result.WriteString(fmt.Sprintf("%s)\n", p.indent()))
 	return p
 }
+
+// VisitHTMLComment handles HTMLComment nodes
+func (p *ASTPrinter) VisitHTMLComment(node *ast.HTMLComment) ast.Visitor {
+	p.printNodeStart("HTMLComment", node)
+	p.result.WriteString(fmt.Sprintf(" (%s)\n", node.Text))
+	return p
+}
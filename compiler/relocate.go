@@ -0,0 +1,259 @@
+package compiler
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+)
+
+// relocateImports rewrites the imports of filePath, at any depth, that name
+// other compiled files, so they resolve from where the outputs are written
+// under Options.OutputDir. Imports are made absolute from the output
+// directory, the package root of the output tree, which is on the Python
+// path: outputs written at its top belong to no package, so relative
+// imports in them would not resolve. Imports of shared libraries on the
+// search paths, other imports, and imports whose path does not change, are
+// left as written. The module is not modified; the result is a copy.
+func (c *MultiFileCompiler) relocateImports(filePath string, module *ast.Module) (*ast.Module, error) {
+	if c.options.OutputDir == "" || filepath.Ext(c.options.OutputDir) == ".py" {
+		// Outputs next to their sources, or a single output file
+		return module, nil
+	}
+	outputRoot, err := filepath.Abs(c.options.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	importer, err := c.fs.GetOutputPath(filePath, c.options.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative imports only resolve in outputs written to a package below
+	// the output directory
+	inPackage := filepath.Dir(importer) != outputRoot
+	relocate := func(target string, relative bool) (string, int, error) {
+		output, err := c.fs.GetOutputPath(target, c.options.OutputDir)
+		if err != nil {
+			return "", 0, err
+		}
+		return relocatedModule(outputRoot, filepath.Dir(importer), output, relative && inPackage)
+	}
+
+	// Imports nested in functions, classes and blocks are dependencies too
+	var relocateErr error
+	relocated := ast.Rewrite(module, func(node ast.Node) ast.Node {
+		stmt, ok := node.(ast.Stmt)
+		if !ok || relocateErr != nil {
+			return node
+		}
+		rewritten, err := c.relocateImport(filePath, stmt, relocate)
+		if err != nil {
+			relocateErr = err
+			return node
+		}
+		if rewritten != nil {
+			return rewritten
+		}
+		return node
+	})
+	if relocateErr != nil {
+		return nil, relocateErr
+	}
+	return relocated.(*ast.Module), nil
+}
+
+// relocateImport returns stmt rewritten by relocateImports, or nil when stmt
+// is not an import or is left as written
+func (c *MultiFileCompiler) relocateImport(filePath string, stmt ast.Stmt, relocate func(string, bool) (string, int, error)) (ast.Stmt, error) {
+	switch s := stmt.(type) {
+	case *ast.ImportFromStmt:
+		target, ok := c.resolveImportFrom(filePath, s)
+		if !ok {
+			return c.relocateNamespaceImport(filePath, s, relocate)
+		}
+		if c.libraries[target] {
+			return nil, nil
+		}
+		path, dots, err := relocate(target, s.DotCount > 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot relocate import at %s: %w", s.Span, err)
+		}
+		if path == dottedPath(s.DottedName) && dots == s.DotCount {
+			return nil, nil
+		}
+		rewritten := *s
+		rewritten.DottedName = newDottedName(path, s.DottedName)
+		rewritten.DotCount = dots
+		return &rewritten, nil
+
+	case *ast.ImportStmt:
+		var names []*ast.ImportName
+		for j, name := range s.Names {
+			target, err := c.moduleResolver.ResolveAbsolute(context.Background(), dottedPath(name.DottedName))
+			if err != nil || filepath.Ext(target) != ".psx" || c.libraries[target] {
+				continue
+			}
+			path, _, err := relocate(target, false)
+			if err != nil {
+				return nil, fmt.Errorf("cannot relocate import at %s: %w", s.Span, err)
+			}
+			if path == dottedPath(name.DottedName) {
+				continue
+			}
+			if name.AsName == nil {
+				// Code using the module refers to it by its full path
+				return nil, fmt.Errorf("cannot relocate 'import %s' at %s to %s: import it with an alias or from-import its names", dottedPath(name.DottedName), s.Span, path)
+			}
+			if names == nil {
+				names = make([]*ast.ImportName, len(s.Names))
+				copy(names, s.Names)
+			}
+			rewrittenName := *name
+			rewrittenName.DottedName = newDottedName(path, name.DottedName)
+			names[j] = &rewrittenName
+		}
+		if names != nil {
+			rewritten := *s
+			rewritten.Names = names
+			return &rewritten, nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveImportFrom returns the .psx file a from-import names, the way the
+// dependency graph resolves it
+func (c *MultiFileCompiler) resolveImportFrom(filePath string, stmt *ast.ImportFromStmt) (string, bool) {
+	if stmt.DottedName == nil {
+		// "from . import name" names a package, which is not compiled
+		return "", false
+	}
+	var target string
+	var err error
+	if stmt.DotCount > 0 {
+		target, err = c.moduleResolver.ResolveRelative(context.Background(), stmt.DotCount, dottedPath(stmt.DottedName), filePath)
+	} else {
+		target, err = c.moduleResolver.ResolveAbsolute(context.Background(), dottedPath(stmt.DottedName))
+	}
+	if err != nil || filepath.Ext(target) != ".psx" {
+		return "", false
+	}
+	return target, true
+}
+
+// relocateNamespaceImport rewrites "from ns import a, b", which imports
+// modules of a namespace package, to import them from the package their
+// outputs are written to, or with "import a, b" when that is the top of the
+// output tree. It returns nil when the import is left as written: it names
+// no namespace package, or names that are not compiled modules.
+func (c *MultiFileCompiler) relocateNamespaceImport(filePath string, stmt *ast.ImportFromStmt, relocate func(string, bool) (string, int, error)) (ast.Stmt, error) {
+	if stmt.IsWildcard {
+		return nil, nil
+	}
+	var err error
+	if stmt.DotCount > 0 {
+		_, err = c.moduleResolver.ResolveRelative(context.Background(), stmt.DotCount, dottedPath(stmt.DottedName), filePath)
+	} else {
+		_, err = c.moduleResolver.ResolveAbsolute(context.Background(), dottedPath(stmt.DottedName))
+	}
+	if !module.IsNamespacePackage(err) {
+		return nil, nil
+	}
+
+	pkg, dots := "", -1
+	for _, name := range stmt.Names {
+		submodule := name.DottedName.Names[0].Token.Lexeme
+		if stmt.DottedName != nil {
+			submodule = dottedPath(stmt.DottedName) + "." + submodule
+		}
+		target, ok := c.resolveImportFrom(filePath, &ast.ImportFromStmt{DottedName: newDottedName(submodule, nil), DotCount: stmt.DotCount})
+		if !ok || c.libraries[target] {
+			return nil, nil
+		}
+		path, d, err := relocate(target, stmt.DotCount > 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot relocate import at %s: %w", stmt.Span, err)
+		}
+		parent := ""
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			parent = path[:i]
+		}
+		if dots >= 0 && (parent != pkg || d != dots) {
+			return nil, fmt.Errorf("cannot relocate import at %s: the modules it imports from %s are written to different packages; import them separately", stmt.Span, dottedPath(stmt.DottedName))
+		}
+		pkg, dots = parent, d
+	}
+	if pkg == dottedPath(stmt.DottedName) && dots == stmt.DotCount {
+		return nil, nil
+	}
+
+	if pkg == "" && dots == 0 {
+		// Modules at the top of the output tree belong to no package
+		return &ast.ImportStmt{Names: stmt.Names, Span: stmt.Span}, nil
+	}
+	rewritten := *stmt
+	rewritten.DottedName = nil
+	if pkg != "" {
+		rewritten.DottedName = newDottedName(pkg, stmt.DottedName)
+	}
+	rewritten.DotCount = dots
+	return &rewritten, nil
+}
+
+// relocatedModule returns the module path of the output file target as an
+// import written in fromDir sees it: relative to fromDir with its dot count,
+// or absolute from outputRoot
+func relocatedModule(outputRoot, fromDir, target string, relative bool) (string, int, error) {
+	target = strings.TrimSuffix(target, filepath.Ext(target))
+	if !relative {
+		rel, err := filepath.Rel(outputRoot, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", 0, fmt.Errorf("%s.py is outside the output directory %s", target, outputRoot)
+		}
+		return strings.ReplaceAll(filepath.ToSlash(rel), "/", "."), 0, nil
+	}
+
+	rel, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return "", 0, err
+	}
+	dots := 1
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for len(parts) > 1 && parts[0] == ".." {
+		dots++
+		parts = parts[1:]
+	}
+	return strings.Join(parts, "."), dots, nil
+}
+
+func dottedPath(name *ast.DottedName) string {
+	if name == nil {
+		return ""
+	}
+	parts := make([]string, len(name.Names))
+	for i, part := range name.Names {
+		parts[i] = part.Token.Lexeme
+	}
+	return strings.Join(parts, ".")
+}
+
+// newDottedName creates the dotted name of path, spanning what it replaces
+func newDottedName(path string, replaced *ast.DottedName) *ast.DottedName {
+	var span lexer.Span
+	if replaced != nil {
+		span = replaced.Span
+	}
+	name := &ast.DottedName{Span: span}
+	for _, part := range strings.Split(path, ".") {
+		name.Names = append(name.Names, &ast.Name{
+			Token: lexer.Token{Lexeme: part, Type: lexer.Identifier},
+			Span:  span,
+		})
+	}
+	return name
+}
@@ -0,0 +1,245 @@
+package compiler
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/codegen"
+)
+
+func TestRelocatedModule(t *testing.T) {
+	root := filepath.FromSlash("/out")
+	tests := []struct {
+		name     string
+		fromDir  string
+		target   string
+		relative bool
+		path     string
+		dots     int
+	}{
+		{"same directory", "/out", "/out/button.py", true, "button", 1},
+		{"subdirectory", "/out", "/out/components/button.py", true, "components.button", 1},
+		{"parent directory", "/out/pages", "/out/button.py", true, "button", 2},
+		{"sibling directory", "/out/pages", "/out/components/button.py", true, "components.button", 2},
+		{"absolute", "/out/pages", "/out/components/button.py", false, "components.button", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, dots, err := relocatedModule(root, filepath.FromSlash(tt.fromDir), filepath.FromSlash(tt.target), tt.relative)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != tt.path || dots != tt.dots {
+				t.Errorf("got %q with %d dots, want %q with %d", path, dots, tt.path, tt.dots)
+			}
+		})
+	}
+
+	if _, _, err := relocatedModule(root, root, filepath.FromSlash("/elsewhere/button.py"), false); err == nil {
+		t.Error("expected a target outside the output directory to be rejected")
+	}
+}
+
+func TestMultiFileCompiler_RelocatedImports(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": "view Button(label: str):\n    <button>{label}</button>\n",
+		"pages/home.psx": `from ..components.button import Button
+from components.button import Button as Primary
+import components.button as buttons
+import os
+
+view Home():
+    <div>
+        <Button label="a" />
+        <Primary label="b" />
+    </div>
+`,
+	})
+	home := filepath.Join(tmpDir, "pages", "home.psx")
+
+	compile := func(outputDir string) (*MultiFileOutput, error) {
+		return NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+			RootDir: tmpDir,
+			Files:   []string{tmpDir},
+			Options: Options{OutputDir: outputDir},
+		})
+	}
+
+	// Outputs next to the sources keep their imports
+	output, err := compile("")
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+	if code := string(output.CompiledFiles[home]); !strings.Contains(code, "from ..components.button import Button\n") {
+		t.Errorf("expected imports to be left alone, got:\n%s", code)
+	}
+
+	// An output directory holds every file directly
+	output, err = compile(filepath.Join(tmpDir, "dist"))
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+	code := string(output.CompiledFiles[home])
+	for _, expected := range []string{
+		"from button import Button\n",
+		"from button import Button as Primary\n",
+		"import button as buttons\n",
+		"import os\n",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("expected %q in:\n%s", expected, code)
+		}
+	}
+
+	// Without an alias, code names the module by the path that changed
+	tmpDir = setupTestFiles(t, map[string]string{
+		"components/button.psx": "view Button(label: str):\n    <button>{label}</button>\n",
+		"home.psx":              "import components.button\n\nview Home():\n    <p>home</p>\n",
+	})
+	output, err = NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Options: Options{OutputDir: filepath.Join(tmpDir, "dist")},
+	})
+	if err == nil && len(output.Errors) == 0 {
+		t.Fatal("expected the bare import to be rejected")
+	}
+	if len(output.Errors) != 1 || output.Errors[0].Stage != "relocate" || !strings.Contains(output.Errors[0].Error(), "import it with an alias") {
+		t.Errorf("unexpected errors %v", output.Errors)
+	}
+}
+
+func TestMultiFileCompiler_RelocatedNestedImports(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": "view Button(label: str):\n    <button>{label}</button>\n",
+		"pages/home.psx": `def button():
+    from ..components.button import Button
+    return Button
+
+class Page:
+    if True:
+        import components.button as buttons
+
+view Home():
+    <p>home</p>
+`,
+	})
+	home := filepath.Join(tmpDir, "pages", "home.psx")
+
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Options: Options{OutputDir: filepath.Join(tmpDir, "dist")},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	// Imports in functions, classes and blocks are relocated too
+	code := string(output.CompiledFiles[home])
+	for _, expected := range []string{"    from button import Button\n", "        import button as buttons\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("expected %q in:\n%s", expected, code)
+		}
+	}
+	if strings.Contains(code, "components") {
+		t.Errorf("expected every import to be relocated:\n%s", code)
+	}
+}
+
+func TestMultiFileCompiler_RelocatedNamespaceImports(t *testing.T) {
+	// ui and pages have no __init__.psx: they are namespace packages
+	tmpDir := setupTestFiles(t, map[string]string{
+		"ui/card.psx":    "view Card(title: str):\n    <div>{title}</div>\n",
+		"ui/badge.psx":   "view Badge(text: str):\n    <span>{text}</span>\n",
+		"pages/nav.psx":  "view Nav():\n    <nav>nav</nav>\n",
+		"pages/home.psx": "from ui import card as cards, badge\nfrom . import nav\n\nview Home():\n    <p>home</p>\n",
+	})
+	home := filepath.Join(tmpDir, "pages", "home.psx")
+
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Options: Options{OutputDir: filepath.Join(tmpDir, "dist")},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+
+	// The modules are written to the top of the output directory
+	code := string(output.CompiledFiles[home])
+	for _, expected := range []string{"import card as cards, badge\n", "import nav\n"} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("expected %q in:\n%s", expected, code)
+		}
+	}
+	if strings.Contains(code, "from ui import") {
+		t.Errorf("expected the namespace import to be relocated:\n%s", code)
+	}
+}
+
+func TestRelocatedImportsRunInPython(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err == nil {
+		err = exec.Command(python, "-c", "import sys; assert sys.version_info >= (3, 10)").Run()
+	}
+	if err != nil {
+		t.Skip("python3.10 or later is not installed")
+	}
+
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/button.psx": "view Button(label: str):\n    <button>{label}</button>\n",
+		"components/card.psx":   "from .button import Button\n\nview Card(title: str):\n    <div><Button label={title} /></div>\n",
+		"pages/nav.psx":         "view Nav():\n    <nav>nav</nav>\n",
+		"pages/home.psx": `from ..components.card import Card
+from components.button import Button
+from .nav import Nav
+from . import nav
+
+view Home():
+    <main>
+        <Card title="a" />
+        <Button label="b" />
+        <Nav />
+    </main>
+`,
+	})
+	dist := filepath.Join(tmpDir, "dist")
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+		Options: Options{OutputDir: dist, Target: codegen.Py310},
+	})
+	if err != nil {
+		t.Fatalf("CompileProject failed: %v (%v)", err, output.Errors)
+	}
+	if err := os.MkdirAll(dist, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, code := range output.CompiledFiles {
+		name := strings.TrimSuffix(filepath.Base(path), ".psx") + ".py"
+		if err := os.WriteFile(filepath.Join(dist, name), code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the output directory on the Python path, every module imports
+	cmd := exec.Command(python, "-c", "import home; print(home.Home().render())")
+	cmd.Dir = t.TempDir()
+	cmd.Env = append(os.Environ(), "PYTHONPATH="+dist+string(os.PathListSeparator)+root)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("python3 failed: %v\n%s", err, out)
+	}
+	if rendered := strings.TrimSpace(string(out)); rendered != "<main><div><button>a</button></div><button>b</button><nav>nav</nav></main>" {
+		t.Errorf("unexpected render %q", rendered)
+	}
+}
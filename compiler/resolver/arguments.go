@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// ArgumentError reports a view composition whose attributes do not match the
+// parameters of the view: an attribute that names no parameter, which would
+// be dropped, or a required parameter that nothing passes, which would fail
+// when the view is instantiated
+type ArgumentError struct {
+	Message    string
+	Suggestion string     // Parameter an unknown attribute probably meant; "" when none is close
+	Params     []string   // Parameters of the view, in declaration order
+	Where      lexer.Span // Attribute name, or the tag name for missing parameters
+}
+
+// Error returns the error with its position
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("%s (position %s)", e.Message, e.Where)
+}
+
+// Span returns the span of the error
+func (e *ArgumentError) Span() lexer.Span {
+	return e.Where
+}
+
+// Diagnostic returns the structured form of the error, with the closest
+// parameter as a suggested fix
+func (e *ArgumentError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     diagnostics.CodeViewArgument,
+		Message:  e.Message,
+		Span:     lexer.DiagnosticSpan(e.Where),
+	}
+	if e.Suggestion != "" {
+		d.Suggestions = append(d.Suggestions, diagnostics.Suggestion{
+			Span:        d.Span,
+			Replacement: e.Suggestion,
+			Message:     fmt.Sprintf("did you mean '%s'?", e.Suggestion),
+		})
+	}
+	if len(e.Params) > 0 {
+		d.Notes = append(d.Notes, fmt.Sprintf("parameters: %s", strings.Join(e.Params, ", ")))
+	} else {
+		d.Notes = append(d.Notes, "the view takes no parameters")
+	}
+	return d
+}
+
+// checkViewArguments reports the attributes of a view composition that name
+// no parameter of the view, and the required parameters neither an attribute
+// nor child content passes. Views taking **kwargs or rendering a slot named by
+// an expression accept any attribute, and required parameters are not checked when a {...mapping} spread or a slot
+// with a computed name may pass them.
+func (r *Resolver) checkViewArguments(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	accepted := viewParameters(viewStmt)
+	if hasComputedSlot(viewStmt) {
+		accepted = nil
+	}
+	var params []string
+	if viewStmt.Params != nil {
+		for _, param := range viewStmt.Params.Parameters {
+			if param != nil && param.Name != nil && !param.IsStar && !param.IsDoubleStar {
+				params = append(params, param.Name.Token.Lexeme)
+			}
+		}
+	}
+	// Each <slot> of the view adds a parameter taking its content
+	var slots []string
+	for slot := range viewSlots(viewStmt) {
+		if accepted != nil && !accepted[slot] {
+			accepted[slot] = true
+			slots = append(slots, slot)
+		}
+	}
+	sort.Strings(slots)
+	params = append(params, slots...)
+	view := viewStmt.Name.Token.Lexeme
+
+	passed := make(map[string]bool)
+	complete := true
+	for _, attr := range h.Attributes {
+		if attr.Spread {
+			complete = false
+			continue
+		}
+		name := attr.Name.Lexeme
+		passed[name] = true
+		// slot="..." and let={...} place the composition in a slot of its parent
+		if accepted == nil || accepted[name] || name == "slot" || name == "let" {
+			continue
+		}
+		r.ReportError(&ArgumentError{
+			Message:    fmt.Sprintf("'%s' is not a parameter of %s", name, view),
+			Suggestion: closestName(name, params),
+			Params:     params,
+			Where:      attr.Name.Span,
+		})
+	}
+
+	for _, stmt := range h.Content {
+		if _, ok := stmt.(*ast.HTMLComment); ok {
+			continue
+		}
+		target, _, ok := slotTarget(stmt)
+		if !ok {
+			complete = false
+			continue
+		}
+		passed[target] = true
+	}
+	if !complete || viewStmt.Params == nil {
+		return
+	}
+
+	var missing []string
+	for _, param := range viewStmt.Params.Parameters {
+		if param == nil || param.Name == nil || param.IsStar || param.IsDoubleStar || param.IsSlash || param.Default != nil {
+			continue
+		}
+		if name := param.Name.Token.Lexeme; !passed[name] {
+			missing = append(missing, "'"+name+"'")
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+	noun := "parameter"
+	if len(missing) > 1 {
+		noun = "parameters"
+	}
+	r.ReportError(&ArgumentError{
+		Message: fmt.Sprintf("<%s> is missing required %s %s", h.TagName.Lexeme, noun, strings.Join(missing, ", ")),
+		Params:  params,
+		Where:   h.TagName.Span,
+	})
+}
+
+// closestName returns the name of names closest to name, if it is within a
+// couple of edits of it, such as "lable" for "label"
+func closestName(name string, names []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
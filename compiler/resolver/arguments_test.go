@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+func TestViewArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		site    string
+		want    []string // Messages of the errors, in order
+		suggest string   // Replacement suggested for the first error
+	}{
+		{
+			name: "all parameters",
+			site: "<Button label=\"x\" size=\"lg\" />",
+		},
+		{
+			name:    "misspelled attribute",
+			site:    "<Button lable=\"x\" />",
+			want:    []string{"'lable' is not a parameter of Button", "<Button> is missing required parameter 'label'"},
+			suggest: "label",
+		},
+		{
+			name: "unknown attribute",
+			site: "<Button label=\"x\" extra={1} />",
+			want: []string{"'extra' is not a parameter of Button"},
+		},
+		{
+			name: "missing required parameters",
+			site: "<Link />",
+			want: []string{"<Link> is missing required parameters 'href', 'children'"},
+		},
+		{
+			name: "children passed as content",
+			site: "<Link href=\"/\">Home</Link>",
+		},
+		{
+			name: "named slot attribute and content",
+			site: "<Card title=\"t\"><p slot=\"footer\">f</p></Card>",
+		},
+		{
+			name: "slot passed as content",
+			site: "<Card><h1 slot=\"title\">t</h1></Card>",
+		},
+		{
+			name: "spread may pass required parameters",
+			site: "<Link {...props}>Home</Link>",
+		},
+		{
+			name: "kwargs accept any attribute",
+			site: "<Box anything={1} />",
+		},
+		{
+			name: "slot attribute places the composition",
+			site: "<Card title=\"t\"><Button slot=\"footer\" label=\"x\" /></Card>",
+		},
+		{
+			name: "computed slot accepts any slot",
+			site: "<Tabs active=\"a\" profile=\"p\"><b slot=\"billing\">b</b></Tabs>",
+		},
+		{
+			name: "computed slot is not the default slot",
+			site: "<Tabs />",
+			want: []string{"<Tabs> is missing required parameter 'active'"},
+		},
+		{
+			name: "HTML elements are not checked",
+			site: "<div extra={1}></div>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "view Button(label: str, size=\"md\"):\n    <button>{label}</button>\n\n" +
+				"view Link(href, children):\n    <a href={href}>{children}</a>\n\n" +
+				"view Card(title):\n    <div>{title}<slot name=\"footer\" /></div>\n\n" +
+				"view Box(**attrs):\n    <div></div>\n\n" +
+				"view Tabs(active):\n    <div><slot name={active} /></div>\n\n" +
+				"view Page(props):\n    " + tt.site + "\n"
+			scanner := lexer.NewScanner([]byte(source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			registry := symbol.NewRegistry()
+			registry.RegisterModule("/project/page.psx", symbol.NewCollector("/project/page.psx").CollectFromModule(module))
+			table, _ := NewResolverWithDeps(nil, registry, "/project/page.psx").Resolve(module)
+
+			var got []string
+			for i, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodeViewArgument {
+					t.Errorf("Expected a %s error, got %s for %v", diagnostics.CodeViewArgument, d.Code, err)
+				}
+				if len(d.Notes) != 1 {
+					t.Errorf("Expected the parameters as a note, got %v", d.Notes)
+				}
+				if i == 0 && tt.suggest != "" && (len(d.Suggestions) != 1 || d.Suggestions[0].Replacement != tt.suggest) {
+					t.Errorf("Expected the suggestion %q, got %+v", tt.suggest, d.Suggestions)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
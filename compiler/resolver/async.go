@@ -0,0 +1,23 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// checkAsyncView reports a composition of an async view outside an async
+// function or view. The composing view awaits the child's rendering, which a
+// synchronous _render cannot do.
+func (r *Resolver) checkAsyncView(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	if !viewStmt.IsAsync || r.inAsync {
+		return
+	}
+	r.ReportError(&Error{
+		Code:    diagnostics.CodeAsyncContext,
+		Message: fmt.Sprintf("async view %s can only be used in an async view", viewStmt.Name.Token.Lexeme),
+		Span:    lexer.Span{Start: h.TagName.Start(), End: h.TagName.End()},
+	})
+}
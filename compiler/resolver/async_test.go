@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestAsyncContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string // Messages of the errors, in order
+	}{
+		{
+			name: "async function and view",
+			source: `
+async def load():
+    async with session() as s:
+        return await s.get()
+
+async view Item(n):
+    value = await load()
+    async for x in stream():
+        <p>{x}</p>
+    <p>{value}</p>
+
+async view List():
+    <Item n={1} />
+`,
+		},
+		{
+			name: "module level await",
+			source: `
+x = await load()
+`,
+			want: []string{"'await' outside async function or view"},
+		},
+		{
+			name: "sync function",
+			source: `
+def f(items):
+    async for x in items:
+        pass
+    async with lock:
+        pass
+`,
+			want: []string{
+				"'async for' outside async function or view",
+				"'async with' outside async function or view",
+			},
+		},
+		{
+			name: "sync view",
+			source: `
+async view Item(n):
+    <p>{n}</p>
+
+view Page():
+    x = await load()
+    <Item n={x} />
+`,
+			want: []string{
+				"'await' outside async function or view",
+				"async view Item can only be used in an async view",
+			},
+		},
+		{
+			name: "nested scopes",
+			source: `
+async def outer():
+    def inner():
+        return await load()
+    class A:
+        y = await load()
+    async def fine():
+        return await load()
+`,
+			want: []string{
+				"'await' outside async function or view",
+				"'await' outside async function or view",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := lexer.NewScanner([]byte(tt.source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+			table, _ := NewResolver().Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodeAsyncContext {
+					t.Errorf("Expected code %s, got %s for %v", diagnostics.CodeAsyncContext, d.Code, err)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestWithTargetsAreBound(t *testing.T) {
+	_, table := parseAndResolve(t, `
+def f():
+    with open(path) as handle:
+        return handle
+`)
+	uses := 0
+	for name, variable := range table.Variables {
+		if name.Token.Lexeme != "handle" {
+			continue
+		}
+		uses++
+		if variable.State == VariableUndefined || variable.DefinitionDepth != 1 {
+			t.Errorf("Expected the with target to be bound in the function, got %+v", variable)
+		}
+	}
+	if uses == 0 {
+		t.Error("Expected the with body to be resolved")
+	}
+}
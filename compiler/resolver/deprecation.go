@@ -0,0 +1,49 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// DeprecationWarning reports an import or composition of a view, function
+// or class marked @deprecated. It does not fail compilation.
+type DeprecationWarning struct {
+	Token   lexer.Token // Imported name or tag name of the use
+	Name    string      // Name the symbol was defined with
+	Message string      // Message of the marker, usually naming the replacement
+}
+
+// Description returns the warning without its position
+func (w *DeprecationWarning) Description() string {
+	if w.Message == "" {
+		return fmt.Sprintf("'%s' is deprecated", w.Name)
+	}
+	return fmt.Sprintf("'%s' is deprecated: %s", w.Name, w.Message)
+}
+
+// Error returns the warning with its position
+func (w *DeprecationWarning) Error() string {
+	return fmt.Sprintf("%s (position %s)", w.Description(), w.Token.Span)
+}
+
+// Span returns the span of the use
+func (w *DeprecationWarning) Span() lexer.Span {
+	return w.Token.Span
+}
+
+// Diagnostic returns the structured form of the warning
+func (w *DeprecationWarning) Diagnostic() *diagnostics.Diagnostic {
+	return &diagnostics.Diagnostic{
+		Severity: diagnostics.Warning,
+		Code:     diagnostics.CodeDeprecated,
+		Message:  w.Description(),
+		Span:     lexer.DiagnosticSpan(w.Token.Span),
+	}
+}
+
+// warnDeprecated records a use of a deprecated symbol
+func (r *Resolver) warnDeprecated(token lexer.Token, name, message string) {
+	r.Warnings = append(r.Warnings, &DeprecationWarning{Token: token, Name: name, Message: message})
+}
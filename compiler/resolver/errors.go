@@ -0,0 +1,35 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Error is a resolution error, located at the statement or expression that
+// caused it
+type Error struct {
+	Code    diagnostics.Code
+	Message string
+	Span    lexer.Span
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (position %s)", e.Message, e.Span)
+}
+
+// Diagnostic returns the structured form of the error
+func (e *Error) Diagnostic() *diagnostics.Diagnostic {
+	return &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     e.Code,
+		Message:  e.Message,
+		Span:     lexer.DiagnosticSpan(e.Span),
+	}
+}
+
+// errorAt reports a resolution error at node
+func (r *Resolver) errorAt(node interface{ GetSpan() lexer.Span }, code diagnostics.Code, format string, args ...any) {
+	r.ReportError(&Error{Code: code, Message: fmt.Sprintf(format, args...), Span: node.GetSpan()})
+}
@@ -2,8 +2,10 @@ package resolver
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
@@ -91,6 +93,27 @@ func formatScopeType(scopeType ScopeType) string {
 	}
 }
 
+// formatImportModule returns the module of a from-import as written, such as "..utils"
+func formatImportModule(stmt *ast.ImportFromStmt) string {
+	return strings.Repeat(".", stmt.DotCount) + convertDottedNameToPath(stmt.DottedName)
+}
+
+// sortedWildcardImports returns the wildcard imports of a table in source order
+func sortedWildcardImports(rt *ResolutionTable) []*ast.ImportFromStmt {
+	stmts := make([]*ast.ImportFromStmt, 0, len(rt.WildcardImports))
+	for stmt := range rt.WildcardImports {
+		stmts = append(stmts, stmt)
+	}
+	sort.Slice(stmts, func(i, j int) bool {
+		si, sj := stmts[i].Span.Start, stmts[j].Span.Start
+		if si.Line != sj.Line {
+			return si.Line < sj.Line
+		}
+		return si.Column < sj.Column
+	})
+	return stmts
+}
+
 // formatSpan returns a human-readable string for a source span (line:column).
 func formatSpan(span lexer.Span) string {
 	if span.Start.Line == 0 && span.Start.Column == 0 {
@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+)
+
+// UnresolvedImportError reports an absolute import that resolves to no PSX
+// module although the import policy requires one. Without a policy such an
+// import is taken for a Python module and emitted as written.
+type UnresolvedImportError struct {
+	Module string     // Dotted module path of the import
+	Prefix string     // Internal prefix requiring a PSX module; "" under strict imports
+	Where  lexer.Span // Module path of the import
+}
+
+// Error returns the error with its position
+func (e *UnresolvedImportError) Error() string {
+	return fmt.Sprintf("cannot resolve import '%s' (position %s)", e.Module, e.Where)
+}
+
+// Span returns the span of the error
+func (e *UnresolvedImportError) Span() lexer.Span {
+	return e.Where
+}
+
+// Diagnostic returns the structured form of the error, with the policy that
+// requires a PSX module
+func (e *UnresolvedImportError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     diagnostics.CodeModuleNotFound,
+		Message:  fmt.Sprintf("cannot resolve import '%s'", e.Module),
+		Span:     lexer.DiagnosticSpan(e.Where),
+	}
+	if e.Prefix != "" {
+		d.Notes = append(d.Notes, fmt.Sprintf("modules under '%s' are declared internal and must be PSX modules", e.Prefix))
+	} else {
+		d.Notes = append(d.Notes, "with strict imports, a module must be a PSX module or declared external")
+		d.Hints = append(d.Hints, fmt.Sprintf("if '%s' is a Python module, declare it external", e.Module))
+	}
+	return d
+}
+
+// checkUnresolvedImport reports an absolute import that resolved to no
+// module when the import policy requires it to be a PSX module
+func (r *Resolver) checkUnresolvedImport(modulePath string, where lexer.Span, err error) {
+	if !module.IsModuleNotFound(err) {
+		return
+	}
+	if required, prefix := r.ModuleResolver.RequiresPSX(modulePath); required {
+		r.ReportError(&UnresolvedImportError{Module: modulePath, Prefix: prefix, Where: where})
+	}
+}
@@ -3,6 +3,7 @@ package resolver
 import (
 	"context"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"github.com/fjvillamarin/topple/compiler/module"
 	"github.com/fjvillamarin/topple/compiler/symbol"
@@ -57,10 +58,31 @@ func (m *mockFileSystem) ListPSXFiles(dir string, recursive bool) ([]string, err
 	return nil, nil
 }
 
+func (m *mockFileSystem) ReadDir(dir string) ([]string, error) {
+	prefix := dir + string(filepath.Separator)
+	seen := make(map[string]bool)
+	var names []string
+	for p := range m.files {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		name := strings.SplitN(p[len(prefix):], string(filepath.Separator), 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
 func (m *mockFileSystem) MkdirAll(path string, perm os.FileMode) error {
 	return nil
 }
 
+func (m *mockFileSystem) RemoveAll(path string) error {
+	return nil
+}
+
 func (m *mockFileSystem) ResolvePath(path string) (string, error) {
 	return filepath.Abs(path)
 }
@@ -336,6 +358,39 @@ func TestImportStmt_ModuleNotFound(t *testing.T) {
 	}
 }
 
+func TestImportPolicy(t *testing.T) {
+	mockFS := newMockFS(map[string]bool{"/project/ui/button.psx": true})
+	moduleResolver := module.NewResolver(module.Config{
+		RootDir:    "/project",
+		FileSystem: mockFS,
+		Imports:    module.ImportPolicy{External: []string{"datetime"}, Internal: []string{"ui"}, Strict: true},
+	})
+
+	mod := &ast.Module{
+		Body: []ast.Stmt{
+			&ast.ImportStmt{Names: []*ast.ImportName{{DottedName: createDottedName("datetime")}}},
+			&ast.ImportFromStmt{DottedName: createDottedName("ui", "card"), Names: []*ast.ImportName{{DottedName: createDottedName("Card")}}},
+			&ast.ImportStmt{Names: []*ast.ImportName{{DottedName: createDottedName("requests")}}},
+		},
+	}
+	table, _ := NewResolverWithDeps(moduleResolver, symbol.NewRegistry(), "/project/main.psx").Resolve(mod)
+
+	var got []string
+	for _, err := range table.Errors {
+		importErr, ok := err.(*UnresolvedImportError)
+		if !ok {
+			t.Fatalf("Expected an UnresolvedImportError, got %T: %v", err, err)
+		}
+		got = append(got, importErr.Module+":"+importErr.Prefix)
+	}
+	if want := "ui.card:ui, requests:"; strings.Join(got, ", ") != want {
+		t.Errorf("Expected unresolved imports %s, got %v", want, got)
+	}
+	if d := diagnostics.From(table.Errors[1]); d.Code != diagnostics.CodeModuleNotFound || len(d.Hints) != 1 {
+		t.Errorf("Expected an E0400 diagnostic with a hint, got %+v", d)
+	}
+}
+
 func TestImportFromStmt_SpecificImport(t *testing.T) {
 	moduleResolver, symbolRegistry := setupTestEnvironment()
 
@@ -516,6 +571,50 @@ func TestImportFromStmt_Wildcard(t *testing.T) {
 	if _, exists := resolver.ModuleGlobals["_private_func"]; exists {
 		t.Error("Expected private symbol '_private_func' not to be imported with wildcard")
 	}
+
+	// The table records exactly the names brought into scope
+	names := table.WildcardImports[importStmt]
+	if strings.Join(names, ",") != "MyView,helper_func" {
+		t.Errorf("Expected wildcard names [MyView helper_func], got %v", names)
+	}
+}
+
+func TestImportFromStmt_WildcardOutput(t *testing.T) {
+	moduleResolver, symbolRegistry := setupTestEnvironment()
+
+	importStmt := &ast.ImportFromStmt{
+		DottedName: createDottedName("utils"),
+		IsWildcard: true,
+		Span:       lexer.Span{Start: lexer.Position{Line: 1, Column: 0}},
+	}
+	module := &ast.Module{Body: []ast.Stmt{importStmt}}
+
+	resolver := NewResolverWithDeps(moduleResolver, symbolRegistry, "/project/main.psx")
+	table, err := resolver.Resolve(module)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	text, err := table.ToText("main.psx")
+	if err != nil {
+		t.Fatalf("ToText failed: %v", err)
+	}
+	want := "  from utils import * (1:0):\n    • MyView\n    • helper_func\n"
+	if !strings.Contains(text, "WILDCARD IMPORTS:") || !strings.Contains(text, want) {
+		t.Errorf("Expected the wildcard import names in the text output, got:\n%s", text)
+	}
+
+	result, err := table.ToJSON("main.psx")
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if len(result.WildcardImports) != 1 {
+		t.Fatalf("Expected 1 wildcard import, got %d", len(result.WildcardImports))
+	}
+	wildcard := result.WildcardImports[0]
+	if wildcard.Module != "utils" || strings.Join(wildcard.Names, ",") != "MyView,helper_func" {
+		t.Errorf("Unexpected wildcard import %+v", wildcard)
+	}
 }
 
 func TestImportFromStmt_RelativeImport(t *testing.T) {
@@ -622,6 +721,45 @@ func TestImportFromStmt_DottedAbsoluteImport(t *testing.T) {
 	}
 }
 
+func TestImportFromStmt_NamespacePackage(t *testing.T) {
+	// lib has no __init__.psx: "from lib import core" imports the module
+	moduleResolver, symbolRegistry := setupTestEnvironment()
+
+	alias := &ast.Name{Token: lexer.Token{Lexeme: "c", Type: lexer.Identifier}}
+	importStmt := &ast.ImportFromStmt{
+		DottedName: createDottedName("lib"),
+		Names: []*ast.ImportName{
+			{DottedName: createDottedName("core"), AsName: alias},
+			{DottedName: createDottedName("missing")},
+		},
+	}
+
+	resolver := NewResolverWithDeps(moduleResolver, symbolRegistry, "/project/main.psx")
+	table, err := resolver.Resolve(&ast.Module{Body: []ast.Stmt{importStmt}})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(table.Errors) > 0 {
+		t.Fatalf("Expected no errors, got: %v", table.Errors)
+	}
+
+	variable, exists := resolver.ModuleGlobals["c"]
+	if !exists {
+		t.Fatal("Expected the module to be bound to its alias")
+	}
+	if !variable.IsImported || variable.ImportSource != "/project/lib/core.psx" {
+		t.Errorf("Expected an import of /project/lib/core.psx, got %+v", variable)
+	}
+	if resolver.Variables[alias] != variable {
+		t.Error("Expected the alias to be tracked in the Variables map")
+	}
+
+	// Names that are not modules of the package pass through
+	if _, exists := resolver.ModuleGlobals["missing"]; exists {
+		t.Error("Expected 'missing' to be left to Python")
+	}
+}
+
 func TestImportFromStmt_SymbolNotFound(t *testing.T) {
 	moduleResolver, symbolRegistry := setupTestEnvironment()
 
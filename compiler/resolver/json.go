@@ -141,15 +141,23 @@ type JSONSummary struct {
 	FreeVars        int `json:"free_vars"`
 }
 
+// JSONWildcardImport lists the names a 'from x import *' brought into scope
+type JSONWildcardImport struct {
+	Module string        `json:"module"`
+	Span   JSONSpanRange `json:"span"`
+	Names  []string      `json:"names"`
+}
+
 // JSONResolution is the top-level structure for JSON output
 type JSONResolution struct {
-	File            string          `json:"file"`
-	Scopes          []JSONScope     `json:"scopes"`
-	Variables       []JSONVariable  `json:"variables"`
-	Views           JSONViews       `json:"views"`
-	ClosureAnalysis JSONClosure     `json:"closure_analysis"`
-	Diagnostics     JSONDiagnostics `json:"diagnostics"`
-	Summary         JSONSummary     `json:"summary"`
+	File            string               `json:"file"`
+	Scopes          []JSONScope          `json:"scopes"`
+	Variables       []JSONVariable       `json:"variables"`
+	WildcardImports []JSONWildcardImport `json:"wildcard_imports"`
+	Views           JSONViews            `json:"views"`
+	ClosureAnalysis JSONClosure          `json:"closure_analysis"`
+	Diagnostics     JSONDiagnostics      `json:"diagnostics"`
+	Summary         JSONSummary          `json:"summary"`
 }
 
 // ToJSON converts a ResolutionTable to JSON format
@@ -211,6 +219,9 @@ func (rt *ResolutionTable) ToJSON(filename string) (*JSONResolution, error) {
 	// Convert variables with references
 	result.Variables = convertVariables(rt, varIDMap)
 
+	// Convert wildcard imports
+	result.WildcardImports = convertWildcardImports(rt)
+
 	// Convert views
 	result.Views = convertViews(rt)
 
@@ -465,6 +476,19 @@ func convertClosureAnalysis(rt *ResolutionTable) JSONClosure {
 	return closure
 }
 
+// convertWildcardImports converts wildcard imports to JSON format, in source order
+func convertWildcardImports(rt *ResolutionTable) []JSONWildcardImport {
+	result := []JSONWildcardImport{}
+	for _, stmt := range sortedWildcardImports(rt) {
+		result = append(result, JSONWildcardImport{
+			Module: formatImportModule(stmt),
+			Span:   spanToJSONRange(stmt.Span),
+			Names:  append([]string{}, rt.WildcardImports[stmt]...),
+		})
+	}
+	return result
+}
+
 // convertDiagnostics converts errors to JSON format
 func convertDiagnostics(errors []error) JSONDiagnostics {
 	diagnostics := JSONDiagnostics{
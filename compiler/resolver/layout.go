@@ -0,0 +1,49 @@
+package resolver
+
+import (
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+// checkLayout reports a layout without a default slot to place the content of
+// the views using it, and a 'use layout' of v that names a view that is not a
+// layout, passes the content itself, or needs an await v cannot do. The
+// layout is recorded in Layouts when it is a view of the file or an imported
+// one.
+func (r *Resolver) checkLayout(v *ast.ViewStmt) {
+	if v.IsLayout && v.Name != nil && !viewSlots(v)["children"] {
+		r.errorAt(v.Name, diagnostics.CodeLayout, "layout %s has no default <slot /> for the content of the views using it", v.Name.Token.Lexeme)
+	}
+	if v.Layout == nil {
+		return
+	}
+
+	callee := v.Layout
+	if call, ok := v.Layout.(*ast.Call); ok {
+		callee = call.Callee
+		for _, arg := range call.Arguments {
+			if arg.Name != nil && arg.Name.Token.Lexeme == "children" {
+				r.errorAt(arg, diagnostics.CodeLayout, "'use layout' cannot pass children: the content of the view fills the layout's default slot")
+			}
+		}
+	}
+	name, ok := callee.(*ast.Name)
+	if !ok {
+		// A layout of another module, like layouts.Base, is left to the runtime
+		return
+	}
+	layout, exists := r.Views[name.Token.Lexeme]
+	if !exists {
+		if layout, _ = r.importedView(name.Token.Lexeme); layout == nil {
+			return
+		}
+	}
+	if !layout.IsLayout {
+		r.errorAt(name, diagnostics.CodeLayout, "view %s is not a layout; declare it with 'layout %s(...)'", name.Token.Lexeme, name.Token.Lexeme)
+		return
+	}
+	if layout.IsAsync && !v.IsAsync {
+		r.errorAt(name, diagnostics.CodeAsyncContext, "async layout %s can only be used in an async view", name.Token.Lexeme)
+	}
+	r.Layouts[v] = layout
+}
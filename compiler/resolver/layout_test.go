@@ -0,0 +1,129 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestLayouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string // Messages of the errors, in order
+		layouts map[string]string
+	}{
+		{
+			name: "nested layouts",
+			source: `
+layout Base(title):
+    <html>
+        <body><slot /></body>
+    </html>
+
+layout Section(title):
+    use layout Base(title=title)
+    <section><slot /></section>
+
+view Page(name):
+    use layout Section(title=name)
+    <p>{name}</p>
+`,
+			layouts: map[string]string{"Section": "Base", "Page": "Section"},
+		},
+		{
+			name: "layout without default slot",
+			source: `
+layout Base():
+    <main><slot name="header" /></main>
+`,
+			want: []string{"layout Base has no default <slot /> for the content of the views using it"},
+		},
+		{
+			name: "not a layout",
+			source: `
+view Card():
+    <div><slot /></div>
+
+view Page():
+    use layout Card
+`,
+			want: []string{"view Card is not a layout; declare it with 'layout Card(...)'"},
+		},
+		{
+			name: "children argument",
+			source: `
+layout Base():
+    <main><slot /></main>
+
+view Page():
+    use layout Base(children="x")
+`,
+			want:    []string{"'use layout' cannot pass children: the content of the view fills the layout's default slot"},
+			layouts: map[string]string{"Page": "Base"},
+		},
+		{
+			name: "async layout",
+			source: `
+async layout Base():
+    <main><slot /></main>
+
+view Page():
+    use layout Base
+
+async view Feed():
+    use layout Base
+`,
+			want:    []string{"async layout Base can only be used in an async view"},
+			layouts: map[string]string{"Page": "Base", "Feed": "Base"},
+		},
+		{
+			name: "layout of another module",
+			source: `
+import layouts
+
+view Page():
+    use layout layouts.Base
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := lexer.NewScanner([]byte(tt.source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+			table, _ := NewResolver().Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				got = append(got, diagnostics.From(err).Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+
+			layouts := make(map[string]string)
+			for view, layout := range table.Layouts {
+				layouts[view.Name.Token.Lexeme] = layout.Name.Token.Lexeme
+			}
+			if len(layouts) != len(tt.layouts) {
+				t.Errorf("Expected layouts %v, got %v", tt.layouts, layouts)
+			}
+			for view, layout := range tt.layouts {
+				if layouts[view] != layout {
+					t.Errorf("Expected %s to use layout %s, got %q", view, layout, layouts[view])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package resolver
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Case is a naming convention
+type Case string
+
+const (
+	PascalCase Case = "pascal" // CardHeader
+	CamelCase  Case = "camel"  // cardHeader
+	SnakeCase  Case = "snake"  // card_header
+	KebabCase  Case = "kebab"  // card-header
+)
+
+// NamingConventions are the naming rules of a project. A rule left empty is
+// not checked.
+type NamingConventions struct {
+	Views   Case // Names of module-level views
+	Modules Case // File names of modules, without the .psx extension
+	Slots   Case // Names of <slot name="..."> elements and slot="..." attributes
+}
+
+// ParseNamingConventions parses rules written as "views=pascal,modules=snake,slots=kebab"
+func ParseNamingConventions(s string) (NamingConventions, error) {
+	var conventions NamingConventions
+	if s == "" {
+		return conventions, nil
+	}
+	for _, rule := range strings.Split(s, ",") {
+		kind, value, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok {
+			return NamingConventions{}, fmt.Errorf("invalid naming rule %q (expected kind=case, such as views=pascal)", rule)
+		}
+		c := Case(strings.TrimSpace(value))
+		switch c {
+		case PascalCase, CamelCase, SnakeCase, KebabCase:
+		default:
+			return NamingConventions{}, fmt.Errorf("unknown naming case %q (valid: pascal, camel, snake, kebab)", value)
+		}
+		switch strings.TrimSpace(kind) {
+		case "views":
+			conventions.Views = c
+		case "modules":
+			conventions.Modules = c
+		case "slots":
+			conventions.Slots = c
+		default:
+			return NamingConventions{}, fmt.Errorf("unknown naming rule %q (valid: views, modules, slots)", kind)
+		}
+	}
+	return conventions, nil
+}
+
+// IsZero reports whether no rule is set
+func (n NamingConventions) IsZero() bool {
+	return n == NamingConventions{}
+}
+
+// Matches reports whether name follows the convention. Leading underscores,
+// which mark private names, are ignored.
+func (c Case) Matches(name string) bool {
+	name = strings.TrimLeft(name, "_")
+	if name == "" {
+		return true
+	}
+	first := rune(name[0])
+	switch c {
+	case PascalCase:
+		return unicode.IsUpper(first) && !strings.ContainsAny(name, "_-")
+	case CamelCase:
+		return unicode.IsLower(first) && !strings.ContainsAny(name, "_-")
+	case SnakeCase:
+		return name == strings.ToLower(name) && !strings.Contains(name, "-") && !strings.Contains(name, "__")
+	case KebabCase:
+		return name == strings.ToLower(name) && !strings.Contains(name, "_") && !strings.Contains(name, "--")
+	}
+	return true
+}
+
+// Convert rewrites name in the convention, keeping its leading underscores
+func (c Case) Convert(name string) string {
+	trimmed := strings.TrimLeft(name, "_")
+	prefix := name[:len(name)-len(trimmed)]
+	words := splitWords(trimmed)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if c == PascalCase || c == CamelCase && i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	switch c {
+	case SnakeCase:
+		return prefix + strings.Join(words, "_")
+	case KebabCase:
+		return prefix + strings.Join(words, "-")
+	}
+	return prefix + strings.Join(words, "")
+}
+
+// label names the convention in its own style, such as "snake_case"
+func (c Case) label() string {
+	switch c {
+	case PascalCase:
+		return "PascalCase"
+	case CamelCase:
+		return "camelCase"
+	case SnakeCase:
+		return "snake_case"
+	case KebabCase:
+		return "kebab-case"
+	}
+	return string(c)
+}
+
+// splitWords splits a name at underscores, hyphens and changes of case:
+// "HTMLCardHeader" and "html_card-header" both give html, card, header
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i == len(runes) || runes[i] == '_' || runes[i] == '-' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i > start && unicode.IsUpper(runes[i]) &&
+			(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return words
+}
+
+// NamingError reports a name that does not follow the project's naming
+// conventions, with the name it should have
+type NamingError struct {
+	Kind    string     // "view", "module" or "slot"
+	Name    string     // Name as written
+	Want    string     // Name in the convention
+	Case    Case       // Convention of the rule
+	Where   lexer.Span // Span of the name; zero for a module, named by its file
+	Literal bool       // Whether Where covers a quoted string rather than an identifier
+}
+
+// Description returns the error without its position
+func (e *NamingError) Description() string {
+	return fmt.Sprintf("%s name '%s' is not %s", e.Kind, e.Name, e.Case.label())
+}
+
+// Error returns the error with its position
+func (e *NamingError) Error() string {
+	if e.Where.Start.Line == 0 {
+		return e.Description()
+	}
+	return fmt.Sprintf("%s (position %s)", e.Description(), e.Where)
+}
+
+// Span returns the span of the name
+func (e *NamingError) Span() lexer.Span {
+	return e.Where
+}
+
+// Diagnostic returns the structured form of the error, with the rename as a
+// suggested fix
+func (e *NamingError) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Error,
+		Code:     diagnostics.CodeNamingConvention,
+		Message:  e.Description(),
+		Span:     lexer.DiagnosticSpan(e.Where),
+	}
+	switch {
+	case e.Kind == "module":
+		d.Hints = append(d.Hints, fmt.Sprintf("rename the file to %s.psx", e.Want))
+	case e.Literal:
+		d.Suggestions = append(d.Suggestions, diagnostics.Suggestion{
+			Span:        d.Span,
+			Replacement: fmt.Sprintf("%q", e.Want),
+			Message:     fmt.Sprintf("rename it to '%s'", e.Want),
+		})
+	default:
+		d.Suggestions = append(d.Suggestions, diagnostics.Suggestion{
+			Span:        d.Span,
+			Replacement: e.Want,
+			Message:     fmt.Sprintf("rename it to '%s'", e.Want),
+		})
+	}
+	if e.Kind == "view" || e.Kind == "slot" {
+		d.Notes = append(d.Notes, fmt.Sprintf("the places that use this %s must be renamed too", e.Kind))
+	}
+	return d
+}
+
+// checkNaming reports the views, slots and module name of module that break
+// the naming conventions
+func (r *Resolver) checkNaming(module *ast.Module) {
+	if r.Naming.IsZero() {
+		return
+	}
+
+	if c := r.Naming.Modules; c != "" && r.SourceFilePath != "" {
+		name := strings.TrimSuffix(filepath.Base(r.SourceFilePath), filepath.Ext(r.SourceFilePath))
+		if !isDunder(name) && !c.Matches(name) {
+			r.ReportError(&NamingError{Kind: "module", Name: name, Want: c.Convert(name), Case: c})
+		}
+	}
+
+	for _, stmt := range module.Body {
+		view, ok := stmt.(*ast.ViewStmt)
+		if !ok {
+			continue
+		}
+		if c := r.Naming.Views; c != "" && view.Name != nil && !c.Matches(view.Name.Token.Lexeme) {
+			name := view.Name.Token.Lexeme
+			r.ReportError(&NamingError{Kind: "view", Name: name, Want: c.Convert(name), Case: c, Where: view.Name.Span})
+		}
+		if r.Naming.Slots != "" {
+			r.checkSlotNames(view.Body)
+		}
+	}
+}
+
+// checkSlotNames reports the literal slot names of <slot name="..."> elements
+// and slot="..." attributes in body that break the slot naming convention
+func (r *Resolver) checkSlotNames(body []ast.Stmt) {
+	c := r.Naming.Slots
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.HTMLElement:
+			for _, attr := range s.Attributes {
+				if attr.Name.Lexeme != "slot" && !(attr.Name.Lexeme == "name" && s.TagName.Lexeme == "slot") {
+					continue
+				}
+				literal, ok := attr.Value.(*ast.Literal)
+				if !ok {
+					continue
+				}
+				if name, ok := literal.Value.(string); ok && name != "" && !c.Matches(name) {
+					r.ReportError(&NamingError{Kind: "slot", Name: name, Want: c.Convert(name), Case: c, Where: literal.Span, Literal: true})
+				}
+			}
+			r.checkSlotNames(s.Content)
+		case *ast.For:
+			r.checkSlotNames(s.Body)
+			r.checkSlotNames(s.Else)
+		case *ast.If:
+			r.checkSlotNames(s.Body)
+			r.checkSlotNames(s.Else)
+		case *ast.While:
+			r.checkSlotNames(s.Body)
+			r.checkSlotNames(s.Else)
+		}
+	}
+}
+
+// isDunder reports whether name is a special name such as __init__
+func isDunder(name string) bool {
+	return len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+}
@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestCaseConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Case
+		matches bool
+		want    string
+	}{
+		{"CardHeader", PascalCase, true, "CardHeader"},
+		{"HTMLCardHeader", PascalCase, true, "HtmlCardHeader"},
+		{"card_header", PascalCase, false, "CardHeader"},
+		{"cardHeader", CamelCase, true, "cardHeader"},
+		{"card-header", CamelCase, false, "cardHeader"},
+		{"card_header", SnakeCase, true, "card_header"},
+		{"_private_card", SnakeCase, true, "_private_card"},
+		{"CardHeader2", SnakeCase, false, "card_header2"},
+		{"card-header", KebabCase, true, "card-header"},
+		{"cardHeader", KebabCase, false, "card-header"},
+		{"card_header", KebabCase, false, "card-header"},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Matches(tt.name); got != tt.matches {
+			t.Errorf("%s.Matches(%q) = %v, want %v", tt.c, tt.name, got, tt.matches)
+		}
+		if got := tt.c.Convert(tt.name); got != tt.want {
+			t.Errorf("%s.Convert(%q) = %q, want %q", tt.c, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseNamingConventions(t *testing.T) {
+	got, err := ParseNamingConventions("views=pascal, modules=snake,slots=kebab")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := NamingConventions{Views: PascalCase, Modules: SnakeCase, Slots: KebabCase}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, bad := range []string{"views", "views=title", "functions=snake"} {
+		if _, err := ParseNamingConventions(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestNamingConventions(t *testing.T) {
+	source := `
+view card_list(items):
+    <div>
+        <slot name="cardHeader" />
+        for item in items:
+            <slot name="item-body" />
+    </div>
+
+view Page():
+    <Layout>
+        <p slot="pageFooter">hi</p>
+    </Layout>
+
+def helper_function():
+    pass
+`
+	scanner := lexer.NewScanner([]byte(source))
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		t.Fatalf("Scanner errors: %v", scanner.Errors)
+	}
+	module, errs := parser.NewParser(tokens).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("Parser errors: %v", errs)
+	}
+
+	r := NewResolverWithDeps(nil, nil, "/project/CardList.psx")
+	r.Naming = NamingConventions{Views: PascalCase, Modules: SnakeCase, Slots: KebabCase}
+	table, _ := r.Resolve(module)
+
+	want := []string{
+		"module name 'CardList' is not snake_case",
+		"view name 'card_list' is not PascalCase",
+		"slot name 'cardHeader' is not kebab-case",
+		"slot name 'pageFooter' is not kebab-case",
+	}
+	var got []string
+	for _, err := range table.Errors {
+		d := diagnostics.From(err)
+		if d.Code != diagnostics.CodeNamingConvention {
+			t.Errorf("Expected code %s, got %s for %v", diagnostics.CodeNamingConvention, d.Code, err)
+		}
+		got = append(got, d.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Expected errors:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if hints := diagnostics.From(table.Errors[0]).Hints; len(hints) != 1 || hints[0] != "rename the file to card_list.psx" {
+		t.Errorf("module hints = %v", hints)
+	}
+	view := diagnostics.From(table.Errors[1])
+	if len(view.Suggestions) != 1 || view.Suggestions[0].Replacement != "CardList" || view.Suggestions[0].Span != view.Span {
+		t.Errorf("view suggestions = %+v", view.Suggestions)
+	}
+	slot := diagnostics.From(table.Errors[2])
+	if len(slot.Suggestions) != 1 || slot.Suggestions[0].Replacement != `"card-header"` {
+		t.Errorf("slot suggestions = %+v", slot.Suggestions)
+	}
+}
@@ -0,0 +1,188 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// checkPropTypes reports literal attribute values of a composition site that
+// do not match the annotation of the view parameter they are passed to. Only
+// annotations built from str, int, float, complex, bool and None, joined with
+// '|', Optional or Union, are checked; values that are expressions and
+// parameters with other annotations are left to Python.
+func (r *Resolver) checkPropTypes(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	if viewStmt.Params == nil {
+		return
+	}
+
+	annotations := make(map[string]ast.Expr)
+	for _, param := range viewStmt.Params.Parameters {
+		if param == nil || param.Name == nil || param.IsStar || param.IsDoubleStar || param.Annotation == nil {
+			continue
+		}
+		annotations[param.Name.Token.Lexeme] = param.Annotation
+	}
+
+	for _, attr := range h.Attributes {
+		annotation, ok := annotations[attr.Name.Lexeme]
+		if !ok {
+			continue
+		}
+		accepted, ok := literalTypes(annotation)
+		if !ok {
+			continue
+		}
+		got, ok := attributeType(attr)
+		if !ok || acceptsType(accepted, got) {
+			continue
+		}
+
+		r.ReportError(&Error{
+			Code: diagnostics.CodePropType,
+			Message: fmt.Sprintf("prop '%s' of %s expects %s, got %s",
+				attr.Name.Lexeme, viewStmt.Name.Token.Lexeme, describeTypes(accepted), got),
+			Span: attr.Span,
+		})
+	}
+}
+
+// attributeType returns the Python type of a literal attribute value. A
+// boolean attribute without a value passes True.
+func attributeType(attr ast.HTMLAttribute) (string, bool) {
+	if attr.Value == nil {
+		return "bool", true
+	}
+	return literalType(attr.Value)
+}
+
+// literalType returns the Python type of a literal expression. The type is
+// taken from the decoded value, since literals parsed inside {...} are not
+// tagged by kind.
+func literalType(expr ast.Expr) (string, bool) {
+	literal, ok := expr.(*ast.Literal)
+	if !ok {
+		return "", false
+	}
+
+	switch literal.Value.(type) {
+	case string:
+		if isBytes(literal.Token) {
+			return "bytes", true
+		}
+		return "str", true
+	case bool:
+		return "bool", true
+	case int64, int:
+		return "int", true
+	case float64:
+		return "float", true
+	case complex128:
+		return "complex", true
+	case nil:
+		if literal.Type == ast.LiteralTypeNone && literal.Token.Type != lexer.Ellipsis {
+			return "None", true
+		}
+	}
+	return "", false
+}
+
+// isBytes reports whether a string token has a b prefix, such as b"..." or rb"..."
+func isBytes(token lexer.Token) bool {
+	quote := strings.IndexAny(token.Lexeme, `'"`)
+	return quote > 0 && strings.ContainsAny(token.Lexeme[:quote], "bB")
+}
+
+// literalTypes returns the types an annotation accepts, or false when it
+// names a type the check does not know
+func literalTypes(annotation ast.Expr) (map[string]bool, bool) {
+	switch a := annotation.(type) {
+	case *ast.Name:
+		switch name := a.Token.Lexeme; name {
+		case "str", "int", "float", "complex", "bool":
+			return map[string]bool{name: true}, true
+		case "None":
+			return map[string]bool{"None": true}, true
+		}
+	case *ast.Literal:
+		if a.Type == ast.LiteralTypeNone {
+			return map[string]bool{"None": true}, true
+		}
+	case *ast.Binary:
+		if a.Operator.Type != lexer.Pipe {
+			return nil, false
+		}
+		return unionTypes([]ast.Expr{a.Left, a.Right})
+	case *ast.Subscript:
+		name, ok := a.Object.(*ast.Name)
+		if !ok {
+			return nil, false
+		}
+		members := a.Indices
+		if len(members) == 1 {
+			if tuple, ok := members[0].(*ast.TupleExpr); ok {
+				members = tuple.Elements
+			}
+		}
+		switch name.Token.Lexeme {
+		case "Optional":
+			if len(members) != 1 {
+				return nil, false
+			}
+			return unionTypes([]ast.Expr{members[0], &ast.Literal{Type: ast.LiteralTypeNone}})
+		case "Union":
+			return unionTypes(members)
+		}
+	}
+	return nil, false
+}
+
+// unionTypes returns the types accepted by any of members
+func unionTypes(members []ast.Expr) (map[string]bool, bool) {
+	accepted := make(map[string]bool)
+	for _, member := range members {
+		types, ok := literalTypes(member)
+		if !ok {
+			return nil, false
+		}
+		for name := range types {
+			accepted[name] = true
+		}
+	}
+	return accepted, true
+}
+
+// acceptsType reports whether a parameter accepting the given types takes a
+// value of type got. As in Python's numeric tower, an int is accepted where
+// a float or complex is expected and a float where a complex is; a bool is
+// not taken for a number.
+func acceptsType(accepted map[string]bool, got string) bool {
+	switch {
+	case accepted[got]:
+		return true
+	case got == "int":
+		return accepted["float"] || accepted["complex"]
+	case got == "float":
+		return accepted["complex"]
+	}
+	return false
+}
+
+// describeTypes formats accepted types as an annotation, such as "int | None"
+func describeTypes(accepted map[string]bool) string {
+	names := make([]string, 0, len(accepted))
+	for name := range accepted {
+		if name != "None" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if accepted["None"] {
+		names = append(names, "None")
+	}
+	return strings.Join(names, " | ")
+}
@@ -0,0 +1,111 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestStrictProps(t *testing.T) {
+	views := `
+view Counter(label: str, count: int, ratio: float = 1.0, open: bool = False, note: str | None = None, tag: Optional[str] = None, size: Union[int, str] = 1, extra = 0, style: Style = None):
+    <p>{label}</p>
+`
+
+	tests := []struct {
+		name string
+		site string
+		want []string // Messages of the errors, in order
+	}{
+		{
+			name: "matching literals",
+			site: `<Counter label="a" count={5} ratio={2} open note="n" tag={None} size="lg" />`,
+		},
+		{
+			name: "string for int",
+			site: `<Counter label="a" count="5" />`,
+			want: []string{"prop 'count' of Counter expects int, got str"},
+		},
+		{
+			name: "interpolated literals",
+			site: `<Counter label={1} count={2.5} />`,
+			want: []string{
+				"prop 'label' of Counter expects str, got int",
+				"prop 'count' of Counter expects int, got float",
+			},
+		},
+		{
+			name: "bool is not a number",
+			site: `<Counter label="a" count={True} />`,
+			want: []string{"prop 'count' of Counter expects int, got bool"},
+		},
+		{
+			name: "boolean attribute",
+			site: `<Counter label count={1} />`,
+			want: []string{"prop 'label' of Counter expects str, got bool"},
+		},
+		{
+			name: "optional and union",
+			site: `<Counter label="a" count={1} note={1} tag={False} size={1.5} />`,
+			want: []string{
+				"prop 'note' of Counter expects str | None, got int",
+				"prop 'tag' of Counter expects str | None, got bool",
+				"prop 'size' of Counter expects int | str, got float",
+			},
+		},
+		{
+			name: "unchecked values and annotations",
+			site: `<Counter label={name} count="{n}" extra="x" style="bold" />`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := views + "\nview Page(name: str, n: int):\n    " + tt.site + "\n"
+			scanner := lexer.NewScanner([]byte(source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			r := NewResolver()
+			r.StrictProps = true
+			table, _ := r.Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodePropType {
+					t.Errorf("Expected code %s, got %s for %v", diagnostics.CodePropType, d.Code, err)
+				}
+				if d.Span.Start.Line != 6 {
+					t.Errorf("Expected the error on the composition site, got line %d", d.Span.Start.Line)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestStrictPropsDisabled(t *testing.T) {
+	_, table := parseAndResolve(t, `
+view Counter(count: int):
+    <p>{count}</p>
+
+view Page():
+    <Counter count="5" />
+`)
+	if len(table.Errors) != 0 {
+		t.Errorf("Expected no errors without StrictProps, got %v", table.Errors)
+	}
+}
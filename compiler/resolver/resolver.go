@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
@@ -33,14 +34,37 @@ type Resolver struct {
 	// View composition support
 	Views        map[string]*ast.ViewStmt           // View name → ViewStmt mapping
 	ViewElements map[*ast.HTMLElement]*ast.ViewStmt // HTMLElement → ViewStmt mapping
+	Layouts      map[*ast.ViewStmt]*ast.ViewStmt    // View → layout named by its 'use layout'
 
 	// Import resolution support
 	ModuleResolver *module.StandardResolver // Resolves import paths to file paths
 	SymbolRegistry *symbol.Registry         // Cross-file symbol registry
 	SourceFilePath string                   // Current source file being resolved
 
+	// Names each 'from x import *' brought into scope, sorted
+	WildcardImports map[*ast.ImportFromStmt][]string
+
+	// StrictProps checks literal attribute values at composition sites
+	// against the annotations of the view's parameters
+	StrictProps bool
+
+	// Naming enforces naming conventions on the views, slots and module
+	// name of the file
+	Naming NamingConventions
+
 	// Error tracking
-	Errors []error
+	Errors   []error
+	Warnings []error // Diagnostics that do not fail resolution
+
+	// Same-file views marked @deprecated, with the marker's message
+	deprecatedViews map[*ast.ViewStmt]string
+
+	// Whether the module binds the name deprecated, so its @deprecated
+	// decorators are not the marker
+	bindsDeprecated bool
+
+	// Cancellation of ResolveContext, checked before each module statement
+	ctx context.Context
 
 	// Context tracking - use counters for nested scopes
 	FunctionScopeDepth int // How many function scopes deep we are
@@ -48,6 +72,7 @@ type Resolver struct {
 	ViewScopeDepth     int // How many view scopes deep we are
 	CurrentFunction    *ast.Function
 	CurrentView        *ast.ViewStmt
+	inAsync            bool // Whether the innermost function or view is async
 
 	// Embed visitor interface
 	ast.Visitor
@@ -65,21 +90,24 @@ func NewResolver() *Resolver {
 //   - sourceFilePath: path to the current source file being resolved (optional, can be empty)
 func NewResolverWithDeps(moduleResolver *module.StandardResolver, symbolRegistry *symbol.Registry, sourceFilePath string) *Resolver {
 	resolver := &Resolver{
-		AllScopes:      make(map[int]*Scope),
-		NextScopeID:    0,
-		ModuleGlobals:  make(map[string]*Variable),
-		Variables:      make(map[*ast.Name]*Variable),
-		ScopeDepths:    make(map[*ast.Name]int),
-		NameToBinding:  make(map[*ast.Name]*Binding),
-		NodeScopes:     make(map[ast.Node]*Scope),
-		CellVars:       make(map[string]bool),
-		FreeVars:       make(map[string]bool),
-		Errors:         []error{},
-		Views:          make(map[string]*ast.ViewStmt),
-		ViewElements:   make(map[*ast.HTMLElement]*ast.ViewStmt),
-		ModuleResolver: moduleResolver,
-		SymbolRegistry: symbolRegistry,
-		SourceFilePath: sourceFilePath,
+		AllScopes:       make(map[int]*Scope),
+		NextScopeID:     0,
+		ModuleGlobals:   make(map[string]*Variable),
+		Variables:       make(map[*ast.Name]*Variable),
+		ScopeDepths:     make(map[*ast.Name]int),
+		NameToBinding:   make(map[*ast.Name]*Binding),
+		NodeScopes:      make(map[ast.Node]*Scope),
+		CellVars:        make(map[string]bool),
+		FreeVars:        make(map[string]bool),
+		Errors:          []error{},
+		Views:           make(map[string]*ast.ViewStmt),
+		deprecatedViews: make(map[*ast.ViewStmt]string),
+		ViewElements:    make(map[*ast.HTMLElement]*ast.ViewStmt),
+		Layouts:         make(map[*ast.ViewStmt]*ast.ViewStmt),
+		WildcardImports: make(map[*ast.ImportFromStmt][]string),
+		ModuleResolver:  moduleResolver,
+		SymbolRegistry:  symbolRegistry,
+		SourceFilePath:  sourceFilePath,
 	}
 
 	// Begin with module scope
@@ -88,8 +116,27 @@ func NewResolverWithDeps(moduleResolver *module.StandardResolver, symbolRegistry
 	return resolver
 }
 
+// ResolveContext resolves like Resolve but stops before the next module-level
+// statement once ctx is cancelled, returning ctx's error. Like Resolve, it
+// returns the table with its Errors when resolution fails.
+func (r *Resolver) ResolveContext(ctx context.Context, module *ast.Module) (*ResolutionTable, error) {
+	r.ctx = ctx
+	defer func() { r.ctx = nil }()
+
+	table, err := r.Resolve(module)
+	if err != nil {
+		return table, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
 // Resolve performs variable resolution on the given module
 func (r *Resolver) Resolve(module *ast.Module) (*ResolutionTable, error) {
+	r.checkNaming(module)
+
 	// Visit the module to perform resolution
 	module.Accept(r)
 
@@ -104,8 +151,12 @@ func (r *Resolver) Resolve(module *ast.Module) (*ResolutionTable, error) {
 		CellVars:       r.CellVars,
 		FreeVars:       r.FreeVars,
 		Errors:         r.Errors,
+		Warnings:       r.Warnings,
 		Views:          r.Views,
 		ViewElements:   r.ViewElements,
+		Layouts:        r.Layouts,
+
+		WildcardImports: r.WildcardImports,
 	}
 
 	// Extract view parameters
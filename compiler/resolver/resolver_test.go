@@ -2,6 +2,7 @@ package resolver
 
 import (
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"testing"
 )
@@ -863,7 +864,7 @@ func TestErrorReporting(t *testing.T) {
 						Span:  lexer.Span{},
 					},
 				},
-				Span: lexer.Span{},
+				Span: lexer.Span{Start: lexer.Position{Line: 3, Column: 1}, End: lexer.Position{Line: 3, Column: 9}},
 			},
 		},
 		Span: lexer.Span{},
@@ -882,6 +883,87 @@ func TestErrorReporting(t *testing.T) {
 	}
 
 	if len(table.Errors) == 0 {
-		t.Error("Should have errors in resolution table")
+		t.Fatal("Should have errors in resolution table")
+	}
+
+	d := diagnostics.From(table.Errors[0])
+	if d.Code != diagnostics.CodeScopeDeclaration {
+		t.Errorf("Expected code %s, got %s", diagnostics.CodeScopeDeclaration, d.Code)
+	}
+	if d.Span.Start.Line != 3 || d.Span.Start.Column != 1 {
+		t.Errorf("Expected error at 3:1, got %d:%d", d.Span.Start.Line, d.Span.Start.Column)
+	}
+}
+
+func TestAssignmentExpressionBinding(t *testing.T) {
+	_, table := parseAndResolve(t, `
+def f(items):
+    if (n := len(items)) > 1:
+        return n
+`)
+	names := map[string]int{}
+	for name, variable := range table.Variables {
+		lexeme := name.Token.Lexeme
+		if lexeme != "n" && lexeme != "items" {
+			continue
+		}
+		names[lexeme]++
+		if variable.State == VariableUndefined || variable.DefinitionDepth != 1 {
+			t.Errorf("Expected %q to be bound in the function, got %+v", lexeme, variable)
+		}
+	}
+	if names["n"] != 2 {
+		t.Errorf("Expected the walrus target and its use to resolve, got %d references to n", names["n"])
+	}
+	// The parameter and its use in the walrus value
+	if names["items"] != 2 {
+		t.Errorf("Expected the walrus value to be resolved, got %d references to items", names["items"])
+	}
+}
+
+// Parameter defaults are resolved where the function or lambda is defined,
+// and lambda parameters are local to the lambda's body
+func TestParameterDefaultScoping(t *testing.T) {
+	// view Card(items):
+	//     def first(items=items): return items
+	//     double = lambda items=items: items
+	viewParam := ast.HParam("items")
+	functionParam := ast.HParamWithDefault("items", "list", ast.N("items"))
+	functionBody := ast.N("items")
+	lambdaParam := ast.HParamWithDefault("items", "list", ast.N("items"))
+	lambdaBody := ast.N("items")
+	view := ast.HView("Card", []*ast.Parameter{viewParam},
+		ast.HFunction("first", []*ast.Parameter{functionParam}, []ast.Stmt{ast.HReturn(functionBody)}, nil),
+		ast.HAssign([]ast.Expr{ast.N("double")}, ast.HLambda([]*ast.Parameter{lambdaParam}, lambdaBody)),
+	)
+
+	table, err := NewResolver().Resolve(ast.HModule(view))
+	if err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+
+	viewVariable := table.Variables[viewParam.Name]
+	tests := []struct {
+		name string
+		node *ast.Name
+		want *Variable
+	}{
+		{"function default", functionParam.Default.(*ast.Name), viewVariable},
+		{"function body", functionBody, table.Variables[functionParam.Name]},
+		{"lambda default", lambdaParam.Default.(*ast.Name), viewVariable},
+		{"lambda body", lambdaBody, table.Variables[lambdaParam.Name]},
+	}
+	for _, tt := range tests {
+		binding, ok := table.NameToBinding[tt.node]
+		if !ok {
+			t.Errorf("%s: not resolved", tt.name)
+			continue
+		}
+		if binding.Variable != tt.want {
+			t.Errorf("%s: resolved to the wrong parameter", tt.name)
+		}
+	}
+	if table.Variables[lambdaParam.Name] == viewVariable || table.Variables[functionParam.Name] == viewVariable {
+		t.Errorf("expected the function and lambda parameters to be new variables")
 	}
 }
@@ -0,0 +1,401 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+// checkSlotTargets reports content of a view composition that fills a slot
+// the element's attributes already pass. Slot content becomes a keyword
+// argument named after its slot, and default content the children argument,
+// so both would set the same parameter. Children with a dynamic slot name are
+// not checked.
+func (r *Resolver) checkSlotTargets(h *ast.HTMLElement) {
+	attributes := make(map[string]bool, len(h.Attributes))
+	for _, attr := range h.Attributes {
+		attributes[attr.Name.Lexeme] = true
+	}
+
+	reported := make(map[string]bool)
+	for _, stmt := range h.Content {
+		target, span, ok := slotTarget(stmt)
+		if !ok || !attributes[target] || reported[target] {
+			continue
+		}
+		reported[target] = true
+
+		message := fmt.Sprintf("slot '%s' of <%s> is filled by both an attribute and child content", target, h.TagName.Lexeme)
+		if target == "children" {
+			message = fmt.Sprintf("<%s> is passed children both as an attribute and as content", h.TagName.Lexeme)
+		}
+		r.ReportError(&Error{Code: diagnostics.CodeDuplicateSlotTarget, Message: message, Span: span})
+	}
+}
+
+// slotTarget returns the parameter a direct child of a view composition is
+// passed as, and the span to report it at
+func slotTarget(stmt ast.Stmt) (string, lexer.Span, bool) {
+	if _, ok := stmt.(*ast.HTMLComment); ok {
+		return "", lexer.Span{}, false
+	}
+	element, ok := stmt.(*ast.HTMLElement)
+	if !ok {
+		return "children", stmt.GetSpan(), true
+	}
+	for _, attr := range element.Attributes {
+		if attr.Name.Lexeme != "slot" {
+			continue
+		}
+		literal, ok := attr.Value.(*ast.Literal)
+		if !ok {
+			return "", lexer.Span{}, false
+		}
+		name, ok := literal.Value.(string)
+		if !ok {
+			return "", lexer.Span{}, false
+		}
+		if name == "" {
+			return "children", attr.Span, true
+		}
+		return name, attr.Span, true
+	}
+	return "children", element.Span, true
+}
+
+// SlotContentWarning reports a slot of a view composition filled by an
+// attribute whose value cannot be rendered as content, such as a number or an
+// uncalled function. It does not fail compilation.
+type SlotContentWarning struct {
+	Slot  string     // Parameter the value is passed as
+	Tag   string     // Tag name of the composition
+	Got   string     // Description of the value, such as "int"
+	Hint  string     // How to pass content instead
+	Where lexer.Span // Span of the attribute
+}
+
+// Description returns the warning without its position
+func (w *SlotContentWarning) Description() string {
+	if w.Slot == "children" {
+		return fmt.Sprintf("children of <%s> should be markup, a string, a view or a list of them, got %s", w.Tag, w.Got)
+	}
+	return fmt.Sprintf("slot '%s' of <%s> should be markup, a string, a view or a list of them, got %s", w.Slot, w.Tag, w.Got)
+}
+
+// Error returns the warning with its position
+func (w *SlotContentWarning) Error() string {
+	return fmt.Sprintf("%s (position %s)", w.Description(), w.Where)
+}
+
+// Span returns the span of the attribute
+func (w *SlotContentWarning) Span() lexer.Span {
+	return w.Where
+}
+
+// Diagnostic returns the structured form of the warning
+func (w *SlotContentWarning) Diagnostic() *diagnostics.Diagnostic {
+	d := &diagnostics.Diagnostic{
+		Severity: diagnostics.Warning,
+		Code:     diagnostics.CodeSlotContent,
+		Message:  w.Description(),
+		Span:     lexer.DiagnosticSpan(w.Where),
+	}
+	if w.Hint != "" {
+		d.Hints = append(d.Hints, w.Hint)
+	}
+	return d
+}
+
+// checkSlotContent warns about attributes of a view composition that fill a
+// slot with a value the static type of which is not content. Markup, strings,
+// views and lists of them are content; only literals, displays, lambdas and
+// names the symbol registry knows as functions are checked, other
+// expressions are left to the runtime.
+func (r *Resolver) checkSlotContent(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	slots := viewSlots(viewStmt)
+	for _, attr := range h.Attributes {
+		if attr.Spread || !slots[attr.Name.Lexeme] {
+			continue
+		}
+		var got, hint string
+		var ok bool
+		if attr.Value == nil {
+			got, hint, ok = "bool", "pass text as a string", true
+		} else {
+			got, hint, ok = r.nonContentType(attr.Value)
+		}
+		if !ok {
+			continue
+		}
+		r.Warnings = append(r.Warnings, &SlotContentWarning{
+			Slot:  attr.Name.Lexeme,
+			Tag:   h.TagName.Lexeme,
+			Got:   got,
+			Hint:  hint,
+			Where: attr.Span,
+		})
+	}
+}
+
+// nonContentType describes a value that is known not to be content, with a
+// hint on how to pass content instead
+func (r *Resolver) nonContentType(expr ast.Expr) (string, string, bool) {
+	switch e := expr.(type) {
+	case *ast.GroupExpr:
+		return r.nonContentType(e.Expression)
+	case *ast.Literal:
+		got, ok := literalType(e)
+		if !ok || got == "str" || got == "None" {
+			return "", "", false
+		}
+		if got == "bytes" {
+			return got, "decode it to a string", true
+		}
+		return got, "pass text as a string, such as f\"{value}\"", true
+	case *ast.ListExpr:
+		return r.nonContentElements("list", e.Elements)
+	case *ast.TupleExpr:
+		return r.nonContentElements("tuple", e.Elements)
+	case *ast.DictExpr, *ast.DictComp:
+		return "dict", "spread it into attributes with {...mapping}", true
+	case *ast.SetExpr, *ast.SetComp:
+		return "set", "pass a list to keep the order of the content", true
+	case *ast.Lambda:
+		return "function", "call it to pass what it returns", true
+	case *ast.Name:
+		name := e.Token.Lexeme
+		if sym := r.moduleSymbol(name); sym != nil && sym.Type == symbol.SymbolFunction {
+			return fmt.Sprintf("function '%s'", name), fmt.Sprintf("call it to pass what it returns: %s()", name), true
+		}
+	}
+	return "", "", false
+}
+
+// nonContentElements describes a list or tuple holding a value that is not
+// content
+func (r *Resolver) nonContentElements(kind string, elements []ast.Expr) (string, string, bool) {
+	for _, element := range elements {
+		if got, hint, ok := r.nonContentType(element); ok {
+			return fmt.Sprintf("%s containing %s", kind, got), hint, true
+		}
+	}
+	return "", "", false
+}
+
+// moduleSymbol returns the registry's symbol for a module-level name that no
+// enclosing scope shadows: the imported symbol for an imported name, or the
+// symbol this file defines otherwise
+func (r *Resolver) moduleSymbol(name string) *symbol.Symbol {
+	if r.SymbolRegistry == nil {
+		return nil
+	}
+	for scope := r.ScopeChain; scope != nil && scope.Parent != nil; scope = scope.Parent {
+		if _, shadowed := scope.Bindings[name]; shadowed {
+			return nil
+		}
+	}
+
+	filePath := r.SourceFilePath
+	if variable, ok := r.ModuleGlobals[name]; ok && variable.IsImported {
+		filePath = variable.ImportSource
+	}
+	if filePath == "" {
+		return nil
+	}
+	sym, err := r.SymbolRegistry.LookupSymbol(filePath, name)
+	if err != nil {
+		return nil
+	}
+	return sym
+}
+
+// viewSlots returns the parameters of a view that take content: one per
+// <slot> element of its body, children for the default slot, and a children
+// parameter it declares. Parameters with an annotation are checked against
+// it instead.
+func viewSlots(viewStmt *ast.ViewStmt) map[string]bool {
+	slots := make(map[string]bool)
+	annotated := make(map[string]bool)
+	if viewStmt.Params != nil {
+		for _, param := range viewStmt.Params.Parameters {
+			if param == nil || param.Name == nil || param.IsStar || param.IsDoubleStar {
+				continue
+			}
+			if param.Annotation != nil {
+				annotated[param.Name.Token.Lexeme] = true
+			} else if param.Name.Token.Lexeme == "children" {
+				slots["children"] = true
+			}
+		}
+	}
+
+	var walk func(body []ast.Stmt)
+	walk = func(body []ast.Stmt) {
+		for _, stmt := range body {
+			switch s := stmt.(type) {
+			case *ast.HTMLElement:
+				if s.TagName.Lexeme == "slot" {
+					if name, ok := slotParameter(s); ok {
+						slots[name] = true
+					}
+					walk(s.Content)
+				} else {
+					walk(s.Content)
+				}
+			case *ast.For:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.If:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.While:
+				walk(s.Body)
+				walk(s.Else)
+			}
+		}
+	}
+	walk(viewStmt.Body)
+
+	for name := range annotated {
+		delete(slots, name)
+	}
+	return slots
+}
+
+// slotParameter returns the parameter a <slot> element is filled by; false
+// for a slot named by an expression, which is filled by whichever slot it
+// names when the view renders
+func slotParameter(slot *ast.HTMLElement) (string, bool) {
+	for _, attr := range slot.Attributes {
+		if attr.Name.Lexeme != "name" || attr.Value == nil {
+			continue
+		}
+		literal, ok := attr.Value.(*ast.Literal)
+		if !ok {
+			return "", false
+		}
+		if name, ok := literal.Value.(string); ok && name != "" {
+			return name, true
+		}
+	}
+	return "children", true
+}
+
+// hasComputedSlot reports whether a view has a <slot> named by an
+// expression, which makes the view take the content of any slot
+func hasComputedSlot(viewStmt *ast.ViewStmt) bool {
+	var walk func(body []ast.Stmt) bool
+	walk = func(body []ast.Stmt) bool {
+		for _, stmt := range body {
+			switch s := stmt.(type) {
+			case *ast.HTMLElement:
+				if s.TagName.Lexeme == "slot" {
+					if _, ok := slotParameter(s); !ok {
+						return true
+					}
+				}
+				if walk(s.Content) {
+					return true
+				}
+			case *ast.For:
+				if walk(s.Body) || walk(s.Else) {
+					return true
+				}
+			case *ast.If:
+				if walk(s.Body) || walk(s.Else) {
+					return true
+				}
+			case *ast.While:
+				if walk(s.Body) || walk(s.Else) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(viewStmt.Body)
+}
+
+// checkScopedSlots reports content of a view composition whose let attribute
+// names a value the slot it fills does not pass. Slots the view renders in
+// several places pass the values of any of them; content for a slot the view
+// does not render, or names at render time, is not checked.
+func (r *Resolver) checkScopedSlots(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	for _, stmt := range h.Content {
+		element, ok := stmt.(*ast.HTMLElement)
+		if !ok {
+			continue
+		}
+		params, ok := element.SlotParams()
+		if !ok {
+			continue
+		}
+		target, _, ok := slotTarget(stmt)
+		if !ok {
+			continue
+		}
+		values, ok := slotValues(viewStmt, target)
+		if !ok {
+			continue
+		}
+		for _, param := range params {
+			name := param.Token.Lexeme
+			if values[name] {
+				continue
+			}
+			message := fmt.Sprintf("slot '%s' of <%s> does not pass '%s'", target, h.TagName.Lexeme, name)
+			if target == "children" {
+				message = fmt.Sprintf("the default slot of <%s> does not pass '%s'", h.TagName.Lexeme, name)
+			}
+			r.ReportError(&Error{Code: diagnostics.CodeScopedSlot, Message: message, Span: param.Span})
+		}
+	}
+}
+
+// slotValues returns the names of the values the <slot> elements of a view
+// filled by the given parameter pass to scoped content, as in
+// <slot name="row" item={item} />; false when the view renders no such slot
+// or has a slot named by an expression, which may be any of them
+func slotValues(viewStmt *ast.ViewStmt, parameter string) (map[string]bool, bool) {
+	values := make(map[string]bool)
+	found, computed := false, false
+	var walk func(body []ast.Stmt)
+	walk = func(body []ast.Stmt) {
+		for _, stmt := range body {
+			switch s := stmt.(type) {
+			case *ast.HTMLElement:
+				if s.TagName.Lexeme == "slot" && s.TagExpr == nil {
+					name, ok := slotParameter(s)
+					if !ok {
+						computed = true
+					} else if name == parameter {
+						found = true
+						for _, attr := range s.Attributes {
+							if attr.Spread {
+								// A spread may pass anything
+								computed = true
+							} else if attr.Name.Lexeme != "name" && attr.Name.Lexeme != "slot" {
+								values[attr.Name.Lexeme] = true
+							}
+						}
+					}
+				}
+				walk(s.Content)
+			case *ast.For:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.If:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.While:
+				walk(s.Body)
+				walk(s.Else)
+			}
+		}
+	}
+	walk(viewStmt.Body)
+	return values, found && !computed
+}
@@ -0,0 +1,263 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+func TestDuplicateSlotTargets(t *testing.T) {
+	tests := []struct {
+		name string
+		site string
+		want []string // Messages of the errors, in order
+	}{
+		{
+			name: "attribute and slot content",
+			site: "<Card header=\"h\">\n        <h1 slot=\"header\">Head</h1>\n    </Card>",
+			want: []string{"slot 'header' of <Card> is filled by both an attribute and child content"},
+		},
+		{
+			name: "children attribute and content",
+			site: "<Card children=\"c\">\n        Body\n    </Card>",
+			want: []string{"<Card> is passed children both as an attribute and as content"},
+		},
+		{
+			name: "reported once per slot",
+			site: "<Card header=\"h\">\n        <h1 slot=\"header\">Head</h1>\n        <p slot=\"header\">More</p>\n    </Card>",
+			want: []string{"slot 'header' of <Card> is filled by both an attribute and child content"},
+		},
+		{
+			name: "different slots",
+			site: "<Card header=\"h\">\n        <p>Body</p>\n        <footer slot=\"footer\">Foot</footer>\n    </Card>",
+		},
+		{
+			name: "dynamic slot name",
+			site: "<Card header=\"h\">\n        <h1 slot={name}>Head</h1>\n    </Card>",
+		},
+		{
+			name: "comments are not content",
+			site: "<Card children=\"c\">\n        <!-- note -->\n    </Card>",
+		},
+		{
+			name: "HTML elements are not checked",
+			site: "<div header=\"h\">\n        <h1 slot=\"header\">Head</h1>\n    </div>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "view Card(header=None, footer=None, children=None):\n    <div>{children}</div>\n\nview Page(name):\n    " + tt.site + "\n"
+			config := lexer.DefaultScannerConfig()
+			config.PreserveHTMLComments = true
+			scanner := lexer.NewScannerWithConfig([]byte(source), config)
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			table, _ := NewResolver().Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodeDuplicateSlotTarget {
+					t.Errorf("Expected code %s, got %s for %v", diagnostics.CodeDuplicateSlotTarget, d.Code, err)
+				}
+				if d.Span.Start.Line != 6 {
+					t.Errorf("Expected the error on the first conflicting child, got line %d", d.Span.Start.Line)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestSlotContentTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		site string
+		want []string // Messages of the warnings, in order
+	}{
+		{
+			name: "integer in named slot",
+			site: "<Card header={42} />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got int"},
+		},
+		{
+			name: "float children",
+			site: "<Card children={1.5} />",
+			want: []string{"children of <Card> should be markup, a string, a view or a list of them, got float"},
+		},
+		{
+			name: "boolean attribute",
+			site: "<Card header />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got bool"},
+		},
+		{
+			name: "list with a number",
+			site: "<Card header={[\"a\", 2]} />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got list containing int"},
+		},
+		{
+			name: "dict",
+			site: "<Card header={{\"a\": 1}} />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got dict"},
+		},
+		{
+			name: "lambda",
+			site: "<Card header={lambda: \"h\"} />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got function"},
+		},
+		{
+			name: "uncalled function",
+			site: "<Card header={helper} />",
+			want: []string{"slot 'header' of <Card> should be markup, a string, a view or a list of them, got function 'helper'"},
+		},
+		{
+			name: "content",
+			site: "<Card header=\"h\" children={[Badge(), \"text\", Badge]} footer={None} />",
+		},
+		{
+			name: "called function and names",
+			site: "<Card header={helper()} children={name} />",
+		},
+		{
+			name: "local shadows module function",
+			site: "<Card header={title} />",
+		},
+		{
+			name: "annotated parameter",
+			site: "<Card count={3} />",
+		},
+		{
+			name: "not a slot",
+			site: "<Card label={3} />",
+		},
+		{
+			name: "HTML elements are not checked",
+			site: "<div header={3}></div>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "def helper():\n    return \"h\"\n\ndef title():\n    return \"t\"\n\n" +
+				"view Badge():\n    <b>new</b>\n\n" +
+				"view Card(count: int = 0, label=None, children=None):\n    <div>\n        <slot name=\"header\" />\n        {children}\n        <slot name=\"footer\" />\n    </div>\n\n" +
+				"view Page(name):\n    title = \"Home\"\n    " + tt.site + "\n"
+			scanner := lexer.NewScanner([]byte(source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			registry := symbol.NewRegistry()
+			registry.RegisterModule("/project/page.psx", symbol.NewCollector("/project/page.psx").CollectFromModule(module))
+			table, err := NewResolverWithDeps(nil, registry, "/project/page.psx").Resolve(module)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+
+			var got []string
+			for _, warning := range table.Warnings {
+				d := diagnostics.From(warning)
+				if d.Code != diagnostics.CodeSlotContent || d.Severity != diagnostics.Warning {
+					t.Errorf("Expected a %s warning, got %s %s for %v", diagnostics.CodeSlotContent, d.Severity, d.Code, warning)
+				}
+				if len(d.Hints) != 1 {
+					t.Errorf("Expected one hint, got %v", d.Hints)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected warnings:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestScopedSlotValues(t *testing.T) {
+	tests := []struct {
+		name string
+		site string
+		want []string // Messages of the errors, in order
+	}{
+		{
+			name: "values the slot passes",
+			site: "<Table rows={rows}>\n        <tr slot=\"row\" let={(row, index)}>{row}</tr>\n    </Table>",
+		},
+		{
+			name: "value the slot does not pass",
+			site: "<Table rows={rows}>\n        <tr slot=\"row\" let={(row, position)}>{row}</tr>\n    </Table>",
+			want: []string{"slot 'row' of <Table> does not pass 'position'"},
+		},
+		{
+			name: "template",
+			site: "<Table rows={rows}>\n        <template slot=\"row\" let={cell}><td>{cell}</td></template>\n    </Table>",
+			want: []string{"slot 'row' of <Table> does not pass 'cell'"},
+		},
+		{
+			name: "default slot",
+			site: "<Table rows={rows}>\n        <p let={total}>{total}</p>\n    </Table>",
+			want: []string{"the default slot of <Table> does not pass 'total'"},
+		},
+		{
+			name: "spread passes anything",
+			site: "<Table rows={rows}>\n        <p slot=\"footer\" let={total}>{total}</p>\n    </Table>",
+		},
+		{
+			name: "slot not rendered",
+			site: "<Table rows={rows}>\n        <p slot=\"caption\" let={text}>{text}</p>\n    </Table>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "view Table(rows, caption=None):\n    <table>\n        for i, row in enumerate(rows):\n" +
+				"            <slot name=\"row\" row={row} index={i} />\n        <slot />\n        <slot name=\"footer\" {...totals(rows)} />\n    </table>\n\n" +
+				"view Page(rows):\n    " + tt.site + "\n"
+			scanner := lexer.NewScanner([]byte(source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			table, _ := NewResolver().Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodeScopedSlot {
+					t.Errorf("Expected code %s, got %s for %v", diagnostics.CodeScopedSlot, d.Code, err)
+				}
+				if d.Span.Start.Line != 11 {
+					t.Errorf("Expected the error on the let attribute, got line %d", d.Span.Start.Line)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
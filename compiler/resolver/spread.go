@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+// checkSpreads reports {...mapping} attribute spreads that cannot pass
+// attributes to h: values that are literals of another kind than a mapping,
+// and literal keys that are not attribute names for an HTML element, or not
+// parameters of the view h composes. The branches of a conditional spread
+// are checked separately; other expressions are left to Python.
+func (r *Resolver) checkSpreads(h *ast.HTMLElement, viewStmt *ast.ViewStmt) {
+	var params map[string]bool
+	if viewStmt != nil {
+		params = viewParameters(viewStmt)
+	}
+
+	for _, attr := range h.Attributes {
+		if !attr.Spread || attr.Value == nil {
+			continue
+		}
+		for _, value := range spreadBranches(attr.Value) {
+			dict, ok := value.(*ast.DictExpr)
+			if !ok {
+				if kind, literal := literalKind(value); literal {
+					r.ReportError(&Error{
+						Code:    diagnostics.CodeAttributeSpread,
+						Message: fmt.Sprintf("cannot spread %s as attributes of <%s>; expected a mapping", kind, h.TagName.Lexeme),
+						Span:    value.GetSpan(),
+					})
+				}
+				continue
+			}
+
+			for _, pair := range dict.Pairs {
+				kv, ok := pair.(*ast.KeyValuePair)
+				if !ok {
+					continue
+				}
+				key, ok := kv.Key.(*ast.Literal)
+				if !ok {
+					continue
+				}
+				name, ok := key.Value.(string)
+				if !ok {
+					r.ReportError(&Error{
+						Code:    diagnostics.CodeAttributeSpread,
+						Message: fmt.Sprintf("attribute names spread on <%s> must be strings", h.TagName.Lexeme),
+						Span:    key.Span,
+					})
+					continue
+				}
+
+				switch {
+				case viewStmt == nil && !isAttributeName(name):
+					r.ReportError(&Error{
+						Code:    diagnostics.CodeAttributeSpread,
+						Message: fmt.Sprintf("'%s' is not a valid attribute name for <%s>", name, h.TagName.Lexeme),
+						Span:    key.Span,
+					})
+				case params != nil && !params[name]:
+					r.ReportError(&Error{
+						Code:    diagnostics.CodeAttributeSpread,
+						Message: fmt.Sprintf("spread passes '%s', which is not a parameter of %s", name, viewStmt.Name.Token.Lexeme),
+						Span:    key.Span,
+					})
+				}
+			}
+		}
+	}
+}
+
+// viewParameters returns the names a view can be passed as keyword
+// arguments, or nil when it takes **kwargs and so accepts any name
+func viewParameters(viewStmt *ast.ViewStmt) map[string]bool {
+	params := make(map[string]bool)
+	if viewStmt.Params == nil {
+		return params
+	}
+	for _, param := range viewStmt.Params.Parameters {
+		if param == nil || param.Name == nil || param.IsStar {
+			continue
+		}
+		if param.IsDoubleStar {
+			return nil
+		}
+		params[param.Name.Token.Lexeme] = true
+	}
+	return params
+}
+
+// spreadBranches returns the values a spread can take: both branches of a
+// conditional expression, looking through parentheses
+func spreadBranches(value ast.Expr) []ast.Expr {
+	switch v := value.(type) {
+	case *ast.GroupExpr:
+		return spreadBranches(v.Expression)
+	case *ast.TernaryExpr:
+		return append(spreadBranches(v.TrueExpr), spreadBranches(v.FalseExpr)...)
+	}
+	return []ast.Expr{value}
+}
+
+// literalKind names the kind of a literal that is not a mapping
+func literalKind(value ast.Expr) (string, bool) {
+	switch v := value.(type) {
+	case *ast.ListExpr, *ast.ListComp:
+		return "a list", true
+	case *ast.TupleExpr:
+		return "a tuple", true
+	case *ast.SetExpr, *ast.SetComp:
+		return "a set", true
+	case *ast.FString, *ast.ConcatenatedString:
+		return "a string", true
+	case *ast.Literal:
+		switch v.Value.(type) {
+		case string:
+			return "a string", true
+		case bool:
+			return "a boolean", true
+		case nil:
+			return "None", true
+		}
+		return "a number", true
+	}
+	return "", false
+}
+
+// isAttributeName reports whether name can be written as an HTML attribute:
+// it must be non-empty and free of whitespace, control characters, quotes,
+// '>', '/' and '='
+func isAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"'>/=`, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/parser"
+)
+
+func TestAttributeSpreads(t *testing.T) {
+	tests := []struct {
+		name string
+		site string
+		want []string // Messages of the errors, in order
+	}{
+		{
+			name: "dynamic mapping",
+			site: `<div {...attrs}></div>`,
+		},
+		{
+			name: "literal attributes",
+			site: `<div {...{"data-id": 1, "aria-label": "x"} if ok}></div>`,
+		},
+		{
+			name: "invalid attribute name",
+			site: `<div {...{"data id": 1}}></div>`,
+			want: []string{"'data id' is not a valid attribute name for <div>"},
+		},
+		{
+			name: "non-string key",
+			site: `<div {...{1: "a"}}></div>`,
+			want: []string{"attribute names spread on <div> must be strings"},
+		},
+		{
+			name: "not a mapping",
+			site: `<div {...["a"]} {..."b"}></div>`,
+			want: []string{
+				"cannot spread a list as attributes of <div>; expected a mapping",
+				"cannot spread a string as attributes of <div>; expected a mapping",
+			},
+		},
+		{
+			name: "both branches are checked",
+			site: `<div {...({"id": "a"} if ok else (1, 2))}></div>`,
+			want: []string{"cannot spread a tuple as attributes of <div>; expected a mapping"},
+		},
+		{
+			name: "view parameters",
+			site: `<Card {...{"title": "a"} if ok else {"footer": "b"}} />`,
+		},
+		{
+			name: "unknown view parameter",
+			site: `<Card {...{"title": "a", "size": 2}} />`,
+			want: []string{"spread passes 'size', which is not a parameter of Card"},
+		},
+		{
+			name: "view taking keyword arguments",
+			site: `<Panel {...{"size": 2}} />`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "view Card(title=None, footer=None):\n    <div>{title}</div>\n\n" +
+				"view Panel(**props):\n    <div>{props}</div>\n\n" +
+				"view Page(attrs, ok):\n    " + tt.site + "\n"
+			scanner := lexer.NewScanner([]byte(source))
+			tokens := scanner.ScanTokens()
+			if len(scanner.Errors) > 0 {
+				t.Fatalf("Scanner errors: %v", scanner.Errors)
+			}
+			module, errs := parser.NewParser(tokens).Parse()
+			if len(errs) > 0 {
+				t.Fatalf("Parser errors: %v", errs)
+			}
+
+			table, _ := NewResolver().Resolve(module)
+
+			var got []string
+			for _, err := range table.Errors {
+				d := diagnostics.From(err)
+				if d.Code != diagnostics.CodeAttributeSpread {
+					t.Errorf("Expected code %s, got %s for %v", diagnostics.CodeAttributeSpread, d.Code, err)
+				}
+				if d.Span.Start.Line != 8 {
+					t.Errorf("Expected the error on the composition site, got line %d", d.Span.Start.Line)
+				}
+				got = append(got, d.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected errors:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
@@ -22,6 +22,9 @@ func (rt *ResolutionTable) ToText(filename string) (string, error) {
 	// Variables section
 	writeVariablesSection(&sb, rt)
 
+	// Wildcard imports section
+	writeWildcardImportsSection(&sb, rt)
+
 	// View composition section
 	writeViewCompositionSection(&sb, rt)
 
@@ -222,6 +225,30 @@ func writeVariablesSection(sb *strings.Builder, rt *ResolutionTable) {
 	sb.WriteString("\n")
 }
 
+// Wildcard imports section
+
+func writeWildcardImportsSection(sb *strings.Builder, rt *ResolutionTable) {
+	writeSectionHeader(sb, "wildcard imports")
+
+	if len(rt.WildcardImports) == 0 {
+		sb.WriteString("  (No wildcard imports)\n\n")
+		return
+	}
+
+	for _, stmt := range sortedWildcardImports(rt) {
+		names := rt.WildcardImports[stmt]
+		sb.WriteString(fmt.Sprintf("  from %s import * (%s):\n", formatImportModule(stmt), formatSpan(stmt.Span)))
+		if len(names) == 0 {
+			sb.WriteString("    (no names)\n")
+		}
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("    • %s\n", name))
+		}
+	}
+
+	sb.WriteString("\n")
+}
+
 // View composition section
 
 func writeViewCompositionSection(sb *strings.Builder, rt *ResolutionTable) {
@@ -145,11 +145,16 @@ type ResolutionTable struct {
 	FreeVars map[string]bool // Free variables
 
 	// Errors
-	Errors []error // Resolution errors
+	Errors   []error // Resolution errors
+	Warnings []error // Diagnostics that do not fail resolution, such as uses of deprecated symbols
 
 	// View composition support
 	Views        map[string]*ast.ViewStmt           // View name → ViewStmt mapping (module level views)
 	ViewElements map[*ast.HTMLElement]*ast.ViewStmt // HTMLElement → ViewStmt mapping (for composition)
+	Layouts      map[*ast.ViewStmt]*ast.ViewStmt    // View → layout named by its 'use layout', when the layout is known
+
+	// Import tracking
+	WildcardImports map[*ast.ImportFromStmt][]string // 'from x import *' → names it brought into scope, sorted
 }
 
 // NewResolutionTable returns a new ResolutionTable with all internal maps and slices initialized for variable resolution and view composition.
@@ -164,7 +169,11 @@ func NewResolutionTable() *ResolutionTable {
 		CellVars:       make(map[string]bool),
 		FreeVars:       make(map[string]bool),
 		Errors:         []error{},
+		Warnings:       []error{},
 		Views:          make(map[string]*ast.ViewStmt),
 		ViewElements:   make(map[*ast.HTMLElement]*ast.ViewStmt),
+		Layouts:        make(map[*ast.ViewStmt]*ast.ViewStmt),
+
+		WildcardImports: make(map[*ast.ImportFromStmt][]string),
 	}
 }
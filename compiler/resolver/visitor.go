@@ -2,9 +2,11 @@ package resolver
 
 import (
 	"context"
-	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/module"
 	"github.com/fjvillamarin/topple/compiler/symbol"
+	"sort"
 	"strings"
 )
 
@@ -19,8 +21,13 @@ func (r *Resolver) Visit(node ast.Node) ast.Visitor {
 // ===== Module and Top-level =====
 
 func (r *Resolver) VisitModule(m *ast.Module) ast.Visitor {
+	r.bindsDeprecated = m.BindsDeprecated()
+
 	// Visit all statements in the module
 	for _, stmt := range m.Body {
+		if r.ctx != nil && r.ctx.Err() != nil {
+			break
+		}
 		if stmt != nil {
 			stmt.Accept(r)
 		}
@@ -119,9 +126,21 @@ func (r *Resolver) VisitAssignStmt(a *ast.AssignStmt) ast.Visitor {
 	return r
 }
 
+// VisitAssignExpr resolves an assignment expression (x := value) like an
+// assignment statement: the value first, then the binding of its name.
+func (r *Resolver) VisitAssignExpr(a *ast.AssignExpr) ast.Visitor {
+	if a.Right != nil {
+		a.Right.Accept(r)
+	}
+	if a.Left != nil {
+		r.AnalyzeAssignmentTarget(a.Left)
+	}
+	return r
+}
+
 func (r *Resolver) VisitGlobalStmt(g *ast.GlobalStmt) ast.Visitor {
 	if r.ScopeChain.ScopeType == ModuleScopeType {
-		r.ReportError(fmt.Errorf("'global' declaration at module level"))
+		r.errorAt(g, diagnostics.CodeScopeDeclaration, "'global' declaration at module level")
 		return r
 	}
 
@@ -171,12 +190,12 @@ func (r *Resolver) VisitGlobalStmt(g *ast.GlobalStmt) ast.Visitor {
 
 func (r *Resolver) VisitNonlocalStmt(n *ast.NonlocalStmt) ast.Visitor {
 	if r.ScopeChain == nil {
-		r.ReportError(fmt.Errorf("'nonlocal' declaration outside any scope"))
+		r.errorAt(n, diagnostics.CodeScopeDeclaration, "'nonlocal' declaration outside any scope")
 		return r
 	}
 
 	if r.ScopeChain.ScopeType != FunctionScopeType && r.ScopeChain.ScopeType != ViewScopeType {
-		r.ReportError(fmt.Errorf("'nonlocal' declaration not in function scope"))
+		r.errorAt(n, diagnostics.CodeScopeDeclaration, "'nonlocal' declaration not in function scope")
 		return r
 	}
 
@@ -198,7 +217,7 @@ func (r *Resolver) VisitNonlocalStmt(n *ast.NonlocalStmt) ast.Visitor {
 		}
 
 		if !found {
-			r.ReportError(fmt.Errorf("no binding for nonlocal '%s' found", varName))
+			r.errorAt(name, diagnostics.CodeScopeDeclaration, "no binding for nonlocal '%s' found", varName)
 		}
 	}
 	return r
@@ -227,53 +246,75 @@ func (r *Resolver) VisitFunction(f *ast.Function) ast.Visitor {
 		r.ScopeDepths[f.Name] = depth - 1
 	}
 
+	// Default values are evaluated in the enclosing scope, where the
+	// function is defined, so they see its names rather than the parameters
+	r.visitDefaults(f.Parameters)
+
 	// Function body has its own scope
 	r.BeginScope(FunctionScopeType)
 	oldFunction := r.CurrentFunction
 	r.CurrentFunction = f
+	oldAsync := r.inAsync
+	r.inAsync = f.IsAsync
 
 	defer func() {
 		r.EndScope()
 		r.CurrentFunction = oldFunction
+		r.inAsync = oldAsync
 	}()
 
 	// Parameters create local bindings
-	if f.Parameters != nil {
-		for _, param := range f.Parameters.Parameters {
-			if param.Name != nil {
-				variable := r.DefineVariable(param.Name.Token.Lexeme, param.Name.Span)
-				variable.IsParameter = true
-				variable.State = VariableDefined
-				// IMPORTANT: Add parameter name to Variables map
-				r.Variables[param.Name] = variable
+	r.defineParameters(f.Parameters)
 
-				// Track binding for the parameter
-				if binding, exists := r.ScopeChain.Bindings[param.Name.Token.Lexeme]; exists {
-					r.NameToBinding[param.Name] = binding
-					r.NodeScopes[param.Name] = r.ScopeChain
-				}
+	// Visit function body
+	for _, stmt := range f.Body {
+		stmt.Accept(r)
+	}
 
-				// Calculate scope depth
-				depth := 0
-				for scope := r.ScopeChain; scope != nil; scope = scope.Parent {
-					depth++
-				}
-				r.ScopeDepths[param.Name] = depth - 1
-			}
+	return r
+}
 
-			// Visit default values in enclosing scope
-			if param.Default != nil {
-				param.Default.Accept(r)
-			}
+// visitDefaults resolves the default values of params in the current scope
+func (r *Resolver) visitDefaults(params *ast.ParameterList) {
+	if params == nil {
+		return
+	}
+	for _, param := range params.Parameters {
+		if param.Default != nil {
+			param.Default.Accept(r)
 		}
 	}
+}
 
-	// Visit function body
-	for _, stmt := range f.Body {
-		stmt.Accept(r)
+// defineParameters binds the parameters of a function or lambda in the
+// current scope
+func (r *Resolver) defineParameters(params *ast.ParameterList) {
+	if params == nil {
+		return
 	}
+	for _, param := range params.Parameters {
+		if param.Name == nil {
+			continue
+		}
+		variable := r.DefineVariable(param.Name.Token.Lexeme, param.Name.Span)
+		variable.IsParameter = true
+		variable.State = VariableDefined
+		// IMPORTANT: Add parameter name to Variables map
+		r.Variables[param.Name] = variable
+
+		// Track binding for the parameter
+		if binding, exists := r.ScopeChain.Bindings[param.Name.Token.Lexeme]; exists {
+			r.NameToBinding[param.Name] = binding
+			r.NodeScopes[param.Name] = r.ScopeChain
+		}
 
-	return r
+		// Calculate scope depth
+		depth := 0
+		for scope := r.ScopeChain; scope != nil; scope = scope.Parent {
+			depth++
+		}
+		r.ScopeDepths[param.Name] = depth - 1
+	}
 }
 
 func (r *Resolver) VisitViewStmt(v *ast.ViewStmt) ast.Visitor {
@@ -306,10 +347,13 @@ func (r *Resolver) VisitViewStmt(v *ast.ViewStmt) ast.Visitor {
 	r.BeginScope(ViewScopeType)
 	oldView := r.CurrentView
 	r.CurrentView = v
+	oldAsync := r.inAsync
+	r.inAsync = v.IsAsync
 
 	defer func() {
 		r.EndScope()
 		r.CurrentView = oldView
+		r.inAsync = oldAsync
 	}()
 
 	// View parameters create local bindings
@@ -339,6 +383,13 @@ func (r *Resolver) VisitViewStmt(v *ast.ViewStmt) ast.Visitor {
 		}
 	}
 
+	// The layout of 'use layout' is resolved in the view scope, where it may
+	// use the view's parameters
+	if v.Layout != nil {
+		v.Layout.Accept(r)
+	}
+	r.checkLayout(v)
+
 	// Visit view body
 	for _, stmt := range v.Body {
 		stmt.Accept(r)
@@ -347,15 +398,57 @@ func (r *Resolver) VisitViewStmt(v *ast.ViewStmt) ast.Visitor {
 	return r
 }
 
+// importedView returns the view name is imported as, with its symbol, or nil
+// when name is not an imported view
+func (r *Resolver) importedView(name string) (*ast.ViewStmt, *symbol.Symbol) {
+	if r.SymbolRegistry == nil {
+		return nil, nil
+	}
+	// Look up the name in module globals to see if it's imported
+	variable, exists := r.ModuleGlobals[name]
+	if !exists || !variable.IsImported {
+		return nil, nil
+	}
+
+	// Try ImportSource first (O(1) lookup) if available
+	if variable.ImportSource != "" {
+		if sym, err := r.SymbolRegistry.LookupSymbol(variable.ImportSource, name); err == nil {
+			if sym.Type == symbol.SymbolView {
+				if viewStmt, ok := sym.Node.(*ast.ViewStmt); ok {
+					return viewStmt, sym
+				}
+			}
+		}
+	}
+
+	// If not found via ImportSource (e.g., re-exported from __init__.psx),
+	// search all registered modules (O(n) fallback)
+	for _, filePath := range r.SymbolRegistry.GetAllModules() {
+		if sym, err := r.SymbolRegistry.LookupSymbol(filePath, name); err == nil {
+			if sym.Type == symbol.SymbolView {
+				if viewStmt, ok := sym.Node.(*ast.ViewStmt); ok {
+					return viewStmt, sym
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
 func (r *Resolver) VisitClass(c *ast.Class) ast.Visitor {
 	// Class name is bound in enclosing scope
 	if c.Name != nil {
 		r.DefineVariable(c.Name.Token.Lexeme, c.Name.Span)
 	}
 
-	// Class body has its own scope
+	// Class body has its own scope, which is never async
 	r.BeginScope(ClassScopeType)
-	defer r.EndScope()
+	oldAsync := r.inAsync
+	r.inAsync = false
+	defer func() {
+		r.EndScope()
+		r.inAsync = oldAsync
+	}()
 
 	// Visit class body
 	for _, stmt := range c.Body {
@@ -462,15 +555,14 @@ func (r *Resolver) AnalyzeAssignmentTarget(target ast.Expr) {
 		}
 
 	default:
-		r.ReportError(fmt.Errorf("invalid assignment target"))
+		r.errorAt(target, diagnostics.CodeAssignTarget, "invalid assignment target")
 	}
 }
 
 // ===== Placeholder visitors for other nodes =====
 // TODO: Implement these as needed
 
-func (r *Resolver) VisitAssignExpr(a *ast.AssignExpr) ast.Visitor { return r }
-func (r *Resolver) VisitStarExpr(s *ast.StarExpr) ast.Visitor     { return r }
+func (r *Resolver) VisitStarExpr(s *ast.StarExpr) ast.Visitor { return r }
 func (r *Resolver) VisitTernaryExpr(t *ast.TernaryExpr) ast.Visitor {
 	// Visit condition, true expression, and false expression
 	if t.Condition != nil {
@@ -540,9 +632,28 @@ func (r *Resolver) VisitParameterList(p *ast.ParameterList) ast.Visitor { return
 func (r *Resolver) VisitParameter(p *ast.Parameter) ast.Visitor         { return r }
 func (r *Resolver) VisitTypeParamExpr(t *ast.TypeParam) ast.Visitor     { return r }
 func (r *Resolver) VisitSlice(s *ast.Slice) ast.Visitor                 { return r }
-func (r *Resolver) VisitAwaitExpr(a *ast.AwaitExpr) ast.Visitor         { return r }
-func (r *Resolver) VisitArgument(a *ast.Argument) ast.Visitor           { return r }
-func (r *Resolver) VisitLambda(l *ast.Lambda) ast.Visitor               { return r }
+func (r *Resolver) VisitAwaitExpr(a *ast.AwaitExpr) ast.Visitor {
+	if !r.inAsync {
+		r.errorAt(a, diagnostics.CodeAsyncContext, "'await' outside async function or view")
+	}
+	if a.Expr != nil {
+		a.Expr.Accept(r)
+	}
+	return r
+}
+func (r *Resolver) VisitArgument(a *ast.Argument) ast.Visitor { return r }
+func (r *Resolver) VisitLambda(l *ast.Lambda) ast.Visitor {
+	// Defaults are evaluated where the lambda is defined; its parameters
+	// are local to its body
+	r.visitDefaults(l.Parameters)
+	r.BeginScope(FunctionScopeType)
+	defer r.EndScope()
+	r.defineParameters(l.Parameters)
+	if l.Body != nil {
+		l.Body.Accept(r)
+	}
+	return r
+}
 func (r *Resolver) VisitFString(f *ast.FString) ast.Visitor {
 	// Visit all parts of the f-string
 	for _, part := range f.Parts {
@@ -552,6 +663,12 @@ func (r *Resolver) VisitFString(f *ast.FString) ast.Visitor {
 	}
 	return r
 }
+func (r *Resolver) VisitConcatenatedString(c *ast.ConcatenatedString) ast.Visitor {
+	for _, part := range c.Parts {
+		part.Accept(r)
+	}
+	return r
+}
 func (r *Resolver) VisitFStringMiddle(f *ast.FStringMiddle) ast.Visitor { return r }
 func (r *Resolver) VisitFStringReplacementField(f *ast.FStringReplacementField) ast.Visitor {
 	// Visit the expression inside the replacement field
@@ -613,7 +730,8 @@ func (r *Resolver) VisitImportStmt(i *ast.ImportStmt) ast.Visitor {
 		filePath, err := r.ModuleResolver.ResolveAbsolute(context.Background(), modulePath)
 		if err != nil {
 			// Not a PSX module - treat as a regular Python import (pass-through)
-			// Don't report an error; the import will be emitted as-is in codegen
+			// unless the import policy requires one; it is emitted as-is in codegen
+			r.checkUnresolvedImport(modulePath, importName.DottedName.Span, err)
 			continue
 		}
 
@@ -677,10 +795,6 @@ func (r *Resolver) VisitImportFromStmt(i *ast.ImportFromStmt) ast.Visitor {
 			modulePath,
 			r.SourceFilePath,
 		)
-		if err != nil {
-			// Not a PSX module - treat as a regular Python relative import (pass-through)
-			return r
-		}
 	} else {
 		// Absolute import: from x import y
 		modulePath = convertDottedNameToPath(i.DottedName)
@@ -688,25 +802,39 @@ func (r *Resolver) VisitImportFromStmt(i *ast.ImportFromStmt) ast.Visitor {
 			context.Background(),
 			modulePath,
 		)
-		if err != nil {
-			// Not a PSX module - treat as a regular Python import (pass-through)
-			return r
+	}
+	if module.IsNamespacePackage(err) && !i.IsWildcard {
+		// A namespace package has no file: the names it imports are its modules
+		r.bindNamespaceImports(i, modulePath)
+		return r
+	}
+	if err != nil {
+		// Not a PSX module - treat as a regular Python import (pass-through)
+		// unless the import policy requires one
+		if i.DotCount == 0 {
+			r.checkUnresolvedImport(modulePath, i.DottedName.Span, err)
 		}
+		return r
 	}
 
 	// 2. Handle wildcard vs specific imports
 	if i.IsWildcard {
-		// from module import *
-		symbols, err := r.SymbolRegistry.GetPublicSymbols(filePath)
+		// from module import *, bound like Python does: the names of the
+		// module's __all__, or its public names
+		symbols, err := r.SymbolRegistry.GetWildcardSymbols(filePath)
 		if err != nil {
 			// Module not yet registered - skip silently
 			return r
 		}
 
+		names := make([]string, 0, len(symbols))
 		for _, sym := range symbols {
 			variable := r.DefineImportedVariable(sym.Name, i.Span)
 			variable.ImportSource = filePath
+			names = append(names, sym.Name)
 		}
+		sort.Strings(names)
+		r.WildcardImports[i] = names
 	} else {
 		// from module import x, y as z
 		for _, importName := range i.Names {
@@ -730,6 +858,10 @@ func (r *Resolver) VisitImportFromStmt(i *ast.ImportFromStmt) ast.Visitor {
 				nameNode = importName.DottedName.Names[0]
 			}
 
+			if sym.Deprecated {
+				r.warnDeprecated(importName.DottedName.Names[0].Token, symbolName, sym.DeprecationMessage)
+			}
+
 			// Create binding
 			variable := r.DefineImportedVariable(bindingName, importName.GetSpan())
 			variable.ImportSource = filePath
@@ -752,8 +884,62 @@ func (r *Resolver) VisitImportFromStmt(i *ast.ImportFromStmt) ast.Visitor {
 
 	return r
 }
+
+// bindNamespaceImports binds the modules "from ns import a, b as c" imports
+// from the namespace package at modulePath, like "import ns.a" binds a module
+func (r *Resolver) bindNamespaceImports(i *ast.ImportFromStmt, modulePath string) {
+	for _, importName := range i.Names {
+		name := importName.DottedName.Names[0]
+		submodulePath := name.Token.Lexeme
+		if modulePath != "" {
+			submodulePath = modulePath + "." + submodulePath
+		}
+
+		var filePath string
+		var err error
+		if i.DotCount > 0 {
+			filePath, err = r.ModuleResolver.ResolveRelative(context.Background(), i.DotCount, submodulePath, r.SourceFilePath)
+		} else {
+			filePath, err = r.ModuleResolver.ResolveAbsolute(context.Background(), submodulePath)
+		}
+		if err != nil {
+			// Not a PSX module of the package - pass through
+			continue
+		}
+
+		bindingName, nameNode := name.Token.Lexeme, name
+		if importName.AsName != nil {
+			bindingName, nameNode = importName.AsName.Token.Lexeme, importName.AsName
+		}
+
+		variable := r.DefineImportedVariable(bindingName, importName.GetSpan())
+		variable.ImportSource = filePath
+		r.Variables[nameNode] = variable
+		r.ScopeDepths[nameNode] = 0 // Imports are always at module level
+		if binding, exists := r.ScopeChain.Bindings[bindingName]; exists {
+			r.NameToBinding[nameNode] = binding
+		}
+	}
+}
+
 func (r *Resolver) VisitTypeAlias(t *ast.TypeAlias) ast.Visitor { return r }
-func (r *Resolver) VisitDecorator(d *ast.Decorator) ast.Visitor { return r }
+
+func (r *Resolver) VisitDecorator(d *ast.Decorator) ast.Visitor {
+	// The @deprecated marker is read by the compiler and never evaluated
+	if _, marker := d.Deprecation(); (!marker || r.bindsDeprecated) && d.Expr != nil {
+		d.Expr.Accept(r)
+	}
+	if view, ok := d.Definition().(*ast.ViewStmt); ok && !r.bindsDeprecated {
+		if message, deprecated := d.Deprecated(); deprecated {
+			r.deprecatedViews[view] = message
+		}
+	}
+	if d.Stmt != nil {
+		d.Stmt.Accept(r)
+	}
+	return r
+}
+
 func (r *Resolver) VisitMultiStmt(m *ast.MultiStmt) ast.Visitor {
 	// Visit all sub-statements
 	for _, stmt := range m.Stmts {
@@ -810,6 +996,10 @@ func (r *Resolver) VisitWhile(w *ast.While) ast.Visitor {
 }
 
 func (r *Resolver) VisitFor(f *ast.For) ast.Visitor {
+	if f.IsAsync && !r.inAsync {
+		r.errorAt(f, diagnostics.CodeAsyncContext, "'async for' outside async function or view")
+	}
+
 	// Visit the iterable first
 	if f.Iterable != nil {
 		f.Iterable.Accept(r)
@@ -837,7 +1027,31 @@ func (r *Resolver) VisitFor(f *ast.For) ast.Visitor {
 	return r
 }
 
-func (r *Resolver) VisitWith(w *ast.With) ast.Visitor       { return r }
+func (r *Resolver) VisitWith(w *ast.With) ast.Visitor {
+	if w.IsAsync && !r.inAsync {
+		r.errorAt(w, diagnostics.CodeAsyncContext, "'async with' outside async function or view")
+	}
+
+	// Context managers are evaluated before their targets are bound
+	for _, item := range w.Items {
+		if item.Expr != nil {
+			item.Expr.Accept(r)
+		}
+		if item.As != nil {
+			r.AnalyzeAssignmentTarget(item.As)
+		}
+	}
+
+	// Visit the body
+	for _, stmt := range w.Body {
+		if stmt != nil {
+			stmt.Accept(r)
+		}
+	}
+
+	return r
+}
+
 func (r *Resolver) VisitTry(t *ast.Try) ast.Visitor         { return r }
 func (r *Resolver) VisitMatch(m *ast.MatchStmt) ast.Visitor { return r }
 
@@ -854,6 +1068,21 @@ func (r *Resolver) VisitAsPattern(ap *ast.AsPattern) ast.Visitor             { r
 func (r *Resolver) VisitOrPattern(op *ast.OrPattern) ast.Visitor             { return r }
 
 func (r *Resolver) VisitHTMLElement(h *ast.HTMLElement) ast.Visitor {
+	// The content of a scoped slot becomes a function of the values the slot
+	// passes, which its let attribute binds
+	if params, ok := h.SlotParams(); ok {
+		r.BeginScope(FunctionScopeType)
+		defer r.EndScope()
+		for _, param := range params {
+			variable := r.DefineVariable(param.Token.Lexeme, param.Span)
+			variable.IsParameter = true
+			variable.State = VariableDefined
+			r.Variables[param] = variable
+			r.NameToBinding[param] = r.ScopeChain.Bindings[param.Token.Lexeme]
+			r.NodeScopes[param] = r.ScopeChain
+		}
+	}
+
 	// Check if this HTML element references a view (for composition)
 	tagName := h.TagName.Lexeme
 
@@ -861,42 +1090,38 @@ func (r *Resolver) VisitHTMLElement(h *ast.HTMLElement) ast.Visitor {
 	if viewStmt, exists := r.Views[tagName]; exists {
 		// This HTML element is actually a view reference - bind it
 		r.ViewElements[h] = viewStmt
-	} else if r.SymbolRegistry != nil {
+		if message, deprecated := r.deprecatedViews[viewStmt]; deprecated {
+			r.warnDeprecated(h.TagName, tagName, message)
+		}
+		if r.StrictProps {
+			r.checkPropTypes(h, viewStmt)
+		}
+		r.checkViewArguments(h, viewStmt)
+		r.checkSlotTargets(h)
+		r.checkSlotContent(h, viewStmt)
+		r.checkScopedSlots(h, viewStmt)
+		r.checkAsyncView(h, viewStmt)
+	} else if foundView, foundSym := r.importedView(tagName); foundView != nil {
 		// Second check: imported view
-		// Look up the name in module globals to see if it's imported
-		if variable, exists := r.ModuleGlobals[tagName]; exists && variable.IsImported {
-			var foundView *ast.ViewStmt
-
-			// Try ImportSource first (O(1) lookup) if available
-			if variable.ImportSource != "" {
-				if sym, err := r.SymbolRegistry.LookupSymbol(variable.ImportSource, tagName); err == nil {
-					if sym.Type == symbol.SymbolView {
-						if viewStmt, ok := sym.Node.(*ast.ViewStmt); ok {
-							foundView = viewStmt
-						}
-					}
-				}
-			}
+		r.ViewElements[h] = foundView
+		if foundSym.Deprecated {
+			r.warnDeprecated(h.TagName, foundView.Name.Token.Lexeme, foundSym.DeprecationMessage)
+		}
+		if r.StrictProps {
+			r.checkPropTypes(h, foundView)
+		}
+		r.checkViewArguments(h, foundView)
+		r.checkSlotTargets(h)
+		r.checkSlotContent(h, foundView)
+		r.checkScopedSlots(h, foundView)
+		r.checkAsyncView(h, foundView)
+	}
 
-			// If not found via ImportSource (e.g., re-exported from __init__.psx),
-			// search all registered modules (O(n) fallback)
-			if foundView == nil {
-				for _, filePath := range r.SymbolRegistry.GetAllModules() {
-					if sym, err := r.SymbolRegistry.LookupSymbol(filePath, tagName); err == nil {
-						if sym.Type == symbol.SymbolView {
-							if viewStmt, ok := sym.Node.(*ast.ViewStmt); ok {
-								foundView = viewStmt
-								break
-							}
-						}
-					}
-				}
-			}
+	r.checkSpreads(h, r.ViewElements[h])
 
-			if foundView != nil {
-				r.ViewElements[h] = foundView
-			}
-		}
+	// A computed tag name never names a view; its expression is resolved like any other
+	if h.TagExpr != nil {
+		h.TagExpr.Accept(r)
 	}
 
 	// Visit all attributes first - they contain expressions that need resolution
@@ -937,3 +1162,8 @@ func (r *Resolver) VisitHTMLInterpolation(h *ast.HTMLInterpolation) ast.Visitor
 	}
 	return r
 }
+
+func (r *Resolver) VisitHTMLComment(h *ast.HTMLComment) ast.Visitor {
+	// HTML comments don't contain variables to resolve
+	return r
+}
@@ -0,0 +1,145 @@
+// Package sourcemap maps generated Python back to the .psx source it was
+// compiled from.
+//
+// A map is written next to each generated file, as home.py.map for home.py.
+// It lists the generated range of every statement whose AST node carries a
+// source span, so a line of a Python traceback can be traced to the view
+// code that produced it:
+//
+//	{
+//	  "version": 1,
+//	  "file": "home.py",
+//	  "source": "home.psx",
+//	  "mappings": [
+//	    {"generated": {"start": {"line": 3, "column": 1}, "end": {"line": 9, "column": 35}},
+//	     "original": {"start": {"line": 1, "column": 1}, "end": {"line": 4, "column": 12}}}
+//	  ]
+//	}
+//
+// Lines and columns start at 1. Statements nest, so ranges do too; Lookup
+// returns the innermost one.
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// Version is the format version written to new maps
+const Version = 1
+
+// Extension is appended to a generated file's name to name its map
+const Extension = ".map"
+
+// Position is a location in a file, counting from 1
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Range spans from Start to End, both inclusive
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Mapping ties a generated range to the source range it was compiled from
+type Mapping struct {
+	Generated Range `json:"generated"`
+	Original  Range `json:"original"`
+}
+
+// Map is the source map of one generated file
+type Map struct {
+	Version  int       `json:"version"`
+	File     string    `json:"file"`   // Generated file, relative to the map
+	Source   string    `json:"source"` // Source file, relative to the map
+	Mappings []Mapping `json:"mappings"`
+}
+
+// New creates a map of the given mappings, ordered by generated start
+func New(mappings []Mapping) *Map {
+	if mappings == nil {
+		mappings = []Mapping{}
+	}
+	return &Map{Version: Version, Mappings: mappings}
+}
+
+// PathFor returns where the map of a generated file is written
+func PathFor(generatedPath string) string {
+	return generatedPath + Extension
+}
+
+// Locate records the generated and source files relative to the map of
+// generatedPath, so the files can be moved together
+func (m *Map) Locate(generatedPath, sourcePath string) {
+	m.File = filepath.Base(generatedPath)
+	m.Source = filepath.ToSlash(sourcePath)
+	if rel, err := filepath.Rel(filepath.Dir(generatedPath), sourcePath); err == nil {
+		m.Source = filepath.ToSlash(rel)
+	}
+}
+
+// SourcePath returns the source file of a map read from mapPath
+func (m *Map) SourcePath(mapPath string) string {
+	source := filepath.FromSlash(m.Source)
+	if filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(filepath.Dir(mapPath), source)
+}
+
+// Shift moves the generated ranges down by n lines, for lines written above
+// the generated code such as a header. A nil map is left alone.
+func (m *Map) Shift(n int) {
+	if m == nil {
+		return
+	}
+	for i := range m.Mappings {
+		m.Mappings[i].Generated.Start.Line += n
+		m.Mappings[i].Generated.End.Line += n
+	}
+}
+
+// Lookup returns the innermost mapping covering a generated line
+func (m *Map) Lookup(line int) (Mapping, bool) {
+	var best Mapping
+	found := false
+	for _, mapping := range m.Mappings {
+		generated := mapping.Generated
+		if line < generated.Start.Line || line > generated.End.Line {
+			continue
+		}
+		if !found || lines(generated) < lines(best.Generated) ||
+			lines(generated) == lines(best.Generated) && lines(mapping.Original) <= lines(best.Original) {
+			best, found = mapping, true
+		}
+	}
+	return best, found
+}
+
+func lines(r Range) int {
+	return r.End.Line - r.Start.Line
+}
+
+// Marshal encodes the map as JSON
+func (m *Map) Marshal() ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Unmarshal decodes a map, rejecting versions this package cannot read
+func Unmarshal(data []byte) (*Map, error) {
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("unsupported source map version %d", m.Version)
+	}
+	return &m, nil
+}
@@ -0,0 +1,98 @@
+package sourcemap
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mapping(genStart, genEnd, srcStart, srcEnd int) Mapping {
+	return Mapping{
+		Generated: Range{Start: Position{Line: genStart, Column: 1}, End: Position{Line: genEnd, Column: 1}},
+		Original:  Range{Start: Position{Line: srcStart, Column: 1}, End: Position{Line: srcEnd, Column: 1}},
+	}
+}
+
+func TestLookup(t *testing.T) {
+	m := New([]Mapping{
+		mapping(3, 20, 1, 9),  // view class
+		mapping(10, 18, 1, 9), // _render method, synthesized from the view
+		mapping(12, 14, 4, 6), // for loop
+		mapping(13, 13, 5, 5), // element
+		mapping(13, 13, 5, 6), // wider statement on the same line
+	})
+
+	tests := []struct {
+		line     int
+		expected int // Original start line; 0 for no mapping
+	}{
+		{1, 0},
+		{3, 1},
+		{12, 4},
+		{13, 5},
+		{16, 1},
+		{21, 0},
+	}
+	for _, tt := range tests {
+		got, ok := m.Lookup(tt.line)
+		switch {
+		case tt.expected == 0 && ok:
+			t.Errorf("line %d: expected no mapping, got %+v", tt.line, got)
+		case tt.expected != 0 && (!ok || got.Original.Start.Line != tt.expected):
+			t.Errorf("line %d: expected source line %d, got %+v (%v)", tt.line, tt.expected, got, ok)
+		}
+	}
+	if got, _ := m.Lookup(13); got.Original.End.Line != 5 {
+		t.Errorf("expected the narrowest source range on ties, got %+v", got)
+	}
+}
+
+func TestShift(t *testing.T) {
+	m := New([]Mapping{mapping(3, 20, 1, 9), mapping(13, 13, 5, 5)})
+	m.Shift(2)
+	if got, ok := m.Lookup(15); !ok || got.Original.Start.Line != 5 {
+		t.Errorf("expected line 15 to map to source line 5 after shifting, got %+v (%v)", got, ok)
+	}
+	if _, ok := m.Lookup(4); ok {
+		t.Error("expected no mapping above the shifted code")
+	}
+
+	var none *Map
+	none.Shift(2)
+}
+
+func TestLocateAndRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	m := New([]Mapping{mapping(1, 2, 3, 4)})
+	m.Locate(filepath.Join(root, "build", "home.py"), filepath.Join(root, "src", "home.psx"))
+	if m.File != "home.py" || m.Source != "../src/home.psx" {
+		t.Fatalf("unexpected file names %q and %q", m.File, m.Source)
+	}
+
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Mappings) != 1 || decoded.Mappings[0] != m.Mappings[0] {
+		t.Errorf("mappings changed in a round trip: %+v", decoded.Mappings)
+	}
+
+	mapPath := PathFor(filepath.Join(root, "build", "home.py"))
+	if !strings.HasSuffix(mapPath, "home.py.map") {
+		t.Errorf("unexpected map path %s", mapPath)
+	}
+	if source := decoded.SourcePath(mapPath); source != filepath.Join(root, "src", "home.psx") {
+		t.Errorf("unexpected source path %s", source)
+	}
+
+	if _, err := Unmarshal([]byte(`{"version": 99, "mappings": []}`)); err == nil {
+		t.Error("expected an unknown version to be rejected")
+	}
+	if _, err := Unmarshal([]byte(`not json`)); err == nil {
+		t.Error("expected invalid JSON to be rejected")
+	}
+}
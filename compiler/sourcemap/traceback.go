@@ -0,0 +1,138 @@
+package sourcemap
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// frameLine matches the location line of a traceback frame:
+//
+//	File "/app/pages/home.py", line 42, in _render
+var frameLine = regexp.MustCompile(`^(\s*)File "([^"]+\.py)", line (\d+)(.*)$`)
+
+// caretLine matches the markers Python prints under a frame's code
+var caretLine = regexp.MustCompile(`^\s*[~^]+\s*$`)
+
+// Rewriter rewrites Python tracebacks so frames in generated files point at
+// the .psx lines they were compiled from. Frames without a map next to their
+// file are left alone.
+type Rewriter struct {
+	readFile func(path string) ([]byte, error)
+	maps     map[string]*Map     // By generated path; nil when there is none
+	sources  map[string][]string // Lines of source files; nil when unreadable
+}
+
+// NewRewriter creates a rewriter reading maps and sources with readFile
+func NewRewriter(readFile func(path string) ([]byte, error)) *Rewriter {
+	return &Rewriter{
+		readFile: readFile,
+		maps:     make(map[string]*Map),
+		sources:  make(map[string][]string),
+	}
+}
+
+// Rewrite copies a traceback from in to out, translating every frame of a
+// mapped file. The code shown under a translated frame is replaced with the
+// source line, and Python's position markers, which refer to the generated
+// line, are dropped.
+func (r *Rewriter) Rewrite(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	w := bufio.NewWriter(out)
+
+	var excerpt string // Source line to show instead of the next code line
+	replacing := false // Whether the previous frame was translated
+	replaced := false  // Whether its code line has been replaced
+	indent := ""       // Indentation of the previous frame line
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if replacing {
+			switch {
+			case !replaced && strings.HasPrefix(line, indent+" ") && !frameLine.MatchString(line):
+				if excerpt != "" {
+					line = indent + "  " + excerpt
+				}
+				replaced = true
+			case replaced && excerpt != "" && caretLine.MatchString(line):
+				continue
+			default:
+				replacing = false
+			}
+		}
+
+		if match := frameLine.FindStringSubmatch(line); match != nil {
+			if translated, source, ok := r.translate(match); ok {
+				line = translated
+				excerpt, indent = source, match[1]
+				replacing, replaced = true, false
+			}
+		}
+
+		if _, err := w.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// translate rewrites a matched frame line, returning it and the source line
+// it now points at (empty when the source cannot be read)
+func (r *Rewriter) translate(match []string) (string, string, bool) {
+	indent, path, rest := match[1], match[2], match[4]
+	line, err := strconv.Atoi(match[3])
+	if err != nil {
+		return "", "", false
+	}
+
+	m := r.load(path)
+	if m == nil {
+		return "", "", false
+	}
+	mapping, ok := m.Lookup(line)
+	if !ok {
+		return "", "", false
+	}
+
+	sourcePath := m.SourcePath(PathFor(path))
+	sourceLine := mapping.Original.Start.Line
+	translated := indent + `File "` + sourcePath + `", line ` + strconv.Itoa(sourceLine) + rest
+
+	excerpt := ""
+	if lines := r.source(sourcePath); sourceLine >= 1 && sourceLine <= len(lines) {
+		excerpt = strings.TrimSpace(lines[sourceLine-1])
+	}
+	return translated, excerpt, true
+}
+
+// load returns the map of a generated file, or nil without a readable one
+func (r *Rewriter) load(path string) *Map {
+	if m, ok := r.maps[path]; ok {
+		return m
+	}
+	var m *Map
+	if data, err := r.readFile(PathFor(path)); err == nil {
+		m, _ = Unmarshal(data)
+	}
+	r.maps[path] = m
+	return m
+}
+
+// source returns the lines of a source file, or nil when it is unreadable
+func (r *Rewriter) source(path string) []string {
+	if lines, ok := r.sources[path]; ok {
+		return lines
+	}
+	var lines []string
+	if data, err := r.readFile(path); err == nil {
+		lines = strings.Split(string(data), "\n")
+	}
+	r.sources[path] = lines
+	return lines
+}
@@ -0,0 +1,87 @@
+package sourcemap
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestRewriteTraceback(t *testing.T) {
+	m := New([]Mapping{
+		mapping(3, 14, 1, 5),
+		mapping(12, 12, 4, 4),
+	})
+	m.Locate("/app/pages/home.py", "/app/pages/home.psx")
+	data, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"/app/pages/home.py.map":   string(data),
+		"/app/pages/home.psx":      "view Home(items: list):\n    <ul>\n        for item in items:\n            <li>{item.name}</li>\n    </ul>\n",
+		"/app/pages/broken.py.map": "{",
+	}
+	readFile := func(path string) ([]byte, error) {
+		if content, ok := files[path]; ok {
+			return []byte(content), nil
+		}
+		return nil, fs.ErrNotExist
+	}
+
+	traceback := `Traceback (most recent call last):
+  File "/app/main.py", line 3, in <module>
+    print(Home(items=[1]).render())
+          ^^^^^^^^^^^^^^^^^^^^^^^^
+  File "/app/pages/home.py", line 12, in _render
+    _ul_children_2000.append(el("li", escape(item.name)))
+                                             ^^^^^^^^^
+  File "/app/pages/home.py", line 40, in helper
+    return 1
+  File "/app/pages/broken.py", line 1, in <module>
+    x = 1
+AttributeError: 'int' object has no attribute 'name'
+`
+	expected := `Traceback (most recent call last):
+  File "/app/main.py", line 3, in <module>
+    print(Home(items=[1]).render())
+          ^^^^^^^^^^^^^^^^^^^^^^^^
+  File "/app/pages/home.psx", line 4, in _render
+    <li>{item.name}</li>
+  File "/app/pages/home.py", line 40, in helper
+    return 1
+  File "/app/pages/broken.py", line 1, in <module>
+    x = 1
+AttributeError: 'int' object has no attribute 'name'
+`
+
+	var out strings.Builder
+	if err := NewRewriter(readFile).Rewrite(strings.NewReader(traceback), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected {
+		t.Errorf("rewritten traceback:\n%s\nwant:\n%s", out.String(), expected)
+	}
+}
+
+func TestRewriteTracebackWithoutSource(t *testing.T) {
+	m := New([]Mapping{mapping(1, 1, 7, 7)})
+	m.Locate("/app/home.py", "/app/home.psx")
+	data, _ := m.Marshal()
+	readFile := func(path string) ([]byte, error) {
+		if path == "/app/home.py.map" {
+			return data, nil
+		}
+		return nil, fs.ErrNotExist
+	}
+
+	// The generated code stays when the source cannot be read
+	traceback := "  File \"/app/home.py\", line 1, in _render\n    boom()\n    ^^^^^^\n"
+	var out strings.Builder
+	if err := NewRewriter(readFile).Rewrite(strings.NewReader(traceback), &out); err != nil {
+		t.Fatal(err)
+	}
+	expected := "  File \"/app/home.psx\", line 7, in _render\n    boom()\n    ^^^^^^\n"
+	if out.String() != expected {
+		t.Errorf("got %q, want %q", out.String(), expected)
+	}
+}
@@ -0,0 +1,243 @@
+package compiler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/codegen"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// GenerateStub returns the .pyi stub of a compiled module, written for target
+func GenerateStub(module *ast.Module, target codegen.Target) ([]byte, error) {
+	stub, err := codegen.Lower(Stub(module), target)
+	if err != nil {
+		return nil, err
+	}
+	generator := codegen.NewCodeGenerator()
+	generator.Target = target
+	code, err := generator.GenerateContext(context.Background(), stub)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(code), nil
+}
+
+// Stub returns the stub of a compiled module, for type checkers and editors
+// of the Python code importing it: its imports, type aliases and type
+// variables, and the signatures of the public views, functions, classes and
+// variables it defines. Views declare their constructor, slots included, and
+// an attribute for each annotated parameter. Bodies and default values are
+// replaced by ..., and variables keep their annotation or, for literals,
+// their type. module is not modified.
+func Stub(module *ast.Module) *ast.Module {
+	s := &stubber{}
+	body := s.stmts(module.Body, false)
+	if s.needsAny {
+		body = append([]ast.Stmt{&ast.ImportFromStmt{
+			DottedName: &ast.DottedName{Names: []*ast.Name{stubName("typing")}},
+			Names:      []*ast.ImportName{{DottedName: &ast.DottedName{Names: []*ast.Name{stubName("Any")}}}},
+		}}, body...)
+	}
+	return &ast.Module{Body: body, Span: module.Span}
+}
+
+// stubber builds the stub of a module
+type stubber struct {
+	needsAny bool // Whether a variable is declared as typing.Any
+}
+
+// typeVarFactories are the typing calls whose assignments a stub keeps
+var typeVarFactories = map[string]bool{"TypeVar": true, "ParamSpec": true, "TypeVarTuple": true, "NewType": true}
+
+// stmts returns the stubs of a block's statements, leaving out the private
+// ones and those that declare nothing
+func (s *stubber) stmts(stmts []ast.Stmt, inClass bool) []ast.Stmt {
+	var stubs []ast.Stmt
+	for _, stmt := range stmts {
+		if stub := s.stmt(stmt, inClass); stub != nil {
+			stubs = append(stubs, stub)
+		}
+	}
+	return stubs
+}
+
+func (s *stubber) stmt(stmt ast.Stmt, inClass bool) ast.Stmt {
+	switch st := stmt.(type) {
+	case *ast.ImportFromStmt:
+		if st.DottedName != nil && dottedPath(st.DottedName) == "__future__" {
+			return nil
+		}
+		return stmt
+
+	case *ast.ImportStmt, *ast.TypeAlias:
+		return stmt
+
+	case *ast.AssignStmt:
+		if len(st.Targets) != 1 {
+			return nil
+		}
+		name, ok := st.Targets[0].(*ast.Name)
+		if !ok {
+			return nil
+		}
+		if call, ok := st.Value.(*ast.Call); ok && typeVarFactories[lastName(call.Callee)] {
+			return stmt
+		}
+		if name.Token.Lexeme == "__all__" {
+			return stmt
+		}
+		if !stubPublic(name.Token.Lexeme) {
+			return nil
+		}
+		return &ast.AnnotationStmt{Target: name, Type: s.literalType(st.Value), Span: st.Span}
+
+	case *ast.AnnotationStmt:
+		name, ok := st.Target.(*ast.Name)
+		if !ok || !stubPublic(name.Token.Lexeme) {
+			return nil
+		}
+		return &ast.AnnotationStmt{Target: name, Type: st.Type, Span: st.Span}
+
+	case *ast.Function:
+		if !stubPublic(st.Name.Token.Lexeme) {
+			return nil
+		}
+		return stubFunction(st, inClass)
+
+	case *ast.Class:
+		if !stubPublic(st.Name.Token.Lexeme) {
+			return nil
+		}
+		class := *st
+		class.Body = s.stmts(st.Body, true)
+		if isViewClass(st) {
+			class.Body = append(viewAttributes(st), class.Body...)
+		}
+		if len(class.Body) == 0 {
+			class.Body = []ast.Stmt{&ast.ExprStmt{Expr: ellipsis()}}
+		}
+		return &class
+
+	case *ast.Decorator:
+		inner := s.stmt(st.Stmt, inClass)
+		if inner == nil {
+			return nil
+		}
+		decorator := *st
+		decorator.Stmt = inner
+		return &decorator
+
+	case *ast.If:
+		// Imports only type checkers see, under "if TYPE_CHECKING:"
+		if lastName(st.Condition) != "TYPE_CHECKING" {
+			return nil
+		}
+		body := s.stmts(st.Body, inClass)
+		if len(body) == 0 {
+			return nil
+		}
+		return &ast.If{Condition: st.Condition, Body: body, Span: st.Span}
+	}
+	return nil
+}
+
+// literalType returns the type of a variable's value, typing.Any unless it
+// is a literal
+func (s *stubber) literalType(value ast.Expr) ast.Expr {
+	if literal, ok := value.(*ast.Literal); ok && literal.Token.Type != lexer.Ellipsis {
+		switch literal.Value.(type) {
+		case string:
+			return stubName("str")
+		case int, int64:
+			return stubName("int")
+		case float64:
+			return stubName("float")
+		case bool:
+			return stubName("bool")
+		case []byte:
+			return stubName("bytes")
+		}
+	}
+	s.needsAny = true
+	return stubName("Any")
+}
+
+// stubFunction returns the signature of a function, its default values and
+// body replaced by ...
+func stubFunction(function *ast.Function, inClass bool) *ast.Function {
+	stub := *function
+	stub.Body = []ast.Stmt{&ast.ExprStmt{Expr: ellipsis()}}
+	if function.Parameters != nil {
+		params := *function.Parameters
+		params.Parameters = make([]*ast.Parameter, len(function.Parameters.Parameters))
+		for i, param := range function.Parameters.Parameters {
+			p := *param
+			if p.Default != nil {
+				p.Default = ellipsis()
+			}
+			params.Parameters[i] = &p
+		}
+		stub.Parameters = &params
+	}
+	if inClass && function.Name.Token.Lexeme == "__init__" && function.ReturnType == nil {
+		stub.ReturnType = stubName("None")
+	}
+	return &stub
+}
+
+// viewAttributes returns the attributes a view class sets from its annotated
+// constructor parameters
+func viewAttributes(class *ast.Class) []ast.Stmt {
+	var attributes []ast.Stmt
+	for _, stmt := range class.Body {
+		init, ok := stmt.(*ast.Function)
+		if !ok || init.Name.Token.Lexeme != "__init__" || init.Parameters == nil {
+			continue
+		}
+		for _, param := range init.Parameters.Parameters {
+			if param.Name == nil || param.Annotation == nil || param.IsStar || param.IsDoubleStar || !stubPublic(param.Name.Token.Lexeme) {
+				continue
+			}
+			attributes = append(attributes, &ast.AnnotationStmt{Target: param.Name, Type: param.Annotation, Span: param.Span})
+		}
+	}
+	return attributes
+}
+
+// isViewClass reports whether a class was compiled from a view
+func isViewClass(class *ast.Class) bool {
+	for _, arg := range class.Args {
+		if arg.Name == nil && lastName(arg.Value) == "BaseView" {
+			return true
+		}
+	}
+	return false
+}
+
+// lastName returns the last name of a name or attribute, such as TypeVar
+// for typing.TypeVar
+func lastName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Name:
+		return e.Token.Lexeme
+	case *ast.Attribute:
+		return e.Name.Lexeme
+	}
+	return ""
+}
+
+// stubPublic reports whether a name belongs in a stub: public names and
+// dunder methods such as __init__
+func stubPublic(name string) bool {
+	return !strings.HasPrefix(name, "_") || (strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__"))
+}
+
+func ellipsis() *ast.Literal {
+	return &ast.Literal{Token: lexer.Token{Lexeme: "...", Type: lexer.Ellipsis}, Type: ast.LiteralTypeNone}
+}
+
+func stubName(name string) *ast.Name {
+	return &ast.Name{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}}
+}
@@ -0,0 +1,111 @@
+package compiler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/codegen"
+)
+
+func TestCompileStub(t *testing.T) {
+	src := `from __future__ import annotations
+from typing import Optional
+
+__all__ = ["Card", "helper"]
+
+def helper(x: int, /, *, y: str = "a") -> str:
+    return str(x)
+
+def _private():
+    pass
+
+class Model:
+    """A model."""
+    name: str
+    count = 0
+    def greet(self) -> str:
+        return self.name
+    def _hidden(self):
+        pass
+
+LIMIT = 10
+DEFAULTS = {"a": 1}
+
+view Card(title: str, count: int = 0):
+    <div>
+        <h1>{title}</h1>
+        <slot name="header"><p>x</p></slot>
+        <slot />
+    </div>
+
+view Page[T](item: T, *, note: Optional[str] = None):
+    <Card title="x" />
+`
+	expected := `from typing import Any
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+from typing import Optional
+__all__ = ["Card", "helper"]
+def helper(x: int, /, *, y: str=...) -> str:
+    ...
+
+class Model:
+    name: str
+    count: int
+    def greet(self) -> str:
+        ...
+
+LIMIT: int
+DEFAULTS: Any
+class Card(BaseView):
+    title: str
+    count: int
+    children: Union[Element, BaseView, str, None]
+    header: Union[Element, BaseView, str, None]
+    def __init__(self, title: str, count: int=..., *, children: Union[Element, BaseView, str, None]=..., header: Union[Element, BaseView, str, None]=...) -> None:
+        ...
+
+class Page[T](BaseView):
+    item: T
+    note: Optional[str]
+    def __init__(self, item: T, *, note: Optional[str]=...) -> None:
+        ...
+
+`
+	compiler := NewCompilerWithOptions(nil, Options{Header: Header{Lines: []string{"# Generated from {source}"}}})
+	stub, errs := compiler.CompileStub(context.Background(), File{Name: "card.psx", Content: []byte(src)})
+	if len(errs) > 0 {
+		t.Fatalf("compile errors: %v", errs)
+	}
+	if string(stub) != "# Generated from card.psx\n"+expected {
+		t.Errorf("unexpected stub:\n%s", stub)
+	}
+
+	// Multi-file builds return a stub of every file, lowered for the target
+	dir := t.TempDir()
+	input := filepath.Join(dir, "card.psx")
+	if err := os.WriteFile(input, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{
+		RootDir: dir,
+		Files:   []string{input},
+		Options: Options{Stubs: true, Target: codegen.Py310},
+	})
+	if err != nil || len(output.Errors) > 0 {
+		t.Fatalf("compile errors: %v %v", err, output.Errors)
+	}
+	stub = output.Stubs[input]
+	if !strings.Contains(string(stub), "T = TypeVar(\"T\")\nclass Page(BaseView, Generic[T]):\n") {
+		t.Errorf("expected the stub lowered for py310, got:\n%s", stub)
+	}
+
+	// Without Options.Stubs, none are made
+	output, _ = NewMultiFileCompiler(nil).CompileProject(context.Background(), MultiFileOptions{RootDir: dir, Files: []string{input}})
+	if output.Stubs != nil {
+		t.Errorf("expected no stubs, got %v", output.Stubs)
+	}
+}
@@ -1,9 +1,11 @@
 package symbol
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
 	"github.com/fjvillamarin/topple/compiler/module"
 )
 
@@ -11,8 +13,12 @@ import (
 type Collector struct {
 	filePath       string                   // Current file being processed
 	symbols        map[string]*Symbol       // Collected symbols
+	all            []string                 // Names listed by __all__ so far; nil when undefined or not static
+	imports        []Import                 // Resolved "from ... import" statements so far
 	registry       *Registry                // Symbol registry (for re-exports)
 	moduleResolver *module.StandardResolver // Module resolver (for import paths)
+
+	bindsDeprecated bool // Whether @deprecated decorators are a function of the module rather than the marker
 }
 
 // NewCollector creates a new symbol collector
@@ -39,6 +45,9 @@ func NewCollectorWithDeps(filePath string, registry *Registry, resolver *module.
 func (c *Collector) CollectFromModule(module *ast.Module) *ModuleSymbols {
 	// Reset state
 	c.symbols = make(map[string]*Symbol)
+	c.all = nil
+	c.imports = nil
+	c.bindsDeprecated = module.BindsDeprecated()
 
 	// Visit all top-level statements
 	for _, stmt := range module.Body {
@@ -50,6 +59,8 @@ func (c *Collector) CollectFromModule(module *ast.Module) *ModuleSymbols {
 	for _, symbol := range c.symbols {
 		moduleSymbols.AddSymbol(symbol)
 	}
+	moduleSymbols.All = c.all
+	moduleSymbols.Imports = c.imports
 
 	return moduleSymbols
 }
@@ -63,9 +74,22 @@ func (c *Collector) visitStatement(stmt ast.Stmt) {
 		c.addSymbol(s.Name.Token.Lexeme, SymbolFunction, s)
 	case *ast.Class:
 		c.addSymbol(s.Name.Token.Lexeme, SymbolClass, s)
+	case *ast.Decorator:
+		// The decorated definition is exported, marked when it is deprecated
+		c.visitStatement(s.Definition())
+		if message, deprecated := s.Deprecated(); deprecated && !c.bindsDeprecated {
+			if symbol, ok := c.symbols[definitionName(s.Definition())]; ok {
+				symbol.Deprecated = true
+				symbol.DeprecationMessage = message
+			}
+		}
 	case *ast.AssignStmt:
 		// Only collect simple module-level assignments
 		c.collectAssignmentTargets(s)
+		c.collectAll(s)
+	case *ast.ExprStmt:
+		// __all__.append("x") and __all__.extend([...])
+		c.collectAllCall(s)
 	case *ast.AnnotationStmt:
 		// Type annotations at module level (e.g., x: int)
 		if name, ok := s.Target.(*ast.Name); ok {
@@ -99,6 +123,107 @@ func (c *Collector) collectAssignmentTargets(assign *ast.AssignStmt) {
 	}
 }
 
+// collectAll records the names of an assignment to __all__, including the
+// __all__ = __all__ + [...] that __all__ += [...] is parsed as. A value that
+// is not a list or tuple of strings leaves __all__ unknown, so wildcard
+// imports fall back to the public names.
+func (c *Collector) collectAll(assign *ast.AssignStmt) {
+	if len(assign.Targets) != 1 || !isAllName(assign.Targets[0]) {
+		return
+	}
+	names, ok := c.allNames(assign.Value)
+	if !ok {
+		names = nil
+	}
+	c.all = names
+}
+
+// collectAllCall records the names added by __all__.append("x") and
+// __all__.extend([...])
+func (c *Collector) collectAllCall(stmt *ast.ExprStmt) {
+	call, ok := stmt.Expr.(*ast.Call)
+	if !ok || c.all == nil || len(call.Arguments) != 1 {
+		return
+	}
+	method, ok := call.Callee.(*ast.Attribute)
+	if !ok || !isAllName(method.Object) {
+		return
+	}
+	switch method.Name.Lexeme {
+	case "append":
+		if name, ok := stringLiteral(call.Arguments[0].Value); ok {
+			c.all = append(c.all, name)
+			return
+		}
+	case "extend":
+		if names, ok := c.allNames(call.Arguments[0].Value); ok {
+			c.all = append(c.all, names...)
+			return
+		}
+	default:
+		return
+	}
+	c.all = nil
+}
+
+// allNames returns the names of a static __all__ value: a list or tuple of
+// strings, or a sum of them and __all__ itself
+func (c *Collector) allNames(expr ast.Expr) ([]string, bool) {
+	switch e := expr.(type) {
+	case *ast.ListExpr:
+		return stringLiterals(e.Elements)
+	case *ast.TupleExpr:
+		return stringLiterals(e.Elements)
+	case *ast.Binary:
+		if e.Operator.Type != lexer.Plus {
+			return nil, false
+		}
+		left, ok := c.allNames(e.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := c.allNames(e.Right)
+		if !ok {
+			return nil, false
+		}
+		return append(left, right...), true
+	case *ast.Name:
+		if isAllName(e) && c.all != nil {
+			return slices.Clone(c.all), true
+		}
+	}
+	return nil, false
+}
+
+// stringLiterals returns the values of elements that are all string literals
+func stringLiterals(elements []ast.Expr) ([]string, bool) {
+	names := make([]string, 0, len(elements))
+	for _, element := range elements {
+		name, ok := stringLiteral(element)
+		if !ok {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
+
+// stringLiteral returns the value of a string literal
+func stringLiteral(expr ast.Expr) (string, bool) {
+	literal, ok := expr.(*ast.Literal)
+	if !ok {
+		return "", false
+	}
+	value, ok := literal.Value.(string)
+	return value, ok
+}
+
+// isAllName reports whether expr is the name __all__
+func isAllName(expr ast.Expr) bool {
+	name, ok := expr.(*ast.Name)
+	return ok && name.Token.Lexeme == "__all__"
+}
+
 // collectTupleTargets extracts names from tuple unpacking
 func (c *Collector) collectTupleTargets(tuple *ast.TupleExpr, assign *ast.AssignStmt) {
 	for _, elem := range tuple.Elements {
@@ -170,9 +295,16 @@ func (c *Collector) collectReexports(stmt *ast.ImportFromStmt) {
 		return
 	}
 
-	// Handle wildcard imports (from foo import *)
+	imp := Import{File: filePath, Wildcard: stmt.IsWildcard}
+	for _, importName := range stmt.Names {
+		imp.Names = append(imp.Names, convertDottedNameToPath(importName.DottedName))
+	}
+	c.imports = append(c.imports, imp)
+
+	// Handle wildcard imports (from foo import *), which follow the
+	// __all__ of foo like Python does
 	if stmt.IsWildcard {
-		symbols, err := c.registry.GetPublicSymbols(filePath)
+		symbols, err := c.registry.GetWildcardSymbols(filePath)
 		if err != nil {
 			// Module not in registry yet - skip
 			return
@@ -210,6 +342,9 @@ func (c *Collector) collectReexports(stmt *ast.ImportFromStmt) {
 			Node:       symbol.Node,
 			Location:   extractLocation(stmt, c.filePath),
 			Visibility: determineVisibility(bindingName),
+
+			Deprecated:         symbol.Deprecated,
+			DeprecationMessage: symbol.DeprecationMessage,
 		}
 		c.symbols[bindingName] = reexportedSymbol
 	}
@@ -234,3 +369,16 @@ func convertDottedNameToPath(dottedName *ast.DottedName) string {
 	}
 	return strings.Join(parts, ".")
 }
+
+// definitionName returns the name of a view, function or class definition
+func definitionName(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.ViewStmt:
+		return s.Name.Token.Lexeme
+	case *ast.Function:
+		return s.Name.Token.Lexeme
+	case *ast.Class:
+		return s.Name.Token.Lexeme
+	}
+	return ""
+}
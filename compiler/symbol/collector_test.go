@@ -1,9 +1,12 @@
 package symbol
 
 import (
+	"slices"
+	"testing"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
-	"testing"
+	"github.com/fjvillamarin/topple/compiler/parser"
 )
 
 func createTestName(name string) *ast.Name {
@@ -468,3 +471,137 @@ func TestSymbolLocation(t *testing.T) {
 		t.Errorf("expected column 1, got %d", symbol.Location.Column)
 	}
 }
+
+func TestCollectDeprecatedDefinitions(t *testing.T) {
+	module := &ast.Module{
+		Body: []ast.Stmt{
+			&ast.Decorator{
+				Expr: &ast.Call{
+					Callee: createTestName("deprecated"),
+					Arguments: []*ast.Argument{{
+						Value: &ast.Literal{Type: ast.LiteralTypeString, Value: "use NewButton"},
+					}},
+				},
+				Stmt: &ast.ViewStmt{
+					Name:   createTestName("Button"),
+					Params: &ast.ParameterList{},
+				},
+			},
+			&ast.Decorator{
+				Expr: createTestName("cache"),
+				Stmt: &ast.Decorator{
+					Expr: createTestName("deprecated"),
+					Stmt: &ast.Function{
+						Name:       createTestName("old_helper"),
+						Parameters: &ast.ParameterList{},
+					},
+				},
+			},
+			&ast.Decorator{
+				Expr: createTestName("cache"),
+				Stmt: &ast.Function{
+					Name:       createTestName("helper"),
+					Parameters: &ast.ParameterList{},
+				},
+			},
+		},
+	}
+
+	moduleSymbols := NewCollector("/test/file.psx").CollectFromModule(module)
+
+	tests := []struct {
+		name       string
+		symbolType SymbolType
+		deprecated bool
+		message    string
+	}{
+		{"Button", SymbolView, true, "use NewButton"},
+		{"old_helper", SymbolFunction, true, ""},
+		{"helper", SymbolFunction, false, ""},
+	}
+	for _, tt := range tests {
+		symbol, exists := moduleSymbols.LookupSymbol(tt.name)
+		if !exists {
+			t.Fatalf("%s symbol not found", tt.name)
+		}
+		if symbol.Type != tt.symbolType {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.symbolType, symbol.Type)
+		}
+		if symbol.Deprecated != tt.deprecated || symbol.DeprecationMessage != tt.message {
+			t.Errorf("%s: expected deprecated=%v %q, got deprecated=%v %q",
+				tt.name, tt.deprecated, tt.message, symbol.Deprecated, symbol.DeprecationMessage)
+		}
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	collect := func(source string) *ModuleSymbols {
+		t.Helper()
+		module, errs := parser.NewParser(lexer.NewScanner([]byte(source)).ScanTokens()).Parse()
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		return NewCollector("/test/__init__.psx").CollectFromModule(module)
+	}
+	names := func(symbols []*Symbol) []string {
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+
+	moduleSymbols := collect(`__all__ = ["Button", "_helper"]
+__all__ += ("Card",)
+__all__.append("Missing")
+__all__.extend(["Badge"])
+
+def _helper():
+    pass
+
+def Button():
+    pass
+
+def Card():
+    pass
+
+def Badge():
+    pass
+
+def Hidden():
+    pass
+`)
+	if want := []string{"Button", "_helper", "Card", "Missing", "Badge"}; !slices.Equal(moduleSymbols.All, want) {
+		t.Errorf("expected __all__ %v, got %v", want, moduleSymbols.All)
+	}
+	// Listed names keep their order; names the module does not define are left out
+	if got, want := names(moduleSymbols.GetWildcardSymbols()), []string{"Button", "_helper", "Card", "Badge"}; !slices.Equal(got, want) {
+		t.Errorf("expected wildcard symbols %v, got %v", want, got)
+	}
+
+	// An __all__ that is not a static list leaves the public names
+	moduleSymbols = collect(`__all__ = ["Button"] + compute()
+
+def Button():
+    pass
+
+def Card():
+    pass
+
+def _helper():
+    pass
+`)
+	if moduleSymbols.All != nil {
+		t.Errorf("expected unknown __all__, got %v", moduleSymbols.All)
+	}
+	got := names(moduleSymbols.GetWildcardSymbols())
+	slices.Sort(got)
+	if want := []string{"Button", "Card"}; !slices.Equal(got, want) {
+		t.Errorf("expected public wildcard symbols %v, got %v", want, got)
+	}
+
+	// An empty __all__ exports nothing
+	if moduleSymbols := collect("__all__ = []\n\ndef Button():\n    pass\n"); moduleSymbols.All == nil || len(moduleSymbols.GetWildcardSymbols()) != 0 {
+		t.Errorf("expected an empty __all__ to export nothing, got %v", moduleSymbols.All)
+	}
+}
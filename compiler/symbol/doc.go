@@ -4,7 +4,9 @@
 // classes, variables) from parsed PSX modules. It supports:
 //   - Symbol collection from AST nodes
 //   - Symbol lookup by module path and name
-//   - Wildcard import expansion (all public symbols)
+//   - Wildcard import expansion (the names of __all__, or all public symbols)
+//   - Re-exports through "from .x import Name" and "from .x import *",
+//     followed transitively when modules are registered in dependency order
 //   - Symbol visibility rules (public vs private)
 //
 // # Usage
@@ -19,6 +21,21 @@
 //	// Lookup symbols
 //	symbol, err := registry.LookupSymbol(filePath, "MyView")
 //	publicSymbols, err := registry.GetPublicSymbols(filePath)
+//	wildcardSymbols, err := registry.GetWildcardSymbols(filePath)
+//
+// # Incremental Updates
+//
+// After an edit, a long-running process such as the language server
+// re-collects the edited file and passes its symbols to Update instead of
+// rebuilding the registry. Update compares them with the registered ones,
+// ignoring definitions that only moved, and returns the names that were
+// added, removed or changed along with the modules importing them, whose
+// resolutions are stale:
+//
+//	change := registry.Update(filePath, collector.CollectFromModule(edited))
+//	for _, dependent := range change.Invalidated {
+//		// re-resolve dependent, and Update it in turn if it re-exports
+//	}
 //
 // # Integration
 //
@@ -26,6 +26,13 @@ func (r *Registry) RegisterModule(filePath string, symbols *ModuleSymbols) {
 	r.modules[filePath] = symbols
 }
 
+// RemoveModule unregisters a module, such as a file deleted from the project
+func (r *Registry) RemoveModule(filePath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.modules, filePath)
+}
+
 // GetModuleSymbols retrieves all symbols from a module
 func (r *Registry) GetModuleSymbols(filePath string) (*ModuleSymbols, error) {
 	r.mu.RLock()
@@ -61,6 +68,16 @@ func (r *Registry) GetPublicSymbols(filePath string) ([]*Symbol, error) {
 	return moduleSymbols.GetPublicSymbols(), nil
 }
 
+// GetWildcardSymbols returns the symbols a wildcard import of a module binds,
+// honoring its __all__
+func (r *Registry) GetWildcardSymbols(filePath string) ([]*Symbol, error) {
+	moduleSymbols, err := r.GetModuleSymbols(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return moduleSymbols.GetWildcardSymbols(), nil
+}
+
 // HasModule checks if a module is registered
 func (r *Registry) HasModule(filePath string) bool {
 	r.mu.RLock()
@@ -142,6 +142,29 @@ func TestGetPublicSymbols(t *testing.T) {
 	}
 }
 
+func TestGetWildcardSymbols(t *testing.T) {
+	registry := NewRegistry()
+
+	moduleSymbols := NewModuleSymbols("/test/module.psx")
+	moduleSymbols.AddSymbol(&Symbol{Name: "PublicView", Type: SymbolView, Visibility: Public})
+	moduleSymbols.AddSymbol(&Symbol{Name: "OtherView", Type: SymbolView, Visibility: Public})
+	moduleSymbols.AddSymbol(&Symbol{Name: "_private_view", Type: SymbolView, Visibility: Private})
+	moduleSymbols.All = []string{"_private_view", "PublicView"}
+	registry.RegisterModule("/test/module.psx", moduleSymbols)
+
+	symbols, err := registry.GetWildcardSymbols("/test/module.psx")
+	if err != nil {
+		t.Fatalf("GetWildcardSymbols() error = %v", err)
+	}
+	if len(symbols) != 2 || symbols[0].Name != "_private_view" || symbols[1].Name != "PublicView" {
+		t.Errorf("expected the symbols listed by __all__, got %v", symbols)
+	}
+
+	if _, err := registry.GetWildcardSymbols("/test/missing.psx"); err == nil {
+		t.Error("expected an error for an unregistered module")
+	}
+}
+
 func TestClear(t *testing.T) {
 	registry := NewRegistry()
 
@@ -159,6 +182,21 @@ func TestClear(t *testing.T) {
 	}
 }
 
+func TestRemoveModule(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterModule("/test/kept.psx", NewModuleSymbols("/test/kept.psx"))
+	registry.RegisterModule("/test/removed.psx", NewModuleSymbols("/test/removed.psx"))
+
+	registry.RemoveModule("/test/removed.psx")
+
+	if registry.HasModule("/test/removed.psx") {
+		t.Error("module still registered after RemoveModule()")
+	}
+	if !registry.HasModule("/test/kept.psx") {
+		t.Error("RemoveModule() removed another module")
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	registry := NewRegistry()
 
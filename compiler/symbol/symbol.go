@@ -58,6 +58,11 @@ type Symbol struct {
 	Location   Location   // Source location
 	Visibility Visibility // Public or private
 	Docstring  string     // Documentation (for future use)
+
+	// Deprecated is set by the @deprecated marker, whose message usually
+	// names the replacement
+	Deprecated         bool
+	DeprecationMessage string
 }
 
 // Location represents a position in source code
@@ -71,6 +76,16 @@ type Location struct {
 type ModuleSymbols struct {
 	FilePath string             // Absolute file path
 	Symbols  map[string]*Symbol // Symbol name -> Symbol
+	All      []string           // Names listed by __all__, in order; nil when the module does not define it
+	Imports  []Import           // Modules the module imports names from, resolved by a collector with dependencies
+}
+
+// Import is a "from module import ..." statement of a module, whose
+// resolution depends on the symbols of the imported file
+type Import struct {
+	File     string   // Absolute path of the imported module
+	Names    []string // Imported names, before any "as"
+	Wildcard bool     // from module import *
 }
 
 // NewModuleSymbols creates a new ModuleSymbols
@@ -103,6 +118,23 @@ func (ms *ModuleSymbols) GetPublicSymbols() []*Symbol {
 	return public
 }
 
+// GetWildcardSymbols returns the symbols bound by "from module import *":
+// the names listed by __all__, private ones included, or all public symbols
+// when the module does not define __all__. Listed names the module does not
+// define are left out.
+func (ms *ModuleSymbols) GetWildcardSymbols() []*Symbol {
+	if ms.All == nil {
+		return ms.GetPublicSymbols()
+	}
+	symbols := make([]*Symbol, 0, len(ms.All))
+	for _, name := range ms.All {
+		if symbol, exists := ms.Symbols[name]; exists {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
 // GetAllSymbols returns all symbols (public and private)
 func (ms *ModuleSymbols) GetAllSymbols() []*Symbol {
 	symbols := make([]*Symbol, 0, len(ms.Symbols))
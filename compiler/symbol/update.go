@@ -0,0 +1,220 @@
+package symbol
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// Change describes how the symbols of a module changed in an Update. A
+// symbol that only moved within the file is not a change.
+type Change struct {
+	Added       []string // Names the module now defines, sorted
+	Removed     []string // Names the module no longer defines, sorted
+	Changed     []string // Names whose kind, visibility, deprecation or signature changed, sorted
+	AllChanged  bool     // The module's __all__ changed, and with it what "import *" binds
+	Invalidated []string // Registered modules importing an affected name, whose resolutions are stale, sorted
+}
+
+// Empty reports whether the update changed nothing other modules can see
+func (c Change) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0 && !c.AllChanged
+}
+
+// Update replaces the symbols of a module, as after an edit of its file, and
+// returns what changed and the modules whose resolutions it invalidates:
+// those importing an added, removed or changed name, or importing * when
+// the names it binds changed. Nil symbols remove the module. Only direct
+// importers are returned; re-collecting them and updating them in turn
+// reaches the modules re-exporting through them.
+func (r *Registry) Update(filePath string, symbols *ModuleSymbols) Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.modules[filePath]
+	if symbols == nil {
+		delete(r.modules, filePath)
+		symbols = NewModuleSymbols(filePath)
+	} else {
+		r.modules[filePath] = symbols
+	}
+	if previous == nil {
+		previous = NewModuleSymbols(filePath)
+	}
+
+	var change Change
+	affected := make(map[string]bool)
+	for name, symbol := range symbols.Symbols {
+		old, existed := previous.Symbols[name]
+		switch {
+		case !existed:
+			change.Added = append(change.Added, name)
+		case !sameSymbol(old, symbol):
+			change.Changed = append(change.Changed, name)
+		default:
+			continue
+		}
+		affected[name] = true
+	}
+	for name := range previous.Symbols {
+		if _, exists := symbols.Symbols[name]; !exists {
+			change.Removed = append(change.Removed, name)
+			affected[name] = true
+		}
+	}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	sort.Strings(change.Changed)
+	change.AllChanged = !slices.Equal(previous.All, symbols.All) || (previous.All == nil) != (symbols.All == nil)
+
+	wildcardChanged := change.AllChanged
+	for name := range affected {
+		if wildcardBinds(previous, name) || wildcardBinds(symbols, name) {
+			wildcardChanged = true
+			break
+		}
+	}
+
+	for path, module := range r.modules {
+		if path == filePath {
+			continue
+		}
+		for _, imp := range module.Imports {
+			if imp.File == filePath && (imp.Wildcard && wildcardChanged || slices.ContainsFunc(imp.Names, func(name string) bool { return affected[name] })) {
+				change.Invalidated = append(change.Invalidated, path)
+				break
+			}
+		}
+	}
+	sort.Strings(change.Invalidated)
+	return change
+}
+
+// wildcardBinds reports whether "from module import *" binds name
+func wildcardBinds(module *ModuleSymbols, name string) bool {
+	if module.All != nil {
+		return slices.Contains(module.All, name)
+	}
+	return determineVisibility(name) == Public
+}
+
+// sameSymbol reports whether two versions of a symbol look the same to the
+// modules importing it
+func sameSymbol(a, b *Symbol) bool {
+	return a.Type == b.Type &&
+		a.Visibility == b.Visibility &&
+		a.Deprecated == b.Deprecated &&
+		a.DeprecationMessage == b.DeprecationMessage &&
+		signature(a.Node) == signature(b.Node)
+}
+
+// signature describes what importers check a definition against: the
+// parameters of a function, and those of a view with the slots its
+// compositions fill
+func signature(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.ViewStmt:
+		return parameterSignature(n.Params) + " " + strings.Join(slotNames(n.Body), ",")
+	case *ast.Function:
+		return parameterSignature(n.Parameters)
+	}
+	return ""
+}
+
+// parameterSignature writes the names and kinds of parameters, marking those
+// with an annotation or a default
+func parameterSignature(params *ast.ParameterList) string {
+	if params == nil {
+		return "()"
+	}
+	var b strings.Builder
+	b.WriteString("(")
+	for _, param := range params.Parameters {
+		if param == nil {
+			continue
+		}
+		switch {
+		case param.IsSlash:
+			b.WriteString("/")
+		case param.IsStar:
+			b.WriteString("*")
+		case param.IsDoubleStar:
+			b.WriteString("**")
+		}
+		if param.Name != nil {
+			b.WriteString(param.Name.Token.Lexeme)
+		}
+		if param.Annotation != nil {
+			b.WriteString(":")
+		}
+		if param.Default != nil {
+			b.WriteString("=")
+		}
+		b.WriteString(",")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// slotNames returns the names of the <slot> elements of a view body, in
+// order, "" for the default slot and "{}" for a computed name, with the
+// values a scoped slot passes: row(row,index,)
+func slotNames(body []ast.Stmt) []string {
+	var names []string
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.HTMLElement:
+			if s.TagName.Lexeme != "slot" {
+				names = append(names, slotNames(s.Content)...)
+				continue
+			}
+			name, values := "", ""
+			for _, attr := range s.Attributes {
+				switch {
+				case attr.Spread:
+					values += "**,"
+				case attr.Name.Lexeme == "name":
+					if literal, ok := attr.Value.(*ast.Literal); ok {
+						name, _ = literal.Value.(string)
+					} else {
+						name = "{}"
+					}
+				case attr.Name.Lexeme != "slot":
+					values += attr.Name.Lexeme + ","
+				}
+			}
+			if values != "" {
+				name += "(" + values + ")"
+			}
+			names = append(names, name)
+			names = append(names, slotNames(s.Content)...)
+		case *ast.For:
+			names = append(names, slotNames(s.Body)...)
+			names = append(names, slotNames(s.Else)...)
+		case *ast.While:
+			names = append(names, slotNames(s.Body)...)
+			names = append(names, slotNames(s.Else)...)
+		case *ast.If:
+			names = append(names, slotNames(s.Body)...)
+			names = append(names, slotNames(s.Else)...)
+		case *ast.With:
+			names = append(names, slotNames(s.Body)...)
+		case *ast.Try:
+			names = append(names, slotNames(s.Body)...)
+			for _, except := range s.Excepts {
+				names = append(names, slotNames(except.Body)...)
+			}
+			names = append(names, slotNames(s.Else)...)
+			names = append(names, slotNames(s.Finally)...)
+		case *ast.MatchStmt:
+			for _, c := range s.Cases {
+				names = append(names, slotNames(c.Body)...)
+			}
+		case *ast.MultiStmt:
+			names = append(names, slotNames(s.Stmts)...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,211 @@
+package symbol
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/parser"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+func TestRegistryUpdate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"ui.psx": `view Button(label):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot /></div>
+
+def _helper():
+    pass
+`,
+		"page.psx": `from .ui import Button
+`,
+		"layout.psx": `from .ui import Card
+`,
+		"all.psx": `from .ui import *
+`,
+	}
+	for name, source := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	resolver := module.NewResolver(module.Config{RootDir: dir, FileSystem: filesystem.NewFileSystem(nil)})
+	registry := NewRegistry()
+	collect := func(name, source string) *ModuleSymbols {
+		t.Helper()
+		mod, errs := parser.NewParser(lexer.NewScanner([]byte(source)).ScanTokens()).Parse()
+		if len(errs) > 0 {
+			t.Fatalf("parse errors in %s: %v", name, errs)
+		}
+		return NewCollectorWithDeps(path(name), registry, resolver).CollectFromModule(mod)
+	}
+
+	// A first update registers every symbol as added
+	change := registry.Update(path("ui.psx"), collect("ui.psx", files["ui.psx"]))
+	if want := []string{"Button", "Card", "_helper"}; !slices.Equal(change.Added, want) {
+		t.Errorf("expected added %v, got %v", want, change.Added)
+	}
+	for _, name := range []string{"page.psx", "layout.psx", "all.psx"} {
+		symbols := collect(name, files[name])
+		if len(symbols.Imports) != 1 || symbols.Imports[0].File != path("ui.psx") {
+			t.Fatalf("%s: expected an import of ui.psx, got %+v", name, symbols.Imports)
+		}
+		registry.Update(path(name), symbols)
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		added       []string
+		removed     []string
+		changed     []string
+		invalidated []string
+	}{
+		{
+			name: "moved definitions",
+			source: `def _helper():
+    pass
+
+view Card(title):
+    <div>{title}<slot /></div>
+
+
+view Button(label):
+    <button class="btn">{label}</button>
+`,
+		},
+		{
+			name: "new parameter",
+			source: `view Button(label, variant="primary"):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot /></div>
+
+def _helper():
+    pass
+`,
+			changed:     []string{"Button"},
+			invalidated: []string{"all.psx", "page.psx"},
+		},
+		{
+			name: "named slot",
+			source: `view Button(label, variant="primary"):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot name="footer" /></div>
+
+def _helper():
+    pass
+`,
+			changed:     []string{"Card"},
+			invalidated: []string{"all.psx", "layout.psx"},
+		},
+		{
+			name: "scoped slot",
+			source: `view Button(label, variant="primary"):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot name="footer" count={count} /></div>
+
+def _helper():
+    pass
+`,
+			changed:     []string{"Card"},
+			invalidated: []string{"all.psx", "layout.psx"},
+		},
+		{
+			name: "private helper",
+			source: `view Button(label, variant="primary"):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot name="footer" count={count} /></div>
+
+def _helper(x):
+    pass
+
+_cache = {}
+`,
+			added:   []string{"_cache"},
+			changed: []string{"_helper"},
+		},
+		{
+			name: "__all__",
+			source: `__all__ = ["Button"]
+
+view Button(label, variant="primary"):
+    <button>{label}</button>
+
+view Card(title):
+    <div>{title}<slot name="footer" count={count} /></div>
+
+def _helper(x):
+    pass
+
+_cache = {}
+`,
+			added:       []string{"__all__"},
+			invalidated: []string{"all.psx"},
+		},
+		{
+			name: "removed view",
+			source: `__all__ = ["Button"]
+
+view Button(label, variant="primary"):
+    <button>{label}</button>
+
+def _helper(x):
+    pass
+
+_cache = {}
+`,
+			removed:     []string{"Card"},
+			invalidated: []string{"layout.psx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := registry.Update(path("ui.psx"), collect("ui.psx", tt.source))
+			if !slices.Equal(change.Added, tt.added) {
+				t.Errorf("expected added %v, got %v", tt.added, change.Added)
+			}
+			if !slices.Equal(change.Removed, tt.removed) {
+				t.Errorf("expected removed %v, got %v", tt.removed, change.Removed)
+			}
+			if !slices.Equal(change.Changed, tt.changed) {
+				t.Errorf("expected changed %v, got %v", tt.changed, change.Changed)
+			}
+			var invalidated []string
+			for _, file := range tt.invalidated {
+				invalidated = append(invalidated, path(file))
+			}
+			if !slices.Equal(change.Invalidated, invalidated) {
+				t.Errorf("expected invalidated %v, got %v", invalidated, change.Invalidated)
+			}
+		})
+	}
+
+	// Removing the module invalidates the modules importing its remaining names
+	change = registry.Update(path("ui.psx"), nil)
+	if registry.HasModule(path("ui.psx")) {
+		t.Error("expected ui.psx to be removed")
+	}
+	if !change.AllChanged {
+		t.Error("expected __all__ to change")
+	}
+	if want := []string{path("all.psx"), path("page.psx")}; !slices.Equal(change.Invalidated, want) {
+		t.Errorf("expected invalidated %v, got %v", want, change.Invalidated)
+	}
+}
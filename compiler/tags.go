@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WriteCtags writes the definitions of the index as an extended-format
+// ctags file, sorted by name. File names are relative to baseDir, usually the
+// directory the tags file is written to.
+func (x *ProjectIndex) WriteCtags(w io.Writer, baseDir string) error {
+	sources := sourceCache{}
+	var lines []string
+	for _, def := range x.Definitions {
+		src, err := sources.read(def.File)
+		if err != nil {
+			return err
+		}
+		file := def.File
+		if rel, err := filepath.Rel(baseDir, def.File); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+		line := fmt.Sprintf("%s\t%s\t/^%s$/;\"\tkind:%s\tline:%d",
+			def.Name, file, ctagsPattern(sourceLine(src, def.Span.Start.Line)), def.Kind, def.Span.Start.Line)
+		if def.Private {
+			line += "\taccess:private"
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/\n")
+	fmt.Fprintf(out, "!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/\n")
+	fmt.Fprintf(out, "!_TAG_PROGRAM_NAME\ttopple\t//\n")
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	return out.Flush()
+}
+
+// ctagsPattern escapes a source line for a /^...$/ search pattern
+func ctagsPattern(line string) string {
+	return strings.NewReplacer(`\`, `\\`, `/`, `\/`).Replace(line)
+}
+
+// sourceCache reads each source file of an index once
+type sourceCache map[string][]byte
+
+func (c sourceCache) read(path string) ([]byte, error) {
+	if src, ok := c[path]; ok {
+		return src, nil
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	c[path] = src
+	return src, nil
+}
+
+// sourceLine returns the one-based line of src without its line ending
+func sourceLine(src []byte, line int) string {
+	lines := strings.SplitN(string(src), "\n", line+1)
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line-1], "\r")
+}
@@ -1 +1 @@
-COMPILATION_ERRORS: [at 'f': expected string, number, boolean, or expression for attribute value (position L4:6-L6:16)]
\ No newline at end of file
+COMPILATION_ERRORS: [at 'f': expected string, number, boolean, or expression for attribute value (position L5:15-L5:16)]
\ No newline at end of file
@@ -0,0 +1,19 @@
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api, style_value
+require_api(3)
+class Toolbar(BaseView):
+    def __init__(self, on_save, busy: bool, active: str, width: int) -> None:
+        super().__init__()
+        self.on_save = on_save
+        self.busy = busy
+        self.active = active
+        self.width = width
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("button", "Save", {"onclick": self.on_save, "disabled": bool(self.busy), "hidden": True}))
+        _div_children_2000.append(el("input", "", {"type": "checkbox", "checked": bool(self.active == "all"), "readonly": False}))
+        _div_children_2000.append(el("a", "Link", {"class": class_names(self.active), "onmouseover": "highlight()"}))
+        _root_children_1000.append(el("div", _div_children_2000, {"class": class_names(["toolbar", {"is-busy": self.busy}]), "style": style_value({"width": f"{self.width}px", "color": None})}))
+        return fragment(_root_children_1000)
+
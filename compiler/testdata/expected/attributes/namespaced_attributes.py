@@ -0,0 +1,16 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Icon(BaseView):
+    def __init__(self, href: str, label: str) -> None:
+        super().__init__()
+        self.href = href
+        self.label = label
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _svg_children_2000 = []
+        _svg_children_2000.append(el("title", escape(self.label), {"xml:lang": "en"}))
+        _svg_children_2000.append(el("use", "", {"xlink:href": escape(self.href)}))
+        _root_children_1000.append(el("svg", _svg_children_2000, {"xmlns": "http://www.w3.org/2000/svg", "xmlns:xlink": "http://www.w3.org/1999/xlink", "viewBox": "0 0 24 24"}))
+        return fragment(_root_children_1000)
+
@@ -0,0 +1,29 @@
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
+class Button(BaseView):
+    def __init__(self, label: str, variant: str="primary", disabled: bool=False) -> None:
+        super().__init__()
+        self.label = label
+        self.variant = variant
+        self.disabled = disabled
+
+    def _render(self) -> Element:
+        return el("button", escape(self.label), {"class": class_names(f"btn btn-{self.variant}"), "disabled": bool(self.disabled)})
+
+class SpreadAttributes(BaseView):
+    def __init__(self, is_admin: bool, extra: dict, button_props: dict) -> None:
+        super().__init__()
+        self.is_admin = is_admin
+        self.extra = extra
+        self.button_props = button_props
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        admin_attrs = {"data-role": "admin", "aria-label": "Admin panel"}
+        _section_children_2000 = []
+        _section_children_2000.append(el("a", "Home", {"href": "/", **({"target": "_blank", "rel": "noopener"} if self.is_admin else {})}))
+        _section_children_2000.append(Button(**{"label": "Save", **({"variant": "danger"} if self.is_admin else {})}))
+        _section_children_2000.append(Button(**{**self.button_props, "disabled": True}))
+        _root_children_1000.append(el("section", _section_children_2000, {"id": "panel", **self.extra, **(admin_attrs if self.is_admin else {})}))
+        return fragment(_root_children_1000)
+
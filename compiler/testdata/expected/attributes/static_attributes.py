@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class StaticAttributes(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
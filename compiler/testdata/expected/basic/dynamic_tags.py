@@ -0,0 +1,25 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Heading(BaseView):
+    def __init__(self, level: int=2, text: str="") -> None:
+        super().__init__()
+        self.level = level
+        self.text = text
+
+    def _render(self) -> Element:
+        return el(f"h{self.level}", escape(self.text), {"class": "heading"})
+
+class Box(BaseView):
+    def __init__(self, tag: str="div", items: list=[]) -> None:
+        super().__init__()
+        self.tag = tag
+        self.items = items
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _element_children_2000 = []
+        for item in self.items:
+            _element_children_2000.append(el("p", escape(item)))
+        _root_children_1000.append(el(self.tag, _element_children_2000, {"class": "box"}))
+        return fragment(_root_children_1000)
+
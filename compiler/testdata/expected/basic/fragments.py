@@ -0,0 +1,41 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Cells(BaseView):
+    def __init__(self, name: str, count: int) -> None:
+        super().__init__()
+        self.name = name
+        self.count = count
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _fragment_children_2000 = []
+        _fragment_children_2000.append(el("td", escape(self.name)))
+        _fragment_children_2000.append(el("td", escape(self.count)))
+        _root_children_1000.append(fragment(_fragment_children_2000))
+        return fragment(_root_children_1000)
+
+class Fragments(BaseView):
+    def __init__(self, items: list) -> None:
+        super().__init__()
+        self.items = items
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        _table_children_4000 = []
+        for item in self.items:
+            _table_children_4000.append(el("tr", Cells(name=item, count=len(item))))
+        _root_children_3000.append(el("table", _table_children_4000))
+        _ul_children_5000 = []
+        _fragment_children_6000 = []
+        _fragment_children_6000.append(el("li", "first"))
+        _fragment_children_6000.append(el("li", "second"))
+        _ul_children_5000.append(fragment(_fragment_children_6000))
+        if self.items:
+            _fragment_children_7000 = []
+            _fragment_children_7000.append(el("li", escape(self.items[0])))
+            _fragment_children_7000.append(el("li", f"and {escape(len(self.items) - 1)} more"))
+            _ul_children_5000.append(fragment(_fragment_children_7000))
+        _root_children_3000.append(el("ul", _ul_children_5000))
+        _root_children_3000.append(fragment([]))
+        return fragment(_root_children_3000)
+
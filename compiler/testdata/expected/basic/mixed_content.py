@@ -1,4 +1,5 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 from datetime import datetime
 count = 0
 def increment():
@@ -7,7 +8,7 @@ def increment():
     return count
 
 class Counter(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
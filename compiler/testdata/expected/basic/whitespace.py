@@ -0,0 +1,20 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class CodeSample(BaseView):
+    def __init__(self, title: str, code: str) -> None:
+        super().__init__()
+        self.title = title
+        self.code = code
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _figure_children_2000 = []
+        _figure_children_2000.append(el("figcaption", f"{escape(self.title)}: sample"))
+        _figure_children_2000.append(el("pre", el("code", f"\ndef   greet(name):\n    return {escape(self.code)}\n")))
+        _figure_children_2000.append(el("textarea", "\n  first line\n  second line", {"name": "notes"}))
+        _figure_children_2000.append(el("style", raw("figure > pre { margin: 0 }")))
+        _figure_children_2000.append(el("script", raw("\n            if (window.innerWidth < 600) { document.body.classList.add(\"narrow\"); }\n        ")))
+        _figure_children_2000.append(el("p", "Spaces and tabs are collapsed"))
+        _root_children_1000.append(el("figure", _figure_children_2000))
+        return fragment(_root_children_1000)
+
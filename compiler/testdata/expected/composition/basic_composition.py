@@ -1,15 +1,16 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
 class Button(BaseView):
-    def __init__(self, text: str, variant: str="primary"):
+    def __init__(self, text: str, variant: str="primary") -> None:
         super().__init__()
         self.text = text
         self.variant = variant
 
     def _render(self) -> Element:
-        return el("button", escape(self.text), {"class": escape(f"btn btn-{self.variant}")})
+        return el("button", escape(self.text), {"class": class_names(f"btn btn-{self.variant}")})
 
 class Card(BaseView):
-    def __init__(self, title: str):
+    def __init__(self, title: str) -> None:
         super().__init__()
         self.title = title
 
@@ -22,7 +23,7 @@ class Card(BaseView):
         return fragment(_root_children_1000)
 
 class App(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
@@ -1,14 +1,15 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
 class Icon(BaseView):
-    def __init__(self, name: str):
+    def __init__(self, name: str) -> None:
         super().__init__()
         self.name = name
 
     def _render(self) -> Element:
-        return el("i", "", {"class": escape(f"icon icon-{self.name}")})
+        return el("i", "", {"class": class_names(f"icon icon-{self.name}")})
 
 class Button(BaseView):
-    def __init__(self, text: str, icon: str=""):
+    def __init__(self, text: str, icon: str="") -> None:
         super().__init__()
         self.text = text
         self.icon = icon
@@ -23,7 +24,7 @@ class Button(BaseView):
         return fragment(_root_children_1000)
 
 class Toolbar(BaseView):
-    def __init__(self, title: str):
+    def __init__(self, title: str) -> None:
         super().__init__()
         self.title = title
 
@@ -40,7 +41,7 @@ class Toolbar(BaseView):
         return fragment(_root_children_3000)
 
 class Page(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
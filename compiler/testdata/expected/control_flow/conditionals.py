@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class ConditionalView(BaseView):
-    def __init__(self, user_type: str, is_admin: bool=False):
+    def __init__(self, user_type: str, is_admin: bool=False) -> None:
         super().__init__()
         self.user_type = user_type
         self.is_admin = is_admin
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class EarlyReturnView(BaseView):
-    def __init__(self, items: list, show_empty: bool=True):
+    def __init__(self, items: list, show_empty: bool=True) -> None:
         super().__init__()
         self.items = items
         self.show_empty = show_empty
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Greeting(BaseView):
-    def __init__(self, is_admin: bool, name: str):
+    def __init__(self, is_admin: bool, name: str) -> None:
         super().__init__()
         self.is_admin = is_admin
         self.name = name
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class LoopView(BaseView):
-    def __init__(self, items: list, max_count: int=10):
+    def __init__(self, items: list, max_count: int=10) -> None:
         super().__init__()
         self.items = items
         self.max_count = max_count
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class MatchView(BaseView):
-    def __init__(self, status: str, data: dict):
+    def __init__(self, status: str, data: dict) -> None:
         super().__init__()
         self.status = status
         self.data = data
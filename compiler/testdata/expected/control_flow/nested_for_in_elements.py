@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Table(BaseView):
-    def __init__(self, rows: list):
+    def __init__(self, rows: list) -> None:
         super().__init__()
         self.rows = rows
 
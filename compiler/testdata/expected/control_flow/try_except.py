@@ -1,11 +1,12 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 def risky_operation(value):
     if value < 0:
         raise ValueError("Negative value not allowed")
     return value * 2
 
 class ErrorHandlingView(BaseView):
-    def __init__(self, input_value: int):
+    def __init__(self, input_value: int) -> None:
         super().__init__()
         self.input_value = input_value
 
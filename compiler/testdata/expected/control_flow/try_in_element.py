@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class SafeDisplay(BaseView):
-    def __init__(self, value: str):
+    def __init__(self, value: str) -> None:
         super().__init__()
         self.value = value
 
@@ -0,0 +1,60 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Page(BaseView):
+    def __init__(self, show_main: bool) -> None:
+        super().__init__()
+        self.show_main = show_main
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _root_children_1000.append(el("header", "Header"))
+        _main_children_2000 = []
+        if not self.show_main:
+            return fragment(_root_children_1000)
+        _main_children_2000.append(el("p", "Main content"))
+        _root_children_1000.append(el("main", _main_children_2000))
+        return fragment(_root_children_1000)
+
+class Logged(BaseView):
+    def __init__(self, message: str) -> None:
+        super().__init__()
+        self.message = message
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        _root_children_3000.append(el("p", escape(self.message)))
+        print("rendered", self.message)
+        return fragment(_root_children_3000)
+
+class Setup(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_4000 = []
+        config = {"debug": True}
+        return fragment(_root_children_4000)
+
+class Delegate(BaseView):
+    def __init__(self, content) -> None:
+        super().__init__()
+        self.content = content
+
+    def _render(self) -> Element:
+        _root_children_5000 = []
+        if self.content is None:
+            _root_children_5000.append(el("p", "Nothing to show"))
+            return fragment(_root_children_5000)
+        return self.content
+
+class Doubled(BaseView):
+    def __init__(self, n: int) -> None:
+        super().__init__()
+        self.n = n
+
+    def _render(self) -> Element:
+        def double(value):
+            return value * 2
+
+        return el("span", escape(double(self.n)))
+
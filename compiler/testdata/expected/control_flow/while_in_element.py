@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Counter(BaseView):
-    def __init__(self, start: int, end: int):
+    def __init__(self, start: int, end: int) -> None:
         super().__init__()
         self.start = start
         self.end = end
@@ -0,0 +1 @@
+COMPILATION_ERRORS: [failed to transform view Broken: cannot evaluate icon_path() at L7:15-L7:32 at compile time: KeyError: 'chek' at L4:31-L4:42]
\ No newline at end of file
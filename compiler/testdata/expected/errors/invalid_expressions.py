@@ -1 +1 @@
-COMPILATION_ERRORS: [Error: string literal cannot span newline at position L12:37]
\ No newline at end of file
+COMPILATION_ERRORS: [Error: string literal cannot span newline at position L12:37 (in interpolation mode since L12:22)]
\ No newline at end of file
@@ -1 +1 @@
-COMPILATION_ERRORS: [failed to transform view ViewWithNestedContent: view element 'Card' cannot have nested content]
\ No newline at end of file
+COMPILATION_ERRORS: [failed to transform view BadSlots: slot name 'double-nested' of view BadSlots is not a valid Python identifier]
\ No newline at end of file
@@ -1,2 +1,3 @@
 COMPILATION_ERRORS: [at '
-': expected closing tag (position L5:30-L6:1)]
\ No newline at end of file
+': missing closing tag: expected closing tag </p> for <p> opened at L5:10 (position L5:30-L6:1) at '
+': unexpected token (position L8:13-L9:1) at '/': unexpected token (position L11:38-L11:39)]
\ No newline at end of file
@@ -1,2 +1,4 @@
 COMPILATION_ERRORS: [at '
-': expected closing tag (position L3:33-L4:1)]
\ No newline at end of file
+': missing closing tag: expected closing tag </h1> for <h1> opened at L3:10 (position L3:33-L4:1) at '
+': missing closing tag: expected closing tag </p> for <p> opened at L4:10 (position L4:40-L5:1) at '
+': missing closing tag: expected closing tag </span> for <span> opened at L6:14 (position L6:43-L7:1) at '': expected closing tag </div> for <div> opened at L5:10 (position L8:21-L8:21) at '': expected closing tag </div> for <div> opened at L2:6 (position L8:21-L8:21)]
\ No newline at end of file
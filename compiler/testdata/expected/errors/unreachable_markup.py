@@ -0,0 +1 @@
+COMPILATION_ERRORS: [failed to transform view Unreachable: unreachable markup at L6:9-L6:24: it follows the return at L5:9-L5:15]
\ No newline at end of file
@@ -1 +1 @@
-COMPILATION_ERRORS: [failed to transform view InvalidNestedContent: view element 'Card' cannot have nested content]
\ No newline at end of file
+COMPILATION_ERRORS: [failed to transform view InvalidNestedContent: slot attributes can only be used on direct children of view elements, not nested within control structures or other elements. Found slot attribute nested within <Card> view: found slot attribute inside control structure]
\ No newline at end of file
@@ -0,0 +1 @@
+COMPILATION_ERRORS: [<input> is a void element and cannot have content (position L4:9-L4:43) <hr> is a void element and cannot have content (position L6:5-L6:21)]
\ No newline at end of file
@@ -0,0 +1,32 @@
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
+def icon_path(name: str, size: int=16) -> str:
+    icons = {"check": "check.svg", "close": "x.svg"}
+    if name not in icons:
+        raise ValueError(f"unknown icon {name!r}")
+    return f"/static/icons/{size}/{icons[name]}"
+
+def spacing(steps):
+    scale = [0, 4, 8, 12, 16]
+    classes = []
+    for step in steps:
+        classes.append("p-" + str(scale[step]))
+    return " ".join(classes)
+
+def ratio(width, height):
+    return width / height
+
+class Toolbar(BaseView):
+    def __init__(self, selected: str) -> None:
+        super().__init__()
+        self.selected = selected
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _nav_children_2000 = []
+        _nav_children_2000.append(el("img", "", {"src": escape("/static/icons/16/check.svg")}))
+        _nav_children_2000.append(el("img", "", {"src": escape("/static/icons/24/x.svg")}))
+        _nav_children_2000.append(el("img", "", {"src": escape(icon_path(self.selected))}))
+        _root_children_1000.append(el("nav", _nav_children_2000, {"class": class_names("p-4 p-8"), "data-ratio": escape(2.0)}))
+        return fragment(_root_children_1000)
+
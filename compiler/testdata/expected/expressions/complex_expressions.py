@@ -1,4 +1,5 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
 def format_currency(amount):
     return f"${amount:,.2f}"
 
@@ -12,7 +13,7 @@ def get_status(count):
             return "good"
 
 class ComplexExpressions(BaseView):
-    def __init__(self, items: list, user: dict):
+    def __init__(self, items: list, user: dict) -> None:
         super().__init__()
         self.items = items
         self.user = user
@@ -25,7 +26,7 @@ class ComplexExpressions(BaseView):
         _div_children_2000.append(el("p", f"Items: {escape(len(self.items))} ({escape(get_status(len(self.items)))})"))
         _div_children_2000.append(el("p", f"Total: {escape(format_currency(total_value))}"))
         _div_children_2000.append(el("p", f"Average: {escape(format_currency(total_value / len(self.items)) if self.items else "N/A")}"))
-        _div_children_2000.append(el("div", escape(f"Status: {get_status(len(self.items)).upper()}"), {"class": escape(f"status-{get_status(len(self.items))}")}))
+        _div_children_2000.append(el("div", escape(f"Status: {get_status(len(self.items)).upper()}"), {"class": class_names(f"status-{get_status(len(self.items))}")}))
         _ul_children_3000 = []
         for item in self.items[:3]:
             _ul_children_3000.append(el("li", escape(f"{item.get("name", "Unknown")} - {format_currency(item.get("price", 0))}")))
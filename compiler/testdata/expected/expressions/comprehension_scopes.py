@@ -0,0 +1,21 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Listing(BaseView):
+    def __init__(self, items: list, limit: int, key: str) -> None:
+        super().__init__()
+        self.items = items
+        self.limit = limit
+        self.key = key
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _ul_children_2000 = []
+        _ul_children_2000.append(el("li", escape([items for items in range(self.limit)])))
+        _ul_children_2000.append(el("li", escape([y for items in self.items for y in items if y != self.key])))
+        _ul_children_2000.append(el("li", escape({key: len(self.items) for key in self.items})))
+        _ul_children_2000.append(el("li", escape(sum((limit for limit in [self.limit, 1])))))
+        _ul_children_2000.append(el("li", escape([[key for key in items] for items in [self.items]])))
+        _ul_children_2000.append(el("li", f"{escape(self.key)}{escape(self.items)}"))
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        return fragment(_root_children_1000)
+
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class FStringExpressions(BaseView):
-    def __init__(self, name: str, items: list, total: float):
+    def __init__(self, name: str, items: list, total: float) -> None:
         super().__init__()
         self.name = name
         self.items = items
@@ -0,0 +1,18 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Report(BaseView):
+    def __init__(self, items: list, width: int, extra: dict, limit: int) -> None:
+        super().__init__()
+        self.items = items
+        self.width = width
+        self.extra = extra
+        self.limit = limit
+
+    def _render(self) -> Element:
+        total = max(self.limit, *self.items)
+        merged = {"width": self.width, **self.extra}
+        label = f"{total:>{self.width}}"
+        scale = lambda value, factor=self.width: value * factor
+        assert self.limit > 0, "limit must be positive"
+        return el("p", f"{escape(label)}{escape(merged)}{escape(scale(self.limit))}")
+
@@ -1 +1 @@
-COMPILATION_ERRORS: [Error: invalid int literal: strconv.ParseInt: parsing ".2": invalid syntax at position L7:38 Error: invalid int literal: strconv.ParseInt: parsing ".2": invalid syntax at position L8:50]
\ No newline at end of file
+COMPILATION_ERRORS: [Error: invalid int literal: strconv.ParseInt: parsing ".2": invalid syntax at position L7:38 (in interpolation mode since L7:29) Error: invalid int literal: strconv.ParseInt: parsing ".2": invalid syntax at position L8:50 (in interpolation mode since L8:40)]
\ No newline at end of file
@@ -0,0 +1,24 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+greeting = "Hello,\tworld!"
+path = r"C:\temp\new"
+data = b"\x00\xffraw bytes"
+pattern = rb"\d+"
+legacy = "unicode"
+message = ("Implicit concatenation")
+label = "Total: " f"{len(data)} bytes" r" \o/"
+escaped = f"{{braces}} and a newline\n"
+class StringLiterals(BaseView):
+    def __init__(self, name: str) -> None:
+        super().__init__()
+        self.name = name
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("p", escape(greeting)))
+        _div_children_2000.append(el("p", escape(self.name)))
+        _div_children_2000.append(el("p", escape(label)))
+        _root_children_1000.append(el("div", _div_children_2000, {"title": "C:\\temp", "data-kind": "ab"}))
+        return fragment(_root_children_1000)
+
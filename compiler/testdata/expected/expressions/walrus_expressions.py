@@ -0,0 +1,21 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Inventory(BaseView):
+    def __init__(self, items: list, threshold: int) -> None:
+        super().__init__()
+        self.items = items
+        self.threshold = threshold
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        if (count := len(self.items)) > self.threshold:
+            _root_children_1000.append(el("p", f"{escape(count)} items"))
+        _ul_children_2000 = []
+        for name in [label for item in self.items if (label := item.strip())]:
+            _ul_children_2000.append(el("li", escape(name)))
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        _root_children_1000.append(el("p", "First item", {"title": escape(first := self.items[0])}))
+        _root_children_1000.append(el("p", escape(first)))
+        _root_children_1000.append(el("p", f"{escape((total := sum((len(item) for item in self.items))))} characters in total, {escape(total // count)} per item"))
+        return fragment(_root_children_1000)
+
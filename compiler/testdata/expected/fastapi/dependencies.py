@@ -1,2 +1,63 @@
-COMPILATION_ERRORS: [at '
-': only class and function definitions can be decorated (position L38:1-L39:1)]
\ No newline at end of file
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+from fastapi import FastAPI, Depends, HTTPException
+from typing import Optional
+app = FastAPI()
+class Database:
+    def __init__(self):
+        self.users = {1: {"name": "Alice", "email": "alice@example.com"}, 2: {"name": "Bob", "email": "bob@example.com"}}
+
+    def get_user(self, user_id: int):
+        return self.users.get(user_id)
+
+def get_database():
+    return Database()
+
+def get_current_user(user_id: int=1):
+    return {"id": user_id, "name": f"User {user_id}"}
+
+@app.get("/dashboard")
+class Dashboard(BaseView):
+    def __init__(self, current_user: dict=Depends(get_current_user), db: Database=Depends(get_database)) -> None:
+        super().__init__()
+        self.current_user = current_user
+        self.db = db
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("h1", "Dashboard"))
+        _div_children_2000.append(el("p", f"Welcome, {escape(self.current_user["name"])}!"))
+        _div_children_2000.append(el("p", f"User ID: {escape(self.current_user["id"])}"))
+        _div_children_2000.append(el("h2", "All Users"))
+        _ul_children_3000 = []
+        for (user_id, user) in self.db.users.items():
+            _ul_children_3000.append(el("li", f"{escape(user["name"])} - {escape(user["email"])}"))
+        _div_children_2000.append(el("ul", _ul_children_3000))
+        _root_children_1000.append(el("div", _div_children_2000))
+        return fragment(_root_children_1000)
+
+@app.get("/users/{user_id}")
+class UserProfile(BaseView):
+    def __init__(self, user_id: int, db: Database=Depends(get_database)) -> None:
+        super().__init__()
+        self.user_id = user_id
+        self.db = db
+
+    def _render(self) -> Element:
+        _root_children_4000 = []
+        user = self.db.get_user(self.user_id)
+        if not user:
+            _div_children_5000 = []
+            _div_children_5000.append(el("h1", "User Not Found"))
+            _div_children_5000.append(el("p", f"User with ID {escape(self.user_id)} does not exist."))
+            _root_children_4000.append(el("div", _div_children_5000, {"class": "error"}))
+            return fragment(_root_children_4000)
+        _div_children_6000 = []
+        _div_children_6000.append(el("h1", "User Profile"))
+        _div_children_6000.append(el("h2", escape(user["name"])))
+        _div_children_6000.append(el("p", f"Email: {escape(user["email"])}"))
+        _div_children_6000.append(el("p", f"User ID: {escape(self.user_id)}"))
+        _root_children_4000.append(el("div", _div_children_6000))
+        return fragment(_root_children_4000)
+
@@ -1,2 +1,75 @@
-COMPILATION_ERRORS: [at '
-': only class and function definitions can be decorated (position L26:1-L27:1)]
\ No newline at end of file
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+from fastapi import FastAPI, Form, Request
+from typing import Optional
+app = FastAPI()
+@app.get("/contact")
+class ContactForm(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("h1", "Contact Us"))
+        _form_children_3000 = []
+        _div_children_4000 = []
+        _div_children_4000.append(el("label", "Name:", {"for": "name"}))
+        _div_children_4000.append(el("input", "", {"type": "text", "id": "name", "name": "name", "required": True}))
+        _form_children_3000.append(el("div", _div_children_4000))
+        _div_children_5000 = []
+        _div_children_5000.append(el("label", "Email:", {"for": "email"}))
+        _div_children_5000.append(el("input", "", {"type": "email", "id": "email", "name": "email", "required": True}))
+        _form_children_3000.append(el("div", _div_children_5000))
+        _div_children_6000 = []
+        _div_children_6000.append(el("label", "Message:", {"for": "message"}))
+        _div_children_6000.append(el("textarea", "", {"id": "message", "name": "message", "required": True}))
+        _form_children_3000.append(el("div", _div_children_6000))
+        _form_children_3000.append(el("button", "Send Message", {"type": "submit"}))
+        _div_children_2000.append(el("form", _form_children_3000, {"method": "post", "action": "/contact"}))
+        _root_children_1000.append(el("div", _div_children_2000))
+        return fragment(_root_children_1000)
+
+@app.post("/contact")
+class ContactSubmit(BaseView):
+    def __init__(self, name: str=Form(...), email: str=Form(...), message: str=Form(...)) -> None:
+        super().__init__()
+        self.name = name
+        self.email = email
+        self.message = message
+
+    def _render(self) -> Element:
+        _root_children_7000 = []
+        _div_children_8000 = []
+        _div_children_8000.append(el("h1", f"Thank you, {escape(self.name)}!"))
+        _div_children_8000.append(el("p", "Your message has been received."))
+        _div_children_8000.append(el("p", f"We'll respond to {escape(self.email)} shortly."))
+        _div_children_9000 = []
+        _div_children_9000.append(el("h3", "Your message:"))
+        _div_children_9000.append(el("blockquote", escape(self.message)))
+        _div_children_8000.append(el("div", _div_children_9000, {"class": "message-preview"}))
+        _div_children_8000.append(el("a", "Send another message", {"href": "/contact"}))
+        _root_children_7000.append(el("div", _div_children_8000))
+        return fragment(_root_children_7000)
+
+@app.get("/search")
+class SearchResults(BaseView):
+    def __init__(self, q: str, category: Optional[str]=None, sort: str="relevance", page: int=1) -> None:
+        super().__init__()
+        self.q = q
+        self.category = category
+        self.sort = sort
+        self.page = page
+
+    def _render(self) -> Element:
+        _root_children_10000 = []
+        _div_children_11000 = []
+        _div_children_11000.append(el("h1", f"Search Results for \"{escape(self.q)}\""))
+        if self.category:
+            _div_children_11000.append(el("p", f"Category: {escape(self.category)}"))
+        _div_children_11000.append(el("p", f"Sort: {escape(self.sort)}"))
+        _div_children_11000.append(el("p", f"Page: {escape(self.page)}"))
+        _div_children_11000.append(el("div", el("p", "Search results would appear here..."), {"class": "results"}))
+        _root_children_10000.append(el("div", _div_children_11000))
+        return fragment(_root_children_10000)
+
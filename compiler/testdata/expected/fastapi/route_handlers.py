@@ -1,2 +1,51 @@
-COMPILATION_ERRORS: [at '
-': only class and function definitions can be decorated (position L18:1-L19:1)]
\ No newline at end of file
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+from fastapi import FastAPI, Request, Depends
+from fastapi.responses import HTMLResponse
+app = FastAPI()
+@app.get("/")
+class HomePage(BaseView):
+    def __init__(self, request: Request) -> None:
+        super().__init__()
+        self.request = request
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _html_children_2000 = []
+        _html_children_2000.append(el("head", el("title", "Biscuit App")))
+        _body_children_3000 = []
+        _body_children_3000.append(el("h1", "Welcome to Biscuit!"))
+        _body_children_3000.append(el("p", f"Hello, visitor from {escape(self.request.client.host)}"))
+        _body_children_3000.append(el("a", "About", {"href": "/about"}))
+        _html_children_2000.append(el("body", _body_children_3000))
+        _root_children_1000.append(el("html", _html_children_2000))
+        return fragment(_root_children_1000)
+
+@app.get("/about")
+class AboutPage(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_4000 = []
+        _div_children_5000 = []
+        _div_children_5000.append(el("h1", "About Us"))
+        _div_children_5000.append(el("p", "This is a Biscuit application"))
+        _root_children_4000.append(el("div", _div_children_5000))
+        return fragment(_root_children_4000)
+
+@app.get("/products/{product_id}")
+class ProductDetail(BaseView):
+    def __init__(self, product_id: int) -> None:
+        super().__init__()
+        self.product_id = product_id
+
+    def _render(self) -> Element:
+        _root_children_6000 = []
+        _div_children_7000 = []
+        _div_children_7000.append(el("h1", f"Product #{escape(self.product_id)}"))
+        _div_children_7000.append(el("p", "Product details go here"))
+        _div_children_7000.append(el("a", "Back to home", {"href": "/"}))
+        _root_children_6000.append(el("div", _div_children_7000, {"class": "product"}))
+        return fragment(_root_children_6000)
+
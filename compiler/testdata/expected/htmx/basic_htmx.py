@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class HTMXBasic(BaseView):
-    def __init__(self, user_id: int):
+    def __init__(self, user_id: int) -> None:
         super().__init__()
         self.user_id = user_id
 
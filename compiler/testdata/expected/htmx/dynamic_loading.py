@@ -1 +1 @@
-COMPILATION_ERRORS: [at 'f': expected string, number, boolean, or expression for attribute value (position L2:6-L4:17)]
\ No newline at end of file
+COMPILATION_ERRORS: [at 'f': expected string, number, boolean, or expression for attribute value (position L3:16-L3:17) at 'f': expected string, number, boolean, or expression for attribute value (position L20:16-L20:17)]
\ No newline at end of file
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class ValidationErrors(BaseView):
-    def __init__(self, errors: dict):
+    def __init__(self, errors: dict) -> None:
         super().__init__()
         self.errors = errors
 
@@ -16,7 +17,7 @@ class ValidationErrors(BaseView):
         return fragment(_root_children_1000)
 
 class ContactForm(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
@@ -1,4 +1,5 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, class_names, el, escape, fragment, raw, require_api
+require_api(3)
 from dataclasses import dataclass
 from typing import List
 @dataclass
@@ -17,7 +18,7 @@ def filter_adults(users: List[User]) -> List[User]:
     return [user for user in users if user.is_adult()]
 
 class UserList(BaseView):
-    def __init__(self, users: List[User]):
+    def __init__(self, users: List[User]) -> None:
         super().__init__()
         self.users = users
 
@@ -33,7 +34,7 @@ class UserList(BaseView):
             _div_children_3000.append(el("h3", escape(user.display_name)))
             _div_children_3000.append(el("p", f"Email: {escape(user.email)}"))
             _div_children_3000.append(el("p", f"Status: {escape("Adult" if user.is_adult() else "Minor")}"))
-            _div_children_2000.append(el("div", _div_children_3000, {"class": escape(f"user {"adult" if user.is_adult() else "minor"}")}))
+            _div_children_2000.append(el("div", _div_children_3000, {"class": class_names(f"user {"adult" if user.is_adult() else "minor"}")}))
         _root_children_1000.append(el("div", _div_children_2000))
         return fragment(_root_children_1000)
 
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Comprehensions(BaseView):
-    def __init__(self, numbers: list, items: list):
+    def __init__(self, numbers: list, items: list) -> None:
         super().__init__()
         self.numbers = numbers
         self.items = items
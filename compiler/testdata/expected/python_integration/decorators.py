@@ -1,4 +1,5 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 from functools import wraps
 def cache_result(func):
     cache = {}
@@ -33,7 +34,7 @@ class DataProcessor:
         return [expensive_operation(x) for x in data if x > 0]
 
 class DecoratorDemo(BaseView):
-    def __init__(self, values: list):
+    def __init__(self, values: list) -> None:
         super().__init__()
         self.values = values
 
@@ -1 +1,33 @@
-COMPILATION_ERRORS: [failed to transform view App: view element 'Layout' cannot have nested content]
\ No newline at end of file
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Layout(BaseView):
+    def __init__(self, *, children: Union[Element, BaseView, str, None]=None, header: Union[Element, BaseView, str, None]=None, footer: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.children = children
+        self.header = header
+        self.footer = footer
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("header", render_child(self.header) if self.header is not None else el("h1", "Default Header")))
+        _div_children_2000.append(el("main", render_child(self.children) if self.children is not None else el("p", "Default content")))
+        _div_children_2000.append(el("footer", render_child(self.footer) if self.footer is not None else el("p", "Default footer")))
+        _root_children_1000.append(el("div", _div_children_2000, {"class": "layout"}))
+        return fragment(_root_children_1000)
+
+class App(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        _slot_children_4000 = []
+        _div_children_5000 = []
+        _div_children_5000.append(el("p", "This is the main content"))
+        _div_children_5000.append(el("p", "Multiple paragraphs"))
+        _slot_children_4000.append(el("div", _div_children_5000))
+        _root_children_3000.append(Layout(header=el("h1", "Custom Header"), children=fragment(_slot_children_4000), footer=el("div", el("p", "&copy; 2024 My App"))))
+        return fragment(_root_children_3000)
+
@@ -1 +1,36 @@
-COMPILATION_ERRORS: [failed to transform view Example: view element 'Panel' cannot have nested content]
\ No newline at end of file
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Panel(BaseView):
+    def __init__(self, title: str, *, children: Union[Element, BaseView, str, None]=None, actions: Union[Element, BaseView, str, None]=None, footer: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.title = title
+        self.children = children
+        self.actions = actions
+        self.footer = footer
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_3000 = []
+        _div_children_3000.append(el("h3", escape(self.title)))
+        if has_slot("actions"):
+            _div_children_3000.append(el("div", render_child(self.actions) if self.actions is not None else "", {"class": "panel-actions"}))
+        _div_children_2000.append(el("div", _div_children_3000, {"class": "panel-header"}))
+        _div_children_2000.append(el("div", render_child(self.children) if self.children is not None else "", {"class": "panel-body"}))
+        if has_slot("footer"):
+            _div_children_2000.append(el("div", render_child(self.footer) if self.footer is not None else "", {"class": "panel-footer"}))
+        else:
+            _div_children_2000.append(el("div", el("p", "No footer content provided."), {"class": "panel-footer text-muted"}))
+        _root_children_1000.append(el("div", _div_children_2000, {"class": "panel"}))
+        return fragment(_root_children_1000)
+
+class Example(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_4000 = []
+        _root_children_4000.append(Panel(title="My Panel", children=el("p", "This is the main content"), actions=el("button", "Edit"), footer=el("p", "Custom footer")))
+        return fragment(_root_children_4000)
+
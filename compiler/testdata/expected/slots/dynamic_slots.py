@@ -0,0 +1,27 @@
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Tabs(BaseView):
+    def __init__(self, active: str, *, tabs: Union[Element, BaseView, str, None]=None, **slots: Union[Element, BaseView, str, None]) -> None:
+        super().__init__()
+        self.active = active
+        self.tabs = tabs
+        self._slots = {"tabs": tabs, **slots}
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _root_children_1000.append(el("nav", render_child(self.tabs) if self.tabs is not None else "", {"class": "tabs"}))
+        _root_children_1000.append(el("section", render_child(self._slots.get(self.active)) if self._slots.get(self.active) is not None else el("p", "No content for this tab")))
+        return fragment(_root_children_1000)
+
+class Settings(BaseView):
+    def __init__(self, tab: str, extra: str) -> None:
+        super().__init__()
+        self.tab = tab
+        self.extra = extra
+
+    def _render(self) -> Element:
+        _root_children_2000 = []
+        _root_children_2000.append(Tabs(active=self.tab, tabs=el("a", "Profile", {"href": "#profile"}), profile=el("form", el("input", "", {"name": "email"})), **{self.extra: el("div", "More")}))
+        return fragment(_root_children_2000)
+
@@ -0,0 +1,31 @@
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Card(BaseView):
+    def __init__(self, title: str, *, children: Union[Element, BaseView, str, None]=None, header: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.title = title
+        self.children = children
+        self.header = header
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("h2", escape(self.title)))
+        _div_children_2000.append(el("header", render_child(self.header) if self.header is not None else el("span", "Card header")))
+        _div_children_2000.append(render_child(self.children) if self.children is not None else "")
+        _root_children_1000.append(el("div", _div_children_2000, {"class": "card"}))
+        return fragment(_root_children_1000)
+
+class Panel(BaseView):
+    def __init__(self, title: str, *, children: Union[Element, BaseView, str, None]=None, header: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.title = title
+        self.children = children
+        self.header = header
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        _root_children_3000.append(Card(title=self.title, header=self.header, children=self.children if self.children is not None else el("p", "Empty panel")))
+        return fragment(_root_children_3000)
+
@@ -0,0 +1,37 @@
+from typing import Any, Callable, Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Table(BaseView):
+    def __init__(self, rows: list, *, children: Union[Element, BaseView, str, Callable[..., Any], None]=None, row: Union[Element, BaseView, str, Callable[..., Any], None]=None) -> None:
+        super().__init__()
+        self.rows = rows
+        self.children = children
+        self.row = row
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _table_children_2000 = []
+        for (i, row) in enumerate(self.rows):
+            _table_children_2000.append(el("tr", render_child(self.row(row=row, index=i) if callable(self.row) else self.row) if self.row is not None else el("td", escape(row))))
+        _table_children_2000.append(el("tfoot", render_child(self.children(count=len(self.rows)) if callable(self.children) else self.children) if self.children is not None else ""))
+        _root_children_1000.append(el("table", _table_children_2000))
+        return fragment(_root_children_1000)
+
+class Report(BaseView):
+    def __init__(self, groups: dict) -> None:
+        super().__init__()
+        self.groups = groups
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        for (group, rows) in self.groups.items():
+            _root_children_3000.append(el("h2", escape(group)))
+            def _row_slot_4000(group=group, /, *, row, index, **_):
+                return fragment([el("td", escape(group)), el("td", escape(index + 1)), el("td", escape(row))])
+
+            def _children_slot_5000(*, count, **_):
+                return el("td", f"{escape(count)} rows")
+
+            _root_children_3000.append(Table(rows=rows, row=_row_slot_4000, children=_children_slot_5000))
+        return fragment(_root_children_3000)
+
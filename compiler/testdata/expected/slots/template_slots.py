@@ -1 +1,28 @@
-COMPILATION_ERRORS: [failed to transform view App: view element 'Card' cannot have nested content]
\ No newline at end of file
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Card(BaseView):
+    def __init__(self, *, children: Union[Element, BaseView, str, None]=None, header: Union[Element, BaseView, str, None]=None, footer: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.children = children
+        self.header = header
+        self.footer = footer
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("div", render_child(self.header) if self.header is not None else el("h4", "Default Header"), {"class": "card-header"}))
+        _div_children_2000.append(el("div", render_child(self.children) if self.children is not None else "", {"class": "card-body"}))
+        _div_children_2000.append(el("div", render_child(self.footer) if self.footer is not None else "", {"class": "card-footer"}))
+        _root_children_1000.append(el("div", _div_children_2000, {"class": "card"}))
+        return fragment(_root_children_1000)
+
+class App(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        _root_children_3000 = []
+        _root_children_3000.append(Card(header=fragment([el("h2", "Complex Header"), el("p", "With subtitle")]), children=fragment([el("p", "Main content"), el("p", "More content")]), footer=fragment([el("button", "Save"), el("button", "Cancel")])))
+        return fragment(_root_children_3000)
+
@@ -0,0 +1,34 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+from contextlib import asynccontextmanager
+@asynccontextmanager
+async def span(name):
+    yield name
+
+async def load(n):
+    return n * 2
+
+class Item(BaseView):
+    def __init__(self, n: int) -> None:
+        super().__init__()
+        self.n = n
+
+    async def _render(self) -> Element:
+        value = await load(self.n)
+        return el("li", escape(value))
+
+class List(BaseView):
+    def __init__(self, items) -> None:
+        super().__init__()
+        self.items = items
+
+    async def _render(self) -> Element:
+        _root_children_1000 = []
+        async with span("list") as name:
+            _root_children_1000.append(el("h2", escape(name)))
+        _ul_children_2000 = []
+        async for n in self.items:
+            _ul_children_2000.append(await Item(n=n)._render())
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        return fragment(_root_children_1000)
+
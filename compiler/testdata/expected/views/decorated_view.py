@@ -0,0 +1,32 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+registry = {}
+def register(name):
+    def wrap(cls):
+        registry[name] = cls
+        return cls
+
+    return wrap
+
+def traced(cls):
+    cls.traced = True
+    return cls
+
+@register("home")
+@traced
+class HomePage(BaseView):
+    def __init__(self, title: str) -> None:
+        super().__init__()
+        self.title = title
+
+    def _render(self) -> Element:
+        return el("h1", escape(self.title))
+
+@register("legacy")
+class LegacyPage(BaseView):
+    def __init__(self) -> None:
+        super().__init__()
+
+    def _render(self) -> Element:
+        return el("p", "Legacy")
+
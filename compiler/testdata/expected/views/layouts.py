@@ -0,0 +1,62 @@
+from typing import Union
+from topple.psx import BaseView, Element, el, escape, fragment, raw, render_child, require_api
+require_api(3)
+class Base(BaseView):
+    def __init__(self, title: str, *, children: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.title = title
+        self.children = children
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _html_children_2000 = []
+        _html_children_2000.append(el("head", el("title", escape(self.title))))
+        _html_children_2000.append(el("body", render_child(self.children) if self.children is not None else ""))
+        _root_children_1000.append(el("html", _html_children_2000))
+        return fragment(_root_children_1000)
+
+class Section(BaseView):
+    def __init__(self, title: str, heading: str="Section", *, children: Union[Element, BaseView, str, None]=None) -> None:
+        super().__init__()
+        self.title = title
+        self.heading = heading
+        self.children = children
+
+    def _render(self) -> Element:
+        return Base(title=self.title, children=self._render_content())
+
+    def _render_content(self) -> Element:
+        _root_children_3000 = []
+        _section_children_4000 = []
+        _section_children_4000.append(el("h1", escape(self.heading)))
+        _section_children_4000.append(render_child(self.children) if self.children is not None else "")
+        _root_children_3000.append(el("section", _section_children_4000))
+        return fragment(_root_children_3000)
+
+class Home(BaseView):
+    def __init__(self, user: str) -> None:
+        super().__init__()
+        self.user = user
+
+    def _render(self) -> Element:
+        return Base(title=f"Welcome, {self.user}", children=self._render_content())
+
+    def _render_content(self) -> Element:
+        return el("p", f"Hello, {escape(self.user)}!")
+
+class Items(BaseView):
+    def __init__(self, items: list) -> None:
+        super().__init__()
+        self.items = items
+
+    def _render(self) -> Element:
+        return Section(title="Items", heading=f"{len(self.items)} items", children=self._render_content())
+
+    def _render_content(self) -> Element:
+        _root_children_5000 = []
+        _ul_children_6000 = []
+        for item in self.items:
+            _ul_children_6000.append(el("li", escape(item)))
+        _root_children_5000.append(el("ul", _ul_children_6000))
+        return fragment(_root_children_5000)
+
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class MultiRoot(BaseView):
-    def __init__(self, title: str, content: str):
+    def __init__(self, title: str, content: str) -> None:
         super().__init__()
         self.title = title
         self.content = content
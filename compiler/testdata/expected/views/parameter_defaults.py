@@ -0,0 +1,21 @@
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
+class Card(BaseView):
+    def __init__(self, items: list, limit: int) -> None:
+        super().__init__()
+        self.items = items
+        self.limit = limit
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        def first(items=self.items, n=self.limit):
+            return items[:n]
+
+        double = lambda limit=self.limit: limit * 2
+        _ul_children_2000 = []
+        for item in first():
+            _ul_children_2000.append(el("li", escape(item)))
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        _root_children_1000.append(el("p", escape(double())))
+        return fragment(_root_children_1000)
+
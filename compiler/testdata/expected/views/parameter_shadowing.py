@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Greeting(BaseView):
-    def __init__(self, name: str):
+    def __init__(self, name: str) -> None:
         super().__init__()
         self.name = name
 
@@ -8,7 +9,7 @@ class Greeting(BaseView):
         return el("h1", f"Hello, {escape(self.name)}!")
 
 class Profile(BaseView):
-    def __init__(self, name: str, title: str):
+    def __init__(self, name: str, title: str) -> None:
         super().__init__()
         self.name = name
         self.title = title
@@ -22,7 +23,7 @@ class Profile(BaseView):
         return fragment(_root_children_1000)
 
 class Dashboard(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
@@ -1,6 +1,7 @@
-from topple.psx import BaseView, Element, el, escape, fragment, raw
+from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+require_api(3)
 class Greeting(BaseView):
-    def __init__(self, name: str, age: int=25):
+    def __init__(self, name: str, age: int=25) -> None:
         super().__init__()
         self.name = name
         self.age = age
@@ -0,0 +1,71 @@
+package transformers
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fjvillamarin/topple/compiler/assets"
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// AssetResolver checks a relative asset reference used in sourceFile and
+// returns the value the attribute should be compiled to
+type AssetResolver interface {
+	Resolve(sourceFile, ref string) (string, error)
+}
+
+// assetAttributes lists the attributes that refer to files, per tag. The
+// empty tag applies to every element; href is only an asset on <link>, since
+// on <a> it is a page.
+var assetAttributes = map[string][]string{
+	"":     {"src", "poster"},
+	"link": {"href"},
+}
+
+// isAssetAttribute reports whether attribute name of tag refers to a file
+func isAssetAttribute(tag, name string) bool {
+	return slices.Contains(assetAttributes[""], name) || slices.Contains(assetAttributes[tag], name)
+}
+
+// resolveAssetAttributes returns the attributes of element with relative
+// asset references ("./logo.png") checked and rewritten by the asset
+// resolver. Without a resolver the attributes are returned as they are.
+func (vm *ViewTransformer) resolveAssetAttributes(element *ast.HTMLElement) ([]ast.HTMLAttribute, error) {
+	if vm.assets == nil || vm.sourceFile == "" {
+		return element.Attributes, nil
+	}
+
+	attributes, copied := element.Attributes, false
+	for i, attr := range element.Attributes {
+		if !isAssetAttribute(element.TagName.Lexeme, attr.Name.Lexeme) {
+			continue
+		}
+		literal, ok := attr.Value.(*ast.Literal)
+		if !ok || literal.Type != ast.LiteralTypeString {
+			continue
+		}
+		ref, ok := literal.Value.(string)
+		if !ok || !assets.IsReference(ref) {
+			continue
+		}
+
+		resolved, err := vm.assets.Resolve(vm.sourceFile, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s attribute at %s: %w", attr.Name.Lexeme, attr.Span, err)
+		}
+		if resolved == ref {
+			continue
+		}
+
+		// Copy before the first change; the module AST is not modified
+		if !copied {
+			attributes, copied = slices.Clone(element.Attributes), true
+		}
+		attributes[i].Value = &ast.Literal{
+			Type:  ast.LiteralTypeString,
+			Value: resolved,
+			Span:  literal.Span,
+		}
+	}
+	return attributes, nil
+}
@@ -0,0 +1,87 @@
+package transformers
+
+import (
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// booleanAttributes are the boolean attributes of HTML5: present means true,
+// whatever their value, and absent means false
+var booleanAttributes = map[string]bool{
+	"allowfullscreen": true, "async": true, "autofocus": true, "autoplay": true,
+	"checked": true, "controls": true, "default": true, "defer": true,
+	"disabled": true, "formnovalidate": true, "hidden": true, "inert": true,
+	"ismap": true, "itemscope": true, "loop": true, "multiple": true,
+	"muted": true, "nomodule": true, "novalidate": true, "open": true,
+	"playsinline": true, "readonly": true, "required": true, "reversed": true,
+	"selected": true,
+}
+
+// isEventHandler reports whether an attribute is an event handler, on
+// followed by the event name: onclick, onsubmit
+func isEventHandler(name string) bool {
+	if len(name) <= 2 || !strings.HasPrefix(name, "on") {
+		return false
+	}
+	for _, r := range name[2:] {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// lowerAttributeValue returns the value an element's attribute dictionary
+// holds for an attribute given a value:
+//   - Event handlers keep their value, so a callable reaches frameworks
+//     consuming the element tree. The runtime leaves callables out of HTML
+//     and escapes other values.
+//   - Boolean attributes are present whatever their static value, and
+//     present when their dynamic value is truthy: bool(value).
+//   - class and style values may be dicts and lists, serialized by
+//     class_names and style_value.
+//   - Other dynamic values are escaped.
+func (vm *ViewTransformer) lowerAttributeValue(name string, value ast.Expr, span lexer.Span) ast.Expr {
+	name = strings.ToLower(name)
+	_, static := value.(*ast.Literal)
+	if literal, ok := value.(*ast.Literal); ok && literal.Type != ast.LiteralTypeString {
+		static = false
+	}
+
+	switch {
+	case booleanAttributes[name]:
+		if static {
+			return &ast.Literal{Type: ast.LiteralTypeBool, Value: true, Span: span}
+		}
+		if literal, ok := value.(*ast.Literal); ok && (literal.Type == ast.LiteralTypeBool || literal.Type == ast.LiteralTypeNone) {
+			return literal
+		}
+		return vm.callFunction("bool", vm.transformExpression(value), span)
+	case static:
+		return vm.transformExpression(value)
+	case isEventHandler(name):
+		return vm.transformExpression(value)
+	case name == "class" && vm.runtimeAPI.version() >= RuntimeAPIAttributes:
+		vm.needsClassNames = true
+		return vm.callFunction("class_names", vm.transformExpression(value), span)
+	case name == "style" && vm.runtimeAPI.version() >= RuntimeAPIAttributes:
+		vm.needsStyleValue = true
+		return vm.callFunction("style_value", vm.transformExpression(value), span)
+	}
+	// Dynamic expression - wrap with escape() for security
+	return vm.callFunction("escape", vm.transformExpression(value), span)
+}
+
+// callFunction calls a runtime or builtin function with one argument
+func (vm *ViewTransformer) callFunction(function string, value ast.Expr, span lexer.Span) ast.Expr {
+	return &ast.Call{
+		Callee: &ast.Name{
+			Token: lexer.Token{Lexeme: function, Type: lexer.Identifier},
+			Span:  span,
+		},
+		Arguments: []*ast.Argument{{Value: value, Span: span}},
+		Span:      span,
+	}
+}
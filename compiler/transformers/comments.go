@@ -0,0 +1,116 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// HTMLCommentMode selects what happens to preserved <!-- ... --> comments
+type HTMLCommentMode int
+
+const (
+	// HTMLCommentsStrip discards comments (the default)
+	HTMLCommentsStrip HTMLCommentMode = iota
+	// HTMLCommentsRender emits comments into the rendered HTML, e.g. for conditional comments
+	HTMLCommentsRender
+	// HTMLCommentsPython emits comments as Python comments in the generated code.
+	// Elements containing comments are built statement by statement so the
+	// comments can sit between them.
+	HTMLCommentsPython
+)
+
+// ParseHTMLCommentMode parses "strip", "render" or "python"
+func ParseHTMLCommentMode(s string) (HTMLCommentMode, error) {
+	switch s {
+	case "", "strip":
+		return HTMLCommentsStrip, nil
+	case "render":
+		return HTMLCommentsRender, nil
+	case "python":
+		return HTMLCommentsPython, nil
+	default:
+		return HTMLCommentsStrip, fmt.Errorf("unknown HTML comment mode %q (valid: strip, render, python)", s)
+	}
+}
+
+// Preserved reports whether the scanner must keep comments for this mode
+func (m HTMLCommentMode) Preserved() bool {
+	return m != HTMLCommentsStrip
+}
+
+// Options configures the transformer
+type Options struct {
+	HTMLComments  HTMLCommentMode   // How preserved HTML comments are emitted
+	Elements      ElementPolicy     // Project-specific intrinsic and denied elements
+	Defaults      AttributeDefaults // Attributes elements and compositions get unless they set them
+	EarlyReturns  EarlyReturnMode   // Whether return may end view rendering early
+	Markdown      bool              // Render <Markdown> blocks to static HTML at compile time
+	Assets        AssetResolver     // Checks relative asset references; nil leaves them as written
+	SourceFile    string            // Path of the file being transformed, for asset references and markers
+	Markers       bool              // Mark the PSX lines each view class was compiled from with comments
+	RuntimeAPI    RuntimeAPI        // Runtime API version generated code targets; 0 targets CurrentRuntimeAPI
+	RuntimeModule string            // Module the runtime is imported from; "" imports DefaultRuntimeModule
+}
+
+// processHTMLComment processes an HTMLComment in statement position
+func (vm *ViewTransformer) processHTMLComment(comment *ast.HTMLComment) ([]ast.Stmt, error) {
+	switch vm.htmlComments {
+	case HTMLCommentsRender:
+		commentExpr := vm.transformHTMLComment(comment)
+		if vm.currentContext != "" {
+			return []ast.Stmt{vm.createAppendStatement(vm.currentContext, commentExpr)}, nil
+		}
+		return []ast.Stmt{&ast.ExprStmt{
+			Expr: commentExpr,
+			Span: comment.Span,
+		}}, nil
+	case HTMLCommentsPython:
+		// Code generation writes the comment as '#' lines
+		return []ast.Stmt{comment}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// transformHTMLComment creates raw("<!--text-->") so the comment is rendered unescaped
+func (vm *ViewTransformer) transformHTMLComment(comment *ast.HTMLComment) ast.Expr {
+	return &ast.Call{
+		Callee: &ast.Name{
+			Token: lexer.Token{Lexeme: "raw", Type: lexer.Identifier},
+			Span:  comment.Span,
+		},
+		Arguments: []*ast.Argument{{
+			Value: &ast.Literal{
+				Type:  ast.LiteralTypeString,
+				Value: comment.String(),
+				Span:  comment.Span,
+			},
+			Span: comment.Span,
+		}},
+		Span: comment.Span,
+	}
+}
+
+// rendersHTMLComments reports whether comments become part of the rendered output
+func (vm *ViewTransformer) rendersHTMLComments() bool {
+	return vm.htmlComments == HTMLCommentsRender
+}
+
+// withoutHTMLComments drops comments from element content used in expression
+// position, where they can only be kept by rendering them (view composition
+// and slot content never switch to statement-by-statement building)
+func (vm *ViewTransformer) withoutHTMLComments(content []ast.Stmt) []ast.Stmt {
+	if vm.rendersHTMLComments() {
+		return content
+	}
+
+	filtered := make([]ast.Stmt, 0, len(content))
+	for _, stmt := range content {
+		if _, ok := stmt.(*ast.HTMLComment); !ok {
+			filtered = append(filtered, stmt)
+		}
+	}
+	return filtered
+}
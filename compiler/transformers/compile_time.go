@@ -0,0 +1,69 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/consteval"
+	"github.com/fjvillamarin/topple/compiler/resolver"
+)
+
+// evaluateCompileTime inlines the result of a call of a @compile_time
+// function whose arguments are all constant, such as icon_path("check").
+// Other calls are returned as they are. A call that fails to evaluate would
+// fail at runtime too, so the error is kept and fails the view.
+func (vm *ViewTransformer) evaluateCompileTime(original, call *ast.Call) ast.Expr {
+	callee, ok := original.Callee.(*ast.Name)
+	if !ok || !vm.compileTime.Has(callee.Token.Lexeme) || vm.isShadowed(callee) {
+		return call
+	}
+	for _, arg := range call.Arguments {
+		if arg.IsStar || arg.IsDoubleStar || !consteval.IsConstant(arg.Value) {
+			return call
+		}
+	}
+
+	var args []consteval.Value
+	var keywords []consteval.Keyword
+	for _, arg := range call.Arguments {
+		value, err := vm.compileTime.Constant(arg.Value)
+		if err != nil {
+			vm.failCompileTime(call, callee, err)
+			return call
+		}
+		if arg.Name != nil {
+			keywords = append(keywords, consteval.Keyword{Name: arg.Name.Token.Lexeme, Value: value})
+		} else {
+			args = append(args, value)
+		}
+	}
+
+	result, err := vm.compileTime.Call(callee.Token.Lexeme, args, keywords, call.Span)
+	if err != nil {
+		vm.failCompileTime(call, callee, err)
+		return call
+	}
+	literal, err := consteval.ToExpr(result, call.Span)
+	if err != nil {
+		vm.failCompileTime(call, callee, err)
+		return call
+	}
+	return literal
+}
+
+// isShadowed reports whether a name refers to something other than a
+// module-level definition, such as a local variable of the view
+func (vm *ViewTransformer) isShadowed(name *ast.Name) bool {
+	if vm.resolutionTable == nil {
+		return false
+	}
+	binding, exists := vm.resolutionTable.NameToBinding[name]
+	return exists && binding.Scope != nil && binding.Scope.ScopeType != resolver.ModuleScopeType
+}
+
+// failCompileTime records the first compile-time evaluation error of a view
+func (vm *ViewTransformer) failCompileTime(call *ast.Call, callee *ast.Name, err error) {
+	if vm.compileTimeErr == nil {
+		vm.compileTimeErr = fmt.Errorf("cannot evaluate %s() at %s at compile time: %w", callee.Token.Lexeme, call.Span, err)
+	}
+}
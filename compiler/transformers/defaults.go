@@ -0,0 +1,126 @@
+package transformers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// AttributeDefaults are attributes every element of a tag, or every
+// composition of a view, gets unless it sets them itself: design tokens such
+// as type="button" on every <button>. An attribute written on the element,
+// or passed by a {...mapping} spread, overrides the default.
+type AttributeDefaults struct {
+	Source string                        // File the defaults were declared in, named in diagnostics
+	Tags   map[string][]DefaultAttribute // Tag or view name -> its defaults, sorted by name
+}
+
+// DefaultAttribute is one attribute of AttributeDefaults
+type DefaultAttribute struct {
+	Name    string
+	Value   string // Ignored for a boolean attribute
+	Boolean bool   // Written without a value, as in <input required>
+}
+
+// ParseAttributeDefaults reads attribute defaults from JSON mapping tag and
+// view names to their attributes, whose values are strings, or true for a
+// boolean attribute:
+//
+//	{"button": {"type": "button"}, "Button": {"variant": "primary"}}
+//
+// source names the file in errors and in the diagnostics of the compositions
+// the defaults apply to.
+func ParseAttributeDefaults(source string, data []byte) (AttributeDefaults, error) {
+	var raw map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return AttributeDefaults{}, fmt.Errorf("%s: expected an object mapping tag names to attributes: %w", source, err)
+	}
+
+	defaults := AttributeDefaults{Source: source, Tags: make(map[string][]DefaultAttribute, len(raw))}
+	for tag, attributes := range raw {
+		if !isDefaultName(tag) {
+			return AttributeDefaults{}, fmt.Errorf("%s: '%s' is not a tag or view name", source, tag)
+		}
+		for name, value := range attributes {
+			if !isDefaultName(name) {
+				return AttributeDefaults{}, fmt.Errorf("%s: %s: '%s' is not an attribute name", source, tag, name)
+			}
+			attr := DefaultAttribute{Name: name}
+			if bytes.Equal(bytes.TrimSpace(value), []byte("true")) {
+				attr.Boolean = true
+			} else if err := json.Unmarshal(value, &attr.Value); err != nil {
+				return AttributeDefaults{}, fmt.Errorf("%s: %s.%s must be a string, or true for a boolean attribute", source, tag, name)
+			}
+			defaults.Tags[tag] = append(defaults.Tags[tag], attr)
+		}
+		sort.Slice(defaults.Tags[tag], func(i, j int) bool { return defaults.Tags[tag][i].Name < defaults.Tags[tag][j].Name })
+	}
+	return defaults, nil
+}
+
+// isDefaultName reports whether name can be written as a tag or attribute
+// name, such as "button", "ui-card" or "hx-get"
+func isDefaultName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n\"'<>/=")
+}
+
+// withDefaultAttributes returns the attributes of an element with the
+// defaults declared for tag first, leaving out those the element sets. With
+// a spread, the defaults come before it so the spread overrides them too.
+func (vm *ViewTransformer) withDefaultAttributes(tag string, element *ast.HTMLElement, attributes []ast.HTMLAttribute) []ast.HTMLAttribute {
+	defaults := vm.defaults.Tags[tag]
+	if len(defaults) == 0 {
+		return attributes
+	}
+	set := make(map[string]bool, len(attributes))
+	for _, attr := range attributes {
+		if !attr.Spread {
+			set[attr.Name.Lexeme] = true
+		}
+	}
+
+	var merged []ast.HTMLAttribute
+	for _, def := range defaults {
+		if set[def.Name] {
+			continue
+		}
+		// The default is written where the element's tag is, so errors and
+		// source maps point at the element it was applied to
+		span := element.TagName.Span
+		attr := ast.HTMLAttribute{
+			Name: lexer.Token{Type: lexer.Identifier, Lexeme: def.Name, Span: span},
+			Span: span,
+		}
+		if !def.Boolean {
+			attr.Value = &ast.Literal{Type: ast.LiteralTypeString, Value: def.Value, Span: span}
+		}
+		merged = append(merged, attr)
+	}
+	return append(merged, attributes...)
+}
+
+// checkViewDefaults reports a default declared for a view that names none
+// of its parameters, with the file that declared it
+func (vm *ViewTransformer) checkViewDefaults(viewStmt *ast.ViewStmt, element *ast.HTMLElement) error {
+	view := viewStmt.Name.Token.Lexeme
+	for _, def := range vm.defaults.Tags[view] {
+		found := false
+		if viewStmt.Params != nil {
+			for _, param := range viewStmt.Params.Parameters {
+				if param != nil && param.Name != nil && !param.IsStar && !param.IsDoubleStar && param.Name.Token.Lexeme == def.Name {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("default attribute '%s' declared for %s in %s is not a parameter of %s, composed at %s", def.Name, view, vm.defaults.Source, view, element.Span)
+		}
+	}
+	return nil
+}
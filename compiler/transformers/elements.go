@@ -0,0 +1,51 @@
+package transformers
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+)
+
+// ElementPolicy declares project-specific elements. Intrinsic elements are
+// rendered as plain el() calls even when their names look like components
+// (design-system primitives handled by the runtime); denied elements are
+// rejected wherever they appear in a view.
+type ElementPolicy struct {
+	Intrinsic []string // Tag names treated as built-in elements
+	Denied    []string // Tag names that may not be used
+}
+
+// IsIntrinsic reports whether tag was declared as a built-in element
+func (p ElementPolicy) IsIntrinsic(tag string) bool {
+	return slices.Contains(p.Intrinsic, tag)
+}
+
+// IsDenied reports whether tag is banned
+func (p ElementPolicy) IsDenied(tag string) bool {
+	return slices.Contains(p.Denied, tag)
+}
+
+// validateElementTag checks an element that is not a view composition
+// against the element policy and the component naming convention
+func (vm *ViewTransformer) validateElementTag(element *ast.HTMLElement) error {
+	// A computed tag name is only known when the view renders
+	if element.TagExpr != nil {
+		return nil
+	}
+	tagName := element.TagName.Lexeme
+
+	if vm.elements.IsDenied(tagName) {
+		return fmt.Errorf("element '%s' at %s is not allowed by the project's element policy", tagName, element.Span)
+	}
+
+	if vm.elements.IsIntrinsic(tagName) {
+		return nil
+	}
+
+	// Check for undefined PascalCase components (likely a typo or missing view definition)
+	if vm.isPascalCase(tagName) {
+		return fmt.Errorf("undefined view component '%s' at %s. Views must be defined before use. If this is meant to be an HTML tag, use lowercase", tagName, element.Span)
+	}
+	return nil
+}
@@ -6,37 +6,40 @@ import (
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
 
-// validateViewElementContent checks if a view element has nested content and returns an error if it does
-func (vm *ViewTransformer) validateViewElementContent(element *ast.HTMLElement) error {
-	if len(element.Content) > 0 {
-		return fmt.Errorf("view element '%s' cannot have nested content", element.TagName.Lexeme)
-	}
-	return nil
-}
-
 // processHTMLElement processes an HTMLElement and returns the transformed statements
 func (vm *ViewTransformer) processHTMLElement(element *ast.HTMLElement) ([]ast.Stmt, error) {
 	var statements []ast.Stmt
 
-	tagName := element.TagName.Lexeme
+	if err := vm.validateSlotParams(element); err != nil {
+		return nil, err
+	}
 
-	// Check if this element is actually a view composition
-	if viewStmt, isView := vm.isViewElement(element); isView {
-		// Validate that view elements don't have nested content
-		if err := vm.validateViewElementContent(element); err != nil {
+	if vm.isMarkdownElement(element) {
+		rendered, err := vm.transformMarkdownElement(element)
+		if err != nil {
 			return nil, err
 		}
+		if vm.currentContext != "" {
+			return []ast.Stmt{vm.createAppendStatement(vm.currentContext, rendered)}, nil
+		}
+		return []ast.Stmt{&ast.ExprStmt{Expr: rendered, Span: element.Span}}, nil
+	}
 
+	// Check if this element is actually a view composition
+	if viewStmt, isView := vm.isViewElement(element); isView {
 		// Validate slot usage before processing
 		if err := vm.validateSlotUsage(element); err != nil {
 			return nil, err
 		}
 
-		// Create the view call with slot processing
-		transformedView, err := vm.transformViewCallWithSlots(viewStmt, element)
+		// Create the view call with slot processing, after the statements
+		// building slot content that needs them
+		setup, viewCall, err := vm.transformViewCallWithSlots(viewStmt, element)
 		if err != nil {
 			return nil, err
 		}
+		statements = append(statements, setup...)
+		transformedView := awaitRender(viewStmt, viewCall)
 
 		// Append to current context if we have one
 		if vm.currentContext != "" {
@@ -53,9 +56,13 @@ func (vm *ViewTransformer) processHTMLElement(element *ast.HTMLElement) ([]ast.S
 		return statements, nil
 	}
 
-	// Check for undefined PascalCase components (likely a typo or missing view definition)
-	if vm.isPascalCase(tagName) {
-		return nil, fmt.Errorf("undefined view component '%s' at %s. Views must be defined before use. If this is meant to be an HTML tag, use lowercase", tagName, element.Span)
+	// A <slot> renders the content given for it, or its fallback content
+	if element.TagName.Lexeme == "slot" && element.TagExpr == nil {
+		return vm.processSlotElement(element)
+	}
+
+	if err := vm.validateElementTag(element); err != nil {
+		return nil, err
 	}
 
 	// Regular HTML element processing...
@@ -105,51 +112,98 @@ func (vm *ViewTransformer) createAppendStatement(arrayName string, element ast.E
 		Span: lexer.Span{},
 	}
 
+	// The statement keeps the element's span so source maps point at the markup
 	return &ast.ExprStmt{
 		Expr: appendCall,
-		Span: lexer.Span{},
+		Span: element.GetSpan(),
 	}
 }
 
 // transformHTMLElement transforms an HTMLElement into an el() call
 func (vm *ViewTransformer) transformHTMLElement(element *ast.HTMLElement) (ast.Expr, error) {
-	// Extract the tag name first
-	tagName := element.TagName.Lexeme
+	if err := vm.validateSlotParams(element); err != nil {
+		return nil, err
+	}
+
+	if vm.isMarkdownElement(element) {
+		return vm.transformMarkdownElement(element)
+	}
 
 	// Check if this element is actually a view composition
 	if viewStmt, isView := vm.isViewElement(element); isView {
-		// Validate that view elements don't have nested content
-		if err := vm.validateViewElementContent(element); err != nil {
+		if err := vm.validateSlotUsage(element); err != nil {
 			return nil, err
 		}
 		// This is a view composition - create a view instantiation call
-		return vm.transformViewCall(viewStmt, element.Attributes), nil
+		setup, call, err := vm.transformViewCallWithSlots(viewStmt, element)
+		if err != nil {
+			return nil, err
+		}
+		if len(setup) > 0 {
+			// Content needing statements makes the enclosing element use
+			// hierarchical processing, so this indicates a logic error
+			return nil, fmt.Errorf("slot content of <%s> needs statements in expression context at %s", element.TagName.Lexeme, element.Span)
+		}
+		return awaitRender(viewStmt, call), nil
+	}
+
+	// A <slot> renders the content given for it, or its fallback content
+	if element.TagName.Lexeme == "slot" && element.TagExpr == nil {
+		return vm.transformSlotElementToExpression(element)
 	}
 
-	// Check for undefined PascalCase components (likely a typo or missing view definition)
-	if vm.isPascalCase(tagName) {
-		return nil, fmt.Errorf("undefined view component '%s' at %s. Views must be defined before use. If this is meant to be an HTML tag, use lowercase", tagName, element.Span)
+	if element.IsFragment() {
+		return vm.transformFragment(element)
+	}
+
+	if err := vm.validateElementTag(element); err != nil {
+		return nil, err
 	}
 
 	// Regular HTML element processing...
 
 	// Transform attributes
-	var attrsExpr ast.Expr
-	if len(element.Attributes) > 0 {
-		transformedAttrs, err := vm.transformHTMLAttributes(element.Attributes)
+	attributes, err := vm.resolveAssetAttributes(element)
+	if err != nil {
+		return nil, err
+	}
+	attrsExpr, err := vm.transformHTMLAttributes(element, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transform the content: the text of a <script> or <style> as written,
+	// and that of a whitespace element without collapsing its whitespace
+	var contentExpr ast.Expr
+	if isScriptElement(element) {
+		contentExpr = vm.scriptContent(element)
+	} else {
+		if isWhitespaceElement(element) {
+			vm.preformatted++
+			defer func() { vm.preformatted-- }()
+		}
+		contentExpr, err = vm.transformHTMLContent(element.Content)
 		if err != nil {
 			return nil, err
 		}
-		attrsExpr = transformedAttrs
 	}
 
-	// Transform the content
-	contentExpr, err := vm.transformHTMLContent(element.Content)
-	if err != nil {
-		return nil, err
-	}
+	return vm.createElCall(vm.elementTag(element), contentExpr, attrsExpr), nil
+}
 
-	return vm.createElCall(tagName, contentExpr, attrsExpr), nil
+// transformFragment transforms a <>...</> fragment into a fragment() call
+// of its content
+func (vm *ViewTransformer) transformFragment(element *ast.HTMLElement) (ast.Expr, error) {
+	content := vm.withoutHTMLComments(element.Content)
+	items := make([]ast.Expr, 0, len(content))
+	for _, item := range content {
+		expr, err := vm.transformHTMLContentItem(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, expr)
+	}
+	return vm.createFragmentCall(&ast.ListExpr{Elements: items, Span: element.Span}, element.Span), nil
 }
 
 // transformHTMLElementWithStatements transforms an HTML element whose content
@@ -158,22 +212,29 @@ func (vm *ViewTransformer) transformHTMLElement(element *ast.HTMLElement) (ast.E
 func (vm *ViewTransformer) transformHTMLElementWithStatements(
 	element *ast.HTMLElement,
 ) ([]ast.Stmt, error) {
-	// Extract the tag name
-	tagName := element.TagName.Lexeme
-
 	// Transform attributes (same as expression mode)
-	var attrsExpr ast.Expr
-	if len(element.Attributes) > 0 {
-		transformedAttrs, err := vm.transformHTMLAttributes(element.Attributes)
-		if err != nil {
-			return nil, err
-		}
-		attrsExpr = transformedAttrs
+	attributes, err := vm.resolveAssetAttributes(element)
+	if err != nil {
+		return nil, err
+	}
+	attrsExpr, err := vm.transformHTMLAttributes(element, attributes)
+	if err != nil {
+		return nil, err
 	}
 
 	// Push a new context for this element's children
 	// This creates a unique variable name like "_div_children_1000"
-	contextName := vm.pushContext(tagName)
+	prefix := element.TagName.Lexeme
+	if element.TagExpr != nil {
+		prefix = "element"
+	} else if element.IsFragment() {
+		prefix = "fragment"
+	}
+	contextName := vm.pushContext(prefix)
+	if isWhitespaceElement(element) {
+		vm.preformatted++
+		defer func() { vm.preformatted-- }()
+	}
 
 	// Create the children array initialization: _div_children_1000 = []
 	createArray := &ast.AssignStmt{
@@ -207,11 +268,16 @@ func (vm *ViewTransformer) transformHTMLElementWithStatements(
 	// Pop the context to restore the previous one
 	vm.popContext()
 
-	// Create the el() call with the children array as content
-	elCall := vm.createElCall(tagName, &ast.Name{
+	// Create the el() call with the children array as content, or the
+	// fragment() call of a fragment
+	children := &ast.Name{
 		Token: lexer.Token{Lexeme: contextName, Type: lexer.Identifier},
 		Span:  lexer.Span{},
-	}, attrsExpr)
+	}
+	var elCall ast.Expr = vm.createElCall(vm.elementTag(element), children, attrsExpr)
+	if element.IsFragment() {
+		elCall = vm.createFragmentCall(children, element.Span)
+	}
 
 	// If we're in a parent context, append this element to it
 	if vm.currentContext != "" {
@@ -229,8 +295,12 @@ func (vm *ViewTransformer) transformHTMLElementWithStatements(
 	return statements, nil
 }
 
-// transformHTMLAttributes transforms HTML attributes into a Python dictionary expression
-func (vm *ViewTransformer) transformHTMLAttributes(attributes []ast.HTMLAttribute) (ast.Expr, error) {
+// transformHTMLAttributes transforms the attributes of an element, with the
+// defaults declared for its tag, into a Python dictionary expression
+func (vm *ViewTransformer) transformHTMLAttributes(element *ast.HTMLElement, attributes []ast.HTMLAttribute) (ast.Expr, error) {
+	if element.TagExpr == nil {
+		attributes = vm.withDefaultAttributes(element.TagName.Lexeme, element, attributes)
+	}
 	if len(attributes) == 0 {
 		return nil, nil
 	}
@@ -238,6 +308,17 @@ func (vm *ViewTransformer) transformHTMLAttributes(attributes []ast.HTMLAttribut
 	var dictPairs []ast.DictPair
 
 	for _, attr := range attributes {
+		// A {...mapping} spread unpacks into the dictionary in place, so the
+		// attributes after it override its items and it overrides those
+		// before. The runtime escapes its values when rendering.
+		if attr.Spread {
+			dictPairs = append(dictPairs, &ast.DoubleStarredPair{
+				Expr: vm.transformExpression(attr.Value),
+				Span: attr.Span,
+			})
+			continue
+		}
+
 		// Create the key (attribute name)
 		keyExpr := &ast.Literal{
 			Type:  ast.LiteralTypeString,
@@ -255,23 +336,8 @@ func (vm *ViewTransformer) transformHTMLAttributes(attributes []ast.HTMLAttribut
 				Span:  attr.Span,
 			}
 		} else {
-			// Transform the attribute value, applying view parameter transformation
-			transformedValue := vm.transformExpression(attr.Value)
-
-			// Check if this is a static string literal - no need to escape
-			if literal, ok := attr.Value.(*ast.Literal); ok && literal.Type == ast.LiteralTypeString {
-				valueExpr = transformedValue
-			} else {
-				// Dynamic expression - wrap with escape() for security
-				valueExpr = &ast.Call{
-					Callee: &ast.Name{
-						Token: lexer.Token{Lexeme: "escape", Type: lexer.Identifier},
-						Span:  attr.Span,
-					},
-					Arguments: []*ast.Argument{{Value: transformedValue, Span: attr.Span}},
-					Span:      attr.Span,
-				}
-			}
+			// Lower the attribute value, applying view parameter transformation
+			valueExpr = vm.lowerAttributeValue(attr.Name.Lexeme, attr.Value, attr.Span)
 		}
 
 		// Create the key-value pair
@@ -293,6 +359,7 @@ func (vm *ViewTransformer) transformHTMLAttributes(attributes []ast.HTMLAttribut
 
 // transformHTMLContent transforms HTML content (nested elements, text, etc.) into appropriate expressions
 func (vm *ViewTransformer) transformHTMLContent(content []ast.Stmt) (ast.Expr, error) {
+	content = vm.withoutHTMLComments(content)
 	if len(content) == 0 {
 		// Empty content
 		return &ast.Literal{
@@ -338,6 +405,10 @@ func (vm *ViewTransformer) transformHTMLContentItem(item ast.Stmt) (ast.Expr, er
 		// HTML content with text and interpolations
 		return vm.transformHTMLContentParts(content.Parts)
 
+	case *ast.HTMLComment:
+		// Only reached when comments are rendered
+		return vm.transformHTMLComment(content), nil
+
 	case *ast.ExprStmt:
 		// Expression statement - escape all expressions used as HTML content
 		transformedExpr := vm.transformExpression(content.Expr)
@@ -367,6 +438,7 @@ func (vm *ViewTransformer) transformHTMLContentItem(item ast.Stmt) (ast.Expr, er
 
 // transformHTMLContentParts transforms HTML content parts (text + interpolations)
 func (vm *ViewTransformer) transformHTMLContentParts(parts []ast.HTMLContentPart) (ast.Expr, error) {
+	parts = trimParts(parts)
 	if len(parts) == 0 {
 		return &ast.Literal{
 			Type:  ast.LiteralTypeString,
@@ -382,7 +454,7 @@ func (vm *ViewTransformer) transformHTMLContentParts(parts []ast.HTMLContentPart
 			// Pure text
 			return &ast.Literal{
 				Type:  ast.LiteralTypeString,
-				Value: part.Value,
+				Value: vm.textValue(part.Value),
 				Span:  part.Span,
 			}, nil
 
@@ -409,7 +481,7 @@ func (vm *ViewTransformer) transformHTMLContentParts(parts []ast.HTMLContentPart
 		case *ast.HTMLText:
 			// Add text as an f-string middle part
 			fStringParts = append(fStringParts, &ast.FStringMiddle{
-				Value: p.Value,
+				Value: vm.textValue(p.Value),
 				Span:  p.Span,
 			})
 
@@ -464,8 +536,21 @@ func (vm *ViewTransformer) processHTMLContent(content *ast.HTMLContent) ([]ast.S
 	}}, nil
 }
 
+// elementTag returns the tag argument of an element's el() call: the tag
+// name as a string, or the expression computing it
+func (vm *ViewTransformer) elementTag(element *ast.HTMLElement) ast.Expr {
+	if element.TagExpr != nil {
+		return vm.transformExpression(element.TagExpr)
+	}
+	return &ast.Literal{
+		Type:  ast.LiteralTypeString,
+		Value: element.TagName.Lexeme,
+		Span:  element.TagName.Span,
+	}
+}
+
 // createElCall creates an el() function call
-func (vm *ViewTransformer) createElCall(tag string, content ast.Expr, attrs ast.Expr) *ast.Call {
+func (vm *ViewTransformer) createElCall(tag ast.Expr, content ast.Expr, attrs ast.Expr) *ast.Call {
 	// Create el function reference
 	elFunc := &ast.Name{
 		Token: lexer.Token{
@@ -477,12 +562,8 @@ func (vm *ViewTransformer) createElCall(tag string, content ast.Expr, attrs ast.
 
 	// Create tag argument
 	tagLiteral := &ast.Argument{
-		Value: &ast.Literal{
-			Type:  ast.LiteralTypeString,
-			Value: tag,
-			Span:  content.GetSpan(),
-		},
-		Span: content.GetSpan(),
+		Value: tag,
+		Span:  content.GetSpan(),
 	}
 
 	contentArg := &ast.Argument{
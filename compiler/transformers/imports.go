@@ -1,6 +1,9 @@
 package transformers
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
@@ -9,127 +12,89 @@ import (
 func (vm *ViewTransformer) GetRequiredImports() []*ast.ImportFromStmt {
 	var imports []*ast.ImportFromStmt
 
-	if vm.needsRuntimeImports {
-		// Create single combined import: from topple.psx import BaseView, Element, el, escape, fragment, raw
-		runtimeImport := &ast.ImportFromStmt{
-			DottedName: &ast.DottedName{
-				Names: []*ast.Name{
-					{
-						Token: lexer.Token{
-							Lexeme: "topple",
-							Type:   lexer.Identifier,
-						},
-						Span: lexer.Span{},
-					},
-					{
-						Token: lexer.Token{
-							Lexeme: "psx",
-							Type:   lexer.Identifier,
-						},
-						Span: lexer.Span{},
-					},
+	// Slot annotations use typing.Union, and scoped slot annotations also
+	// typing.Callable and typing.Any: from typing import Any, Callable, Union
+	if vm.needsUnion {
+		typingNames := []string{"Union"}
+		if vm.needsCallable {
+			typingNames = []string{"Any", "Callable", "Union"}
+		}
+		var names []*ast.ImportName
+		for _, name := range typingNames {
+			names = append(names, &ast.ImportName{
+				DottedName: &ast.DottedName{
+					Names: []*ast.Name{{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}}},
 				},
-				Span: lexer.Span{},
+			})
+		}
+		imports = append(imports, &ast.ImportFromStmt{
+			DottedName: &ast.DottedName{
+				Names: []*ast.Name{{Token: lexer.Token{Lexeme: "typing", Type: lexer.Identifier}}},
 			},
-			Names: []*ast.ImportName{
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "BaseView",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
-							},
-						},
-						Span: lexer.Span{},
-					},
-					AsName: nil,
-					Span:   lexer.Span{},
-				},
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "Element",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
-							},
-						},
-						Span: lexer.Span{},
-					},
-					AsName: nil,
-					Span:   lexer.Span{},
-				},
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "el",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
-							},
-						},
-						Span: lexer.Span{},
-					},
-					AsName: nil,
-					Span:   lexer.Span{},
-				},
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "escape",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
-							},
-						},
-						Span: lexer.Span{},
-					},
-					AsName: nil,
-					Span:   lexer.Span{},
-				},
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "fragment",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
+			Names: names,
+		})
+	}
+
+	if vm.needsRuntimeImports {
+		// Create single combined import, such as: from topple.psx import BaseView, Element, el, escape, fragment, raw, require_api
+		// The names depend on the targeted runtime API version
+		var names []*ast.ImportName
+		shimNames := vm.runtimeAPI.shim().names
+		var helpers []string
+		if vm.needsRenderChild {
+			helpers = append(helpers, "render_child")
+		}
+		if vm.needsClassNames {
+			helpers = append(helpers, "class_names")
+		}
+		if vm.needsStyleValue {
+			helpers = append(helpers, "style_value")
+		}
+		if len(helpers) > 0 {
+			shimNames = append(slices.Clone(shimNames), helpers...)
+			slices.Sort(shimNames)
+		}
+		for _, name := range shimNames {
+			names = append(names, &ast.ImportName{
+				DottedName: &ast.DottedName{
+					Names: []*ast.Name{
+						{
+							Token: lexer.Token{
+								Lexeme: name,
+								Type:   lexer.Identifier,
 							},
+							Span: lexer.Span{},
 						},
-						Span: lexer.Span{},
 					},
-					AsName: nil,
-					Span:   lexer.Span{},
+					Span: lexer.Span{},
 				},
-				{
-					DottedName: &ast.DottedName{
-						Names: []*ast.Name{
-							{
-								Token: lexer.Token{
-									Lexeme: "raw",
-									Type:   lexer.Identifier,
-								},
-								Span: lexer.Span{},
-							},
-						},
-						Span: lexer.Span{},
-					},
-					AsName: nil,
-					Span:   lexer.Span{},
+				AsName: nil,
+				Span:   lexer.Span{},
+			})
+		}
+
+		runtimeModule := vm.runtimeModule
+		if runtimeModule == "" {
+			runtimeModule = DefaultRuntimeModule
+		}
+		var parts []*ast.Name
+		for _, part := range strings.Split(runtimeModule, ".") {
+			parts = append(parts, &ast.Name{
+				Token: lexer.Token{
+					Lexeme: part,
+					Type:   lexer.Identifier,
 				},
+				Span: lexer.Span{},
+			})
+		}
+
+		runtimeImport := &ast.ImportFromStmt{
+			DottedName: &ast.DottedName{
+				Names: parts,
+				Span:  lexer.Span{},
 			},
-			Span: lexer.Span{},
+			Names: names,
+			Span:  lexer.Span{},
 		}
 		imports = append(imports, runtimeImport)
 	}
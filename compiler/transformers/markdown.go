@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/markdown"
+)
+
+// MarkdownTag is the element whose content is rendered as Markdown when the
+// Markdown option is enabled
+const MarkdownTag = "Markdown"
+
+// isMarkdownElement reports whether element is a <Markdown> block to render
+// at compile time
+func (vm *ViewTransformer) isMarkdownElement(element *ast.HTMLElement) bool {
+	return vm.markdown && element.TagName.Lexeme == MarkdownTag
+}
+
+// transformMarkdownElement renders a <Markdown> block to HTML and hoists it to
+// a module-level constant, returning a reference to that constant
+func (vm *ViewTransformer) transformMarkdownElement(element *ast.HTMLElement) (ast.Expr, error) {
+	if len(element.Attributes) > 0 {
+		return nil, fmt.Errorf("<%s> at %s does not take attributes", MarkdownTag, element.Span)
+	}
+
+	var source strings.Builder
+	for _, item := range element.Content {
+		content, ok := item.(*ast.HTMLContent)
+		if !ok {
+			return nil, fmt.Errorf("<%s> at %s may only contain Markdown text", MarkdownTag, element.Span)
+		}
+		for _, part := range content.Parts {
+			text, ok := part.(*ast.HTMLText)
+			if !ok {
+				return nil, fmt.Errorf("<%s> at %s may only contain Markdown text", MarkdownTag, element.Span)
+			}
+			source.WriteString(text.Value)
+		}
+	}
+
+	name := fmt.Sprintf("_markdown_%d", len(vm.hoisted)+1)
+	vm.hoisted = append(vm.hoisted, &ast.AssignStmt{
+		Targets: []ast.Expr{&ast.Name{
+			Token: lexer.Token{Lexeme: name, Type: lexer.Identifier},
+			Span:  element.Span,
+		}},
+		Value: &ast.Call{
+			Callee: &ast.Name{
+				Token: lexer.Token{Lexeme: "raw", Type: lexer.Identifier},
+				Span:  element.Span,
+			},
+			Arguments: []*ast.Argument{{
+				Value: &ast.Literal{
+					Type:  ast.LiteralTypeString,
+					Value: markdown.Render(dedent(source.String())),
+					Span:  element.Span,
+				},
+				Span: element.Span,
+			}},
+			Span: element.Span,
+		},
+		Span: element.Span,
+	})
+
+	return &ast.Name{
+		Token: lexer.Token{Lexeme: name, Type: lexer.Identifier},
+		Span:  element.Span,
+	}, nil
+}
+
+// GetHoistedStatements returns the module-level statements, such as rendered
+// Markdown blocks, that transformed views refer to
+func (vm *ViewTransformer) GetHoistedStatements() []ast.Stmt {
+	return vm.hoisted
+}
+
+// dedent removes the indentation that all non-blank lines of text share, so
+// Markdown nested in a view is not read as an indented code block
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
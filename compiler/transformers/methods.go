@@ -1,6 +1,8 @@
 package transformers
 
 import (
+	"fmt"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
@@ -45,17 +47,28 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 		}
 	}
 
+	// Slots become keyword parameters, so their names must be identifiers
+	for _, slotName := range vm.slotOrder {
+		if slotName != "" && !isIdentifier(slotName) {
+			return nil, fmt.Errorf("slot name '%s' of view %s is not a valid Python identifier", slotName, viewStmt.Name.Token.Lexeme)
+		}
+	}
+
 	// Add slot parameters if we have slots
 	if len(vm.slots) > 0 {
-		// Add star parameter to make slot parameters keyword-only
-		starParam := &ast.Parameter{
-			Name:       nil, // Unnamed star parameter
-			Default:    nil,
-			Annotation: nil,
-			IsStar:     true,
-			Span:       viewStmt.Span,
+		// Add star parameter to make slot parameters keyword-only, unless
+		// the view's own parameters end with *args or keyword-only ones
+		last := initParams[len(initParams)-1]
+		if !last.IsStar && !last.IsKeywordOnly {
+			starParam := &ast.Parameter{
+				Name:       nil, // Unnamed star parameter
+				Default:    nil,
+				Annotation: nil,
+				IsStar:     true,
+				Span:       viewStmt.Span,
+			}
+			initParams = append(initParams, starParam)
 		}
-		initParams = append(initParams, starParam)
 
 		// Add default slot parameter first (children)
 		if _, hasDefaultSlot := vm.slots[""]; hasDefaultSlot {
@@ -72,7 +85,7 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 					Value: nil,
 					Span:  viewStmt.Span,
 				},
-				Annotation: vm.createSlotTypeAnnotation(),
+				Annotation: vm.createSlotTypeAnnotation(vm.slots[""].Scoped),
 				IsStar:     false,
 				Span:       viewStmt.Span,
 			}
@@ -95,7 +108,7 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 						Value: nil,
 						Span:  viewStmt.Span,
 					},
-					Annotation: vm.createSlotTypeAnnotation(),
+					Annotation: vm.createSlotTypeAnnotation(vm.slots[slotName].Scoped),
 					IsStar:     false,
 					Span:       viewStmt.Span,
 				}
@@ -104,6 +117,34 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 		}
 	}
 
+	// A slot named at render time takes the content of any slot: **slots
+	if vm.computedSlots {
+		if viewStmt.Params != nil {
+			for _, param := range viewStmt.Params.Parameters {
+				if param == nil || param.Name == nil {
+					continue
+				}
+				if param.IsDoubleStar {
+					return nil, fmt.Errorf("view %s cannot take **%s: its <slot name={...}> takes the keyword arguments no parameter does",
+						viewStmt.Name.Token.Lexeme, param.Name.Token.Lexeme)
+				}
+				if param.Name.Token.Lexeme == "slots" {
+					return nil, fmt.Errorf("view %s cannot have a parameter named 'slots': its <slot name={...}> takes the content of slots as **slots",
+						viewStmt.Name.Token.Lexeme)
+				}
+			}
+		}
+		initParams = append(initParams, &ast.Parameter{
+			Name: &ast.Name{
+				Token: lexer.Token{Lexeme: "slots", Type: lexer.Identifier},
+				Span:  viewStmt.Span,
+			},
+			Annotation:   vm.createSlotTypeAnnotation(vm.computedSlotsScoped),
+			IsDoubleStar: true,
+			Span:         viewStmt.Span,
+		})
+	}
+
 	// Create parameter list
 	paramList := &ast.ParameterList{
 		Parameters:  initParams,
@@ -112,6 +153,11 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 		KwArgIndex:  -1,
 		Span:        viewStmt.Span,
 	}
+	if viewStmt.Params != nil && viewStmt.Params.HasSlash {
+		// Positional-only parameters stay so, after self
+		paramList.HasSlash = true
+		paramList.SlashIndex = viewStmt.Params.SlashIndex + 1
+	}
 
 	// Create assignment statements for each view parameter
 	var initBody []ast.Stmt
@@ -213,6 +259,44 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 		}
 	}
 
+	// Slots named at render time are looked up in self._slots, which holds
+	// the named slots and the content of any other: {"header": header, **slots}
+	if vm.computedSlots {
+		var pairs []ast.DictPair
+		for _, slotName := range vm.slotOrder {
+			if slotName == "" {
+				continue
+			}
+			pairs = append(pairs, &ast.KeyValuePair{
+				Key: &ast.Literal{Type: ast.LiteralTypeString, Value: slotName, Span: viewStmt.Span},
+				Value: &ast.Name{
+					Token: lexer.Token{Lexeme: slotName, Type: lexer.Identifier},
+					Span:  viewStmt.Span,
+				},
+				Span: viewStmt.Span,
+			})
+		}
+		pairs = append(pairs, &ast.DoubleStarredPair{
+			Expr: &ast.Name{
+				Token: lexer.Token{Lexeme: "slots", Type: lexer.Identifier},
+				Span:  viewStmt.Span,
+			},
+			Span: viewStmt.Span,
+		})
+		initBody = append(initBody, &ast.AssignStmt{
+			Targets: []ast.Expr{&ast.Attribute{
+				Object: &ast.Name{
+					Token: lexer.Token{Lexeme: "self", Type: lexer.Identifier},
+					Span:  viewStmt.Span,
+				},
+				Name: lexer.Token{Lexeme: "_slots", Type: lexer.Identifier},
+				Span: viewStmt.Span,
+			}},
+			Value: &ast.DictExpr{Pairs: pairs, Span: viewStmt.Span},
+			Span:  viewStmt.Span,
+		})
+	}
+
 	// If no body, add pass statement
 	if len(initBody) == 0 {
 		passStmt := &ast.PassStmt{
@@ -225,10 +309,13 @@ func (vm *ViewTransformer) createInitMethod(viewStmt *ast.ViewStmt) (*ast.Functi
 		Name:           initName,
 		TypeParameters: nil,
 		Parameters:     paramList,
-		ReturnType:     nil,
-		Body:           initBody,
-		IsAsync:        false,
-		Span:           viewStmt.Span,
+		ReturnType: &ast.Name{
+			Token: lexer.Token{Lexeme: "None", Type: lexer.Identifier},
+			Span:  viewStmt.Span,
+		},
+		Body:    initBody,
+		IsAsync: false,
+		Span:    viewStmt.Span,
 	}, nil
 }
 
@@ -276,6 +363,10 @@ func (vm *ViewTransformer) createRenderMethod(viewStmt *ast.ViewStmt) (*ast.Func
 		Span: viewStmt.Span,
 	}
 
+	if err := vm.validateViewReturns(viewStmt.Body); err != nil {
+		return nil, err
+	}
+
 	// Transform view body into _render method body
 	renderBody, err := vm.transformViewBody(viewStmt.Body)
 	if err != nil {
@@ -288,7 +379,79 @@ func (vm *ViewTransformer) createRenderMethod(viewStmt *ast.ViewStmt) (*ast.Func
 		Parameters:     paramList,
 		ReturnType:     elementType,
 		Body:           renderBody,
-		IsAsync:        false,
+		IsAsync:        viewStmt.IsAsync,
 		Span:           viewStmt.Span,
 	}, nil
 }
+
+// createLayoutRenderMethod creates the _render method of a view with a
+// layout: it instantiates the layout of 'use layout' with the content that
+// _render_content renders as children, so the layout wraps the view's output
+func (vm *ViewTransformer) createLayoutRenderMethod(viewStmt *ast.ViewStmt) *ast.Function {
+	span := viewStmt.Layout.GetSpan()
+	self := &ast.Name{
+		Token: lexer.Token{Lexeme: "self", Type: lexer.Identifier},
+		Span:  span,
+	}
+
+	// self._render_content(), awaited in an async view
+	var content ast.Expr = &ast.Call{
+		Callee: &ast.Attribute{
+			Object: self,
+			Name:   lexer.Token{Lexeme: "_render_content", Type: lexer.Identifier},
+			Span:   span,
+		},
+		Span: span,
+	}
+	if viewStmt.IsAsync {
+		content = &ast.AwaitExpr{Expr: content, Span: span}
+	}
+	children := &ast.Argument{
+		Name: &ast.Name{
+			Token: lexer.Token{Lexeme: "children", Type: lexer.Identifier},
+			Span:  span,
+		},
+		Value: content,
+		Span:  span,
+	}
+
+	// Layout(args..., children=content); a bare layout name takes no arguments
+	layout := vm.transformExpression(viewStmt.Layout)
+	call, ok := layout.(*ast.Call)
+	if ok {
+		call = &ast.Call{
+			Callee:    call.Callee,
+			Arguments: append(append([]*ast.Argument{}, call.Arguments...), children),
+			Span:      call.Span,
+		}
+	} else {
+		call = &ast.Call{Callee: layout, Arguments: []*ast.Argument{children}, Span: span}
+	}
+	var result ast.Expr = call
+	if vm.resolutionTable != nil {
+		if layoutStmt, known := vm.resolutionTable.Layouts[viewStmt]; known {
+			result = awaitRender(layoutStmt, call)
+		}
+	}
+
+	return &ast.Function{
+		Name: &ast.Name{
+			Token: lexer.Token{Lexeme: "_render", Type: lexer.Identifier},
+			Span:  viewStmt.Span,
+		},
+		Parameters: &ast.ParameterList{
+			Parameters:  []*ast.Parameter{{Name: self, Span: span}},
+			SlashIndex:  -1,
+			VarArgIndex: -1,
+			KwArgIndex:  -1,
+			Span:        span,
+		},
+		ReturnType: &ast.Name{
+			Token: lexer.Token{Lexeme: "Element", Type: lexer.Identifier},
+			Span:  span,
+		},
+		Body:    []ast.Stmt{&ast.ReturnStmt{Value: result, Span: span}},
+		IsAsync: viewStmt.IsAsync,
+		Span:    viewStmt.Span,
+	}
+}
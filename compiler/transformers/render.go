@@ -9,8 +9,16 @@ import (
 func (vm *ViewTransformer) transformViewBody(body []ast.Stmt) ([]ast.Stmt, error) {
 	var transformedBody []ast.Stmt
 
-	// Handle empty body
-	if len(body) == 0 {
+	// Handle empty body (comments that aren't rendered produce no output)
+	if len(vm.withoutHTMLComments(body)) == 0 {
+		for _, stmt := range body {
+			stmts, err := vm.processViewStatement(stmt)
+			if err != nil {
+				return nil, err
+			}
+			transformedBody = append(transformedBody, stmts...)
+		}
+
 		// Return empty fragment for empty views
 		returnValue := &ast.Call{
 			Callee: &ast.Name{
@@ -27,12 +35,14 @@ func (vm *ViewTransformer) transformViewBody(body []ast.Stmt) ([]ast.Stmt, error
 			Value: returnValue,
 			Span:  lexer.Span{},
 		}
-		return []ast.Stmt{returnStmt}, nil
+		return append(transformedBody, returnStmt), nil
 	}
 
 	// Check if we need hierarchical processing FIRST before processing statements
 	// This ensures we have the correct context when processing control structures
-	needsHierarchy := vm.needsHierarchicalProcessing(body)
+	// Bodies that don't end with markup are built into a children array too,
+	// so trailing Python statements run before the markup is returned
+	needsHierarchy := vm.needsHierarchicalProcessing(body) || !vm.endsWithMarkup(body)
 
 	// Handle single statement case
 	if len(body) == 1 && !needsHierarchy {
@@ -60,6 +70,9 @@ func (vm *ViewTransformer) transformViewBody(body []ast.Stmt) ([]ast.Stmt, error
 	if needsHierarchy {
 		// Push a new context for the root elements
 		rootContext := vm.pushContext("root")
+		outerRoot := vm.rootContext
+		vm.rootContext = rootContext
+		defer func() { vm.rootContext = outerRoot }()
 
 		// Create the array for root elements
 		createArray := &ast.AssignStmt{
@@ -90,6 +103,11 @@ func (vm *ViewTransformer) transformViewBody(body []ast.Stmt) ([]ast.Stmt, error
 		// Pop context
 		vm.popContext()
 
+		// A trailing return already decides what the view renders
+		if _, ok := body[len(body)-1].(*ast.ReturnStmt); ok {
+			return transformedBody, nil
+		}
+
 		// Return the fragment with the root elements
 		returnValue := &ast.Call{
 			Callee: &ast.Name{
@@ -123,8 +141,15 @@ func (vm *ViewTransformer) transformViewBody(body []ast.Stmt) ([]ast.Stmt, error
 		// Convert the last ExprStmt to a ReturnStmt
 		// This handles cases where we have variable assignments followed by an HTML element
 		// Example: features = [...]; <html>...</html> should return the html element
-		if len(transformedBody) > 0 {
-			lastIdx := len(transformedBody) - 1
+		// Trailing comments kept for the generated code don't count as the last statement.
+		lastIdx := len(transformedBody) - 1
+		for lastIdx >= 0 {
+			if _, ok := transformedBody[lastIdx].(*ast.HTMLComment); !ok {
+				break
+			}
+			lastIdx--
+		}
+		if lastIdx >= 0 {
 			if exprStmt, ok := transformedBody[lastIdx].(*ast.ExprStmt); ok {
 				transformedBody[lastIdx] = &ast.ReturnStmt{
 					Value: exprStmt.Expr,
@@ -158,6 +183,8 @@ func (vm *ViewTransformer) processViewStatement(stmt ast.Stmt) ([]ast.Stmt, erro
 	case *ast.HTMLContent:
 		// Process HTML content
 		return vm.processHTMLContent(s)
+	case *ast.HTMLComment:
+		return vm.processHTMLComment(s)
 	case *ast.ExprStmt:
 		// Transform the expression normally
 		transformed := vm.transformStatement(s)
@@ -167,27 +194,9 @@ func (vm *ViewTransformer) processViewStatement(stmt ast.Stmt) ([]ast.Stmt, erro
 		transformed := vm.transformStatement(s)
 		return []ast.Stmt{transformed}, nil
 	case *ast.ReturnStmt:
-		// Check if this is a bare return in hierarchical processing mode
+		// A bare return in hierarchical processing mode renders the markup built so far
 		if s.Value == nil && vm.currentContext != "" {
-			// Transform bare return into return fragment(current_children)
-			returnValue := &ast.Call{
-				Callee: &ast.Name{
-					Token: lexer.Token{Lexeme: "fragment", Type: lexer.Identifier},
-					Span:  lexer.Span{},
-				},
-				Arguments: []*ast.Argument{{
-					Value: &ast.Name{
-						Token: lexer.Token{Lexeme: vm.currentContext, Type: lexer.Identifier},
-						Span:  lexer.Span{},
-					},
-					Span: lexer.Span{},
-				}},
-				Span: s.Span,
-			}
-			return []ast.Stmt{&ast.ReturnStmt{
-				Value: returnValue,
-				Span:  s.Span,
-			}}, nil
+			return []ast.Stmt{vm.earlyReturn(s)}, nil
 		}
 		// Otherwise, transform normally (returns with values, or bare returns outside hierarchical mode)
 		transformed := vm.transformStatement(s)
@@ -212,10 +221,23 @@ func (vm *ViewTransformer) needsHierarchicalProcessing(content []ast.Stmt) bool
 			return true
 		case *ast.HTMLElement:
 			htmlElementCount++
+			// Scoped slot content is defined as a function before its view
+			if _, scoped := s.SlotParams(); scoped {
+				return true
+			}
 			// Check if the HTML element has complex content
 			if vm.needsHierarchicalProcessing(s.Content) {
 				return true
 			}
+		case *ast.HTMLComment:
+			switch vm.htmlComments {
+			case HTMLCommentsRender:
+				// Rendered comments are output nodes just like elements
+				htmlElementCount++
+			case HTMLCommentsPython:
+				// Python comments can only be emitted between statements
+				return true
+			}
 		}
 	}
 
@@ -0,0 +1,165 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// Python statements in a view body run in source order, interleaved with
+// the markup they surround. Statements other than markup, control flow and
+// return pass through unchanged; nested def and class bodies are plain
+// Python and are never rewritten.
+//
+// A bare `return` ends rendering early. The view renders the top-level
+// markup completed before the return: elements that are still open at that
+// point, and anything after it, are dropped. `return <value>` renders value
+// instead of the markup. Markup that follows a return, raise, break or
+// continue in the same block can never render and is reported as an error.
+
+// EarlyReturnMode selects whether `return` may short-circuit rendering
+type EarlyReturnMode int
+
+const (
+	// EarlyReturnsAllow lets `return` end rendering early (the default)
+	EarlyReturnsAllow EarlyReturnMode = iota
+	// EarlyReturnsForbid rejects `return` in view bodies, so all markup is
+	// always rendered and conditional output must use if/else
+	EarlyReturnsForbid
+)
+
+// ParseEarlyReturnMode parses "allow" or "forbid"
+func ParseEarlyReturnMode(s string) (EarlyReturnMode, error) {
+	switch s {
+	case "", "allow":
+		return EarlyReturnsAllow, nil
+	case "forbid":
+		return EarlyReturnsForbid, nil
+	default:
+		return EarlyReturnsAllow, fmt.Errorf("unknown early return mode %q (valid: allow, forbid)", s)
+	}
+}
+
+// validateViewReturns checks the returns of a view body and every block of
+// markup and control flow nested in it
+func (vm *ViewTransformer) validateViewReturns(body []ast.Stmt) error {
+	var exit ast.Stmt // Statement that leaves the block, once seen
+
+	for _, stmt := range body {
+		if exit != nil && isMarkup(stmt) {
+			return fmt.Errorf("unreachable markup at %s: it follows the %s at %s", stmt.GetSpan(), exitKeyword(exit), exit.GetSpan())
+		}
+
+		switch s := stmt.(type) {
+		case *ast.ReturnStmt:
+			if vm.earlyReturns == EarlyReturnsForbid {
+				return fmt.Errorf("'return' at %s is not allowed in view bodies by the project's settings; wrap conditional markup in if/else instead", s.Span)
+			}
+			exit = s
+		case *ast.RaiseStmt, *ast.BreakStmt, *ast.ContinueStmt:
+			exit = s
+		}
+
+		for _, block := range nestedViewBlocks(stmt) {
+			if err := vm.validateViewReturns(block); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isMarkup reports whether a view body statement produces output
+func isMarkup(stmt ast.Stmt) bool {
+	switch stmt.(type) {
+	case *ast.HTMLElement, *ast.HTMLContent:
+		return true
+	}
+	return false
+}
+
+// exitKeyword names the statement that leaves a block
+func exitKeyword(stmt ast.Stmt) string {
+	switch stmt.(type) {
+	case *ast.RaiseStmt:
+		return "raise"
+	case *ast.BreakStmt:
+		return "break"
+	case *ast.ContinueStmt:
+		return "continue"
+	default:
+		return "return"
+	}
+}
+
+// nestedViewBlocks returns the statement lists inside stmt that are part of
+// the view body. Function and class bodies are not.
+func nestedViewBlocks(stmt ast.Stmt) [][]ast.Stmt {
+	switch s := stmt.(type) {
+	case *ast.HTMLElement:
+		return [][]ast.Stmt{s.Content}
+	case *ast.If:
+		return [][]ast.Stmt{s.Body, s.Else}
+	case *ast.For:
+		return [][]ast.Stmt{s.Body, s.Else}
+	case *ast.While:
+		return [][]ast.Stmt{s.Body, s.Else}
+	case *ast.With:
+		return [][]ast.Stmt{s.Body}
+	case *ast.Try:
+		blocks := [][]ast.Stmt{s.Body, s.Else, s.Finally}
+		for _, handler := range s.Excepts {
+			blocks = append(blocks, handler.Body)
+		}
+		return blocks
+	case *ast.MatchStmt:
+		var blocks [][]ast.Stmt
+		for _, c := range s.Cases {
+			blocks = append(blocks, c.Body)
+		}
+		return blocks
+	}
+	return nil
+}
+
+// endsWithMarkup reports whether the last statement of a view body that is
+// not a discarded comment produces output, so it can become the return value
+func (vm *ViewTransformer) endsWithMarkup(body []ast.Stmt) bool {
+	for i := len(body) - 1; i >= 0; i-- {
+		if _, ok := body[i].(*ast.HTMLComment); ok {
+			if vm.rendersHTMLComments() {
+				return true
+			}
+			continue
+		}
+		return isMarkup(body[i])
+	}
+	return false
+}
+
+// earlyReturn renders the markup completed so far: the top-level children
+// when a view body is being built, otherwise the current children
+func (vm *ViewTransformer) earlyReturn(stmt *ast.ReturnStmt) *ast.ReturnStmt {
+	children := vm.currentContext
+	if vm.rootContext != "" {
+		children = vm.rootContext
+	}
+	return &ast.ReturnStmt{
+		Value: &ast.Call{
+			Callee: &ast.Name{
+				Token: lexer.Token{Lexeme: "fragment", Type: lexer.Identifier},
+				Span:  lexer.Span{},
+			},
+			Arguments: []*ast.Argument{{
+				Value: &ast.Name{
+					Token: lexer.Token{Lexeme: children, Type: lexer.Identifier},
+					Span:  lexer.Span{},
+				},
+				Span: lexer.Span{},
+			}},
+			Span: stmt.Span,
+		},
+		Span: stmt.Span,
+	}
+}
@@ -0,0 +1,120 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// RuntimeAPI is a version of the API the topple.psx runtime package offers
+// generated code. A runtime supports modules compiled for its own version
+// and every earlier one.
+type RuntimeAPI int
+
+const (
+	// RuntimeAPILegacy is the runtime of topple 0.1.0, which predates
+	// versioning: modules compiled for it carry no version check
+	RuntimeAPILegacy RuntimeAPI = 1
+	// RuntimeAPIVersioned adds require_api, which generated modules call on
+	// import so an older runtime fails loudly instead of misrendering
+	RuntimeAPIVersioned RuntimeAPI = 2
+	// RuntimeAPIAttributes adds class_names and style_value, which serialize
+	// dicts and lists given to class and style
+	RuntimeAPIAttributes RuntimeAPI = 3
+
+	// CurrentRuntimeAPI is the version targeted unless another is requested.
+	// It must match API_VERSION in topple/psx.py.
+	CurrentRuntimeAPI = RuntimeAPIAttributes
+)
+
+// DefaultRuntimeModule is the module generated code imports the runtime from
+// unless another is configured
+const DefaultRuntimeModule = "topple.psx"
+
+// ParseRuntimeModule validates the dotted path of the module generated code
+// imports the runtime from, such as a vendored copy at myapp._vendor.psx;
+// "" selects DefaultRuntimeModule
+func ParseRuntimeModule(path string) (string, error) {
+	if path == "" {
+		return DefaultRuntimeModule, nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		valid := part != ""
+		for i, r := range part {
+			if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+				valid = false
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("invalid runtime module %q: expected a dotted module path such as topple.psx", path)
+		}
+	}
+	return path, nil
+}
+
+// runtimeShim describes how generated code uses one runtime API version
+type runtimeShim struct {
+	names []string // Names imported from the runtime module
+	guard bool     // Whether the module calls require_api with its version
+}
+
+var runtimeShims = map[RuntimeAPI]runtimeShim{
+	RuntimeAPILegacy: {
+		names: []string{"BaseView", "Element", "el", "escape", "fragment", "raw"},
+	},
+	RuntimeAPIVersioned: {
+		names: []string{"BaseView", "Element", "el", "escape", "fragment", "raw", "require_api"},
+		guard: true,
+	},
+	RuntimeAPIAttributes: {
+		names: []string{"BaseView", "Element", "el", "escape", "fragment", "raw", "require_api"},
+		guard: true,
+	},
+}
+
+// ParseRuntimeAPI validates a runtime API version; 0 selects CurrentRuntimeAPI
+func ParseRuntimeAPI(version int) (RuntimeAPI, error) {
+	if version == 0 {
+		return CurrentRuntimeAPI, nil
+	}
+	if _, ok := runtimeShims[RuntimeAPI(version)]; !ok {
+		return CurrentRuntimeAPI, fmt.Errorf("unsupported runtime API %d (valid: %d to %d)", version, RuntimeAPILegacy, CurrentRuntimeAPI)
+	}
+	return RuntimeAPI(version), nil
+}
+
+// shim returns the shim of the targeted version, the current one when unset
+func (v RuntimeAPI) shim() runtimeShim {
+	if shim, ok := runtimeShims[v]; ok {
+		return shim
+	}
+	return runtimeShims[CurrentRuntimeAPI]
+}
+
+// version returns the targeted version, the current one when unset
+func (v RuntimeAPI) version() RuntimeAPI {
+	if _, ok := runtimeShims[v]; ok {
+		return v
+	}
+	return CurrentRuntimeAPI
+}
+
+// GetRuntimeGuard returns the require_api call that checks the installed
+// runtime on import, or nil when no runtime imports are needed or the
+// targeted version has no check
+func (vm *ViewTransformer) GetRuntimeGuard() ast.Stmt {
+	if !vm.needsRuntimeImports || !vm.runtimeAPI.shim().guard {
+		return nil
+	}
+	return &ast.ExprStmt{
+		Expr: &ast.Call{
+			Callee: &ast.Name{Token: lexer.Token{Lexeme: "require_api", Type: lexer.Identifier}},
+			Arguments: []*ast.Argument{{
+				Value: &ast.Literal{Type: ast.LiteralTypeNumber, Value: int(vm.runtimeAPI.version())},
+			}},
+		},
+	}
+}
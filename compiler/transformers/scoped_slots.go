@@ -0,0 +1,272 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// validateSlotParams reports a let attribute on an element that is not
+// content passed to a view, the only place it binds values. Scoped content
+// reaches the element transformations without its let attribute.
+func (vm *ViewTransformer) validateSlotParams(element *ast.HTMLElement) error {
+	if _, scoped := element.SlotParams(); scoped {
+		return fmt.Errorf("let on <%s> at %s binds the values of a scoped slot, so it can only be used on content passed to a view element",
+			element.TagName.Lexeme, element.Span)
+	}
+	return nil
+}
+
+// slotArguments returns the values a <slot> passes to scoped content, its
+// attributes other than name and slot: <slot name="row" item={item} /> passes
+// item=item
+func (vm *ViewTransformer) slotArguments(slotElement *ast.HTMLElement) []*ast.Argument {
+	var args []*ast.Argument
+	for _, attr := range slotElement.Attributes {
+		if attr.Spread {
+			args = append(args, &ast.Argument{
+				Value:        vm.transformExpression(attr.Value),
+				IsDoubleStar: true,
+				Span:         attr.Span,
+			})
+			continue
+		}
+		if attr.Name.Lexeme == "name" || attr.Name.Lexeme == "slot" {
+			continue
+		}
+		var value ast.Expr = &ast.Literal{Type: ast.LiteralTypeBool, Value: true, Span: attr.Span}
+		if attr.Value != nil {
+			value = vm.transformExpression(attr.Value)
+		}
+		args = append(args, &ast.Argument{
+			Name:  &ast.Name{Token: attr.Name, Span: attr.Name.Span},
+			Value: value,
+			Span:  attr.Span,
+		})
+	}
+	return args
+}
+
+// scopedSlotContent returns the content to render for a slot given the
+// content passed for it. A slot passing values calls scoped content with
+// them, and renders other content as it is:
+// self.row(item=item) if callable(self.row) else self.row
+func (vm *ViewTransformer) scopedSlotContent(slotElement *ast.HTMLElement, value ast.Expr) ast.Expr {
+	args := vm.slotArguments(slotElement)
+	if len(args) == 0 {
+		return value
+	}
+	return &ast.TernaryExpr{
+		Condition: &ast.Call{
+			Callee: &ast.Name{
+				Token: lexer.Token{Lexeme: "callable", Type: lexer.Identifier},
+				Span:  slotElement.Span,
+			},
+			Arguments: []*ast.Argument{{Value: value, Span: slotElement.Span}},
+			Span:      slotElement.Span,
+		},
+		TrueExpr:  &ast.Call{Callee: value, Arguments: args, Span: slotElement.Span},
+		FalseExpr: value,
+		Span:      slotElement.Span,
+	}
+}
+
+// transformScopedSlotContent transforms content with a let attribute into a
+// function of the values the slot passes, defined by the returned statements:
+//
+//	def _row_slot_1000(row=row, /, *, item, **_):
+//	    return el("li", escape(item))
+//
+// Names the view binds itself, such as loop variables, are captured as
+// positional-only defaults when the function is defined, as the slot renders
+// after the enclosing loop has moved on. Values the content does not bind are
+// ignored.
+func (vm *ViewTransformer) transformScopedSlotContent(fill *slotFill, span lexer.Span) ([]ast.Stmt, ast.Expr, error) {
+	prefix := fill.Name
+	if fill.NameExpr != nil {
+		prefix = ""
+	} else if prefix == "" {
+		prefix = "children"
+	}
+	functionName := fmt.Sprintf("_%s_slot_%d", prefix, vm.nextContextId)
+	if prefix == "" {
+		functionName = fmt.Sprintf("_slot_%d", vm.nextContextId)
+	}
+	vm.nextContextId += 1000
+
+	// The content, as statements returning it
+	var body []ast.Stmt
+	content := vm.withoutHTMLComments(fill.Content)
+	if !vm.needsSlotStatements(content) && len(content) == 1 {
+		expr, err := vm.transformHTMLContentItem(content[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		body = []ast.Stmt{&ast.ReturnStmt{Value: expr, Span: span}}
+	} else {
+		// A fresh context stack, so the content appends to its own array
+		savedStack, savedContext := vm.contextStack, vm.currentContext
+		vm.contextStack, vm.currentContext = nil, ""
+		setup, expr, err := vm.transformSlotContent(fill.Content, span)
+		vm.contextStack, vm.currentContext = savedStack, savedContext
+		if err != nil {
+			return nil, nil, err
+		}
+		body = append(setup, &ast.ReturnStmt{Value: expr, Span: span})
+	}
+	if containsAwait(body) {
+		return nil, nil, fmt.Errorf("scoped slot content at %s cannot render async views: the slot calls it synchronously", span)
+	}
+
+	// def _row_slot_1000(captured=captured, /, *, item, **_)
+	var params []*ast.Parameter
+	bound := make(map[string]bool, len(fill.Params))
+	for _, name := range fill.Params {
+		bound[name.Token.Lexeme] = true
+	}
+	for _, name := range referencedNames(fill.Content) {
+		if !vm.viewLocals[name] || bound[name] {
+			continue
+		}
+		params = append(params, &ast.Parameter{
+			Name:    &ast.Name{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}, Span: span},
+			Default: &ast.Name{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}, Span: span},
+			Span:    span,
+		})
+	}
+	paramList := &ast.ParameterList{SlashIndex: -1, VarArgIndex: -1, KwArgIndex: -1, Span: span}
+	if len(params) > 0 {
+		paramList.HasSlash = true
+		paramList.SlashIndex = len(params) - 1
+	}
+	for _, name := range fill.Params {
+		params = append(params, &ast.Parameter{
+			Name:          &ast.Name{Token: name.Token, Span: name.Span},
+			IsKeywordOnly: true,
+			Span:          name.Span,
+		})
+	}
+	paramList.KwArgIndex = len(params)
+	params = append(params, &ast.Parameter{
+		Name:         &ast.Name{Token: lexer.Token{Lexeme: "_", Type: lexer.Identifier}, Span: span},
+		IsDoubleStar: true,
+		Span:         span,
+	})
+	paramList.Parameters = params
+
+	function := &ast.Function{
+		Name:       &ast.Name{Token: lexer.Token{Lexeme: functionName, Type: lexer.Identifier}, Span: span},
+		Parameters: paramList,
+		Body:       body,
+		Span:       span,
+	}
+	return []ast.Stmt{function}, &ast.Name{
+		Token: lexer.Token{Lexeme: functionName, Type: lexer.Identifier},
+		Span:  span,
+	}, nil
+}
+
+// localNames returns the names a view body binds itself: loop variables,
+// assignment and with targets, and exception names, outside scoped content
+func localNames(body []ast.Stmt) map[string]bool {
+	names := make(map[string]bool)
+	var targets func(target ast.Expr)
+	targets = func(target ast.Expr) {
+		switch t := target.(type) {
+		case *ast.Name:
+			names[t.Token.Lexeme] = true
+		case *ast.TupleExpr:
+			for _, element := range t.Elements {
+				targets(element)
+			}
+		case *ast.ListExpr:
+			for _, element := range t.Elements {
+				targets(element)
+			}
+		case *ast.GroupExpr:
+			targets(t.Expression)
+		case *ast.StarExpr:
+			targets(t.Expr)
+		}
+	}
+	var walk func(body []ast.Stmt)
+	walk = func(body []ast.Stmt) {
+		for _, stmt := range body {
+			switch s := stmt.(type) {
+			case *ast.AssignStmt:
+				for _, target := range s.Targets {
+					targets(target)
+				}
+			case *ast.For:
+				targets(s.Target)
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.While:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.If:
+				walk(s.Body)
+				walk(s.Else)
+			case *ast.With:
+				for _, item := range s.Items {
+					if item.As != nil {
+						targets(item.As)
+					}
+				}
+				walk(s.Body)
+			case *ast.Try:
+				walk(s.Body)
+				for _, except := range s.Excepts {
+					if except.Name != nil {
+						names[except.Name.Token.Lexeme] = true
+					}
+					walk(except.Body)
+				}
+				walk(s.Else)
+				walk(s.Finally)
+			case *ast.MatchStmt:
+				for _, c := range s.Cases {
+					walk(c.Body)
+				}
+			case *ast.HTMLElement:
+				// Scoped content binds its names in its own function
+				if _, scoped := s.SlotParams(); !scoped {
+					walk(s.Content)
+				}
+			}
+		}
+	}
+	walk(body)
+	return names
+}
+
+// referencedNames returns the identifiers used in content, in order
+func referencedNames(content []ast.Stmt) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, stmt := range content {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if name, ok := n.(*ast.Name); ok && !seen[name.Token.Lexeme] {
+				seen[name.Token.Lexeme] = true
+				names = append(names, name.Token.Lexeme)
+			}
+			return true
+		})
+	}
+	return names
+}
+
+// containsAwait reports whether statements await anything
+func containsAwait(body []ast.Stmt) bool {
+	found := false
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if _, ok := n.(*ast.AwaitExpr); ok {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
@@ -24,6 +24,14 @@ func (vm *ViewTransformer) analyzeSlotInStatement(stmt ast.Stmt) {
 	switch s := stmt.(type) {
 	case *ast.HTMLElement:
 		if s.TagName.Lexeme == "slot" {
+			// A slot whose name is computed at render time takes any slot
+			// content the view is given
+			if vm.getSlotNameExpr(s) != nil {
+				vm.computedSlots = true
+				vm.computedSlotsScoped = vm.computedSlotsScoped || len(vm.slotArguments(s)) > 0
+				return
+			}
+
 			// Found a slot element
 			slotName := vm.getSlotName(s)
 
@@ -32,10 +40,20 @@ func (vm *ViewTransformer) analyzeSlotInStatement(stmt ast.Stmt) {
 				vm.slotOrder = append(vm.slotOrder, slotName)
 			}
 
+			scoped := len(vm.slotArguments(s)) > 0
+			if previous, exists := vm.slots[slotName]; exists {
+				scoped = scoped || previous.Scoped
+			}
 			vm.slots[slotName] = &SlotInfo{
 				Name:         slotName,
 				FallbackHTML: s.Content,
 				Element:      s,
+				Scoped:       scoped,
+			}
+
+			// Fallback content may render other slots
+			for _, contentStmt := range s.Content {
+				vm.analyzeSlotInStatement(contentStmt)
 			}
 		} else {
 			// Recursively check content of non-slot elements
@@ -79,34 +97,93 @@ func (vm *ViewTransformer) getSlotName(slotElement *ast.HTMLElement) string {
 	return "" // Default slot
 }
 
-// createSlotTypeAnnotation creates a type annotation for slot parameters: Union[BaseView, str, None]
-func (vm *ViewTransformer) createSlotTypeAnnotation() ast.Expr {
-	// For now, return None as a placeholder
-	// TODO: Implement proper Union[BaseView, str, None] type annotation
+// getSlotNameExpr returns the expression of a slot element's name computed at
+// render time, as in <slot name={section} />, or nil for a literal name
+func (vm *ViewTransformer) getSlotNameExpr(slotElement *ast.HTMLElement) ast.Expr {
+	return computedName(slotElement, "name")
+}
+
+// computedName returns the value of the named attribute of an element when it
+// is an expression rather than a string literal
+func computedName(element *ast.HTMLElement, attribute string) ast.Expr {
+	for _, attr := range element.Attributes {
+		if attr.Name.Lexeme != attribute || attr.Value == nil {
+			continue
+		}
+		if literal, ok := attr.Value.(*ast.Literal); ok && literal.Type == ast.LiteralTypeString {
+			return nil
+		}
+		return attr.Value
+	}
 	return nil
 }
 
-// transformSlotElementToExpression transforms a slot element into a conditional expression
-func (vm *ViewTransformer) transformSlotElementToExpression(slotElement *ast.HTMLElement) (ast.Expr, error) {
-	slotName := vm.getSlotName(slotElement)
+// slotValue returns the content given for a slot element: self.children for
+// the default slot, self.<name> for a named one, and a lookup in self._slots
+// for a slot whose name is computed at render time
+func (vm *ViewTransformer) slotValue(slotElement *ast.HTMLElement) ast.Expr {
+	self := &ast.Name{
+		Token: lexer.Token{Lexeme: "self", Type: lexer.Identifier},
+		Span:  slotElement.Span,
+	}
+	if nameExpr := vm.getSlotNameExpr(slotElement); nameExpr != nil {
+		// self._slots.get(name)
+		return &ast.Call{
+			Callee: &ast.Attribute{
+				Object: &ast.Attribute{
+					Object: self,
+					Name:   lexer.Token{Lexeme: "_slots", Type: lexer.Identifier},
+					Span:   slotElement.Span,
+				},
+				Name: lexer.Token{Lexeme: "get", Type: lexer.Identifier},
+				Span: slotElement.Span,
+			},
+			Arguments: []*ast.Argument{{
+				Value: vm.transformExpression(nameExpr),
+				Span:  nameExpr.GetSpan(),
+			}},
+			Span: slotElement.Span,
+		}
+	}
 
-	// Determine the slot variable name
-	var slotVarName string
-	if slotName == "" {
+	slotVarName := vm.getSlotName(slotElement)
+	if slotVarName == "" {
 		slotVarName = "children"
-	} else {
-		slotVarName = slotName
 	}
+	return &ast.Attribute{
+		Object: self,
+		Name:   lexer.Token{Lexeme: slotVarName, Type: lexer.Identifier},
+		Span:   slotElement.Span,
+	}
+}
 
-	// Create the slot attribute access (self.slotName)
-	slotAttr := &ast.Attribute{
-		Object: &ast.Name{
-			Token: lexer.Token{Lexeme: "self", Type: lexer.Identifier},
-			Span:  slotElement.Span,
-		},
-		Name: lexer.Token{Lexeme: slotVarName, Type: lexer.Identifier},
-		Span: slotElement.Span,
+// createSlotTypeAnnotation creates the type annotation of slot parameters,
+// the content render_child takes: Union[Element, BaseView, str, None]. Slots
+// passing values also take scoped content, a function of them returning
+// content: Union[Element, BaseView, str, Callable[..., Any], None]
+func (vm *ViewTransformer) createSlotTypeAnnotation(scoped bool) ast.Expr {
+	vm.needsUnion = true
+	name := func(name string) ast.Expr {
+		return &ast.Name{Token: lexer.Token{Lexeme: name, Type: lexer.Identifier}}
+	}
+	indices := []ast.Expr{name("Element"), name("BaseView"), name("str")}
+	if scoped {
+		vm.needsCallable = true
+		indices = append(indices, &ast.Subscript{
+			Object:  name("Callable"),
+			Indices: []ast.Expr{&ast.Literal{Token: lexer.Token{Lexeme: "...", Type: lexer.Ellipsis}}, name("Any")},
+		})
+	}
+	return &ast.Subscript{
+		Object:  name("Union"),
+		Indices: append(indices, &ast.Literal{Type: ast.LiteralTypeNone}),
 	}
+}
+
+// transformSlotElementToExpression transforms a slot element into a conditional expression
+func (vm *ViewTransformer) transformSlotElementToExpression(slotElement *ast.HTMLElement) (ast.Expr, error) {
+	// The content given for the slot (self.slotName)
+	slotAttr := vm.slotValue(slotElement)
 
 	// Create conditional: if self.slotName is not None
 	condition := &ast.Binary{
@@ -121,13 +198,14 @@ func (vm *ViewTransformer) transformSlotElementToExpression(slotElement *ast.HTM
 	}
 
 	// Create render_child call for provided content
+	vm.needsRenderChild = true
 	renderChildCall := &ast.Call{
 		Callee: &ast.Name{
 			Token: lexer.Token{Lexeme: "render_child", Type: lexer.Identifier},
 			Span:  slotElement.Span,
 		},
 		Arguments: []*ast.Argument{{
-			Value: slotAttr,
+			Value: vm.scopedSlotContent(slotElement, slotAttr),
 			Span:  slotElement.Span,
 		}},
 		Span: slotElement.Span,
@@ -160,88 +238,59 @@ func (vm *ViewTransformer) transformSlotElementToExpression(slotElement *ast.HTM
 	}, nil
 }
 
-// transformViewCallWithSlots creates a view instantiation call with slot content support
-func (vm *ViewTransformer) transformViewCallWithSlots(viewStmt *ast.ViewStmt, element *ast.HTMLElement) (*ast.Call, error) {
+// transformViewCallWithSlots creates a view instantiation call with slot content support.
+// Slot content that needs statements to build, such as a loop, is collected in
+// a children array by the returned statements, which must run before the call.
+func (vm *ViewTransformer) transformViewCallWithSlots(viewStmt *ast.ViewStmt, element *ast.HTMLElement) ([]ast.Stmt, *ast.Call, error) {
 	// Get the base call without slot content
-	baseCall := vm.transformViewCall(viewStmt, element.Attributes)
+	baseCall, err := vm.transformViewCall(viewStmt, element)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Collect slot content from the element's children
 	slotContent, err := vm.collectSlotContent(element.Content)
 	if err != nil {
-		return nil, fmt.Errorf("invalid slot usage in view %s: %v", viewStmt.Name.Token.Lexeme, err)
+		return nil, nil, fmt.Errorf("invalid slot usage in view %s: %v", viewStmt.Name.Token.Lexeme, err)
 	}
 
-	// Add slot arguments to the call
-	for slotName, content := range slotContent {
-		// Skip empty content arrays
-		if len(content) == 0 {
-			continue
-		}
-
-		var paramName string
-		if slotName == "" {
-			paramName = "children"
+	// Add slot arguments to the call, in the order the content appears
+	var statements []ast.Stmt
+	for _, fill := range slotContent {
+		var setup []ast.Stmt
+		var contentExpr ast.Expr
+		if fill.Params != nil {
+			setup, contentExpr, err = vm.transformScopedSlotContent(fill, element.Span)
 		} else {
-			paramName = slotName
+			setup, contentExpr, err = vm.transformSlotContent(fill.Content, element.Span)
 		}
-
-		// For slot content that might contain control structures,
-		// we need to create an element that can handle complex statements
-		var contentExpr ast.Expr
-
-		// Use the proper view body transformation to handle control structures
-		transformedContent, err := vm.transformViewBody(content)
 		if err != nil {
-			// If transformation fails, skip this slot
-			continue
-		}
-
-		// If we have transformed content, we need to wrap it in a way that can be passed as an argument
-		if len(transformedContent) == 1 {
-			// Single statement - check if it's directly usable
-			if exprStmt, ok := transformedContent[0].(*ast.ExprStmt); ok {
-				contentExpr = exprStmt.Expr
-			} else {
-				// Complex statement - we need to wrap it in a lambda or similar
-				// For now, fall back to fragment approach
-				tempContext := vm.generateContextName("slot")
-				fragmentCall := &ast.Call{
-					Callee: &ast.Name{
-						Token: lexer.Token{Lexeme: "fragment", Type: lexer.Identifier},
-						Span:  element.Span,
-					},
-					Arguments: []*ast.Argument{{
-						Value: &ast.Name{
-							Token: lexer.Token{Lexeme: tempContext, Type: lexer.Identifier},
-							Span:  element.Span,
-						},
-						Span: element.Span,
+			return nil, nil, err
+		}
+		statements = append(statements, setup...)
+
+		// Content for a slot named at render time: **{name: content}
+		if fill.NameExpr != nil {
+			baseCall.Arguments = append(baseCall.Arguments, &ast.Argument{
+				Value: &ast.DictExpr{
+					Pairs: []ast.DictPair{&ast.KeyValuePair{
+						Key:   vm.transformExpression(fill.NameExpr),
+						Value: contentExpr,
+						Span:  fill.NameExpr.GetSpan(),
 					}},
-					Span: element.Span,
-				}
-				contentExpr = fragmentCall
-			}
-		} else {
-			// Multiple statements - use fragment approach
-			tempContext := vm.generateContextName("slot")
-			fragmentCall := &ast.Call{
-				Callee: &ast.Name{
-					Token: lexer.Token{Lexeme: "fragment", Type: lexer.Identifier},
-					Span:  element.Span,
+					Span: fill.NameExpr.GetSpan(),
 				},
-				Arguments: []*ast.Argument{{
-					Value: &ast.Name{
-						Token: lexer.Token{Lexeme: tempContext, Type: lexer.Identifier},
-						Span:  element.Span,
-					},
-					Span: element.Span,
-				}},
-				Span: element.Span,
-			}
-			contentExpr = fragmentCall
+				IsDoubleStar: true,
+				Span:         element.Span,
+			})
+			continue
 		}
 
-		slotArg := &ast.Argument{
+		paramName := fill.Name
+		if paramName == "" {
+			paramName = "children"
+		}
+		baseCall.Arguments = append(baseCall.Arguments, &ast.Argument{
 			Name: &ast.Name{
 				Token: lexer.Token{
 					Lexeme: paramName,
@@ -251,33 +300,209 @@ func (vm *ViewTransformer) transformViewCallWithSlots(viewStmt *ast.ViewStmt, el
 			},
 			Value: contentExpr,
 			Span:  element.Span,
+		})
+	}
+
+	return statements, baseCall, nil
+}
+
+// transformSlotContent transforms the content a view composition passes for
+// one slot into the argument's value. A lone <slot> forwards the content the
+// enclosing view was given for it unrendered, so the composed view's fallback
+// applies when there is none. Content made of elements, text and expressions
+// becomes an expression, a fragment when there are several items; other
+// content is built into a children array by the returned statements.
+func (vm *ViewTransformer) transformSlotContent(content []ast.Stmt, span lexer.Span) ([]ast.Stmt, ast.Expr, error) {
+	content = vm.withoutHTMLComments(content)
+
+	if len(content) == 1 {
+		if slot, ok := content[0].(*ast.HTMLElement); ok && slot.TagName.Lexeme == "slot" && slot.TagExpr == nil {
+			return vm.forwardSlot(slot)
+		}
+	}
+
+	if !vm.needsSlotStatements(content) {
+		var items []ast.Expr
+		for _, item := range content {
+			expr, err := vm.transformHTMLContentItem(item)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, expr)
+		}
+		if len(items) == 1 {
+			return nil, items[0], nil
+		}
+		return nil, vm.createFragmentCall(&ast.ListExpr{Elements: items, Span: span}, span), nil
+	}
+
+	// Build the content in its own children array: _slot_children_1000 = []
+	contextName := vm.pushContext("slot")
+	statements := []ast.Stmt{&ast.AssignStmt{
+		Targets: []ast.Expr{&ast.Name{
+			Token: lexer.Token{Lexeme: contextName, Type: lexer.Identifier},
+		}},
+		Value: &ast.ListExpr{Elements: []ast.Expr{}},
+	}}
+	for _, stmt := range content {
+		processedStmts, err := vm.processViewStatement(stmt)
+		if err != nil {
+			vm.popContext()
+			return nil, nil, err
+		}
+		statements = append(statements, processedStmts...)
+	}
+	vm.popContext()
+
+	return statements, vm.createFragmentCall(&ast.Name{
+		Token: lexer.Token{Lexeme: contextName, Type: lexer.Identifier},
+		Span:  span,
+	}, span), nil
+}
+
+// forwardSlot returns the content the enclosing view was given for a slot, to
+// pass on to a composed view: self.header, or with fallback content,
+// self.header if self.header is not None else fallback
+func (vm *ViewTransformer) forwardSlot(slot *ast.HTMLElement) ([]ast.Stmt, ast.Expr, error) {
+	value := vm.slotValue(slot)
+	if len(vm.withoutHTMLComments(slot.Content)) == 0 {
+		return nil, value, nil
+	}
+
+	fallback, err := vm.transformHTMLContent(slot.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, &ast.TernaryExpr{
+		Condition: &ast.Binary{
+			Left:     value,
+			Operator: lexer.Token{Type: lexer.IsNot, Lexeme: "is not"},
+			Right:    &ast.Literal{Type: ast.LiteralTypeNone, Span: slot.Span},
+			Span:     slot.Span,
+		},
+		TrueExpr:  value,
+		FalseExpr: fallback,
+		Span:      slot.Span,
+	}, nil
+}
+
+// needsSlotStatements reports whether slot content needs statements to build:
+// compound statements, Python comments, or elements that do
+func (vm *ViewTransformer) needsSlotStatements(content []ast.Stmt) bool {
+	for _, stmt := range content {
+		switch s := stmt.(type) {
+		case *ast.HTMLElement:
+			if vm.needsHierarchicalProcessing(s.Content) {
+				return true
+			}
+		case *ast.HTMLContent, *ast.ExprStmt, *ast.HTMLComment:
+		default:
+			return true
 		}
+	}
+	return false
+}
 
-		baseCall.Arguments = append(baseCall.Arguments, slotArg)
+// createFragmentCall creates a fragment(children) call
+func (vm *ViewTransformer) createFragmentCall(children ast.Expr, span lexer.Span) *ast.Call {
+	return &ast.Call{
+		Callee: &ast.Name{
+			Token: lexer.Token{Lexeme: "fragment", Type: lexer.Identifier},
+			Span:  span,
+		},
+		Arguments: []*ast.Argument{{
+			Value: children,
+			Span:  span,
+		}},
+		Span: span,
 	}
+}
 
-	return baseCall, nil
+// slotFill is the content a view composition passes for one of its slots
+type slotFill struct {
+	Name     string      // Slot name (empty for the default slot)
+	NameExpr ast.Expr    // Slot name computed at render time, or nil
+	Params   []*ast.Name // Values scoped content binds with let, or nil
+	Content  []ast.Stmt  // Content for the slot
 }
 
-// collectSlotContent groups the element's content by slot name
-func (vm *ViewTransformer) collectSlotContent(content []ast.Stmt) (map[string][]ast.Stmt, error) {
-	slotContent := make(map[string][]ast.Stmt)
+// collectSlotContent groups the element's content by slot name, in the order
+// each slot's content first appears. Content for a slot named by an
+// expression is passed on its own, as its name is only known at render time.
+func (vm *ViewTransformer) collectSlotContent(content []ast.Stmt) ([]*slotFill, error) {
+	var fills []*slotFill
+	named := make(map[string]*slotFill)
+	scopedError := func(name string) error {
+		if name == "" {
+			return fmt.Errorf("content with let must be all the content of the default slot")
+		}
+		return fmt.Errorf("content with let must be all the content of slot '%s'", name)
+	}
+	var err error
+	add := func(name string, stmt ast.Stmt) {
+		fill, ok := named[name]
+		if !ok {
+			fill = &slotFill{Name: name}
+			named[name] = fill
+			fills = append(fills, fill)
+		} else if fill.Params != nil && err == nil {
+			err = scopedError(name)
+		}
+		fill.Content = append(fill.Content, stmt)
+	}
+	// Scoped content is a function of the slot's values, so it is all the
+	// content of its slot
+	addScoped := func(name string, params []*ast.Name, items []ast.Stmt) error {
+		if _, ok := named[name]; ok {
+			return scopedError(name)
+		}
+		fill := &slotFill{Name: name, Params: params, Content: items}
+		named[name] = fill
+		fills = append(fills, fill)
+		return nil
+	}
 
 	for _, stmt := range content {
 		if htmlElement, ok := stmt.(*ast.HTMLElement); ok {
 			// Check if this element has a slot attribute
 			if vm.hasSlotAttribute(htmlElement) {
 				// Element has a slot attribute - get the slot name and place in that slot
-				slotName := vm.getElementSlotName(htmlElement)
 				// Remove the slot attribute from the element before adding to content
-				filteredElement := vm.removeSlotAttribute(htmlElement)
-				slotContent[slotName] = append(slotContent[slotName], filteredElement)
+				items := []ast.Stmt{vm.removeSlotAttribute(htmlElement)}
+				// A <template slot="..."> only groups the content it passes
+				if vm.isSlotTemplate(htmlElement) {
+					items = htmlElement.Content
+				}
+				params, scoped := htmlElement.SlotParams()
+				if nameExpr := computedName(htmlElement, "slot"); nameExpr != nil {
+					fills = append(fills, &slotFill{NameExpr: nameExpr, Params: params, Content: items})
+					continue
+				}
+				if scoped {
+					if err := addScoped(vm.getElementSlotName(htmlElement), params, items); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				for _, item := range items {
+					add(vm.getElementSlotName(htmlElement), item)
+				}
 			} else {
 				// Element without slot attribute - check for nested slots which should be invalid
 				if vm.hasNestedSlotAttributes(htmlElement) {
 					return nil, fmt.Errorf("slot attributes found nested inside HTML element <%s>. Slot attributes can only be used on direct children of view elements", htmlElement.TagName.Lexeme)
 				}
-				slotContent[""] = append(slotContent[""], stmt)
+				if params, scoped := htmlElement.SlotParams(); scoped {
+					items := []ast.Stmt{vm.removeSlotAttribute(htmlElement)}
+					if vm.isSlotTemplate(htmlElement) {
+						items = htmlElement.Content
+					}
+					if err := addScoped("", params, items); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				add("", stmt)
 			}
 		} else {
 			// Check for nested slot attributes in control structures
@@ -285,11 +510,22 @@ func (vm *ViewTransformer) collectSlotContent(content []ast.Stmt) (map[string][]
 				return nil, fmt.Errorf("slot attributes found inside control structures. Slot attributes can only be used on direct children of view elements, not within if/for/while statements")
 			}
 			// Non-HTML elements go to the default slot
-			slotContent[""] = append(slotContent[""], stmt)
+			add("", stmt)
 		}
 	}
 
-	return slotContent, nil
+	if err != nil {
+		return nil, err
+	}
+
+	// Comments alone pass no content
+	var filled []*slotFill
+	for _, fill := range fills {
+		if fill.Params != nil || len(vm.withoutHTMLComments(fill.Content)) > 0 {
+			filled = append(filled, fill)
+		}
+	}
+	return filled, nil
 }
 
 // collectSlotContentInSourceOrder groups the element's content by slot name in source order
@@ -470,12 +706,26 @@ func (vm *ViewTransformer) validateSlotElementContent(element *ast.HTMLElement)
 	return nil
 }
 
-// removeSlotAttribute creates a copy of the HTML element without the slot attribute
+// isSlotTemplate reports whether an element is a <template> only grouping
+// the content it passes to a slot, with no attributes but slot and let
+func (vm *ViewTransformer) isSlotTemplate(element *ast.HTMLElement) bool {
+	if element.TagName.Lexeme != "template" || element.TagExpr != nil {
+		return false
+	}
+	for _, attr := range element.Attributes {
+		if attr.Spread || (attr.Name.Lexeme != "slot" && attr.Name.Lexeme != "let") {
+			return false
+		}
+	}
+	return true
+}
+
+// removeSlotAttribute creates a copy of the HTML element without the slot and let attributes
 func (vm *ViewTransformer) removeSlotAttribute(element *ast.HTMLElement) *ast.HTMLElement {
 	var filteredAttrs []ast.HTMLAttribute
 
 	for _, attr := range element.Attributes {
-		if attr.Name.Lexeme != "slot" {
+		if attr.Spread || (attr.Name.Lexeme != "slot" && attr.Name.Lexeme != "let") {
 			filteredAttrs = append(filteredAttrs, attr)
 		}
 	}
@@ -484,6 +734,7 @@ func (vm *ViewTransformer) removeSlotAttribute(element *ast.HTMLElement) *ast.HT
 	return &ast.HTMLElement{
 		Type:       element.Type,
 		TagName:    element.TagName,
+		TagExpr:    element.TagExpr,
 		Attributes: filteredAttrs,
 		Content:    element.Content,
 		IsClosing:  element.IsClosing,
@@ -505,75 +756,10 @@ func (vm *ViewTransformer) getElementSlotName(element *ast.HTMLElement) string {
 
 // processSlotElement processes a slot element and generates the appropriate slot rendering code
 func (vm *ViewTransformer) processSlotElement(slotElement *ast.HTMLElement) ([]ast.Stmt, error) {
-	slotName := vm.getSlotName(slotElement)
-
-	// Determine the slot variable name
-	var slotVarName string
-	if slotName == "" {
-		slotVarName = "children"
-	} else {
-		slotVarName = slotName
-	}
-
-	// Create the slot attribute access (self.slotName)
-	slotAttr := &ast.Attribute{
-		Object: &ast.Name{
-			Token: lexer.Token{Lexeme: "self", Type: lexer.Identifier},
-			Span:  slotElement.Span,
-		},
-		Name: lexer.Token{Lexeme: slotVarName, Type: lexer.Identifier},
-		Span: slotElement.Span,
-	}
-
-	// Create conditional: if self.slotName is not None
-	condition := &ast.Binary{
-		Left:     slotAttr,
-		Operator: lexer.Token{Type: lexer.IsNot, Lexeme: "is not"},
-		Right: &ast.Literal{
-			Type:  ast.LiteralTypeNone,
-			Value: nil,
-			Span:  slotElement.Span,
-		},
-		Span: slotElement.Span,
-	}
-
-	// Create render_child call for provided content
-	renderChildCall := &ast.Call{
-		Callee: &ast.Name{
-			Token: lexer.Token{Lexeme: "render_child", Type: lexer.Identifier},
-			Span:  slotElement.Span,
-		},
-		Arguments: []*ast.Argument{{
-			Value: slotAttr,
-			Span:  slotElement.Span,
-		}},
-		Span: slotElement.Span,
-	}
-
-	// Create fallback content
-	var fallbackExpr ast.Expr
-	if len(slotElement.Content) > 0 {
-		// Transform fallback content
-		fallbackContentExpr, err := vm.transformHTMLContent(slotElement.Content)
-		if err != nil {
-			return nil, err
-		}
-		fallbackExpr = fallbackContentExpr
-	} else {
-		// Empty fallback
-		fallbackExpr = &ast.Literal{
-			Type:  ast.LiteralTypeString,
-			Value: "",
-			Span:  slotElement.Span,
-		}
-	}
-
-	// Create ternary expression: render_child(self.slot) if self.slot is not None else fallback
-	slotExpr := &ast.TernaryExpr{
-		Condition: condition,
-		TrueExpr:  renderChildCall,
-		FalseExpr: fallbackExpr,
-		Span:      slotElement.Span,
+	// render_child(self.slot) if self.slot is not None else fallback
+	slotExpr, err := vm.transformSlotElementToExpression(slotElement)
+	if err != nil {
+		return nil, err
 	}
 
 	// Handle based on whether we have a parent context
@@ -665,6 +851,11 @@ func (vm *ViewTransformer) analyzeSlotOrderInStatement(stmt ast.Stmt, slotOrder
 	switch s := stmt.(type) {
 	case *ast.HTMLElement:
 		if s.TagName.Lexeme == "slot" {
+			// Slots named at render time have no place in the order
+			if vm.getSlotNameExpr(s) != nil {
+				return
+			}
+
 			// Found a slot element
 			slotName := vm.getSlotName(s)
 
@@ -673,6 +864,7 @@ func (vm *ViewTransformer) analyzeSlotOrderInStatement(stmt ast.Stmt, slotOrder
 				*slotOrder = append(*slotOrder, slotName)
 				slots[slotName] = true
 			}
+			vm.analyzeSlotOrderInStatements(s.Content, slotOrder, slots)
 		} else {
 			// Recursively check content of non-slot elements
 			vm.analyzeSlotOrderInStatements(s.Content, slotOrder, slots)
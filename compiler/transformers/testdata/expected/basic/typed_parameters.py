@@ -1,5 +1,5 @@
 class UserCard(BaseView):
-    def __init__(self, user: User, show_email: bool=False):
+    def __init__(self, user: User, show_email: bool=False) -> None:
         super().__init__()
         self.user = user
         self.show_email = show_email
@@ -1,5 +1,5 @@
 class ItemList(BaseView):
-    def __init__(self, items):
+    def __init__(self, items) -> None:
         super().__init__()
         self.items = items
 
@@ -1,5 +1,5 @@
 class ConditionalView(BaseView):
-    def __init__(self, show_message, message):
+    def __init__(self, show_message, message) -> None:
         super().__init__()
         self.show_message = show_message
         self.message = message
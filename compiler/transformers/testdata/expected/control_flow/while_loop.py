@@ -1,5 +1,5 @@
 class CounterView(BaseView):
-    def __init__(self, count):
+    def __init__(self, count) -> None:
         super().__init__()
         self.count = count
 
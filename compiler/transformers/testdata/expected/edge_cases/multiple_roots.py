@@ -1,5 +1,5 @@
 class MultipleRoots(BaseView):
-    def __init__(self):
+    def __init__(self) -> None:
         super().__init__()
 
     def _render(self) -> Element:
@@ -1,5 +1,5 @@
 class UserProfile(BaseView):
-    def __init__(self, username, email):
+    def __init__(self, username, email) -> None:
         super().__init__()
         self.username = username
         self.email = email
@@ -0,0 +1,16 @@
+class Fragment(BaseView):
+    def __init__(self, title) -> None:
+        super().__init__()
+        self.title = title
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _ul_children_2000 = []
+        _ul_children_2000.append(fragment([el("li", escape(self.title))]))
+        _fragment_children_3000 = []
+        _fragment_children_3000.append(el("li", escape("first")))
+        _fragment_children_3000.append(el("li", escape("second")))
+        _ul_children_2000.append(fragment(_fragment_children_3000))
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        return fragment(_root_children_1000)
+
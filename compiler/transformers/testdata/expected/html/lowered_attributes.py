@@ -0,0 +1,10 @@
+class LoweredAttributes(BaseView):
+    def __init__(self, on_save, busy: bool, classes: dict) -> None:
+        super().__init__()
+        self.on_save = on_save
+        self.busy = busy
+        self.classes = classes
+
+    def _render(self) -> Element:
+        return el("button", escape("Save"), {"onclick": self.on_save, "disabled": bool(self.busy), "hidden": True, "class": class_names(self.classes), "style": style_value({"color": "red"})})
+
@@ -0,0 +1,9 @@
+class ElementWithSpread(BaseView):
+    def __init__(self, attrs: dict, is_admin: bool) -> None:
+        super().__init__()
+        self.attrs = attrs
+        self.is_admin = is_admin
+
+    def _render(self) -> Element:
+        return el("div", escape("Content with spread attributes"), {"id": "main", **self.attrs, **({"data-role": "admin"} if self.is_admin else {}), "class": "panel"})
+
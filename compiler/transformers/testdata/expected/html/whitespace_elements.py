@@ -0,0 +1,14 @@
+class Snippet(BaseView):
+    def __init__(self, code) -> None:
+        super().__init__()
+        self.code = code
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _div_children_2000 = []
+        _div_children_2000.append(el("pre", f"\n  def  f():\n    {escape(self.code)}"))
+        _div_children_2000.append(el("script", raw("if (a < b) { go(); }")))
+        _div_children_2000.append(el("p", "Hello world,again"))
+        _root_children_1000.append(el("div", _div_children_2000))
+        return fragment(_root_children_1000)
+
@@ -0,0 +1,15 @@
+class Tabs(BaseView):
+    def __init__(self, active, *, intro: Union[Element, BaseView, str, None]=None, **slots: Union[Element, BaseView, str, None]) -> None:
+        super().__init__()
+        self.active = active
+        self.intro = intro
+        self._slots = {"intro": intro, **slots}
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _section_children_2000 = []
+        _section_children_2000.append(render_child(self.intro) if self.intro is not None else "")
+        _section_children_2000.append(render_child(self._slots.get(self.active)) if self._slots.get(self.active) is not None else escape("No tab"))
+        _root_children_1000.append(el("section", _section_children_2000))
+        return fragment(_root_children_1000)
+
@@ -1,5 +1,5 @@
 class Layout(BaseView):
-    def __init__(self, *, children=None, header=None, footer=None):
+    def __init__(self, *, children: Union[Element, BaseView, str, None]=None, header: Union[Element, BaseView, str, None]=None, footer: Union[Element, BaseView, str, None]=None) -> None:
         super().__init__()
         self.children = children
         self.header = header
@@ -0,0 +1,14 @@
+class List(BaseView):
+    def __init__(self, items, *, row: Union[Element, BaseView, str, Callable[..., Any], None]=None) -> None:
+        super().__init__()
+        self.items = items
+        self.row = row
+
+    def _render(self) -> Element:
+        _root_children_1000 = []
+        _ul_children_2000 = []
+        for item in self.items:
+            _ul_children_2000.append(render_child(self.row(item=item) if callable(self.row) else self.row) if self.row is not None else el("li", escape(item)))
+        _root_children_1000.append(el("ul", _ul_children_2000))
+        return fragment(_root_children_1000)
+
@@ -1,6 +1,8 @@
 package transformers
 
 import (
+	"unicode"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 )
@@ -53,7 +55,7 @@ func (vm *ViewTransformer) isStringLikeExpression(expr ast.Expr) bool {
 	switch e := expr.(type) {
 	case *ast.Literal:
 		return e.Type == ast.LiteralTypeString
-	case *ast.FString:
+	case *ast.FString, *ast.ConcatenatedString:
 		return true
 	case *ast.Binary:
 		// Check if it's string concatenation (both operands are string-like)
@@ -68,3 +70,16 @@ func (vm *ViewTransformer) isStringLikeExpression(expr ast.Expr) bool {
 	}
 	return false
 }
+
+// isIdentifier reports whether s is a Python identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
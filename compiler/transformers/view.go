@@ -19,8 +19,13 @@ func (vm *ViewTransformer) isViewElement(element *ast.HTMLElement) (*ast.ViewStm
 }
 
 // transformViewCall creates a view instantiation call from an HTML element and its attributes,
-// now with support for slot content
-func (vm *ViewTransformer) transformViewCall(viewStmt *ast.ViewStmt, attributes []ast.HTMLAttribute) *ast.Call {
+// now with support for slot content. The call spans the element it replaces.
+func (vm *ViewTransformer) transformViewCall(viewStmt *ast.ViewStmt, element *ast.HTMLElement) (*ast.Call, error) {
+	if err := vm.checkViewDefaults(viewStmt, element); err != nil {
+		return nil, err
+	}
+	attributes := vm.withDefaultAttributes(viewStmt.Name.Token.Lexeme, element, element.Attributes)
+
 	// Create the view class name reference
 	viewName := &ast.Name{
 		Token: lexer.Token{
@@ -43,21 +48,41 @@ func (vm *ViewTransformer) transformViewCall(viewStmt *ast.ViewStmt, attributes
 		}
 	}
 
+	// With a {...mapping} spread, the attributes are merged into one
+	// dictionary unpacked into the call, so a name given both by the spread
+	// and by an attribute takes the later value instead of being passed twice
+	if hasSpread(attributes) {
+		var pairs []ast.DictPair
+		for _, attr := range attributes {
+			if attr.Spread {
+				pairs = append(pairs, &ast.DoubleStarredPair{Expr: vm.transformExpression(attr.Value), Span: attr.Span})
+				continue
+			}
+			if !validParams[attr.Name.Lexeme] {
+				continue
+			}
+			pairs = append(pairs, &ast.KeyValuePair{
+				Key:   &ast.Literal{Type: ast.LiteralTypeString, Value: attr.Name.Lexeme, Span: attr.Name.Span},
+				Value: vm.attributeArgument(attr),
+				Span:  attr.Span,
+			})
+		}
+		return &ast.Call{
+			Callee: viewName,
+			Arguments: []*ast.Argument{{
+				Value:        &ast.DictExpr{Pairs: pairs, Span: element.Span},
+				IsDoubleStar: true,
+				Span:         element.Span,
+			}},
+			Span: element.Span,
+		}, nil
+	}
+
 	// Process attributes into keyword arguments
 	for _, attr := range attributes {
 		// Only include attributes that match view parameters
 		if _, isValid := validParams[attr.Name.Lexeme]; isValid {
-			var value ast.Expr
-			if attr.Value != nil {
-				value = vm.transformExpression(attr.Value)
-			} else {
-				// Boolean attribute (no value) - use True
-				value = &ast.Literal{
-					Type:  ast.LiteralTypeBool,
-					Value: true,
-					Span:  attr.Span,
-				}
-			}
+			value := vm.attributeArgument(attr)
 
 			arg := &ast.Argument{
 				Name: &ast.Name{
@@ -77,6 +102,50 @@ func (vm *ViewTransformer) transformViewCall(viewStmt *ast.ViewStmt, attributes
 	return &ast.Call{
 		Callee:    viewName,
 		Arguments: args,
-		Span:      viewStmt.Span,
+		Span:      element.Span,
+	}, nil
+}
+
+// attributeArgument returns the value an attribute passes to a view; a
+// boolean attribute without a value passes True
+func (vm *ViewTransformer) attributeArgument(attr ast.HTMLAttribute) ast.Expr {
+	if attr.Value != nil {
+		return vm.transformExpression(attr.Value)
+	}
+	return &ast.Literal{
+		Type:  ast.LiteralTypeBool,
+		Value: true,
+		Span:  attr.Span,
+	}
+}
+
+// hasSpread reports whether attributes include a {...mapping} spread
+func hasSpread(attributes []ast.HTMLAttribute) bool {
+	for _, attr := range attributes {
+		if attr.Spread {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitRender wraps the instantiation of an async view in an await of its
+// _render, so the composing view places the rendered element instead of a
+// coroutine. The resolver only allows this inside async views.
+func awaitRender(viewStmt *ast.ViewStmt, call ast.Expr) ast.Expr {
+	if !viewStmt.IsAsync {
+		return call
+	}
+	span := call.GetSpan()
+	return &ast.AwaitExpr{
+		Expr: &ast.Call{
+			Callee: &ast.Attribute{
+				Object: call,
+				Name:   lexer.Token{Lexeme: "_render", Type: lexer.Identifier},
+				Span:   span,
+			},
+			Span: span,
+		},
+		Span: span,
 	}
 }
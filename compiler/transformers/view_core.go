@@ -3,6 +3,7 @@ package transformers
 import (
 	"fmt"
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/consteval"
 	"github.com/fjvillamarin/topple/compiler/lexer"
 	"github.com/fjvillamarin/topple/compiler/resolver"
 )
@@ -12,17 +13,78 @@ type ViewTransformer struct {
 	// Track if we need to add psx_runtime imports
 	needsRuntimeImports bool
 
+	// Track if a slot renders its content with render_child
+	needsRenderChild bool
+
+	// Track if class and style values are serialized with class_names and
+	// style_value
+	needsClassNames bool
+	needsStyleValue bool
+
+	// Track if a slot parameter is annotated with typing.Union, and with
+	// typing.Callable and typing.Any for scoped content
+	needsUnion    bool
+	needsCallable bool
+
 	// Resolution table for parameter transformation
 	resolutionTable *resolver.ResolutionTable
 
+	// Open whitespace elements, whose text is kept as written
+	preformatted int
+
 	// Context tracking for hierarchical HTML generation
 	contextStack   []string // Stack of current children array names
 	currentContext string   // Current children array name
+	rootContext    string   // Top-level children array of the view body being built
 	nextContextId  int      // Counter for generating unique context names
 
 	// Slot information
 	slots     map[string]*SlotInfo // Map of slot name to slot info (empty string for default slot)
 	slotOrder []string             // Order of slot names as they appear in view definition
+
+	// Whether a slot of the view is named at render time, so the view takes
+	// the content of any slot
+	computedSlots       bool
+	computedSlotsScoped bool // Whether such a slot passes values
+
+	// Names the view body binds, which scoped slot content captures
+	viewLocals map[string]bool
+
+	// Variables of the comprehensions being transformed, which hide view
+	// parameters of the same name
+	comprehensionNames map[string]bool
+
+	// How preserved HTML comments are emitted
+	htmlComments HTMLCommentMode
+
+	// Project-specific intrinsic and denied elements
+	elements ElementPolicy
+
+	// Attributes elements and compositions get unless they set them
+	defaults AttributeDefaults
+
+	// Whether return may end rendering early
+	earlyReturns EarlyReturnMode
+
+	// Whether <Markdown> blocks are rendered at compile time
+	markdown bool
+
+	// Asset reference resolution and the file references are relative to
+	assets     AssetResolver
+	sourceFile string
+
+	// Runtime API version the generated code targets, and the module the
+	// runtime is imported from; "" for DefaultRuntimeModule
+	runtimeAPI    RuntimeAPI
+	runtimeModule string
+
+	// Module-level statements the views refer to, such as rendered Markdown
+	hoisted []ast.Stmt
+
+	// The module's @compile_time functions, and the first error evaluating
+	// a call of one in the view being transformed
+	compileTime    *consteval.Interpreter
+	compileTimeErr error
 }
 
 // SlotInfo contains information about a slot in a view
@@ -30,6 +92,7 @@ type SlotInfo struct {
 	Name         string           // Slot name (empty for default slot)
 	FallbackHTML []ast.Stmt       // Fallback content for the slot
 	Element      *ast.HTMLElement // The slot element itself
+	Scoped       bool             // Whether the slot passes values to scoped content
 }
 
 // SlotContent represents content designated for a specific slot
@@ -88,6 +151,9 @@ func (vm *ViewTransformer) TransformViewToClass(viewStmt *ast.ViewStmt) (*ast.Cl
 	// Reset slots for each view transformation
 	vm.slots = make(map[string]*SlotInfo)
 	vm.slotOrder = []string{}
+	vm.computedSlots = false
+	vm.computedSlotsScoped = false
+	vm.viewLocals = localNames(viewStmt.Body)
 
 	// Analyze slots in the view body
 	vm.analyzeSlots(viewStmt.Body)
@@ -117,9 +183,21 @@ func (vm *ViewTransformer) TransformViewToClass(viewStmt *ast.ViewStmt) (*ast.Cl
 	if err != nil {
 		return nil, err
 	}
+	if err := vm.compileTimeErr; err != nil {
+		vm.compileTimeErr = nil
+		return nil, err
+	}
 
-	// Create the class body with both methods
+	// Create the class body with both methods. A view with a layout renders
+	// its own content in _render_content, which _render passes to the layout.
 	classBody := []ast.Stmt{initMethod, renderMethod}
+	if viewStmt.Layout != nil {
+		renderMethod.Name = &ast.Name{
+			Token: lexer.Token{Lexeme: "_render_content", Type: lexer.Identifier},
+			Span:  viewStmt.Span,
+		}
+		classBody = []ast.Stmt{initMethod, vm.createLayoutRenderMethod(viewStmt), renderMethod}
+	}
 
 	// Convert TypeParams from []*TypeParam to []TypeParam
 	var typeParams []ast.TypeParam
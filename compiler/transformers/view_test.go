@@ -227,6 +227,81 @@ func TestHTMLElements(t *testing.T) {
 			category: "html",
 			testFile: "attributes",
 		},
+		{
+			name: "element_with_spread",
+			view: ast.HView("ElementWithSpread", []*ast.Parameter{
+				ast.HParam("attrs", "dict"),
+				ast.HParam("is_admin", "bool"),
+			},
+				ast.HElement("div",
+					ast.HAttr("id", ast.S("main")),
+					ast.HSpread(ast.N("attrs")),
+					ast.HSpread(&ast.TernaryExpr{
+						Condition: ast.N("is_admin"),
+						TrueExpr:  ast.HDict(ast.HKeyValue(ast.S("data-role"), ast.S("admin"))),
+						FalseExpr: ast.HDict(),
+					}),
+					ast.HAttr("class", ast.S("panel")),
+					"Content with spread attributes",
+				),
+			),
+			category: "html",
+			testFile: "spread_attributes",
+		},
+		{
+			name: "lowered_attributes",
+			view: ast.HView("LoweredAttributes", []*ast.Parameter{
+				ast.HParam("on_save"),
+				ast.HParam("busy", "bool"),
+				ast.HParam("classes", "dict"),
+			},
+				ast.HElement("button",
+					ast.HAttr("onclick", ast.N("on_save")),
+					ast.HAttr("disabled", ast.N("busy")),
+					ast.HAttr("hidden", ast.S("false")),
+					ast.HAttr("class", ast.N("classes")),
+					ast.HAttr("style", ast.HDict(ast.HKeyValue(ast.S("color"), ast.S("red")))),
+					"Save",
+				),
+			),
+			category: "html",
+			testFile: "lowered_attributes",
+		},
+		{
+			name: "whitespace_elements",
+			view: ast.HView("Snippet", []*ast.Parameter{ast.HParam("code")},
+				ast.HElement("div",
+					ast.HElement("pre", &ast.HTMLContent{Parts: []ast.HTMLContentPart{
+						&ast.HTMLText{Value: "\n  def  f():\n    "},
+						&ast.HTMLInterpolation{Expression: ast.N("code")},
+					}}),
+					ast.HElement("script", &ast.HTMLContent{Parts: []ast.HTMLContentPart{
+						&ast.HTMLText{Value: "if (a < b) { go(); }"},
+					}}),
+					ast.HElement("p", &ast.HTMLContent{Parts: []ast.HTMLContentPart{
+						&ast.HTMLText{Value: "Hello   world,\t  "},
+						&ast.HTMLText{Trim: true},
+						&ast.HTMLText{Value: "  again"},
+					}}),
+				),
+			),
+			category: "html",
+			testFile: "whitespace_elements",
+		},
+		{
+			name: "fragment",
+			view: ast.HView("Fragment", []*ast.Parameter{ast.HParam("title")},
+				ast.HElement("ul",
+					ast.HElement("", ast.HElement("li", ast.N("title"))),
+					ast.HElement("",
+						ast.HElement("li", "first"),
+						ast.HElement("li", "second"),
+					),
+				),
+			),
+			category: "html",
+			testFile: "fragment",
+		},
 	}
 
 	runViewTests(t, tests)
@@ -264,6 +339,31 @@ func TestSlotSystem(t *testing.T) {
 			category: "slots",
 			testFile: "named_slots",
 		},
+		{
+			name: "computed_slot",
+			view: ast.HView("Tabs", []*ast.Parameter{ast.HParam("active")},
+				ast.HElement("section",
+					ast.HElement("slot", ast.HAttr("name", ast.S("intro"))),
+					ast.HElement("slot", ast.HAttr("name", ast.N("active")), "No tab"),
+				),
+			),
+			category: "slots",
+			testFile: "computed_slot",
+		},
+		{
+			name: "scoped_slot",
+			view: ast.HView("List", []*ast.Parameter{ast.HParam("items")},
+				ast.HElement("ul",
+					ast.HFor(ast.N("item"), ast.N("items"), []ast.Stmt{
+						ast.HElement("slot", ast.HAttr("name", ast.S("row")), ast.HAttr("item", ast.N("item")),
+							ast.HElement("li", ast.N("item")),
+						),
+					}),
+				),
+			),
+			category: "slots",
+			testFile: "scoped_slot",
+		},
 	}
 
 	runViewTests(t, tests)
@@ -1,8 +1,12 @@
 package transformers
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+
 	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/consteval"
 	"github.com/fjvillamarin/topple/compiler/resolver"
 )
 
@@ -11,6 +15,12 @@ type TransformerVisitor struct {
 	// Track transformations
 	hasTransformed bool
 	errors         []error
+	options        Options
+	ctx            context.Context
+
+	// Whether the module binds the name deprecated, so its @deprecated
+	// decorators are kept rather than stripped as the marker
+	bindsDeprecated bool
 
 	// AST visitor implementation
 	ast.Visitor
@@ -18,16 +28,46 @@ type TransformerVisitor struct {
 
 // NewTransformerVisitor creates a new TransformerVisitor
 func NewTransformerVisitor() *TransformerVisitor {
+	return NewTransformerVisitorWithOptions(Options{})
+}
+
+// NewTransformerVisitorWithOptions creates a new TransformerVisitor with the given options
+func NewTransformerVisitorWithOptions(options Options) *TransformerVisitor {
 	return &TransformerVisitor{
 		hasTransformed: false,
 		errors:         []error{},
+		options:        options,
 	}
 }
 
+// TransformModuleContext transforms like TransformModule but stops before the
+// next module-level statement once ctx is cancelled, returning ctx's error.
+func (mv *TransformerVisitor) TransformModuleContext(ctx context.Context, module *ast.Module, resolutionTable *resolver.ResolutionTable) (*ast.Module, error) {
+	mv.ctx = ctx
+	defer func() { mv.ctx = nil }()
+	return mv.TransformModule(module, resolutionTable)
+}
+
 // TransformModule transforms a module by replacing ViewStmt nodes with Class nodes
 func (mv *TransformerVisitor) TransformModule(module *ast.Module, resolutionTable *resolver.ResolutionTable) (*ast.Module, error) {
 	// Create view transformer with resolution table
 	viewTransformer := NewViewTransformer(resolutionTable)
+	viewTransformer.htmlComments = mv.options.HTMLComments
+	viewTransformer.elements = mv.options.Elements
+	viewTransformer.defaults = mv.options.Defaults
+	viewTransformer.earlyReturns = mv.options.EarlyReturns
+	viewTransformer.markdown = mv.options.Markdown
+	viewTransformer.assets = mv.options.Assets
+	viewTransformer.sourceFile = mv.options.SourceFile
+	viewTransformer.runtimeAPI = mv.options.RuntimeAPI
+	viewTransformer.runtimeModule = mv.options.RuntimeModule
+
+	compileTime, err := consteval.New(module)
+	if err != nil {
+		return nil, err
+	}
+	viewTransformer.compileTime = compileTime
+	mv.bindsDeprecated = module.BindsDeprecated()
 
 	// Transform the module body
 	transformedBody, err := mv.transformStatements(module.Body, viewTransformer)
@@ -38,11 +78,16 @@ func (mv *TransformerVisitor) TransformModule(module *ast.Module, resolutionTabl
 	// Add required imports if any views were transformed
 	if mv.hasTransformed {
 		imports := viewTransformer.GetRequiredImports()
-		// Prepend imports to the module body
-		allStmts := make([]ast.Stmt, 0, len(imports)+len(transformedBody))
+		hoisted := viewTransformer.GetHoistedStatements()
+		// Prepend imports, the runtime version check and hoisted constants to the module body
+		allStmts := make([]ast.Stmt, 0, len(imports)+len(hoisted)+len(transformedBody)+1)
 		for _, imp := range imports {
 			allStmts = append(allStmts, imp)
 		}
+		if guard := viewTransformer.GetRuntimeGuard(); guard != nil {
+			allStmts = append(allStmts, guard)
+		}
+		allStmts = append(allStmts, hoisted...)
 		allStmts = append(allStmts, transformedBody...)
 		transformedBody = allStmts
 	}
@@ -58,6 +103,12 @@ func (mv *TransformerVisitor) transformStatements(stmts []ast.Stmt, viewTransfor
 	var transformed []ast.Stmt
 
 	for _, stmt := range stmts {
+		if mv.ctx != nil {
+			if err := mv.ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
 		switch s := stmt.(type) {
 		case *ast.ViewStmt:
 			// Transform ViewStmt to Class using the configured view transformer
@@ -65,9 +116,36 @@ func (mv *TransformerVisitor) transformStatements(stmts []ast.Stmt, viewTransfor
 			if err != nil {
 				return nil, fmt.Errorf("failed to transform view %s: %w", s.Name.Token.Lexeme, err)
 			}
-			transformed = append(transformed, class)
+			transformed = append(transformed, mv.markView(s, class)...)
 			mv.hasTransformed = true
 
+		case *ast.Decorator:
+			if _, deprecated := s.Deprecated(); deprecated && !mv.bindsDeprecated {
+				// The marker only produces warnings where the definition is used
+				stripped, err := mv.transformStatements([]ast.Stmt{withoutDeprecation(s)}, viewTransformer)
+				if err != nil {
+					return nil, err
+				}
+				transformed = append(transformed, stripped...)
+				continue
+			}
+			if consteval.IsDecorator(s) {
+				// The function stays for calls evaluated at runtime
+				transformed = append(transformed, s.Stmt)
+				continue
+			}
+			if view, ok := s.Definition().(*ast.ViewStmt); ok {
+				// The decorators apply to the class the view compiles to
+				class, err := viewTransformer.TransformViewToClass(view)
+				if err != nil {
+					return nil, fmt.Errorf("failed to transform view %s: %w", view.Name.Token.Lexeme, err)
+				}
+				transformed = append(transformed, mv.markView(view, redecorate(s, class))...)
+				mv.hasTransformed = true
+				continue
+			}
+			transformed = append(transformed, stmt)
+
 		default:
 			// Keep other statements as-is
 			transformed = append(transformed, stmt)
@@ -77,6 +155,61 @@ func (mv *TransformerVisitor) transformStatements(stmts []ast.Stmt, viewTransfor
 	return transformed, nil
 }
 
+// redecorate returns a stack of decorators applied to definition in place of
+// the one they decorate
+func redecorate(d *ast.Decorator, definition ast.Stmt) ast.Stmt {
+	inner := definition
+	if next, ok := d.Stmt.(*ast.Decorator); ok {
+		inner = redecorate(next, definition)
+	}
+	return &ast.Decorator{Expr: d.Expr, Stmt: inner, Span: d.Span}
+}
+
+// markView returns the statements compiled from a view, between source
+// markers when they are enabled
+func (mv *TransformerVisitor) markView(view *ast.ViewStmt, compiled ast.Stmt) []ast.Stmt {
+	if !mv.options.Markers {
+		return []ast.Stmt{compiled}
+	}
+	begin, end := mv.viewMarkers(view)
+	return []ast.Stmt{begin, compiled, end}
+}
+
+// withoutDeprecation returns a stack of decorators without its @deprecated
+// markers, or the bare definition when nothing else decorates it
+func withoutDeprecation(d *ast.Decorator) ast.Stmt {
+	var inner ast.Stmt = d.Stmt
+	if next, ok := d.Stmt.(*ast.Decorator); ok {
+		inner = withoutDeprecation(next)
+	}
+	if _, marker := d.Deprecation(); marker {
+		return inner
+	}
+	return &ast.Decorator{Expr: d.Expr, Stmt: inner, Span: d.Span}
+}
+
+// viewMarkers returns the comments placed around the class compiled from a
+// view, naming the PSX lines it came from:
+//
+//	# topple:begin view Home home.psx:5-12
+//	class Home(BaseView): ...
+//	# topple:end view Home
+//
+// Only the file's base name is used, so builds stay reproducible across
+// checkouts; generated files normally sit next to their sources.
+// The end marker has no span: it belongs to no source line, so it gets no
+// line directive or source map entry of its own.
+func (mv *TransformerVisitor) viewMarkers(view *ast.ViewStmt) (*ast.HTMLComment, *ast.HTMLComment) {
+	name := view.Name.Token.Lexeme
+	lines := fmt.Sprintf("%d-%d", view.Span.Start.Line, view.Span.End.Line)
+	if mv.options.SourceFile != "" {
+		lines = filepath.Base(mv.options.SourceFile) + ":" + lines
+	}
+	begin := &ast.HTMLComment{Text: fmt.Sprintf("topple:begin view %s %s", name, lines), Span: view.Span}
+	end := &ast.HTMLComment{Text: "topple:end view " + name}
+	return begin, end
+}
+
 // Visitor interface methods - most just delegate to default behavior
 // We only override the ones we need to transform
 
@@ -124,6 +257,9 @@ func (mv *TransformerVisitor) VisitAwaitExpr(a *ast.AwaitExpr) ast.Visitor
 func (mv *TransformerVisitor) VisitArgument(a *ast.Argument) ast.Visitor           { return mv }
 func (mv *TransformerVisitor) VisitLambda(l *ast.Lambda) ast.Visitor               { return mv }
 func (mv *TransformerVisitor) VisitFString(f *ast.FString) ast.Visitor             { return mv }
+func (mv *TransformerVisitor) VisitConcatenatedString(c *ast.ConcatenatedString) ast.Visitor {
+	return mv
+}
 func (mv *TransformerVisitor) VisitFStringMiddle(f *ast.FStringMiddle) ast.Visitor { return mv }
 func (mv *TransformerVisitor) VisitFStringReplacementField(f *ast.FStringReplacementField) ast.Visitor {
 	return mv
@@ -184,3 +320,4 @@ func (mv *TransformerVisitor) VisitHTMLElement(h *ast.HTMLElement) ast.Visitor
 func (mv *TransformerVisitor) VisitHTMLContent(h *ast.HTMLContent) ast.Visitor             { return mv }
 func (mv *TransformerVisitor) VisitHTMLText(h *ast.HTMLText) ast.Visitor                   { return mv }
 func (mv *TransformerVisitor) VisitHTMLInterpolation(h *ast.HTMLInterpolation) ast.Visitor { return mv }
+func (mv *TransformerVisitor) VisitHTMLComment(h *ast.HTMLComment) ast.Visitor             { return mv }
@@ -0,0 +1,111 @@
+package transformers
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+)
+
+// isWhitespaceElement reports whether an element keeps its text as written
+func isWhitespaceElement(element *ast.HTMLElement) bool {
+	return element.TagExpr == nil && slices.Contains(lexer.WhitespaceElements, element.TagName.Lexeme)
+}
+
+// isScriptElement reports whether an element is a <script> or <style>, whose
+// text is passed to the browser as written, unescaped
+func isScriptElement(element *ast.HTMLElement) bool {
+	return element.TagExpr == nil && slices.Contains(lexer.ScriptElements, element.TagName.Lexeme)
+}
+
+// scriptContent returns the content of a <script> or <style>: its text,
+// marked safe so the runtime does not escape it
+func (vm *ViewTransformer) scriptContent(element *ast.HTMLElement) ast.Expr {
+	var text strings.Builder
+	for _, stmt := range element.Content {
+		if content, ok := stmt.(*ast.HTMLContent); ok {
+			for _, part := range content.Parts {
+				text.WriteString(part.String())
+			}
+		}
+	}
+	if text.Len() == 0 {
+		return &ast.Literal{Type: ast.LiteralTypeString, Value: "", Span: element.Span}
+	}
+	return vm.callFunction("raw", &ast.Literal{Type: ast.LiteralTypeString, Value: text.String(), Span: element.Span}, element.Span)
+}
+
+// textValue returns the text an HTMLText renders: as written inside
+// whitespace elements, and with each run of whitespace collapsed to a single
+// space elsewhere, as browsers render it
+func (vm *ViewTransformer) textValue(text string) string {
+	if vm.preformatted > 0 {
+		return text
+	}
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// trimParts applies the {~} markers of content: each is dropped, and the
+// whitespace of the text on either side of it is trimmed. Text left next to
+// text is joined.
+func trimParts(parts []ast.HTMLContentPart) []ast.HTMLContentPart {
+	trimmed := make([]ast.HTMLContentPart, 0, len(parts))
+	trimNext := false
+	for _, part := range parts {
+		text, ok := part.(*ast.HTMLText)
+		switch {
+		case ok && text.Trim:
+			if len(trimmed) > 0 {
+				if previous, ok := trimmed[len(trimmed)-1].(*ast.HTMLText); ok {
+					trimmed = trimmed[:len(trimmed)-1]
+					if value := strings.TrimRightFunc(previous.Value, unicode.IsSpace); value != "" {
+						trimmed = append(trimmed, &ast.HTMLText{Value: value, Span: previous.Span})
+					}
+				}
+			}
+			trimNext = true
+			continue
+		case ok && trimNext:
+			if value := strings.TrimLeftFunc(text.Value, unicode.IsSpace); value != "" {
+				trimmed = append(trimmed, &ast.HTMLText{Value: value, Span: text.Span})
+			}
+		default:
+			trimmed = append(trimmed, part)
+		}
+		trimNext = false
+	}
+
+	joined := trimmed[:0]
+	for _, part := range trimmed {
+		text, ok := part.(*ast.HTMLText)
+		if ok && len(joined) > 0 {
+			if previous, ok := joined[len(joined)-1].(*ast.HTMLText); ok {
+				joined[len(joined)-1] = &ast.HTMLText{
+					Value: previous.Value + text.Value,
+					Span:  lexer.Span{Start: previous.Span.Start, End: text.Span.End},
+				}
+				continue
+			}
+		}
+		joined = append(joined, part)
+	}
+	return joined
+}
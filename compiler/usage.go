@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// UsageReport is a census of where the views of a project are instantiated
+type UsageReport struct {
+	Views  []*ViewUsage        // Sorted by file, then line
+	Errors []*CompilationError // Files that could not be parsed or resolved; their uses may be missing
+}
+
+// ViewUsage lists the instantiations of one view
+type ViewUsage struct {
+	Name       string
+	File       string // File defining the view
+	Line       int
+	Params     []string       // Parameter names, in declaration order
+	Uses       []ViewInstance // Sorted by file, then position
+	Attributes map[string]int // How many uses pass each attribute, or "..." for a {...mapping} spread; declared parameters are always present
+}
+
+// ViewInstance is one element instantiating a view
+type ViewInstance struct {
+	File       string
+	Line       int
+	Column     int
+	Attributes []string // Attribute names in source order, "..." for a {...mapping} spread
+}
+
+// Usage resolves every file of the project and reports, per view, where it
+// is instantiated and with which attributes. Views defined in vendored
+// packages and in shared libraries on the search paths are only listed when
+// the project uses them. Files with errors are reported in the Errors of the
+// result, which is still returned: the uses they contain may be incomplete.
+func (c *MultiFileCompiler) Usage(ctx context.Context, opts MultiFileOptions) (*UsageReport, error) {
+	p, err := c.resolveProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &UsageReport{Errors: p.errors}
+
+	views := make(map[*ast.ViewStmt]*ViewUsage)
+	usageOf := func(filePath string, view *ast.ViewStmt) *ViewUsage {
+		if usage, ok := views[view]; ok {
+			return usage
+		}
+		usage := &ViewUsage{
+			Name:       view.Name.Token.Lexeme,
+			File:       filePath,
+			Line:       view.Span.Start.Line,
+			Attributes: make(map[string]int),
+		}
+		if view.Params != nil {
+			for _, param := range view.Params.Parameters {
+				if param.Name != nil && !param.IsStar && !param.IsDoubleStar {
+					usage.Params = append(usage.Params, param.Name.Token.Lexeme)
+					usage.Attributes[param.Name.Token.Lexeme] = 0
+				}
+			}
+		}
+		views[view] = usage
+		return usage
+	}
+
+	// Every view of the project is listed, used or not
+	for _, filePath := range p.order {
+		if table, ok := p.tables[filePath]; ok && p.files[filePath] {
+			for _, view := range table.Views {
+				usageOf(filePath, view)
+			}
+		}
+	}
+
+	definedIn := make(map[*ast.ViewStmt]string)
+	for filePath, table := range p.tables {
+		for _, view := range table.Views {
+			definedIn[view] = filePath
+		}
+	}
+	for filePath, table := range p.tables {
+		if !p.files[filePath] {
+			continue
+		}
+		for element, view := range table.ViewElements {
+			usage := usageOf(definedIn[view], view)
+			instance := ViewInstance{
+				File:   filePath,
+				Line:   element.Span.Start.Line,
+				Column: element.Span.Start.Column,
+			}
+			for _, attr := range element.Attributes {
+				instance.Attributes = append(instance.Attributes, attr.Name.Lexeme)
+				usage.Attributes[attr.Name.Lexeme]++
+			}
+			usage.Uses = append(usage.Uses, instance)
+		}
+	}
+
+	for _, usage := range views {
+		sort.Slice(usage.Uses, func(i, j int) bool {
+			a, b := usage.Uses[i], usage.Uses[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Column < b.Column
+		})
+		report.Views = append(report.Views, usage)
+	}
+	sort.Slice(report.Views, func(i, j int) bool {
+		a, b := report.Views[i], report.Views[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].File < report.Errors[j].File })
+
+	return report, nil
+}
+
+// resolvedProject is every file of a project, and the files they import,
+// parsed and resolved for a whole-project report
+type resolvedProject struct {
+	files   map[string]bool // Project files, as opposed to their dependencies
+	modules map[string]*ast.Module
+	order   []string // Dependencies first
+	tables  map[string]*resolver.ResolutionTable
+	errors  []*CompilationError // Files that could not be parsed or resolved
+}
+
+// resolveProject parses the files of opts and those they import, collects
+// their symbols and resolves each of them. Files with errors keep what
+// parsed and resolved, and are listed in the errors of the result.
+func (c *MultiFileCompiler) resolveProject(ctx context.Context, opts MultiFileOptions) (*resolvedProject, error) {
+	p, err := c.parseProject(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := c.depGraph.GetCompilationLayers()
+	if err != nil {
+		return nil, fmt.Errorf("circular dependency detected: %w", err)
+	}
+	for _, layer := range layers {
+		p.order = append(p.order, layer...)
+	}
+	c.collectSymbols(ctx, p.modules, p.order)
+
+	for _, filePath := range p.order {
+		if ctx.Err() != nil {
+			return nil, cancelledError(ctx.Err())
+		}
+		mod, ok := p.modules[filePath]
+		if !ok {
+			continue
+		}
+		// Resolve keeps the table of a file with errors: what it resolved still counts
+		table, _ := resolver.NewResolverWithDeps(c.moduleResolver, c.symbolRegistry, filePath).Resolve(mod)
+		if len(table.Errors) > 0 {
+			p.errors = append(p.errors, &CompilationError{
+				File:    filePath,
+				Stage:   "resolve",
+				Message: fmt.Sprintf("resolution failed with %d errors", len(table.Errors)),
+				Details: errors.Join(table.Errors...),
+			})
+		}
+		p.tables[filePath] = table
+	}
+	return p, nil
+}
+
+// parseProject parses the files of opts and those they import, and builds
+// their dependency graph. Files that do not parse are listed in the errors
+// of the result.
+func (c *MultiFileCompiler) parseProject(ctx context.Context, opts MultiFileOptions) (*resolvedProject, error) {
+	if opts.RootDir == "" {
+		return nil, fmt.Errorf("RootDir is required")
+	}
+
+	c.fs = filesystem.NewFileSystem(c.logger)
+	c.options = opts.Options
+	c.metrics = opts.Options.metrics()
+	c.moduleResolver = module.NewResolver(module.Config{
+		RootDir:     opts.RootDir,
+		SearchPaths: opts.SearchPaths,
+		Logger:      c.logger,
+		FileSystem:  c.fs,
+		Imports:     opts.Options.Imports,
+	})
+	c.setLibraryRoots(opts.RootDir, opts.SearchPaths)
+
+	files, err := c.collectAllFiles(opts.Files)
+	if err != nil {
+		return nil, fmt.Errorf("file collection failed: %w", err)
+	}
+	p := &resolvedProject{
+		files:  make(map[string]bool, len(files)),
+		tables: make(map[string]*resolver.ResolutionTable),
+	}
+	for _, filePath := range files {
+		p.files[filePath] = true
+	}
+
+	astMap, errs := c.parseAllFiles(ctx, files)
+	p.errors = append(p.errors, errs...)
+	p.errors = append(p.errors, c.parseDependencies(ctx, astMap, nil)...)
+	p.errors = append(p.errors, c.buildDependencyGraph(ctx, astMap)...)
+	if err := ctx.Err(); err != nil {
+		return nil, cancelledError(err)
+	}
+	p.modules = astMap
+	return p, nil
+}
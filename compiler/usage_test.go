@@ -0,0 +1,117 @@
+package compiler
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"components/ui.psx": `view Button(label: str, variant: str = "primary", legacy: bool = False):
+    <button class={variant}>{label}</button>
+
+view Unused(text: str):
+    <p>{text}</p>
+`,
+		"pages/home.psx": `from components.ui import Button
+
+view Card(title: str, **rest):
+    <div>
+        <h2>{title}</h2>
+        <Button label="More" />
+    </div>
+
+view Home():
+    <main>
+        <Button label="Go" variant="ghost" />
+        <Card title="Hello" />
+    </main>
+`,
+	})
+	ui := filepath.Join(tmpDir, "components/ui.psx")
+	home := filepath.Join(tmpDir, "pages/home.psx")
+
+	report, err := NewMultiFileCompiler(nil).Usage(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if len(report.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", report.Errors)
+	}
+
+	expected := []*ViewUsage{
+		{
+			Name:   "Button",
+			File:   ui,
+			Line:   1,
+			Params: []string{"label", "variant", "legacy"},
+			Uses: []ViewInstance{
+				{File: home, Line: 6, Column: 9, Attributes: []string{"label"}},
+				{File: home, Line: 11, Column: 9, Attributes: []string{"label", "variant"}},
+			},
+			Attributes: map[string]int{"label": 2, "variant": 1, "legacy": 0},
+		},
+		{
+			Name:       "Unused",
+			File:       ui,
+			Line:       4,
+			Params:     []string{"text"},
+			Attributes: map[string]int{"text": 0},
+		},
+		{
+			Name:   "Card",
+			File:   home,
+			Line:   3,
+			Params: []string{"title"},
+			Uses: []ViewInstance{
+				{File: home, Line: 12, Column: 9, Attributes: []string{"title"}},
+			},
+			Attributes: map[string]int{"title": 1},
+		},
+		{
+			Name:       "Home",
+			File:       home,
+			Line:       9,
+			Attributes: map[string]int{},
+		},
+	}
+	if len(report.Views) != len(expected) {
+		t.Fatalf("got %d views, want %d: %+v", len(report.Views), len(expected), report.Views)
+	}
+	for i, want := range expected {
+		if got := report.Views[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("view %d:\ngot  %+v\nwant %+v", i, got, want)
+		}
+	}
+}
+
+func TestUsageReportsResolutionErrors(t *testing.T) {
+	tmpDir := setupTestFiles(t, map[string]string{
+		"broken.psx": `view Broken():
+    nonlocal x
+    <p>broken</p>
+`,
+		"ok.psx": `view Ok():
+    <p>ok</p>
+`,
+	})
+
+	report, err := NewMultiFileCompiler(nil).Usage(context.Background(), MultiFileOptions{
+		RootDir: tmpDir,
+		Files:   []string{tmpDir},
+	})
+	if err != nil {
+		t.Fatalf("Usage failed: %v", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].File != filepath.Join(tmpDir, "broken.psx") {
+		t.Errorf("expected one error in broken.psx, got %v", report.Errors)
+	}
+	if len(report.Views) != 2 {
+		t.Errorf("expected both views to be listed, got %d", len(report.Views))
+	}
+}
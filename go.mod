@@ -7,6 +7,7 @@ toolchain go1.23.8
 require (
 	github.com/alecthomas/kong v1.11.0
 	github.com/fsnotify/fsnotify v1.9.0
+	golang.org/x/sync v0.9.0
 )
 
 require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,206 @@
+// Package buildstatus writes the state of a watch session to a JSON status
+// file, which editor plug-ins and task runners read instead of parsing log
+// output. The file is replaced atomically on every update, so a reader never
+// sees a partially written document.
+package buildstatus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+// Build states reported in the status file
+const (
+	Running   = "building"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+	Cancelled = "cancelled"
+)
+
+// Status is the machine-readable state of a watch session
+type Status struct {
+	State       string       `json:"state"`
+	Directory   string       `json:"directory"`
+	PID         int          `json:"pid"`
+	Build       int          `json:"build"` // Sequence number of the build, starting at 1
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+	DurationMs  int64        `json:"duration_ms"`
+	Error       string       `json:"error,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Diagnostic is one compilation error of a failed build
+type Diagnostic struct {
+	File      string `json:"file"`
+	Stage     string `json:"stage"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+
+	Expected    []string     `json:"expected,omitempty"` // Tokens that would have been accepted, for syntax errors
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+}
+
+// Suggestion is a fix for a diagnostic: replace the text from
+// Line:Column to EndLine:EndColumn with Replacement
+type Suggestion struct {
+	Message     string `json:"message"`
+	Replacement string `json:"replacement"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+}
+
+// File keeps the status of a watch session on disk. A nil File ignores all
+// updates.
+type File struct {
+	path   string
+	status Status
+}
+
+// New returns the status file at path for a session watching directory, or
+// nil when path is empty
+func New(path, directory string) *File {
+	if path == "" {
+		return nil
+	}
+	return &File{
+		path:   path,
+		status: Status{Directory: directory, PID: os.Getpid()},
+	}
+}
+
+// Started records the start of a new build
+func (f *File) Started() error {
+	if f == nil {
+		return nil
+	}
+	f.status.State = Running
+	f.status.Build++
+	f.status.StartedAt = time.Now()
+	f.status.FinishedAt = nil
+	f.status.DurationMs = 0
+	f.status.Error = ""
+	f.status.Diagnostics = []Diagnostic{}
+	return f.write()
+}
+
+// Finished records the result of the current build: err is the build's
+// error, and cancelled tells that a newer change interrupted it
+func (f *File) Finished(err error, cancelled bool) error {
+	if f == nil {
+		return nil
+	}
+	now := time.Now()
+	f.status.FinishedAt = &now
+	f.status.DurationMs = now.Sub(f.status.StartedAt).Milliseconds()
+
+	switch {
+	case cancelled:
+		f.status.State = Cancelled
+	case err != nil:
+		f.status.State = Failed
+		f.status.Error = err.Error()
+		f.status.Diagnostics = collect(err)
+	default:
+		f.status.State = Succeeded
+	}
+	return f.write()
+}
+
+// write replaces the status file atomically so readers never see a
+// partially written document
+func (f *File) write() error {
+	data, err := json.MarshalIndent(f.status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding build status: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".topple-status-*")
+	if err != nil {
+		return fmt.Errorf("error writing status file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing status file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing status file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("error writing status file: %w", err)
+	}
+	return nil
+}
+
+// collect lists the per-file errors of a failed project build, one per
+// diagnostic, or the build error itself when it failed before compiling any
+// file
+func collect(err error) []Diagnostic {
+	var projErr *ProjectError
+	if !errors.As(err, &projErr) || len(projErr.Errors) == 0 {
+		return []Diagnostic{{Stage: "build", Message: err.Error()}}
+	}
+
+	var all []Diagnostic
+	for _, compErr := range projErr.Errors {
+		if compErr.Details == nil {
+			all = append(all, Diagnostic{File: compErr.File, Stage: compErr.Stage, Message: compErr.Message})
+			continue
+		}
+		for _, d := range diagnostics.Collect(compErr.Details) {
+			diagnostic := Diagnostic{
+				File:     compErr.File,
+				Stage:    compErr.Stage,
+				Code:     string(d.Code),
+				Message:  d.Message,
+				Expected: d.Expected,
+			}
+			for _, suggestion := range d.Suggestions {
+				diagnostic.Suggestions = append(diagnostic.Suggestions, Suggestion{
+					Message:     suggestion.Message,
+					Replacement: suggestion.Replacement,
+					Line:        suggestion.Span.Start.Line,
+					Column:      suggestion.Span.Start.Column,
+					EndLine:     suggestion.Span.End.Line,
+					EndColumn:   suggestion.Span.End.Column,
+				})
+			}
+			if !d.Span.IsZero() {
+				diagnostic.Line, diagnostic.Column = d.Span.Start.Line, d.Span.Start.Column
+				diagnostic.EndLine, diagnostic.EndColumn = d.Span.End.Line, d.Span.End.Column
+			}
+			all = append(all, diagnostic)
+		}
+	}
+	return all
+}
+
+// ProjectError is the error of a project build that has compilation errors,
+// keeping them available to the status file
+type ProjectError struct {
+	Errors []*compiler.CompilationError
+	Err    error
+}
+
+func (e *ProjectError) Error() string {
+	return fmt.Sprintf("multi-file compilation failed: %v", e.Err)
+}
+
+func (e *ProjectError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,131 @@
+package buildstatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+)
+
+func readStatus(t *testing.T, path string) Status {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatalf("invalid status file: %v\n%s", err, data)
+	}
+	return status
+}
+
+func TestStatusFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.json")
+	f := New(path, "/src")
+
+	if err := f.Started(); err != nil {
+		t.Fatal(err)
+	}
+	status := readStatus(t, path)
+	if status.State != Running || status.Build != 1 || status.Directory != "/src" || status.PID != os.Getpid() {
+		t.Errorf("unexpected status of a running build: %+v", status)
+	}
+	if status.FinishedAt != nil || status.Diagnostics == nil {
+		t.Errorf("expected no finish time and an empty diagnostics list, got %+v", status)
+	}
+
+	if err := f.Finished(nil, false); err != nil {
+		t.Fatal(err)
+	}
+	status = readStatus(t, path)
+	if status.State != Succeeded || status.FinishedAt == nil || status.Error != "" {
+		t.Errorf("unexpected status of a successful build: %+v", status)
+	}
+
+	f.Started()
+	f.Finished(context.Canceled, true)
+	status = readStatus(t, path)
+	if status.State != Cancelled || status.Build != 2 {
+		t.Errorf("unexpected status of a cancelled build: %+v", status)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected only the status file to be left in %s, got %v", dir, entries)
+	}
+}
+
+func TestNilStatusFile(t *testing.T) {
+	f := New("", "/src")
+	if f != nil {
+		t.Fatalf("expected no status file without a path, got %+v", f)
+	}
+	if err := f.Started(); err != nil {
+		t.Error(err)
+	}
+	if err := f.Finished(errors.New("failed"), false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailedBuildDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status.json")
+	f := New(path, "/src")
+	f.Started()
+
+	syntax := &diagnostics.Diagnostic{
+		Code:     "E0200",
+		Message:  "unexpected token",
+		Span:     diagnostics.Span{Start: diagnostics.Position{Line: 2, Column: 5}, End: diagnostics.Position{Line: 2, Column: 6}},
+		Expected: []string{"':'"},
+		Suggestions: []diagnostics.Suggestion{{
+			Message:     "add a colon",
+			Replacement: ":",
+			Span:        diagnostics.Span{Start: diagnostics.Position{Line: 2, Column: 5}, End: diagnostics.Position{Line: 2, Column: 5}},
+		}},
+	}
+	err := &ProjectError{
+		Errors: []*compiler.CompilationError{
+			{File: "a.psx", Stage: "parse", Message: "parse failed", Details: syntax},
+			{File: "b.psx", Stage: "codegen", Message: "cannot write"},
+		},
+		Err: fmt.Errorf("2 files failed"),
+	}
+	if err := f.Finished(err, false); err != nil {
+		t.Fatal(err)
+	}
+
+	status := readStatus(t, path)
+	if status.State != Failed || status.Error != err.Error() {
+		t.Errorf("unexpected status of a failed build: %+v", status)
+	}
+	expected := []Diagnostic{
+		{
+			File: "a.psx", Stage: "parse", Code: "E0200", Message: "unexpected token",
+			Line: 2, Column: 5, EndLine: 2, EndColumn: 6,
+			Expected:    []string{"':'"},
+			Suggestions: []Suggestion{{Message: "add a colon", Replacement: ":", Line: 2, Column: 5, EndLine: 2, EndColumn: 5}},
+		},
+		{File: "b.psx", Stage: "codegen", Message: "cannot write"},
+	}
+	if !reflect.DeepEqual(status.Diagnostics, expected) {
+		t.Errorf("expected diagnostics %+v, got %+v", expected, status.Diagnostics)
+	}
+}
+
+// A build that fails before compiling any file reports its own error
+func TestBuildErrorDiagnostic(t *testing.T) {
+	expected := []Diagnostic{{Stage: "build", Message: "no such directory"}}
+	if got := collect(errors.New("no such directory")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
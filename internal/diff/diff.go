@@ -0,0 +1,196 @@
+// Package diff produces line-based unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is how many unchanged lines surround each change in a hunk
+const context = 3
+
+// opKind is what an edit does to a line
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// edit is one line of the edit script turning the old text into the new
+type edit struct {
+	kind opKind
+	line string
+}
+
+// Unified returns the unified diff from oldText to newText with the given
+// file names in its header, or "" when the texts are equal
+func Unified(oldName, newName string, oldText, newText []byte) string {
+	if string(oldText) == string(newText) {
+		return ""
+	}
+	a, b := splitLines(string(oldText)), splitLines(string(newText))
+	edits := lineEdits(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Positions in the old and new text of each edit, counted from 1
+	oldLine, newLine := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	oldLine[0], newLine[0] = 1, 1
+	for i, e := range edits {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if e.kind != opInsert {
+			oldLine[i+1]++
+		}
+		if e.kind != opDelete {
+			newLine[i+1]++
+		}
+	}
+
+	for start := 0; start < len(edits); {
+		// Find the next change and the end of the hunk around it
+		first := start
+		for first < len(edits) && edits[first].kind == opEqual {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+		from := max(first-context, start)
+		end := first
+		for unchanged := 0; end < len(edits) && unchanged <= 2*context; end++ {
+			if edits[end].kind == opEqual {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Trim the unchanged lines after the last change to the context size
+		last := end - 1
+		for edits[last].kind == opEqual {
+			last--
+		}
+		to := min(last+1+context, len(edits))
+
+		oldCount, newCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.kind != opInsert {
+				oldCount++
+			}
+			if e.kind != opDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine[from], oldCount), hunkRange(newLine[from], newCount))
+		for _, e := range edits[from:to] {
+			switch e.kind {
+			case opEqual:
+				out.WriteString(" ")
+			case opDelete:
+				out.WriteString("-")
+			case opInsert:
+				out.WriteString("+")
+			}
+			out.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk side. An empty side is
+// numbered by the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text after each newline, keeping the newlines
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineEdits returns a shortest edit script from a to b, found with Myers'
+// algorithm
+func lineEdits(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d, k)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved frontiers of lineEdits back from the end to
+// recover the edit script
+func backtrack(a, b []string, trace [][]int, offset, d, k int) []edit {
+	x, y := len(a), len(b)
+	var edits []edit
+	for ; d > 0; d-- {
+		v := trace[d]
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{opEqual, a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{opInsert, b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{opDelete, a[x]})
+		}
+		k = prevK
+	}
+	for x > 0 {
+		x--
+		edits = append(edits, edit{opEqual, a[x]})
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
@@ -0,0 +1,55 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "separate hunks",
+			old:  "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n",
+			new:  "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nM\nn\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -10,4 +10,5 @@\n j\n k\n l\n-m\n+M\n+n\n",
+		},
+		{
+			name: "merged hunk",
+			old:  "a\nb\nc\nd\ne\nf\ng\nh\n",
+			new:  "A\nb\nc\nd\ne\nf\ng\nH\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,8 +1,8 @@\n-a\n+A\n b\n c\n d\n e\n f\n g\n-h\n+H\n",
+		},
+		{
+			name: "missing newline",
+			old:  "x\ny",
+			new:  "x\ny\n",
+			want: "--- old\n+++ new\n" +
+				"@@ -1,2 +1,2 @@\n x\n-y\n\\ No newline at end of file\n+y\n",
+		},
+		{
+			name: "from empty",
+			old:  "",
+			new:  "a\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("old", "new", []byte(tt.old), []byte(tt.new))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -58,7 +58,9 @@ type FileSystem interface {
 	// Directory Operations
 	ListFiles(dir string, recursive bool) ([]string, error)
 	ListPSXFiles(dir string, recursive bool) ([]string, error)
+	ReadDir(dir string) ([]string, error)
 	MkdirAll(path string, perm os.FileMode) error
+	RemoveAll(path string) error
 
 	// Path Operations
 	ResolvePath(path string) (string, error)
@@ -74,6 +76,20 @@ type FileSystem interface {
 	StopWatching() error
 }
 
+// Logger receives the file system's log output. *slog.Logger and the
+// compiler's observe.Logger satisfy it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
 // StandardFileSystem implements FileSystem using the standard library
 type StandardFileSystem struct {
 	watcher   *fsnotify.Watcher
@@ -81,15 +97,15 @@ type StandardFileSystem struct {
 	mu        sync.Mutex
 	watching  bool
 	done      chan struct{}
-	logger    *slog.Logger
+	logger    Logger
 }
 
-// NewFileSystem creates a new FileSystem instance with a logger
-func NewFileSystem(logger *slog.Logger) FileSystem {
-	if logger == nil {
-		logger = slog.Default()
+// NewFileSystem creates a new FileSystem instance with a logger.
+// A nil logger discards all output.
+func NewFileSystem(logger Logger) FileSystem {
+	if l, ok := logger.(*slog.Logger); logger == nil || (ok && l == nil) {
+		logger = nopLogger{}
 	}
-
 	return &StandardFileSystem{
 		eventChan: make(chan FileEvent),
 		done:      make(chan struct{}),
@@ -218,6 +234,20 @@ func (s *StandardFileSystem) ListPSXFiles(dir string, recursive bool) ([]string,
 	return psxFiles, nil
 }
 
+// ReadDir lists the names of the entries of a directory, as cased on disk
+func (s *StandardFileSystem) ReadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.logger.Debug("Failed to read directory", "directory", dir, "error", err)
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names, nil
+}
+
 // MkdirAll creates a directory and all necessary parent directories
 func (s *StandardFileSystem) MkdirAll(path string, perm os.FileMode) error {
 	s.logger.Debug("Creating directory", "path", path, "permission", perm)
@@ -228,6 +258,16 @@ func (s *StandardFileSystem) MkdirAll(path string, perm os.FileMode) error {
 	return err
 }
 
+// RemoveAll removes a file or a directory and everything in it
+func (s *StandardFileSystem) RemoveAll(path string) error {
+	s.logger.Debug("Removing", "path", path)
+	err := os.RemoveAll(path)
+	if err != nil {
+		s.logger.Error("Failed to remove", "path", path, "error", err)
+	}
+	return err
+}
+
 // ResolvePath resolves a path to its absolute form, handling symlinks
 func (s *StandardFileSystem) ResolvePath(path string) (string, error) {
 	s.logger.Debug("Resolving path", "path", path)
@@ -0,0 +1,43 @@
+package livereload
+
+// clientScript connects to the server it was loaded from and reloads the
+// page after each successful build, unless a "topple:reload" listener calls
+// preventDefault. It reconnects when the connection drops, such as when
+// watch mode restarts.
+const clientScript = `(function () {
+  "use strict";
+  var script = document.currentScript;
+  var base = script && script.src ? new URL(script.src) : window.location;
+  var url = (base.protocol === "https:" ? "wss://" : "ws://") + base.host + "/livereload";
+  var retry = 500;
+
+  function connect() {
+    var socket = new WebSocket(url);
+    socket.onopen = function () {
+      retry = 500;
+    };
+    socket.onmessage = function (event) {
+      var message;
+      try {
+        message = JSON.parse(event.data);
+      } catch (e) {
+        return;
+      }
+      if (message.type === "error") {
+        console.error("[topple] build failed: " + message.error);
+        return;
+      }
+      var reload = new CustomEvent("topple:reload", {detail: message, cancelable: true});
+      if (window.dispatchEvent(reload)) {
+        window.location.reload();
+      }
+    };
+    socket.onclose = function () {
+      setTimeout(connect, retry);
+      retry = Math.min(retry * 2, 5000);
+    };
+  }
+
+  connect();
+})();
+`
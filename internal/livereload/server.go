@@ -0,0 +1,194 @@
+// Package livereload notifies browsers when watch mode recompiles views.
+//
+// The server accepts WebSocket connections at /livereload and serves the
+// script pages include to open one at /livereload.js. After each build,
+// Notify sends every connected browser a JSON message: "reload" with the
+// modules and files the build rewrote, or "error" with why it failed. On a
+// reload, the script dispatches a "topple:reload" event on the window with
+// the message, and reloads the page unless a listener calls preventDefault,
+// so apps can refresh only what changed instead. Errors are logged to the
+// browser console.
+//
+// # Usage
+//
+//	server := livereload.NewServer(logger)
+//	go server.ListenAndServe(ctx, "127.0.0.1:35729")
+//	server.Notify(livereload.Message{Type: livereload.Reload, Modules: modules})
+//
+// Pages load the script with:
+//
+//	<script src="http://127.0.0.1:35729/livereload.js"></script>
+package livereload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler/observe"
+)
+
+// Message types
+const (
+	Reload = "reload" // A build succeeded and rewrote modules
+	Error  = "error"  // A build failed; the previous outputs are still served
+)
+
+// Message is what browsers are notified of after a build
+type Message struct {
+	Type    string   `json:"type"`
+	Modules []string `json:"modules,omitempty"` // Dotted names of the rewritten modules, sorted
+	Files   []string `json:"files,omitempty"`   // Rewritten source files relative to the watched directory, sorted
+	Error   string   `json:"error,omitempty"`
+}
+
+// Server broadcasts build notifications to connected browsers
+type Server struct {
+	logger observe.Logger
+
+	mu      sync.Mutex
+	clients map[*conn]bool
+}
+
+// NewServer creates a server with no connected browsers. A nil logger
+// discards all output.
+func NewServer(logger observe.Logger) *Server {
+	return &Server{logger: observe.LoggerOrNop(logger), clients: make(map[*conn]bool)}
+}
+
+// Handler serves the WebSocket endpoint and the client script
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livereload", s.serveWebSocket)
+	mux.HandleFunc("/livereload.js", serveScript)
+	return mux
+}
+
+// ListenAndServe serves on addr until ctx is done, then disconnects the
+// browsers. It returns nil once stopped by ctx.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on listener until ctx is done, then disconnects the browsers.
+// It returns nil once stopped by ctx.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+		s.closeAll()
+	}()
+
+	s.logger.Info("Live-reload server listening", "address", listener.Addr().String())
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+		return nil
+	}
+	return err
+}
+
+// Notify sends msg to every connected browser, disconnecting those that
+// cannot receive it
+func (s *Server) Notify(msg Message) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		s.logger.Error("Could not encode live-reload message", "error", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	clients := make([]*conn, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		if err := c.writeFrame(opText, payload); err != nil {
+			s.logger.Debug("Dropping live-reload client", "error", err.Error())
+			s.remove(c)
+		}
+	}
+	s.logger.Debug("Notified browsers", "type", msg.Type, "clients", len(clients))
+}
+
+// Clients returns how many browsers are connected
+func (s *Server) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// serveWebSocket upgrades a browser connection and keeps it registered until
+// the browser closes it
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	c, err := accept(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.clients[c] = true
+	s.mu.Unlock()
+	s.logger.Debug("Live-reload client connected", "remote", r.RemoteAddr)
+
+	// Browsers only send control frames; answer pings and stop at a close
+	defer s.remove(c)
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case opPing:
+			if c.writeFrame(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			_ = c.writeFrame(opClose, nil)
+			return
+		}
+	}
+}
+
+// remove disconnects a browser
+func (s *Server) remove(c *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[c] {
+		delete(s.clients, c)
+		c.close()
+	}
+}
+
+// closeAll disconnects every browser
+func (s *Server) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		_ = c.writeFrame(opClose, nil)
+		c.close()
+	}
+	s.clients = make(map[*conn]bool)
+}
+
+// serveScript serves the script pages include to connect
+func serveScript(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write([]byte(clientScript))
+}
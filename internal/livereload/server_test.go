@@ -0,0 +1,231 @@
+package livereload
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves on a free local port until the test ends
+func startServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewServer(nil)
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve returned %v after cancellation", err)
+		}
+	})
+	return server, listener.Addr().String()
+}
+
+// client is a minimal browser side of the protocol
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	request := "GET /livereload HTTP/1.1\r\nHost: " + addr + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %s", resp.Status)
+	}
+	// The example of RFC 6455, section 1.3
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected accept key %q", got)
+	}
+	return &client{conn: conn, reader: reader}
+}
+
+// send writes a masked frame, as browsers do
+func (c *client) send(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+// receive reads an unmasked frame from the server
+func (c *client) receive(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		t.Fatalf("Reading frame: %v", err)
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("Server frames must not be masked")
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.reader, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatalf("Reading payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// waitForClients waits until the server has registered n browsers
+func waitForClients(t *testing.T, server *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.Clients() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, got %d", n, server.Clients())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	server, addr := startServer(t)
+	first, second := dial(t, addr), dial(t, addr)
+	waitForClients(t, server, 2)
+
+	sent := Message{
+		Type:    Reload,
+		Modules: []string{"components.card", "pages." + strings.Repeat("x", 200)},
+		Files:   []string{"components/card.psx"},
+	}
+	server.Notify(sent)
+	for _, c := range []*client{first, second} {
+		opcode, payload := c.receive(t)
+		if opcode != opText {
+			t.Fatalf("Expected a text frame, got opcode %d", opcode)
+		}
+		var got Message
+		if err := json.Unmarshal(payload, &got); err != nil {
+			t.Fatalf("Invalid message %s: %v", payload, err)
+		}
+		if !reflect.DeepEqual(got, sent) {
+			t.Errorf("Expected %+v, got %+v", sent, got)
+		}
+	}
+
+	server.Notify(Message{Type: Error, Error: "parsing failed"})
+	if _, payload := first.receive(t); string(payload) != `{"type":"error","error":"parsing failed"}` {
+		t.Errorf("Unexpected error message %s", payload)
+	}
+}
+
+func TestControlFrames(t *testing.T) {
+	server, addr := startServer(t)
+	c := dial(t, addr)
+	waitForClients(t, server, 1)
+
+	c.send(t, opPing, []byte("hi"))
+	if opcode, payload := c.receive(t); opcode != opPong || string(payload) != "hi" {
+		t.Errorf("Expected a pong echoing the ping, got opcode %d %q", opcode, payload)
+	}
+
+	c.send(t, opClose, nil)
+	if opcode, _ := c.receive(t); opcode != opClose {
+		t.Errorf("Expected a close frame, got opcode %d", opcode)
+	}
+	waitForClients(t, server, 0)
+}
+
+func TestShutdownClosesClients(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	server := NewServer(nil)
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, listener) }()
+
+	c := dial(t, listener.Addr().String())
+	waitForClients(t, server, 1)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve returned %v", err)
+	}
+	if opcode, _ := c.receive(t); opcode != opClose {
+		t.Errorf("Expected a close frame on shutdown, got opcode %d", opcode)
+	}
+	if server.Clients() != 0 {
+		t.Errorf("Expected no clients after shutdown, got %d", server.Clients())
+	}
+}
+
+func TestHTTPEndpoints(t *testing.T) {
+	_, addr := startServer(t)
+
+	resp, err := http.Get("http://" + addr + "/livereload.js")
+	if err != nil {
+		t.Fatalf("GET script: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "javascript") {
+		t.Errorf("Unexpected script response %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "topple:reload") {
+		t.Error("Expected the script to dispatch topple:reload")
+	}
+
+	// A plain request is not upgraded
+	resp, err = http.Get("http://" + addr + "/livereload")
+	if err != nil {
+		t.Fatalf("GET endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an upgrade, got %s", resp.Status)
+	}
+}
+
+func TestListenAndServeError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	if err := NewServer(nil).ListenAndServe(context.Background(), listener.Addr().String()); err == nil {
+		t.Error("Expected an error for an address in use")
+	}
+}
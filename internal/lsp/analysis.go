@@ -0,0 +1,511 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/fjvillamarin/topple/compiler/ast"
+	"github.com/fjvillamarin/topple/compiler/depgraph"
+	"github.com/fjvillamarin/topple/compiler/diagnostics"
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/compiler/module"
+	"github.com/fjvillamarin/topple/compiler/parser"
+	"github.com/fjvillamarin/topple/compiler/resolver"
+	"github.com/fjvillamarin/topple/compiler/symbol"
+)
+
+// diagnosticSource names the server in diagnostics
+const diagnosticSource = "topple"
+
+// analysis is what the server knows about a document: its AST, name
+// resolution and the symbols of the files it imports
+type analysis struct {
+	path    string
+	rootDir string
+	fs      *Overlay
+
+	module *ast.Module               // nil when the document does not scan; partial with syntax errors
+	table  *resolver.ResolutionTable // nil when module is
+
+	registry  *symbol.Registry
+	sources   map[string][]byte   // Contents of the loaded files
+	definedIn map[ast.Node]string // Top-level definition -> file defining it
+	aliases   map[string]string   // Local name -> imported name, for "from x import a as b"
+
+	diagnostics []Diagnostic
+}
+
+// analyze scans, parses and resolves the document at path. Files it imports
+// are loaded through fs, so open documents are read from their buffers.
+func analyze(fs *Overlay, rootDir, path string, config lexer.ScannerConfig) (a *analysis) {
+	a = &analysis{
+		path:      path,
+		rootDir:   rootDir,
+		fs:        fs,
+		registry:  symbol.NewRegistry(),
+		sources:   make(map[string][]byte),
+		definedIn: make(map[ast.Node]string),
+		aliases:   make(map[string]string),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a.module, a.table = nil, nil
+			a.diagnostics = append(a.diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("internal compiler error: %v", r),
+			})
+		}
+	}()
+
+	src, err := fs.ReadFile(path)
+	if err != nil {
+		a.diagnostics = append(a.diagnostics, a.diagnostic(err))
+		return a
+	}
+	a.sources[path] = src
+
+	// A document with syntax errors still has the statements that parsed,
+	// so navigation keeps working while it is being edited
+	mod, errs := parse(src, config)
+	for _, err := range errs {
+		a.diagnostics = append(a.diagnostics, a.diagnostic(err))
+	}
+	if mod == nil {
+		return a
+	}
+
+	modules := module.NewResolver(module.Config{RootDir: rootDir, SearchPaths: module.SearchPathsFromEnv(), FileSystem: fs})
+	a.register(path, mod, modules, config)
+
+	r := resolver.NewResolverWithDeps(modules, a.registry, path)
+	table, _ := r.Resolve(mod)
+	// Names defined by the statements that did not parse would be reported
+	// as undefined: resolution errors wait until the syntax is fixed
+	if len(errs) == 0 {
+		for _, err := range table.Errors {
+			a.diagnostics = append(a.diagnostics, a.diagnostic(err))
+		}
+		for _, warning := range table.Warnings {
+			a.diagnostics = append(a.diagnostics, a.diagnostic(warning))
+		}
+	}
+	a.module, a.table = mod, table
+
+	for _, stmt := range mod.Body {
+		if imp, ok := stmt.(*ast.ImportFromStmt); ok {
+			for _, name := range imp.Names {
+				if name.AsName != nil {
+					a.aliases[name.AsName.Token.Lexeme] = name.DottedName.Names[0].Token.Lexeme
+				}
+			}
+		}
+	}
+	return a
+}
+
+// load parses an imported file and registers its symbols, those of the
+// statements that parsed when it has syntax errors. Errors in imported files
+// are reported when those files are analyzed, not here.
+func (a *analysis) load(path string, modules *module.StandardResolver, config lexer.ScannerConfig) {
+	if _, loaded := a.sources[path]; loaded {
+		return
+	}
+	src, err := a.fs.ReadFile(path)
+	if err != nil {
+		return
+	}
+	a.sources[path] = src
+
+	mod, _ := parse(src, config)
+	if mod == nil {
+		return
+	}
+	a.register(path, mod, modules, config)
+}
+
+// register loads the imports of a parsed file, then collects its symbols,
+// so re-exports of imported names resolve
+func (a *analysis) register(path string, mod *ast.Module, modules *module.StandardResolver, config lexer.ScannerConfig) {
+	imports, _ := depgraph.ExtractImports(mod, path, modules)
+	for _, imp := range imports {
+		a.load(imp.ModulePath, modules, config)
+	}
+
+	symbols := symbol.NewCollectorWithDeps(path, a.registry, modules).CollectFromModule(mod)
+	a.registry.RegisterModule(path, symbols)
+	// Imports are registered first, so re-exported nodes keep their file
+	for _, sym := range symbols.Symbols {
+		if _, seen := a.definedIn[sym.Node]; !seen {
+			a.definedIn[sym.Node] = path
+		}
+	}
+}
+
+func parse(src []byte, config lexer.ScannerConfig) (*ast.Module, []error) {
+	scanner := lexer.NewScannerWithConfig(src, config)
+	tokens := scanner.ScanTokens()
+	if len(scanner.Errors) > 0 {
+		return nil, scanner.Errors
+	}
+	p := parser.NewParser(tokens)
+	p.Syntax = scanner.Syntax()
+	return p.Parse()
+}
+
+// diagnostic converts a compiler error to a diagnostic of the document
+func (a *analysis) diagnostic(err error) Diagnostic {
+	src := a.sources[a.path]
+	d := diagnostics.From(err)
+	out := Diagnostic{
+		Severity: SeverityError,
+		Source:   diagnosticSource,
+		Code:     string(d.Code),
+		Message:  strings.Join(append([]string{d.Message}, d.Hints...), "\n"),
+	}
+	if d.Severity == diagnostics.Warning {
+		out.Severity = SeverityWarning
+	}
+	if d.Code == diagnostics.CodeDeprecated {
+		out.Tags = []DiagnosticTag{DiagnosticTagDeprecated}
+	}
+	if !d.Span.IsZero() {
+		out.Range = toRange(src, lexerSpan(d.Span))
+	}
+	for _, label := range d.Labels {
+		out.RelatedInformation = append(out.RelatedInformation, DiagnosticRelatedInformation{
+			Location: Location{URI: pathToURI(a.path), Range: toRange(src, lexerSpan(label.Span))},
+			Message:  label.Message,
+		})
+	}
+	if len(d.Expected) > 0 || len(d.Suggestions) > 0 {
+		out.Data = &DiagnosticData{Expected: d.Expected}
+		for _, suggestion := range d.Suggestions {
+			out.Message += "\n" + suggestion.Message
+			out.Data.Suggestions = append(out.Data.Suggestions, DiagnosticSuggestion{
+				Title: suggestion.Message,
+				Edit:  TextEdit{Range: toRange(src, lexerSpan(suggestion.Span)), NewText: suggestion.Replacement},
+			})
+		}
+	}
+	return out
+}
+
+// lexerSpan converts the span of a diagnostic back to a source span
+func lexerSpan(span diagnostics.Span) lexer.Span {
+	return lexer.Span{
+		Start: lexer.Position{Line: span.Start.Line, Column: span.Start.Column},
+		End:   lexer.Position{Line: span.End.Line, Column: span.End.Column},
+	}
+}
+
+// target is the definition a name or view tag refers to
+type target struct {
+	file string
+	span lexer.Span // Name of the definition; zero for a whole module
+	node ast.Node   // Defining statement of top-level names
+	kind string     // view, function, class, variable, parameter or module
+	name string
+}
+
+// lookup finds the name or view tag at pos and what it refers to
+func (a *analysis) lookup(pos Position) (lexer.Span, *target) {
+	if a.module == nil {
+		return lexer.Span{}, nil
+	}
+	at := fromPosition(a.sources[a.path], pos)
+
+	switch node := nodeAt(a.module, at).(type) {
+	case *ast.Name:
+		return node.Span, a.resolveName(node)
+	case *ast.HTMLElement:
+		view, ok := a.table.ViewElements[node]
+		if !ok {
+			return node.TagName.Span, nil
+		}
+		file := a.definedIn[view]
+		if file == "" {
+			return node.TagName.Span, nil
+		}
+		return node.TagName.Span, &target{file: file, span: view.Name.Span, node: view, kind: "view", name: view.Name.Token.Lexeme}
+	}
+	return lexer.Span{}, nil
+}
+
+// resolveName follows a name to its definition: the symbol it imports, the
+// top-level definition, or the local binding
+func (a *analysis) resolveName(name *ast.Name) *target {
+	v := a.table.Variables[name]
+	if v == nil {
+		return nil
+	}
+
+	if v.IsImported && v.ImportSource != "" {
+		imported := v.Name
+		if original, ok := a.aliases[v.Name]; ok {
+			imported = original
+		}
+		if sym, err := a.registry.LookupSymbol(v.ImportSource, imported); err == nil {
+			return a.symbolTarget(sym)
+		}
+		return &target{file: v.ImportSource, kind: "module", name: v.Name}
+	}
+
+	if v.DefinitionDepth == 0 && !v.IsParameter {
+		if sym, err := a.registry.LookupSymbol(a.path, v.Name); err == nil && a.definedIn[sym.Node] == a.path {
+			return a.symbolTarget(sym)
+		}
+	}
+
+	if v.FirstDefSpan.Start.Line == 0 {
+		// Builtins and other names without a definition in the project
+		return nil
+	}
+	kind := "variable"
+	if v.IsParameter || v.IsViewParameter {
+		kind = "parameter"
+	}
+	return &target{file: a.path, span: v.FirstDefSpan, kind: kind, name: v.Name}
+}
+
+func (a *analysis) symbolTarget(sym *symbol.Symbol) *target {
+	t := &target{file: a.definedIn[sym.Node], node: sym.Node, kind: sym.Type.String(), name: sym.Name}
+	if t.file == "" {
+		t.file = sym.Location.File
+	}
+	switch node := sym.Node.(type) {
+	case *ast.ViewStmt:
+		t.span = node.Name.Span
+	case *ast.Function:
+		t.span = node.Name.Span
+	case *ast.Class:
+		t.span = node.Name.Span
+	default:
+		t.span = sym.Node.GetSpan()
+		t.node = nil
+	}
+	return t
+}
+
+// definition returns where the name or view tag at pos is defined
+func (a *analysis) definition(pos Position) *Location {
+	_, t := a.lookup(pos)
+	if t == nil {
+		return nil
+	}
+	return &Location{URI: pathToURI(t.file), Range: toRange(a.source(t.file), t.span)}
+}
+
+// hover describes the name or view tag at pos
+func (a *analysis) hover(pos Position) *Hover {
+	ref, t := a.lookup(pos)
+	if t == nil {
+		return nil
+	}
+
+	header := fmt.Sprintf("(%s) %s", t.kind, t.name)
+	if t.node != nil {
+		header = definitionHeader(a.source(t.file), t.node)
+	}
+	text := "```python\n" + header + "\n```"
+	if t.file != a.path {
+		text += fmt.Sprintf("\n\nDefined in `%s`", a.relative(t.file))
+	}
+
+	r := toRange(a.sources[a.path], ref)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &r}
+}
+
+// symbols lists the definitions of the document in source order
+func (a *analysis) symbols() []DocumentSymbol {
+	result := []DocumentSymbol{}
+	if a.module == nil {
+		return result
+	}
+	src := a.sources[a.path]
+	for _, stmt := range a.module.Body {
+		result = append(result, documentSymbols(src, stmt, false)...)
+	}
+	return result
+}
+
+func documentSymbols(src []byte, stmt ast.Stmt, inClass bool) []DocumentSymbol {
+	switch s := stmt.(type) {
+	case *ast.ViewStmt:
+		return []DocumentSymbol{{
+			Name: s.Name.Token.Lexeme, Detail: "view", Kind: SymbolKindFunction,
+			Range: toRange(src, s.Span), SelectionRange: toRange(src, s.Name.Span),
+		}}
+	case *ast.Function:
+		kind, detail := SymbolKindFunction, "function"
+		if inClass {
+			kind, detail = SymbolKindMethod, "method"
+		}
+		return []DocumentSymbol{{
+			Name: s.Name.Token.Lexeme, Detail: detail, Kind: kind,
+			Range: toRange(src, s.Span), SelectionRange: toRange(src, s.Name.Span),
+		}}
+	case *ast.Class:
+		var children []DocumentSymbol
+		for _, member := range s.Body {
+			children = append(children, documentSymbols(src, member, true)...)
+		}
+		return []DocumentSymbol{{
+			Name: s.Name.Token.Lexeme, Detail: "class", Kind: SymbolKindClass,
+			Range: toRange(src, s.Span), SelectionRange: toRange(src, s.Name.Span),
+			Children: children,
+		}}
+	case *ast.Decorator:
+		return documentSymbols(src, s.Stmt, inClass)
+	case *ast.AssignStmt:
+		var result []DocumentSymbol
+		for _, name := range targetNames(s.Targets) {
+			result = append(result, DocumentSymbol{
+				Name: name.Token.Lexeme, Kind: SymbolKindVariable,
+				Range: toRange(src, s.Span), SelectionRange: toRange(src, name.Span),
+			})
+		}
+		return result
+	case *ast.AnnotationStmt:
+		if name, ok := s.Target.(*ast.Name); ok {
+			return []DocumentSymbol{{
+				Name: name.Token.Lexeme, Kind: SymbolKindVariable,
+				Range: toRange(src, s.Span), SelectionRange: toRange(src, name.Span),
+			}}
+		}
+	}
+	return nil
+}
+
+// targetNames returns the names bound by assignment targets, including
+// tuple and list unpacking
+func targetNames(targets []ast.Expr) []*ast.Name {
+	var names []*ast.Name
+	for _, target := range targets {
+		switch t := target.(type) {
+		case *ast.Name:
+			names = append(names, t)
+		case *ast.TupleExpr:
+			names = append(names, targetNames(t.Elements)...)
+		case *ast.ListExpr:
+			names = append(names, targetNames(t.Elements)...)
+		}
+	}
+	return names
+}
+
+// source returns the contents of a loaded file, reading it if needed
+func (a *analysis) source(path string) []byte {
+	if src, ok := a.sources[path]; ok {
+		return src
+	}
+	src, _ := a.fs.ReadFile(path)
+	return src
+}
+
+// relative returns path relative to the project root when it lies inside it
+func (a *analysis) relative(path string) string {
+	if rel, err := filepath.Rel(a.rootDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// definitionHeader returns the first line of a definition, up to its colon,
+// joining the lines of a signature that spans several
+func definitionHeader(src []byte, node ast.Node) string {
+	lines := strings.Split(string(src), "\n")
+	var header []string
+	for line := node.GetSpan().Start.Line; line >= 1 && line <= len(lines) && len(header) < 10; line++ {
+		text := strings.TrimSpace(lines[line-1])
+		header = append(header, text)
+		if strings.HasSuffix(text, ":") {
+			break
+		}
+	}
+	return strings.TrimSuffix(strings.Join(header, " "), ":")
+}
+
+// nodeAt returns the name, or the element whose tag name, is at pos
+func nodeAt(node ast.Node, pos lexer.Position) ast.Node {
+	var found ast.Node
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Name:
+			if contains(n.Span, pos) {
+				found = n
+			}
+		case *ast.HTMLElement:
+			if contains(n.TagName.Span, pos) {
+				found = n
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// contains reports whether pos lies in span; the end counts, so a cursor
+// right after a name still refers to it
+func contains(span lexer.Span, pos lexer.Position) bool {
+	return !before(pos, span.Start) && !before(span.End, pos)
+}
+
+func before(a, b lexer.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// toRange converts a lexer span of src to a protocol range
+func toRange(src []byte, span lexer.Span) Range {
+	end := span.End
+	if end.Line == 0 {
+		end = span.Start
+	}
+	return Range{Start: toPosition(src, span.Start), End: toPosition(src, end)}
+}
+
+// toPosition converts a one-based lexer position, counting runes, to a
+// zero-based protocol position counting UTF-16 code units
+func toPosition(src []byte, pos lexer.Position) Position {
+	if pos.Line < 1 {
+		return Position{}
+	}
+	units, column := 0, 1
+	for _, r := range sourceLine(src, pos.Line) {
+		if column >= pos.Column {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	// Columns past the end of the line, such as an end of line, are kept
+	units += max(pos.Column-column, 0)
+	return Position{Line: pos.Line - 1, Character: units}
+}
+
+// fromPosition converts a protocol position to a lexer position of src
+func fromPosition(src []byte, pos Position) lexer.Position {
+	units, column := 0, 1
+	for _, r := range sourceLine(src, pos.Line+1) {
+		if units >= pos.Character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return lexer.Position{Line: pos.Line + 1, Column: column}
+}
+
+// sourceLine returns the one-based line of src without its line ending
+func sourceLine(src []byte, line int) string {
+	lines := strings.SplitN(string(src), "\n", line+1)
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line-1], "\r")
+}
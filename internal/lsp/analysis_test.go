@@ -0,0 +1,252 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjvillamarin/topple/compiler/lexer"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// writeProject writes files under a temporary root and returns the root
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// positionOf returns the protocol position of the nth occurrence (from 1) of
+// needle in src, offset by delta characters
+func positionOf(t *testing.T, src, needle string, nth, delta int) Position {
+	t.Helper()
+	offset := -1
+	for i := 0; i < nth; i++ {
+		next := strings.Index(src[offset+1:], needle)
+		if next < 0 {
+			t.Fatalf("occurrence %d of %q not found", nth, needle)
+		}
+		offset += next + 1
+	}
+	line := strings.Count(src[:offset], "\n")
+	start := strings.LastIndex(src[:offset], "\n") + 1
+	return Position{Line: line, Character: len(src[start:offset]) + delta}
+}
+
+const (
+	buttonSource = `view Button(label: str):
+    <button>{label}</button>
+`
+	homeSource = `from components.button import Button
+from components.button import Button as Btn
+import components.button
+
+view Home(title: str):
+    heading = title.upper()
+    <div>
+        <h1>{heading}</h1>
+        <Button label="Go" />
+        <Btn label={title} />
+    </div>
+`
+)
+
+func TestAnalysisDefinition(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"components/button.psx": buttonSource,
+		"pages/home.psx":        homeSource,
+	})
+	button := filepath.Join(root, "components/button.psx")
+	home := filepath.Join(root, "pages/home.psx")
+
+	a := analyze(NewOverlay(filesystem.NewFileSystem(nil)), root, home, lexer.DefaultScannerConfig())
+	if len(a.diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %+v", a.diagnostics)
+	}
+
+	buttonName := Location{URI: pathToURI(button), Range: Range{Start: Position{0, 5}, End: Position{0, 11}}}
+	tests := []struct {
+		name     string
+		at       Position
+		expected *Location
+	}{
+		{"view tag", positionOf(t, homeSource, "<Button", 1, 2), &buttonName},
+		{"aliased view tag", positionOf(t, homeSource, "<Btn", 1, 1), &buttonName},
+		{"imported name", positionOf(t, homeSource, "Button", 1, 0), &buttonName},
+		{"module import", positionOf(t, homeSource, "components", 3, 0), &Location{URI: pathToURI(button)}},
+		{"local variable", positionOf(t, homeSource, "{heading}", 1, 1),
+			&Location{URI: pathToURI(home), Range: Range{Start: Position{5, 4}, End: Position{5, 11}}}},
+		{"parameter", positionOf(t, homeSource, "title.upper", 1, 0),
+			&Location{URI: pathToURI(home), Range: Range{Start: Position{4, 10}, End: Position{4, 15}}}},
+		{"builtin element", positionOf(t, homeSource, "<div", 1, 1), nil},
+		{"whitespace", Position{Line: 4, Character: 0}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.definition(tt.at)
+			switch {
+			case tt.expected == nil && got != nil:
+				t.Errorf("expected no definition, got %+v", *got)
+			case tt.expected != nil && got == nil:
+				t.Errorf("expected %+v, got no definition", *tt.expected)
+			case tt.expected != nil && *got != *tt.expected:
+				t.Errorf("expected %+v, got %+v", *tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestAnalysisHoverAndSymbols(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"components/button.psx": buttonSource,
+		"pages/home.psx":        homeSource,
+	})
+	home := filepath.Join(root, "pages/home.psx")
+	a := analyze(NewOverlay(filesystem.NewFileSystem(nil)), root, home, lexer.DefaultScannerConfig())
+
+	hover := a.hover(positionOf(t, homeSource, "<Button", 1, 1))
+	if hover == nil {
+		t.Fatal("expected hover for the view tag")
+	}
+	expected := "```python\nview Button(label: str)\n```\n\nDefined in `components/button.psx`"
+	if hover.Contents.Value != expected {
+		t.Errorf("hover:\n got: %q\nwant: %q", hover.Contents.Value, expected)
+	}
+	if hover = a.hover(positionOf(t, homeSource, "title.upper", 1, 0)); hover == nil || hover.Contents.Value != "```python\n(parameter) title\n```" {
+		t.Errorf("unexpected parameter hover: %+v", hover)
+	}
+
+	symbols := a.symbols()
+	if len(symbols) != 1 || symbols[0].Name != "Home" || symbols[0].Detail != "view" {
+		t.Fatalf("expected the Home view, got %+v", symbols)
+	}
+	if symbols[0].SelectionRange != (Range{Start: Position{4, 5}, End: Position{4, 9}}) {
+		t.Errorf("unexpected selection range %+v", symbols[0].SelectionRange)
+	}
+}
+
+func TestAnalysisDiagnostics(t *testing.T) {
+	root := writeProject(t, map[string]string{"page.psx": "view Page():\n    <div>\n"})
+	page := filepath.Join(root, "page.psx")
+
+	overlay := NewOverlay(filesystem.NewFileSystem(nil))
+	a := analyze(overlay, root, page, lexer.DefaultScannerConfig())
+	if len(a.diagnostics) == 0 {
+		t.Fatal("expected a diagnostic for the unclosed element")
+	}
+	if d := a.diagnostics[0]; d.Severity != SeverityError || d.Source != diagnosticSource || d.Code != "E0200" || d.Range.Start.Line == 0 && d.Range.Start.Character == 0 {
+		t.Errorf("unexpected diagnostic %+v", d)
+	}
+
+	// The editor's buffer wins over the file on disk
+	overlay.Set(page, 2, []byte("view Page():\n    <div></div>\n"))
+	if a = analyze(overlay, root, page, lexer.DefaultScannerConfig()); len(a.diagnostics) != 0 {
+		t.Errorf("expected the buffer to be analyzed, got %+v", a.diagnostics)
+	}
+}
+
+func TestAnalysisSuggestions(t *testing.T) {
+	src := "view Page(x: int):\n    if x = 1:\n        <p>one</p>\n"
+	root := writeProject(t, map[string]string{"page.psx": src})
+	page := filepath.Join(root, "page.psx")
+
+	a := analyze(NewOverlay(filesystem.NewFileSystem(nil)), root, page, lexer.DefaultScannerConfig())
+	if len(a.diagnostics) != 1 {
+		t.Fatalf("expected one syntax error, got %+v", a.diagnostics)
+	}
+	d := a.diagnostics[0]
+	if d.Data == nil || len(d.Data.Suggestions) != 1 {
+		t.Fatalf("expected a suggestion on the diagnostic, got %+v", d.Data)
+	}
+	want := positionOf(t, src, "=", 1, 0)
+	if fix := d.Data.Suggestions[0]; fix.Edit.NewText != "==" || fix.Edit.Range.Start != want || !strings.Contains(d.Message, "did you mean '=='?") {
+		t.Errorf("unexpected suggestion %+v in %q", fix, d.Message)
+	}
+
+	// The suggestion comes back as a quick fix
+	uri := "file:///page.psx"
+	actions := codeActions(CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Context:      CodeActionContext{Diagnostics: []Diagnostic{d}},
+	})
+	if len(actions) != 1 || actions[0].Kind != CodeActionKindQuickFix || !actions[0].IsPreferred {
+		t.Fatalf("expected one preferred quick fix, got %+v", actions)
+	}
+	if edits := actions[0].Edit.Changes[uri]; len(edits) != 1 || edits[0].NewText != "==" {
+		t.Errorf("unexpected edits %+v", actions[0].Edit)
+	}
+}
+
+func TestAnalysisWithSyntaxErrors(t *testing.T) {
+	src := "view Broken():\n    <p class=></p>\n\nview Page():\n    <p>{missing}</p>\n"
+	root := writeProject(t, map[string]string{"page.psx": src})
+	page := filepath.Join(root, "page.psx")
+
+	a := analyze(NewOverlay(filesystem.NewFileSystem(nil)), root, page, lexer.DefaultScannerConfig())
+	if len(a.diagnostics) != 1 || a.diagnostics[0].Range.Start.Line != 1 {
+		t.Errorf("expected only the syntax error, got %+v", a.diagnostics)
+	}
+
+	// The statements around the error are still analyzed
+	symbols := a.symbols()
+	if len(symbols) != 2 || symbols[0].Name != "Broken" || symbols[1].Name != "Page" {
+		t.Errorf("expected both views, got %+v", symbols)
+	}
+}
+
+func TestAnalysisDeprecationWarnings(t *testing.T) {
+	src := "from components import Button\n\nview Page():\n    <Button />\n"
+	root := writeProject(t, map[string]string{
+		"components.psx": "@deprecated(\"use NewButton\")\nview Button():\n    <button></button>\n",
+		"page.psx":       src,
+	})
+	page := filepath.Join(root, "page.psx")
+
+	a := analyze(NewOverlay(filesystem.NewFileSystem(nil)), root, page, lexer.DefaultScannerConfig())
+	if len(a.diagnostics) != 2 {
+		t.Fatalf("expected a warning for the import and the use, got %+v", a.diagnostics)
+	}
+	for i, want := range []Position{positionOf(t, src, "Button", 1, 0), positionOf(t, src, "Button", 2, 0)} {
+		d := a.diagnostics[i]
+		if d.Severity != SeverityWarning || d.Message != "'Button' is deprecated: use NewButton" || d.Range.Start != want {
+			t.Errorf("diagnostic %d: unexpected %+v", i, d)
+		}
+		if d.Code != "W0300" || len(d.Tags) != 1 || d.Tags[0] != DiagnosticTagDeprecated {
+			t.Errorf("diagnostic %d: expected the deprecated tag, got %v", i, d.Tags)
+		}
+	}
+}
+
+func TestPositionConversion(t *testing.T) {
+	// "é" is one rune and one UTF-16 unit, "😀" one rune and two units
+	src := []byte("x = 1\ns = \"é😀\" + y\n")
+	tests := []struct {
+		lexer    lexer.Position
+		protocol Position
+	}{
+		{lexer.Position{Line: 1, Column: 1}, Position{Line: 0, Character: 0}},
+		{lexer.Position{Line: 2, Column: 6}, Position{Line: 1, Character: 5}},
+		{lexer.Position{Line: 2, Column: 7}, Position{Line: 1, Character: 6}},
+		{lexer.Position{Line: 2, Column: 8}, Position{Line: 1, Character: 8}},
+		{lexer.Position{Line: 2, Column: 12}, Position{Line: 1, Character: 12}},
+	}
+	for _, tt := range tests {
+		if got := toPosition(src, tt.lexer); got != tt.protocol {
+			t.Errorf("toPosition(%v) = %+v, want %+v", tt.lexer, got, tt.protocol)
+		}
+		if got := fromPosition(src, tt.protocol); got != tt.lexer {
+			t.Errorf("fromPosition(%+v) = %v, want %v", tt.protocol, got, tt.lexer)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConnFraming(t *testing.T) {
+	var out bytes.Buffer
+	c := newConn(strings.NewReader(""), &out)
+	if err := c.reply(json.RawMessage("7"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.reply(json.RawMessage(`"a"`), nil, &rpcError{Code: codeMethodNotFound, Message: "nope"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := frame(`{"jsonrpc":"2.0","id":7,"result":null}`) +
+		frame(`{"jsonrpc":"2.0","id":"a","error":{"code":-32601,"message":"nope"}}`)
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n got: %q\nwant: %q", out.String(), expected)
+	}
+}
+
+func TestConnRead(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	input := "Content-Length: " + strconv.Itoa(len(body)) + "\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n" + body +
+		frame("{bad}")
+	c := newConn(strings.NewReader(input), io.Discard)
+
+	msg, err := c.read()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if msg.Method != "initialize" || !msg.isRequest() || string(msg.ID) != "1" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	var rpcErr *rpcError
+	if _, err := c.read(); !errors.As(err, &rpcErr) || rpcErr.Code != codeParseError {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if _, err := c.read(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestConnReadTooLarge(t *testing.T) {
+	tests := map[string]string{
+		strconv.Itoa(maxMessageSize + 1): "too large",
+		"99999999999999999999":           "invalid Content-Length",
+	}
+	for length, want := range tests {
+		c := newConn(strings.NewReader("Content-Length: "+length+"\r\n\r\n{}"), io.Discard)
+		if _, err := c.read(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Content-Length %s: expected an error containing %q, got %v", length, want, err)
+		}
+	}
+}
+
+// frame adds the base protocol header to a message body
+func frame(body string) string {
+	return "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+}
@@ -0,0 +1,25 @@
+// Package lsp implements a Language Server Protocol server for PSX files.
+//
+// The server speaks JSON-RPC over a pair of streams, usually stdin and
+// stdout, and provides:
+//   - Diagnostics: scanner, parser and resolver errors, published whenever a
+//     document is opened, changed or saved
+//   - Go to definition: for names and view tags, across files
+//   - Hover: the definition header of views, functions and classes, and the
+//     kind of other names
+//   - Document symbols: the views, functions, classes and variables of a file
+//
+// Open documents are kept in an in-memory overlay, so unsaved buffers are
+// analyzed instead of the files on disk, and imports of them resolve to the
+// buffer contents.
+//
+// # Usage
+//
+//	server := lsp.NewServer(logger, compiler.Options{})
+//	err := server.Run(ctx, os.Stdin, os.Stdout)
+//
+// Each analysis reuses the compiler front end: the document and the files it
+// imports, transitively, are scanned and parsed, their symbols are collected
+// into a symbol.Registry in dependency order, and the document is resolved
+// against it. Definitions in other files are found through the registry.
+package lsp
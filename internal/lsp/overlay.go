@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// Overlay is a FileSystem that serves open documents from memory and
+// everything else from the underlying FileSystem. It is safe for concurrent
+// use.
+type Overlay struct {
+	filesystem.FileSystem
+
+	mu   sync.RWMutex
+	docs map[string]*document // Absolute path -> open document
+}
+
+// document is the editor's copy of a file
+type document struct {
+	version int
+	text    []byte
+}
+
+// NewOverlay creates an overlay over fs with no open documents
+func NewOverlay(fs filesystem.FileSystem) *Overlay {
+	return &Overlay{FileSystem: fs, docs: make(map[string]*document)}
+}
+
+// Set opens path, or replaces its contents, with the editor's text
+func (o *Overlay) Set(path string, version int, text []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.docs[path] = &document{version: version, text: text}
+}
+
+// Close forgets the editor's copy of path; reads go to disk again
+func (o *Overlay) Close(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.docs, path)
+}
+
+// Version returns the version of an open document
+func (o *Overlay) Version(path string) (int, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	doc, ok := o.docs[path]
+	if !ok {
+		return 0, false
+	}
+	return doc.version, true
+}
+
+// Open returns the paths of the open documents, sorted
+func (o *Overlay) Open() []string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	paths := make([]string, 0, len(o.docs))
+	for path := range o.docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ReadFile returns the editor's copy of an open document, or the file on disk
+func (o *Overlay) ReadFile(path string) ([]byte, error) {
+	o.mu.RLock()
+	doc, ok := o.docs[path]
+	o.mu.RUnlock()
+	if ok {
+		return doc.text, nil
+	}
+	return o.FileSystem.ReadFile(path)
+}
+
+// Exists reports open documents as existing, even if they were never saved
+func (o *Overlay) Exists(path string) (bool, error) {
+	o.mu.RLock()
+	_, ok := o.docs[path]
+	o.mu.RUnlock()
+	if ok {
+		return true, nil
+	}
+	return o.FileSystem.Exists(path)
+}
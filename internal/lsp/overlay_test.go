@@ -0,0 +1,45 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+func TestOverlay(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "saved.psx")
+	unsaved := filepath.Join(dir, "unsaved.psx")
+	if err := os.WriteFile(saved, []byte("on disk"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay := NewOverlay(filesystem.NewFileSystem(nil))
+	overlay.Set(saved, 3, []byte("in buffer"))
+	overlay.Set(unsaved, 1, []byte("new"))
+
+	if content, _ := overlay.ReadFile(saved); string(content) != "in buffer" {
+		t.Errorf("expected the buffer contents, got %q", content)
+	}
+	if exists, _ := overlay.Exists(unsaved); !exists {
+		t.Error("expected an unsaved open document to exist")
+	}
+	if version, _ := overlay.Version(saved); version != 3 {
+		t.Errorf("expected version 3, got %d", version)
+	}
+	if open := overlay.Open(); !reflect.DeepEqual(open, []string{saved, unsaved}) {
+		t.Errorf("unexpected open documents %v", open)
+	}
+
+	overlay.Close(saved)
+	overlay.Close(unsaved)
+	if content, _ := overlay.ReadFile(saved); string(content) != "on disk" {
+		t.Errorf("expected the file on disk after close, got %q", content)
+	}
+	if exists, _ := overlay.Exists(unsaved); exists {
+		t.Error("expected a closed unsaved document to be gone")
+	}
+}
@@ -0,0 +1,321 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/fjvillamarin/topple/compiler"
+	"github.com/fjvillamarin/topple/compiler/observe"
+	"github.com/fjvillamarin/topple/internal/filesystem"
+)
+
+// ErrExitWithoutShutdown is returned by Run when the client sends exit
+// without asking the server to shut down first
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+
+// Server answers LSP requests for PSX documents
+type Server struct {
+	logger  observe.Logger
+	options compiler.Options
+	version string
+	overlay *Overlay
+	conn    *conn
+
+	rootDir     string // Workspace root, for resolving absolute imports
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer creates a server analyzing documents with the given compiler
+// options and reporting version to clients. A nil logger discards all
+// output; it must not write to the server's output stream.
+func NewServer(logger observe.Logger, options compiler.Options, version string) *Server {
+	return &Server{
+		logger:  observe.LoggerOrNop(logger),
+		options: options,
+		version: version,
+		overlay: NewOverlay(filesystem.NewFileSystem(nil)),
+	}
+}
+
+// Run serves requests read from in, writing responses and notifications to
+// out, until the client sends exit, in is closed or ctx is cancelled.
+func (s *Server) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.conn = newConn(in, out)
+
+	messages := make(chan *message)
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := s.conn.read()
+			var rpcErr *rpcError
+			if errors.As(err, &rpcErr) {
+				// Malformed JSON has no usable ID
+				if err := s.conn.reply(nil, nil, rpcErr); err != nil {
+					failed <- err
+					return
+				}
+				continue
+			}
+			if err != nil {
+				failed <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-failed:
+			if err == io.EOF {
+				if s.shutdown {
+					return nil
+				}
+				return fmt.Errorf("connection closed before shutdown")
+			}
+			return err
+		case msg := <-messages:
+			if msg.Method == "exit" {
+				if !s.shutdown {
+					return ErrExitWithoutShutdown
+				}
+				return nil
+			}
+			if err := s.handle(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle dispatches one message. Only errors writing to the client are
+// returned; request errors are sent back as responses.
+func (s *Server) handle(msg *message) error {
+	s.logger.Debug("LSP message", "method", msg.Method)
+
+	if !msg.isRequest() {
+		if !s.initialized {
+			return nil
+		}
+		s.notification(msg)
+		return nil
+	}
+
+	var result any
+	var rpcErr *rpcError
+	switch {
+	case msg.Method == "initialize":
+		result, rpcErr = s.initialize(msg.Params)
+	case !s.initialized:
+		rpcErr = &rpcError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	case s.shutdown:
+		rpcErr = &rpcError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	case msg.Method == "shutdown":
+		s.shutdown = true
+	case msg.Method == "textDocument/definition":
+		result, rpcErr = s.positionRequest(msg.Params, func(a *analysis, pos Position) any {
+			if loc := a.definition(pos); loc != nil {
+				return loc
+			}
+			return nil
+		})
+	case msg.Method == "textDocument/hover":
+		result, rpcErr = s.positionRequest(msg.Params, func(a *analysis, pos Position) any {
+			if hover := a.hover(pos); hover != nil {
+				return hover
+			}
+			return nil
+		})
+	case msg.Method == "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if rpcErr = decode(msg.Params, &params); rpcErr == nil {
+			if a := s.analyzeURI(params.TextDocument.URI); a != nil {
+				result = a.symbols()
+			}
+		}
+	case msg.Method == "textDocument/codeAction":
+		var params CodeActionParams
+		if rpcErr = decode(msg.Params, &params); rpcErr == nil {
+			result = codeActions(params)
+		}
+	default:
+		rpcErr = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+	}
+	return s.conn.reply(msg.ID, result, rpcErr)
+}
+
+func (s *Server) initialize(raw json.RawMessage) (any, *rpcError) {
+	if s.initialized {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server already initialized"}
+	}
+	var params InitializeParams
+	if rpcErr := decode(raw, &params); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	switch {
+	case len(params.WorkspaceFolders) > 0:
+		s.rootDir, _ = uriToPath(params.WorkspaceFolders[0].URI)
+	case params.RootURI != "":
+		s.rootDir, _ = uriToPath(params.RootURI)
+	case params.RootPath != "":
+		s.rootDir, _ = filepath.Abs(params.RootPath)
+	}
+	s.initialized = true
+	s.logger.Info("Language server initialized", "root", s.rootDir)
+
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       syncFull,
+			DefinitionProvider:     true,
+			HoverProvider:          true,
+			DocumentSymbolProvider: true,
+			CodeActionProvider:     true,
+		},
+		ServerInfo: ServerInfo{Name: "topple", Version: s.version},
+	}, nil
+}
+
+// notification handles document synchronization. Every open document is
+// analyzed again after a change, since it may import the changed one.
+func (s *Server) notification(msg *message) {
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if decode(msg.Params, &params) != nil {
+			return
+		}
+		if path, ok := uriToPath(params.TextDocument.URI); ok {
+			s.overlay.Set(path, params.TextDocument.Version, []byte(params.TextDocument.Text))
+			s.publishAll()
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if decode(msg.Params, &params) != nil || len(params.ContentChanges) == 0 {
+			return
+		}
+		if path, ok := uriToPath(params.TextDocument.URI); ok {
+			// With full sync the last change holds the whole document
+			text := params.ContentChanges[len(params.ContentChanges)-1].Text
+			s.overlay.Set(path, params.TextDocument.Version, []byte(text))
+			s.publishAll()
+		}
+	case "textDocument/didSave":
+		s.publishAll()
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if decode(msg.Params, &params) != nil {
+			return
+		}
+		if path, ok := uriToPath(params.TextDocument.URI); ok {
+			s.overlay.Close(path)
+			s.publish(path, []Diagnostic{})
+			s.publishAll()
+		}
+	}
+}
+
+// publishAll publishes the diagnostics of every open document
+func (s *Server) publishAll() {
+	for _, path := range s.overlay.Open() {
+		a := s.analyze(path)
+		diagnostics := a.diagnostics
+		if diagnostics == nil {
+			diagnostics = []Diagnostic{}
+		}
+		s.publish(path, diagnostics)
+	}
+}
+
+func (s *Server) publish(path string, diagnostics []Diagnostic) {
+	params := PublishDiagnosticsParams{URI: pathToURI(path), Diagnostics: diagnostics}
+	if version, ok := s.overlay.Version(path); ok {
+		params.Version = &version
+	}
+	if err := s.conn.notify("textDocument/publishDiagnostics", params); err != nil {
+		s.logger.Warn("Could not publish diagnostics", "file", path, "error", err)
+	}
+}
+
+// codeActions offers the suggestions of the diagnostics in params as quick
+// fixes. The suggestions travel in the data of each diagnostic, so the
+// document does not need to be analyzed again.
+func codeActions(params CodeActionParams) []CodeAction {
+	actions := []CodeAction{}
+	for _, diagnostic := range params.Context.Diagnostics {
+		if diagnostic.Source != diagnosticSource || diagnostic.Data == nil {
+			continue
+		}
+		for i, suggestion := range diagnostic.Data.Suggestions {
+			actions = append(actions, CodeAction{
+				Title:       suggestion.Title,
+				Kind:        CodeActionKindQuickFix,
+				Diagnostics: []Diagnostic{diagnostic},
+				IsPreferred: i == 0,
+				Edit: WorkspaceEdit{Changes: map[string][]TextEdit{
+					params.TextDocument.URI: {suggestion.Edit},
+				}},
+			})
+		}
+	}
+	return actions
+}
+
+// positionRequest decodes the parameters of a position request and answers
+// it from the analysis of the document
+func (s *Server) positionRequest(raw json.RawMessage, answer func(*analysis, Position) any) (any, *rpcError) {
+	var params TextDocumentPositionParams
+	if rpcErr := decode(raw, &params); rpcErr != nil {
+		return nil, rpcErr
+	}
+	a := s.analyzeURI(params.TextDocument.URI)
+	if a == nil {
+		return nil, nil
+	}
+	return answer(a, params.Position), nil
+}
+
+func (s *Server) analyzeURI(uri string) *analysis {
+	path, ok := uriToPath(uri)
+	if !ok {
+		return nil
+	}
+	return s.analyze(path)
+}
+
+func (s *Server) analyze(path string) *analysis {
+	return analyze(s.overlay, s.rootFor(path), path, s.options.ScannerConfig())
+}
+
+// rootFor returns the directory absolute imports of path resolve from: the
+// workspace root when path lies inside it, else the file's directory
+func (s *Server) rootFor(path string) string {
+	if s.rootDir != "" {
+		if rel, err := filepath.Rel(s.rootDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return s.rootDir
+		}
+	}
+	return filepath.Dir(path)
+}
+
+func decode(raw json.RawMessage, v any) *rpcError {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
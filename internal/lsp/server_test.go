@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjvillamarin/topple/compiler"
+)
+
+// testClient drives a server over in-memory pipes
+type testClient struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	done   chan error
+
+	diagnostics []PublishDiagnosticsParams // Notifications received so far
+}
+
+func startServer(t *testing.T) *testClient {
+	t.Helper()
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	c := &testClient{t: t, conn: newConn(clientIn, clientOut), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(nil, compiler.Options{}, "test").Run(context.Background(), serverIn, serverOut)
+		serverOut.Close()
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+// call sends a request and returns its response, collecting the
+// notifications sent before it
+func (c *testClient) call(method string, params any, result any) *rpcError {
+	c.t.Helper()
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}); err != nil {
+		c.t.Fatalf("write %s: %v", method, err)
+	}
+	for {
+		resp := c.receive()
+		if resp.Method != "" {
+			continue
+		}
+		if string(resp.ID) != string(id) {
+			c.t.Fatalf("response to %s has id %s, want %s", method, resp.ID, id)
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				c.t.Fatalf("decode %s result: %v", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+// notify sends a notification and waits for the diagnostics it publishes
+func (c *testClient) notify(method string, params any, published int) {
+	c.t.Helper()
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params}); err != nil {
+		c.t.Fatalf("write %s: %v", method, err)
+	}
+	for i := 0; i < published; i++ {
+		if msg := c.receive(); msg.Method != "textDocument/publishDiagnostics" {
+			c.t.Fatalf("expected diagnostics after %s, got %+v", method, msg)
+		}
+	}
+}
+
+// incoming is any message the server sends
+type incoming struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func (c *testClient) receive() incoming {
+	c.t.Helper()
+	header, err := c.conn.in.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("read header: %v", err)
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatalf("bad Content-Length: %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.conn.in.R, body); err != nil {
+		c.t.Fatalf("read body: %v", err)
+	}
+	var msg incoming
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("decode message: %v", err)
+	}
+	if msg.Method == "textDocument/publishDiagnostics" {
+		var params PublishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.t.Fatalf("decode diagnostics: %v", err)
+		}
+		c.diagnostics = append(c.diagnostics, params)
+	}
+	return msg
+}
+
+func (c *testClient) lastDiagnostics(uri string) []Diagnostic {
+	for i := len(c.diagnostics) - 1; i >= 0; i-- {
+		if c.diagnostics[i].URI == uri {
+			return c.diagnostics[i].Diagnostics
+		}
+	}
+	c.t.Fatalf("no diagnostics published for %s", uri)
+	return nil
+}
+
+func TestServerSession(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"components/button.psx": buttonSource,
+		"pages/home.psx":        "view Home():\n    <p>on disk</p>\n",
+	})
+	buttonURI := pathToURI(filepath.Join(root, "components/button.psx"))
+	homeURI := pathToURI(filepath.Join(root, "pages/home.psx"))
+
+	c := startServer(t)
+
+	if err := c.call("textDocument/hover", TextDocumentPositionParams{}, nil); err == nil || err.Code != codeServerNotInitialized {
+		t.Fatalf("expected a not-initialized error, got %v", err)
+	}
+
+	var init InitializeResult
+	if err := c.call("initialize", InitializeParams{RootURI: pathToURI(root)}, &init); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	if !init.Capabilities.DefinitionProvider || !init.Capabilities.HoverProvider || !init.Capabilities.DocumentSymbolProvider {
+		t.Errorf("missing capabilities: %+v", init.Capabilities)
+	}
+	c.notify("initialized", struct{}{}, 0)
+
+	// The unsaved buffer imports Button; the file on disk does not
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI: homeURI, LanguageID: "psx", Version: 1, Text: homeSource,
+	}}, 1)
+	if diagnostics := c.lastDiagnostics(homeURI); len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %+v", diagnostics)
+	}
+
+	var location Location
+	at := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: homeURI}, Position: positionOf(t, homeSource, "<Button", 1, 1)}
+	if err := c.call("textDocument/definition", at, &location); err != nil {
+		t.Fatalf("definition: %v", err)
+	}
+	if location.URI != buttonURI || location.Range.Start != (Position{0, 5}) {
+		t.Errorf("unexpected definition %+v", location)
+	}
+
+	var hover Hover
+	if err := c.call("textDocument/hover", at, &hover); err != nil {
+		t.Fatalf("hover: %v", err)
+	}
+	if !strings.Contains(hover.Contents.Value, "view Button(label: str)") {
+		t.Errorf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	var symbols []DocumentSymbol
+	if err := c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: homeURI}}, &symbols); err != nil {
+		t.Fatalf("documentSymbol: %v", err)
+	}
+	if len(symbols) != 1 || symbols[0].Name != "Home" {
+		t.Errorf("unexpected symbols %+v", symbols)
+	}
+
+	// Opening the imported file publishes for both open documents
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{
+		URI: buttonURI, LanguageID: "psx", Version: 1, Text: buttonSource,
+	}}, 2)
+
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: homeURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "view Home():\n    <div>\n"}},
+	}, 2)
+	if diagnostics := c.lastDiagnostics(homeURI); len(diagnostics) == 0 {
+		t.Error("expected diagnostics for the broken buffer")
+	}
+
+	// Nothing is defined at an unknown position
+	var result json.RawMessage
+	nowhere := TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: homeURI}, Position: Position{Line: 40}}
+	if err := c.call("textDocument/definition", nowhere, &result); err != nil || string(result) != "null" {
+		t.Errorf("expected a null definition, got %s (%v)", result, err)
+	}
+
+	if err := c.call("workspace/symbol", struct{}{}, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %v", err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "method": "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-c.done:
+		if err != nil {
+			t.Errorf("Run returned %v after shutdown and exit", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit")
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := startServer(t)
+	if err := c.conn.write(map[string]any{"jsonrpc": "2.0", "method": "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-c.done:
+		if err != ErrExitWithoutShutdown {
+			t.Errorf("expected ErrExitWithoutShutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not exit")
+	}
+}
@@ -0,0 +1,421 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the project manifest
+const FileName = "topple.toml"
+
+// Manifest is a project's topple.toml: where its sources are, where they
+// compile to, and the compile options every command uses unless a flag
+// overrides them. Relative paths in it are resolved against its directory.
+type Manifest struct {
+	Path string // Absolute path of the manifest
+	Root string // Source root; the manifest's directory unless root is set
+	Out  string // Output directory; "" writes outputs next to the sources
+
+	builds []*Build // Build targets of [build.<name>] tables, by name
+
+	flags map[string]any // Flag name -> value, as the CLI decodes it
+}
+
+// Build is a build target declared in a [build.<name>] table: a part of the
+// project, such as an app or a library, compiled with its own source root,
+// output directory, entry points and strictness. Settings it leaves out come
+// from the rest of the manifest.
+type Build struct {
+	Name string
+	Root string // Source root; the manifest's root unless root is set
+	Out  string // Output directory; the manifest's unless out is set
+
+	flags map[string]any // Flag name -> value, as the CLI decodes it
+}
+
+// kind is the type of a manifest setting
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindInt
+	kindStrings
+	kindPath  // A path, resolved against the manifest's directory
+	kindPaths // A list of paths
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "true or false"
+	case kindInt:
+		return "an integer"
+	case kindStrings:
+		return "an array of strings"
+	case kindPaths:
+		return "an array of paths"
+	case kindPath:
+		return "a path"
+	default:
+		return "a string"
+	}
+}
+
+// setting is a key of the manifest and the flag it sets
+type setting struct {
+	kind kind
+	flag string // "" for keys read into Manifest fields only
+}
+
+// settings lists the keys of the manifest by table, "" for the top level
+var settings = map[string]map[string]setting{
+	"": {
+		"root":         {kindPath, "source-root"},
+		"out":          {kindPath, ""},
+		"recursive":    {kindBool, "recursive"},
+		"search_paths": {kindPaths, "search-path"},
+		"runtime":      {kindString, "runtime-module"},
+		"runtime_api":  {kindInt, "runtime-api"},
+		"target":       {kindString, "target"},
+	},
+	"compile": {
+		"html_comments":       {kindString, "html-comments"},
+		"intrinsic_elements":  {kindStrings, "intrinsic-element"},
+		"deny_elements":       {kindStrings, "deny-element"},
+		"attribute_defaults":  {kindPath, "attribute-defaults"},
+		"early_returns":       {kindString, "early-returns"},
+		"markdown":            {kindBool, "markdown"},
+		"preserve_whitespace": {kindBool, "preserve-whitespace"},
+		"source_markers":      {kindBool, "source-markers"},
+		"line_directives":     {kindBool, "line-directives"},
+		"strict_props":        {kindBool, "strict-props"},
+		"html_check":          {kindBool, "html-check"},
+		"naming":              {kindString, "naming"},
+		"source_map":          {kindBool, "source-map"},
+		"stubs":               {kindBool, "stubs"},
+		"lockfile":            {kindString, "lockfile"},
+		"check_assets":        {kindBool, "check-assets"},
+		"asset_dir":           {kindPath, "asset-dir"},
+		"asset_url":           {kindString, "asset-url"},
+		"header":              {kindString, "header"},
+	},
+	"imports": {
+		"external": {kindStrings, "external-module"},
+		"internal": {kindStrings, "internal-module"},
+		"strict":   {kindBool, "strict-imports"},
+	},
+}
+
+// buildSettings lists the keys of a [build.<name>] table
+var buildSettings = map[string]setting{
+	"root":           {kindPath, "source-root"},
+	"out":            {kindPath, ""},
+	"entry":          {kindPaths, "entry"},
+	"search_paths":   {kindPaths, "search-path"},
+	"target":         {kindString, "target"},
+	"strict_props":   {kindBool, "strict-props"},
+	"strict_imports": {kindBool, "strict-imports"},
+}
+
+// Find looks for the manifest in dir and its parents, returning nil when
+// there is none
+func Find(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the manifest at path
+func Load(path string) (*Manifest, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse decodes the manifest at path from data, rejecting unknown keys and
+// values of the wrong type
+func Parse(path string, data []byte) (*Manifest, error) {
+	values, err := decodeTOML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	m := &Manifest{Path: path, Root: dir, flags: make(map[string]any)}
+
+	var builds map[string]any
+	for _, table := range sortedKeys(values) {
+		if section, ok := values[table].(map[string]any); ok {
+			if table == "build" {
+				builds = section
+				continue
+			}
+			keys, known := settings[table]
+			if !known {
+				return nil, fmt.Errorf("%s: unknown table [%s] (valid: [compile], [imports], [build.<name>])", path, table)
+			}
+			for _, key := range sortedKeys(section) {
+				value, err := checkSetting(dir, table+"."+key, keys[key], section[key])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+				m.set(keys[key], value)
+			}
+			continue
+		}
+		s := settings[""][table]
+		value, err := checkSetting(dir, table, s, values[table])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		switch table {
+		case "root":
+			m.Root = value.(string)
+		case "out":
+			m.Out = value.(string)
+		}
+		m.set(s, value)
+	}
+	m.flags["source-root"] = m.Root
+
+	// Builds are read last, as their root and out default to the manifest's
+	for _, name := range sortedKeys(builds) {
+		build, err := m.parseBuild(dir, name, builds[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		m.builds = append(m.builds, build)
+	}
+	return m, nil
+}
+
+// parseBuild decodes the [build.<name>] table of a build target
+func (m *Manifest) parseBuild(dir, name string, value any) (*Build, error) {
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("build.%s must be a table such as [build.%s]", name, name)
+	}
+	build := &Build{Name: name, Root: m.Root, Out: m.Out, flags: make(map[string]any)}
+	for _, key := range sortedKeys(table) {
+		s := buildSettings[key]
+		value, err := checkSetting(dir, "build."+name+"."+key, s, table[key])
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "root":
+			build.Root = value.(string)
+		case "out":
+			build.Out = value.(string)
+		}
+		if s.flag != "" {
+			build.flags[s.flag] = value
+		}
+	}
+	build.flags["source-root"] = build.Root
+	return build, nil
+}
+
+// set records the value of a setting
+func (m *Manifest) set(s setting, value any) {
+	if s.flag != "" {
+		m.flags[s.flag] = value
+	}
+}
+
+// checkSetting checks the value of a key, returning it with its paths
+// resolved against dir
+func checkSetting(dir, key string, s setting, value any) (any, error) {
+	if s == (setting{}) {
+		return nil, fmt.Errorf("unknown setting %s", key)
+	}
+	invalid := fmt.Errorf("%s must be %s", key, s.kind)
+
+	switch s.kind {
+	case kindString, kindPath:
+		str, ok := value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if s.kind == kindPath {
+			if str == "" {
+				return nil, invalid
+			}
+			str = resolvePath(dir, str)
+		}
+		value = str
+	case kindBool:
+		if _, ok := value.(bool); !ok {
+			return nil, invalid
+		}
+	case kindInt:
+		if _, ok := value.(int64); !ok {
+			return nil, invalid
+		}
+	case kindStrings, kindPaths:
+		list, ok := value.([]any)
+		if !ok {
+			return nil, invalid
+		}
+		for i, item := range list {
+			str, ok := item.(string)
+			if !ok || (s.kind == kindPaths && str == "") {
+				return nil, invalid
+			}
+			if s.kind == kindPaths {
+				list[i] = resolvePath(dir, str)
+			}
+		}
+	}
+	return value, nil
+}
+
+// OutputDir returns the output directory, "" when the manifest declares none
+// or there is no manifest
+func (m *Manifest) OutputDir() string {
+	if m == nil {
+		return ""
+	}
+	return m.Out
+}
+
+// Flag returns the value the manifest gives a command-line flag, in the
+// form the flag parser decodes: a string, bool, int64 or []any of strings
+func (m *Manifest) Flag(name string) (any, bool) {
+	if m == nil {
+		return nil, false
+	}
+	value, ok := m.flags[name]
+	return value, ok
+}
+
+// Builds returns the build targets of the manifest, sorted by name
+func (m *Manifest) Builds() []*Build {
+	if m == nil {
+		return nil
+	}
+	return m.builds
+}
+
+// Build returns the build target named name, nil when there is none
+func (m *Manifest) Build(name string) *Build {
+	if m == nil {
+		return nil
+	}
+	for _, build := range m.builds {
+		if build.Name == name {
+			return build
+		}
+	}
+	return nil
+}
+
+// Flag returns the value the build gives a command-line flag, overriding the
+// rest of the manifest, in the form Manifest.Flag returns
+func (b *Build) Flag(name string) (any, bool) {
+	value, ok := b.flags[name]
+	return value, ok
+}
+
+// Scaffold returns a manifest declaring root and out, "" leaving them
+// unset, with the other settings commented out at their defaults
+func Scaffold(root, out string) []byte {
+	var b strings.Builder
+	b.WriteString("# Topple project manifest. Relative paths are resolved against this file,\n")
+	b.WriteString("# and command-line flags override the settings here.\n\n")
+	writeSetting(&b, "root", root, "src", "Directory holding the .psx sources, and root of absolute imports")
+	writeSetting(&b, "out", out, "build", "Where compiled .py files are written (default: next to the sources)")
+	b.WriteString(`recursive = true
+
+# Extra directories searched for absolute imports, such as a shared library
+# search_paths = ["../shared"]
+
+# Module generated code imports the runtime from, and its API version
+# runtime = "topple.psx"
+# runtime_api = 3
+
+# Python version generated code must run on: py38, py310 or py312
+# target = "py312"
+
+[compile]
+# html_comments = "strip"       # strip, render or python
+# early_returns = "allow"       # allow or forbid
+# intrinsic_elements = []
+# deny_elements = []
+# attribute_defaults = "defaults.json"
+# markdown = false
+# preserve_whitespace = false
+# strict_props = false
+# html_check = true             # Check void elements and nesting of markup
+# naming = "views=pascal,modules=snake"
+# source_map = false
+# stubs = false                 # Write a .pyi stub next to each generated file
+
+[imports]
+# external = ["fastapi"]
+# internal = []
+# strict = false
+
+# Build targets compiled together by "topple compile", each overriding the
+# settings above, such as an app and the component library it imports
+# [build.app]
+# root = "apps/web"
+# out = "build/web"
+# entry = ["apps/web/main.psx"]  # Release build keeping what these reach
+# search_paths = ["libs/ui"]
+# strict_props = true
+`)
+	return []byte(b.String())
+}
+
+// writeSetting writes a path setting, commented out with an example when
+// value is ""
+func writeSetting(b *strings.Builder, key, value, example, doc string) {
+	fmt.Fprintf(b, "# %s\n", doc)
+	if value == "" {
+		fmt.Fprintf(b, "# %s = %q\n\n", key, example)
+	} else {
+		fmt.Fprintf(b, "%s = %q\n\n", key, filepath.ToSlash(value))
+	}
+}
+
+// resolvePath resolves a path of the manifest against its directory
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(dir, filepath.FromSlash(path))
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	m, err := Parse(path, []byte(`root = "src"
+out = "/srv/build"
+search_paths = ["../shared", "/opt/ui"]
+runtime_api = 1
+target = "py310"
+
+[compile]
+html_comments = "render"
+intrinsic_elements = ["ui-icon"]
+attribute_defaults = "defaults.json"
+markdown = true
+
+[imports]
+external = ["fastapi"]
+strict = true
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Root != filepath.Join(dir, "src") || m.Out != "/srv/build" {
+		t.Errorf("expected root and out resolved against %s, got %q and %q", dir, m.Root, m.Out)
+	}
+
+	flags := map[string]any{
+		"source-root":        filepath.Join(dir, "src"),
+		"search-path":        []any{filepath.Join(filepath.Dir(dir), "shared"), "/opt/ui"},
+		"runtime-api":        int64(1),
+		"target":             "py310",
+		"html-comments":      "render",
+		"intrinsic-element":  []any{"ui-icon"},
+		"attribute-defaults": filepath.Join(dir, "defaults.json"),
+		"markdown":           true,
+		"external-module":    []any{"fastapi"},
+		"strict-imports":     true,
+	}
+	for name, expected := range flags {
+		if value, ok := m.Flag(name); !ok || !reflect.DeepEqual(value, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, value)
+		}
+	}
+	if _, ok := m.Flag("deny-element"); ok {
+		t.Error("expected settings left out to set no flag")
+	}
+
+	// Without root, the manifest's directory is the source root
+	m, err = Parse(path, []byte("recursive = true\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := m.Flag("source-root"); m.Root != dir || value != dir || m.OutputDir() != "" {
+		t.Errorf("expected root %s and no output directory, got %q, %v and %q", dir, m.Root, value, m.OutputDir())
+	}
+
+	// Without a manifest, nothing is set
+	var none *Manifest
+	if _, ok := none.Flag("source-root"); ok || none.OutputDir() != "" {
+		t.Error("expected a nil manifest to set nothing")
+	}
+}
+
+func TestParseBuilds(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Parse(filepath.Join(dir, FileName), []byte(`root = "src"
+target = "py310"
+
+[build.web]
+root = "apps/web"
+out = "build/web"
+entry = ["apps/web/main.psx:App"]
+search_paths = ["libs/ui"]
+strict_props = true
+
+[build.ui]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.Builds()) != 2 || m.Builds()[0].Name != "ui" || m.Builds()[1].Name != "web" {
+		t.Fatalf("expected the builds ui and web, got %v", m.Builds())
+	}
+
+	web := m.Build("web")
+	if web.Root != filepath.Join(dir, "apps", "web") || web.Out != filepath.Join(dir, "build", "web") {
+		t.Errorf("expected root and out resolved against %s, got %q and %q", dir, web.Root, web.Out)
+	}
+	flags := map[string]any{
+		"source-root":  filepath.Join(dir, "apps", "web"),
+		"entry":        []any{filepath.Join(dir, "apps", "web", "main.psx:App")},
+		"search-path":  []any{filepath.Join(dir, "libs", "ui")},
+		"strict-props": true,
+	}
+	for name, expected := range flags {
+		if value, ok := web.Flag(name); !ok || !reflect.DeepEqual(value, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, value)
+		}
+	}
+	// Settings a build leaves out come from the rest of the manifest
+	if _, ok := web.Flag("target"); ok {
+		t.Error("expected the build to leave target to the manifest")
+	}
+
+	// A build without root compiles the manifest's
+	if ui := m.Build("ui"); ui.Root != filepath.Join(dir, "src") || ui.Out != "" {
+		t.Errorf("expected the ui build to compile %s next to the sources, got %q and %q", filepath.Join(dir, "src"), ui.Root, ui.Out)
+	}
+	if m.Build("api") != nil {
+		t.Error("expected no api build")
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		error  string
+	}{
+		{"unknown key", "roots = \"src\"\n", "unknown setting roots"},
+		{"unknown table key", "[compile]\nmarkdwn = true\n", "unknown setting compile.markdwn"},
+		{"unknown table", "[builds]\n", "unknown table [builds]"},
+		{"build key", "[build]\nroot = \"src\"\n", "build.root must be a table such as [build.root]"},
+		{"unknown build key", "[build.app]\nmarkdown = true\n", "unknown setting build.app.markdown"},
+		{"wrong build type", "[build.app]\nentry = \"main.psx\"\n", "build.app.entry must be an array of paths"},
+		{"top-level table key", "[compile]\n[root]\n", "unknown table [root]"},
+		{"wrong type", "recursive = \"yes\"\n", "recursive must be true or false"},
+		{"wrong list type", "[imports]\nexternal = \"fastapi\"\n", "imports.external must be an array of strings"},
+		{"empty path", "out = \"\"\n", "out must be a path"},
+		{"syntax", "root = src\n", "line 1: expected a value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("/project/topple.toml", []byte(tt.source))
+			if err == nil || !strings.Contains(err.Error(), tt.error) || !strings.HasPrefix(err.Error(), "/project/topple.toml: ") {
+				t.Errorf("expected error containing %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
+
+func TestFindManifest(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "src", "pages")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, err := Find(nested); err != nil || m != nil {
+		t.Fatalf("expected no manifest, got %v (%v)", m, err)
+	}
+
+	// The scaffold declares what it is given and loads as written
+	if err := os.WriteFile(filepath.Join(dir, FileName), Scaffold("src", "build"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Find(nested)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m == nil || m.Path != filepath.Join(dir, FileName) {
+		t.Fatalf("expected the manifest of %s, got %v", dir, m)
+	}
+	if m.Root != filepath.Join(dir, "src") || m.Out != filepath.Join(dir, "build") {
+		t.Errorf("expected root src and out build, got %q and %q", m.Root, m.Out)
+	}
+	if value, _ := m.Flag("recursive"); value != true {
+		t.Errorf("expected the scaffold to compile recursively, got %v", value)
+	}
+
+	// A scaffold without root or out leaves them commented out
+	if _, err := Parse(filepath.Join(dir, FileName), Scaffold("", "")); err != nil {
+		t.Errorf("unexpected error in the default scaffold: %v", err)
+	}
+}
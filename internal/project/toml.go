@@ -0,0 +1,367 @@
+package project
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeTOML decodes the subset of TOML a manifest uses: [table] and
+// [table.name] headers, and bare keys set to strings, multi-line strings,
+// integers, booleans or arrays of them. Tables
+// decode to map[string]any, integers to int64 and arrays to []any. The rest
+// of TOML, such as floats, dates, inline tables, quoted or dotted keys and
+// arrays of tables, fails with an "unsupported TOML syntax" error naming
+// its line.
+func decodeTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{data: data, line: 1}
+	root := make(map[string]any)
+	current := root
+	defined := make(map[string]bool) // Headers seen
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.unsupported("arrays of tables ([[name]] headers)")
+			}
+			p.skipSpaces()
+			var names []string
+			for {
+				if err := p.checkBareKey(); err != nil {
+					return nil, err
+				}
+				name := p.key()
+				if name == "" {
+					return nil, p.errorf("expected a table header such as [compile]")
+				}
+				names = append(names, name)
+				p.skipSpaces()
+				if !p.consume('.') {
+					break
+				}
+				p.skipSpaces()
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected a table header such as [compile]")
+			}
+			header := strings.Join(names, ".")
+			if defined[header] {
+				return nil, p.errorf("%s is defined twice", header)
+			}
+			defined[header] = true
+
+			// [a.b] is the table b of the table a, which it creates if needed
+			current = root
+			for i, name := range names {
+				table, ok := current[name].(map[string]any)
+				if !ok {
+					if _, exists := current[name]; exists {
+						return nil, p.errorf("%s is not a table", strings.Join(names[:i+1], "."))
+					}
+					table = make(map[string]any)
+					current[name] = table
+				}
+				current = table
+			}
+		} else {
+			line := p.line
+			if err := p.checkBareKey(); err != nil {
+				return nil, err
+			}
+			key := p.key()
+			if key == "" {
+				return nil, p.errorf("expected a key")
+			}
+			p.skipSpaces()
+			if p.consume('.') {
+				p.skipSpaces()
+				return nil, p.unsupported("dotted keys such as %s.%s; set %[2]s in a [%[1]s] table", key, p.key())
+			}
+			if !p.consume('=') {
+				return nil, p.errorf("expected = after %s", key)
+			}
+			p.skipSpaces()
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := current[key]; exists {
+				return nil, fmt.Errorf("line %d: %s is set twice", line, key)
+			}
+			current[key] = value
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type tomlParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// unsupported reports TOML the manifest decoder does not read
+func (p *tomlParser) unsupported(format string, args ...any) error {
+	return p.errorf("unsupported TOML syntax: %s", fmt.Sprintf(format, args...))
+}
+
+// checkBareKey fails on a quoted key, which only bare keys may replace
+func (p *tomlParser) checkBareKey() error {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.unsupported("quoted keys; use a bare key of letters, digits, _ and -")
+	}
+	return nil
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) consume(c byte) bool {
+	if p.peek() != c || p.eof() {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+// skipSpaces skips spaces and tabs
+func (p *tomlParser) skipSpaces() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine expects nothing but a comment before the next line
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	p.consume('\r')
+	if !p.eof() && !p.consume('\n') {
+		return p.errorf("unexpected %q at the end of the line", p.peek())
+	}
+	p.line++
+	return nil
+}
+
+// key reads a bare key, such as search_paths
+func (p *tomlParser) key() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c != '_' && c != '-' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			break
+		}
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		if delimiter := strings.Repeat(string(c), 3); strings.HasPrefix(string(p.data[p.pos:]), delimiter) {
+			return p.multilineString(delimiter)
+		}
+		if c == '"' {
+			return p.basicString()
+		}
+		p.pos++
+		start := p.pos
+		for !p.eof() && p.peek() != '\'' && p.peek() != '\n' {
+			p.pos++
+		}
+		if !p.consume('\'') {
+			return nil, p.errorf("unterminated string")
+		}
+		return string(p.data[start : p.pos-1]), nil
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return nil, p.unsupported("inline tables; write a [table] instead")
+	case c == 't' || c == 'f' || c == 'i' || c == 'n':
+		word := p.key()
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "inf", "nan":
+			return nil, p.unsupported("floats such as %s", word)
+		}
+		return nil, p.errorf("unexpected %s; strings are written in quotes", word)
+	case c == '+' || c == '-' || ('0' <= c && c <= '9'):
+		start := p.pos
+		p.pos++
+		for !p.eof() && (('0' <= p.peek() && p.peek() <= '9') || p.peek() == '_') {
+			p.pos++
+		}
+		switch p.peek() {
+		case '.', 'e', 'E', 'i', 'n':
+			for !p.eof() && !strings.ContainsRune(" \t\r\n,]#", rune(p.peek())) {
+				p.pos++
+			}
+			return nil, p.unsupported("floats such as %s", p.data[start:p.pos])
+		case '-', ':':
+			return nil, p.unsupported("dates and times")
+		case 'x', 'o', 'b':
+			return nil, p.unsupported("hexadecimal, octal and binary integers")
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(string(p.data[start:p.pos]), "_", ""), 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %s", p.data[start:p.pos])
+		}
+		return n, nil
+	}
+	return nil, p.errorf("expected a value")
+}
+
+// basicString reads a double-quoted string, decoding its escapes
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// multilineString reads a string between triple quotes, which may span
+// lines; a newline right after the opening quotes is left out. Escapes are
+// decoded in """ strings only, as in TOML.
+func (p *tomlParser) multilineString(delimiter string) (string, error) {
+	p.pos += len(delimiter)
+	p.consume('\r')
+	if p.consume('\n') {
+		p.line++
+	}
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(string(p.data[p.pos:]), delimiter) {
+			p.pos += len(delimiter)
+			return b.String(), nil
+		}
+		c := p.peek()
+		p.pos++
+		switch {
+		case c == '\\' && delimiter == `"""`:
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		case c == '\n':
+			p.line++
+			b.WriteByte(c)
+		case c != '\r':
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape decodes the escape sequence after a backslash
+func (p *tomlParser) escape(b *strings.Builder) error {
+	escape := p.peek()
+	p.pos++
+	switch escape {
+	case '"', '\\':
+		b.WriteByte(escape)
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'u':
+		if p.pos+4 > len(p.data) {
+			return p.errorf("invalid escape \\u")
+		}
+		r, err := strconv.ParseUint(string(p.data[p.pos:p.pos+4]), 16, 32)
+		if err != nil {
+			return p.errorf("invalid escape \\u%s", p.data[p.pos:p.pos+4])
+		}
+		p.pos += 4
+		b.WriteRune(rune(r))
+	default:
+		return p.errorf("invalid escape \\%c", escape)
+	}
+	return nil
+}
+
+// array reads an array, which may span lines and end with a comma
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.consume(']') {
+			return values, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skipBlank()
+		if p.consume(']') {
+			return values, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
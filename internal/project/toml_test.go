@@ -0,0 +1,103 @@
+package project
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTOML(t *testing.T) {
+	values, err := decodeTOML([]byte(`# comment
+name = "a \"quoted\" \u00e9 value" # trailing comment
+path = 'C:\literal'
+count = 1_000
+negative = -2
+enabled = true
+
+[table]
+list = [
+    "one", # first
+    'two',
+]
+empty = []
+
+[group.first]
+name = "a"
+
+[group . second]
+
+[text]
+basic = """
+Line one
+\tLine "two"
+"""
+literal = '''C:\no\escapes'''
+after = 1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]any{
+		"name":     `a "quoted" é value`,
+		"path":     `C:\literal`,
+		"count":    int64(1000),
+		"negative": int64(-2),
+		"enabled":  true,
+		"table": map[string]any{
+			"list":  []any{"one", "two"},
+			"empty": []any{},
+		},
+		"group": map[string]any{
+			"first":  map[string]any{"name": "a"},
+			"second": map[string]any{},
+		},
+		"text": map[string]any{
+			"basic":   "Line one\n\tLine \"two\"\n",
+			"literal": `C:\no\escapes`,
+			"after":   int64(1),
+		},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		error  string
+	}{
+		{"missing equals", "name \"x\"\n", "line 1: expected = after name"},
+		{"bare word", "name = src\n", "line 1: expected a value"},
+		{"unknown literal", "name = truth\n", "line 1: unexpected truth"},
+		{"unterminated string", "\nname = \"x\n", "line 2: unterminated string"},
+		{"trailing value", "a = 1 2\n", "line 1: unexpected '2' at the end of the line"},
+		{"duplicate key", "a = 1\na = 2\n", "line 2: a is set twice"},
+		{"duplicate table", "[t]\n[t]\n", "line 2: t is defined twice"},
+		{"duplicate nested table", "[t.a]\n[t]\n[t.a]\n", "line 3: t.a is defined twice"},
+		{"value as table", "t = 1\n[t.a]\n", "line 2: t is not a table"},
+		{"empty header part", "[t.]\n", "line 1: expected a table header"},
+		{"unclosed array", "a = [\"x\"\n\"y\"]\n", "line 2: expected , or ] in array"},
+		{"bad escape", "a = \"\\q\"\n", "line 1: invalid escape \\q"},
+		{"unterminated multi-line string", "a = \"\"\"\nx\n", "line 3: unterminated string"},
+		{"float", "[build.web]\nratio = 1.5\n", "line 2: unsupported TOML syntax: floats such as 1.5"},
+		{"exponent", "a = [1, 2e3]\n", "line 1: unsupported TOML syntax: floats such as 2e3"},
+		{"infinity", "a = inf\n", "line 1: unsupported TOML syntax: floats such as inf"},
+		{"date", "\nreleased = 2024-05-01\n", "line 2: unsupported TOML syntax: dates and times"},
+		{"hexadecimal integer", "a = 0xff\n", "line 1: unsupported TOML syntax: hexadecimal"},
+		{"inline table", "[compile]\n\nopts = { strict = true }\n", "line 3: unsupported TOML syntax: inline tables"},
+		{"quoted key", "[compile]\n\"source-root\" = \"src\"\n", "line 2: unsupported TOML syntax: quoted keys"},
+		{"quoted header", "[\"compile\"]\n", "line 1: unsupported TOML syntax: quoted keys"},
+		{"dotted key", "[build]\nweb.root = \"src\"\n", "line 2: unsupported TOML syntax: dotted keys such as web.root; set root in a [web] table"},
+		{"array of tables", "a = 1\n[[build]]\n", "line 2: unsupported TOML syntax: arrays of tables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTOML([]byte(tt.source))
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("expected error containing %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
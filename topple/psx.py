@@ -1,6 +1,7 @@
 # psx_runtime.py
 
 import html
+import inspect
 from abc import ABC, abstractmethod
 from typing import Any, Dict, List, Optional, Union
 
@@ -71,19 +72,92 @@ def escape(raw: Any) -> Union[str, SafeHTML]:
 
 
 # -----------------------------------------------------------------------------
-# 3) Internal helper to format HTML attributes from a dict
+# 3) Attribute values: class and style serialization, and attribute formatting
 # -----------------------------------------------------------------------------
+def class_names(value: Any) -> Optional[str]:
+    """
+    Serialize a class attribute value to its canonical string.
+    - A str is kept as it is.
+    - A dict contributes its keys whose values are truthy.
+    - A list, tuple or set contributes its truthy items, serialized in turn.
+    Class names are joined with single spaces, each kept once in the order
+    it first appears. None is returned when no class remains, so the
+    attribute is left out.
+    """
+    if isinstance(value, str):
+        return value
+    names: List[str] = []
+
+    def collect(item: Any) -> None:
+        if not item:
+            return
+        if isinstance(item, dict):
+            for key, enabled in item.items():
+                if enabled:
+                    collect(str(key))
+        elif isinstance(item, (list, tuple, set, frozenset)):
+            for part in item:
+                collect(part)
+        else:
+            for name in str(item).split():
+                if name not in names:
+                    names.append(name)
+
+    collect(value)
+    return " ".join(names) or None
+
+
+def style_value(value: Any) -> Optional[str]:
+    """
+    Serialize a style attribute value to its canonical string.
+    - A str is kept as it is.
+    - A dict contributes "property: value" declarations, leaving out
+      properties whose value is None or False.
+    - A list or tuple contributes its items, serialized in turn.
+    Declarations are joined with "; ". None is returned when none remains,
+    so the attribute is left out.
+    """
+    if isinstance(value, str):
+        return value
+    declarations: List[str] = []
+
+    def collect(item: Any) -> None:
+        if item is None or item is False:
+            return
+        if isinstance(item, dict):
+            for prop, val in item.items():
+                if val is not None and val is not False:
+                    declarations.append(f"{prop}: {val}")
+        elif isinstance(item, (list, tuple)):
+            for part in item:
+                collect(part)
+        else:
+            declaration = str(item).strip().rstrip(";").strip()
+            if declaration:
+                declarations.append(declaration)
+
+    collect(value)
+    return "; ".join(declarations) or None
+
+
 def _render_attrs(attrs: Dict[str, Any]) -> str:
     """
     Given a dict mapping attribute names to values, produce a string like:
       ' class="btn" disabled id="foo"'
     - Skip any key whose value is False or None.
+    - Skip callables, such as event handlers kept for frameworks consuming the
+      element tree: they have no HTML form.
+    - Serialize dicts and lists given to class and style canonically.
     - If value is True → render ' key' (boolean attr).
     - Otherwise → render ' key="escaped_value"'.
     """
     pieces: List[str] = []
     for key, val in attrs.items():
-        if val is False or val is None:
+        if key == "class" and isinstance(val, (dict, list, tuple, set, frozenset)):
+            val = class_names(val)
+        elif key == "style" and isinstance(val, (dict, list, tuple)):
+            val = style_value(val)
+        if val is False or val is None or callable(val):
             continue
         if val is True:
             pieces.append(f" {key}")
@@ -311,9 +385,32 @@ class BaseView(ABC):
         This ensures _render() is only called once per instance.
         """
         if self._render_cache is None:
-            self._render_cache = self._render()
+            result = self._render()
+            if inspect.isawaitable(result):
+                # An async view: rendering it needs an event loop
+                if inspect.iscoroutine(result):
+                    result.close()
+                raise TypeError(
+                    f"{type(self).__name__} is an async view: render it with "
+                    f"'await view.render_async()'"
+                )
+            self._render_cache = result
         return self._render_cache
 
+    async def render_async(self) -> str:
+        """
+        Render an async view (one declared with 'async view') to a string.
+        Also works for synchronous views, so callers need not know which
+        kind they hold. Async views composed inside it are awaited by the
+        generated code.
+        """
+        if self._render_cache is None:
+            result = self._render()
+            if inspect.isawaitable(result):
+                result = await result
+            self._render_cache = result
+        return self.render()
+
     def render(self) -> str:
         """
         Calls _render() (with caching), then ensures the result is a string.
@@ -349,3 +446,31 @@ def fragment(children: List[Union["BaseView", Element, SafeHTML, str]]) -> Fragm
     Returns a FragmentElement that when rendered produces the concatenated HTML.
     """
     return FragmentElement(children)
+
+
+# -----------------------------------------------------------------------------
+# 9) Runtime API version negotiation
+# -----------------------------------------------------------------------------
+# Version of the API generated modules rely on. Bump it whenever the compiler
+# starts emitting code that older runtimes cannot run, and keep supporting
+# modules compiled for earlier versions.
+API_VERSION = 3
+
+
+def require_api(version: int) -> None:
+    """
+    Check that this runtime can run a module compiled for the given API version.
+
+    Generated modules call this right after importing the runtime, so a
+    compiler upgrade that needs a newer runtime fails at import time with a
+    clear message instead of rendering incorrectly.
+
+    Raises:
+        ImportError: if the module needs a newer runtime than the one installed
+    """
+    if version > API_VERSION:
+        raise ImportError(
+            f"module was compiled for topple runtime API {version}, but the installed "
+            f"runtime provides API {API_VERSION}: upgrade the topple package or "
+            f"recompile with --runtime-api {API_VERSION}"
+        )